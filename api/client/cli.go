@@ -2,17 +2,16 @@ package client
 
 import (
 	"crypto/tls"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
-	"text/template"
 	"time"
 
 	"github.com/docker/docker/cliconfig"
@@ -54,13 +53,11 @@ type DockerCli struct {
 	isTerminalOut bool
 	// transport holds the client transport instance.
 	transport *http.Transport
-}
-
-var funcMap = template.FuncMap{
-	"json": func(v interface{}) string {
-		a, _ := json.Marshal(v)
-		return string(a)
-	},
+	// timeout holds the timeout of the connections to the daemon.
+	timeout time.Duration
+	// described holds the command created by Subcmd while the commands are
+	// described for docker completion, instead of being run.
+	described *completionCommand
 }
 
 func (cli *DockerCli) Out() io.Writer {
@@ -117,7 +114,17 @@ func (cli *DockerCli) Subcmd(name, signature, description string, exitOnError bo
 	} else {
 		errorHandling = flag.ContinueOnError
 	}
+	if cli.described != nil {
+		// The command stops at the parsing of its arguments
+		errorHandling = flag.PanicOnError
+	}
 	flags := flag.NewFlagSet(name, errorHandling)
+	if cli.described != nil {
+		cli.described.name, cli.described.description, cli.described.flags = name, description, flags
+		flags.SetOutput(ioutil.Discard)
+		flags.Usage = func() { panic(errDescribed) }
+		return flags
+	}
 	flags.Usage = func() {
 		options := ""
 		if signature != "" {
@@ -149,7 +156,8 @@ func (cli *DockerCli) CheckTtyInput(attachStdin, ttyMode bool) error {
 // NewDockerCli returns a DockerCli instance with IO output and error streams set by in, out and err.
 // The key file, protocol (i.e. unix) and address are passed in as strings, along with the tls.Config. If the tls.Config
 // is set the client scheme will be set to https.
-// The client will be given a 32-second timeout (see https://github.com/docker/docker/pull/8035).
+// The client will be given a 32-second timeout (see https://github.com/docker/docker/pull/8035), unless
+// the configuration file sets another one.
 func NewDockerCli(in io.ReadCloser, out, err io.Writer, keyFile string, proto, addr string, tlsConfig *tls.Config) *DockerCli {
 	var (
 		inFd          uintptr
@@ -174,13 +182,22 @@ func NewDockerCli(in io.ReadCloser, out, err io.Writer, keyFile string, proto, a
 		err = out
 	}
 
+	configFile, e := cliconfig.Load(filepath.Join(homedir.Get(), ".docker"))
+	if e != nil {
+		fmt.Fprintf(err, "WARNING: Error loading config file:%v\n", e)
+	}
+
 	// The transport is created here for reuse during the client session.
 	tr := &http.Transport{
-		TLSClientConfig: tlsConfig,
+		TLSClientConfig:       tlsConfig,
+		ResponseHeaderTimeout: time.Duration(configFile.ResponseTimeout) * time.Second,
 	}
 
 	// Why 32? See https://github.com/docker/docker/pull/8035.
 	timeout := 32 * time.Second
+	if configFile.ConnectTimeout > 0 {
+		timeout = time.Duration(configFile.ConnectTimeout) * time.Second
+	}
 	if proto == "unix" {
 		// No need for compression in local communications.
 		tr.DisableCompression = true
@@ -192,11 +209,6 @@ func NewDockerCli(in io.ReadCloser, out, err io.Writer, keyFile string, proto, a
 		tr.Dial = (&net.Dialer{Timeout: timeout}).Dial
 	}
 
-	configFile, e := cliconfig.Load(filepath.Join(homedir.Get(), ".docker"))
-	if e != nil {
-		fmt.Fprintf(err, "WARNING: Error loading config file:%v\n", e)
-	}
-
 	return &DockerCli{
 		proto:         proto,
 		addr:          addr,
@@ -212,5 +224,6 @@ func NewDockerCli(in io.ReadCloser, out, err io.Writer, keyFile string, proto, a
 		tlsConfig:     tlsConfig,
 		scheme:        scheme,
 		transport:     tr,
+		timeout:       timeout,
 	}
 }
@@ -0,0 +1,417 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	flag "github.com/docker/docker/pkg/mflag"
+)
+
+var (
+	// errDescribed stops the commands run to describe them, once their flags
+	// are defined.
+	errDescribed = errors.New("command described")
+	// errDescribing is returned to the commands trying to reach the daemon
+	// while they are described, before they define their flags.
+	errDescribing = errors.New("the commands don't connect to the daemon while they are described")
+)
+
+// completionFlag is a flag of a command, as completed by the shells.
+type completionFlag struct {
+	names    []string // With their dashes, e.g. "-a" and "--all"
+	usage    string
+	hasValue bool
+}
+
+// completionCommand is a command, or a command of a group of commands such
+// as "network create", as completed by the shells.
+type completionCommand struct {
+	name        string
+	description string
+	flags       *flag.FlagSet
+}
+
+// CmdCompletion outputs a script completing the docker commands and their
+// flags in the given shell.
+//
+// Usage: docker completion bash|zsh|fish
+func (cli *DockerCli) CmdCompletion(args ...string) error {
+	cmd := cli.Subcmd("completion", "bash|zsh|fish", "Output a shell completion script", true)
+	cmd.Require(flag.Exact, 1)
+
+	cmd.ParseFlags(args, true)
+
+	var write func(io.Writer, []completionFlag, []completionCommand) error
+	switch shell := cmd.Arg(0); shell {
+	case "bash":
+		write = writeBashCompletion
+	case "zsh":
+		write = writeZshCompletion
+	case "fish":
+		write = writeFishCompletion
+	default:
+		return fmt.Errorf("Unsupported shell %q, the supported shells are bash, zsh and fish", shell)
+	}
+	return write(cli.out, completionFlags(flag.CommandLine), cli.describeCommands())
+}
+
+// describeCommands returns the commands of the client, sorted by name, each
+// command run to the definition of its flags.
+func (cli *DockerCli) describeCommands() []completionCommand {
+	var commands []completionCommand
+	t := reflect.TypeOf(cli)
+	for i := 0; i < t.NumMethod(); i++ {
+		name := t.Method(i).Name
+		if !strings.HasPrefix(name, "Cmd") || len(name) == len("Cmd") {
+			continue
+		}
+		method, ok := reflect.ValueOf(cli).Method(i).Interface().(func(...string) error)
+		if !ok {
+			continue
+		}
+		if c := cli.describeCommand(method); c != nil {
+			commands = append(commands, *c)
+		}
+	}
+	sort.Sort(byCommandName(commands))
+	return commands
+}
+
+// describeCommand runs method with --help, which stops after creating the
+// flags of the command, and returns the command, nil if method did not
+// create any flags. The client doesn't connect to the daemon meanwhile.
+func (cli *DockerCli) describeCommand(method func(...string) error) (c *completionCommand) {
+	cli.described = &completionCommand{}
+	defer func() {
+		if r := recover(); r != nil && r != errDescribed {
+			cli.described = nil
+			panic(r)
+		}
+		if cli.described.flags != nil {
+			c = cli.described
+		}
+		cli.described = nil
+	}()
+	method("--help")
+	return nil
+}
+
+type byCommandName []completionCommand
+
+func (a byCommandName) Len() int           { return len(a) }
+func (a byCommandName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byCommandName) Less(i, j int) bool { return a[i].name < a[j].name }
+
+// summary returns the first line of the description of the command.
+func (c *completionCommand) summary() string {
+	return strings.SplitN(c.description, "\n", 2)[0]
+}
+
+// group returns the group of the command, "" for the top-level commands.
+func (c *completionCommand) group() string {
+	if i := strings.Index(c.name, " "); i != -1 {
+		return c.name[:i]
+	}
+	return ""
+}
+
+// completionFlags returns the flags of fs, without their deprecated names.
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		cf := completionFlag{usage: strings.SplitN(f.Usage, "\n", 2)[0], hasValue: true}
+		if b, ok := f.Value.(interface {
+			IsBoolFlag() bool
+		}); ok && b.IsBoolFlag() {
+			cf.hasValue = false
+		}
+		for _, name := range f.Names {
+			if !strings.HasPrefix(name, "#") {
+				cf.names = append(cf.names, "-"+name)
+			}
+		}
+		if len(cf.names) > 0 {
+			flags = append(flags, cf)
+		}
+	})
+	return flags
+}
+
+// commandPatterns returns the "command:flag" patterns of the flags taking a
+// value, "" being the command of the global flags, and the groups of
+// commands, matched by the completion scripts against the words before the
+// current one.
+func commandPatterns(global []completionFlag, commands []completionCommand) (valueFlags, groups []string) {
+	addValueFlags := func(command string, flags []completionFlag) {
+		for _, f := range flags {
+			if f.hasValue {
+				for _, name := range f.names {
+					valueFlags = append(valueFlags, command+":"+name)
+				}
+			}
+		}
+	}
+	addValueFlags("", global)
+	seen := make(map[string]bool)
+	for _, c := range commands {
+		addValueFlags(c.name, completionFlags(c.flags))
+		if g := c.group(); g != "" && !seen[g] {
+			seen[g] = true
+			groups = append(groups, g)
+		}
+	}
+	return valueFlags, groups
+}
+
+// quoteAll returns the strings quoted by quote.
+func quoteAll(strs []string, quote func(string) string) []string {
+	quoted := make([]string, len(strs))
+	for i, s := range strs {
+		quoted[i] = quote(s)
+	}
+	return quoted
+}
+
+// shQuote quotes s in double quotes, for the names of the commands and
+// flags.
+func shQuote(s string) string {
+	return `"` + s + `"`
+}
+
+// writeCommandCase writes the cases of a shell case statement, for bash and
+// zsh, finding the command being completed from the words before the
+// current one: $command is the command, $skip is set after a flag taking a
+// value.
+func writeCommandCase(buf *bytes.Buffer, global []completionFlag, commands []completionCommand) {
+	valueFlags, groups := commandPatterns(global, commands)
+	buf.WriteString("\t\tcase \"$command:$word\" in\n")
+	buf.WriteString("\t\t\t(*:-*=*) ;;\n")
+	if len(valueFlags) > 0 {
+		fmt.Fprintf(buf, "\t\t\t(%s) skip=1 ;;\n", strings.Join(quoteAll(valueFlags, shQuote), "|"))
+	}
+	buf.WriteString("\t\t\t(*:-*) ;;\n")
+	buf.WriteString("\t\t\t(:*) command=\"$word\" ;;\n")
+	if len(groups) > 0 {
+		patterns := make([]string, len(groups))
+		for i, g := range groups {
+			patterns[i] = shQuote(g+":") + "*"
+		}
+		fmt.Fprintf(buf, "\t\t\t(%s) command=\"$command $word\" ;;\n", strings.Join(patterns, "|"))
+	}
+	buf.WriteString("\t\tesac\n")
+}
+
+// completionCandidates returns the candidates of the command name: its
+// flags and its commands, or the global flags and the top-level commands.
+func completionCandidates(name string, global []completionFlag, commands []completionCommand) ([]completionFlag, []completionCommand) {
+	var flags []completionFlag
+	if name == "" {
+		flags = global
+	}
+	var subcommands []completionCommand
+	for _, c := range commands {
+		if c.name == name {
+			flags = completionFlags(c.flags)
+		} else if c.group() == name {
+			subcommands = append(subcommands, c)
+		}
+	}
+	return flags, subcommands
+}
+
+// completionNames returns the names of the commands completed, "" being the
+// command of the global flags and the top-level commands.
+func completionNames(commands []completionCommand) []string {
+	names := []string{""}
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// subcommandName returns the name of c in its group.
+func subcommandName(c completionCommand) string {
+	return c.name[strings.LastIndex(c.name, " ")+1:]
+}
+
+func writeBashCompletion(w io.Writer, global []completionFlag, commands []completionCommand) error {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(`# bash completion for docker, generated by "docker completion bash"
+#
+# To load it in the current shell:
+#   source <(docker completion bash)
+
+_docker() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	local command= skip= word i
+
+	for (( i=1; i < COMP_CWORD; i++ )); do
+		word="${COMP_WORDS[i]}"
+		if [ -n "$skip" ]; then
+			skip=
+			continue
+		fi
+`)
+	writeCommandCase(buf, global, commands)
+	buf.WriteString(`	done
+	if [ -n "$skip" ]; then
+		return
+	fi
+
+	local words=
+	case "$command" in
+`)
+	for _, name := range completionNames(commands) {
+		flags, subcommands := completionCandidates(name, global, commands)
+		var words []string
+		for _, f := range flags {
+			words = append(words, f.names...)
+		}
+		for _, c := range subcommands {
+			words = append(words, subcommandName(c))
+		}
+		fmt.Fprintf(buf, "\t\t(%s) words=%s ;;\n", shQuote(name), shQuote(strings.Join(words, " ")))
+	}
+	buf.WriteString(`	esac
+	COMPREPLY=( $( compgen -W "$words" -- "$cur" ) )
+}
+
+complete -o default -F _docker docker
+`)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// zshQuote quotes s in single quotes.
+func zshQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func writeZshCompletion(w io.Writer, global []completionFlag, commands []completionCommand) error {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(`#compdef docker
+# zsh completion for docker, generated by "docker completion zsh"
+#
+# To load it in the current shell:
+#   source <(docker completion zsh)
+
+_docker() {
+	local command= skip= word i
+	local -a candidates
+
+	for (( i=2; i < CURRENT; i++ )); do
+		word="${words[i]}"
+		if [[ -n "$skip" ]]; then
+			skip=
+			continue
+		fi
+`)
+	writeCommandCase(buf, global, commands)
+	buf.WriteString(`	done
+	if [[ -n "$skip" ]]; then
+		_files
+		return
+	fi
+
+	case "$command" in
+`)
+	for _, name := range completionNames(commands) {
+		flags, subcommands := completionCandidates(name, global, commands)
+		var candidates []string
+		for _, c := range subcommands {
+			candidates = append(candidates, zshQuote(subcommandName(c)+":"+c.summary()))
+		}
+		for _, f := range flags {
+			for _, n := range f.names {
+				candidates = append(candidates, zshQuote(n+":"+f.usage))
+			}
+		}
+		fmt.Fprintf(buf, "\t\t(%s) candidates=(%s) ;;\n", shQuote(name), strings.Join(candidates, " "))
+	}
+	buf.WriteString(`	esac
+	_describe -t commands docker candidates || _files
+}
+
+if [[ "$funcstack[1]" = "_docker" ]]; then
+	_docker "$@"
+else
+	compdef _docker docker
+fi
+`)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// fishQuote quotes s in single quotes.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func writeFishCompletion(w io.Writer, global []completionFlag, commands []completionCommand) error {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(`# fish completion for docker, generated by "docker completion fish"
+#
+# To load it in the current shell:
+#   docker completion fish | source
+
+function __fish_docker_command --description 'Print the docker command being completed'
+	set -l words (commandline -opc)
+	set -e words[1]
+	set -l command
+	set -l skip
+	for word in $words
+		if test -n "$skip"
+			set skip
+			continue
+		end
+		switch "$command:$word"
+			case '*:-*=*'
+`)
+	valueFlags, groups := commandPatterns(global, commands)
+	if len(valueFlags) > 0 {
+		fmt.Fprintf(buf, "\t\t\tcase %s\n\t\t\t\tset skip 1\n", strings.Join(quoteAll(valueFlags, fishQuote), " "))
+	}
+	buf.WriteString("\t\t\tcase '*:-*'\n\t\t\tcase ':*'\n\t\t\t\tset command $word\n")
+	if len(groups) > 0 {
+		fmt.Fprintf(buf, "\t\t\tcase %s\n\t\t\t\tset command \"$command $word\"\n", strings.Join(quoteAll(groups, func(g string) string { return fishQuote(g + ":*") }), " "))
+	}
+	buf.WriteString(`		end
+	end
+	echo $command
+end
+
+function __fish_docker_using_command --description 'Test if the docker command being completed is the argument'
+	set -l command (__fish_docker_command)
+	test "$command" = "$argv"
+end
+
+`)
+	for _, name := range completionNames(commands) {
+		flags, subcommands := completionCandidates(name, global, commands)
+		cond := fishQuote("__fish_docker_using_command " + fishQuote(name))
+		for _, c := range subcommands {
+			fmt.Fprintf(buf, "complete -c docker -f -n %s -a %s -d %s\n", cond, subcommandName(c), fishQuote(c.summary()))
+		}
+		for _, f := range flags {
+			fmt.Fprintf(buf, "complete -c docker -n %s", cond)
+			for _, n := range f.names {
+				switch {
+				case strings.HasPrefix(n, "--"):
+					fmt.Fprintf(buf, " -l %s", n[2:])
+				case len(n) == 2:
+					fmt.Fprintf(buf, " -s %s", n[1:])
+				default:
+					fmt.Fprintf(buf, " -o %s", n[1:])
+				}
+			}
+			fmt.Fprintf(buf, " -d %s\n", fishQuote(f.usage))
+		}
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDescribeCommands(t *testing.T) {
+	cli := NewDockerCli(nil, new(bytes.Buffer), new(bytes.Buffer), "", "unix", "/var/run/docker.sock", nil)
+	commands := cli.describeCommands()
+
+	// Every command is described, but help which has no flags
+	names := make(map[string]completionCommand)
+	for _, c := range commands {
+		names[c.name] = c
+	}
+	typ := reflect.TypeOf(cli)
+	methods := 0
+	for i := 0; i < typ.NumMethod(); i++ {
+		if name := typ.Method(i).Name; strings.HasPrefix(name, "Cmd") && len(name) > len("Cmd") && name != "CmdHelp" {
+			methods++
+		}
+	}
+	if len(commands) != methods {
+		t.Fatalf("Expected %d commands, got %d", methods, len(commands))
+	}
+
+	ps, ok := names["ps"]
+	if !ok || ps.summary() != "List containers" {
+		t.Fatalf("Wrong ps command %+v", ps)
+	}
+	create, ok := names["network create"]
+	if !ok || create.group() != "network" || create.summary() != "Create a network" {
+		t.Fatalf("Wrong network create command %+v", create)
+	}
+	if network := names["network"]; network.summary() != "Manage networks" {
+		t.Fatalf("Wrong network command summary %q", network.summary())
+	}
+
+	var driver *completionFlag
+	flags := completionFlags(create.flags)
+	for i, f := range flags {
+		if f.usage == "Driver to manage the network" {
+			driver = &flags[i]
+		}
+	}
+	if driver == nil || !driver.hasValue || !reflect.DeepEqual(driver.names, []string{"-d", "--driver"}) {
+		t.Fatalf("Wrong --driver flag %+v", driver)
+	}
+	if cli.described != nil {
+		t.Fatal("The client is still describing commands")
+	}
+}
+
+// readCloserFunc is an io.ReadCloser reading with its function.
+type readCloserFunc func(p []byte) (int, error)
+
+func (f readCloserFunc) Read(p []byte) (int, error) { return f(p) }
+func (f readCloserFunc) Close() error               { return nil }
+
+func TestDescribeCommandsWithoutSideEffects(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+	}))
+	defer server.Close()
+	read := false
+	in := readCloserFunc(func(p []byte) (int, error) {
+		read = true
+		return 0, io.EOF
+	})
+	var out, errOut bytes.Buffer
+	cli := NewDockerCli(in, &out, &errOut, "", "tcp", strings.TrimPrefix(server.URL, "http://"), nil)
+
+	// Every command is described by its flags, without being run
+	typ := reflect.TypeOf(cli)
+	for i := 0; i < typ.NumMethod(); i++ {
+		name := typ.Method(i).Name
+		if !strings.HasPrefix(name, "Cmd") || len(name) == len("Cmd") || name == "CmdHelp" {
+			continue
+		}
+		method := reflect.ValueOf(cli).Method(i).Interface().(func(...string) error)
+		if c := cli.describeCommand(method); c == nil {
+			t.Errorf("%s doesn't define its flags before running", name)
+		}
+	}
+	if len(requests) > 0 || read || out.Len() > 0 || errOut.Len() > 0 {
+		t.Fatalf("Expected the commands not to run, got the requests %v, the input read: %v and the output %q %q", requests, read, out.String(), errOut.String())
+	}
+
+	// The commands can't reach the daemon while they are described
+	cli.described = &completionCommand{}
+	defer func() { cli.described = nil }()
+	if _, _, err := cli.call("GET", "/info", nil, nil); err != errDescribing {
+		t.Fatalf("Expected %v, got %v", errDescribing, err)
+	}
+	if _, err := cli.dial(); err != errDescribing {
+		t.Fatalf("Expected %v, got %v", errDescribing, err)
+	}
+	if len(requests) > 0 {
+		t.Fatalf("Expected no request to the daemon, got %v", requests)
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	cli := NewDockerCli(nil, new(bytes.Buffer), new(bytes.Buffer), "", "unix", "/var/run/docker.sock", nil)
+	commands := cli.describeCommands()
+	global := []completionFlag{{names: []string{"-H", "--host"}, usage: "Daemon socket to connect to", hasValue: true}}
+
+	scripts := map[string]struct {
+		write    func(*bytes.Buffer) error
+		expected []string
+	}{
+		"bash": {
+			func(b *bytes.Buffer) error { return writeBashCompletion(b, global, commands) },
+			[]string{`(":-H"|":--host"|`, `("network:"*|"secret:"*|"system:"*|"volume:"*) command="$command $word"`, `("events") words="-f --filter --format --help -q --quiet --since --until"`},
+		},
+		"zsh": {
+			func(b *bytes.Buffer) error { return writeZshCompletion(b, global, commands) },
+			[]string{`("") candidates=('attach:Attach to a running container'`, `'--host:Daemon socket to connect to'`},
+		},
+		"fish": {
+			func(b *bytes.Buffer) error { return writeFishCompletion(b, global, commands) },
+			[]string{`case ':-H' ':--host'`, `complete -c docker -n '__fish_docker_using_command \'network create\'' -s d -l driver -d 'Driver to manage the network'`},
+		},
+	}
+	for shell, s := range scripts {
+		var b bytes.Buffer
+		if err := s.write(&b); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range s.expected {
+			if !strings.Contains(b.String(), e) {
+				t.Fatalf("%s: expected %q in the script:\n%s", shell, e, b.String())
+			}
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package client
 
 import (
+	"encoding/json"
+	"io"
 	"net/url"
+	"text/template"
 
 	"github.com/docker/docker/opts"
+	"github.com/docker/docker/pkg/jsonmessage"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers/filters"
 	"github.com/docker/docker/pkg/timeutils"
@@ -16,12 +20,26 @@ func (cli *DockerCli) CmdEvents(args ...string) error {
 	cmd := cli.Subcmd("events", "", "Get real time events from the server", true)
 	since := cmd.String([]string{"#since", "-since"}, "", "Show all events created since timestamp")
 	until := cmd.String([]string{"-until"}, "", "Stream events until this timestamp")
+	format := cmd.String([]string{"-format"}, "", "Format the output using the given go template")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display the IDs of the events")
 	flFilter := opts.NewListOpts(nil)
 	cmd.Var(&flFilter, []string{"f", "-filter"}, "Filter output based on conditions provided")
 	cmd.Require(flag.Exact, 0)
 
 	cmd.ParseFlags(args, true)
 
+	if *quiet {
+		*format = "{{.ID}}"
+	}
+
+	var tmpl *template.Template
+	if *format != "" {
+		var err error
+		if tmpl, err = parseFormat(*format); err != nil {
+			return err
+		}
+	}
+
 	var (
 		v               = url.Values{}
 		eventFilterArgs = filters.Args{}
@@ -49,6 +67,14 @@ func (cli *DockerCli) CmdEvents(args ...string) error {
 		}
 		v.Set("filters", filterJSON)
 	}
+	if tmpl != nil {
+		body, _, _, err := cli.clientRequest("GET", "/events?"+v.Encode(), nil, nil)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		return formatEvents(body, cli.out, tmpl)
+	}
 	sopts := &streamOpts{
 		rawTerminal: true,
 		out:         cli.out,
@@ -58,3 +84,25 @@ func (cli *DockerCli) CmdEvents(args ...string) error {
 	}
 	return nil
 }
+
+// formatEvents writes a line per event of the stream in, the template
+// executed against it.
+func formatEvents(in io.Reader, out io.Writer, tmpl *template.Template) error {
+	dec := json.NewDecoder(in)
+	for {
+		var event jsonmessage.JSONMessage
+		if err := dec.Decode(&event); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if event.Error != nil {
+			return event.Error
+		}
+		if err := tmpl.Execute(out, &event); err != nil {
+			return err
+		}
+		out.Write([]byte{'\n'})
+	}
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFormatEvents(t *testing.T) {
+	events := `{"status":"create","id":"4386fb97867d","from":"ubuntu:14.04","time":1435167353}
+{"status":"start","id":"4386fb97867d","from":"ubuntu:14.04","time":1435167354}
+`
+	formats := map[string]string{
+		"{{.Status}} {{.ID}}": "create 4386fb97867d\nstart 4386fb97867d\n",
+		"{{.ID}}":             "4386fb97867d\n4386fb97867d\n",
+		"{{json .}}":          events,
+	}
+	for format, expected := range formats {
+		tmpl, err := parseFormat(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := formatEvents(strings.NewReader(events), &b, tmpl); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != expected {
+			t.Fatalf("%s: expected %q, got %q", format, expected, b.String())
+		}
+	}
+}
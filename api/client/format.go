@@ -0,0 +1,212 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/pkg/stringutils"
+	"github.com/docker/docker/pkg/units"
+)
+
+// funcMap holds the functions available in the templates of --format.
+var funcMap = template.FuncMap{
+	"json": func(v interface{}) string {
+		a, _ := json.Marshal(v)
+		return string(a)
+	},
+	"join":     strings.Join,
+	"split":    strings.Split,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"title":    strings.Title,
+	"truncate": stringutils.Truncate,
+}
+
+// parseFormat parses the template of a --format flag.
+func parseFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("").Funcs(funcMap).Parse(format)
+	if err != nil {
+		return nil, StatusError{StatusCode: 64,
+			Status: "Template parsing error: " + err.Error()}
+	}
+	return tmpl, nil
+}
+
+// formatLabels returns the labels as sorted, comma-separated key=value pairs.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// formatReclaimable returns the space reclaimable from the disk usage s,
+// with its percentage of the space used.
+func formatReclaimable(s types.DiskUsageSummary) string {
+	reclaimable := units.HumanSize(float64(s.Reclaimable))
+	if s.Size > 0 {
+		reclaimable += fmt.Sprintf(" (%d%%)", s.Reclaimable*100/s.Size)
+	}
+	return reclaimable
+}
+
+// infoContext is the object the template of docker info --format is
+// executed against: the information of the daemon, with the warnings the
+// command prints.
+type infoContext struct {
+	*types.Info
+	Warnings []string
+}
+
+// containerContext is the object the template of docker ps --format is
+// executed against, its methods return the columns of the table.
+type containerContext struct {
+	trunc bool
+	c     types.Container
+}
+
+// ID returns the ID of the container.
+func (ctx *containerContext) ID() string {
+	if ctx.trunc {
+		return stringid.TruncateID(ctx.c.ID)
+	}
+	return ctx.c.ID
+}
+
+// Names returns the comma-separated names of the container, only its default
+// name when truncated.
+func (ctx *containerContext) Names() string {
+	names := make([]string, 0, len(ctx.c.Names))
+	for _, name := range ctx.c.Names {
+		name = strings.TrimPrefix(name, "/")
+		if ctx.trunc && !strings.Contains(name, "/") {
+			return name
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+// Image returns the image of the container.
+func (ctx *containerContext) Image() string {
+	if ctx.c.Image == "" {
+		return "<no image>"
+	}
+	return ctx.c.Image
+}
+
+// Command returns the quoted command of the container.
+func (ctx *containerContext) Command() string {
+	command := strconv.Quote(ctx.c.Command)
+	if ctx.trunc {
+		command = stringutils.Truncate(command, 20)
+	}
+	return command
+}
+
+// CreatedAt returns the time the container was created at.
+func (ctx *containerContext) CreatedAt() string {
+	return time.Unix(int64(ctx.c.Created), 0).String()
+}
+
+// RunningFor returns the time elapsed since the container was created.
+func (ctx *containerContext) RunningFor() string {
+	return units.HumanDuration(time.Now().UTC().Sub(time.Unix(int64(ctx.c.Created), 0)))
+}
+
+// Ports returns the exposed and published ports of the container.
+func (ctx *containerContext) Ports() string {
+	return api.DisplayablePorts(ctx.c.Ports)
+}
+
+// Status returns the status of the container.
+func (ctx *containerContext) Status() string {
+	return ctx.c.Status
+}
+
+// Size returns the size of the container, with its virtual size.
+func (ctx *containerContext) Size() string {
+	size := units.HumanSize(float64(ctx.c.SizeRw))
+	if ctx.c.SizeRootFs > 0 {
+		size = fmt.Sprintf("%s (virtual %s)", size, units.HumanSize(float64(ctx.c.SizeRootFs)))
+	}
+	return size
+}
+
+// Labels returns the labels of the container.
+func (ctx *containerContext) Labels() string {
+	return formatLabels(ctx.c.Labels)
+}
+
+// Label returns the value of the label name of the container.
+func (ctx *containerContext) Label(name string) string {
+	return ctx.c.Labels[name]
+}
+
+// imageContext is the object the template of docker images --format is
+// executed against, for each repository and tag or digest of an image.
+type imageContext struct {
+	trunc  bool
+	i      types.Image
+	repo   string
+	tag    string
+	digest string
+}
+
+// ID returns the ID of the image.
+func (ctx *imageContext) ID() string {
+	if ctx.trunc {
+		return stringid.TruncateID(ctx.i.ID)
+	}
+	return ctx.i.ID
+}
+
+// Repository returns the repository of the image.
+func (ctx *imageContext) Repository() string {
+	return ctx.repo
+}
+
+// Tag returns the tag of the image.
+func (ctx *imageContext) Tag() string {
+	return ctx.tag
+}
+
+// Digest returns the digest of the image.
+func (ctx *imageContext) Digest() string {
+	return ctx.digest
+}
+
+// CreatedAt returns the time the image was created at.
+func (ctx *imageContext) CreatedAt() string {
+	return time.Unix(int64(ctx.i.Created), 0).String()
+}
+
+// CreatedSince returns the time elapsed since the image was created.
+func (ctx *imageContext) CreatedSince() string {
+	return units.HumanDuration(time.Now().UTC().Sub(time.Unix(int64(ctx.i.Created), 0)))
+}
+
+// Size returns the virtual size of the image.
+func (ctx *imageContext) Size() string {
+	return units.HumanSize(float64(ctx.i.VirtualSize))
+}
+
+// Labels returns the labels of the image.
+func (ctx *imageContext) Labels() string {
+	return formatLabels(ctx.i.Labels)
+}
+
+// Label returns the value of the label name of the image.
+func (ctx *imageContext) Label(name string) string {
+	return ctx.i.Labels[name]
+}
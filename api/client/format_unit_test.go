@@ -0,0 +1,116 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestContainerContext(t *testing.T) {
+	c := types.Container{
+		ID:      "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657",
+		Names:   []string{"/web/db", "/db"},
+		Command: "postgres --data-checksums",
+		Ports:   []types.Port{{IP: "0.0.0.0", PrivatePort: 5432, PublicPort: 5432, Type: "tcp"}},
+		Labels:  map[string]string{"tier": "db", "com.example.team": "data"},
+		SizeRw:  1000,
+	}
+	contexts := []struct {
+		ctx      *containerContext
+		format   string
+		expected string
+	}{
+		{&containerContext{trunc: true, c: c}, "{{.ID}} {{.Names}} {{.Image}}", "a7317399f3f8 db <no image>"},
+		{&containerContext{c: c}, "{{.ID}} {{.Names}}", c.ID + " web/db,db"},
+		{&containerContext{trunc: true, c: c}, "{{.Command}}", `"postgres --data-che`},
+		{&containerContext{c: c}, "{{.Command}}", `"postgres --data-checksums"`},
+		{&containerContext{c: c}, "{{.Ports}} {{.Size}}", "0.0.0.0:5432->5432/tcp 1 kB"},
+		{&containerContext{c: c}, `{{.Labels}} {{.Label "tier"}}`, "com.example.team=data,tier=db db"},
+		{&containerContext{c: c}, `{{join (split .Names ",") " "}} {{upper (.Label "tier")}}`, "web/db db DB"},
+	}
+	for _, tt := range contexts {
+		tmpl, err := parseFormat(tt.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, tt.ctx); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.format, tt.expected, b.String())
+		}
+	}
+}
+
+func TestImageContext(t *testing.T) {
+	ctx := &imageContext{
+		trunc: true,
+		i: types.Image{
+			ID:          "ae6f1d35b6b5e1b8c2a3ba6f24bac2fbd1b4ad3d3b0a3d0ec4b1d7e4d8b1ec59",
+			VirtualSize: 2000000,
+			Labels:      map[string]string{"version": "1.0"},
+		},
+		repo:   "example/app",
+		tag:    "latest",
+		digest: "<none>",
+	}
+	tmpl, err := parseFormat(`{{.Repository}}:{{.Tag}} {{.ID}} {{.Digest}} {{.Size}} {{.Label "version"}} {{json .Labels}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `example/app:latest ae6f1d35b6b5 <none> 2 MB 1.0 "version=1.0"`; b.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestParseFormatError(t *testing.T) {
+	if _, err := parseFormat("{{.ID"); err == nil {
+		t.Fatal("Expected an error for an invalid template")
+	} else if e, ok := err.(StatusError); !ok || e.StatusCode != 64 {
+		t.Fatalf("Expected a status error with code 64, got %v", err)
+	}
+}
+
+func TestInfoContext(t *testing.T) {
+	info := &types.Info{Containers: 3, Driver: "aufs", DriverStatus: [][2]string{{"Dirs", "12"}}, MemoryLimit: true, IPv4Forwarding: true}
+	ctx := &infoContext{Info: info, Warnings: infoWarnings(info)}
+	formats := map[string]string{
+		"{{.Containers}} {{.Driver}}":                               "3 aufs",
+		"{{range .DriverStatus}}{{index . 0}}={{index . 1}}{{end}}": "Dirs=12",
+		"{{json .Warnings}}":                                        `["No swap limit support"]`,
+	}
+	for format, expected := range formats {
+		tmpl, err := parseFormat(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, ctx); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != expected {
+			t.Fatalf("%s: expected %q, got %q", format, expected, b.String())
+		}
+	}
+}
+
+func TestFormatReclaimable(t *testing.T) {
+	for _, c := range []struct {
+		summary  types.DiskUsageSummary
+		expected string
+	}{
+		{types.DiskUsageSummary{Size: 2000, Reclaimable: 1500}, "1.5 kB (75%)"},
+		{types.DiskUsageSummary{Size: 300, Reclaimable: 0}, "0 B (0%)"},
+		{types.DiskUsageSummary{}, "0 B"},
+	} {
+		if s := formatReclaimable(c.summary); s != c.expected {
+			t.Fatalf("Expected %q, got %q", c.expected, s)
+		}
+	}
+}
@@ -1,13 +1,16 @@
 package client
 
 import (
+	"bufio"
 	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
 	"os"
 	"runtime"
 	"strings"
@@ -37,16 +40,13 @@ func (c *tlsClientCon) CloseWrite() error {
 	return nil
 }
 
-func tlsDial(network, addr string, config *tls.Config) (net.Conn, error) {
-	return tlsDialWithDialer(new(net.Dialer), network, addr, config)
-}
-
 // We need to copy Go's implementation of tls.Dial (pkg/cryptor/tls/tls.go) in
 // order to return our custom tlsClientCon struct which holds both the tls.Conn
 // object _and_ its underlying raw connection. The rationale for this is that
 // we need to be able to close the write end of the connection when attaching,
-// which tls.Conn does not provide.
-func tlsDialWithDialer(dialer *net.Dialer, network, addr string, config *tls.Config) (net.Conn, error) {
+// which tls.Conn does not provide. The raw connection is made by dial, which
+// may go through a proxy; the timeouts are the ones of dialer.
+func tlsDialWithDialer(dialer *net.Dialer, dial func(network, addr string) (net.Conn, error), network, addr string, config *tls.Config) (net.Conn, error) {
 	// We want the Timeout and Deadline values from dialer to cover the
 	// whole process: TCP connection and TLS handshake. This means that we
 	// also need to start our own timers now.
@@ -68,7 +68,7 @@ func tlsDialWithDialer(dialer *net.Dialer, network, addr string, config *tls.Con
 		})
 	}
 
-	rawConn, err := dialer.Dial(network, addr)
+	rawConn, err := dial(network, addr)
 	if err != nil {
 		return nil, err
 	}
@@ -119,12 +119,70 @@ func tlsDialWithDialer(dialer *net.Dialer, network, addr string, config *tls.Con
 	return &tlsClientCon{conn, rawConn}, nil
 }
 
+// dial connects to the daemon for a hijacked request, through the proxy the
+// transport uses for its address, if any.
 func (cli *DockerCli) dial() (net.Conn, error) {
+	if cli.described != nil {
+		return nil, errDescribing
+	}
+	dialer := &net.Dialer{Timeout: cli.timeout}
+	dial := dialer.Dial
+	if cli.transport.Proxy != nil {
+		proxyURL, err := cli.transport.Proxy(&http.Request{URL: &url.URL{Scheme: cli.scheme, Host: cli.addr}})
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL != nil {
+			dial = func(network, addr string) (net.Conn, error) {
+				return dialProxy(dialer, proxyURL, addr)
+			}
+		}
+	}
 	if cli.tlsConfig != nil && cli.proto != "unix" {
 		// Notice this isn't Go standard's tls.Dial function
-		return tlsDial(cli.proto, cli.addr, cli.tlsConfig)
+		return tlsDialWithDialer(dialer, dial, cli.proto, cli.addr, cli.tlsConfig)
+	}
+	return dial(cli.proto, cli.addr)
+}
+
+// dialProxy opens a tunnel to addr through the HTTP proxy proxyURL, with a
+// CONNECT request.
+func dialProxy(dialer *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if _, _, err := net.SplitHostPort(proxyAddr); err != nil {
+		proxyAddr = net.JoinHostPort(proxyAddr, "80")
+	}
+	conn, err := dialer.Dial("tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("Proxy %s failed to connect to %s: %s", proxyURL.Host, addr, resp.Status)
 	}
-	return net.Dial(cli.proto, cli.addr)
+	return conn, nil
 }
 
 func (cli *DockerCli) hijack(method, path string, setRawTerminal bool, in io.ReadCloser, stdout, stderr io.Writer, started chan io.Closer, data interface{}) error {
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestDialProxy(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	requests := make(chan *http.Request, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rdr := bufio.NewReader(conn)
+		req, err := http.ReadRequest(rdr)
+		if err != nil {
+			return
+		}
+		requests <- req
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		// Echo the tunnelled bytes
+		line, _ := rdr.ReadString('\n')
+		conn.Write([]byte(line))
+	}()
+
+	proxyURL := &url.URL{Scheme: "http", Host: l.Addr().String(), User: url.UserPassword("user", "secret")}
+	conn, err := dialProxy(&net.Dialer{}, proxyURL, "10.0.0.5:2376")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	req := <-requests
+	if req.Method != "CONNECT" || req.Host != "10.0.0.5:2376" {
+		t.Fatalf("Unexpected request %s %s", req.Method, req.Host)
+	}
+	if auth := req.Header.Get("Proxy-Authorization"); auth != "Basic dXNlcjpzZWNyZXQ=" {
+		t.Fatalf("Unexpected Proxy-Authorization %q", auth)
+	}
+
+	conn.Write([]byte("ping\n"))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "ping\n" {
+		t.Fatalf("Expected the tunnel to echo ping, got %q (%v)", line, err)
+	}
+}
+
+func TestDialProxyRefused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		http.ReadRequest(bufio.NewReader(conn))
+		conn.Write([]byte("HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\n\r\n"))
+	}()
+
+	proxyURL := &url.URL{Scheme: "http", Host: l.Addr().String()}
+	if _, err := dialProxy(&net.Dialer{}, proxyURL, "10.0.0.5:2376"); err == nil {
+		t.Fatal("Expected an error when the proxy refuses the tunnel")
+	}
+}
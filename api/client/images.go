@@ -5,15 +5,13 @@ import (
 	"fmt"
 	"net/url"
 	"text/tabwriter"
-	"time"
+	"text/template"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/opts"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/parsers/filters"
-	"github.com/docker/docker/pkg/stringid"
-	"github.com/docker/docker/pkg/units"
 	"github.com/docker/docker/utils"
 )
 
@@ -26,6 +24,7 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 	all := cmd.Bool([]string{"a", "-all"}, false, "Show all images (default hides intermediate images)")
 	noTrunc := cmd.Bool([]string{"#notrunc", "-no-trunc"}, false, "Don't truncate output")
 	showDigests := cmd.Bool([]string{"-digests"}, false, "Show digests")
+	format := cmd.String([]string{"-format"}, "", "Pretty-print images using a Go template")
 
 	flFilter := opts.NewListOpts(nil)
 	cmd.Var(&flFilter, []string{"f", "-filter"}, "Filter output based on conditions provided")
@@ -43,6 +42,18 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 		}
 	}
 
+	if *format == "" && !*quiet {
+		*format = cli.configFile.ImagesFormat
+	}
+
+	var tmpl *template.Template
+	if *format != "" && !*quiet {
+		var err error
+		if tmpl, err = parseFormat(*format); err != nil {
+			return err
+		}
+	}
+
 	matchName := cmd.Arg(0)
 	v := url.Values{}
 	if len(imageFilterArgs) > 0 {
@@ -72,7 +83,7 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 	}
 
 	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
-	if !*quiet {
+	if !*quiet && tmpl == nil {
 		if *showDigests {
 			fmt.Fprintln(w, "REPOSITORY\tTAG\tDIGEST\tIMAGE ID\tCREATED\tVIRTUAL SIZE")
 		} else {
@@ -81,11 +92,6 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 	}
 
 	for _, image := range images {
-		ID := image.ID
-		if !*noTrunc {
-			ID = stringid.TruncateID(ID)
-		}
-
 		repoTags := image.RepoTags
 		repoDigests := image.RepoDigests
 
@@ -97,29 +103,35 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 		// combine the tags and digests lists
 		tagsAndDigests := append(repoTags, repoDigests...)
 		for _, repoAndRef := range tagsAndDigests {
-			repo, ref := parsers.ParseRepositoryTag(repoAndRef)
+			ctx := &imageContext{trunc: !*noTrunc, i: image}
+			var ref string
+			ctx.repo, ref = parsers.ParseRepositoryTag(repoAndRef)
 			// default tag and digest to none - if there's a value, it'll be set below
-			tag := "<none>"
-			digest := "<none>"
+			ctx.tag = "<none>"
+			ctx.digest = "<none>"
 			if utils.DigestReference(ref) {
-				digest = ref
+				ctx.digest = ref
 			} else {
-				tag = ref
+				ctx.tag = ref
 			}
 
-			if !*quiet {
-				if *showDigests {
-					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s ago\t%s\n", repo, tag, digest, ID, units.HumanDuration(time.Now().UTC().Sub(time.Unix(int64(image.Created), 0))), units.HumanSize(float64(image.VirtualSize)))
-				} else {
-					fmt.Fprintf(w, "%s\t%s\t%s\t%s ago\t%s\n", repo, tag, ID, units.HumanDuration(time.Now().UTC().Sub(time.Unix(int64(image.Created), 0))), units.HumanSize(float64(image.VirtualSize)))
+			switch {
+			case *quiet:
+				fmt.Fprintln(w, ctx.ID())
+			case tmpl != nil:
+				if err := tmpl.Execute(cli.out, ctx); err != nil {
+					return err
 				}
-			} else {
-				fmt.Fprintln(w, ID)
+				cli.out.Write([]byte{'\n'})
+			case *showDigests:
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s ago\t%s\n", ctx.repo, ctx.tag, ctx.digest, ctx.ID(), ctx.CreatedSince(), ctx.Size())
+			default:
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s ago\t%s\n", ctx.repo, ctx.tag, ctx.ID(), ctx.CreatedSince(), ctx.Size())
 			}
 		}
 	}
 
-	if !*quiet {
+	if !*quiet && tmpl == nil {
 		w.Flush()
 	}
 	return nil
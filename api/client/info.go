@@ -3,6 +3,7 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"text/template"
 
 	"github.com/docker/docker/api/types"
 	flag "github.com/docker/docker/pkg/mflag"
@@ -14,9 +15,18 @@ import (
 // Usage: docker info
 func (cli *DockerCli) CmdInfo(args ...string) error {
 	cmd := cli.Subcmd("info", "", "Display system-wide information", true)
+	format := cmd.String([]string{"-format"}, "", "Format the output using the given go template")
 	cmd.Require(flag.Exact, 0)
 	cmd.ParseFlags(args, false)
 
+	var tmpl *template.Template
+	if *format != "" {
+		var err error
+		if tmpl, err = parseFormat(*format); err != nil {
+			return err
+		}
+	}
+
 	rdr, _, err := cli.call("GET", "/info", nil, nil)
 	if err != nil {
 		return err
@@ -27,6 +37,14 @@ func (cli *DockerCli) CmdInfo(args ...string) error {
 		return fmt.Errorf("Error reading remote info: %v", err)
 	}
 
+	if tmpl != nil {
+		if err := tmpl.Execute(cli.out, &infoContext{Info: info, Warnings: infoWarnings(info)}); err != nil {
+			return err
+		}
+		cli.out.Write([]byte{'\n'})
+		return nil
+	}
+
 	fmt.Fprintf(cli.out, "Containers: %d\n", info.Containers)
 	fmt.Fprintf(cli.out, "Images: %d\n", info.Images)
 	fmt.Fprintf(cli.out, "Storage Driver: %s\n", info.Driver)
@@ -72,14 +90,8 @@ func (cli *DockerCli) CmdInfo(args ...string) error {
 			fmt.Fprintf(cli.out, "Registry: %v\n", info.IndexServerAddress)
 		}
 	}
-	if !info.MemoryLimit {
-		fmt.Fprintf(cli.err, "WARNING: No memory limit support\n")
-	}
-	if !info.SwapLimit {
-		fmt.Fprintf(cli.err, "WARNING: No swap limit support\n")
-	}
-	if !info.IPv4Forwarding {
-		fmt.Fprintf(cli.err, "WARNING: IPv4 forwarding is disabled.\n")
+	for _, warning := range infoWarnings(info) {
+		fmt.Fprintf(cli.err, "WARNING: %s\n", warning)
 	}
 	if info.Labels != nil {
 		fmt.Fprintln(cli.out, "Labels:")
@@ -90,3 +102,18 @@ func (cli *DockerCli) CmdInfo(args ...string) error {
 
 	return nil
 }
+
+// infoWarnings returns the warnings about the features the daemon lacks.
+func infoWarnings(info *types.Info) []string {
+	var warnings []string
+	if !info.MemoryLimit {
+		warnings = append(warnings, "No memory limit support")
+	}
+	if !info.SwapLimit {
+		warnings = append(warnings, "No swap limit support")
+	}
+	if !info.IPv4Forwarding {
+		warnings = append(warnings, "IPv4 forwarding is disabled.")
+	}
+	return warnings
+}
@@ -18,38 +18,48 @@ import (
 func (cli *DockerCli) CmdInspect(args ...string) error {
 	cmd := cli.Subcmd("inspect", "CONTAINER|IMAGE [CONTAINER|IMAGE...]", "Return low-level information on a container or image", true)
 	tmplStr := cmd.String([]string{"f", "#format", "-format"}, "", "Format the output using the given go template")
+	inspectType := cmd.String([]string{"-type"}, "", "Return JSON for specified type, (e.g image, container, volume or network)")
+	size := cmd.Bool([]string{"s", "-size"}, false, "Display total file sizes if the type is container")
 	cmd.Require(flag.Min, 1)
 
 	cmd.ParseFlags(args, true)
 
+	switch *inspectType {
+	case "", "container":
+	case "image", "volume", "network":
+		if *size {
+			return fmt.Errorf("--size is only supported for containers")
+		}
+	default:
+		return fmt.Errorf("%q is not a valid value for --type", *inspectType)
+	}
+
 	var tmpl *template.Template
 	if *tmplStr != "" {
 		var err error
-		if tmpl, err = template.New("").Funcs(funcMap).Parse(*tmplStr); err != nil {
-			return StatusError{StatusCode: 64,
-				Status: "Template parsing error: " + err.Error()}
+		if tmpl, err = parseFormat(*tmplStr); err != nil {
+			return err
 		}
 	}
 
 	indented := new(bytes.Buffer)
 	indented.WriteString("[\n")
 	status := 0
-	isImage := false
 
 	for _, name := range cmd.Args() {
-		obj, _, err := readBody(cli.call("GET", "/containers/"+name+"/json", nil, nil))
+		obj, typed, err := cli.inspectObject(name, *inspectType, *size)
 		if err != nil {
-			obj, _, err = readBody(cli.call("GET", "/images/"+name+"/json", nil, nil))
-			isImage = true
-			if err != nil {
-				if strings.Contains(err.Error(), "No such") {
+			if strings.Contains(err.Error(), "No such") {
+				if *inspectType == "" {
 					fmt.Fprintf(cli.err, "Error: No such image or container: %s\n", name)
 				} else {
-					fmt.Fprintf(cli.err, "%s", err)
+					fmt.Fprintf(cli.err, "Error: No such %s: %s\n", *inspectType, name)
 				}
-				status = 1
-				continue
+			} else {
+				fmt.Fprintf(cli.err, "%s", err)
 			}
+			status = 1
+			continue
 		}
 
 		if tmpl == nil {
@@ -59,42 +69,18 @@ func (cli *DockerCli) CmdInspect(args ...string) error {
 				continue
 			}
 		} else {
-			rdr := bytes.NewReader(obj)
-			dec := json.NewDecoder(rdr)
-
-			if isImage {
-				inspPtr := types.ImageInspect{}
-				if err := dec.Decode(&inspPtr); err != nil {
-					fmt.Fprintf(cli.err, "%s\n", err)
-					status = 1
-					continue
-				}
-				if err := tmpl.Execute(cli.out, inspPtr); err != nil {
-					rdr.Seek(0, 0)
-					var raw interface{}
-					if err := dec.Decode(&raw); err != nil {
-						return err
-					}
-					if err = tmpl.Execute(cli.out, raw); err != nil {
-						return err
-					}
-				}
-			} else {
-				inspPtr := types.ContainerJSON{}
-				if err := dec.Decode(&inspPtr); err != nil {
-					fmt.Fprintf(cli.err, "%s\n", err)
-					status = 1
-					continue
+			if err := json.Unmarshal(obj, typed); err != nil {
+				fmt.Fprintf(cli.err, "%s\n", err)
+				status = 1
+				continue
+			}
+			if err := tmpl.Execute(cli.out, typed); err != nil {
+				var raw interface{}
+				if err := json.Unmarshal(obj, &raw); err != nil {
+					return err
 				}
-				if err := tmpl.Execute(cli.out, inspPtr); err != nil {
-					rdr.Seek(0, 0)
-					var raw interface{}
-					if err := dec.Decode(&raw); err != nil {
-						return err
-					}
-					if err = tmpl.Execute(cli.out, raw); err != nil {
-						return err
-					}
+				if err = tmpl.Execute(cli.out, raw); err != nil {
+					return err
 				}
 			}
 			cli.out.Write([]byte{'\n'})
@@ -119,3 +105,33 @@ func (cli *DockerCli) CmdInspect(args ...string) error {
 	}
 	return nil
 }
+
+// inspectObject returns the JSON of the object name of the given type, a
+// container or else an image when the type is empty, and a pointer to the
+// type the JSON decodes to.
+func (cli *DockerCli) inspectObject(name, inspectType string, size bool) ([]byte, interface{}, error) {
+	var (
+		obj []byte
+		err error
+	)
+	if inspectType == "" || inspectType == "container" {
+		path := "/containers/" + name + "/json"
+		if size {
+			path += "?size=1"
+		}
+		if obj, _, err = readBody(cli.call("GET", path, nil, nil)); err == nil || inspectType != "" {
+			return obj, &types.ContainerJSON{}, err
+		}
+	}
+	switch inspectType {
+	case "volume":
+		obj, _, err = readBody(cli.call("GET", "/volumes/"+name+"/json", nil, nil))
+		return obj, &types.Volume{}, err
+	case "network":
+		obj, _, err = readBody(cli.call("GET", "/networks/"+name+"/json", nil, nil))
+		return obj, &types.NetworkResource{}, err
+	default:
+		obj, _, err = readBody(cli.call("GET", "/images/"+name+"/json", nil, nil))
+		return obj, &types.ImageInspect{}, err
+	}
+}
@@ -15,11 +15,12 @@ import (
 // docker logs [OPTIONS] CONTAINER
 func (cli *DockerCli) CmdLogs(args ...string) error {
 	var (
-		cmd    = cli.Subcmd("logs", "CONTAINER", "Fetch the logs of a container", true)
-		follow = cmd.Bool([]string{"f", "-follow"}, false, "Follow log output")
-		since  = cmd.String([]string{"-since"}, "", "Show logs since timestamp")
-		times  = cmd.Bool([]string{"t", "-timestamps"}, false, "Show timestamps")
-		tail   = cmd.String([]string{"-tail"}, "all", "Number of lines to show from the end of the logs")
+		cmd     = cli.Subcmd("logs", "CONTAINER", "Fetch the logs of a container", true)
+		follow  = cmd.Bool([]string{"f", "-follow"}, false, "Follow log output")
+		since   = cmd.String([]string{"-since"}, "", "Show logs since timestamp")
+		times   = cmd.Bool([]string{"t", "-timestamps"}, false, "Show timestamps")
+		details = cmd.Bool([]string{"-details"}, false, "Show the attributes of the log lines")
+		tail    = cmd.String([]string{"-tail"}, "all", "Number of lines to show from the end of the logs")
 	)
 	cmd.Require(flag.Exact, 1)
 
@@ -37,8 +38,10 @@ func (cli *DockerCli) CmdLogs(args ...string) error {
 		return err
 	}
 
-	if logType := c.HostConfig.LogConfig.Type; logType != "json-file" {
-		return fmt.Errorf("\"logs\" command is supported only for \"json-file\" logging driver (got: %s)", logType)
+	// The daemon reads back the logs of the other drivers from the local
+	// cache of their logs, unless it is disabled
+	if c.HostConfig.LogConfig.Type == "none" {
+		return fmt.Errorf("\"logs\" command is not supported when logging is disabled")
 	}
 
 	v := url.Values{}
@@ -53,6 +56,10 @@ func (cli *DockerCli) CmdLogs(args ...string) error {
 		v.Set("timestamps", "1")
 	}
 
+	if *details {
+		v.Set("details", "1")
+	}
+
 	if *follow {
 		v.Set("follow", "1")
 	}
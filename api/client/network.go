@@ -0,0 +1,224 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/opts"
+	flag "github.com/docker/docker/pkg/mflag"
+	"github.com/docker/docker/pkg/stringid"
+)
+
+// CmdNetwork is the parent subcommand for all network commands.
+//
+// Usage: docker network <COMMAND> [OPTIONS]
+func (cli *DockerCli) CmdNetwork(args ...string) error {
+	cmd := cli.Subcmd("network", "COMMAND [OPTIONS]", "Manage networks\n\n"+networkUsage(), false)
+	cmd.Require(flag.Min, 1)
+	err := cmd.ParseFlags(args, true)
+	cmd.Usage()
+	return err
+}
+
+// CmdNetworkCreate creates a new network with a given name.
+//
+// Usage: docker network create [OPTIONS] NETWORK-NAME
+func (cli *DockerCli) CmdNetworkCreate(args ...string) error {
+	cmd := cli.Subcmd("network create", "NETWORK-NAME", "Create a network", true)
+	flDriver := cmd.String([]string{"d", "-driver"}, "", "Driver to manage the network")
+	flSubnet := cmd.String([]string{"-subnet"}, "", "Subnet in CIDR format to allocate endpoint addresses from")
+	flGateway := cmd.String([]string{"-gateway"}, "", "Gateway for the subnet")
+	flSubnetIPv6 := cmd.String([]string{"-subnet-v6"}, "", "IPv6 subnet in CIDR format to allocate endpoint IPv6 addresses from")
+	flMTU := cmd.Int([]string{"-mtu"}, 0, "MTU of the interfaces of the network")
+	flOpts := opts.NewListOpts(nil)
+	cmd.Var(&flOpts, []string{"o", "-opt"}, "Set driver specific options")
+	cmd.Require(flag.Exact, 1)
+
+	cmd.ParseFlags(args, true)
+
+	options := make(map[string]string)
+	for _, opt := range flOpts.GetAll() {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid driver option %s, expected key=value", opt)
+		}
+		options[kv[0]] = kv[1]
+	}
+
+	config := &types.NetworkCreate{
+		Name:       cmd.Arg(0),
+		Driver:     *flDriver,
+		Subnet:     *flSubnet,
+		Gateway:    *flGateway,
+		SubnetIPv6: *flSubnetIPv6,
+		MTU:        *flMTU,
+		Options:    options,
+	}
+	stream, _, err := cli.call("POST", "/networks/create", config, nil)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	var response types.NetworkCreateResponse
+	if err := json.NewDecoder(stream).Decode(&response); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "%s\n", response.ID)
+	return nil
+}
+
+// CmdNetworkConnect connects a running container to a network.
+//
+// Usage: docker network connect NETWORK CONTAINER
+func (cli *DockerCli) CmdNetworkConnect(args ...string) error {
+	cmd := cli.Subcmd("network connect", "NETWORK CONTAINER", "Connect a running container to a network", true)
+	cmd.Require(flag.Exact, 2)
+
+	cmd.ParseFlags(args, true)
+
+	config := &types.NetworkConnect{Container: cmd.Arg(1)}
+	_, _, err := readBody(cli.call("POST", "/networks/"+cmd.Arg(0)+"/connect", config, nil))
+	return err
+}
+
+// CmdNetworkDisconnect disconnects a running container from a network.
+//
+// Usage: docker network disconnect NETWORK CONTAINER
+func (cli *DockerCli) CmdNetworkDisconnect(args ...string) error {
+	cmd := cli.Subcmd("network disconnect", "NETWORK CONTAINER", "Disconnect a running container from a network", true)
+	cmd.Require(flag.Exact, 2)
+
+	cmd.ParseFlags(args, true)
+
+	config := &types.NetworkConnect{Container: cmd.Arg(1)}
+	_, _, err := readBody(cli.call("POST", "/networks/"+cmd.Arg(0)+"/disconnect", config, nil))
+	return err
+}
+
+// CmdNetworkRm deletes one or more networks.
+//
+// Usage: docker network rm NETWORK-NAME|NETWORK-ID [NETWORK-NAME|NETWORK-ID...]
+func (cli *DockerCli) CmdNetworkRm(args ...string) error {
+	cmd := cli.Subcmd("network rm", "NETWORK [NETWORK...]", "Remove one or more networks", true)
+	cmd.Require(flag.Min, 1)
+
+	cmd.ParseFlags(args, true)
+
+	var errNames []string
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("DELETE", "/networks/"+name, nil, nil)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			errNames = append(errNames, name)
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	if len(errNames) > 0 {
+		return fmt.Errorf("Error: failed to remove networks: %v", errNames)
+	}
+	return nil
+}
+
+// CmdNetworkLs lists all the networks.
+//
+// Usage: docker network ls [OPTIONS]
+func (cli *DockerCli) CmdNetworkLs(args ...string) error {
+	cmd := cli.Subcmd("network ls", "", "List networks", true)
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display numeric IDs")
+	noTrunc := cmd.Bool([]string{"#notrunc", "-no-trunc"}, false, "Don't truncate output")
+	cmd.Require(flag.Exact, 0)
+
+	cmd.ParseFlags(args, true)
+
+	rdr, _, err := cli.call("GET", "/networks/json", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer rdr.Close()
+
+	networks := []types.NetworkResource{}
+	if err := json.NewDecoder(rdr).Decode(&networks); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*quiet {
+		fmt.Fprintln(w, "NETWORK ID\tNAME\tDRIVER\tSUBNET")
+	}
+	for _, n := range networks {
+		id := n.ID
+		if !*noTrunc {
+			id = stringid.TruncateID(id)
+		}
+		if *quiet {
+			fmt.Fprintln(w, id)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", id, n.Name, n.Driver, n.Subnet)
+	}
+	w.Flush()
+	return nil
+}
+
+// CmdNetworkInspect displays detailed information on one or more networks.
+//
+// Usage: docker network inspect NETWORK [NETWORK...]
+func (cli *DockerCli) CmdNetworkInspect(args ...string) error {
+	cmd := cli.Subcmd("network inspect", "NETWORK [NETWORK...]", "Return low-level information on one or more networks", true)
+	cmd.Require(flag.Min, 1)
+
+	cmd.ParseFlags(args, true)
+
+	status := 0
+	indented := new(bytes.Buffer)
+	indented.WriteString("[\n")
+	for _, name := range cmd.Args() {
+		obj, _, err := readBody(cli.call("GET", "/networks/"+name+"/json", nil, nil))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		if err := json.Indent(indented, obj, "", "    "); err != nil {
+			return err
+		}
+		indented.WriteString(",")
+	}
+	if indented.Len() > 1 {
+		// Remove trailing ','
+		indented.Truncate(indented.Len() - 1)
+	}
+	indented.WriteString("]\n")
+
+	if _, err := io.Copy(cli.out, indented); err != nil {
+		return err
+	}
+	if status != 0 {
+		return StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+func networkUsage() string {
+	networkCommands := [][]string{
+		{"connect", "Connect a running container to a network"},
+		{"create", "Create a network"},
+		{"disconnect", "Disconnect a running container from a network"},
+		{"inspect", "Display detailed network information"},
+		{"ls", "List all networks"},
+		{"rm", "Remove a network"},
+	}
+
+	help := "Commands:\n"
+	for _, cmd := range networkCommands {
+		help += fmt.Sprintf("  %-10.10s%s\n", cmd[0], cmd[1])
+	}
+	help += "\nRun 'docker network COMMAND --help' for more information on a command."
+	return help
+}
@@ -7,16 +7,12 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
-	"time"
+	"text/template"
 
-	"github.com/docker/docker/api"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/opts"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers/filters"
-	"github.com/docker/docker/pkg/stringid"
-	"github.com/docker/docker/pkg/stringutils"
-	"github.com/docker/docker/pkg/units"
 )
 
 // CmdPs outputs a list of Docker containers.
@@ -38,6 +34,7 @@ func (cli *DockerCli) CmdPs(args ...string) error {
 		since    = cmd.String([]string{"#sinceId", "#-since-id", "-since"}, "", "Show created since Id or Name, include non-running")
 		before   = cmd.String([]string{"#beforeId", "#-before-id", "-before"}, "", "Show only container created before Id or Name")
 		last     = cmd.Int([]string{"n"}, -1, "Show n last created containers, include non-running")
+		format   = cmd.String([]string{"-format"}, "", "Pretty-print containers using a Go template")
 		flFilter = opts.NewListOpts(nil)
 	)
 	cmd.Require(flag.Exact, 0)
@@ -65,7 +62,19 @@ func (cli *DockerCli) CmdPs(args ...string) error {
 		v.Set("before", *before)
 	}
 
-	if *size {
+	if *format == "" && !*quiet {
+		*format = cli.configFile.PsFormat
+	}
+
+	var tmpl *template.Template
+	if *format != "" {
+		if tmpl, err = parseFormat(*format); err != nil {
+			return err
+		}
+	}
+
+	// The sizes are only computed when they are shown
+	if *size || strings.Contains(*format, ".Size") {
 		v.Set("size", "1")
 	}
 
@@ -96,6 +105,16 @@ func (cli *DockerCli) CmdPs(args ...string) error {
 		return err
 	}
 
+	if *format != "" && !*quiet {
+		for _, container := range containers {
+			if err := tmpl.Execute(cli.out, &containerContext{trunc: !*noTrunc, c: container}); err != nil {
+				return err
+			}
+			cli.out.Write([]byte{'\n'})
+		}
+		return nil
+	}
+
 	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
 	if !*quiet {
 		fmt.Fprint(w, "CONTAINER ID\tIMAGE\tCOMMAND\tCREATED\tSTATUS\tPORTS\tNAMES")
@@ -107,59 +126,20 @@ func (cli *DockerCli) CmdPs(args ...string) error {
 		}
 	}
 
-	stripNamePrefix := func(ss []string) []string {
-		for i, s := range ss {
-			ss[i] = s[1:]
-		}
-
-		return ss
-	}
-
 	for _, container := range containers {
-		ID := container.ID
-
-		if !*noTrunc {
-			ID = stringid.TruncateID(ID)
-		}
+		ctx := &containerContext{trunc: !*noTrunc, c: container}
 
 		if *quiet {
-			fmt.Fprintln(w, ID)
+			fmt.Fprintln(w, ctx.ID())
 
 			continue
 		}
 
-		var (
-			names   = stripNamePrefix(container.Names)
-			command = strconv.Quote(container.Command)
-		)
-
-		if !*noTrunc {
-			command = stringutils.Truncate(command, 20)
-
-			// only display the default name for the container with notrunc is passed
-			for _, name := range names {
-				if len(strings.Split(name, "/")) == 1 {
-					names = []string{name}
-					break
-				}
-			}
-		}
-
-		image := container.Image
-		if image == "" {
-			image = "<no image>"
-		}
-
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s ago\t%s\t%s\t%s\t", ID, image, command,
-			units.HumanDuration(time.Now().UTC().Sub(time.Unix(int64(container.Created), 0))),
-			container.Status, api.DisplayablePorts(container.Ports), strings.Join(names, ","))
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s ago\t%s\t%s\t%s\t", ctx.ID(), ctx.Image(), ctx.Command(),
+			ctx.RunningFor(), ctx.Status(), ctx.Ports(), ctx.Names())
 
 		if *size {
-			if container.SizeRootFs > 0 {
-				fmt.Fprintf(w, "%s (virtual %s)\n", units.HumanSize(float64(container.SizeRw)), units.HumanSize(float64(container.SizeRootFs)))
-			} else {
-				fmt.Fprintf(w, "%s\n", units.HumanSize(float64(container.SizeRw)))
-			}
+			fmt.Fprintf(w, "%s\n", ctx.Size())
 
 			continue
 		}
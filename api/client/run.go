@@ -51,7 +51,6 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 
 		ErrConflictAttachDetach               = fmt.Errorf("Conflicting options: -a and -d")
 		ErrConflictRestartPolicyAndAutoRemove = fmt.Errorf("Conflicting options: --restart and --rm")
-		ErrConflictDetachAutoRemove           = fmt.Errorf("Conflicting options: --rm and -d")
 	)
 
 	config, hostConfig, cmd, err := runconfig.Parse(cmd, args)
@@ -87,9 +86,8 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 				return ErrConflictAttachDetach
 			}
 		}
-		if *flAutoRemove {
-			return ErrConflictDetachAutoRemove
-		}
+		// Detached containers are removed by the daemon once they exit
+		hostConfig.AutoRemove = *flAutoRemove
 
 		config.AttachStdin = false
 		config.AttachStdout = false
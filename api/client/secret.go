@@ -0,0 +1,193 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	flag "github.com/docker/docker/pkg/mflag"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/pkg/units"
+)
+
+// CmdSecret is the parent subcommand for all secret commands.
+//
+// Usage: docker secret <COMMAND> [OPTIONS]
+func (cli *DockerCli) CmdSecret(args ...string) error {
+	cmd := cli.Subcmd("secret", "COMMAND [OPTIONS]", "Manage secrets\n\n"+secretUsage(), false)
+	cmd.Require(flag.Min, 1)
+	err := cmd.ParseFlags(args, true)
+	cmd.Usage()
+	return err
+}
+
+// CmdSecretCreate registers a secret with the daemon, its data read from a
+// file or STDIN.
+//
+// Usage: docker secret create NAME [FILE|-]
+func (cli *DockerCli) CmdSecretCreate(args ...string) error {
+	cmd := cli.Subcmd("secret create", "NAME [FILE|-]", "Create a secret from a file (read from STDIN by default)", true)
+	cmd.Require(flag.Min, 1)
+	cmd.Require(flag.Max, 2)
+
+	cmd.ParseFlags(args, true)
+
+	var input io.Reader = cli.in
+	if src := cmd.Arg(1); src != "" && src != "-" {
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return err
+	}
+
+	config := &types.SecretCreate{
+		Name: cmd.Arg(0),
+		Data: data,
+	}
+	stream, _, err := cli.call("POST", "/secrets/create", config, nil)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	var res types.SecretCreateResponse
+	if err := json.NewDecoder(stream).Decode(&res); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "%s\n", res.ID)
+	return nil
+}
+
+// CmdSecretRm removes one or more secrets.
+//
+// Usage: docker secret rm SECRET [SECRET...]
+func (cli *DockerCli) CmdSecretRm(args ...string) error {
+	cmd := cli.Subcmd("secret rm", "SECRET [SECRET...]", "Remove one or more secrets", true)
+	cmd.Require(flag.Min, 1)
+
+	cmd.ParseFlags(args, true)
+
+	var errNames []string
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("DELETE", "/secrets/"+name, nil, nil)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			errNames = append(errNames, name)
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	if len(errNames) > 0 {
+		return fmt.Errorf("Error: failed to remove secrets: %v", errNames)
+	}
+	return nil
+}
+
+// CmdSecretLs lists all the secrets.
+//
+// Usage: docker secret ls [OPTIONS]
+func (cli *DockerCli) CmdSecretLs(args ...string) error {
+	cmd := cli.Subcmd("secret ls", "", "List secrets", true)
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display secret names")
+	noTrunc := cmd.Bool([]string{"-no-trunc"}, false, "Don't truncate output")
+	cmd.Require(flag.Exact, 0)
+
+	cmd.ParseFlags(args, true)
+
+	rdr, _, err := cli.call("GET", "/secrets/json", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer rdr.Close()
+
+	secrets := []types.Secret{}
+	if err := json.NewDecoder(rdr).Decode(&secrets); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*quiet {
+		fmt.Fprintln(w, "SECRET ID\tNAME\tCREATED\tSIZE")
+	}
+	for _, s := range secrets {
+		if *quiet {
+			fmt.Fprintln(w, s.Name)
+			continue
+		}
+		id := s.ID
+		if !*noTrunc {
+			id = stringid.TruncateID(id)
+		}
+		created := units.HumanDuration(time.Now().UTC().Sub(s.Created)) + " ago"
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", id, s.Name, created, units.HumanSize(float64(s.Size)))
+	}
+	w.Flush()
+	return nil
+}
+
+// CmdSecretInspect displays detailed information on one or more secrets,
+// without their data.
+//
+// Usage: docker secret inspect SECRET [SECRET...]
+func (cli *DockerCli) CmdSecretInspect(args ...string) error {
+	cmd := cli.Subcmd("secret inspect", "SECRET [SECRET...]", "Return low-level information on one or more secrets", true)
+	cmd.Require(flag.Min, 1)
+
+	cmd.ParseFlags(args, true)
+
+	status := 0
+	indented := new(bytes.Buffer)
+	indented.WriteString("[\n")
+	for _, name := range cmd.Args() {
+		obj, _, err := readBody(cli.call("GET", "/secrets/"+name+"/json", nil, nil))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		if err := json.Indent(indented, obj, "", "    "); err != nil {
+			return err
+		}
+		indented.WriteString(",")
+	}
+	if indented.Len() > 1 {
+		// Remove trailing ','
+		indented.Truncate(indented.Len() - 1)
+	}
+	indented.WriteString("]\n")
+
+	if _, err := io.Copy(cli.out, indented); err != nil {
+		return err
+	}
+	if status != 0 {
+		return StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+func secretUsage() string {
+	secretCommands := [][]string{
+		{"create", "Create a secret"},
+		{"inspect", "Display detailed secret information"},
+		{"ls", "List secrets"},
+		{"rm", "Remove a secret"},
+	}
+
+	help := "Commands:\n"
+	for _, cmd := range secretCommands {
+		help += fmt.Sprintf("  %-10.10s%s\n", cmd[0], cmd[1])
+	}
+	help += "\nRun 'docker secret COMMAND --help' for more information on a command."
+	return help
+}
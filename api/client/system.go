@@ -0,0 +1,89 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/docker/docker/api/types"
+	flag "github.com/docker/docker/pkg/mflag"
+	"github.com/docker/docker/pkg/units"
+)
+
+// CmdSystem is the parent subcommand for all system commands.
+//
+// Usage: docker system <COMMAND> [OPTIONS]
+func (cli *DockerCli) CmdSystem(args ...string) error {
+	cmd := cli.Subcmd("system", "COMMAND [OPTIONS]", "Manage Docker\n\n"+systemUsage(), false)
+	cmd.Require(flag.Min, 1)
+	err := cmd.ParseFlags(args, true)
+	cmd.Usage()
+	return err
+}
+
+// CmdSystemDf displays the disk space used by the daemon.
+//
+// Usage: docker system df [OPTIONS]
+func (cli *DockerCli) CmdSystemDf(args ...string) error {
+	cmd := cli.Subcmd("system df", "", "Show the disk space used by the images, containers and volumes", true)
+	format := cmd.String([]string{"-format"}, "", "Format the output using the given go template")
+	cmd.Require(flag.Exact, 0)
+	cmd.ParseFlags(args, true)
+
+	var tmpl *template.Template
+	if *format != "" {
+		var err error
+		if tmpl, err = parseFormat(*format); err != nil {
+			return err
+		}
+	}
+
+	rdr, _, err := cli.call("GET", "/system/df", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer rdr.Close()
+
+	du := &types.DiskUsage{}
+	if err := json.NewDecoder(rdr).Decode(du); err != nil {
+		return fmt.Errorf("Error reading the disk usage: %v", err)
+	}
+
+	if tmpl != nil {
+		if err := tmpl.Execute(cli.out, du); err != nil {
+			return err
+		}
+		cli.out.Write([]byte{'\n'})
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintf(w, "TYPE\tTOTAL\tACTIVE\tSIZE\tRECLAIMABLE\n")
+	for _, row := range []struct {
+		name    string
+		summary types.DiskUsageSummary
+	}{
+		{"Images", du.Images},
+		{"Containers", du.Containers},
+		{"Local Volumes", du.Volumes},
+	} {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", row.name, row.summary.TotalCount, row.summary.Active,
+			units.HumanSize(float64(row.summary.Size)), formatReclaimable(row.summary))
+	}
+	w.Flush()
+	return nil
+}
+
+func systemUsage() string {
+	systemCommands := [][]string{
+		{"df", "Show the disk space used by the images, containers and volumes"},
+	}
+
+	help := "Commands:\n"
+	for _, cmd := range systemCommands {
+		help += fmt.Sprintf("  %-10.10s%s\n", cmd[0], cmd[1])
+	}
+	help += "\nRun 'docker system COMMAND --help' for more information on a command."
+	return help
+}
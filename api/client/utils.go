@@ -33,6 +33,10 @@ var (
 	errConnectionRefused = errors.New("Cannot connect to the Docker daemon. Is 'docker -d' running on this host?")
 )
 
+// retryDelay is the delay before the first retry of a request, doubled for
+// each of the next ones.
+const retryDelay = 500 * time.Millisecond
+
 // HTTPClient creates a new HTP client with the cli's client transport instance.
 func (cli *DockerCli) HTTPClient() *http.Client {
 	return &http.Client{Transport: cli.transport}
@@ -49,13 +53,26 @@ func (cli *DockerCli) encodeData(data interface{}) (*bytes.Buffer, error) {
 }
 
 func (cli *DockerCli) clientRequest(method, path string, in io.Reader, headers map[string][]string) (io.ReadCloser, string, int, error) {
+	resp, statusCode, err := cli.sendRequest(method, path, in, headers)
+	if err != nil {
+		return nil, "", statusCode, err
+	}
+	return resp.Body, resp.Header.Get("Content-Type"), statusCode, nil
+}
+
+// sendRequest sends a request to the daemon and returns its response, for
+// the callers which need more than the body, e.g. its trailers.
+func (cli *DockerCli) sendRequest(method, path string, in io.Reader, headers map[string][]string) (*http.Response, int, error) {
+	if cli.described != nil {
+		return nil, -1, errDescribing
+	}
 	expectedPayload := (method == "POST" || method == "PUT")
 	if expectedPayload && in == nil {
 		in = bytes.NewReader([]byte{})
 	}
 	req, err := http.NewRequest(method, fmt.Sprintf("/v%s%s", api.APIVERSION, path), in)
 	if err != nil {
-		return nil, "", -1, err
+		return nil, -1, err
 	}
 
 	// Add CLI Config's HTTP Headers BEFORE we set the Docker headers
@@ -79,33 +96,55 @@ func (cli *DockerCli) clientRequest(method, path string, in io.Reader, headers m
 	}
 
 	resp, err := cli.HTTPClient().Do(req)
+	if retryable(method, in) {
+		for i := 0; err != nil && i < cli.configFile.Retries; i++ {
+			logrus.Debugf("Retrying %s %s: %v", method, path, err)
+			time.Sleep(retryDelay << uint(i))
+			resp, err = cli.HTTPClient().Do(req)
+		}
+	}
 	statusCode := -1
 	if resp != nil {
 		statusCode = resp.StatusCode
 	}
 	if err != nil {
 		if strings.Contains(err.Error(), "connection refused") {
-			return nil, "", statusCode, errConnectionRefused
+			return nil, statusCode, errConnectionRefused
 		}
 
 		if cli.tlsConfig == nil {
-			return nil, "", statusCode, fmt.Errorf("%v. Are you trying to connect to a TLS-enabled daemon without TLS?", err)
+			return nil, statusCode, fmt.Errorf("%v. Are you trying to connect to a TLS-enabled daemon without TLS?", err)
 		}
-		return nil, "", statusCode, fmt.Errorf("An error occurred trying to connect: %v", err)
+		return nil, statusCode, fmt.Errorf("An error occurred trying to connect: %v", err)
 	}
 
 	if statusCode < 200 || statusCode >= 400 {
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, "", statusCode, err
+			return nil, statusCode, err
 		}
 		if len(body) == 0 {
-			return nil, "", statusCode, fmt.Errorf("Error: request returned %s for API route and version %s, check if the server supports the requested API version", http.StatusText(statusCode), req.URL)
+			return nil, statusCode, fmt.Errorf("Error: request returned %s for API route and version %s, check if the server supports the requested API version", http.StatusText(statusCode), req.URL)
 		}
-		return nil, "", statusCode, fmt.Errorf("Error response from daemon: %s", bytes.TrimSpace(body))
+		return nil, statusCode, fmt.Errorf("Error response from daemon: %s", bytes.TrimSpace(body))
 	}
 
-	return resp.Body, resp.Header.Get("Content-Type"), statusCode, nil
+	return resp, statusCode, nil
+}
+
+// retryable returns whether a request can be sent again when it fails to
+// reach the daemon: the ones which don't change its state, without a body.
+func retryable(method string, in io.Reader) bool {
+	if method != "GET" && method != "HEAD" {
+		return false
+	}
+	if in == nil {
+		return true
+	}
+	buf, ok := in.(interface {
+		Len() int
+	})
+	return ok && buf.Len() == 0
 }
 
 func (cli *DockerCli) clientRequestAttemptLogin(method, path string, in io.Reader, out io.Writer, index *registry.IndexInfo, cmdName string) (io.ReadCloser, int, error) {
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRetryable(t *testing.T) {
+	cases := []struct {
+		method    string
+		in        io.Reader
+		retryable bool
+	}{
+		{"GET", nil, true},
+		{"GET", bytes.NewBuffer(nil), true},
+		{"HEAD", nil, true},
+		{"GET", bytes.NewBufferString("{}"), false},
+		{"GET", ioutil.NopCloser(nil), false},
+		{"POST", nil, false},
+		{"DELETE", nil, false},
+	}
+	for _, c := range cases {
+		if retryable(c.method, c.in) != c.retryable {
+			t.Fatalf("%s with %#v: expected retryable %v", c.method, c.in, c.retryable)
+		}
+	}
+}
+
+func TestSendRequestRetries(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		// The first two connections are closed before any response, the
+		// next ones are answered.
+		for i := 0; ; i++ {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			if i > 1 {
+				http.ReadRequest(bufio.NewReader(conn))
+				conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\n{}"))
+			}
+			conn.Close()
+		}
+	}()
+
+	cli := NewDockerCli(nil, ioutil.Discard, ioutil.Discard, "", "tcp", l.Addr().String(), nil)
+	cli.transport.Proxy = nil
+	if _, _, err := cli.sendRequest("GET", "/info", nil, nil); err == nil {
+		t.Fatal("Expected the request to fail without retries")
+	}
+
+	cli.configFile.Retries = 1
+	resp, _, err := cli.sendRequest("GET", "/info", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "{}") {
+		t.Fatalf("Unexpected body %q", body)
+	}
+}
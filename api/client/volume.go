@@ -0,0 +1,355 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/opts"
+	flag "github.com/docker/docker/pkg/mflag"
+	"github.com/docker/docker/pkg/parsers/filters"
+	"github.com/docker/docker/pkg/tarsum"
+	"github.com/docker/docker/pkg/timeutils"
+	"github.com/docker/docker/pkg/units"
+)
+
+// CmdVolume is the parent subcommand for all volume commands.
+//
+// Usage: docker volume <COMMAND> [OPTIONS]
+func (cli *DockerCli) CmdVolume(args ...string) error {
+	cmd := cli.Subcmd("volume", "COMMAND [OPTIONS]", "Manage volumes\n\n"+volumeUsage(), false)
+	cmd.Require(flag.Min, 1)
+	err := cmd.ParseFlags(args, true)
+	cmd.Usage()
+	return err
+}
+
+// CmdVolumeCreate creates a new volume.
+//
+// Usage: docker volume create [OPTIONS]
+func (cli *DockerCli) CmdVolumeCreate(args ...string) error {
+	cmd := cli.Subcmd("volume create", "", "Create a volume", true)
+	flName := cmd.String([]string{"-name"}, "", "Name of the volume, a random name is generated when empty")
+	flDriver := cmd.String([]string{"d", "-driver"}, "local", "Driver to store the volume")
+	flDriverOpts := opts.NewListOpts(nil)
+	cmd.Var(&flDriverOpts, []string{"o", "-opt"}, "Set driver specific options (e.g. size=10G)")
+	flLabels := opts.NewListOpts(opts.ValidateLabel)
+	cmd.Var(&flLabels, []string{"l", "-label"}, "Set metadata on the volume")
+	cmd.Require(flag.Exact, 0)
+
+	cmd.ParseFlags(args, true)
+
+	driverOpts := make(map[string]string)
+	for _, opt := range flDriverOpts.GetAll() {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid driver option %s, expected key=value", opt)
+		}
+		driverOpts[kv[0]] = kv[1]
+	}
+	labels := make(map[string]string)
+	for _, label := range flLabels.GetAll() {
+		kv := strings.SplitN(label, "=", 2)
+		labels[kv[0]] = kv[1]
+	}
+
+	config := &types.VolumeCreate{
+		Name:       *flName,
+		Driver:     *flDriver,
+		DriverOpts: driverOpts,
+		Labels:     labels,
+	}
+	stream, _, err := cli.call("POST", "/volumes/create", config, nil)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	var v types.Volume
+	if err := json.NewDecoder(stream).Decode(&v); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "%s\n", v.Name)
+	return nil
+}
+
+// CmdVolumeRm removes one or more volumes.
+//
+// Usage: docker volume rm VOLUME [VOLUME...]
+func (cli *DockerCli) CmdVolumeRm(args ...string) error {
+	cmd := cli.Subcmd("volume rm", "VOLUME [VOLUME...]", "Remove one or more volumes", true)
+	cmd.Require(flag.Min, 1)
+
+	cmd.ParseFlags(args, true)
+
+	var errNames []string
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("DELETE", "/volumes/"+name, nil, nil)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			errNames = append(errNames, name)
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	if len(errNames) > 0 {
+		return fmt.Errorf("Error: failed to remove volumes: %v", errNames)
+	}
+	return nil
+}
+
+// CmdVolumeLs lists all the volumes.
+//
+// Usage: docker volume ls [OPTIONS]
+func (cli *DockerCli) CmdVolumeLs(args ...string) error {
+	cmd := cli.Subcmd("volume ls", "", "List volumes", true)
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display volume names")
+	cmd.Require(flag.Exact, 0)
+
+	cmd.ParseFlags(args, true)
+
+	rdr, _, err := cli.call("GET", "/volumes/json", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer rdr.Close()
+
+	volumes := []types.Volume{}
+	if err := json.NewDecoder(rdr).Decode(&volumes); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*quiet {
+		fmt.Fprintln(w, "DRIVER\tVOLUME NAME")
+	}
+	for _, v := range volumes {
+		if *quiet {
+			fmt.Fprintln(w, v.Name)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", v.Driver, v.Name)
+	}
+	w.Flush()
+	return nil
+}
+
+// CmdVolumeInspect displays detailed information on one or more volumes.
+//
+// Usage: docker volume inspect VOLUME [VOLUME...]
+func (cli *DockerCli) CmdVolumeInspect(args ...string) error {
+	cmd := cli.Subcmd("volume inspect", "VOLUME [VOLUME...]", "Return low-level information on one or more volumes", true)
+	cmd.Require(flag.Min, 1)
+
+	cmd.ParseFlags(args, true)
+
+	status := 0
+	indented := new(bytes.Buffer)
+	indented.WriteString("[\n")
+	for _, name := range cmd.Args() {
+		obj, _, err := readBody(cli.call("GET", "/volumes/"+name+"/json", nil, nil))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		if err := json.Indent(indented, obj, "", "    "); err != nil {
+			return err
+		}
+		indented.WriteString(",")
+	}
+	if indented.Len() > 1 {
+		// Remove trailing ','
+		indented.Truncate(indented.Len() - 1)
+	}
+	indented.WriteString("]\n")
+
+	if _, err := io.Copy(cli.out, indented); err != nil {
+		return err
+	}
+	if status != 0 {
+		return StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+// CmdVolumeExport streams the contents of a volume as a tar archive and
+// prints its checksum.
+//
+// Usage: docker volume export [OPTIONS] VOLUME
+func (cli *DockerCli) CmdVolumeExport(args ...string) error {
+	cmd := cli.Subcmd("volume export", "VOLUME", "Export the contents of a volume as a tar archive (streamed to STDOUT by default)", true)
+	outfile := cmd.String([]string{"o", "-output"}, "", "Write to a file, instead of STDOUT")
+	cmd.Require(flag.Exact, 1)
+
+	cmd.ParseFlags(args, true)
+
+	var (
+		output io.Writer = cli.out
+		sumOut io.Writer = cli.err
+	)
+	if *outfile != "" {
+		f, err := os.Create(*outfile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		output, sumOut = f, cli.out
+	} else if cli.isTerminalOut {
+		return errors.New("Cowardly refusing to save to a terminal. Use the -o flag or redirect.")
+	}
+
+	resp, _, err := cli.sendRequest("GET", "/volumes/"+cmd.Arg(0)+"/export", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The archive is checked against the checksum computed by the daemon,
+	// sent once the whole archive is
+	ts, err := tarsum.NewTarSum(resp.Body, true, tarsum.Version1)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(output, ts); err != nil {
+		return err
+	}
+	checksum := resp.Trailer.Get("X-Docker-Checksum")
+	if checksum == "" {
+		return fmt.Errorf("Error: the daemon did not send the checksum of volume %s, the export may be incomplete", cmd.Arg(0))
+	}
+	if sum := ts.Sum(nil); sum != checksum {
+		return fmt.Errorf("Error: checksum mismatch for volume %s: the daemon sent %s, got %s", cmd.Arg(0), checksum, sum)
+	}
+	fmt.Fprintf(sumOut, "%s\n", checksum)
+	return nil
+}
+
+// CmdVolumeImport restores the contents of a volume from a tar archive and
+// prints its checksum.
+//
+// Usage: docker volume import [OPTIONS] VOLUME [FILE|-]
+func (cli *DockerCli) CmdVolumeImport(args ...string) error {
+	cmd := cli.Subcmd("volume import", "VOLUME [FILE|-]", "Restore the contents of a volume from a tar archive (read from STDIN by default)", true)
+	flChecksum := cmd.String([]string{"-checksum"}, "", "Only restore the archive if its checksum matches")
+	cmd.Require(flag.Min, 1)
+	cmd.Require(flag.Max, 2)
+
+	cmd.ParseFlags(args, true)
+
+	var input io.Reader = cli.in
+	if src := cmd.Arg(1); src != "" && src != "-" {
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	v := url.Values{}
+	if *flChecksum != "" {
+		v.Set("checksum", *flChecksum)
+	}
+	headers := map[string][]string{"Content-Type": {"application/x-tar"}}
+	stream, _, _, err := cli.clientRequest("POST", "/volumes/"+cmd.Arg(0)+"/import?"+v.Encode(), input, headers)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	var res types.VolumeImportResponse
+	if err := json.NewDecoder(stream).Decode(&res); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "%s\n", res.Checksum)
+	return nil
+}
+
+// CmdVolumePrune removes the volumes not used by any container.
+//
+// Usage: docker volume prune [OPTIONS]
+func (cli *DockerCli) CmdVolumePrune(args ...string) error {
+	cmd := cli.Subcmd("volume prune", "", "Remove all volumes not used by any container", true)
+	force := cmd.Bool([]string{"f", "-force"}, false, "Do not prompt for confirmation")
+	flFilter := opts.NewListOpts(nil)
+	cmd.Var(&flFilter, []string{"-filter"}, "Only remove the volumes matching the filter (e.g. 'label=<key>', 'until=24h')")
+	cmd.Require(flag.Exact, 0)
+
+	cmd.ParseFlags(args, true)
+
+	pruneFilterArgs := filters.Args{}
+	for _, f := range flFilter.GetAll() {
+		var err error
+		pruneFilterArgs, err = filters.ParseFlag(f, pruneFilterArgs)
+		if err != nil {
+			return err
+		}
+	}
+	for i, until := range pruneFilterArgs["until"] {
+		pruneFilterArgs["until"][i] = timeutils.GetTimestamp(until)
+	}
+
+	if !*force {
+		fmt.Fprint(cli.out, "WARNING! This will remove all volumes not used by at least one container.\nAre you sure you want to continue? [y/N] ")
+		answer, _ := bufio.NewReader(cli.in).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return nil
+		}
+	}
+
+	v := url.Values{}
+	if len(pruneFilterArgs) > 0 {
+		filterJSON, err := filters.ToParam(pruneFilterArgs)
+		if err != nil {
+			return err
+		}
+		v.Set("filters", filterJSON)
+	}
+
+	stream, _, err := cli.call("POST", "/volumes/prune?"+v.Encode(), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	var report types.VolumesPruneReport
+	if err := json.NewDecoder(stream).Decode(&report); err != nil {
+		return err
+	}
+	if len(report.VolumesDeleted) > 0 {
+		fmt.Fprintln(cli.out, "Deleted Volumes:")
+		for _, name := range report.VolumesDeleted {
+			fmt.Fprintln(cli.out, name)
+		}
+		fmt.Fprintln(cli.out)
+	}
+	fmt.Fprintf(cli.out, "Total reclaimed space: %s\n", units.HumanSize(float64(report.SpaceReclaimed)))
+	return nil
+}
+
+func volumeUsage() string {
+	volumeCommands := [][]string{
+		{"create", "Create a volume"},
+		{"export", "Export the contents of a volume as a tar archive"},
+		{"import", "Restore the contents of a volume from a tar archive"},
+		{"inspect", "Display detailed volume information"},
+		{"ls", "List volumes"},
+		{"prune", "Remove unused volumes"},
+		{"rm", "Remove a volume"},
+	}
+
+	help := "Commands:\n"
+	for _, cmd := range volumeCommands {
+		help += fmt.Sprintf("  %-10.10s%s\n", cmd[0], cmd[1])
+	}
+	help += "\nRun 'docker volume COMMAND --help' for more information on a command."
+	return help
+}
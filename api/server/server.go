@@ -23,8 +23,10 @@ import (
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/cliconfig"
 	"github.com/docker/docker/daemon"
+	"github.com/docker/docker/daemon/networkdriver"
 	"github.com/docker/docker/daemon/networkdriver/bridge"
 	"github.com/docker/docker/graph"
+	"github.com/docker/docker/pkg/authorization"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/docker/pkg/parsers"
@@ -48,20 +50,26 @@ type ServerConfig struct {
 	TlsCa       string
 	TlsCert     string
 	TlsKey      string
+	TlsCrl      string
+	// AuthorizationPlugins are the names of the plugins allowing or
+	// denying the requests to the API, in the order they are asked.
+	AuthorizationPlugins []string
 }
 
 type Server struct {
-	daemon  *daemon.Daemon
-	cfg     *ServerConfig
-	router  *mux.Router
-	start   chan struct{}
-	servers []serverCloser
+	daemon       *daemon.Daemon
+	cfg          *ServerConfig
+	router       *mux.Router
+	start        chan struct{}
+	servers      []serverCloser
+	authzPlugins []authorization.Plugin
 }
 
 func New(cfg *ServerConfig) *Server {
 	srv := &Server{
-		cfg:   cfg,
-		start: make(chan struct{}),
+		cfg:          cfg,
+		start:        make(chan struct{}),
+		authzPlugins: authorization.NewPlugins(cfg.AuthorizationPlugins),
 	}
 	r := createRouter(srv)
 	srv.router = r
@@ -206,6 +214,7 @@ func httpError(w http.ResponseWriter, err error) {
 		"impossible":            http.StatusNotAcceptable,
 		"wrong login/password":  http.StatusUnauthorized,
 		"hasn't been activated": http.StatusForbidden,
+		authorization.ErrDenied: http.StatusForbidden,
 	} {
 		if strings.Contains(errStr, keyword) {
 			statusCode = status
@@ -367,6 +376,15 @@ func (s *Server) getInfo(version version.Version, w http.ResponseWriter, r *http
 	return writeJSON(w, http.StatusOK, info)
 }
 
+func (s *Server) getSystemDf(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	du, err := s.daemon.SystemDiskUsage()
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, du)
+}
+
 func (s *Server) getEvents(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := parseForm(r); err != nil {
 		return err
@@ -581,11 +599,14 @@ func (s *Server) getContainersLogs(version version.Version, w http.ResponseWrite
 	logsConfig := &daemon.ContainerLogsConfig{
 		Follow:     boolValue(r, "follow"),
 		Timestamps: boolValue(r, "timestamps"),
+		Details:    boolValue(r, "details"),
 		Since:      since,
 		Tail:       r.Form.Get("tail"),
 		UseStdout:  stdout,
 		UseStderr:  stderr,
 		OutStream:  ioutils.NewWriteFlusher(w),
+
+		ExtendedFrames: boolValue(r, "extendedframes"),
 	}
 
 	if err := s.daemon.ContainerLogs(vars["name"], logsConfig); err != nil {
@@ -882,6 +903,245 @@ func (s *Server) postContainersCreate(version version.Version, w http.ResponseWr
 	})
 }
 
+func (s *Server) postNetworksCreate(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := checkForJson(r); err != nil {
+		return err
+	}
+
+	var config types.NetworkCreate
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		return err
+	}
+
+	n, err := s.daemon.NetworkCreate(config.Name, config.Driver, &networkdriver.NetworkConfig{
+		Subnet:     config.Subnet,
+		Gateway:    config.Gateway,
+		SubnetIPv6: config.SubnetIPv6,
+		MTU:        config.MTU,
+		Options:    config.Options,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusCreated, &types.NetworkCreateResponse{
+		ID: n.ID,
+	})
+}
+
+func (s *Server) postNetworksConnect(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := checkForJson(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	var config types.NetworkConnect
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		return err
+	}
+
+	if err := s.daemon.NetworkConnect(vars["name"], config.Container); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) postNetworksDisconnect(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := checkForJson(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	var config types.NetworkConnect
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		return err
+	}
+
+	if err := s.daemon.NetworkDisconnect(vars["name"], config.Container); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) getNetworksJSON(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return writeJSON(w, http.StatusOK, s.daemon.Networks())
+}
+
+func (s *Server) getNetworksByName(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	n, err := s.daemon.NetworkInspect(vars["name"])
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, n)
+}
+
+func (s *Server) deleteNetworks(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	if err := s.daemon.NetworkRm(vars["name"]); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (s *Server) postVolumesCreate(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := checkForJson(r); err != nil {
+		return err
+	}
+
+	var config types.VolumeCreate
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		return err
+	}
+
+	v, err := s.daemon.VolumeCreate(config.Name, config.Driver, config.DriverOpts, config.Labels)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, v)
+}
+
+func (s *Server) getVolumesJSON(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return writeJSON(w, http.StatusOK, s.daemon.Volumes())
+}
+
+func (s *Server) getVolumesByName(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	v, err := s.daemon.VolumeInspect(vars["name"])
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, v)
+}
+
+func (s *Server) deleteVolumes(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	if err := s.daemon.VolumeRm(vars["name"]); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (s *Server) getVolumesExport(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	// The checksum is only known once the whole archive is sent
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Trailer", "X-Docker-Checksum")
+	checksum, err := s.daemon.VolumeExport(vars["name"], w)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("X-Docker-Checksum", checksum)
+	return nil
+}
+
+func (s *Server) postVolumesImport(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	checksum, err := s.daemon.VolumeImport(vars["name"], r.Body, r.Form.Get("checksum"))
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, &types.VolumeImportResponse{Checksum: checksum})
+}
+
+func (s *Server) postVolumesPrune(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+
+	pruneFilters, err := filters.FromParam(r.Form.Get("filters"))
+	if err != nil {
+		return err
+	}
+
+	report, err := s.daemon.VolumesPrune(pruneFilters)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, report)
+}
+
+func (s *Server) postSecretsCreate(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := checkForJson(r); err != nil {
+		return err
+	}
+
+	var config types.SecretCreate
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		return err
+	}
+
+	secret, err := s.daemon.SecretCreate(config.Name, config.Data)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, secret)
+}
+
+func (s *Server) getSecretsJSON(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return writeJSON(w, http.StatusOK, s.daemon.Secrets())
+}
+
+func (s *Server) getSecretsByName(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	secret, err := s.daemon.SecretInspect(vars["name"])
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, secret)
+}
+
+func (s *Server) deleteSecrets(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	if err := s.daemon.SecretRm(vars["name"]); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
 func (s *Server) postContainersRestart(version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := parseForm(r); err != nil {
 		return err
@@ -1088,6 +1348,8 @@ func (s *Server) postContainersAttach(version version.Version, w http.ResponseWr
 		Logs:      boolValue(r, "logs"),
 		Stream:    boolValue(r, "stream"),
 		Multiplex: version.GreaterThanOrEqualTo("1.6"),
+
+		ExtendedFrames: boolValue(r, "extendedframes"),
 	}
 
 	if err := s.daemon.ContainerAttachWithLogs(vars["name"], attachWithLogsConfig); err != nil {
@@ -1130,7 +1392,11 @@ func (s *Server) getContainersByName(version version.Version, w http.ResponseWri
 		return fmt.Errorf("Missing parameter")
 	}
 
-	containerJSON, err := s.daemon.ContainerInspect(vars["name"])
+	if err := parseForm(r); err != nil {
+		return err
+	}
+
+	containerJSON, err := s.daemon.ContainerInspect(vars["name"], boolValue(r, "size"))
 	if err != nil {
 		return err
 	}
@@ -1340,7 +1606,12 @@ func (s *Server) postContainerExecStart(version version.Version, w http.Response
 			fmt.Fprintf(outStream, "HTTP/1.1 200 OK\r\nContent-Type: application/vnd.docker.raw-stream\r\n\r\n")
 		}
 
-		if !execStartCheck.Tty {
+		if !execStartCheck.Tty && boolValue(r, "extendedframes") {
+			// the frames carry the exec ID, for the streams of several execs
+			// to be told apart on a connection
+			errStream = stdcopy.NewExtendedStdWriter(outStream, stdcopy.Stderr, true, execName)
+			outStream = stdcopy.NewExtendedStdWriter(outStream, stdcopy.Stdout, true, execName)
+		} else if !execStartCheck.Tty {
 			errStream = stdcopy.NewStdWriter(outStream, stdcopy.Stderr)
 			outStream = stdcopy.NewStdWriter(outStream, stdcopy.Stdout)
 		}
@@ -1396,7 +1667,7 @@ func (s *Server) ping(version version.Version, w http.ResponseWriter, r *http.Re
 	return err
 }
 
-func makeHttpHandler(logging bool, localMethod string, localRoute string, handlerFunc HttpApiFunc, corsHeaders string, dockerVersion version.Version) http.HandlerFunc {
+func makeHttpHandler(logging bool, localMethod string, localRoute string, handlerFunc HttpApiFunc, corsHeaders string, dockerVersion version.Version, authzPlugins []authorization.Plugin) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// log the request
 		logrus.Debugf("Calling %s %s", localMethod, localRoute)
@@ -1424,6 +1695,19 @@ func makeHttpHandler(logging bool, localMethod string, localRoute string, handle
 			return
 		}
 
+		if len(authzPlugins) > 0 {
+			user, authNMethod := requestUser(r)
+			authCtx := authorization.NewCtx(authzPlugins, user, authNMethod, r)
+			if err := authCtx.AuthZRequest(r); err != nil {
+				logrus.Errorf("Authorization of %s %s failed: %s", localMethod, localRoute, err)
+				httpError(w, err)
+				return
+			}
+			rw := authCtx.NewResponseModifier(w, httpError)
+			defer rw.Finish()
+			w = rw
+		}
+
 		if err := handlerFunc(version, w, r, mux.Vars(r)); err != nil {
 			logrus.Errorf("Handler for %s %s returned error: %s", localMethod, localRoute, err)
 			httpError(w, err)
@@ -1431,6 +1715,15 @@ func makeHttpHandler(logging bool, localMethod string, localRoute string, handle
 	}
 }
 
+// requestUser returns the user sending r and how it authenticated: the
+// common name of its TLS certificate, if any.
+func requestUser(r *http.Request) (string, string) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return "", ""
+	}
+	return r.TLS.PeerCertificates[0].Subject.CommonName, "TLS"
+}
+
 // we keep enableCors just for legacy usage, need to be removed in the future
 func createRouter(s *Server) *mux.Router {
 	r := mux.NewRouter()
@@ -1442,6 +1735,7 @@ func createRouter(s *Server) *mux.Router {
 			"/_ping":                          s.ping,
 			"/events":                         s.getEvents,
 			"/info":                           s.getInfo,
+			"/system/df":                      s.getSystemDf,
 			"/version":                        s.getVersion,
 			"/images/json":                    s.getImagesJSON,
 			"/images/search":                  s.getImagesSearch,
@@ -1459,34 +1753,51 @@ func createRouter(s *Server) *mux.Router {
 			"/containers/{name:.*}/stats":     s.getContainersStats,
 			"/containers/{name:.*}/attach/ws": s.wsContainersAttach,
 			"/exec/{id:.*}/json":              s.getExecByID,
+			"/networks/json":                  s.getNetworksJSON,
+			"/networks/{name:.*}/json":        s.getNetworksByName,
+			"/volumes/json":                   s.getVolumesJSON,
+			"/volumes/{name:.*}/json":         s.getVolumesByName,
+			"/volumes/{name:.*}/export":       s.getVolumesExport,
+			"/secrets/json":                   s.getSecretsJSON,
+			"/secrets/{name:.*}/json":         s.getSecretsByName,
 		},
 		"POST": {
-			"/auth":                         s.postAuth,
-			"/commit":                       s.postCommit,
-			"/build":                        s.postBuild,
-			"/images/create":                s.postImagesCreate,
-			"/images/load":                  s.postImagesLoad,
-			"/images/{name:.*}/push":        s.postImagesPush,
-			"/images/{name:.*}/tag":         s.postImagesTag,
-			"/containers/create":            s.postContainersCreate,
-			"/containers/{name:.*}/kill":    s.postContainersKill,
-			"/containers/{name:.*}/pause":   s.postContainersPause,
-			"/containers/{name:.*}/unpause": s.postContainersUnpause,
-			"/containers/{name:.*}/restart": s.postContainersRestart,
-			"/containers/{name:.*}/start":   s.postContainersStart,
-			"/containers/{name:.*}/stop":    s.postContainersStop,
-			"/containers/{name:.*}/wait":    s.postContainersWait,
-			"/containers/{name:.*}/resize":  s.postContainersResize,
-			"/containers/{name:.*}/attach":  s.postContainersAttach,
-			"/containers/{name:.*}/copy":    s.postContainersCopy,
-			"/containers/{name:.*}/exec":    s.postContainerExecCreate,
-			"/exec/{name:.*}/start":         s.postContainerExecStart,
-			"/exec/{name:.*}/resize":        s.postContainerExecResize,
-			"/containers/{name:.*}/rename":  s.postContainerRename,
+			"/auth":                          s.postAuth,
+			"/commit":                        s.postCommit,
+			"/build":                         s.postBuild,
+			"/images/create":                 s.postImagesCreate,
+			"/images/load":                   s.postImagesLoad,
+			"/images/{name:.*}/push":         s.postImagesPush,
+			"/images/{name:.*}/tag":          s.postImagesTag,
+			"/containers/create":             s.postContainersCreate,
+			"/containers/{name:.*}/kill":     s.postContainersKill,
+			"/containers/{name:.*}/pause":    s.postContainersPause,
+			"/containers/{name:.*}/unpause":  s.postContainersUnpause,
+			"/containers/{name:.*}/restart":  s.postContainersRestart,
+			"/containers/{name:.*}/start":    s.postContainersStart,
+			"/containers/{name:.*}/stop":     s.postContainersStop,
+			"/containers/{name:.*}/wait":     s.postContainersWait,
+			"/containers/{name:.*}/resize":   s.postContainersResize,
+			"/containers/{name:.*}/attach":   s.postContainersAttach,
+			"/containers/{name:.*}/copy":     s.postContainersCopy,
+			"/containers/{name:.*}/exec":     s.postContainerExecCreate,
+			"/exec/{name:.*}/start":          s.postContainerExecStart,
+			"/exec/{name:.*}/resize":         s.postContainerExecResize,
+			"/containers/{name:.*}/rename":   s.postContainerRename,
+			"/networks/create":               s.postNetworksCreate,
+			"/networks/{name:.*}/connect":    s.postNetworksConnect,
+			"/networks/{name:.*}/disconnect": s.postNetworksDisconnect,
+			"/volumes/create":                s.postVolumesCreate,
+			"/volumes/prune":                 s.postVolumesPrune,
+			"/volumes/{name:.*}/import":      s.postVolumesImport,
+			"/secrets/create":                s.postSecretsCreate,
 		},
 		"DELETE": {
 			"/containers/{name:.*}": s.deleteContainers,
 			"/images/{name:.*}":     s.deleteImages,
+			"/networks/{name:.*}":   s.deleteNetworks,
+			"/volumes/{name:.*}":    s.deleteVolumes,
+			"/secrets/{name:.*}":    s.deleteSecrets,
 		},
 		"OPTIONS": {
 			"": s.optionsHandler,
@@ -1509,7 +1820,7 @@ func createRouter(s *Server) *mux.Router {
 			localMethod := method
 
 			// build the handler function
-			f := makeHttpHandler(s.cfg.Logging, localMethod, localRoute, localFct, corsHeaders, version.Version(s.cfg.Version))
+			f := makeHttpHandler(s.cfg.Logging, localMethod, localRoute, localFct, corsHeaders, version.Version(s.cfg.Version), s.authzPlugins)
 
 			// add the new route
 			if localRoute == "" {
@@ -64,7 +64,7 @@ func (s *Server) newServer(proto, addr string) (serverCloser, error) {
 	return &HttpServer{
 		&http.Server{
 			Addr:    addr,
-			Handler: s.router,
+			Handler: rejectRevoked(l, s.router),
 		},
 		l,
 	}, nil
@@ -34,7 +34,7 @@ func (s *Server) newServer(proto, addr string) (Server, error) {
 	return &HttpServer{
 		&http.Server{
 			Addr:    addr,
-			Handler: s.router,
+			Handler: rejectRevoked(l, s.router),
 		},
 		l,
 	}, nil
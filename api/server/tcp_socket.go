@@ -1,12 +1,7 @@
 package server
 
 import (
-	"crypto/tls"
-	"crypto/x509"
-	"fmt"
-	"io/ioutil"
 	"net"
-	"os"
 
 	"github.com/docker/docker/pkg/listenbuffer"
 )
@@ -15,6 +10,7 @@ type tlsConfig struct {
 	CA          string
 	Certificate string
 	Key         string
+	CRL         string
 	Verify      bool
 }
 
@@ -28,6 +24,7 @@ func tlsConfigFromServerConfig(conf *ServerConfig) *tlsConfig {
 		Certificate: conf.TlsCert,
 		Key:         conf.TlsKey,
 		CA:          conf.TlsCa,
+		CRL:         conf.TlsCrl,
 	}
 }
 
@@ -43,31 +40,3 @@ func NewTcpSocket(addr string, config *tlsConfig, activate <-chan struct{}) (net
 	}
 	return l, nil
 }
-
-func setupTls(l net.Listener, config *tlsConfig) (net.Listener, error) {
-	tlsCert, err := tls.LoadX509KeyPair(config.Certificate, config.Key)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("Could not load X509 key pair (%s, %s): %v", config.Certificate, config.Key, err)
-		}
-		return nil, fmt.Errorf("Error reading X509 key pair (%s, %s): %q. Make sure the key is encrypted.",
-			config.Certificate, config.Key, err)
-	}
-	tlsConfig := &tls.Config{
-		NextProtos:   []string{"http/1.1"},
-		Certificates: []tls.Certificate{tlsCert},
-		// Avoid fallback on insecure SSL protocols
-		MinVersion: tls.VersionTLS10,
-	}
-	if config.CA != "" {
-		certPool := x509.NewCertPool()
-		file, err := ioutil.ReadFile(config.CA)
-		if err != nil {
-			return nil, fmt.Errorf("Could not read CA certificate: %v", err)
-		}
-		certPool.AppendCertsFromPEM(file)
-		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
-		tlsConfig.ClientCAs = certPool
-	}
-	return tls.NewListener(l, tlsConfig), nil
-}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/filenotify"
+)
+
+// reloadDelay is how long the listener waits for the other TLS files to be
+// written once one of them changed, before it reloads them.
+const reloadDelay = 500 * time.Millisecond
+
+// tlsListener serves TLS with the certificate, key, CA and CRL of its files,
+// reloaded on SIGHUP or when they change so that they can be rotated without
+// restarting the daemon. A reload failing keeps the material loaded before.
+//
+// The new material applies to the connections accepted afterwards. The
+// revoked client certificates are rejected on each request, so that the
+// connections already established with them are rejected too.
+type tlsListener struct {
+	net.Listener
+	files *tlsConfig
+
+	mu      sync.RWMutex
+	config  *tls.Config
+	revoked map[string]map[string]bool // serial numbers of the revoked certificates, by raw subject of their issuer
+
+	done chan struct{}
+}
+
+// setupTls returns a listener serving TLS on l with the files of config.
+func setupTls(l net.Listener, config *tlsConfig) (net.Listener, error) {
+	tl := &tlsListener{
+		Listener: l,
+		files:    config,
+		done:     make(chan struct{}),
+	}
+	if err := tl.reload(); err != nil {
+		return nil, err
+	}
+	go tl.watch()
+	return tl, nil
+}
+
+func (l *tlsListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.mu.RLock()
+	config := l.config
+	l.mu.RUnlock()
+	return tls.Server(c, config), nil
+}
+
+func (l *tlsListener) Close() error {
+	select {
+	case <-l.done:
+	default:
+		close(l.done)
+	}
+	return l.Listener.Close()
+}
+
+// isRevoked returns whether the client certificate cert is revoked by the
+// CRL.
+func (l *tlsListener) isRevoked(cert *x509.Certificate) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.revoked[string(cert.RawIssuer)][cert.SerialNumber.String()]
+}
+
+// reload loads the TLS files, replacing the material of the listener only
+// if all of them are valid.
+func (l *tlsListener) reload() error {
+	tlsCert, err := tls.LoadX509KeyPair(l.files.Certificate, l.files.Key)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("Could not load X509 key pair (%s, %s): %v", l.files.Certificate, l.files.Key, err)
+		}
+		return fmt.Errorf("Error reading X509 key pair (%s, %s): %q. Make sure the key is encrypted.",
+			l.files.Certificate, l.files.Key, err)
+	}
+	config := &tls.Config{
+		NextProtos:   []string{"http/1.1"},
+		Certificates: []tls.Certificate{tlsCert},
+		// Avoid fallback on insecure SSL protocols
+		MinVersion: tls.VersionTLS10,
+	}
+	var (
+		cas     []*x509.Certificate
+		revoked map[string]map[string]bool
+	)
+	if l.files.CA != "" {
+		file, err := ioutil.ReadFile(l.files.CA)
+		if err != nil {
+			return fmt.Errorf("Could not read CA certificate: %v", err)
+		}
+		certPool := x509.NewCertPool()
+		certPool.AppendCertsFromPEM(file)
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = certPool
+		if cas, err = parseCertificates(file); err != nil {
+			return fmt.Errorf("Could not parse CA certificate: %v", err)
+		}
+	}
+	if l.files.CRL != "" {
+		if l.files.CA == "" {
+			return fmt.Errorf("A CRL needs a CA certificate to verify the client certificates")
+		}
+		if revoked, err = loadCRL(l.files.CRL, cas); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	l.config = config
+	l.revoked = revoked
+	l.mu.Unlock()
+	return nil
+}
+
+// watch reloads the TLS files on SIGHUP or when they change, until the
+// listener is closed. The directories of the files are watched rather than
+// the files, which are often rotated by replacing them.
+func (l *tlsListener) watch() {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGHUP)
+	defer signal.Stop(sigc)
+
+	watched := make(map[string]bool)
+	watcher := filenotify.New()
+	defer watcher.Close()
+	for _, name := range []string{l.files.Certificate, l.files.Key, l.files.CA, l.files.CRL} {
+		if name == "" {
+			continue
+		}
+		name = filepath.Clean(name)
+		dir := filepath.Dir(name)
+		watched[name] = true
+		if watched[dir] {
+			continue
+		}
+		watched[dir] = true
+		if err := watcher.Add(dir); err != nil {
+			logrus.Warnf("Could not watch %s for changes of the TLS files, reload them with SIGHUP: %v", dir, err)
+		}
+	}
+
+	var delay <-chan time.Time
+	for {
+		select {
+		case <-sigc:
+			logrus.Infof("Reloading the TLS files on SIGHUP")
+			delay = time.After(0)
+		case event := <-watcher.Events():
+			if watched[filepath.Clean(event.Name)] && delay == nil {
+				delay = time.After(reloadDelay)
+			}
+			continue
+		case err := <-watcher.Errors():
+			logrus.Warnf("Error watching the TLS files: %v", err)
+			continue
+		case <-delay:
+		case <-l.done:
+			return
+		}
+		delay = nil
+		if err := l.reload(); err != nil {
+			logrus.Errorf("Could not reload the TLS files, keeping the ones loaded before: %v", err)
+			continue
+		}
+		logrus.Infof("Reloaded the TLS files of %s", l.Addr())
+	}
+}
+
+// rejectRevoked returns a handler rejecting the requests of the clients
+// whose certificate is revoked by the CRL of l, if l is a TLS listener, and
+// handing the others to h.
+func rejectRevoked(l net.Listener, h http.Handler) http.Handler {
+	tl, ok := l.(*tlsListener)
+	if !ok || tl.files.CRL == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 && tl.isRevoked(r.TLS.PeerCertificates[0]) {
+			logrus.Warnf("Rejecting %s %s from %s: the client certificate is revoked", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("Connection", "close")
+			http.Error(w, "The client certificate is revoked", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// loadCRL returns the serial numbers of the certificates revoked by the
+// certificate revocation lists of the file name, PEM or DER encoded, by raw
+// subject of their issuer. Each list must be signed by one of cas.
+func loadCRL(name string, cas []*x509.Certificate) (map[string]map[string]bool, error) {
+	file, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read CRL: %v", err)
+	}
+	var ders [][]byte
+	for rest := file; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type == "X509 CRL" {
+			ders = append(ders, block.Bytes)
+		}
+	}
+	if len(ders) == 0 {
+		ders = [][]byte{file}
+	}
+
+	revoked := make(map[string]map[string]bool)
+	for _, der := range ders {
+		crl, err := x509.ParseDERCRL(der)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse CRL %s: %v", name, err)
+		}
+		issuer, err := crlIssuer(crl, cas)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CRL %s: %v", name, err)
+		}
+		if crl.HasExpired(time.Now()) {
+			logrus.Warnf("The CRL %s of %s has expired, it should be renewed", name, issuer.Subject.CommonName)
+		}
+		serials := revoked[string(issuer.RawSubject)]
+		if serials == nil {
+			serials = make(map[string]bool)
+			revoked[string(issuer.RawSubject)] = serials
+		}
+		for _, cert := range crl.TBSCertList.RevokedCertificates {
+			serials[cert.SerialNumber.String()] = true
+		}
+	}
+	return revoked, nil
+}
+
+// crlIssuer returns the certificate of cas which signed crl.
+func crlIssuer(crl *pkix.CertificateList, cas []*x509.Certificate) (*x509.Certificate, error) {
+	for _, ca := range cas {
+		if ca.CheckCRLSignature(crl) == nil {
+			return ca, nil
+		}
+	}
+	return nil, fmt.Errorf("not signed by the CA")
+}
+
+// parseCertificates returns the certificates of the PEM file.
+func parseCertificates(file []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for rest := file; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
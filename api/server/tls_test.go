@@ -0,0 +1,195 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCert returns a certificate of serial signed by parent, or
+// self-signed if parent is nil.
+func newTestCert(t *testing.T, serial int64, name string, parent *testCert) *testCert {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCert) keyPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(c.key)})
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.pem, c.keyPEM())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// crlPEM returns the CRL of ca revoking the certificates revoked.
+func (ca *testCert) crlPEM(t *testing.T, revoked ...*testCert) []byte {
+	var list []pkix.RevokedCertificate
+	for _, c := range revoked {
+		list = append(list, pkix.RevokedCertificate{SerialNumber: c.cert.SerialNumber, RevocationTime: time.Now()})
+	}
+	der, err := ca.cert.CreateCRL(rand.Reader, ca.key, list, time.Now(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	// write then rename, as the files are usually rotated
+	if err := ioutil.WriteFile(name+".tmp", data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(name+".tmp", name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTlsListenerReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-tls-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, 1, "ca", nil)
+	server := newTestCert(t, 2, "server", ca)
+	alice := newTestCert(t, 3, "alice", ca)
+	bob := newTestCert(t, 4, "bob", ca)
+
+	config := &tlsConfig{
+		CA:          filepath.Join(dir, "ca.pem"),
+		Certificate: filepath.Join(dir, "cert.pem"),
+		Key:         filepath.Join(dir, "key.pem"),
+		CRL:         filepath.Join(dir, "crl.pem"),
+		Verify:      true,
+	}
+	writeFile(t, config.CA, ca.pem)
+	writeFile(t, config.Certificate, server.pem)
+	writeFile(t, config.Key, server.keyPEM())
+	writeFile(t, config.CRL, ca.crlPEM(t, bob))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l, err = setupTls(l, config); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go http.Serve(l, rejectRevoked(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	// get returns the status of a request of client, and the serial number
+	// of the certificate of the server.
+	get := func(client *testCert) (int, int64) {
+		c := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:      roots,
+				Certificates: []tls.Certificate{client.tlsCertificate(t)},
+			},
+			DisableKeepAlives: true,
+		}}
+		resp, err := c.Get("https://" + l.Addr().String() + "/info")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode, resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	if status, serial := get(alice); status != http.StatusOK || serial != 2 {
+		t.Fatalf("Expected alice to be allowed by the server 2, got %d from %d", status, serial)
+	}
+	if status, _ := get(bob); status != http.StatusUnauthorized {
+		t.Fatalf("Expected the revoked bob to be rejected, got %d", status)
+	}
+
+	// rotating the certificate of the server
+	server = newTestCert(t, 5, "server", ca)
+	writeFile(t, config.Certificate, server.pem)
+	writeFile(t, config.Key, server.keyPEM())
+	if err := l.(*tlsListener).reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, serial := get(alice); serial != 5 {
+		t.Fatalf("Expected the reloaded certificate 5, got %d", serial)
+	}
+
+	// invalid files keep the ones loaded before
+	writeFile(t, config.Key, []byte("invalid"))
+	if err := l.(*tlsListener).reload(); err == nil {
+		t.Fatal("Expected an invalid key to fail to reload")
+	}
+	if status, serial := get(alice); status != http.StatusOK || serial != 5 {
+		t.Fatalf("Expected the certificate loaded before, got %d from %d", status, serial)
+	}
+	writeFile(t, config.Key, server.keyPEM())
+
+	other := newTestCert(t, 1, "other", nil)
+	writeFile(t, config.CRL, other.crlPEM(t, alice))
+	if err := l.(*tlsListener).reload(); err == nil {
+		t.Fatal("Expected a CRL of another CA to fail to reload")
+	}
+
+	// revoking alice, reloaded as the CRL changes
+	writeFile(t, config.CRL, ca.crlPEM(t, alice, bob))
+	for start := time.Now(); ; time.Sleep(100 * time.Millisecond) {
+		status, _ := get(alice)
+		if status == http.StatusUnauthorized {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("Expected alice to be rejected once revoked, got %d", status)
+		}
+	}
+}
@@ -217,4 +217,148 @@ type ContainerJSON struct {
 	AppArmorProfile string
 	ExecIDs         []string
 	HostConfig      *runconfig.HostConfig
+	SizeRw          *int64 `json:",omitempty"` // Only computed on request
+	SizeRootFs      *int64 `json:",omitempty"`
+}
+
+// POST /volumes/create
+type VolumeCreate struct {
+	Name   string
+	Driver string
+	// DriverOpts are the options passed to the driver.
+	DriverOpts map[string]string
+	Labels     map[string]string
+}
+
+// GET "/volumes/json" and "/volumes/{name:.*}/json"
+type Volume struct {
+	Name   string
+	Driver string
+	// Mountpoint is the path of the volume on the host.
+	Mountpoint string
+	// Options are the options of the driver the volume was created with.
+	Options map[string]string
+	Labels  map[string]string
+	// Containers are the IDs of the containers using the volume.
+	Containers []string
+}
+
+// POST /volumes/{name:.*}/import
+type VolumeImportResponse struct {
+	// Checksum is the tarsum of the imported archive.
+	Checksum string
+}
+
+// GET "/system/df"
+type DiskUsage struct {
+	Images     DiskUsageSummary
+	Containers DiskUsageSummary
+	// Volumes are the volumes stored on the host by the local driver, the
+	// size of the volumes of volume driver plugins is not known.
+	Volumes DiskUsageSummary
+}
+
+// DiskUsageSummary is the disk space used by the images, the containers or
+// the volumes of the daemon.
+type DiskUsageSummary struct {
+	// TotalCount is the number of images, containers or volumes.
+	TotalCount int
+	// Active is the number of the images used by containers, of the running
+	// containers, or of the volumes used by containers.
+	Active int
+	// Size is the size in bytes of their files on the host.
+	Size int64
+	// Reclaimable is the size in bytes removing the ones not in use, and the
+	// layers of the images no container uses, would reclaim.
+	Reclaimable int64
+}
+
+// POST /volumes/prune
+type VolumesPruneReport struct {
+	// VolumesDeleted are the names of the removed volumes.
+	VolumesDeleted []string
+	// SpaceReclaimed is the size in bytes of the removed volumes stored on
+	// the host.
+	SpaceReclaimed uint64
+}
+
+// POST /secrets/create
+type SecretCreate struct {
+	Name string
+	// Data is the content of the secret, base64 encoded in JSON.
+	Data []byte
+}
+
+// POST /secrets/create
+type SecretCreateResponse struct {
+	// ID is the ID of the created secret.
+	ID string `json:"Id"`
+}
+
+// GET "/secrets/json" and "/secrets/{name:.*}/json"
+type Secret struct {
+	ID      string `json:"Id"`
+	Name    string
+	Created time.Time
+	// Size is the size of the data of the secret, which is never returned.
+	Size int
+	// Containers are the IDs of the containers using the secret.
+	Containers []string
+}
+
+// POST /networks/create
+type NetworkCreate struct {
+	Name       string
+	Driver     string
+	Subnet     string
+	Gateway    string
+	SubnetIPv6 string
+	MTU        int
+	Options    map[string]string
+}
+
+// POST /networks/create
+type NetworkCreateResponse struct {
+	// ID is the ID of the created network.
+	ID string `json:"Id"`
+}
+
+// POST /networks/{name:.*}/connect and /networks/{name:.*}/disconnect
+type NetworkConnect struct {
+	Container string
+}
+
+// GET "/networks/json" and "/networks/{name:.*}"
+type NetworkResource struct {
+	ID         string `json:"Id"`
+	Name       string
+	Driver     string
+	Scope      string
+	Subnet     string
+	Gateway    string
+	SubnetIPv6 string
+	MTU        int
+	Options    map[string]string
+	IPAM       []NetworkPool
+	Endpoints  []NetworkEndpoint
+}
+
+// NetworkPool is a subnet the daemon allocates the addresses of a network
+// from. Allocated addresses include the gateway.
+type NetworkPool struct {
+	Subnet    string
+	Gateway   string
+	Allocated int
+	Capacity  uint64
+}
+
+type NetworkEndpoint struct {
+	ID            string `json:"Id"`
+	ContainerID   string `json:"ContainerId"`
+	ContainerName string
+	Address       string
+	AddressIPv6   string
+	MacAddress    string
+	Gateway       string
+	GatewayIPv6   string
 }
@@ -27,6 +27,7 @@ import (
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/chrootarchive"
 	"github.com/docker/docker/pkg/httputils"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/docker/pkg/parsers"
@@ -333,19 +334,11 @@ func calcCopyInfo(b *Builder, cmdName string, cInfos *[]*copyInfo, origPath stri
 		}
 
 		// Calc the checksum, even if we're using the cache
-		r, err := archive.Tar(tmpFileName, archive.Uncompressed)
+		tarSum, err := tarsum.NewTarSumForPath(tmpFileName, tarsum.Version0, nil)
 		if err != nil {
 			return err
 		}
-		tarSum, err := tarsum.NewTarSum(r, true, tarsum.Version0)
-		if err != nil {
-			return err
-		}
-		if _, err := io.Copy(ioutil.Discard, tarSum); err != nil {
-			return err
-		}
 		ci.hash = tarSum.Sum(nil)
-		r.Close()
 
 		return nil
 	}
@@ -684,8 +677,16 @@ func (b *Builder) addContext(container *daemon.Container, orig, dest string, dec
 		return err
 	}
 
+	// The files are owned by the root of the container, which may be
+	// remapped to another user of the host
+	uidMaps, gidMaps := b.Daemon.IDMappings()
+	rootUID, rootGID, err := idtools.GetRootUIDGID(uidMaps, gidMaps)
+	if err != nil {
+		return err
+	}
+
 	if fi.IsDir() {
-		return copyAsDirectory(origPath, destPath, destExists)
+		return copyAsDirectory(origPath, destPath, destExists, rootUID, rootGID)
 	}
 
 	// If we are adding a remote file (or we've been told not to decompress), do not try to untar it
@@ -700,14 +701,14 @@ func (b *Builder) addContext(container *daemon.Container, orig, dest string, dec
 		}
 
 		// try to successfully untar the orig
-		if err := chrootarchive.UntarPath(origPath, tarDest); err == nil {
+		if err := untarPath(origPath, tarDest, uidMaps, gidMaps); err == nil {
 			return nil
 		} else if err != io.EOF {
 			logrus.Debugf("Couldn't untar %s to %s: %s", origPath, tarDest, err)
 		}
 	}
 
-	if err := os.MkdirAll(path.Dir(destPath), 0755); err != nil {
+	if err := idtools.MkdirAllAs(path.Dir(destPath), 0755, rootUID, rootGID); err != nil {
 		return err
 	}
 	if err := chrootarchive.CopyWithTar(origPath, destPath); err != nil {
@@ -719,14 +720,25 @@ func (b *Builder) addContext(container *daemon.Container, orig, dest string, dec
 		resPath = path.Join(destPath, path.Base(origPath))
 	}
 
-	return fixPermissions(origPath, resPath, 0, 0, destExists)
+	return fixPermissions(origPath, resPath, rootUID, rootGID, destExists)
+}
+
+// untarPath extracts the archive src to dst like chrootarchive.UntarPath,
+// mapping the owners of its files to the ones of the host.
+func untarPath(src, dst string, uidMaps, gidMaps []idtools.IDMap) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return chrootarchive.Untar(f, dst, &archive.TarOptions{UIDMaps: uidMaps, GIDMaps: gidMaps})
 }
 
-func copyAsDirectory(source, destination string, destExisted bool) error {
+func copyAsDirectory(source, destination string, destExisted bool, uid, gid int) error {
 	if err := chrootarchive.CopyWithTar(source, destination); err != nil {
 		return err
 	}
-	return fixPermissions(source, destination, 0, 0, destExisted)
+	return fixPermissions(source, destination, uid, gid, destExisted)
 }
 
 func fixPermissions(source, destination string, uid, gid int, destExisted bool) error {
@@ -37,11 +37,26 @@ type AuthConfig struct {
 	ServerAddress string `json:"serveraddress,omitempty"`
 }
 
+// A daemon the client can connect to, selected by name with
+// docker --endpoint or DOCKER_ENDPOINT
+type Endpoint struct {
+	Host      string `json:"host"`
+	TLS       bool   `json:"tls,omitempty"`
+	TLSVerify bool   `json:"tlsverify,omitempty"`
+	CertPath  string `json:"certpath,omitempty"` // Directory of ca.pem, cert.pem and key.pem
+}
+
 // ~/.docker/config.json file info
 type ConfigFile struct {
-	AuthConfigs map[string]AuthConfig `json:"auths"`
-	HttpHeaders map[string]string     `json:"HttpHeaders,omitempty"`
-	filename    string                // Note: not serialized - for internal use only
+	AuthConfigs     map[string]AuthConfig `json:"auths"`
+	HttpHeaders     map[string]string     `json:"HttpHeaders,omitempty"`
+	PsFormat        string                `json:"psFormat,omitempty"`     // Default --format of docker ps
+	ImagesFormat    string                `json:"imagesFormat,omitempty"` // Default --format of docker images
+	Endpoints       map[string]Endpoint   `json:"endpoints,omitempty"`
+	ConnectTimeout  int                   `json:"connectTimeout,omitempty"`  // Seconds to connect to the daemon
+	ResponseTimeout int                   `json:"responseTimeout,omitempty"` // Seconds to wait for the responses of the daemon
+	Retries         int                   `json:"retries,omitempty"`         // Retries of the GET requests failing to reach the daemon
+	filename        string                // Note: not serialized - for internal use only
 }
 
 func NewConfigFile(fn string) *ConfigFile {
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -155,3 +156,63 @@ func TestNewJson(t *testing.T) {
 		t.Fatalf("Should have save in new form: %s", string(buf))
 	}
 }
+
+func TestJsonWithPsFormat(t *testing.T) {
+	tmpHome, _ := ioutil.TempDir("", "config-test")
+	fn := filepath.Join(tmpHome, CONFIGFILE)
+	js := `{
+		"auths": { "https://index.docker.io/v1/": { "auth": "am9lam9lOmhlbGxv", "email": "user@example.com" } },
+		"psFormat": "{{.ID}}\\t{{.Label \"com.docker.label.cpu\"}}",
+		"imagesFormat": "{{.Repository}}:{{.Tag}}"
+}`
+	ioutil.WriteFile(fn, []byte(js), 0600)
+
+	config, err := Load(tmpHome)
+	if err != nil {
+		t.Fatalf("Failed loading on empty json file: %q", err)
+	}
+
+	if config.PsFormat != `{{.ID}}\t{{.Label "com.docker.label.cpu"}}` {
+		t.Fatalf("Unknown ps format: %s\n", config.PsFormat)
+	}
+	if config.ImagesFormat != "{{.Repository}}:{{.Tag}}" {
+		t.Fatalf("Unknown images format: %s\n", config.ImagesFormat)
+	}
+
+	// Now save it and make sure it shows up in new form
+	if err := config.Save(); err != nil {
+		t.Fatalf("Failed to save: %q", err)
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(tmpHome, CONFIGFILE))
+	if !strings.Contains(string(buf), `"psFormat":`) ||
+		!strings.Contains(string(buf), `"imagesFormat":`) {
+		t.Fatalf("Should have save in new form: %s", string(buf))
+	}
+}
+
+func TestJsonWithEndpoints(t *testing.T) {
+	tmpHome, _ := ioutil.TempDir("", "config-test")
+	fn := filepath.Join(tmpHome, CONFIGFILE)
+	js := `{
+		"auths": {},
+		"endpoints": {
+			"local": { "host": "unix:///var/run/docker.sock" },
+			"staging": { "host": "tcp://10.0.0.5:2376", "tlsverify": true, "certpath": "/etc/docker/staging" }
+		}
+}`
+	ioutil.WriteFile(fn, []byte(js), 0600)
+
+	config, err := Load(tmpHome)
+	if err != nil {
+		t.Fatalf("Failed loading on empty json file: %q", err)
+	}
+
+	expected := map[string]Endpoint{
+		"local":   {Host: "unix:///var/run/docker.sock"},
+		"staging": {Host: "tcp://10.0.0.5:2376", TLSVerify: true, CertPath: "/etc/docker/staging"},
+	}
+	if !reflect.DeepEqual(config.Endpoints, expected) {
+		t.Fatalf("Expected endpoints %v, got %v", expected, config.Endpoints)
+	}
+}
@@ -12,6 +12,8 @@ type ContainerAttachWithLogsConfig struct {
 	UseStdin, UseStdout, UseStderr bool
 	Logs, Stream                   bool
 	Multiplex                      bool
+	// Multiplex the streams in extended frames, carrying the time of the data
+	ExtendedFrames bool
 }
 
 func (daemon *Daemon) ContainerAttachWithLogs(name string, c *ContainerAttachWithLogsConfig) error {
@@ -22,7 +24,10 @@ func (daemon *Daemon) ContainerAttachWithLogs(name string, c *ContainerAttachWit
 
 	var errStream io.Writer
 
-	if !container.Config.Tty && c.Multiplex {
+	if !container.Config.Tty && c.Multiplex && c.ExtendedFrames {
+		errStream = stdcopy.NewExtendedStdWriter(c.OutStream, stdcopy.Stderr, true, "")
+		c.OutStream = stdcopy.NewExtendedStdWriter(c.OutStream, stdcopy.Stdout, true, "")
+	} else if !container.Config.Tty && c.Multiplex {
 		errStream = stdcopy.NewStdWriter(c.OutStream, stdcopy.Stderr)
 		c.OutStream = stdcopy.NewStdWriter(c.OutStream, stdcopy.Stdout)
 	} else {
@@ -26,10 +26,14 @@ type Config struct {
 	AutoRestart          bool
 	Dns                  []string
 	DnsSearch            []string
+	DnsOptions           []string
 	GraphDriver          string
 	GraphOptions         []string
 	ExecDriver           string
 	ExecOptions          []string
+	RemappedRoot         string
+	Rootless             bool
+	NoNewPrivileges      bool
 	Mtu                  int
 	SocketGroup          string
 	EnableCors           bool
@@ -41,6 +45,11 @@ type Config struct {
 	Labels               []string
 	Ulimits              map[string]*ulimit.Ulimit
 	LogConfig            runconfig.LogConfig
+	KVStore              string
+	ClusterAdvertise     string
+	AuthorizationPlugins []string
+	TlsCrl               string
+	ImagePolicy          string
 }
 
 // InstallFlags adds command-line options to the top-level flag parser for
@@ -64,6 +73,9 @@ func (config *Config) InstallFlags() {
 	flag.BoolVar(&config.Bridge.InterContainerCommunication, []string{"#icc", "-icc"}, true, "Enable inter-container communication")
 	flag.StringVar(&config.GraphDriver, []string{"s", "-storage-driver"}, "", "Storage driver to use")
 	flag.StringVar(&config.ExecDriver, []string{"e", "-exec-driver"}, "native", "Exec driver to use")
+	flag.StringVar(&config.RemappedRoot, []string{"-userns-remap"}, "", "User/Group setting for user namespaces (USER[:GROUP])")
+	flag.BoolVar(&config.Rootless, []string{"-rootless"}, false, "Run the daemon as an unprivileged user")
+	flag.BoolVar(&config.NoNewPrivileges, []string{"-no-new-privileges"}, false, "Set no-new-privileges on containers by default")
 	flag.BoolVar(&config.EnableSelinuxSupport, []string{"-selinux-enabled"}, false, "Enable selinux support")
 	flag.IntVar(&config.Mtu, []string{"#mtu", "-mtu"}, 0, "Set the containers network MTU")
 	flag.StringVar(&config.SocketGroup, []string{"G", "-group"}, "docker", "Group for the unix socket")
@@ -72,15 +84,23 @@ func (config *Config) InstallFlags() {
 	opts.IPVar(&config.Bridge.DefaultIp, []string{"#ip", "-ip"}, "0.0.0.0", "Default IP when binding container ports")
 	opts.ListVar(&config.GraphOptions, []string{"-storage-opt"}, "Set storage driver options")
 	opts.ListVar(&config.ExecOptions, []string{"-exec-opt"}, "Set exec driver options")
+	opts.ListVar(&config.AuthorizationPlugins, []string{"-authorization-plugin"}, "Authorization plugins to load")
+	flag.StringVar(&config.TlsCrl, []string{"-tlscrl"}, "", "Reject the client certificates revoked by this CRL")
+	flag.StringVar(&config.ImagePolicy, []string{"-image-policy"}, "", "Policy file of the images to pull and run")
 	// FIXME: why the inconsistency between "hosts" and "sockets"?
 	opts.IPListVar(&config.Dns, []string{"#dns", "-dns"}, "DNS server to use")
 	opts.DnsSearchListVar(&config.DnsSearch, []string{"-dns-search"}, "DNS search domains to use")
+	opts.DnsOptionListVar(&config.DnsOptions, []string{"-dns-opt"}, "DNS options to use")
 	opts.LabelListVar(&config.Labels, []string{"-label"}, "Set key=value labels to the daemon")
 	config.Ulimits = make(map[string]*ulimit.Ulimit)
 	opts.UlimitMapVar(config.Ulimits, []string{"-default-ulimit"}, "Set default ulimits for containers")
 	flag.StringVar(&config.LogConfig.Type, []string{"-log-driver"}, "json-file", "Default driver for container logs")
 	flag.BoolVar(&config.Bridge.EnableUserlandProxy, []string{"-userland-proxy"}, true, "Use userland proxy for loopback traffic")
+	flag.BoolVar(&config.Bridge.EnableNATReflection, []string{"-nat-reflection"}, false, "Let containers reach published ports through the host addresses")
+	flag.StringVar(&config.Bridge.PortRange, []string{"-port-range"}, "", "Range of host ports to publish container ports on when none is given (e.g. 30000-40000)")
 	opts.LogOptsVar(config.LogConfig.Config, []string{"-log-opt"}, "Set log driver options")
+	flag.StringVar(&config.KVStore, []string{"-kv-store"}, "", "Key-value store shared with the other daemons of the cluster")
+	flag.StringVar(&config.ClusterAdvertise, []string{"-cluster-advertise"}, "", "Address the other daemons of the cluster reach this one at")
 }
 
 func getDefaultNetworkMtu() int {
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -22,9 +23,11 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/execdriver"
 	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/cache"
 	"github.com/docker/docker/daemon/logger/jsonfilelog"
 	"github.com/docker/docker/daemon/network"
 	"github.com/docker/docker/daemon/networkdriver/bridge"
+	"github.com/docker/docker/daemon/networkdriver/portallocator"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/links"
 	"github.com/docker/docker/nat"
@@ -32,6 +35,7 @@ import (
 	"github.com/docker/docker/pkg/broadcastwriter"
 	"github.com/docker/docker/pkg/directory"
 	"github.com/docker/docker/pkg/etchosts"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/jsonlog"
 	"github.com/docker/docker/pkg/promise"
@@ -41,6 +45,7 @@ import (
 	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/runconfig"
 	"github.com/docker/docker/utils"
+	"github.com/docker/docker/volumes"
 )
 
 const DefaultPathEnv = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
@@ -90,6 +95,9 @@ type Container struct {
 	daemon                   *Daemon
 	MountLabel, ProcessLabel string
 	AppArmorProfile          string
+	SeccompProfile           string
+	SeccompAudit             bool
+	NoNewPrivileges          bool
 	RestartCount             int
 	UpdateDns                bool
 
@@ -109,6 +117,9 @@ type Container struct {
 	logDriver          logger.Logger
 	logCopier          *logger.Copier
 	AppliedVolumesFrom map[string]struct{}
+	// mountedVolumes are the volumes of volume drivers mounted for the
+	// container while it runs
+	mountedVolumes []*volumes.Volume
 }
 
 func (container *Container) FromDisk() error {
@@ -317,7 +328,10 @@ func populateCommand(c *Container, env []string) error {
 		}
 		en.ContainerID = nc.ID
 	default:
-		return fmt.Errorf("invalid network mode: %s", c.hostConfig.NetworkMode)
+		if !c.hostConfig.NetworkMode.IsUserDefined() {
+			return fmt.Errorf("invalid network mode: %s", c.hostConfig.NetworkMode)
+		}
+		en.NamespacePath = c.NetworkSettings.SandboxKey
 	}
 
 	ipc := &execdriver.Ipc{}
@@ -409,6 +423,7 @@ func populateCommand(c *Container, env []string) error {
 		ID:                 c.ID,
 		Rootfs:             c.RootfsPath(),
 		ReadonlyRootfs:     c.hostConfig.ReadonlyRootfs,
+		Tmpfs:              c.hostConfig.TmpfsMounts(),
 		InitPath:           "/.dockerinit",
 		WorkingDir:         c.Config.WorkingDir,
 		Network:            en,
@@ -425,8 +440,15 @@ func populateCommand(c *Container, env []string) error {
 		MountLabel:         c.GetMountLabel(),
 		LxcConfig:          lxcConfig,
 		AppArmorProfile:    c.AppArmorProfile,
+		SeccompProfile:     c.SeccompProfile,
+		SeccompAudit:       c.SeccompAudit,
+		NoNewPrivileges:    c.NoNewPrivileges,
 		CgroupParent:       c.hostConfig.CgroupParent,
 	}
+	if c.daemon.usernsRemapped(c.hostConfig) {
+		c.command.UIDMapping = c.daemon.uidMaps
+		c.command.GIDMapping = c.daemon.gidMaps
+	}
 
 	return nil
 }
@@ -473,6 +495,9 @@ func (container *Container) Start() (err error) {
 	if err := container.prepareVolumes(); err != nil {
 		return err
 	}
+	if err := container.mountDriverVolumes(); err != nil {
+		return err
+	}
 	linkedEnv, err := container.setupLinkedContainers()
 	if err != nil {
 		return err
@@ -480,6 +505,9 @@ func (container *Container) Start() (err error) {
 	if err := container.setupWorkingDirectory(); err != nil {
 		return err
 	}
+	if err := container.setupSecrets(); err != nil {
+		return err
+	}
 	env := container.createDaemonEnvironment(linkedEnv)
 	if err := populateCommand(container, env); err != nil {
 		return err
@@ -534,18 +562,6 @@ func (streamConfig *StreamConfig) StderrPipe() io.ReadCloser {
 	return ioutils.NewBufReader(reader)
 }
 
-func (streamConfig *StreamConfig) StdoutLogPipe() io.ReadCloser {
-	reader, writer := io.Pipe()
-	streamConfig.stdout.AddWriter(writer, "stdout")
-	return ioutils.NewBufReader(reader)
-}
-
-func (streamConfig *StreamConfig) StderrLogPipe() io.ReadCloser {
-	reader, writer := io.Pipe()
-	streamConfig.stderr.AddWriter(writer, "stderr")
-	return ioutils.NewBufReader(reader)
-}
-
 func (container *Container) buildHostnameFile() error {
 	hostnamePath, err := container.GetRootResourcePath("hostname")
 	if err != nil {
@@ -585,6 +601,8 @@ func (container *Container) buildHostsFiles(IP string) error {
 		extraContent = append(extraContent, etchosts.Record{Hosts: aliasList, IP: child.NetworkSettings.IPAddress})
 	}
 
+	extraContent = append(extraContent, container.networkHosts()...)
+
 	for _, extraHost := range container.hostConfig.ExtraHosts {
 		// allow IPv6 addresses in extra hosts; only split on first ":"
 		parts := strings.SplitN(extraHost, ":", 2)
@@ -607,6 +625,9 @@ func (container *Container) AllocateNetwork() error {
 	if container.Config.NetworkDisabled || !mode.IsPrivate() {
 		return nil
 	}
+	if mode.IsUserDefined() {
+		return container.allocateUserNetwork()
+	}
 
 	var err error
 
@@ -647,6 +668,7 @@ func (container *Container) AllocateNetwork() error {
 				bindings[p] = append(bindings[p], nat.PortBinding{
 					HostIp:   bb.HostIp,
 					HostPort: bb.HostPort,
+					NoProxy:  bb.NoProxy,
 				})
 			}
 		}
@@ -661,8 +683,23 @@ func (container *Container) AllocateNetwork() error {
 		i++
 	}
 	nat.SortPortMap(ports, bindings)
+	var (
+		ranges    = dynamicPortRanges(ports, bindings, container.hostConfig.PublishAllPorts)
+		allocated = make(map[nat.Port]bool)
+	)
 	for _, port := range ports {
-		if err = container.allocatePort(port, bindings); err != nil {
+		if allocated[port] {
+			continue
+		}
+		if r, ok := ranges[port]; ok {
+			err = container.allocatePortRange(r, bindings)
+			for _, p := range r {
+				allocated[p] = true
+			}
+		} else {
+			err = container.allocatePort(port, bindings)
+		}
+		if err != nil {
 			bridge.Release(container.ID)
 			return err
 		}
@@ -680,7 +717,12 @@ func (container *Container) ReleaseNetwork() {
 		return
 	}
 
-	bridge.Release(container.ID)
+	container.releaseConnectedNetworks()
+	if container.hostConfig.NetworkMode.IsUserDefined() {
+		container.releaseUserNetwork()
+	} else {
+		bridge.Release(container.ID)
+	}
 
 	container.NetworkSettings = &network.Settings{}
 }
@@ -697,6 +739,12 @@ func (container *Container) RestoreNetwork() error {
 	if !container.isNetworkAllocated() || container.Config.NetworkDisabled || !mode.IsPrivate() {
 		return nil
 	}
+	if err := container.restoreConnectedNetworks(); err != nil {
+		return err
+	}
+	if mode.IsUserDefined() {
+		return container.restoreUserNetwork()
+	}
 
 	// Re-allocate the interface with the same IP and MAC address.
 	if _, err := bridge.Allocate(container.ID, container.NetworkSettings.MacAddress, container.NetworkSettings.IPAddress, ""); err != nil {
@@ -724,6 +772,9 @@ func (container *Container) cleanup() {
 		}
 	}
 
+	container.unmountDriverVolumes()
+	container.unmountSecrets()
+
 	if err := container.Unmount(); err != nil {
 		logrus.Errorf("%v: Failed to umount filesystem: %v", container.ID, err)
 	}
@@ -912,7 +963,12 @@ func (container *Container) Export() (archive.Archive, error) {
 		return nil, err
 	}
 
-	archive, err := archive.Tar(container.basefs, archive.Uncompressed)
+	uidMaps, gidMaps := container.daemon.idMappings(container.hostConfig)
+	archive, err := archive.TarWithOptions(container.basefs, &archive.TarOptions{
+		Compression: archive.Uncompressed,
+		UIDMaps:     uidMaps,
+		GIDMaps:     gidMaps,
+	})
 	if err != nil {
 		container.Unmount()
 		return nil, err
@@ -1044,9 +1100,12 @@ func (container *Container) Copy(resource string) (io.ReadCloser, error) {
 		basePath = path.Dir(basePath)
 	}
 
+	uidMaps, gidMaps := container.daemon.idMappings(container.hostConfig)
 	archive, err := archive.TarWithOptions(basePath, &archive.TarOptions{
 		Compression:  archive.Uncompressed,
 		IncludeFiles: filter,
+		UIDMaps:      uidMaps,
+		GIDMaps:      gidMaps,
 	})
 	if err != nil {
 		return nil, err
@@ -1089,22 +1148,10 @@ func (container *Container) setupContainerDns() error {
 	if container.ResolvConfPath != "" {
 		// check if this is an existing container that needs DNS update:
 		if container.UpdateDns {
-			// read the host's resolv.conf, get the hash and call updateResolvConf
+			// read the host's resolv.conf and call updateResolvConf
 			logrus.Debugf("Check container (%s) for update to resolv.conf - UpdateDns flag was set", container.ID)
-			latestResolvConf, latestHash := resolvconf.GetLastModified()
-
-			// clean container resolv.conf re: localhost nameservers and IPv6 NS (if IPv6 disabled)
-			updatedResolvConf, modified := resolvconf.FilterResolvDns(latestResolvConf, container.daemon.config.Bridge.EnableIPv6)
-			if modified {
-				// changes have occurred during resolv.conf localhost cleanup: generate an updated hash
-				newHash, err := ioutils.HashData(bytes.NewReader(updatedResolvConf))
-				if err != nil {
-					return err
-				}
-				latestHash = newHash
-			}
-
-			if err := container.updateResolvConf(updatedResolvConf, latestHash); err != nil {
+			latestResolvConf, _ := resolvconf.GetLastModified()
+			if err := container.updateResolvConf(latestResolvConf); err != nil {
 				return err
 			}
 			// successful update of the restarting container; set the flag off
@@ -1113,12 +1160,7 @@ func (container *Container) setupContainerDns() error {
 		return nil
 	}
 
-	var (
-		config = container.hostConfig
-		daemon = container.daemon
-	)
-
-	resolvConf, err := resolvconf.Get()
+	hostResolvConf, err := resolvconf.Get()
 	if err != nil {
 		return err
 	}
@@ -1127,29 +1169,7 @@ func (container *Container) setupContainerDns() error {
 		return err
 	}
 
-	if config.NetworkMode.IsBridge() || config.NetworkMode.IsNone() {
-		// check configurations for any container/daemon dns settings
-		if len(config.Dns) > 0 || len(daemon.config.Dns) > 0 || len(config.DnsSearch) > 0 || len(daemon.config.DnsSearch) > 0 {
-			var (
-				dns       = resolvconf.GetNameservers(resolvConf)
-				dnsSearch = resolvconf.GetSearchDomains(resolvConf)
-			)
-			if len(config.Dns) > 0 {
-				dns = config.Dns
-			} else if len(daemon.config.Dns) > 0 {
-				dns = daemon.config.Dns
-			}
-			if len(config.DnsSearch) > 0 {
-				dnsSearch = config.DnsSearch
-			} else if len(daemon.config.DnsSearch) > 0 {
-				dnsSearch = daemon.config.DnsSearch
-			}
-			return resolvconf.Build(container.ResolvConfPath, dns, dnsSearch)
-		}
-
-		// replace any localhost/127.*, and remove IPv6 nameservers if IPv6 disabled in daemon
-		resolvConf, _ = resolvconf.FilterResolvDns(resolvConf, daemon.config.Bridge.EnableIPv6)
-	}
+	resolvConf := container.resolvConf(hostResolvConf)
 	//get a sha256 hash of the resolv conf at this point so we can check
 	//for changes when the host resolv.conf changes (e.g. network update)
 	resolvHash, err := ioutils.HashData(bytes.NewReader(resolvConf))
@@ -1163,10 +1183,56 @@ func (container *Container) setupContainerDns() error {
 	return ioutil.WriteFile(container.ResolvConfPath, resolvConf, 0644)
 }
 
+// resolvConf returns the resolv.conf of the container for the given host
+// resolv.conf. The nameservers, search domains and options of the container,
+// or else the defaults of the daemon, replace the ones of the host.
+func (container *Container) resolvConf(hostResolvConf []byte) []byte {
+	var (
+		config = container.hostConfig
+		daemon = container.daemon
+	)
+
+	if !config.NetworkMode.IsBridge() && !config.NetworkMode.IsNone() && !config.NetworkMode.IsUserDefined() {
+		return hostResolvConf
+	}
+
+	// replace any localhost/127.*, and remove IPv6 nameservers if IPv6 disabled in daemon
+	resolvConf, _ := resolvconf.FilterResolvDns(hostResolvConf, daemon.config.Bridge.EnableIPv6)
+
+	// check configurations for any container/daemon dns settings
+	var (
+		dns        = dnsSetting(config.Dns, daemon.config.Dns)
+		dnsSearch  = dnsSetting(config.DnsSearch, daemon.config.DnsSearch)
+		dnsOptions = dnsSetting(config.DnsOptions, daemon.config.DnsOptions)
+	)
+	if len(dns) == 0 && len(dnsSearch) == 0 && len(dnsOptions) == 0 {
+		return resolvConf
+	}
+	if len(dns) == 0 {
+		dns = resolvconf.GetNameservers(resolvConf)
+	}
+	if len(dnsSearch) == 0 {
+		dnsSearch = resolvconf.GetSearchDomains(resolvConf)
+	}
+	if len(dnsOptions) == 0 {
+		dnsOptions = resolvconf.GetOptions(resolvConf)
+	}
+	return resolvconf.Generate(dns, dnsSearch, dnsOptions)
+}
+
+// dnsSetting returns the DNS setting of the container, or the default of the
+// daemon when the container has none.
+func dnsSetting(container, daemon []string) []string {
+	if len(container) > 0 {
+		return container
+	}
+	return daemon
+}
+
 // called when the host's resolv.conf changes to check whether container's resolv.conf
 // is unchanged by the container "user" since container start: if unchanged, the
-// container's resolv.conf will be updated to match the host's new resolv.conf
-func (container *Container) updateResolvConf(updatedResolvConf []byte, newResolvHash string) error {
+// container's resolv.conf will be regenerated from the host's new resolv.conf
+func (container *Container) updateResolvConf(hostResolvConf []byte) error {
 
 	if container.ResolvConfPath == "" {
 		return nil
@@ -1177,6 +1243,12 @@ func (container *Container) updateResolvConf(updatedResolvConf []byte, newResolv
 		return nil
 	}
 
+	updatedResolvConf := container.resolvConf(hostResolvConf)
+	newResolvHash, err := ioutils.HashData(bytes.NewReader(updatedResolvConf))
+	if err != nil {
+		return err
+	}
+
 	resolvHashFile := container.ResolvConfPath + ".hash"
 
 	//read the container's current resolv.conf and compute the hash
@@ -1206,7 +1278,7 @@ func (container *Container) updateResolvConf(updatedResolvConf []byte, newResolv
 	//if the user has not modified the resolv.conf of the container since we wrote it last
 	//we will replace it with the updated resolv.conf from the host
 	if string(hashBytes) == curHash {
-		logrus.Debugf("replacing %q with updated host resolv.conf", container.ResolvConfPath)
+		logrus.Debugf("replacing %q with updated resolv.conf", container.ResolvConfPath)
 
 		// for atomic updates to these files, use temporary files with os.Rename:
 		dir := path.Dir(container.ResolvConfPath)
@@ -1425,7 +1497,11 @@ func (container *Container) setupWorkingDirectory() error {
 				return err
 			}
 
-			if err := os.MkdirAll(pth, 0755); err != nil {
+			rootUID, rootGID, err := idtools.GetRootUIDGID(container.daemon.idMappings(container.hostConfig))
+			if err != nil {
+				return err
+			}
+			if err := idtools.MkdirAllAs(pth, 0755, rootUID, rootGID); err != nil {
 				return err
 			}
 		}
@@ -1445,16 +1521,17 @@ func (container *Container) getLogConfig() runconfig.LogConfig {
 	return container.daemon.defaultLogConfig
 }
 
+// getLogger creates the logging driver of the container. The logs of the
+// drivers which cannot read them back are also written to a local cache,
+// unless it is disabled.
 func (container *Container) getLogger() (logger.Logger, error) {
 	cfg := container.getLogConfig()
-	c, err := logger.GetLogDriver(cfg.Type)
+	c, err := lookupLogDriver(cfg.Type)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get logging factory: %v", err)
 	}
-	ctx := logger.Context{
-		ContainerID:   container.ID,
-		ContainerName: container.Name,
-	}
+	cacheOpts, driverOpts := cache.SplitLogOpts(cfg.Config)
+	ctx := container.loggerContext(driverOpts)
 
 	// Set logging file for "json-logger"
 	if cfg.Type == jsonfilelog.Name {
@@ -1463,7 +1540,70 @@ func (container *Container) getLogger() (logger.Logger, error) {
 			return nil, err
 		}
 	}
-	return c(ctx)
+	l, err := c(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := l.(logger.LogReader); ok || !cache.Enabled(cacheOpts) {
+		return l, nil
+	}
+
+	path, err := container.logCachePath()
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	lc, err := cache.WithLocalCache(l, ctx, path, cacheOpts)
+	if err != nil {
+		l.Close()
+		return nil, fmt.Errorf("Failed to open the local log cache: %v", err)
+	}
+	return lc, nil
+}
+
+// getLogReader returns a logger reading back the logs of the container, from
+// the local cache of its logs when there is one, without creating a logging
+// driver sending logs to a remote service.
+func (container *Container) getLogReader() (logger.Logger, error) {
+	cacheOpts, _ := cache.SplitLogOpts(container.getLogConfig().Config)
+	if cache.Enabled(cacheOpts) {
+		path, err := container.logCachePath()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); err == nil {
+			return cache.Open(container.loggerContext(nil), path, cacheOpts)
+		}
+	}
+	return container.getLogger()
+}
+
+// liveLogReader returns the logger of the running container, when it reads
+// the logs back, for following them: it tells its readers when it is closed.
+func (container *Container) liveLogReader() (logger.LogReader, bool) {
+	container.Lock()
+	defer container.Unlock()
+	if !container.Running {
+		return nil, false
+	}
+	reader, ok := container.logDriver.(logger.LogReader)
+	return reader, ok
+}
+
+func (container *Container) loggerContext(config map[string]string) logger.Context {
+	return logger.Context{
+		ContainerID:        container.ID,
+		ContainerName:      container.Name,
+		ContainerImageID:   container.ImageID,
+		ContainerImageName: container.Config.Image,
+		ContainerLabels:    container.Config.Labels,
+		ContainerEnv:       container.Config.Env,
+		Config:             config,
+	}
+}
+
+func (container *Container) logCachePath() (string, error) {
+	return container.GetRootResourcePath(fmt.Sprintf("%s-cache.log", container.ID))
 }
 
 func (container *Container) startLogging() error {
@@ -1516,7 +1656,7 @@ func (container *Container) allocatePort(port nat.Port, bindings nat.PortMap) er
 	for i := 0; i < len(binding); i++ {
 		b, err := bridge.AllocatePort(container.ID, port, binding[i])
 		if err != nil {
-			return err
+			return container.portConflict(port.Proto(), err)
 		}
 		binding[i] = b
 	}
@@ -1524,6 +1664,116 @@ func (container *Container) allocatePort(port nat.Port, bindings nat.PortMap) er
 	return nil
 }
 
+// allocatePortRange publishes a run of consecutive container ports on a
+// contiguous block of host ports.
+func (container *Container) allocatePortRange(ports []nat.Port, bindings nat.PortMap) error {
+	var binding nat.PortBinding
+	if b := bindings[ports[0]]; len(b) > 0 {
+		binding = b[0]
+	}
+
+	b, err := bridge.AllocatePortRange(container.ID, ports, binding)
+	if err != nil {
+		return container.portConflict(ports[0].Proto(), err)
+	}
+	for i, port := range ports {
+		bindings[port] = []nat.PortBinding{b[i]}
+	}
+	return nil
+}
+
+// portConflict names the container which published the host port a port
+// allocation failed on, if any.
+func (container *Container) portConflict(proto string, err error) error {
+	e, ok := err.(portallocator.ErrPortAlreadyAllocated)
+	if !ok {
+		return err
+	}
+	hostPort := strconv.Itoa(e.Port())
+	for _, c := range container.daemon.List() {
+		if c.ID == container.ID || c.NetworkSettings == nil {
+			continue
+		}
+		for port, bindings := range c.NetworkSettings.Ports {
+			if port.Proto() != proto {
+				continue
+			}
+			for _, b := range bindings {
+				if b.HostIp == e.IP() && b.HostPort == hostPort {
+					return fmt.Errorf("Bind for %s failed: port is already allocated to container %s", e.IPPort(), c.Name[1:])
+				}
+			}
+		}
+	}
+	return err
+}
+
+// portStatus returns the state of the forwarding of the published ports of
+// the container.
+func (container *Container) portStatus() map[nat.Port][]network.PortStatus {
+	status := make(map[nat.Port][]network.PortStatus)
+	for port, bindings := range container.NetworkSettings.Ports {
+		for _, b := range bindings {
+			s, err := bridge.PortStatus(port.Proto(), b)
+			if err != nil {
+				logrus.Debugf("Error getting the status of port %s of %s: %v", port, container.ID, err)
+				continue
+			}
+			status[port] = append(status[port], s)
+		}
+	}
+	return status
+}
+
+// dynamicPortRanges finds runs of consecutive ports of the same protocol which
+// are published on a random host port of the same host ip, such as the ones
+// produced by `-p 8000-8100`. Each port of a run maps to the whole run so that
+// the run can be allocated as a single contiguous block on the host.
+func dynamicPortRanges(ports []nat.Port, bindings nat.PortMap, publishAll bool) map[nat.Port][]nat.Port {
+	groups := make(map[string][]nat.Port)
+	for _, port := range ports {
+		var (
+			hostIP  string
+			noProxy bool
+		)
+		switch b := bindings[port]; len(b) {
+		case 0:
+			if !publishAll {
+				continue
+			}
+		case 1:
+			if b[0].HostPort != "" {
+				continue
+			}
+			hostIP, noProxy = b[0].HostIp, b[0].NoProxy
+		default:
+			continue
+		}
+		key := fmt.Sprintf("%s/%s/%t", port.Proto(), hostIP, noProxy)
+		groups[key] = append(groups[key], port)
+	}
+
+	ranges := make(map[nat.Port][]nat.Port)
+	for _, group := range groups {
+		nat.Sort(group, func(ip, jp nat.Port) bool {
+			return ip.Int() < jp.Int()
+		})
+		for start := 0; start < len(group); {
+			end := start + 1
+			for end < len(group) && group[end].Int() == group[end-1].Int()+1 {
+				end++
+			}
+			if end-start > 1 {
+				for _, port := range group[start:end] {
+					ranges[port] = group[start:end]
+				}
+			}
+			start = end
+		}
+	}
+	return ranges
+}
+
 func (container *Container) GetProcessLabel() string {
 	// even if we have a process label return "" if we are running
 	// in privileged mode
@@ -1661,16 +1911,27 @@ func (c *Container) Attach(stdin io.ReadCloser, stdout io.Writer, stderr io.Writ
 	return attach(&c.StreamConfig, c.Config.OpenStdin, c.Config.StdinOnce, c.Config.Tty, stdin, stdout, stderr)
 }
 
+// readLogs reads back all the logs of the container from its logging driver.
+func (c *Container) readLogs() (io.ReadCloser, error) {
+	logDriver, err := c.getLogReader()
+	if err != nil {
+		return nil, err
+	}
+	defer logDriver.Close()
+	reader, ok := logDriver.(logger.LogReader)
+	if !ok {
+		return nil, fmt.Errorf("Reading logs not implemented for driver %s", c.LogDriverType())
+	}
+	return reader.ReadLogs(logger.ReadConfig{Tail: -1})
+}
+
 func (c *Container) AttachWithLogs(stdin io.ReadCloser, stdout, stderr io.Writer, logs, stream bool) error {
 	if logs {
-		logDriver, err := c.getLogger()
-		cLog, err := logDriver.GetReader()
-
+		cLog, err := c.readLogs()
 		if err != nil {
 			logrus.Errorf("Error reading logs: %s", err)
-		} else if c.LogDriverType() != jsonfilelog.Name {
-			logrus.Errorf("Reading logs not implemented for driver %s", c.LogDriverType())
 		} else {
+			defer cLog.Close()
 			dec := json.NewDecoder(cLog)
 			for {
 				l := &jsonlog.JSONLog{}
@@ -1682,10 +1943,10 @@ func (c *Container) AttachWithLogs(stdin io.ReadCloser, stdout, stderr io.Writer
 					break
 				}
 				if l.Stream == "stdout" && stdout != nil {
-					io.WriteString(stdout, l.Log)
+					writeLogString(stdout, l.Log, l.Created)
 				}
 				if l.Stream == "stderr" && stderr != nil {
-					io.WriteString(stderr, l.Log)
+					writeLogString(stderr, l.Log, l.Created)
 				}
 			}
 		}
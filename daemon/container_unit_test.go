@@ -1,8 +1,13 @@
 package daemon
 
 import (
-	"github.com/docker/docker/nat"
+	"reflect"
 	"testing"
+
+	"github.com/docker/docker/daemon/network"
+	"github.com/docker/docker/daemon/networkdriver/portallocator"
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/runconfig"
 )
 
 func TestParseNetworkOptsPrivateOnly(t *testing.T) {
@@ -166,6 +171,41 @@ func TestParseNetworkOptsUdp(t *testing.T) {
 	}
 }
 
+func TestDynamicPortRanges(t *testing.T) {
+	_, bindings, err := nat.ParsePortSpecs([]string{"5000-5002/udp", "127.0.0.1::5003/udp", "6000-6001:6000-6001", "7000", "7002"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports := []nat.Port{}
+	for p := range bindings {
+		ports = append(ports, p)
+	}
+
+	ranges := dynamicPortRanges(ports, bindings, false)
+	if r := ranges["5001/udp"]; len(r) != 3 || r[0] != "5000/udp" || r[2] != "5002/udp" {
+		t.Fatalf("Expected 5000-5002/udp to be a single range, got %v", r)
+	}
+	for _, p := range []nat.Port{"5003/udp", "6000/tcp", "7000/tcp", "7002/tcp"} {
+		if r, ok := ranges[p]; ok {
+			t.Fatalf("Expected %s not to be part of a range, got %v", p, r)
+		}
+	}
+
+	ranges = dynamicPortRanges([]nat.Port{"8080/tcp", "8081/tcp"}, nat.PortMap{}, true)
+	if len(ranges["8080/tcp"]) != 2 {
+		t.Fatalf("Expected exposed ports to be published as a range, got %v", ranges)
+	}
+
+	_, bindings, err = nat.ParsePortSpecs([]string{"9000,noproxy", "9001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranges = dynamicPortRanges([]nat.Port{"9000/tcp", "9001/tcp"}, bindings, false)
+	if len(ranges) != 0 {
+		t.Fatalf("Expected ports with and without proxy not to share a range, got %v", ranges)
+	}
+}
+
 func TestGetFullName(t *testing.T) {
 	name, err := GetFullContainerName("testing")
 	if err != nil {
@@ -195,3 +235,104 @@ func TestValidContainerNames(t *testing.T) {
 		}
 	}
 }
+
+func TestResolvConf(t *testing.T) {
+	host := []byte("nameserver 10.0.0.1\nsearch example.com\noptions ndots:1\n")
+	daemon := &Daemon{config: &Config{}}
+
+	for _, c := range []struct {
+		hostConfig, daemonConfig runconfig.HostConfig
+		expected                 string
+	}{
+		{
+			expected: string(host),
+		},
+		{
+			hostConfig: runconfig.HostConfig{DnsOptions: []string{"ndots:2", "timeout:3"}},
+			expected:   "nameserver 10.0.0.1\nsearch example.com\noptions ndots:2 timeout:3\n",
+		},
+		{
+			hostConfig: runconfig.HostConfig{Dns: []string{"10.0.0.2"}, DnsSearch: []string{"."}},
+			expected:   "nameserver 10.0.0.2\noptions ndots:1\n",
+		},
+		{
+			hostConfig:   runconfig.HostConfig{DnsSearch: []string{"foo.com"}},
+			daemonConfig: runconfig.HostConfig{DnsSearch: []string{"bar.com"}, DnsOptions: []string{"rotate"}},
+			expected:     "nameserver 10.0.0.1\nsearch foo.com\noptions rotate\n",
+		},
+		{
+			hostConfig: runconfig.HostConfig{NetworkMode: "host", DnsOptions: []string{"rotate"}},
+			expected:   string(host),
+		},
+	} {
+		daemon.config.Dns = c.daemonConfig.Dns
+		daemon.config.DnsSearch = c.daemonConfig.DnsSearch
+		daemon.config.DnsOptions = c.daemonConfig.DnsOptions
+		if c.hostConfig.NetworkMode == "" {
+			c.hostConfig.NetworkMode = "bridge"
+		}
+		container := &Container{daemon: daemon, hostConfig: &c.hostConfig}
+		if resolvConf := string(container.resolvConf(host)); resolvConf != c.expected {
+			t.Fatalf("Expected resolv.conf %q, got %q", c.expected, resolvConf)
+		}
+	}
+}
+
+func TestPortConflict(t *testing.T) {
+	web := &Container{
+		ID:   "web",
+		Name: "/web",
+		NetworkSettings: &network.Settings{
+			Ports: nat.PortMap{
+				"80/tcp": []nat.PortBinding{{HostIp: "0.0.0.0", HostPort: "8080"}},
+			},
+		},
+	}
+	daemon := &Daemon{containers: &contStore{s: map[string]*Container{"web": web}}}
+	container := &Container{ID: "new", daemon: daemon}
+
+	err := container.portConflict("tcp", portallocator.NewErrPortAlreadyAllocated("0.0.0.0", 8080))
+	if expected := "Bind for 0.0.0.0:8080 failed: port is already allocated to container web"; err == nil || err.Error() != expected {
+		t.Fatalf("Expected error %q, got %v", expected, err)
+	}
+
+	for _, proto := range []string{"udp", "sctp"} {
+		orig := portallocator.NewErrPortAlreadyAllocated("0.0.0.0", 8080)
+		if err := container.portConflict(proto, orig); err != orig {
+			t.Fatalf("Expected the original error for %s, got %v", proto, err)
+		}
+	}
+}
+
+func TestSetEndpoint(t *testing.T) {
+	ep := &network.Endpoint{
+		ID:          "ep",
+		NetworkID:   "net",
+		ContainerID: "web",
+		Name:        "web",
+		SandboxKey:  "/var/run/docker/netns/web",
+		IfaceName:   "eth1",
+		Gateway:     "10.10.0.1",
+		DeviceRoute: true,
+	}
+	ep.Interface.Address = "10.10.0.5/24"
+	ep.Interface.AddressIPv6 = "2001:db8::5/64"
+	ep.Interface.MacAddress = "02:42:0a:0a:00:05"
+
+	settings := &network.Settings{IPAddress: "10.20.0.5", IPPrefixLen: 16, IPv6Gateway: "2001:db9::1"}
+	if err := setEndpoint(settings, ep); err != nil {
+		t.Fatal(err)
+	}
+	if settings.IPAddress != "10.10.0.5" || settings.IPPrefixLen != 24 || settings.GlobalIPv6Address != "2001:db8::5" || settings.IPv6Gateway != "" {
+		t.Fatalf("Unexpected settings %+v", settings)
+	}
+	container := &Container{ID: "web", Name: "/web", NetworkSettings: settings}
+	if got := container.endpoint(); !reflect.DeepEqual(got, ep) {
+		t.Fatalf("Expected endpoint %+v, got %+v", ep, got)
+	}
+
+	ep.Interface.Address = "10.10.0.5"
+	if err := setEndpoint(settings, ep); err == nil {
+		t.Fatal("Expected an error setting an endpoint with an invalid address")
+	}
+}
@@ -60,6 +60,9 @@ func (daemon *Daemon) Create(config *runconfig.Config, hostConfig *runconfig.Hos
 		if err = img.CheckDepth(); err != nil {
 			return nil, nil, err
 		}
+		if err = daemon.repositories.CheckImagePolicy(config.Image, img); err != nil {
+			return nil, nil, err
+		}
 		imgID = img.ID
 	}
 
@@ -1,15 +1,16 @@
 package daemon
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,15 +26,21 @@ import (
 	"github.com/docker/docker/daemon/graphdriver"
 	_ "github.com/docker/docker/daemon/graphdriver/vfs"
 	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/cache"
 	"github.com/docker/docker/daemon/network"
 	"github.com/docker/docker/daemon/networkdriver/bridge"
+	"github.com/docker/docker/daemon/networkdriver/ipvlan"
+	"github.com/docker/docker/daemon/networkdriver/overlay"
+	"github.com/docker/docker/daemon/secrets"
 	"github.com/docker/docker/graph"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/broadcastwriter"
 	"github.com/docker/docker/pkg/fileutils"
 	"github.com/docker/docker/pkg/graphdb"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/docker/pkg/kvstore"
 	"github.com/docker/docker/pkg/namesgenerator"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/parsers/kernel"
@@ -101,14 +108,19 @@ type Daemon struct {
 	idIndex          *truncindex.TruncIndex
 	sysInfo          *sysinfo.SysInfo
 	volumes          *volumes.Repository
+	networks         *network.Store
+	secrets          *secrets.Store
 	config           *Config
 	containerGraph   *graphdb.Database
 	driver           graphdriver.Driver
 	execDriver       execdriver.Driver
 	statsCollector   *statsCollector
+	seccompAuditor   seccompAuditor
 	defaultLogConfig runconfig.LogConfig
 	RegistryService  *registry.Service
 	EventsService    *events.Events
+	uidMaps          []idtools.IDMap
+	gidMaps          []idtools.IDMap
 }
 
 // Get looks for a container using the provided information, which could be
@@ -365,25 +377,14 @@ func (daemon *Daemon) setupResolvconfWatcher() error {
 					(event.Op&(fsnotify.Write|fsnotify.Create) != 0) {
 					// verify a real change happened before we go further--a file write may have happened
 					// without an actual change to the file
-					updatedResolvConf, newResolvConfHash, err := resolvconf.GetIfChanged()
+					updatedResolvConf, _, err := resolvconf.GetIfChanged()
 					if err != nil {
 						logrus.Debugf("Error retrieving updated host resolv.conf: %v", err)
 					} else if updatedResolvConf != nil {
-						// because the new host resolv.conf might have localhost nameservers..
-						updatedResolvConf, modified := resolvconf.FilterResolvDns(updatedResolvConf, daemon.config.Bridge.EnableIPv6)
-						if modified {
-							// changes have occurred during localhost cleanup: generate an updated hash
-							newHash, err := ioutils.HashData(bytes.NewReader(updatedResolvConf))
-							if err != nil {
-								logrus.Debugf("Error generating hash of new resolv.conf: %v", err)
-							} else {
-								newResolvConfHash = newHash
-							}
-						}
 						logrus.Debug("host network resolv.conf changed--walking container list for updates")
 						contList := daemon.containers.List()
 						for _, container := range contList {
-							if err := container.updateResolvConf(updatedResolvConf, newResolvConfHash); err != nil {
+							if err := container.updateResolvConf(updatedResolvConf); err != nil {
 								logrus.Debugf("Error on resolv.conf update check for container ID: %s: %v", container.ID, err)
 							}
 						}
@@ -541,15 +542,48 @@ func parseSecurityOpt(container *Container, config *runconfig.HostConfig) error
 	)
 
 	for _, opt := range config.SecurityOpt {
-		con := strings.SplitN(opt, ":", 2)
+		// the options are given as name:value or name=value
+		sep := ":"
+		if i := strings.IndexAny(opt, ":="); i != -1 {
+			sep = opt[i : i+1]
+		}
+		con := strings.SplitN(opt, sep, 2)
+		if opt == "no-new-privileges" {
+			container.NoNewPrivileges = true
+			continue
+		}
+		if opt == "seccomp-audit" {
+			con = append(con, "true")
+		}
 		if len(con) == 1 {
 			return fmt.Errorf("Invalid --security-opt: %q", opt)
 		}
 		switch con[0] {
 		case "label":
+			if !validLabelOpt(con[1]) {
+				return fmt.Errorf("Invalid --security-opt: %q, the label options are disable, user:USER, role:ROLE, type:TYPE and level:LEVEL", opt)
+			}
 			labelOpts = append(labelOpts, con[1])
 		case "apparmor":
 			container.AppArmorProfile = con[1]
+		case "seccomp":
+			container.SeccompProfile = con[1]
+		case "no-new-privileges":
+			noNewPrivileges, err := strconv.ParseBool(con[1])
+			if err != nil {
+				return fmt.Errorf("Invalid --security-opt: %q", opt)
+			}
+			container.NoNewPrivileges = noNewPrivileges
+		case "seccomp-audit":
+			seccompAudit, err := strconv.ParseBool(con[1])
+			if err != nil {
+				return fmt.Errorf("Invalid --security-opt: %q", opt)
+			}
+			// the syscalls are attributed to the container by its pid namespace
+			if seccompAudit && config.PidMode.IsHost() {
+				return fmt.Errorf("Invalid --security-opt: %q, the syscalls of a container sharing the pid namespace of the host can't be audited", opt)
+			}
+			container.SeccompAudit = seccompAudit
 		default:
 			return fmt.Errorf("Invalid --security-opt: %q", opt)
 		}
@@ -559,6 +593,23 @@ func parseSecurityOpt(container *Container, config *runconfig.HostConfig) error
 	return err
 }
 
+// validLabelOpt returns whether opt, the value of a label security option,
+// disables the SELinux labels of the container or sets a field of them.
+func validLabelOpt(opt string) bool {
+	if opt == "disable" {
+		return true
+	}
+	con := strings.SplitN(opt, ":", 2)
+	if len(con) != 2 || con[1] == "" {
+		return false
+	}
+	switch con[0] {
+	case "user", "role", "type", "level":
+		return true
+	}
+	return false
+}
+
 func (daemon *Daemon) newContainer(name string, config *runconfig.Config, imgID string) (*Container, error) {
 	var (
 		id  string
@@ -585,6 +636,7 @@ func (daemon *Daemon) newContainer(name string, config *runconfig.Config, imgID
 		Name:            name,
 		Driver:          daemon.driver.String(),
 		ExecDriver:      daemon.execDriver.Name(),
+		NoNewPrivileges: daemon.config.NoNewPrivileges,
 		State:           NewState(),
 		execCommands:    newExecStore(),
 	}
@@ -593,11 +645,18 @@ func (daemon *Daemon) newContainer(name string, config *runconfig.Config, imgID
 }
 
 func (daemon *Daemon) createRootfs(container *Container) error {
+	rootUID, rootGID, err := idtools.GetRootUIDGID(daemon.uidMaps, daemon.gidMaps)
+	if err != nil {
+		return err
+	}
 	// Step 1: create the container directory.
 	// This doubles as a barrier to avoid race conditions.
 	if err := os.Mkdir(container.root, 0700); err != nil {
 		return err
 	}
+	if err := os.Chown(container.root, rootUID, rootGID); err != nil {
+		return err
+	}
 	initID := fmt.Sprintf("%s-init", container.ID)
 	if err := daemon.driver.Create(initID, container.ImageID); err != nil {
 		return err
@@ -608,7 +667,7 @@ func (daemon *Daemon) createRootfs(container *Container) error {
 	}
 	defer daemon.driver.Put(initID)
 
-	if err := graph.SetupInitLayer(initPath); err != nil {
+	if err := graph.SetupInitLayer(initPath, rootUID, rootGID); err != nil {
 		return err
 	}
 
@@ -742,7 +801,7 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 		return nil, fmt.Errorf("The Docker daemon is only supported on linux")
 	}
 	if os.Geteuid() != 0 {
-		return nil, fmt.Errorf("The Docker daemon needs to be run as root")
+		return nil, fmt.Errorf("The Docker daemon needs to be run as root, or with --rootless")
 	}
 	if err := checkKernel(); err != nil {
 		return nil, err
@@ -778,11 +837,27 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 		return nil, err
 	}
 
+	// Map the root of the containers to an unprivileged range of IDs
+	uidMaps, gidMaps, err := setupRemappedRoot(config)
+	if err != nil {
+		return nil, err
+	}
+	rootUID, rootGID, err := idtools.GetRootUIDGID(uidMaps, gidMaps)
+	if err != nil {
+		return nil, err
+	}
+	if uidMaps != nil {
+		if config.Root, err = setupRemappedRootDir(config.Root, rootUID, rootGID); err != nil {
+			return nil, err
+		}
+		logrus.Infof("User namespaces: the root of the containers is mapped to %d:%d", rootUID, rootGID)
+	}
+
 	// Set the default driver
 	graphdriver.DefaultDriver = config.GraphDriver
 
 	// Load storage driver
-	driver, err := graphdriver.New(config.Root, config.GraphOptions)
+	driver, err := graphdriver.New(config.Root, config.GraphOptions, uidMaps, gidMaps)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing graphdriver: %v", err)
 	}
@@ -790,6 +865,8 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 
 	d := &Daemon{}
 	d.driver = driver
+	d.uidMaps = uidMaps
+	d.gidMaps = gidMaps
 
 	defer func() {
 		if err != nil {
@@ -801,9 +878,12 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 
 	// Verify logging driver type
 	if config.LogConfig.Type != "none" {
-		if _, err := logger.GetLogDriver(config.LogConfig.Type); err != nil {
+		if _, err := lookupLogDriver(config.LogConfig.Type); err != nil {
 			return nil, fmt.Errorf("error finding the logging driver: %v", err)
 		}
+		if err := validateLogOpts(config.LogConfig); err != nil {
+			return nil, fmt.Errorf("invalid log opts: %v", err)
+		}
 	}
 	logrus.Debugf("Using default logging driver %s", config.LogConfig.Type)
 
@@ -823,12 +903,12 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 
 	daemonRepo := path.Join(config.Root, "containers")
 
-	if err := os.MkdirAll(daemonRepo, 0700); err != nil && !os.IsExist(err) {
+	if err := idtools.MkdirAllAs(daemonRepo, 0700, rootUID, rootGID); err != nil {
 		return nil, err
 	}
 
 	// Migrate the container if it is aufs and aufs is enabled
-	if err := migrateIfAufs(d.driver, config.Root); err != nil {
+	if err := migrateIfAufs(d.driver, config.Root, rootUID, rootGID); err != nil {
 		return nil, err
 	}
 
@@ -838,7 +918,7 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 		return nil, err
 	}
 
-	volumesDriver, err := graphdriver.GetDriver("vfs", config.Root, config.GraphOptions)
+	volumesDriver, err := graphdriver.GetDriver("vfs", config.Root, config.GraphOptions, uidMaps, gidMaps)
 	if err != nil {
 		return nil, err
 	}
@@ -848,6 +928,34 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 		return nil, err
 	}
 
+	if !config.DisableNetwork {
+		if err := ipvlan.Init(); err != nil {
+			return nil, fmt.Errorf("Error initializing ipvlan networks: %v", err)
+		}
+	}
+
+	var kv kvstore.Store
+	if config.KVStore != "" {
+		if kv, err = kvstore.New(config.KVStore); err != nil {
+			return nil, err
+		}
+		if !config.DisableNetwork {
+			if err := overlay.Init(kv, config.ClusterAdvertise); err != nil {
+				return nil, fmt.Errorf("Error initializing overlay networks: %v", err)
+			}
+		}
+	}
+
+	networks, err := network.NewStore(filepath.Join(config.Root, "networks"), kv, config.Mtu)
+	if err != nil {
+		return nil, err
+	}
+
+	secretStore, err := secrets.NewStore(filepath.Join(config.Root, "secrets"))
+	if err != nil {
+		return nil, err
+	}
+
 	trustKey, err := api.LoadOrCreateTrustKey(config.TrustKeyPath)
 	if err != nil {
 		return nil, err
@@ -862,6 +970,13 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 		return nil, fmt.Errorf("could not create trust store: %s", err)
 	}
 
+	var imagePolicy *trust.Policy
+	if config.ImagePolicy != "" {
+		if imagePolicy, err = trust.LoadPolicy(config.ImagePolicy); err != nil {
+			return nil, err
+		}
+	}
+
 	eventsService := events.New()
 	logrus.Debug("Creating repository list")
 	tagCfg := &graph.TagStoreConfig{
@@ -870,6 +985,7 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 		Registry: registryService,
 		Events:   eventsService,
 		Trust:    trustService,
+		Policy:   imagePolicy,
 	}
 	repositories, err := graph.NewTagStore(path.Join(config.Root, "repositories-"+d.driver.String()), tagCfg)
 	if err != nil {
@@ -880,6 +996,11 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 		if err := bridge.InitDriver(&config.Bridge); err != nil {
 			return nil, fmt.Errorf("Error initializing Bridge: %v", err)
 		}
+		if config.Bridge.EnableIptables {
+			if err := networks.EnableIsolation(); err != nil {
+				return nil, fmt.Errorf("Error isolating networks: %v", err)
+			}
+		}
 	}
 
 	graphdbPath := path.Join(config.Root, "linkgraph.db")
@@ -898,7 +1019,7 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 
 	if sysInitPath != localCopy {
 		// When we find a suitable dockerinit binary (even if it's our local binary), we copy it into config.Root at localCopy for future use (so that the original can go away without that being a problem, for example during a package upgrade).
-		if err := os.Mkdir(path.Dir(localCopy), 0700); err != nil && !os.IsExist(err) {
+		if err := idtools.MkdirAs(path.Dir(localCopy), 0700, rootUID, rootGID); err != nil {
 			return nil, err
 		}
 		if _, err := fileutils.CopyFile(sysInitPath, localCopy); err != nil {
@@ -910,7 +1031,12 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 		sysInitPath = localCopy
 	}
 
-	sysInfo := sysinfo.New(false)
+	var sysInfo *sysinfo.SysInfo
+	if config.Rootless {
+		sysInfo = sysinfo.NewWithoutCgroups(false)
+	} else {
+		sysInfo = sysinfo.New(false)
+	}
 	const runDir = "/var/run/docker"
 	ed, err := execdrivers.NewDriver(config.ExecDriver, config.ExecOptions, runDir, config.Root, sysInitPath, sysInfo)
 	if err != nil {
@@ -926,6 +1052,9 @@ func NewDaemon(config *Config, registryService *registry.Service) (daemon *Daemo
 	d.idIndex = truncindex.NewTruncIndex([]string{})
 	d.sysInfo = sysInfo
 	d.volumes = volumes
+	d.networks = networks
+	d.secrets = secretStore
+	d.networks.OnEndpointsChange(d.updateNetworkHosts)
 	d.config = config
 	d.sysInitPath = sysInitPath
 	d.execDriver = ed
@@ -1009,6 +1138,10 @@ func (daemon *Daemon) Changes(container *Container) ([]archive.Change, error) {
 
 func (daemon *Daemon) Diff(container *Container) (archive.Archive, error) {
 	initID := fmt.Sprintf("%s-init", container.ID)
+	if d, ok := daemon.driver.(graphdriver.MappedDiffer); ok {
+		uidMaps, gidMaps := daemon.idMappings(container.hostConfig)
+		return d.DiffWithIDMaps(container.ID, initID, uidMaps, gidMaps)
+	}
 	return daemon.driver.Diff(container.ID, initID)
 }
 
@@ -1139,6 +1272,20 @@ func checkKernel() error {
 	return nil
 }
 
+// validateLogOpts checks the log opts of cfg, those of the local log cache
+// and those of the logging driver.
+func validateLogOpts(cfg runconfig.LogConfig) error {
+	// Logging plugins register their validator when they are activated
+	if _, err := lookupLogDriver(cfg.Type); err != nil {
+		return err
+	}
+	cacheOpts, driverOpts := cache.SplitLogOpts(cfg.Config)
+	if err := cache.ValidateLogOpt(cacheOpts); err != nil {
+		return err
+	}
+	return logger.ValidateLogOpts(cfg.Type, driverOpts)
+}
+
 func (daemon *Daemon) verifyHostConfig(hostConfig *runconfig.HostConfig) ([]string, error) {
 	var warnings []string
 
@@ -1149,6 +1296,9 @@ func (daemon *Daemon) verifyHostConfig(hostConfig *runconfig.HostConfig) ([]stri
 	if hostConfig.LxcConf.Len() > 0 && !strings.Contains(daemon.ExecutionDriver().Name(), "lxc") {
 		return warnings, fmt.Errorf("Cannot use --lxc-conf with execdriver: %s", daemon.ExecutionDriver().Name())
 	}
+	if err := daemon.verifyUsernsConfig(hostConfig); err != nil {
+		return warnings, err
+	}
 	if hostConfig.Memory != 0 && hostConfig.Memory < 4194304 {
 		return warnings, fmt.Errorf("Minimum memory limit allowed is 4MB")
 	}
@@ -1181,10 +1331,87 @@ func (daemon *Daemon) verifyHostConfig(hostConfig *runconfig.HostConfig) ([]stri
 		hostConfig.OomKillDisable = false
 		return warnings, fmt.Errorf("Your kernel does not support oom kill disable.")
 	}
+	if hostConfig.AutoRemove && (hostConfig.RestartPolicy.Name == "always" || hostConfig.RestartPolicy.Name == "on-failure") {
+		return warnings, fmt.Errorf("Conflicting options: the container cannot be both automatically removed and restarted")
+	}
+	if err := runconfig.ValidateMounts(hostConfig.Mounts); err != nil {
+		return warnings, err
+	}
+	for _, m := range hostConfig.Mounts {
+		if _, exists := hostConfig.Tmpfs[filepath.Clean(m.Target)]; exists && m.Type == runconfig.MountTypeTmpfs {
+			return warnings, fmt.Errorf("Duplicate tmpfs mount %s", m.Target)
+		}
+	}
+	tmpfs := hostConfig.TmpfsMounts()
+	if err := runconfig.ValidateTmpfs(tmpfs); err != nil {
+		return warnings, err
+	}
+	mounts, err := parseMounts(hostConfig)
+	if err != nil {
+		return warnings, err
+	}
+	for _, mnt := range mounts {
+		if _, exists := tmpfs[mnt.containerPath]; exists {
+			return warnings, fmt.Errorf("Conflicting mounts on %s, it is both a volume and a tmpfs mount", mnt.containerPath)
+		}
+	}
+	if err := daemon.verifySecrets(hostConfig, tmpfs, mounts); err != nil {
+		return warnings, err
+	}
+	for _, spec := range hostConfig.VolumesFrom {
+		if _, _, err := parseVolumesFromSpec(spec); err != nil {
+			return warnings, err
+		}
+	}
+	if cfg := hostConfig.LogConfig; cfg.Type != "" && cfg.Type != "none" {
+		if err := validateLogOpts(cfg); err != nil {
+			return warnings, fmt.Errorf("Invalid log opts: %v", err)
+		}
+	}
+	if err := verifyBandwidth("egress", hostConfig.EgressRate, hostConfig.EgressCeil); err != nil {
+		return warnings, err
+	}
+	if err := verifyBandwidth("ingress", hostConfig.IngressRate, hostConfig.IngressCeil); err != nil {
+		return warnings, err
+	}
+	if hostConfig.EgressRate > 0 || hostConfig.IngressRate > 0 {
+		if !hostConfig.NetworkMode.IsPrivate() {
+			return warnings, fmt.Errorf("Bandwidth limits require the container to have its own network stack")
+		}
+		if _, err := exec.LookPath("tc"); err != nil {
+			return warnings, fmt.Errorf("Bandwidth limits require tc, which is not installed on the host")
+		}
+	}
+	if hostConfig.NetworkMode.IsUserDefined() {
+		n, err := daemon.networks.Get(string(hostConfig.NetworkMode))
+		if err != nil {
+			return warnings, err
+		}
+		if err := n.ValidateAddresses(hostConfig.IPAddress, hostConfig.IPv6Address); err != nil {
+			return warnings, err
+		}
+	} else if hostConfig.IPAddress != "" || hostConfig.IPv6Address != "" {
+		return warnings, fmt.Errorf("Static IP addresses can only be requested on user-defined networks")
+	}
 
 	return warnings, nil
 }
 
+// verifyBandwidth checks the rate and ceiling of one direction of the
+// bandwidth limits of a container.
+func verifyBandwidth(direction string, rate, ceil int64) error {
+	if rate < 0 || ceil < 0 {
+		return fmt.Errorf("Invalid %s bandwidth, limits can't be negative", direction)
+	}
+	if rate == 0 && ceil > 0 {
+		return fmt.Errorf("You should always set the %s rate when using the %s ceiling", direction, direction)
+	}
+	if ceil > 0 && ceil < rate {
+		return fmt.Errorf("The %s ceiling should be larger than the %s rate", direction, direction)
+	}
+	return nil
+}
+
 func (daemon *Daemon) setHostConfig(container *Container, hostConfig *runconfig.HostConfig) error {
 	container.Lock()
 	defer container.Unlock()
@@ -1,8 +1,12 @@
 package daemon
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
+	"github.com/docker/docker/daemon/secrets"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/runconfig"
 )
 
@@ -19,21 +23,216 @@ func TestParseSecurityOpt(t *testing.T) {
 		t.Fatalf("Unexpected AppArmorProfile, expected: \"test_profile\", got %q", container.AppArmorProfile)
 	}
 
+	config.SecurityOpt = []string{"apparmor=other_profile"}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if container.AppArmorProfile != "other_profile" {
+		t.Fatalf("Unexpected AppArmorProfile, expected: \"other_profile\", got %q", container.AppArmorProfile)
+	}
+
+	// test seccomp
+	config.SecurityOpt = []string{`seccomp:{"defaultAction":"SCMP_ACT_ALLOW"}`}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if container.SeccompProfile != `{"defaultAction":"SCMP_ACT_ALLOW"}` {
+		t.Fatalf("Unexpected SeccompProfile, got %q", container.SeccompProfile)
+	}
+
 	// test valid label
 	config.SecurityOpt = []string{"label:user:USER"}
 	if err := parseSecurityOpt(container, config); err != nil {
 		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
 	}
 
+	config.SecurityOpt = []string{"label=level:s0:c100,c200", "label=type:svirt_apache_t", "label:disable"}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+
 	// test invalid label
-	config.SecurityOpt = []string{"label"}
+	for _, opt := range []string{"label", "label:", "label:user", "label:user:", "label=name:foo", "label:enable"} {
+		config.SecurityOpt = []string{opt}
+		if err := parseSecurityOpt(container, config); err == nil {
+			t.Fatalf("Expected parseSecurityOpt error for %q, got nil", opt)
+		}
+	}
+
+	// test no-new-privileges
+	config.SecurityOpt = []string{"no-new-privileges"}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if !container.NoNewPrivileges {
+		t.Fatal("Expected NoNewPrivileges to be set")
+	}
+	config.SecurityOpt = []string{"no-new-privileges:false"}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if container.NoNewPrivileges {
+		t.Fatal("Expected NoNewPrivileges to be unset")
+	}
+	config.SecurityOpt = []string{"no-new-privileges=false"}
+	container.NoNewPrivileges = true
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if container.NoNewPrivileges {
+		t.Fatal("Expected NoNewPrivileges to be unset")
+	}
+	config.SecurityOpt = []string{"no-new-privileges:maybe"}
 	if err := parseSecurityOpt(container, config); err == nil {
 		t.Fatal("Expected parseSecurityOpt error, got nil")
 	}
 
+	config.SecurityOpt = []string{"seccomp-audit"}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if !container.SeccompAudit {
+		t.Fatal("Expected SeccompAudit to be set")
+	}
+	config.SecurityOpt = []string{"seccomp-audit=false"}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if container.SeccompAudit {
+		t.Fatal("Expected SeccompAudit to be unset")
+	}
+	config.SecurityOpt = []string{"seccomp-audit"}
+	config.PidMode = "host"
+	if err := parseSecurityOpt(container, config); err == nil {
+		t.Fatal("Expected parseSecurityOpt error auditing a container in the pid namespace of the host, got nil")
+	}
+	config.PidMode = ""
+
 	// test invalid opt
 	config.SecurityOpt = []string{"test"}
 	if err := parseSecurityOpt(container, config); err == nil {
 		t.Fatal("Expected parseSecurityOpt error, got nil")
 	}
 }
+
+func TestVerifyBandwidth(t *testing.T) {
+	for _, limits := range [][2]int64{{0, 0}, {1000, 0}, {1000, 1000}, {1000, 2000}} {
+		if err := verifyBandwidth("egress", limits[0], limits[1]); err != nil {
+			t.Fatalf("Unexpected error for rate %d and ceiling %d: %v", limits[0], limits[1], err)
+		}
+	}
+	for _, limits := range [][2]int64{{0, 1000}, {2000, 1000}, {-1, 0}, {1000, -1}} {
+		if err := verifyBandwidth("egress", limits[0], limits[1]); err == nil {
+			t.Fatalf("Expected an error for rate %d and ceiling %d", limits[0], limits[1])
+		}
+	}
+}
+
+func TestParseRemappedRoot(t *testing.T) {
+	for _, usergrp := range []string{"root", "0", "root:root", "0:0", "root:"} {
+		username, groupname, err := parseRemappedRoot(usergrp)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", usergrp, err)
+		}
+		if username != "root" || groupname != "root" {
+			t.Fatalf("Expected root:root for %q, got %s:%s", usergrp, username, groupname)
+		}
+	}
+	for _, usergrp := range []string{"", ":root", "nosuchuser", "root:nosuchgroup"} {
+		if _, _, err := parseRemappedRoot(usergrp); err == nil {
+			t.Fatalf("Expected an error for %q", usergrp)
+		}
+	}
+}
+
+func TestVerifyUsernsConfig(t *testing.T) {
+	daemon := &Daemon{
+		uidMaps: []idtools.IDMap{{ContainerID: 0, HostID: 100000, Size: 65536}},
+		gidMaps: []idtools.IDMap{{ContainerID: 0, HostID: 100000, Size: 65536}},
+	}
+	if err := daemon.verifyUsernsConfig(&runconfig.HostConfig{NetworkMode: "bridge"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, hostConfig := range []*runconfig.HostConfig{
+		{Privileged: true},
+		{NetworkMode: "host"},
+		{NetworkMode: "container:other"},
+		{PidMode: "host"},
+		{IpcMode: "host"},
+		{IpcMode: "container:other"},
+	} {
+		if err := daemon.verifyUsernsConfig(hostConfig); err == nil {
+			t.Fatalf("Expected an error for %+v", hostConfig)
+		}
+		// the host user namespace is not restricted
+		hostConfig.UsernsMode = "host"
+		if err := daemon.verifyUsernsConfig(hostConfig); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// without remapping, nothing is restricted
+	if err := (&Daemon{}).verifyUsernsConfig(&runconfig.HostConfig{Privileged: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIDMappings(t *testing.T) {
+	daemon := &Daemon{
+		uidMaps: []idtools.IDMap{{ContainerID: 0, HostID: 100000, Size: 65536}},
+		gidMaps: []idtools.IDMap{{ContainerID: 0, HostID: 200000, Size: 65536}},
+	}
+	uidMaps, gidMaps := daemon.idMappings(&runconfig.HostConfig{})
+	if len(uidMaps) != 1 || uidMaps[0].HostID != 100000 || len(gidMaps) != 1 || gidMaps[0].HostID != 200000 {
+		t.Fatalf("Expected the mappings of the daemon, got %v and %v", uidMaps, gidMaps)
+	}
+
+	// the files of the containers which aren't remapped keep the host IDs
+	uidMaps, gidMaps = daemon.idMappings(&runconfig.HostConfig{UsernsMode: "host"})
+	if uidMaps != nil || gidMaps != nil {
+		t.Fatalf("Expected no mappings with --userns=host, got %v and %v", uidMaps, gidMaps)
+	}
+	rootUID, rootGID, err := idtools.GetRootUIDGID(uidMaps, gidMaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rootUID != 0 || rootGID != 0 {
+		t.Fatalf("Expected the root of the host, got %d:%d", rootUID, rootGID)
+	}
+}
+
+func TestVerifySecrets(t *testing.T) {
+	root, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	store, err := secrets.NewStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := store.Create("db-password", []byte("s3cr3t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemon := &Daemon{secrets: store}
+
+	hostConfig := &runconfig.HostConfig{Secrets: []string{"db-password"}}
+	if err := daemon.verifySecrets(hostConfig, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, hostConfig := range []*runconfig.HostConfig{
+		{Secrets: []string{"api-key"}},
+		{Secrets: []string{"db-password", secret.ID}},
+	} {
+		if err := daemon.verifySecrets(hostConfig, nil, nil); err == nil {
+			t.Fatalf("Expected an error for the secrets %v", hostConfig.Secrets)
+		}
+	}
+	if err := daemon.verifySecrets(hostConfig, map[string]string{"/run/secrets": ""}, nil); err == nil {
+		t.Fatal("Expected an error for a tmpfs mount on /run/secrets")
+	}
+	if err := daemon.verifySecrets(hostConfig, nil, []*volumeMount{{containerPath: "/run/secrets"}}); err == nil {
+		t.Fatal("Expected an error for a volume on /run/secrets")
+	}
+}
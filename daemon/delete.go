@@ -76,8 +76,20 @@ func (daemon *Daemon) ContainerRm(name string, config *ContainerRmConfig) error
 	return nil
 }
 
+// autoRemove removes the stopped container with its anonymous volumes.
+func (daemon *Daemon) autoRemove(container *Container) {
+	if err := daemon.ContainerRm(container.ID, &ContainerRmConfig{ForceRemove: true, RemoveVolume: true}); err != nil {
+		logrus.Errorf("Error removing container %s: %v", container.ID, err)
+	}
+}
+
+// DeleteVolumes deletes the volumes at the given paths which are no longer
+// used. Named volumes are kept: they are only removed by docker volume rm.
 func (daemon *Daemon) DeleteVolumes(volumeIDs map[string]struct{}) {
 	for id := range volumeIDs {
+		if v := daemon.volumes.Get(id); v == nil || !(v.IsBindMount || v.IsAnonymous()) {
+			continue
+		}
 		if err := daemon.volumes.Delete(id); err != nil {
 			logrus.Infof("%s", err)
 			continue
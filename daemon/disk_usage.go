@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/graph"
+	"github.com/docker/docker/pkg/directory"
+)
+
+// SystemDiskUsage returns the disk space used by the images, the containers
+// and the volumes of the daemon, and the space removing the ones not in use
+// would reclaim.
+func (daemon *Daemon) SystemDiskUsage() (*types.DiskUsage, error) {
+	du := &types.DiskUsage{}
+
+	// the images of the containers, and the layers they are made of
+	usedImages := make(map[string]bool)
+	usedLayers := make(map[string]bool)
+	for _, c := range daemon.List() {
+		sizeRw, _ := c.GetSize()
+		if sizeRw < 0 {
+			sizeRw = 0
+		}
+		du.Containers.TotalCount++
+		du.Containers.Size += sizeRw
+		if c.IsRunning() {
+			du.Containers.Active++
+		} else {
+			du.Containers.Reclaimable += sizeRw
+		}
+
+		usedImages[c.ImageID] = true
+		for id := c.ImageID; id != "" && !usedLayers[id]; {
+			usedLayers[id] = true
+			img, err := daemon.graph.Get(id)
+			if err != nil {
+				logrus.Debugf("Error getting the image %s of container %s: %v", id, c.ID, err)
+				break
+			}
+			id = img.Parent
+		}
+	}
+
+	images, err := daemon.Repositories().Images(&graph.ImagesConfig{})
+	if err != nil {
+		return nil, err
+	}
+	du.Images.TotalCount = len(images)
+	for _, img := range images {
+		if usedImages[img.ID] {
+			du.Images.Active++
+		}
+	}
+	layers, err := daemon.graph.Map()
+	if err != nil {
+		return nil, err
+	}
+	for id, img := range layers {
+		du.Images.Size += img.Size
+		if !usedLayers[id] {
+			du.Images.Reclaimable += img.Size
+		}
+	}
+
+	for _, v := range daemon.volumes.List() {
+		if v.IsExternal() {
+			continue
+		}
+		size, err := directory.Size(v.Path)
+		if err != nil {
+			logrus.Debugf("Error getting the size of volume %s: %v", v.Name, err)
+		}
+		du.Volumes.TotalCount++
+		du.Volumes.Size += size
+		if len(v.Containers()) > 0 {
+			du.Volumes.Active++
+		} else {
+			du.Volumes.Reclaimable += size
+		}
+	}
+	return du, nil
+}
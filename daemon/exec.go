@@ -120,6 +120,11 @@ func (d *Daemon) ContainerExecCreate(config *runconfig.ExecConfig) (string, erro
 		return "", err
 	}
 
+	// check the capabilities now rather than when the exec is started
+	if _, err := execdriver.TweakCapabilities(nil, config.CapAdd, config.CapDrop); err != nil {
+		return "", err
+	}
+
 	cmd := runconfig.NewCommand(config.Cmd...)
 	entrypoint, args := d.getEntrypointAndArgs(runconfig.NewEntrypoint(), cmd)
 
@@ -129,6 +134,8 @@ func (d *Daemon) ContainerExecCreate(config *runconfig.ExecConfig) (string, erro
 		Arguments:  args,
 		User:       config.User,
 		Privileged: config.Privileged,
+		CapAdd:     config.CapAdd,
+		CapDrop:    config.CapDrop,
 	}
 
 	execConfig := &execConfig{
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"time"
 
+	"github.com/docker/docker/pkg/idtools"
 	// TODO Windows: Factor out ulimit
 	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/libcontainer"
@@ -73,6 +74,7 @@ type Network struct {
 	Mtu            int               `json:"mtu"`
 	ContainerID    string            `json:"container_id"` // id of the container to join network.
 	HostNetworking bool              `json:"host_networking"`
+	NamespacePath  string            `json:"namespace_path"` // path of the network namespace to join, set for user-defined networks.
 }
 
 // IPC settings of the container
@@ -131,6 +133,10 @@ type Mount struct {
 	Writable    bool   `json:"writable"`
 	Private     bool   `json:"private"`
 	Slave       bool   `json:"slave"`
+	// Propagation is the propagation mode of the mount: shared, slave,
+	// private or their recursive variants rshared, rslave and rprivate.
+	// Empty leaves the default of the driver.
+	Propagation string `json:"propagation"`
 }
 
 // Describes a process that will be run inside a container.
@@ -139,6 +145,8 @@ type ProcessConfig struct {
 
 	Privileged bool     `json:"privileged"`
 	User       string   `json:"user"`
+	CapAdd     []string `json:"cap_add"`  // capabilities added to those of the container
+	CapDrop    []string `json:"cap_drop"` // capabilities dropped from those of the container
 	Tty        bool     `json:"tty"`
 	Entrypoint string   `json:"entrypoint"`
 	Arguments  []string `json:"arguments"`
@@ -163,6 +171,7 @@ type Command struct {
 	UTS                *UTS              `json:"uts"`
 	Resources          *Resources        `json:"resources"`
 	Mounts             []Mount           `json:"mounts"`
+	Tmpfs              map[string]string `json:"tmpfs"` // tmpfs mounts, from the path in the container to the mount options
 	AllowedDevices     []*configs.Device `json:"allowed_devices"`
 	AutoCreatedDevices []*configs.Device `json:"autocreated_devices"`
 	CapAdd             []string          `json:"cap_add"`
@@ -173,5 +182,10 @@ type Command struct {
 	MountLabel         string            `json:"mount_label"`
 	LxcConfig          []string          `json:"lxc_config"`
 	AppArmorProfile    string            `json:"apparmor_profile"`
-	CgroupParent       string            `json:"cgroup_parent"` // The parent cgroup for this command.
+	SeccompProfile     string            `json:"seccomp_profile"` // JSON profile, "unconfined", or empty for the default one
+	SeccompAudit       bool              `json:"seccomp_audit"`   // the syscalls the profile doesn't allow are logged rather than denied
+	CgroupParent       string            `json:"cgroup_parent"`   // The parent cgroup for this command.
+	UIDMapping         []idtools.IDMap   `json:"uidmapping"`      // user namespace mappings, none without one
+	GIDMapping         []idtools.IDMap   `json:"gidmapping"`
+	NoNewPrivileges    bool              `json:"no_new_privileges"` // processes can't gain privileges with setuid binaries
 }
@@ -87,6 +87,10 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 		dataPath = d.containerDir(c.ID)
 	)
 
+	if c.Network.NamespacePath != "" {
+		return execdriver.ExitStatus{ExitCode: -1}, fmt.Errorf("lxc driver does not support user-defined networks")
+	}
+
 	container, err := d.createContainer(c)
 	if err != nil {
 		return execdriver.ExitStatus{ExitCode: -1}, err
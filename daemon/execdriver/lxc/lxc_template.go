@@ -91,12 +91,16 @@ lxc.mount.entry = shm {{escapeFstabSpaces $ROOTFS}}/dev/shm tmpfs {{formatMountL
 {{range $value := .Mounts}}
 {{$createVal := isDirectory $value.Source}}
 {{if $value.Writable}}
-lxc.mount.entry = {{$value.Source}} {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $value.Destination}} none rbind,rw,create={{$createVal}} 0 0
+lxc.mount.entry = {{$value.Source}} {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $value.Destination}} none rbind,rw,create={{$createVal}}{{if $value.Propagation}},{{$value.Propagation}}{{end}} 0 0
 {{else}}
-lxc.mount.entry = {{$value.Source}} {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $value.Destination}} none rbind,ro,create={{$createVal}} 0 0
+lxc.mount.entry = {{$value.Source}} {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $value.Destination}} none rbind,ro,create={{$createVal}}{{if $value.Propagation}},{{$value.Propagation}}{{end}} 0 0
 {{end}}
 {{end}}
 
+{{range $dest, $options := .Tmpfs}}
+lxc.mount.entry = tmpfs {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $dest}} tmpfs {{formatMountLabel (tmpfsOptions $options) ""}},create=dir 0 0
+{{end}}
+
 # limits
 {{if .Resources}}
 {{if .Resources.Memory}}
@@ -207,6 +211,14 @@ func dropList(drops []string) ([]string, error) {
 	return []string{}, nil
 }
 
+// tmpfsOptions returns the options of a tmpfs mount after the defaults.
+func tmpfsOptions(options string) string {
+	if options == "" {
+		return "noexec,nosuid,nodev"
+	}
+	return "noexec,nosuid,nodev," + options
+}
+
 func isDirectory(source string) string {
 	f, err := os.Stat(source)
 	logrus.Debugf("dir: %s\n", source)
@@ -262,6 +274,7 @@ func init() {
 		"keepCapabilities":  keepCapabilities,
 		"dropList":          dropList,
 		"getHostname":       getHostname,
+		"tmpfsOptions":      tmpfsOptions,
 	}
 	LxcTemplateCompiled, err = template.New("lxc").Funcs(funcMap).Parse(LxcTemplate)
 	if err != nil {
@@ -0,0 +1,84 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/rootless"
+	"github.com/docker/libcontainer/apparmor"
+	"github.com/docker/libcontainer/configs"
+)
+
+// apparmorProfilesPath lists the profiles loaded in the kernel.
+var apparmorProfilesPath = "/sys/kernel/security/apparmor/profiles"
+
+// setupAppArmor sets the AppArmor profile of the container: the profile of
+// the command, "unconfined" for privileged containers, or else the default
+// profile of the driver. Profiles other than the default ones must be loaded
+// in the kernel.
+func (d *driver) setupAppArmor(container *configs.Config, c *execdriver.Command) error {
+	profile := c.AppArmorProfile
+	if profile == "" {
+		if !apparmor.IsEnabled() {
+			return nil
+		}
+		switch {
+		case c.ProcessConfig.Privileged:
+			profile = "unconfined"
+		case d.apparmorProfile != "":
+			profile = d.apparmorProfile
+		case rootless.Enabled():
+			// a daemon running rootless can't load the docker-default
+			// profile applied by the template
+			if loaded, _ := isAppArmorProfileLoaded(container.AppArmorProfile); !loaded {
+				container.AppArmorProfile = ""
+			}
+			return nil
+		default:
+			// the template already applies docker-default
+			return nil
+		}
+	}
+	if profile != "unconfined" {
+		if !apparmor.IsEnabled() {
+			return fmt.Errorf("AppArmor is not enabled on the host, cannot apply the AppArmor profile %s", profile)
+		}
+		loaded, err := isAppArmorProfileLoaded(profile)
+		if err != nil {
+			return err
+		}
+		if !loaded {
+			return fmt.Errorf("AppArmor profile %s is not loaded", profile)
+		}
+	}
+	container.AppArmorProfile = profile
+	return nil
+}
+
+// isAppArmorProfileLoaded returns whether the kernel has the profile name,
+// whatever its mode.
+func isAppArmorProfileLoaded(name string) (bool, error) {
+	f, err := os.Open(apparmorProfilesPath)
+	if err != nil {
+		return false, fmt.Errorf("Listing the AppArmor profiles failed: %v", err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		// each line is "NAME (MODE)"
+		if i := strings.LastIndex(line, " ("); i >= 0 {
+			line = line[:i]
+		}
+		if line == name {
+			return true, nil
+		}
+	}
+	return false, s.Err()
+}
@@ -0,0 +1,45 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestIsAppArmorProfileLoaded(t *testing.T) {
+	f, err := ioutil.TempFile("", "apparmor-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("docker-default (enforce)\n/usr/sbin/ntpd (complain)\nweb server (enforce)\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	defer func(path string) { apparmorProfilesPath = path }(apparmorProfilesPath)
+	apparmorProfilesPath = f.Name()
+
+	for name, expected := range map[string]bool{
+		"docker-default": true,
+		"/usr/sbin/ntpd": true,
+		"web server":     true,
+		"docker":         false,
+		"enforce":        false,
+	} {
+		loaded, err := isAppArmorProfileLoaded(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded != expected {
+			t.Fatalf("Expected %s loaded to be %v, got %v", name, expected, loaded)
+		}
+	}
+
+	apparmorProfilesPath = f.Name() + ".missing"
+	if _, err := isAppArmorProfileLoaded("docker-default"); err == nil {
+		t.Fatal("Expected an error for missing profiles list")
+	}
+}
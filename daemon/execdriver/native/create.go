@@ -6,11 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"syscall"
 
 	"github.com/docker/docker/daemon/execdriver"
-	"github.com/docker/libcontainer/apparmor"
+	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/libcontainer/configs"
 	"github.com/docker/libcontainer/devices"
 	"github.com/docker/libcontainer/utils"
@@ -33,6 +34,9 @@ func (d *driver) createContainer(c *execdriver.Command) (*configs.Config, error)
 		return nil, err
 	}
 
+	d.createUserns(container, c)
+	setupRootless(container)
+
 	if err := d.createNetwork(container, c); err != nil {
 		return nil, err
 	}
@@ -55,9 +59,14 @@ func (d *driver) createContainer(c *execdriver.Command) (*configs.Config, error)
 		}
 	}
 
-	if c.AppArmorProfile != "" {
-		container.AppArmorProfile = c.AppArmorProfile
+	if err := d.setupAppArmor(container, c); err != nil {
+		return nil, err
+	}
+
+	if err := d.setupSeccomp(container, c); err != nil {
+		return nil, err
 	}
+	container.NoNewPrivileges = c.NoNewPrivileges
 
 	if err := execdriver.SetupCgroups(container, c); err != nil {
 		return nil, err
@@ -93,6 +102,11 @@ func (d *driver) createNetwork(container *configs.Config, c *execdriver.Command)
 		container.Namespaces.Remove(configs.NEWNET)
 		return nil
 	}
+	if c.Network.NamespacePath != "" {
+		// The interfaces were already set up in the namespace by the daemon
+		container.Namespaces.Add(configs.NEWNET, c.Network.NamespacePath)
+		return nil
+	}
 
 	container.Networks = []*configs.Network{
 		{
@@ -187,6 +201,27 @@ func (d *driver) createUTS(container *configs.Config, c *execdriver.Command) err
 	return nil
 }
 
+func (d *driver) createUserns(container *configs.Config, c *execdriver.Command) {
+	if len(c.UIDMapping) == 0 {
+		return
+	}
+	container.Namespaces.Add(configs.NEWUSER, "")
+	for _, m := range c.UIDMapping {
+		container.UidMappings = append(container.UidMappings, configs.IDMap{
+			ContainerID: m.ContainerID,
+			HostID:      m.HostID,
+			Size:        m.Size,
+		})
+	}
+	for _, m := range c.GIDMapping {
+		container.GidMappings = append(container.GidMappings, configs.IDMap{
+			ContainerID: m.ContainerID,
+			HostID:      m.HostID,
+			Size:        m.Size,
+		})
+	}
+}
+
 func (d *driver) setPrivileged(container *configs.Config) (err error) {
 	container.Capabilities = execdriver.GetAllCapabilities()
 	container.Cgroups.AllowAllDevices = true
@@ -197,10 +232,6 @@ func (d *driver) setPrivileged(container *configs.Config) (err error) {
 	}
 	container.Devices = hostDevices
 
-	if apparmor.IsEnabled() {
-		container.AppArmorProfile = "unconfined"
-	}
-
 	return nil
 }
 
@@ -228,6 +259,9 @@ func (d *driver) setupMounts(container *configs.Config, c *execdriver.Command) e
 	for _, m := range c.Mounts {
 		userMounts[m.Destination] = struct{}{}
 	}
+	for dest := range c.Tmpfs {
+		userMounts[dest] = struct{}{}
+	}
 
 	// Filter out mounts that are overriden by user supplied mounts
 	var defaultMounts []*configs.Mount
@@ -242,6 +276,7 @@ func (d *driver) setupMounts(container *configs.Config, c *execdriver.Command) e
 	}
 	container.Mounts = defaultMounts
 
+	var mounts []*configs.Mount
 	for _, m := range c.Mounts {
 		flags := syscall.MS_BIND | syscall.MS_REC
 		if !m.Writable {
@@ -250,16 +285,66 @@ func (d *driver) setupMounts(container *configs.Config, c *execdriver.Command) e
 		if m.Slave {
 			flags |= syscall.MS_SLAVE
 		}
-		container.Mounts = append(container.Mounts, &configs.Mount{
+		mnt := &configs.Mount{
 			Source:      m.Source,
 			Destination: m.Destination,
 			Device:      "bind",
 			Flags:       flags,
+		}
+		if m.Propagation != "" {
+			pflag, ok := propagationFlags[m.Propagation]
+			if !ok {
+				return fmt.Errorf("invalid propagation mode %s for mount %s", m.Propagation, m.Destination)
+			}
+			mnt.PropagationFlags = []int{pflag}
+			// Mounts cannot propagate back to the host from the slave
+			// mounts the container gets by default
+			if pflag&syscall.MS_SHARED != 0 {
+				container.RootPropagation = syscall.MS_SHARED | syscall.MS_REC
+			}
+		}
+		mounts = append(mounts, mnt)
+	}
+	for dest, options := range c.Tmpfs {
+		defaults := "noexec,nosuid,nodev"
+		if options != "" {
+			defaults += "," + options
+		}
+		flags, data, err := mount.ParseTmpfsOptions(defaults)
+		if err != nil {
+			return err
+		}
+		mounts = append(mounts, &configs.Mount{
+			Source:      "tmpfs",
+			Destination: dest,
+			Device:      "tmpfs",
+			Flags:       flags,
+			Data:        data,
 		})
 	}
+	// Mount the parents before the mounts nested in them
+	sort.Stable(byDestination(mounts))
+	container.Mounts = append(container.Mounts, mounts...)
 	return nil
 }
 
+type byDestination []*configs.Mount
+
+func (m byDestination) Len() int           { return len(m) }
+func (m byDestination) Less(i, j int) bool { return m[i].Destination < m[j].Destination }
+func (m byDestination) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+
+// propagationFlags maps the propagation modes of execdriver.Mount to mount
+// flags.
+var propagationFlags = map[string]int{
+	"private":  syscall.MS_PRIVATE,
+	"rprivate": syscall.MS_PRIVATE | syscall.MS_REC,
+	"slave":    syscall.MS_SLAVE,
+	"rslave":   syscall.MS_SLAVE | syscall.MS_REC,
+	"shared":   syscall.MS_SHARED,
+	"rshared":  syscall.MS_SHARED | syscall.MS_REC,
+}
+
 func (d *driver) setupLabels(container *configs.Config, c *execdriver.Command) {
 	container.ProcessLabel = c.ProcessLabel
 	container.MountLabel = c.MountLabel
@@ -17,6 +17,7 @@ import (
 	"github.com/docker/docker/daemon/execdriver"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/reexec"
+	"github.com/docker/docker/pkg/rootless"
 	sysinfo "github.com/docker/docker/pkg/system"
 	"github.com/docker/docker/pkg/term"
 	"github.com/docker/libcontainer"
@@ -38,6 +39,7 @@ type driver struct {
 	activeContainers map[string]libcontainer.Container
 	machineMemory    int64
 	factory          libcontainer.Factory
+	apparmorProfile  string
 	sync.Mutex
 }
 
@@ -50,9 +52,13 @@ func NewDriver(root, initPath string, options []string) (*driver, error) {
 	if err := sysinfo.MkdirAll(root, 0700); err != nil {
 		return nil, err
 	}
-	// native driver root is at docker_root/execdriver/native. Put apparmor at docker_root
-	if err := apparmor.InstallDefaultProfile(); err != nil {
-		return nil, err
+	// a daemon running rootless can neither load AppArmor profiles nor
+	// create cgroups
+	if !rootless.Enabled() {
+		// native driver root is at docker_root/execdriver/native. Put apparmor at docker_root
+		if err := apparmor.InstallDefaultProfile(); err != nil {
+			return nil, err
+		}
 	}
 
 	// choose cgroup manager
@@ -62,6 +68,7 @@ func NewDriver(root, initPath string, options []string) (*driver, error) {
 	if systemd.UseSystemd() {
 		cgm = libcontainer.SystemdCgroups
 	}
+	apparmorProfile := ""
 
 	// parse the options
 	for _, option := range options {
@@ -72,6 +79,9 @@ func NewDriver(root, initPath string, options []string) (*driver, error) {
 		key = strings.ToLower(key)
 		switch key {
 		case "native.cgroupdriver":
+			if rootless.Enabled() {
+				return nil, fmt.Errorf("native.cgroupdriver can't be set when running rootless")
+			}
 			// override the default if they set options
 			switch val {
 			case "systemd":
@@ -86,11 +96,19 @@ func NewDriver(root, initPath string, options []string) (*driver, error) {
 			default:
 				return nil, fmt.Errorf("Unknown native.cgroupdriver given %q. try cgroupfs or systemd", val)
 			}
+		case "native.apparmor":
+			if !apparmor.IsEnabled() {
+				return nil, fmt.Errorf("AppArmor is not enabled on the host, cannot use native.apparmor")
+			}
+			apparmorProfile = val
 		default:
 			return nil, fmt.Errorf("Unknown option %s\n", key)
 		}
 	}
 
+	if rootless.Enabled() {
+		cgm = noCgroups
+	}
 	logrus.Debugf("Using %v as native.cgroupdriver", cgm)
 
 	f, err := libcontainer.New(
@@ -108,6 +126,7 @@ func NewDriver(root, initPath string, options []string) (*driver, error) {
 		activeContainers: make(map[string]libcontainer.Container),
 		machineMemory:    meminfo.MemTotal,
 		factory:          f,
+		apparmorProfile:  apparmorProfile,
 	}, nil
 }
 
@@ -10,6 +10,7 @@ import (
 
 	"github.com/docker/docker/daemon/execdriver"
 	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/configs"
 	_ "github.com/docker/libcontainer/nsenter"
 	"github.com/docker/libcontainer/utils"
 )
@@ -27,11 +28,24 @@ func (d *driver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessCo
 		User: processConfig.User,
 	}
 
+	config := active.Config()
 	if processConfig.Privileged {
 		p.Capabilities = execdriver.GetAllCapabilities()
+		p.NoSeccomp = true
+	} else if len(processConfig.CapAdd) > 0 || len(processConfig.CapDrop) > 0 {
+		caps, err := execdriver.TweakCapabilities(config.Capabilities, processConfig.CapAdd, processConfig.CapDrop)
+		if err != nil {
+			return -1, err
+		}
+		// an empty list would give the process the capabilities of the container
+		if caps == nil {
+			caps = []string{}
+		}
+		p.Capabilities = caps
+		if p.Seccomp, err = execSeccomp(&config, c, caps); err != nil {
+			return -1, err
+		}
 	}
-
-	config := active.Config()
 	if err := setupPipes(&config, processConfig, p, pipes); err != nil {
 		return -1, err
 	}
@@ -60,3 +74,19 @@ func (d *driver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessCo
 	}
 	return utils.ExitStatus(ps.Sys().(syscall.WaitStatus)), nil
 }
+
+// execSeccomp returns the syscall filter of a process exec'd in the container
+// of config with the capabilities, when the container has the default
+// profile which depends on them, or else nil for the filter of the container.
+func execSeccomp(config *configs.Config, c *execdriver.Command, capabilities []string) (*configs.Seccomp, error) {
+	if config.Seccomp == nil || c.SeccompProfile != "" {
+		return nil, nil
+	}
+	process := &configs.Config{Seccomp: seccompProfileFor(capabilities)}
+	if c.SeccompAudit {
+		if err := auditSeccomp(process); err != nil {
+			return nil, err
+		}
+	}
+	return process.Seccomp, nil
+}
@@ -0,0 +1,70 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/docker/docker/pkg/rootless"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/configs"
+)
+
+var errNoCgroups = errors.New("cgroups are not available to a daemon running rootless")
+
+// setupRootless adapts the container to a daemon running rootless, whose user
+// namespace may map no other group than root.
+func setupRootless(container *configs.Config) {
+	if !rootless.Enabled() {
+		return
+	}
+	for _, m := range container.Mounts {
+		if m.Device == "devpts" {
+			// the ttys belong to root rather than to the tty group
+			m.Data = strings.Replace(m.Data, ",gid=5", "", 1)
+		}
+	}
+}
+
+// noCgroups is an options func to configure a LinuxFactory to return
+// containers without cgroups, for a daemon running rootless which can't
+// create them. Their resources are not limited, and they can't be paused nor
+// report their stats.
+func noCgroups(l *libcontainer.LinuxFactory) error {
+	l.NewCgroupsManager = func(config *configs.Cgroup, paths map[string]string) cgroups.Manager {
+		return &noCgroupsManager{}
+	}
+	return nil
+}
+
+type noCgroupsManager struct{}
+
+func (m *noCgroupsManager) Apply(pid int) error {
+	return nil
+}
+
+func (m *noCgroupsManager) GetPids() ([]int, error) {
+	return nil, errNoCgroups
+}
+
+func (m *noCgroupsManager) GetStats() (*cgroups.Stats, error) {
+	return nil, errNoCgroups
+}
+
+func (m *noCgroupsManager) Freeze(state configs.FreezerState) error {
+	return errNoCgroups
+}
+
+func (m *noCgroupsManager) Destroy() error {
+	return nil
+}
+
+func (m *noCgroupsManager) GetPaths() map[string]string {
+	return nil
+}
+
+func (m *noCgroupsManager) Set(container *configs.Config) error {
+	return nil
+}
@@ -0,0 +1,108 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/libcontainer/configs"
+	"github.com/docker/libcontainer/seccomp"
+)
+
+// seccompProfile is the format of the profiles given with
+// --security-opt seccomp:PROFILE.
+type seccompProfile struct {
+	DefaultAction string `json:"defaultAction"`
+	Syscalls      []struct {
+		Name   string        `json:"name"`
+		Action string        `json:"action"`
+		Args   []interface{} `json:"args"`
+	} `json:"syscalls"`
+}
+
+var seccompActions = map[string]configs.Action{
+	"SCMP_ACT_KILL":  configs.Kill,
+	"SCMP_ACT_ERRNO": configs.Errno,
+	"SCMP_ACT_TRAP":  configs.Trap,
+	"SCMP_ACT_ALLOW": configs.Allow,
+	"SCMP_ACT_TRACE": configs.Trace,
+	"SCMP_ACT_LOG":   configs.Log,
+}
+
+// setupSeccomp sets the syscall filter of the container: the profile of the
+// command, none if it is "unconfined", or else the default one when the
+// container is not privileged and the kernel supports it, which allows the
+// syscalls of the capabilities of the container, e.g. mount with SYS_ADMIN or
+// ptrace with SYS_PTRACE. With
+// c.SeccompAudit, the syscalls the profile doesn't allow are logged instead.
+func (d *driver) setupSeccomp(container *configs.Config, c *execdriver.Command) (err error) {
+	switch c.SeccompProfile {
+	case "unconfined":
+	case "":
+		if !c.ProcessConfig.Privileged && seccomp.IsEnabled() {
+			container.Seccomp = seccompProfileFor(container.Capabilities)
+		}
+	default:
+		if !seccomp.IsEnabled() {
+			return fmt.Errorf("Seccomp is not enabled in the kernel, cannot apply the seccomp profile")
+		}
+		if container.Seccomp, err = loadSeccompProfile(c.SeccompProfile); err != nil {
+			return err
+		}
+	}
+	if c.SeccompAudit {
+		return auditSeccomp(container)
+	}
+	return nil
+}
+
+// loadSeccompProfile returns the filter of the JSON profile body.
+func loadSeccompProfile(body string) (*configs.Seccomp, error) {
+	var profile seccompProfile
+	if err := json.Unmarshal([]byte(body), &profile); err != nil {
+		return nil, fmt.Errorf("Invalid seccomp profile: %v", err)
+	}
+
+	defaultAction, ok := seccompActions[profile.DefaultAction]
+	if !ok {
+		return nil, fmt.Errorf("Invalid seccomp profile: unknown default action %q", profile.DefaultAction)
+	}
+	config := &configs.Seccomp{DefaultAction: defaultAction}
+	for _, s := range profile.Syscalls {
+		action, ok := seccompActions[s.Action]
+		if !ok {
+			return nil, fmt.Errorf("Invalid seccomp profile: unknown action %q of %s", s.Action, s.Name)
+		}
+		if len(s.Args) > 0 {
+			return nil, fmt.Errorf("Invalid seccomp profile: the arguments of %s can't be filtered", s.Name)
+		}
+		config.Syscalls = append(config.Syscalls, &configs.Syscall{Name: s.Name, Action: action})
+	}
+	return config, nil
+}
+
+// auditSeccomp makes the filter of container log the syscalls it doesn't
+// allow, rather than deny them.
+func auditSeccomp(container *configs.Config) error {
+	if container.Seccomp == nil {
+		return fmt.Errorf("The container has no seccomp profile to audit")
+	}
+	if !seccomp.LogEnabled() {
+		return fmt.Errorf("The kernel can't log the syscalls of the seccomp profile, Linux 4.14 or later is required")
+	}
+	audit := &configs.Seccomp{DefaultAction: auditAction(container.Seccomp.DefaultAction)}
+	for _, s := range container.Seccomp.Syscalls {
+		audit.Syscalls = append(audit.Syscalls, &configs.Syscall{Name: s.Name, Action: auditAction(s.Action)})
+	}
+	container.Seccomp = audit
+	return nil
+}
+
+func auditAction(action configs.Action) configs.Action {
+	if action == configs.Allow {
+		return configs.Allow
+	}
+	return configs.Log
+}
@@ -0,0 +1,107 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"github.com/docker/docker/pkg/stringutils"
+	"github.com/docker/libcontainer/configs"
+)
+
+// defaultSeccompProfile denies the syscalls which administer the host, e.g.
+// its clock, kernel modules, swap or keyrings, and the ones exposing the
+// memory and the namespaces of other processes, as well as obsolete ones.
+// Most of them require capabilities the containers don't have by default,
+// the containers granted them are allowed them, see seccompProfileFor.
+var defaultSeccompProfile = &configs.Seccomp{
+	DefaultAction: configs.Allow,
+	Syscalls: []*configs.Syscall{
+		{Name: "acct", Action: configs.Errno},
+		{Name: "add_key", Action: configs.Errno},
+		{Name: "adjtimex", Action: configs.Errno},
+		{Name: "bpf", Action: configs.Errno},
+		{Name: "clock_adjtime", Action: configs.Errno},
+		{Name: "clock_settime", Action: configs.Errno},
+		{Name: "create_module", Action: configs.Errno},
+		{Name: "delete_module", Action: configs.Errno},
+		{Name: "finit_module", Action: configs.Errno},
+		{Name: "get_kernel_syms", Action: configs.Errno},
+		{Name: "get_mempolicy", Action: configs.Errno},
+		{Name: "init_module", Action: configs.Errno},
+		{Name: "ioperm", Action: configs.Errno},
+		{Name: "iopl", Action: configs.Errno},
+		{Name: "kcmp", Action: configs.Errno},
+		{Name: "kexec_file_load", Action: configs.Errno},
+		{Name: "kexec_load", Action: configs.Errno},
+		{Name: "keyctl", Action: configs.Errno},
+		{Name: "lookup_dcookie", Action: configs.Errno},
+		{Name: "mbind", Action: configs.Errno},
+		{Name: "mount", Action: configs.Errno},
+		{Name: "move_pages", Action: configs.Errno},
+		{Name: "name_to_handle_at", Action: configs.Errno},
+		{Name: "nfsservctl", Action: configs.Errno},
+		{Name: "open_by_handle_at", Action: configs.Errno},
+		{Name: "perf_event_open", Action: configs.Errno},
+		{Name: "pivot_root", Action: configs.Errno},
+		{Name: "process_vm_readv", Action: configs.Errno},
+		{Name: "process_vm_writev", Action: configs.Errno},
+		{Name: "ptrace", Action: configs.Errno},
+		{Name: "query_module", Action: configs.Errno},
+		{Name: "quotactl", Action: configs.Errno},
+		{Name: "reboot", Action: configs.Errno},
+		{Name: "request_key", Action: configs.Errno},
+		{Name: "set_mempolicy", Action: configs.Errno},
+		{Name: "setns", Action: configs.Errno},
+		{Name: "settimeofday", Action: configs.Errno},
+		{Name: "stime", Action: configs.Errno},
+		{Name: "swapoff", Action: configs.Errno},
+		{Name: "swapon", Action: configs.Errno},
+		{Name: "_sysctl", Action: configs.Errno},
+		{Name: "sysfs", Action: configs.Errno},
+		{Name: "umount", Action: configs.Errno},
+		{Name: "umount2", Action: configs.Errno},
+		{Name: "unshare", Action: configs.Errno},
+		{Name: "uselib", Action: configs.Errno},
+		{Name: "userfaultfd", Action: configs.Errno},
+		{Name: "ustat", Action: configs.Errno},
+		{Name: "vm86", Action: configs.Errno},
+		{Name: "vm86old", Action: configs.Errno},
+	},
+}
+
+// seccompCapabilities are the syscalls of the default profile allowed to the
+// processes with each capability, which the kernel checks anyway.
+var seccompCapabilities = map[string][]string{
+	"DAC_READ_SEARCH": {"open_by_handle_at"},
+	"SYS_ADMIN": {"bpf", "lookup_dcookie", "mount", "name_to_handle_at", "perf_event_open", "pivot_root",
+		"quotactl", "setns", "swapoff", "swapon", "umount", "umount2", "unshare"},
+	"SYS_BOOT":   {"reboot"},
+	"SYS_MODULE": {"create_module", "delete_module", "finit_module", "get_kernel_syms", "init_module", "query_module"},
+	"SYS_NICE":   {"get_mempolicy", "mbind", "move_pages", "set_mempolicy"},
+	"SYS_PACCT":  {"acct"},
+	"SYS_PTRACE": {"kcmp", "process_vm_readv", "process_vm_writev", "ptrace"},
+	"SYS_RAWIO":  {"ioperm", "iopl"},
+	"SYS_TIME":   {"adjtimex", "clock_adjtime", "clock_settime", "settimeofday", "stime"},
+}
+
+// seccompProfileFor returns the default profile of the processes with the
+// capabilities, without the denials of the syscalls they are allowed.
+func seccompProfileFor(capabilities []string) *configs.Seccomp {
+	allowed := make(map[string]bool)
+	for capability, syscalls := range seccompCapabilities {
+		if stringutils.InSlice(capabilities, capability) {
+			for _, name := range syscalls {
+				allowed[name] = true
+			}
+		}
+	}
+	if len(allowed) == 0 {
+		return defaultSeccompProfile
+	}
+	profile := &configs.Seccomp{DefaultAction: defaultSeccompProfile.DefaultAction}
+	for _, s := range defaultSeccompProfile.Syscalls {
+		if !allowed[s.Name] {
+			profile.Syscalls = append(profile.Syscalls, s)
+		}
+	}
+	return profile
+}
@@ -0,0 +1,140 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/libcontainer/configs"
+	"github.com/docker/libcontainer/seccomp"
+)
+
+func TestLoadSeccompProfile(t *testing.T) {
+	config, err := loadSeccompProfile(`{"defaultAction":"SCMP_ACT_ERRNO","syscalls":[{"name":"read","action":"SCMP_ACT_ALLOW","args":[]},{"name":"reboot","action":"SCMP_ACT_KILL"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.Seccomp{
+		DefaultAction: configs.Errno,
+		Syscalls: []*configs.Syscall{
+			{Name: "read", Action: configs.Allow},
+			{Name: "reboot", Action: configs.Kill},
+		},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("Expected %v, got %v", expected, config)
+	}
+
+	for _, profile := range []string{
+		`{"defaultAction":"SCMP_ACT_ALLOW"`,
+		`{"syscalls":[]}`,
+		`{"defaultAction":"SCMP_ACT_ALLOW","syscalls":[{"name":"read","action":"DENY"}]}`,
+		`{"defaultAction":"SCMP_ACT_ALLOW","syscalls":[{"name":"clone","action":"SCMP_ACT_ERRNO","args":[{"index":0}]}]}`,
+	} {
+		if _, err := loadSeccompProfile(profile); err == nil {
+			t.Fatalf("Expected an error loading %s", profile)
+		}
+	}
+}
+
+func TestAuditSeccomp(t *testing.T) {
+	container := &configs.Config{Seccomp: &configs.Seccomp{
+		DefaultAction: configs.Errno,
+		Syscalls: []*configs.Syscall{
+			{Name: "read", Action: configs.Allow},
+			{Name: "reboot", Action: configs.Kill},
+		},
+	}}
+	if !seccomp.LogEnabled() {
+		if err := auditSeccomp(container); err == nil {
+			t.Fatal("Expected an error auditing without kernel support")
+		}
+		return
+	}
+	if err := auditSeccomp(container); err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.Seccomp{
+		DefaultAction: configs.Log,
+		Syscalls: []*configs.Syscall{
+			{Name: "read", Action: configs.Allow},
+			{Name: "reboot", Action: configs.Log},
+		},
+	}
+	if !reflect.DeepEqual(container.Seccomp, expected) {
+		t.Fatalf("Expected %v, got %v", expected, container.Seccomp)
+	}
+	// the default profile is shared by the containers
+	if defaultSeccompProfile.DefaultAction == configs.Log {
+		t.Fatal("Expected the default profile not to be changed")
+	}
+
+	if err := auditSeccomp(&configs.Config{}); err == nil {
+		t.Fatal("Expected an error auditing a container without profile")
+	}
+}
+
+func TestSeccompProfileFor(t *testing.T) {
+	denied := func(profile *configs.Seccomp, name string) bool {
+		for _, s := range profile.Syscalls {
+			if s.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the default capabilities allow none of the syscalls
+	if profile := seccompProfileFor([]string{"CHOWN", "SETUID", "NET_BIND_SERVICE"}); profile != defaultSeccompProfile {
+		t.Fatalf("Expected the default profile, got %v", profile)
+	}
+
+	profile := seccompProfileFor([]string{"CHOWN", "SYS_ADMIN", "sys_ptrace"})
+	for _, name := range []string{"mount", "umount2", "unshare", "setns", "ptrace", "process_vm_readv"} {
+		if denied(profile, name) {
+			t.Fatalf("Expected %s to be allowed", name)
+		}
+	}
+	for _, name := range []string{"reboot", "init_module", "settimeofday", "keyctl"} {
+		if !denied(profile, name) {
+			t.Fatalf("Expected %s to be denied", name)
+		}
+	}
+	// the default profile is shared by the containers
+	if !denied(defaultSeccompProfile, "mount") || !denied(defaultSeccompProfile, "ptrace") {
+		t.Fatal("Expected the default profile not to be changed")
+	}
+
+	// the syscalls of the capabilities are the ones of the default profile
+	for capability, syscalls := range seccompCapabilities {
+		for _, name := range syscalls {
+			if !denied(defaultSeccompProfile, name) {
+				t.Fatalf("%s allows %s which the default profile doesn't deny", capability, name)
+			}
+		}
+	}
+}
+
+func TestExecSeccomp(t *testing.T) {
+	c := &execdriver.Command{}
+	config := &configs.Config{Seccomp: defaultSeccompProfile}
+	profile, err := execSeccomp(config, c, []string{"CHOWN", "SYS_PTRACE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile == nil || !reflect.DeepEqual(profile, seccompProfileFor([]string{"SYS_PTRACE"})) {
+		t.Fatalf("Expected the default profile of the capabilities of the process, got %v", profile)
+	}
+
+	// the filters of the containers without the default profile are kept
+	for _, c := range []*execdriver.Command{{SeccompProfile: "unconfined"}, {SeccompProfile: `{"defaultAction":"SCMP_ACT_ALLOW"}`}} {
+		if profile, err := execSeccomp(config, c, []string{"SYS_PTRACE"}); err != nil || profile != nil {
+			t.Fatalf("Expected the filter of the container for %q, got %v, %v", c.SeccompProfile, profile, err)
+		}
+	}
+	if profile, err := execSeccomp(&configs.Config{}, c, []string{"SYS_PTRACE"}); err != nil || profile != nil {
+		t.Fatalf("Expected no filter without the one of the container, got %v, %v", profile, err)
+	}
+}
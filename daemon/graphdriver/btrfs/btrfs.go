@@ -17,6 +17,7 @@ import (
 	"unsafe"
 
 	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/mount"
 )
 
@@ -24,7 +25,11 @@ func init() {
 	graphdriver.Register("btrfs", Init)
 }
 
-func Init(home string, options []string) (graphdriver.Driver, error) {
+func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (graphdriver.Driver, error) {
+	if uidMaps != nil || gidMaps != nil {
+		return nil, graphdriver.ErrUsernsRemap
+	}
+
 	rootdir := path.Dir(home)
 
 	var buf syscall.Statfs_t
@@ -48,7 +53,7 @@ func Init(home string, options []string) (graphdriver.Driver, error) {
 		home: home,
 	}
 
-	return graphdriver.NaiveDiffDriver(driver), nil
+	return graphdriver.NaiveDiffDriver(driver, uidMaps, gidMaps), nil
 }
 
 type Driver struct {
@@ -9,6 +9,8 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/rootless"
 )
 
 type FsMagic uint32
@@ -45,10 +47,19 @@ var (
 		"overlay",
 		"vfs",
 	}
+	// Slice of drivers that should be used in an order by a daemon running
+	// rootless, which can only mount the filesystems allowed in a user
+	// namespace
+	rootlessPriority = []string{
+		"overlay",
+		"fuse-overlayfs",
+		"vfs",
+	}
 
 	ErrNotSupported   = errors.New("driver not supported")
 	ErrPrerequisites  = errors.New("prerequisites for driver not satisfied (wrong filesystem?)")
 	ErrIncompatibleFS = fmt.Errorf("backing file system is unsupported for this graph driver")
+	ErrUsernsRemap    = errors.New("driver does not support remapping the root of the containers (--userns-remap)")
 
 	FsNames = map[FsMagic]string{
 		FsMagicAufs:        "aufs",
@@ -70,7 +81,10 @@ var (
 	}
 )
 
-type InitFunc func(root string, options []string) (Driver, error)
+// InitFunc initializes the storage driver of the root directory. The
+// files of the layers are owned by the host IDs mapped by uidMaps and
+// gidMaps when the containers run in a user namespace.
+type InitFunc func(root string, options []string, uidMaps, gidMaps []idtools.IDMap) (Driver, error)
 
 // ProtoDriver defines the basic capabilities of a driver.
 // This interface exists solely to be a minimum set of methods
@@ -124,6 +138,17 @@ type Driver interface {
 	DiffSize(id, parent string) (size int64, err error)
 }
 
+// MappedDiffer is implemented by the drivers which can produce the diff of a
+// layer with other mappings of the IDs of its files than the ones of the
+// driver, e.g. none for the containers running without remapping
+// (--userns=host) on a daemon with --userns-remap.
+type MappedDiffer interface {
+	// DiffWithIDMaps produces an archive of the changes between the
+	// specified layer and its parent layer which may be "", like Diff,
+	// mapping the host IDs of its files with uidMaps and gidMaps.
+	DiffWithIDMaps(id, parent string, uidMaps, gidMaps []idtools.IDMap) (archive.Archive, error)
+}
+
 func init() {
 	drivers = make(map[string]InitFunc)
 }
@@ -137,24 +162,29 @@ func Register(name string, initFunc InitFunc) error {
 	return nil
 }
 
-func GetDriver(name, home string, options []string) (Driver, error) {
+func GetDriver(name, home string, options []string, uidMaps, gidMaps []idtools.IDMap) (Driver, error) {
 	if initFunc, exists := drivers[name]; exists {
-		return initFunc(path.Join(home, name), options)
+		return initFunc(path.Join(home, name), options, uidMaps, gidMaps)
 	}
 	return nil, ErrNotSupported
 }
 
-func New(root string, options []string) (driver Driver, err error) {
+func New(root string, options []string, uidMaps, gidMaps []idtools.IDMap) (driver Driver, err error) {
 	for _, name := range []string{os.Getenv("DOCKER_DRIVER"), DefaultDriver} {
 		if name != "" {
 			logrus.Debugf("[graphdriver] trying provided driver %q", name) // so the logs show specified driver
-			return GetDriver(name, root, options)
+			return GetDriver(name, root, options, uidMaps, gidMaps)
 		}
 	}
 
+	order := priority
+	if rootless.Enabled() {
+		order = rootlessPriority
+	}
+
 	// Guess for prior driver
 	priorDrivers := scanPriorDrivers(root)
-	for _, name := range priority {
+	for _, name := range order {
 		if name == "vfs" {
 			// don't use vfs even if there is state present.
 			continue
@@ -163,7 +193,7 @@ func New(root string, options []string) (driver Driver, err error) {
 			// of the state found from prior drivers, check in order of our priority
 			// which we would prefer
 			if prior == name {
-				driver, err = GetDriver(name, root, options)
+				driver, err = GetDriver(name, root, options, uidMaps, gidMaps)
 				if err != nil {
 					// unlike below, we will return error here, because there is prior
 					// state, and now it is no longer supported/prereq/compatible, so
@@ -182,21 +212,24 @@ func New(root string, options []string) (driver Driver, err error) {
 	}
 
 	// Check for priority drivers first
-	for _, name := range priority {
-		driver, err = GetDriver(name, root, options)
+	for _, name := range order {
+		driver, err = GetDriver(name, root, options, uidMaps, gidMaps)
 		if err != nil {
-			if err == ErrNotSupported || err == ErrPrerequisites || err == ErrIncompatibleFS {
+			if err == ErrNotSupported || err == ErrPrerequisites || err == ErrIncompatibleFS || err == ErrUsernsRemap {
 				continue
 			}
 			return nil, err
 		}
 		return driver, nil
 	}
+	if rootless.Enabled() {
+		return nil, fmt.Errorf("No storage backend supported rootless found")
+	}
 
 	// Check all registered drivers if no priority driver is found
 	for _, initFunc := range drivers {
-		if driver, err = initFunc(root, options); err != nil {
-			if err == ErrNotSupported || err == ErrPrerequisites || err == ErrIncompatibleFS {
+		if driver, err = initFunc(root, options, uidMaps, gidMaps); err != nil {
+			if err == ErrNotSupported || err == ErrPrerequisites || err == ErrIncompatibleFS || err == ErrUsernsRemap {
 				continue
 			}
 			return nil, err
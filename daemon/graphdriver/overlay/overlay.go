@@ -16,7 +16,9 @@ import (
 	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/libcontainer/label"
+	"github.com/docker/libcontainer/system"
 )
 
 // This is a small wrapper over the NaiveDiffWriter that lets us have a custom
@@ -36,13 +38,19 @@ type naiveDiffDriverWithApply struct {
 	applyDiff ApplyDiffProtoDriver
 }
 
-func NaiveDiffDriverWithApply(driver ApplyDiffProtoDriver) graphdriver.Driver {
+func NaiveDiffDriverWithApply(driver ApplyDiffProtoDriver, uidMaps, gidMaps []idtools.IDMap) graphdriver.Driver {
 	return &naiveDiffDriverWithApply{
-		Driver:    graphdriver.NaiveDiffDriver(driver),
+		Driver:    graphdriver.NaiveDiffDriver(driver, uidMaps, gidMaps),
 		applyDiff: driver,
 	}
 }
 
+// DiffWithIDMaps produces the diff of the naive driver, mapping the IDs of
+// its files with uidMaps and gidMaps.
+func (d *naiveDiffDriverWithApply) DiffWithIDMaps(id, parent string, uidMaps, gidMaps []idtools.IDMap) (archive.Archive, error) {
+	return d.Driver.(graphdriver.MappedDiffer).DiffWithIDMaps(id, parent, uidMaps, gidMaps)
+}
+
 func (d *naiveDiffDriverWithApply) ApplyDiff(id, parent string, diff archive.ArchiveReader) (int64, error) {
 	b, err := d.applyDiff.ApplyDiff(id, parent, diff)
 	if err == ErrApplyDiffFallback {
@@ -86,6 +94,9 @@ type ActiveMount struct {
 }
 type Driver struct {
 	home       string
+	fuse       bool // mounts with fuse-overlayfs rather than the kernel
+	uidMaps    []idtools.IDMap
+	gidMaps    []idtools.IDMap
 	sync.Mutex // Protects concurrent modification to active
 	active     map[string]*ActiveMount
 }
@@ -94,9 +105,10 @@ var backingFs = "<unknown>"
 
 func init() {
 	graphdriver.Register("overlay", Init)
+	graphdriver.Register("fuse-overlayfs", InitFuse)
 }
 
-func Init(home string, options []string) (graphdriver.Driver, error) {
+func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (graphdriver.Driver, error) {
 
 	if err := supportsOverlay(); err != nil {
 		return nil, graphdriver.ErrNotSupported
@@ -128,12 +140,68 @@ func Init(home string, options []string) (graphdriver.Driver, error) {
 		return nil, err
 	}
 
+	// older kernels don't allow mounting overlay in a user namespace
+	if system.RunningInUserNS() {
+		if err := supportsOverlayInUserNS(home); err != nil {
+			logrus.Debugf("'overlay' can't be mounted in the user namespace of the daemon: %v", err)
+			return nil, graphdriver.ErrNotSupported
+		}
+	}
+
 	d := &Driver{
-		home:   home,
-		active: make(map[string]*ActiveMount),
+		home:    home,
+		uidMaps: uidMaps,
+		gidMaps: gidMaps,
+		active:  make(map[string]*ActiveMount),
 	}
 
-	return NaiveDiffDriverWithApply(d), nil
+	return NaiveDiffDriverWithApply(d, uidMaps, gidMaps), nil
+}
+
+// InitFuse returns the overlay driver mounting with fuse-overlayfs, which
+// works in a user namespace where the kernel doesn't allow mounting overlay.
+func InitFuse(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (graphdriver.Driver, error) {
+	if _, err := exec.LookPath("fuse-overlayfs"); err != nil {
+		return nil, graphdriver.ErrNotSupported
+	}
+	if _, err := os.Stat("/dev/fuse"); err != nil {
+		return nil, graphdriver.ErrNotSupported
+	}
+
+	if err := os.MkdirAll(home, 0755); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+
+	d := &Driver{
+		home:    home,
+		fuse:    true,
+		uidMaps: uidMaps,
+		gidMaps: gidMaps,
+		active:  make(map[string]*ActiveMount),
+	}
+
+	return NaiveDiffDriverWithApply(d, uidMaps, gidMaps), nil
+}
+
+// supportsOverlayInUserNS mounts an overlay in home, to check that the kernel
+// allows it in the user namespace of the daemon.
+func supportsOverlayInUserNS(home string) error {
+	dir, err := ioutil.TempDir(home, "check-overlay")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"lower", "upper", "work", "merged"} {
+		if err := os.Mkdir(path.Join(dir, name), 0700); err != nil {
+			return err
+		}
+	}
+	opts := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", path.Join(dir, "lower"), path.Join(dir, "upper"), path.Join(dir, "work"))
+	if err := syscall.Mount("overlay", path.Join(dir, "merged"), "overlay", 0, opts); err != nil {
+		return err
+	}
+	return syscall.Unmount(path.Join(dir, "merged"), 0)
 }
 
 func supportsOverlay() error {
@@ -158,6 +226,9 @@ func supportsOverlay() error {
 }
 
 func (d *Driver) String() string {
+	if d.fuse {
+		return "fuse-overlayfs"
+	}
 	return "overlay"
 }
 
@@ -173,10 +244,14 @@ func (d *Driver) Cleanup() error {
 
 func (d *Driver) Create(id string, parent string) (retErr error) {
 	dir := d.dir(id)
-	if err := os.MkdirAll(path.Dir(dir), 0700); err != nil {
+	rootUID, rootGID, err := idtools.GetRootUIDGID(d.uidMaps, d.gidMaps)
+	if err != nil {
+		return err
+	}
+	if err := idtools.MkdirAllAs(path.Dir(dir), 0700, rootUID, rootGID); err != nil {
 		return err
 	}
-	if err := os.Mkdir(dir, 0700); err != nil {
+	if err := idtools.MkdirAs(dir, 0700, rootUID, rootGID); err != nil {
 		return err
 	}
 
@@ -189,7 +264,7 @@ func (d *Driver) Create(id string, parent string) (retErr error) {
 
 	// Toplevel images are just a "root" dir
 	if parent == "" {
-		if err := os.Mkdir(path.Join(dir, "root"), 0755); err != nil {
+		if err := idtools.MkdirAs(path.Join(dir, "root"), 0755, rootUID, rootGID); err != nil {
 			return err
 		}
 		return nil
@@ -206,13 +281,13 @@ func (d *Driver) Create(id string, parent string) (retErr error) {
 	parentRoot := path.Join(parentDir, "root")
 
 	if s, err := os.Lstat(parentRoot); err == nil {
-		if err := os.Mkdir(path.Join(dir, "upper"), s.Mode()); err != nil {
+		if err := idtools.MkdirAs(path.Join(dir, "upper"), s.Mode(), rootUID, rootGID); err != nil {
 			return err
 		}
-		if err := os.Mkdir(path.Join(dir, "work"), 0700); err != nil {
+		if err := idtools.MkdirAs(path.Join(dir, "work"), 0700, rootUID, rootGID); err != nil {
 			return err
 		}
-		if err := os.Mkdir(path.Join(dir, "merged"), 0700); err != nil {
+		if err := idtools.MkdirAs(path.Join(dir, "merged"), 0700, rootUID, rootGID); err != nil {
 			return err
 		}
 		if err := ioutil.WriteFile(path.Join(dir, "lower-id"), []byte(parent), 0666); err != nil {
@@ -239,13 +314,13 @@ func (d *Driver) Create(id string, parent string) (retErr error) {
 	}
 
 	upperDir := path.Join(dir, "upper")
-	if err := os.Mkdir(upperDir, s.Mode()); err != nil {
+	if err := idtools.MkdirAs(upperDir, s.Mode(), rootUID, rootGID); err != nil {
 		return err
 	}
-	if err := os.Mkdir(path.Join(dir, "work"), 0700); err != nil {
+	if err := idtools.MkdirAs(path.Join(dir, "work"), 0700, rootUID, rootGID); err != nil {
 		return err
 	}
-	if err := os.Mkdir(path.Join(dir, "merged"), 0700); err != nil {
+	if err := idtools.MkdirAs(path.Join(dir, "merged"), 0700, rootUID, rootGID); err != nil {
 		return err
 	}
 
@@ -300,7 +375,11 @@ func (d *Driver) Get(id string, mountLabel string) (string, error) {
 	mergedDir := path.Join(dir, "merged")
 
 	opts := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", lowerDir, upperDir, workDir)
-	if err := syscall.Mount("overlay", mergedDir, "overlay", 0, label.FormatMountLabel(opts, mountLabel)); err != nil {
+	if d.fuse {
+		if out, err := exec.Command("fuse-overlayfs", "-o", label.FormatMountLabel(opts, mountLabel), mergedDir).CombinedOutput(); err != nil {
+			return "", fmt.Errorf("error creating fuse-overlayfs mount to %s: %v: %s", mergedDir, err, out)
+		}
+	} else if err := syscall.Mount("overlay", mergedDir, "overlay", 0, label.FormatMountLabel(opts, mountLabel)); err != nil {
 		return "", fmt.Errorf("error creating overlay mount to %s: %v", mergedDir, err)
 	}
 	mount.path = mergedDir
@@ -375,7 +454,8 @@ func (d *Driver) ApplyDiff(id string, parent string, diff archive.ArchiveReader)
 		return 0, err
 	}
 
-	if size, err = chrootarchive.ApplyLayer(tmpRootDir, diff); err != nil {
+	options := &archive.TarOptions{UIDMaps: d.uidMaps, GIDMaps: d.gidMaps}
+	if size, err = chrootarchive.ApplyLayerWithOptions(tmpRootDir, diff, options); err != nil {
 		return 0, err
 	}
 
@@ -13,6 +13,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/pkg/parsers"
 	zfs "github.com/mistifyio/go-zfs"
@@ -33,7 +34,11 @@ func (*Logger) Log(cmd []string) {
 	log.Debugf("[zfs] %s", strings.Join(cmd, " "))
 }
 
-func Init(base string, opt []string) (graphdriver.Driver, error) {
+func Init(base string, opt []string, uidMaps, gidMaps []idtools.IDMap) (graphdriver.Driver, error) {
+	if uidMaps != nil || gidMaps != nil {
+		return nil, graphdriver.ErrUsernsRemap
+	}
+
 	var err error
 	options, err := parseOptions(opt)
 	if err != nil {
@@ -92,7 +97,7 @@ func Init(base string, opt []string) (graphdriver.Driver, error) {
 		options:          options,
 		filesystemsCache: filesystemsCache,
 	}
-	return graphdriver.NaiveDiffDriver(d), nil
+	return graphdriver.NaiveDiffDriver(d, uidMaps, gidMaps), nil
 }
 
 func parseOptions(opt []string) (ZfsOptions, error) {
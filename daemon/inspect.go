@@ -12,7 +12,9 @@ type ContainerJSONRaw struct {
 	HostConfig *runconfig.HostConfig
 }
 
-func (daemon *Daemon) ContainerInspect(name string) (*types.ContainerJSON, error) {
+// ContainerInspect returns the low-level information of the container name,
+// with the sizes of its filesystem when size is set.
+func (daemon *Daemon) ContainerInspect(name string, size bool) (*types.ContainerJSON, error) {
 	container, err := daemon.Get(name)
 	if err != nil {
 		return nil, err
@@ -48,6 +50,13 @@ func (daemon *Daemon) ContainerInspect(name string) (*types.ContainerJSON, error
 		FinishedAt: container.State.FinishedAt,
 	}
 
+	networkSettings := container.NetworkSettings
+	if container.State.Running && networkSettings != nil && len(networkSettings.Ports) > 0 {
+		settings := *networkSettings
+		settings.PortStatus = container.portStatus()
+		networkSettings = &settings
+	}
+
 	contJSON := &types.ContainerJSON{
 		Id:              container.ID,
 		Created:         container.Created,
@@ -56,7 +65,7 @@ func (daemon *Daemon) ContainerInspect(name string) (*types.ContainerJSON, error
 		Config:          container.Config,
 		State:           containerState,
 		Image:           container.ImageID,
-		NetworkSettings: container.NetworkSettings,
+		NetworkSettings: networkSettings,
 		ResolvConfPath:  container.ResolvConfPath,
 		HostnamePath:    container.HostnamePath,
 		HostsPath:       container.HostsPath,
@@ -74,6 +83,11 @@ func (daemon *Daemon) ContainerInspect(name string) (*types.ContainerJSON, error
 		HostConfig:      &hostConfig,
 	}
 
+	if size {
+		sizeRw, sizeRootFs := container.GetSize()
+		contJSON.SizeRw, contJSON.SizeRootFs = &sizeRw, &sizeRootFs
+	}
+
 	return contJSON, nil
 }
 
@@ -1,9 +1,29 @@
 package daemon
 
-// Importing packages here only to make sure their init gets called and
-// therefore they register themselves to the logdriver factory.
 import (
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/logger"
+	// Importing packages here only to make sure their init gets called and
+	// therefore they register themselves to the logdriver factory.
+	_ "github.com/docker/docker/daemon/logger/fluentd"
+	_ "github.com/docker/docker/daemon/logger/gelf"
 	_ "github.com/docker/docker/daemon/logger/journald"
 	_ "github.com/docker/docker/daemon/logger/jsonfilelog"
+	"github.com/docker/docker/daemon/logger/remote"
 	_ "github.com/docker/docker/daemon/logger/syslog"
 )
+
+// lookupLogDriver returns the logging driver named name, activating the
+// logging plugin of that name when no such driver is built in.
+func lookupLogDriver(name string) (logger.Creator, error) {
+	c, err := logger.GetLogDriver(name)
+	if err == nil {
+		return c, nil
+	}
+	c, perr := remote.Load(name)
+	if perr != nil {
+		logrus.Debugf("Error loading logging plugin %s: %v", name, perr)
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,48 @@
+package logger
+
+import "strings"
+
+// ExtraAttributes returns the labels and environment variables of the
+// container selected by the labels and env log opts, comma-separated lists
+// of label keys and variable names, to be attached to the log records. The
+// keys are passed through keyMod, when not nil, for the drivers restricting
+// the names of the fields of their records. The variables win over the
+// labels of the same key.
+func (ctx *Context) ExtraAttributes(keyMod func(string) string) map[string]string {
+	attrs := make(map[string]string)
+	add := func(key, value string) {
+		if keyMod != nil {
+			key = keyMod(key)
+		}
+		attrs[key] = value
+	}
+
+	for _, key := range splitList(ctx.Config["labels"]) {
+		if value, exists := ctx.ContainerLabels[key]; exists {
+			add(key, value)
+		}
+	}
+	env := make(map[string]string)
+	for _, kv := range ctx.ContainerEnv {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	for _, key := range splitList(ctx.Config["env"]) {
+		if value, exists := env[key]; exists {
+			add(key, value)
+		}
+	}
+	return attrs
+}
+
+func splitList(list string) []string {
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtraAttributes(t *testing.T) {
+	ctx := Context{
+		ContainerLabels: map[string]string{"com.example.service": "frontend", "tier": "web", "other": "x"},
+		ContainerEnv:    []string{"APP=shop", "VERSION=1.2", "SECRET=s3cr3t", "NOVALUE", "tier=env"},
+		Config:          map[string]string{"labels": "com.example.service,tier,missing", "env": " APP, VERSION,NOVALUE,tier"},
+	}
+	expected := map[string]string{
+		"com.example.service": "frontend",
+		"tier":                "env",
+		"APP":                 "shop",
+		"VERSION":             "1.2",
+	}
+	if attrs := ctx.ExtraAttributes(nil); !reflect.DeepEqual(attrs, expected) {
+		t.Fatalf("Wrong attributes %v, expected %v", attrs, expected)
+	}
+
+	attrs := ctx.ExtraAttributes(strings.ToUpper)
+	if len(attrs) != 4 || attrs["COM.EXAMPLE.SERVICE"] != "frontend" || attrs["APP"] != "shop" {
+		t.Fatalf("Wrong attributes %v", attrs)
+	}
+
+	if attrs := (&Context{ContainerEnv: []string{"APP=shop"}}).ExtraAttributes(nil); len(attrs) != 0 {
+		t.Fatalf("Expected no attributes without the log opts, got %v", attrs)
+	}
+}
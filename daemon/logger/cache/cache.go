@@ -0,0 +1,126 @@
+// Package cache keeps a local copy of the logs sent to logging drivers which
+// cannot read them back, so that they can still be read with docker logs.
+package cache
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/jsonfilelog"
+)
+
+// The log opts of the cache, they apply to every logging driver.
+const (
+	DisabledKey = "cache-disabled"
+	MaxSizeKey  = "cache-max-size"
+	MaxFileKey  = "cache-max-file"
+
+	defaultMaxSize = "20m"
+	defaultMaxFile = "5"
+)
+
+// SplitLogOpts splits the log opts cfg into the options of the cache and
+// those of the logging driver.
+func SplitLogOpts(cfg map[string]string) (map[string]string, map[string]string) {
+	cacheOpts := make(map[string]string)
+	driverOpts := make(map[string]string)
+	for key, value := range cfg {
+		if strings.HasPrefix(key, "cache-") {
+			cacheOpts[key] = value
+		} else {
+			driverOpts[key] = value
+		}
+	}
+	return cacheOpts, driverOpts
+}
+
+// ValidateLogOpt checks the options of the cache.
+func ValidateLogOpt(cfg map[string]string) error {
+	for key, value := range cfg {
+		switch key {
+		case DisabledKey:
+			if _, err := strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("invalid value for %s %s", key, value)
+			}
+		case MaxSizeKey, MaxFileKey:
+		default:
+			return fmt.Errorf("unknown log opt '%s' of the local log cache", key)
+		}
+	}
+	if err := jsonfilelog.ValidateLogOpt(fileOpts(cfg)); err != nil {
+		return fmt.Errorf("invalid options of the local log cache: %v", err)
+	}
+	return nil
+}
+
+// Enabled returns whether the cache options cfg enable the cache.
+func Enabled(cfg map[string]string) bool {
+	disabled, _ := strconv.ParseBool(cfg[DisabledKey])
+	return !disabled
+}
+
+func fileOpts(cfg map[string]string) map[string]string {
+	opts := map[string]string{"max-size": defaultMaxSize, "max-file": defaultMaxFile}
+	if v := cfg[MaxSizeKey]; v != "" {
+		opts["max-size"] = v
+	}
+	if v := cfg[MaxFileKey]; v != "" {
+		opts["max-file"] = v
+	}
+	return opts
+}
+
+// Open opens the cache of the logs at path, with the cache options cfg,
+// without any logging driver.
+func Open(ctx logger.Context, path string, cfg map[string]string) (logger.Logger, error) {
+	ctx.LogPath = path
+	ctx.Config = fileOpts(cfg)
+	return jsonfilelog.New(ctx)
+}
+
+// WithLocalCache returns a logger sending the logs to l and writing them to
+// the cache at path as well, the logs are read back from the cache.
+func WithLocalCache(l logger.Logger, ctx logger.Context, path string, cfg map[string]string) (logger.Logger, error) {
+	c, err := Open(ctx, path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &loggerWithCache{l: l, cache: c}, nil
+}
+
+type loggerWithCache struct {
+	l     logger.Logger
+	cache logger.Logger
+}
+
+func (l *loggerWithCache) Log(msg *logger.Message) error {
+	// The line is written to the cache first, drivers may keep the message
+	if err := l.cache.Log(msg); err != nil {
+		logrus.Errorf("Error writing to the local log cache: %v", err)
+	}
+	return l.l.Log(msg)
+}
+
+func (l *loggerWithCache) ReadLogs(cfg logger.ReadConfig) (io.ReadCloser, error) {
+	return l.cache.(logger.LogReader).ReadLogs(cfg)
+}
+
+func (l *loggerWithCache) Name() string {
+	return l.l.Name()
+}
+
+func (l *loggerWithCache) Close() error {
+	err := l.l.Close()
+	if cerr := l.cache.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (l *loggerWithCache) GetReader() (io.Reader, error) {
+	return l.cache.GetReader()
+}
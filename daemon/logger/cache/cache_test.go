@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/daemon/logger"
+)
+
+type remoteLogger struct {
+	lines []string
+}
+
+func (l *remoteLogger) Log(msg *logger.Message) error {
+	l.lines = append(l.lines, string(msg.Line))
+	return nil
+}
+
+func (l *remoteLogger) Name() string {
+	return "remote"
+}
+
+func (l *remoteLogger) Close() error {
+	return nil
+}
+
+func (l *remoteLogger) GetReader() (io.Reader, error) {
+	return nil, logger.ReadLogsNotSupported
+}
+
+func TestSplitLogOpts(t *testing.T) {
+	cacheOpts, driverOpts := SplitLogOpts(map[string]string{"cache-max-size": "1m", "cache-disabled": "false", "tag": "web"})
+	if !reflect.DeepEqual(cacheOpts, map[string]string{"cache-max-size": "1m", "cache-disabled": "false"}) {
+		t.Fatalf("Wrong cache options %v", cacheOpts)
+	}
+	if !reflect.DeepEqual(driverOpts, map[string]string{"tag": "web"}) {
+		t.Fatalf("Wrong driver options %v", driverOpts)
+	}
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	for _, cfg := range []map[string]string{nil, {"cache-disabled": "true"}, {"cache-max-size": "1m", "cache-max-file": "2"}, {"cache-max-file": "1"}} {
+		if err := ValidateLogOpt(cfg); err != nil {
+			t.Fatalf("%v: %v", cfg, err)
+		}
+	}
+	for _, cfg := range []map[string]string{{"cache-disabled": "maybe"}, {"cache-max-size": "0"}, {"cache-max-file": "0"}, {"cache-compress": "true"}} {
+		if err := ValidateLogOpt(cfg); err == nil {
+			t.Fatalf("Expected an error validating %v", cfg)
+		}
+	}
+
+	if !Enabled(nil) || !Enabled(map[string]string{DisabledKey: "false"}) || Enabled(map[string]string{DisabledKey: "true"}) {
+		t.Fatal("Wrong cache-disabled option")
+	}
+}
+
+func TestWithLocalCache(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-logger-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	path := filepath.Join(tmp, "container-cache.log")
+
+	remote := &remoteLogger{}
+	cid := "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
+	l, err := WithLocalCache(remote, logger.Context{ContainerID: cid}, path, map[string]string{"cache-max-size": "100", "cache-max-file": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if l.Name() != "remote" {
+		t.Fatalf("Wrong name %s", l.Name())
+	}
+
+	for _, line := range []string{"line1", "line2", "line3"} {
+		if err := l.Log(&logger.Message{ContainerID: cid, Line: []byte(line), Source: "stdout"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(remote.lines, []string{"line1", "line2", "line3"}) {
+		t.Fatalf("Wrong lines sent to the driver %v", remote.lines)
+	}
+
+	// Each line is 65 bytes, the cache keeps the 2 last ones
+	c, err := Open(logger.Context{ContainerID: cid}, path, map[string]string{"cache-max-size": "100", "cache-max-file": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for _, reader := range []logger.LogReader{l.(logger.LogReader), c.(logger.LogReader)} {
+		r, err := reader.ReadLogs(logger.ReadConfig{Tail: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"log":"line2\n","stream":"stdout","time":"0001-01-01T00:00:00Z"}
+{"log":"line3\n","stream":"stdout","time":"0001-01-01T00:00:00Z"}
+`
+		if string(res) != expected {
+			t.Fatalf("Wrong logs %q, expected %q", res, expected)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"bufio"
+	"bytes"
 	"io"
 	"sync"
 	"time"
@@ -9,6 +10,9 @@ import (
 	"github.com/Sirupsen/logrus"
 )
 
+// bufSize is the size of the longest line logged in a single message.
+const bufSize = 16 * 1024
+
 // Copier can copy logs from specified sources to Logger and attach
 // ContainerID and Timestamp.
 // Writes are concurrent, so you need implement some sync in your logger
@@ -40,14 +44,32 @@ func (c *Copier) Run() {
 
 func (c *Copier) copySrc(name string, src io.Reader) {
 	defer c.copyJobs.Done()
-	scanner := bufio.NewScanner(src)
-	for scanner.Scan() {
-		if err := c.dst.Log(&Message{ContainerID: c.cid, Line: scanner.Bytes(), Source: name, Timestamp: time.Now().UTC()}); err != nil {
-			logrus.Errorf("Failed to log msg %q for logger %s: %s", scanner.Bytes(), c.dst.Name(), err)
+	reader := bufio.NewReaderSize(src, bufSize)
+	partial := false
+	for {
+		line, err := reader.ReadSlice('\n')
+		if err != nil && err != bufio.ErrBufferFull && err != io.EOF {
+			logrus.Errorf("Error scanning log stream: %s", err)
+			return
 		}
-	}
-	if err := scanner.Err(); err != nil {
-		logrus.Errorf("Error scanning log stream: %s", err)
+		if err == io.EOF && len(line) == 0 && !partial {
+			return
+		}
+		msg := &Message{ContainerID: c.cid, Source: name, Timestamp: time.Now().UTC()}
+		// Lines longer than the buffer are logged in several messages, the
+		// last line of the stream ends the partial one even when empty
+		msg.Partial = err == bufio.ErrBufferFull
+		msg.Line = line
+		if !msg.Partial {
+			msg.Line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte{'\n'}), []byte{'\r'})
+		}
+		if logErr := c.dst.Log(msg); logErr != nil {
+			logrus.Errorf("Failed to log msg %q for logger %s: %s", msg.Line, c.dst.Name(), logErr)
+		}
+		if err == io.EOF {
+			return
+		}
+		partial = msg.Partial
 	}
 }
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"strings"
 	"testing"
 	"time"
 )
@@ -106,3 +107,51 @@ func TestCopier(t *testing.T) {
 		}
 	}
 }
+
+type TestLoggerMessages struct {
+	msgs []Message
+}
+
+func (l *TestLoggerMessages) Log(m *Message) error {
+	msg := *m
+	msg.Line = append([]byte(nil), m.Line...)
+	l.msgs = append(l.msgs, msg)
+	return nil
+}
+
+func (l *TestLoggerMessages) Close() error { return nil }
+
+func (l *TestLoggerMessages) Name() string { return "messages" }
+
+func (l *TestLoggerMessages) GetReader() (io.Reader, error) {
+	return nil, errors.New("not used in the test")
+}
+
+func TestCopierLongLines(t *testing.T) {
+	long := strings.Repeat("a", bufSize*2+10)
+	src := strings.NewReader(long + "\nshort\n" + strings.Repeat("b", bufSize))
+	l := &TestLoggerMessages{}
+	c, err := NewCopier("cid", map[string]io.Reader{"stdout": src}, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Run()
+	c.Wait()
+
+	expected := []struct {
+		size    int
+		partial bool
+	}{
+		{bufSize, true}, {bufSize, true}, {10, false},
+		{len("short"), false},
+		{bufSize, true}, {0, false},
+	}
+	if len(l.msgs) != len(expected) {
+		t.Fatalf("Expected %d messages, got %d", len(expected), len(l.msgs))
+	}
+	for i, e := range expected {
+		if m := l.msgs[i]; len(m.Line) != e.size || m.Partial != e.partial {
+			t.Fatalf("Message %d: expected %d bytes, partial %v, got %d bytes, partial %v", i, e.size, e.partial, len(m.Line), m.Partial)
+		}
+	}
+}
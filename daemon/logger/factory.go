@@ -8,16 +8,26 @@ import (
 // Creator is a method that builds a logging driver instance with given context
 type Creator func(Context) (Logger, error)
 
+// LogOptValidator checks the options of a logging driver, as given with
+// --log-opt
+type LogOptValidator func(cfg map[string]string) error
+
 // Context provides enough information for a logging driver to do its function
 type Context struct {
-	ContainerID   string
-	ContainerName string
-	LogPath       string
+	ContainerID        string
+	ContainerName      string
+	ContainerImageID   string
+	ContainerImageName string
+	ContainerLabels    map[string]string
+	ContainerEnv       []string
+	LogPath            string
+	Config             map[string]string // Options of the logging driver
 }
 
 type logdriverFactory struct {
-	registry map[string]Creator
-	m        sync.Mutex
+	registry     map[string]Creator
+	optValidator map[string]LogOptValidator
+	m            sync.Mutex
 }
 
 func (lf *logdriverFactory) register(name string, c Creator) error {
@@ -42,7 +52,25 @@ func (lf *logdriverFactory) get(name string) (Creator, error) {
 	return c, nil
 }
 
-var factory = &logdriverFactory{registry: make(map[string]Creator)} // global factory instance
+func (lf *logdriverFactory) registerLogOptValidator(name string, l LogOptValidator) error {
+	lf.m.Lock()
+	defer lf.m.Unlock()
+
+	if _, ok := lf.optValidator[name]; ok {
+		return fmt.Errorf("logger: log opt validator named '%s' is already registered", name)
+	}
+	lf.optValidator[name] = l
+	return nil
+}
+
+func (lf *logdriverFactory) getLogOptValidator(name string) LogOptValidator {
+	lf.m.Lock()
+	defer lf.m.Unlock()
+
+	return lf.optValidator[name]
+}
+
+var factory = &logdriverFactory{registry: make(map[string]Creator), optValidator: make(map[string]LogOptValidator)} // global factory instance
 
 // RegisterLogDriver registers the given logging driver builder with given logging
 // driver name.
@@ -54,3 +82,24 @@ func RegisterLogDriver(name string, c Creator) error {
 func GetLogDriver(name string) (Creator, error) {
 	return factory.get(name)
 }
+
+// RegisterLogOptValidator registers the validator of the options of the
+// logging driver name. Drivers without a validator accept no options.
+func RegisterLogOptValidator(name string, l LogOptValidator) error {
+	return factory.registerLogOptValidator(name, l)
+}
+
+// ValidateLogOpts checks the options cfg of the logging driver name.
+func ValidateLogOpts(name string, cfg map[string]string) error {
+	if _, err := factory.get(name); err != nil {
+		return err
+	}
+	validator := factory.getLogOptValidator(name)
+	if validator == nil {
+		for key := range cfg {
+			return fmt.Errorf("unknown log opt '%s' for %s log driver", key, name)
+		}
+		return nil
+	}
+	return validator(cfg)
+}
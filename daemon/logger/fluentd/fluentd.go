@@ -0,0 +1,197 @@
+package fluentd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/pkg/units"
+)
+
+const (
+	name = "fluentd"
+
+	defaultHost        = "127.0.0.1"
+	defaultPort        = "24224"
+	defaultBufferLimit = 1024 * 1024
+	defaultRetryWait   = time.Second
+	defaultMaxRetries  = 10
+)
+
+type Fluentd struct {
+	tag           string
+	containerID   string
+	containerName string
+	extra         map[string]string
+	forwarder     *forwarder
+	assembler     logger.Assembler
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(name, New); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := logger.RegisterLogOptValidator(name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// New creates a logger sending the logs of the container to fluentd with
+// its forward protocol. The records hold the line, its source, the ID and
+// name of the container and the attributes selected by the labels and env
+// options.
+func New(ctx logger.Context) (logger.Logger, error) {
+	if err := ValidateLogOpt(ctx.Config); err != nil {
+		return nil, err
+	}
+	network, address, _ := parseAddress(ctx.Config["fluentd-address"])
+	tag, err := parseTag(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &forwarder{
+		network:     network,
+		address:     address,
+		bufferLimit: defaultBufferLimit,
+		retryWait:   defaultRetryWait,
+		maxRetries:  defaultMaxRetries,
+	}
+	if v := ctx.Config["fluentd-async-connect"]; v != "" {
+		f.async, _ = strconv.ParseBool(v)
+	}
+	if v := ctx.Config["fluentd-buffer-limit"]; v != "" {
+		limit, _ := units.RAMInBytes(v)
+		f.bufferLimit = int(limit)
+	}
+	if v := ctx.Config["fluentd-retry-wait"]; v != "" {
+		f.retryWait, _ = time.ParseDuration(v)
+	}
+	if v := ctx.Config["fluentd-max-retries"]; v != "" {
+		f.maxRetries, _ = strconv.Atoi(v)
+	}
+	if err := f.start(); err != nil {
+		return nil, err
+	}
+
+	return &Fluentd{
+		tag:           tag,
+		containerID:   ctx.ContainerID,
+		containerName: ctx.ContainerName,
+		extra:         ctx.ExtraAttributes(nil),
+		forwarder:     f,
+	}, nil
+}
+
+func (f *Fluentd) Log(msg *logger.Message) error {
+	if msg = f.assembler.Add(msg); msg == nil {
+		return nil
+	}
+	record := make(map[string]string, len(f.extra)+4)
+	for k, v := range f.extra {
+		record[k] = v
+	}
+	record["container_id"] = f.containerID
+	record["container_name"] = f.containerName
+	record["source"] = msg.Source
+	record["log"] = string(msg.Line)
+	return f.forwarder.post(encodeMessage(nil, f.tag, msg.Timestamp.Unix(), record))
+}
+
+func (f *Fluentd) Close() error {
+	return f.forwarder.close()
+}
+
+func (f *Fluentd) Name() string {
+	return name
+}
+
+func (f *Fluentd) GetReader() (io.Reader, error) {
+	return nil, logger.ReadLogsNotSupported
+}
+
+// ValidateLogOpt checks the options of the fluentd driver.
+func ValidateLogOpt(cfg map[string]string) error {
+	for key, value := range cfg {
+		var err error
+		switch key {
+		case "labels", "env":
+		case "fluentd-address":
+			_, _, err = parseAddress(value)
+		case "tag", "fluentd-tag":
+			err = logger.ValidateLogTag(value)
+		case "fluentd-async-connect":
+			_, err = strconv.ParseBool(value)
+		case "fluentd-buffer-limit":
+			var limit int64
+			if limit, err = units.RAMInBytes(value); err == nil && limit <= 0 {
+				err = fmt.Errorf("the limit must be positive")
+			}
+		case "fluentd-retry-wait":
+			var wait time.Duration
+			if wait, err = time.ParseDuration(value); err == nil && wait <= 0 {
+				err = fmt.Errorf("the wait must be positive")
+			}
+		case "fluentd-max-retries":
+			var retries int
+			if retries, err = strconv.Atoi(value); err == nil && retries < 0 {
+				err = fmt.Errorf("the number of retries cannot be negative")
+			}
+		default:
+			return fmt.Errorf("unknown log opt '%s' for fluentd log driver", key)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for %s %s: %v", key, value, err)
+		}
+	}
+	return nil
+}
+
+// parseAddress parses the address of fluentd, host[:port], tcp://host[:port]
+// or unix:///path.
+func parseAddress(address string) (string, string, error) {
+	if address == "" {
+		return "tcp", net.JoinHostPort(defaultHost, defaultPort), nil
+	}
+	if u, err := url.Parse(address); err == nil && u.Scheme == "unix" {
+		if !path.IsAbs(u.Path) {
+			return "", "", fmt.Errorf("the socket must be an absolute path")
+		}
+		return "unix", u.Path, nil
+	}
+
+	hostport := address
+	if u, err := url.Parse(address); err == nil && u.Scheme == "tcp" {
+		if u.Path != "" && u.Path != "/" {
+			return "", "", fmt.Errorf("expected tcp://host[:port]")
+		}
+		hostport = u.Host
+	}
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = hostport, defaultPort
+	}
+	if host == "" {
+		host = defaultHost
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", "", fmt.Errorf("invalid port %s", port)
+	}
+	return "tcp", net.JoinHostPort(host, port), nil
+}
+
+// parseTag returns the tag of the records of the container, fluentd-tag
+// being the deprecated name of the tag option.
+func parseTag(ctx logger.Context) (string, error) {
+	defaultTag := "docker.{{.ID}}"
+	if t := ctx.Config["fluentd-tag"]; t != "" {
+		defaultTag = t
+	}
+	return logger.ParseLogTag(ctx, defaultTag)
+}
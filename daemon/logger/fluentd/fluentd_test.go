@@ -0,0 +1,213 @@
+package fluentd
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+)
+
+func TestEncodeMessage(t *testing.T) {
+	msg := encodeMessage(nil, "docker.a7317399f3f8", 1435167353, map[string]string{"source": "stdout", "log": "hello"})
+	expected := []byte{0x93,
+		0xb3, 'd', 'o', 'c', 'k', 'e', 'r', '.', 'a', '7', '3', '1', '7', '3', '9', '9', 'f', '3', 'f', '8',
+		0xce, 0x55, 0x8a, 0xea, 0x79,
+		0x82,
+		0xa3, 'l', 'o', 'g', 0xa5, 'h', 'e', 'l', 'l', 'o',
+		0xa6, 's', 'o', 'u', 'r', 'c', 'e', 0xa6, 's', 't', 'd', 'o', 'u', 't',
+	}
+	if !bytes.Equal(msg, expected) {
+		t.Fatalf("Wrong message %x, expected %x", msg, expected)
+	}
+
+	long := string(bytes.Repeat([]byte{'a'}, 300))
+	if msg := appendString(nil, long); !bytes.Equal(msg[:3], []byte{0xda, 0x01, 0x2c}) || len(msg) != 303 {
+		t.Fatalf("Wrong long string header %x", msg[:3])
+	}
+	if msg := appendInt(nil, 5); !bytes.Equal(msg, []byte{5}) {
+		t.Fatalf("Wrong small int %x", msg)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	valid := map[string][2]string{
+		"":                       {"tcp", "127.0.0.1:24224"},
+		"fluentd":                {"tcp", "fluentd:24224"},
+		"fluentd:24225":          {"tcp", "fluentd:24225"},
+		"1.2.3.4:24225":          {"tcp", "1.2.3.4:24225"},
+		"tcp://fluentd":          {"tcp", "fluentd:24224"},
+		"tcp://[::1]:24225":      {"tcp", "[::1]:24225"},
+		"unix:///var/run/fluent": {"unix", "/var/run/fluent"},
+	}
+	for address, expected := range valid {
+		network, addr, err := parseAddress(address)
+		if err != nil {
+			t.Fatalf("%s: %v", address, err)
+		}
+		if network != expected[0] || addr != expected[1] {
+			t.Fatalf("%s: expected %s %s, got %s %s", address, expected[0], expected[1], network, addr)
+		}
+	}
+	for _, address := range []string{"fluentd:port", "tcp://fluentd/path", "unix://relative"} {
+		if _, _, err := parseAddress(address); err == nil {
+			t.Fatalf("Expected an error parsing %s", address)
+		}
+	}
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	valid := map[string]string{
+		"fluentd-address":       "fluentd:24224",
+		"fluentd-tag":           "app.{{.Name}}",
+		"fluentd-async-connect": "true",
+		"fluentd-buffer-limit":  "8m",
+		"fluentd-retry-wait":    "500ms",
+		"fluentd-max-retries":   "0",
+		"labels":                "com.example.service",
+		"env":                   "APP",
+	}
+	if err := ValidateLogOpt(valid); err != nil {
+		t.Fatal(err)
+	}
+	invalid := []map[string]string{
+		{"syslog-address": "udp://1.2.3.4"},
+		{"fluentd-tag": "{{.Name"},
+		{"tag": "{{.Unknown}}"},
+		{"fluentd-async-connect": "maybe"},
+		{"fluentd-buffer-limit": "0"},
+		{"fluentd-retry-wait": "1"},
+		{"fluentd-max-retries": "-1"},
+	}
+	for _, cfg := range invalid {
+		if err := ValidateLogOpt(cfg); err == nil {
+			t.Fatalf("Expected an error validating %v", cfg)
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	ctx := logger.Context{
+		ContainerID:   "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657",
+		ContainerName: "/web",
+		Config:        map[string]string{},
+	}
+	tags := []struct {
+		fluentdTag, tag, expected string
+	}{
+		{"", "", "docker.a7317399f3f8"},
+		{"app.{{.Name}}", "", "app.web"},
+		{"app.{{.Name}}", "{{.ID}}", "a7317399f3f8"},
+	}
+	for _, tt := range tags {
+		ctx.Config["fluentd-tag"], ctx.Config["tag"] = tt.fluentdTag, tt.tag
+		tag, err := parseTag(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tag != tt.expected {
+			t.Fatalf("Wrong tag %s, expected %s", tag, tt.expected)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	cid := "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
+	timestamp := time.Unix(1435167353, 0)
+	expected := encodeMessage(nil, "app", timestamp.Unix(), map[string]string{
+		"container_id":   cid,
+		"container_name": "/web",
+		"source":         "stderr",
+		"log":            "hello",
+		"tier":           "web",
+	})
+
+	for _, async := range []string{"false", "true"} {
+		received := make(chan []byte, 1)
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				received <- nil
+				return
+			}
+			defer conn.Close()
+			buf := make([]byte, len(expected))
+			io.ReadFull(conn, buf)
+			received <- buf
+		}()
+
+		f, err := New(logger.Context{
+			ContainerID:     cid,
+			ContainerName:   "/web",
+			ContainerLabels: map[string]string{"tier": "web", "other": "x"},
+			Config:          map[string]string{"fluentd-address": l.Addr().String(), "fluentd-tag": "app", "fluentd-async-connect": async, "labels": "tier"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Log(&logger.Message{ContainerID: cid, Line: []byte("hello"), Source: "stderr", Timestamp: timestamp}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case msg := <-received:
+			if !bytes.Equal(msg, expected) {
+				t.Fatalf("async=%s: wrong message %x, expected %x", async, msg, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("async=%s: timeout waiting for the message", async)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLogAsyncBufferLimit(t *testing.T) {
+	// Nothing listens on the address of a closed listener
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	if _, err := New(logger.Context{ContainerID: "a7317399f3f8", Config: map[string]string{"fluentd-address": address}}); err == nil {
+		t.Fatal("Expected an error connecting to fluentd")
+	}
+
+	f, err := New(logger.Context{
+		ContainerID: "a7317399f3f8",
+		Config:      map[string]string{"fluentd-address": address, "fluentd-async-connect": "true", "fluentd-buffer-limit": "1k", "fluentd-retry-wait": "1h"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &logger.Message{Line: bytes.Repeat([]byte{'a'}, 100), Source: "stdout", Timestamp: time.Now()}
+	for i := 0; ; i++ {
+		if err := f.Log(msg); err != nil {
+			break
+		}
+		if i > 100 {
+			t.Fatal("Expected an error once the buffer is full")
+		}
+	}
+
+	closed := make(chan error)
+	go func() {
+		closed <- f.Close()
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout closing a logger waiting to retry")
+	}
+}
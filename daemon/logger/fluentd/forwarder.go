@@ -0,0 +1,154 @@
+package fluentd
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	dialTimeout  = 5 * time.Second
+	maxRetryWait = time.Minute
+)
+
+// forwarder sends encoded messages to fluentd, retrying with an exponential
+// backoff. Synchronous forwarders send the messages as they are posted, the
+// asynchronous ones buffer them and send them in the background.
+type forwarder struct {
+	network     string
+	address     string
+	async       bool
+	bufferLimit int
+	retryWait   time.Duration
+	maxRetries  int
+
+	mu      sync.Mutex
+	conn    net.Conn
+	pending []byte // Messages waiting to be sent by an asynchronous forwarder
+	closed  bool
+	flush   chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// start connects a synchronous forwarder, or starts sending the messages of
+// an asynchronous one.
+func (f *forwarder) start() error {
+	if !f.async {
+		return f.connect()
+	}
+	f.flush = make(chan struct{}, 1)
+	f.stop = make(chan struct{})
+	f.done = make(chan struct{})
+	go f.run()
+	return nil
+}
+
+func (f *forwarder) connect() error {
+	conn, err := net.DialTimeout(f.network, f.address, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("fluentd: error connecting to %s: %v", f.address, err)
+	}
+	f.conn = conn
+	return nil
+}
+
+func (f *forwarder) post(msg []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return fmt.Errorf("fluentd: the logger is closed")
+	}
+	if !f.async {
+		return f.send(msg)
+	}
+
+	if len(f.pending)+len(msg) > f.bufferLimit {
+		return fmt.Errorf("fluentd: the buffer is full, the message is dropped")
+	}
+	f.pending = append(f.pending, msg...)
+	select {
+	case f.flush <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// run sends the buffered messages of an asynchronous forwarder until it is
+// closed, the messages which cannot be sent are dropped.
+func (f *forwarder) run() {
+	defer close(f.done)
+	for {
+		select {
+		case <-f.flush:
+		case <-f.stop:
+		}
+
+		f.mu.Lock()
+		data, closed := f.pending, f.closed
+		f.pending = nil
+		f.mu.Unlock()
+
+		if len(data) > 0 {
+			if err := f.send(data); err != nil {
+				logrus.Errorf("%v, %d bytes of logs are dropped", err, len(data))
+			}
+		}
+		if closed {
+			return
+		}
+	}
+}
+
+// send writes data to fluentd, reconnecting and retrying until the maximum
+// number of retries. The retries stop once the forwarder is closed.
+func (f *forwarder) send(data []byte) error {
+	var err error
+	for i := 0; ; i++ {
+		if f.conn == nil {
+			err = f.connect()
+		}
+		if f.conn != nil {
+			if _, err = f.conn.Write(data); err == nil {
+				return nil
+			}
+			f.conn.Close()
+			f.conn = nil
+		}
+		if i >= f.maxRetries {
+			return fmt.Errorf("fluentd: error sending logs after %d retries: %v", i, err)
+		}
+
+		wait := f.retryWait << uint(i)
+		if wait > maxRetryWait || wait <= 0 {
+			wait = maxRetryWait
+		}
+		select {
+		case <-time.After(wait):
+		case <-f.stop:
+			return fmt.Errorf("fluentd: error sending logs: %v", err)
+		}
+	}
+}
+
+func (f *forwarder) close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	f.mu.Unlock()
+
+	if f.async {
+		close(f.stop)
+		<-f.done
+	}
+	if f.conn != nil {
+		return f.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package fluentd
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// encodeMessage encodes a message of the forward protocol of fluentd in
+// MessagePack, [tag, time, record], the keys of the record being sorted.
+func encodeMessage(buf []byte, tag string, time int64, record map[string]string) []byte {
+	buf = append(buf, 0x93) // fixarray of 3 elements
+	buf = appendString(buf, tag)
+	buf = appendInt(buf, time)
+
+	keys := make([]string, 0, len(record))
+	for k := range record {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if n := len(keys); n < 16 {
+		buf = append(buf, 0x80|byte(n))
+	} else {
+		buf = append(buf, 0xde, byte(n>>8), byte(n))
+	}
+	for _, k := range keys {
+		buf = appendString(buf, k)
+		buf = appendString(buf, record[k])
+	}
+	return buf
+}
+
+func appendString(buf []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		buf = append(buf, 0xa0|byte(n))
+	case n < 1<<8:
+		buf = append(buf, 0xd9, byte(n))
+	case n < 1<<16:
+		buf = append(buf, 0xda, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, 0xdb, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(n))
+	}
+	return append(buf, s...)
+}
+
+func appendInt(buf []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i < 1<<7:
+		return append(buf, byte(i))
+	case i >= 0 && i < 1<<32:
+		buf = append(buf, 0xce, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(i))
+	default:
+		buf = append(buf, 0xd3, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[len(buf)-8:], uint64(i))
+	}
+	return buf
+}
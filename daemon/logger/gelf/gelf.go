@@ -0,0 +1,222 @@
+package gelf
+
+import (
+	"compress/flate"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/logger"
+)
+
+const (
+	name = "gelf"
+
+	defaultPort = "12201"
+	// The maximum size of the datagrams sent over a WAN
+	defaultChunkSize = 1420
+	minChunkSize     = 128
+	maxChunkSize     = 65507
+)
+
+// The syslog severities of the lines of stdout and stderr
+const (
+	levelInfo  = 6
+	levelError = 3
+)
+
+// The characters of the names of the additional fields
+var invalidFieldChars = regexp.MustCompile(`[^\w\.\-]`)
+
+type Gelf struct {
+	writer    *writer
+	hostname  string
+	fields    map[string]string // The additional fields, with their leading _
+	assembler logger.Assembler
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(name, New); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := logger.RegisterLogOptValidator(name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// New creates a logger sending the logs of the container as GELF messages
+// to Graylog, or any server accepting them, at the gelf-address option.
+func New(ctx logger.Context) (logger.Logger, error) {
+	if err := ValidateLogOpt(ctx.Config); err != nil {
+		return nil, err
+	}
+	network, address, _ := parseAddress(ctx.Config["gelf-address"])
+
+	w := &writer{
+		network:          network,
+		address:          address,
+		compressionType:  ctx.Config["gelf-compression-type"],
+		compressionLevel: flate.DefaultCompression,
+		chunkSize:        defaultChunkSize,
+	}
+	if v := ctx.Config["gelf-compression-level"]; v != "" {
+		w.compressionLevel, _ = strconv.Atoi(v)
+	}
+	if v := ctx.Config["gelf-chunk-size"]; v != "" {
+		w.chunkSize, _ = strconv.Atoi(v)
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	fields := extraFields(ctx)
+	if fields["_tag"], err = logger.ParseLogTag(ctx, "{{.ID}}"); err != nil {
+		return nil, err
+	}
+	return &Gelf{
+		writer:   w,
+		hostname: hostname,
+		fields:   fields,
+	}, nil
+}
+
+func (s *Gelf) Log(msg *logger.Message) error {
+	if msg = s.assembler.Add(msg); msg == nil {
+		return nil
+	}
+	level := levelInfo
+	if msg.Source == "stderr" {
+		level = levelError
+	}
+	m := map[string]interface{}{
+		"version":       "1.1",
+		"host":          s.hostname,
+		"short_message": string(msg.Line),
+		"timestamp":     float64(msg.Timestamp.UnixNano()) / float64(time.Second),
+		"level":         level,
+	}
+	for k, v := range s.fields {
+		m[k] = v
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.writer.write(data)
+}
+
+func (s *Gelf) Close() error {
+	return s.writer.close()
+}
+
+func (s *Gelf) Name() string {
+	return name
+}
+
+func (s *Gelf) GetReader() (io.Reader, error) {
+	return nil, logger.ReadLogsNotSupported
+}
+
+// extraFields returns the additional fields of the messages of the
+// container, its ID, name and image, and the labels and environment
+// variables of the labels and env options.
+func extraFields(ctx logger.Context) map[string]string {
+	fields := ctx.ExtraAttributes(fieldName)
+	fields["_container_id"] = ctx.FullID()
+	fields["_container_name"] = ctx.Name()
+	fields["_image_id"] = ctx.ImageID()
+	fields["_image_name"] = ctx.ImageName()
+	return fields
+}
+
+// fieldName returns the name of the additional field of key, _id being
+// reserved by GELF.
+func fieldName(key string) string {
+	key = invalidFieldChars.ReplaceAllString(key, "_")
+	if key == "id" {
+		key = "id_"
+	}
+	return "_" + key
+}
+
+// ValidateLogOpt checks the options of the gelf driver.
+func ValidateLogOpt(cfg map[string]string) error {
+	for key, value := range cfg {
+		var err error
+		switch key {
+		case "gelf-address":
+			_, _, err = parseAddress(value)
+		case "gelf-compression-type":
+			if value != "gzip" && value != "zlib" && value != "none" {
+				err = fmt.Errorf("expected gzip, zlib or none")
+			}
+		case "gelf-compression-level":
+			var level int
+			if level, err = strconv.Atoi(value); err == nil && (level < flate.DefaultCompression || level > flate.BestCompression) {
+				err = fmt.Errorf("the level must be between %d and %d", flate.DefaultCompression, flate.BestCompression)
+			}
+		case "gelf-chunk-size":
+			var size int
+			if size, err = strconv.Atoi(value); err == nil && (size < minChunkSize || size > maxChunkSize) {
+				err = fmt.Errorf("the size must be between %d and %d", minChunkSize, maxChunkSize)
+			}
+		case "tag":
+			err = logger.ValidateLogTag(value)
+		case "labels", "env":
+		default:
+			return fmt.Errorf("unknown log opt '%s' for gelf log driver", key)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for %s %s: %v", key, value, err)
+		}
+	}
+
+	if cfg["gelf-address"] == "" {
+		return fmt.Errorf("the gelf-address log opt is required by the gelf log driver")
+	}
+	if network, _, _ := parseAddress(cfg["gelf-address"]); network == "tcp" {
+		for _, key := range []string{"gelf-compression-type", "gelf-compression-level", "gelf-chunk-size"} {
+			if _, exists := cfg[key]; exists {
+				return fmt.Errorf("%s only applies to udp gelf addresses", key)
+			}
+		}
+	}
+	return nil
+}
+
+// parseAddress parses the address of the GELF server, udp://host[:port] or
+// tcp://host[:port].
+func parseAddress(address string) (string, string, error) {
+	if address == "" {
+		return "", "", nil
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != "udp" && u.Scheme != "tcp" {
+		return "", "", fmt.Errorf("the scheme must be udp or tcp")
+	}
+	if u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return "", "", fmt.Errorf("expected %s://host[:port]", u.Scheme)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host, port = u.Host, defaultPort
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", "", fmt.Errorf("invalid port %s", port)
+	}
+	return u.Scheme, net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,216 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+)
+
+const cid = "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
+
+func TestChunk(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 30)
+	chunks, err := chunk(data, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || !bytes.Equal(chunks[0], data) {
+		t.Fatal("Expected a small message not to be chunked")
+	}
+
+	if chunks, err = chunk(data, 128); err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(chunks))
+	}
+	var joined []byte
+	for i, c := range chunks {
+		if len(c) > 128 {
+			t.Fatalf("Chunk %d of %d bytes", i, len(c))
+		}
+		if !bytes.Equal(c[:2], chunkMagic) || !bytes.Equal(c[2:10], chunks[0][2:10]) || c[10] != byte(i) || c[11] != 3 {
+			t.Fatalf("Wrong header of chunk %d: %x", i, c[:chunkHeaderSize])
+		}
+		joined = append(joined, c[chunkHeaderSize:]...)
+	}
+	if !bytes.Equal(joined, data) {
+		t.Fatal("Wrong data in the chunks")
+	}
+
+	if _, err := chunk(bytes.Repeat(data, 100), 128); err == nil {
+		t.Fatal("Expected an error for a message needing more than 128 chunks")
+	}
+}
+
+func TestCompress(t *testing.T) {
+	message := []byte(`{"short_message":"hello"}`)
+	for _, compression := range []string{"", "gzip", "zlib", "none"} {
+		w := &writer{compressionType: compression, compressionLevel: -1}
+		data, err := w.compress(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = bytes.NewReader(data)
+		switch compression {
+		case "", "gzip":
+			r, err = gzip.NewReader(r)
+		case "zlib":
+			r, err = zlib.NewReader(r)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", compression, err)
+		}
+		if decompressed, err := ioutil.ReadAll(r); err != nil || !bytes.Equal(decompressed, message) {
+			t.Fatalf("%s: wrong message %q: %v", compression, decompressed, err)
+		}
+	}
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	valid := []map[string]string{
+		{"gelf-address": "udp://graylog", "gelf-compression-type": "zlib", "gelf-compression-level": "9", "gelf-chunk-size": "8154"},
+		{"gelf-address": "tcp://graylog:12202", "labels": "com.example.service", "env": "APP,VERSION"},
+	}
+	for _, cfg := range valid {
+		if err := ValidateLogOpt(cfg); err != nil {
+			t.Fatalf("%v: %v", cfg, err)
+		}
+	}
+	invalid := []map[string]string{
+		nil,
+		{"gelf-address": "http://graylog"},
+		{"gelf-address": "udp://graylog", "gelf-compression-type": "bzip2"},
+		{"gelf-address": "udp://graylog", "gelf-compression-level": "10"},
+		{"gelf-address": "udp://graylog", "gelf-chunk-size": "12"},
+		{"gelf-address": "tcp://graylog", "gelf-compression-type": "none"},
+		{"gelf-address": "udp://graylog", "syslog-tag": "web"},
+	}
+	for _, cfg := range invalid {
+		if err := ValidateLogOpt(cfg); err == nil {
+			t.Fatalf("Expected an error validating %v", cfg)
+		}
+	}
+}
+
+func TestExtraFields(t *testing.T) {
+	fields := extraFields(logger.Context{
+		ContainerID:        cid,
+		ContainerName:      "/web",
+		ContainerImageID:   "82cdea7ab5b555f53c2adf8df75b5d1f6fa3c6fa8e0afef9c5ad96ac8b4a0a4c",
+		ContainerImageName: "nginx",
+		ContainerLabels:    map[string]string{"com.example.service": "frontend", "id": "1", "other": "x"},
+		ContainerEnv:       []string{"APP=shop", "VERSION=1.2", "SECRET=s3cr3t", "NOVALUE"},
+		Config:             map[string]string{"labels": "com.example.service,id,missing", "env": "APP, VERSION"},
+	})
+	expected := map[string]string{
+		"_container_id":        cid,
+		"_container_name":      "web",
+		"_image_id":            "82cdea7ab5b5",
+		"_image_name":          "nginx",
+		"_com.example.service": "frontend",
+		"_id_":                 "1",
+		"_APP":                 "shop",
+		"_VERSION":             "1.2",
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("Wrong fields %v, expected %v", fields, expected)
+	}
+}
+
+func TestLogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	l, err := New(logger.Context{
+		ContainerID:   cid,
+		ContainerName: "/web",
+		Config:        map[string]string{"gelf-address": "udp://" + conn.LocalAddr().String()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := l.Log(&logger.Message{Line: []byte("hello"), Source: "stderr", Timestamp: time.Unix(1435167353, 500000000)}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(buf[:n]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m["version"] != "1.1" || m["short_message"] != "hello" || m["level"] != float64(levelError) || m["timestamp"] != 1435167353.5 || m["_container_name"] != "web" {
+		t.Fatalf("Wrong message %v", m)
+	}
+}
+
+func TestLogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		message, _ := bufio.NewReader(conn).ReadBytes(0)
+		received <- message
+	}()
+
+	l, err := New(logger.Context{
+		ContainerID: cid,
+		Config:      map[string]string{"gelf-address": "tcp://" + ln.Addr().String()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := l.Log(&logger.Message{Line: []byte("hello"), Source: "stdout", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case message := <-received:
+		if len(message) == 0 || message[len(message)-1] != 0 {
+			t.Fatalf("Expected a message terminated by a null byte, got %q", message)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(message[:len(message)-1], &m); err != nil {
+			t.Fatal(err)
+		}
+		if m["short_message"] != "hello" || m["level"] != float64(levelInfo) || m["_container_id"] != cid {
+			t.Fatalf("Wrong message %v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the message")
+	}
+}
@@ -0,0 +1,148 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+const (
+	chunkHeaderSize = 12
+	maxChunks       = 128
+)
+
+// The magic bytes of the chunks of a message
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// writer sends GELF messages over udp, compressed and chunked, or over tcp,
+// terminated by a null byte. It reconnects once when sending a message over
+// tcp fails.
+type writer struct {
+	network          string
+	address          string
+	compressionType  string
+	compressionLevel int
+	chunkSize        int
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (w *writer) connect() error {
+	conn, err := net.Dial(w.network, w.address)
+	if err != nil {
+		return fmt.Errorf("gelf: error connecting to %s: %v", w.address, err)
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *writer) write(message []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.network == "tcp" {
+		data := append(message, 0)
+		if w.conn != nil {
+			if _, err := w.conn.Write(data); err == nil {
+				return nil
+			}
+			w.conn.Close()
+			w.conn = nil
+		}
+		if err := w.connect(); err != nil {
+			return err
+		}
+		_, err := w.conn.Write(data)
+		return err
+	}
+
+	data, err := w.compress(message)
+	if err != nil {
+		return err
+	}
+	chunks, err := chunk(data, w.chunkSize)
+	if err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		if _, err := w.conn.Write(c); err != nil {
+			return fmt.Errorf("gelf: error sending message: %v", err)
+		}
+	}
+	return nil
+}
+
+func (w *writer) compress(message []byte) ([]byte, error) {
+	var (
+		buf = bytes.NewBuffer(nil)
+		c   io.WriteCloser
+		err error
+	)
+	switch w.compressionType {
+	case "none":
+		return message, nil
+	case "zlib":
+		c, err = zlib.NewWriterLevel(buf, w.compressionLevel)
+	default:
+		c, err = gzip.NewWriterLevel(buf, w.compressionLevel)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Write(message); err != nil {
+		return nil, err
+	}
+	if err := c.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// chunk splits data in the chunks of at most size bytes, headers included,
+// of the GELF chunking protocol. Data fitting in a single datagram is not
+// chunked.
+func chunk(data []byte, size int) ([][]byte, error) {
+	if len(data) <= size {
+		return [][]byte{data}, nil
+	}
+	dataSize := size - chunkHeaderSize
+	count := (len(data) + dataSize - 1) / dataSize
+	if count > maxChunks {
+		return nil, fmt.Errorf("gelf: message of %d bytes too large, it needs %d chunks of at most %d", len(data), count, maxChunks)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * dataSize
+		if end > len(data) {
+			end = len(data)
+		}
+		c := make([]byte, 0, chunkHeaderSize+end-i*dataSize)
+		c = append(c, chunkMagic...)
+		c = append(c, id...)
+		c = append(c, byte(i), byte(count))
+		chunks = append(chunks, append(c, data[i*dataSize:end]...))
+	}
+	return chunks, nil
+}
+
+func (w *writer) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -3,42 +3,92 @@ package journald
 import (
 	"fmt"
 	"io"
+	"regexp"
+	"strings"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/coreos/go-systemd/journal"
 	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/pkg/stringid"
 )
 
 const name = "journald"
 
+// The characters of the names of the fields of the journal
+var invalidFieldChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
 type Journald struct {
-	Jmap map[string]string
+	Jmap        map[string]string
+	containerID string
+	closed      chan struct{} // closed by Close, to stop the readers following the logs
+	assembler   logger.Assembler
 }
 
 func init() {
 	if err := logger.RegisterLogDriver(name, New); err != nil {
 		logrus.Fatal(err)
 	}
+	if err := logger.RegisterLogOptValidator(name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
 }
 
+// New creates a logger sending the logs of the container to the journal,
+// with the container and its image as structured fields of the entries, as
+// well as the attributes selected by the labels and env options.
 func New(ctx logger.Context) (logger.Logger, error) {
 	if !journal.Enabled() {
 		return nil, fmt.Errorf("journald is not enabled on this host")
 	}
-	// Strip a leading slash so that people can search for
+	tag, err := logger.ParseLogTag(ctx, "{{.ID}}")
+	if err != nil {
+		return nil, err
+	}
+	// The name has no leading slash so that people can search for
 	// CONTAINER_NAME=foo rather than CONTAINER_NAME=/foo.
-	name := ctx.ContainerName
-	if name[0] == '/' {
-		name = name[1:]
+	jmap := ctx.ExtraAttributes(fieldName)
+	jmap["CONTAINER_ID"] = ctx.ID()
+	jmap["CONTAINER_ID_FULL"] = ctx.FullID()
+	jmap["CONTAINER_NAME"] = ctx.Name()
+	jmap["CONTAINER_TAG"] = tag
+	delete(jmap, "")
+	if ctx.ContainerImageName != "" {
+		jmap["CONTAINER_IMAGE"] = ctx.ContainerImageName
 	}
-	jmap := map[string]string{
-		"CONTAINER_ID":      ctx.ContainerID[:12],
-		"CONTAINER_ID_FULL": ctx.ContainerID,
-		"CONTAINER_NAME":    name}
-	return &Journald{Jmap: jmap}, nil
+	if ctx.ContainerImageID != "" {
+		jmap["CONTAINER_IMAGE_ID"] = stringid.TruncateID(ctx.ContainerImageID)
+	}
+	return &Journald{Jmap: jmap, containerID: ctx.ContainerID, closed: make(chan struct{})}, nil
+}
+
+// fieldName returns the name of the field of the journal of key, made of
+// uppercase letters, digits and underscores, starting with a letter: the
+// fields starting with an underscore are reserved by the journal.
+func fieldName(key string) string {
+	key = invalidFieldChars.ReplaceAllString(strings.ToUpper(key), "_")
+	return strings.TrimLeft(key, "_0123456789")
+}
+
+// ValidateLogOpt checks the options of the journald driver.
+func ValidateLogOpt(cfg map[string]string) error {
+	for key, value := range cfg {
+		switch key {
+		case "tag":
+			if err := logger.ValidateLogTag(value); err != nil {
+				return err
+			}
+		case "labels", "env":
+		default:
+			return fmt.Errorf("unknown log opt '%s' for journald log driver", key)
+		}
+	}
+	return nil
 }
 
 func (s *Journald) Log(msg *logger.Message) error {
+	if msg = s.assembler.Add(msg); msg == nil {
+		return nil
+	}
 	if msg.Source == "stderr" {
 		return journal.Send(string(msg.Line), journal.PriErr, s.Jmap)
 	}
@@ -46,6 +96,11 @@ func (s *Journald) Log(msg *logger.Message) error {
 }
 
 func (s *Journald) Close() error {
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
 	return nil
 }
 
@@ -0,0 +1,27 @@
+package journald
+
+import "testing"
+
+func TestFieldName(t *testing.T) {
+	names := map[string]string{
+		"APP":                 "APP",
+		"com.example.service": "COM_EXAMPLE_SERVICE",
+		"_private":            "PRIVATE",
+		"1st-key":             "ST_KEY",
+		"___":                 "",
+	}
+	for key, expected := range names {
+		if name := fieldName(key); name != expected {
+			t.Fatalf("%s: expected %s, got %s", key, expected, name)
+		}
+	}
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	if err := ValidateLogOpt(map[string]string{"tag": "{{.Name}}", "labels": "tier", "env": "APP"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateLogOpt(map[string]string{"max-size": "10m"}); err == nil {
+		t.Fatal("Expected an error validating max-size")
+	}
+}
@@ -0,0 +1,150 @@
+package journald
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/coreos/go-systemd/journal"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/pkg/jsonlog"
+)
+
+// followGracePeriod is the time journalctl --follow is given to output the
+// last entries of the container once the logger is closed.
+const followGracePeriod = time.Second
+
+// ReadLogs reads the logs of the container back from the journal with
+// journalctl, converted to the format of the json-file driver.
+func (s *Journald) ReadLogs(cfg logger.ReadConfig) (io.ReadCloser, error) {
+	path, err := exec.LookPath("journalctl")
+	if err != nil {
+		return nil, fmt.Errorf("reading logs from journald requires journalctl: %v", err)
+	}
+	args := []string{"--no-pager", "--output=json", "CONTAINER_ID_FULL=" + s.containerID}
+	if cfg.Tail >= 0 {
+		args = append(args, "--lines="+strconv.Itoa(cfg.Tail))
+	} else if cfg.Follow {
+		// --follow shows the last 10 entries otherwise
+		args = append(args, "--lines=all")
+	}
+	if cfg.Follow {
+		args = append(args, "--follow")
+	}
+	if !cfg.Since.IsZero() {
+		// journalctl only has a precision of seconds, the caller filters
+		// the older entries
+		args = append(args, "--since="+cfg.Since.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	cmd := exec.Command(path, args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error running journalctl: %v", err)
+	}
+
+	// journalctl --follow is stopped once the logger is closed
+	exited := make(chan struct{})
+	stopped := make(chan struct{})
+	if cfg.Follow {
+		go func() {
+			select {
+			case <-s.closed:
+			case <-exited:
+				return
+			}
+			select {
+			case <-time.After(followGracePeriod):
+				close(stopped)
+				cmd.Process.Kill()
+			case <-exited:
+			}
+		}()
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		err := convertEntries(out, w)
+		if err != nil {
+			// journalctl blocks on its output once the reader is gone
+			cmd.Process.Kill()
+		}
+		werr := cmd.Wait()
+		close(exited)
+		select {
+		case <-stopped:
+		default:
+			if err == nil && werr != nil {
+				err = fmt.Errorf("journalctl: %v", werr)
+			}
+		}
+		w.CloseWithError(err)
+	}()
+	return r, nil
+}
+
+// convertEntries converts the entries of the journal, read from in as
+// written by journalctl --output=json, to jsonlog.JSONLog objects written to
+// out.
+func convertEntries(in io.Reader, out io.Writer) error {
+	dec := json.NewDecoder(in)
+	enc := json.NewEncoder(out)
+	for {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		l, err := convertEntry(entry)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(l); err != nil {
+			return err
+		}
+	}
+}
+
+func convertEntry(entry map[string]interface{}) (*jsonlog.JSONLog, error) {
+	l := &jsonlog.JSONLog{Stream: "stdout"}
+
+	// journalctl writes messages which are not valid UTF-8 as arrays of
+	// bytes
+	switch msg := entry["MESSAGE"].(type) {
+	case string:
+		l.Log = msg + "\n"
+	case []interface{}:
+		line := make([]byte, 0, len(msg)+1)
+		for _, b := range msg {
+			n, ok := b.(float64)
+			if !ok {
+				return nil, fmt.Errorf("invalid journal message %v", msg)
+			}
+			line = append(line, byte(n))
+		}
+		l.Log = string(append(line, '\n'))
+	case nil:
+	default:
+		return nil, fmt.Errorf("invalid journal message %v", msg)
+	}
+
+	if priority, _ := entry["PRIORITY"].(string); priority == strconv.Itoa(int(journal.PriErr)) {
+		l.Stream = "stderr"
+	}
+
+	if ts, ok := entry["__REALTIME_TIMESTAMP"].(string); ok {
+		usec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid journal timestamp %s", ts)
+		}
+		l.Created = time.Unix(0, usec*int64(time.Microsecond)).UTC()
+	}
+	return l, nil
+}
@@ -0,0 +1,29 @@
+package journald
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConvertEntries(t *testing.T) {
+	in := `{"__REALTIME_TIMESTAMP":"1435167353123456","PRIORITY":"6","MESSAGE":"line1","CONTAINER_ID":"a7317399f3f8"}
+{"__REALTIME_TIMESTAMP":"1435167354000000","PRIORITY":"3","MESSAGE":[108,105,110,101,32,195,169]}
+`
+	out := bytes.NewBuffer(nil)
+	if err := convertEntries(strings.NewReader(in), out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"log":"line1\n","stream":"stdout","time":"2015-06-24T17:35:53.123456Z"}
+{"log":"line é\n","stream":"stderr","time":"2015-06-24T17:35:54Z"}
+`
+	if out.String() != expected {
+		t.Fatalf("Wrong logs %q, expected %q", out.String(), expected)
+	}
+
+	for _, entry := range []string{`{"MESSAGE":12}`, `{"MESSAGE":["a"]}`, `{"MESSAGE":"line","__REALTIME_TIMESTAMP":"now"}`, `{"MESSAGE"`} {
+		if err := convertEntries(strings.NewReader(entry), bytes.NewBuffer(nil)); err == nil {
+			t.Fatalf("Expected an error converting %s", entry)
+		}
+	}
+}
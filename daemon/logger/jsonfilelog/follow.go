@@ -0,0 +1,135 @@
+package jsonfilelog
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/filenotify"
+)
+
+// rotationWait is the wait before checking again for a log file missing
+// during its rotation.
+const rotationWait = 10 * time.Millisecond
+
+// follow returns a reader of logs followed by the lines logged to the log
+// file from offset, files[0] being the log file opened by ReadLogs. The
+// reader returns io.EOF once the logger is closed and the lines logged until
+// then are read.
+func (l *JSONFileLogger) follow(logs io.Reader, files []*os.File, offset int64) io.ReadCloser {
+	r, w := io.Pipe()
+	f := &follower{PipeReader: r, done: make(chan struct{})}
+	go func() {
+		_, err := io.Copy(w, logs)
+		closeFiles(files[1:])
+		if err == nil {
+			err = f.followFile(files[0], offset, l.ctx.LogPath, w, l.closed)
+		} else {
+			files[0].Close()
+		}
+		w.CloseWithError(err)
+	}()
+	return f
+}
+
+type follower struct {
+	*io.PipeReader
+	done      chan struct{} // closed when the reader is closed
+	closeOnce sync.Once
+}
+
+func (f *follower) Close() error {
+	f.closeOnce.Do(func() { close(f.done) })
+	return f.PipeReader.Close()
+}
+
+// followFile copies the lines logged to the log file path from offset in
+// file to w, until stop is closed. The log file is reopened when it is
+// rotated, once the lines of the rotated file are copied, and read again
+// from its start when it is truncated.
+func (f *follower) followFile(file *os.File, offset int64, path string, w io.Writer, stop chan struct{}) error {
+	defer func() { file.Close() }()
+	if _, err := file.Seek(offset, os.SEEK_SET); err != nil {
+		return err
+	}
+
+	watcher := filenotify.New()
+	defer watcher.Close()
+	if err := watcher.Add(path); err != nil {
+		return err
+	}
+
+	stopping := false
+	for {
+		if _, err := io.Copy(w, file); err != nil {
+			return err
+		}
+
+		fi, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		pos, err := file.Seek(0, os.SEEK_CUR)
+		if err != nil {
+			return err
+		}
+		if fi.Size() < pos {
+			if _, err := file.Seek(0, os.SEEK_SET); err != nil {
+				return err
+			}
+			continue
+		}
+
+		pathInfo, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			// The log file is missing for a moment during rotations
+			if stopping {
+				return nil
+			}
+			select {
+			case <-time.After(rotationWait):
+			case <-stop:
+				stopping = true
+			case <-f.done:
+				return nil
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		if !os.SameFile(fi, pathInfo) {
+			rotated, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			// The lines logged before the rotation are copied first
+			if _, err := io.Copy(w, file); err != nil {
+				rotated.Close()
+				return err
+			}
+			file.Close()
+			file = rotated
+			watcher.Remove(path)
+			if err := watcher.Add(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if stopping {
+			return nil
+		}
+
+		select {
+		case <-watcher.Events():
+		case err := <-watcher.Errors():
+			logrus.Errorf("Error watching the log file %s: %v", path, err)
+		case <-stop:
+			// The lines logged until the logger was closed are read
+			stopping = true
+		case <-f.done:
+			return nil
+		}
+	}
+}
@@ -1,15 +1,23 @@
 package jsonfilelog
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/logger"
 	"github.com/docker/docker/pkg/jsonlog"
+	"github.com/docker/docker/pkg/tailfile"
 	"github.com/docker/docker/pkg/timeutils"
+	"github.com/docker/docker/pkg/units"
 )
 
 const (
@@ -23,28 +31,112 @@ type JSONFileLogger struct {
 	f   *os.File   // store for closing
 	mu  sync.Mutex // protects buffer
 
-	ctx logger.Context
+	size     int64 // size of the log file
+	capacity int64 // maximum size of the log file, -1 for no limit
+	maxFiles int   // number of log files kept, the current one included
+	compress bool  // whether the rotated files are compressed
+
+	compressing sync.WaitGroup // compression of the last rotated file
+
+	extra  []byte        // the attributes of the records, marshalled
+	closed chan struct{} // closed by Close, to stop the readers following the logs
+	ctx    logger.Context
 }
 
 func init() {
 	if err := logger.RegisterLogDriver(Name, New); err != nil {
 		logrus.Fatal(err)
 	}
+	if err := logger.RegisterLogOptValidator(Name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
 }
 
-// New creates new JSONFileLogger which writes to filename
+// New creates new JSONFileLogger which writes to filename. With the max-size
+// option, the file is rotated when it would exceed the size, up to max-file
+// files being kept, the rotated ones named after the file with a .1, .2, ...
+// suffix, .1 being the most recent. With the compress option, the rotated
+// files are compressed with gzip and get a .gz suffix. The labels and env
+// options select the attributes of the records.
 func New(ctx logger.Context) (logger.Logger, error) {
+	if err := ValidateLogOpt(ctx.Config); err != nil {
+		return nil, err
+	}
+	capacity := int64(-1)
+	if v := ctx.Config["max-size"]; v != "" {
+		capacity, _ = units.RAMInBytes(v)
+	}
+	maxFiles := 1
+	if v := ctx.Config["max-file"]; v != "" {
+		maxFiles, _ = strconv.Atoi(v)
+	}
+	var compress bool
+	if v := ctx.Config["compress"]; v != "" {
+		compress, _ = strconv.ParseBool(v)
+	}
+
+	var extra []byte
+	if attrs := ctx.ExtraAttributes(nil); len(attrs) > 0 {
+		var err error
+		if extra, err = json.Marshal(attrs); err != nil {
+			return nil, err
+		}
+	}
+
 	log, err := os.OpenFile(ctx.LogPath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
 	if err != nil {
 		return nil, err
 	}
+	size, err := log.Seek(0, os.SEEK_END)
+	if err != nil {
+		log.Close()
+		return nil, err
+	}
 	return &JSONFileLogger{
-		f:   log,
-		buf: bytes.NewBuffer(nil),
-		ctx: ctx,
+		f:        log,
+		buf:      bytes.NewBuffer(nil),
+		size:     size,
+		capacity: capacity,
+		maxFiles: maxFiles,
+		compress: compress,
+		extra:    extra,
+		closed:   make(chan struct{}),
+		ctx:      ctx,
 	}, nil
 }
 
+// ValidateLogOpt checks the options of the json-file driver.
+func ValidateLogOpt(cfg map[string]string) error {
+	for key, value := range cfg {
+		switch key {
+		case "max-size":
+			if size, err := units.RAMInBytes(value); err != nil || size <= 0 {
+				return fmt.Errorf("invalid value for max-size %s, expected a positive size", value)
+			}
+		case "max-file":
+			if n, err := strconv.Atoi(value); err != nil || n < 1 {
+				return fmt.Errorf("invalid value for max-file %s, expected a positive number", value)
+			}
+		case "labels", "env":
+		case "compress":
+			if _, err := strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("invalid value for compress %s, expected a boolean", value)
+			}
+		default:
+			return fmt.Errorf("unknown log opt '%s' for json-file log driver", key)
+		}
+	}
+	if _, exists := cfg["max-file"]; exists && cfg["max-size"] == "" {
+		return fmt.Errorf("max-file requires max-size")
+	}
+	if compress, _ := strconv.ParseBool(cfg["compress"]); compress {
+		if n, _ := strconv.Atoi(cfg["max-file"]); n < 2 {
+			return fmt.Errorf("compress requires max-file to be at least 2")
+		}
+	}
+	return nil
+}
+
 // Log converts logger.Message to jsonlog.JSONLog and serializes it to file
 func (l *JSONFileLogger) Log(msg *logger.Message) error {
 	l.mu.Lock()
@@ -54,12 +146,25 @@ func (l *JSONFileLogger) Log(msg *logger.Message) error {
 	if err != nil {
 		return err
 	}
-	err = (&jsonlog.JSONLogBytes{Log: append(msg.Line, '\n'), Stream: msg.Source, Created: timestamp}).MarshalJSONBuf(l.buf)
+	line := msg.Line
+	if !msg.Partial {
+		// The lines split in partial messages end with the last one
+		line = append(line, '\n')
+	}
+	err = (&jsonlog.JSONLogBytes{Log: line, Stream: msg.Source, Created: timestamp, RawAttrs: l.extra}).MarshalJSONBuf(l.buf)
 	if err != nil {
 		return err
 	}
 	l.buf.WriteByte('\n')
-	_, err = l.buf.WriteTo(l.f)
+
+	if l.capacity > 0 && l.size > 0 && l.size+int64(l.buf.Len()) > l.capacity {
+		if err := l.rotate(); err != nil {
+			l.buf.Reset()
+			return err
+		}
+	}
+	n, err := l.buf.WriteTo(l.f)
+	l.size += n
 	if err != nil {
 		// this buffer is screwed, replace it with another to avoid races
 		l.buf = bytes.NewBuffer(nil)
@@ -68,16 +173,293 @@ func (l *JSONFileLogger) Log(msg *logger.Message) error {
 	return nil
 }
 
+// rotate renames the log files to the next suffix, dropping the oldest one,
+// and starts a new log file. The renames replace the files atomically, so
+// readers see either the old or the new file under each name. The compressed
+// files keep their .gz suffix.
+func (l *JSONFileLogger) rotate() error {
+	path := l.ctx.LogPath
+	if l.maxFiles > 1 {
+		// The last rotated file is renamed below, its compression must be over
+		l.compressing.Wait()
+		for _, ext := range []string{"", ".gz"} {
+			oldest := fmt.Sprintf("%s.%d%s", path, l.maxFiles-1, ext)
+			if err := os.Remove(oldest); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error removing log file %s: %v", oldest, err)
+			}
+		}
+		for i := l.maxFiles - 1; i > 1; i-- {
+			for _, ext := range []string{"", ".gz"} {
+				older := fmt.Sprintf("%s.%d%s", path, i-1, ext)
+				if err := os.Rename(older, fmt.Sprintf("%s.%d%s", path, i, ext)); err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("error rotating log file %s: %v", older, err)
+				}
+			}
+		}
+		if err := os.Rename(path, path+".1"); err != nil {
+			return fmt.Errorf("error rotating log file %s: %v", path, err)
+		}
+		if l.compress {
+			l.compressing.Add(1)
+			go func() {
+				defer l.compressing.Done()
+				if err := compressFile(path + ".1"); err != nil {
+					logrus.Errorf("Error compressing log file %s.1: %v", path, err)
+				}
+			}()
+		}
+	}
+
+	// The new file is opened before the old one is closed, the logger keeps
+	// the old file when the new one cannot be opened
+	flags := os.O_RDWR | os.O_APPEND | os.O_CREATE | os.O_TRUNC
+	f, err := os.OpenFile(path, flags, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening log file %s: %v", path, err)
+	}
+	l.f.Close()
+	l.f = f
+	l.size = 0
+	return nil
+}
+
+// compressFile compresses the file path to path.gz and removes it. The
+// compressed file is renamed into place once complete, and the file is only
+// removed afterwards, so readers always find one of them.
+func compressFile(path string) (err error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := path + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
 func (l *JSONFileLogger) GetReader() (io.Reader, error) {
 	return os.Open(l.ctx.LogPath)
 }
 
+// ReadLogs returns the logs of the file and of its rotated files, only the
+// last cfg.Tail lines when cfg.Tail is not negative. The compressed rotated
+// files are decompressed as they are read. With cfg.Follow, the lines logged
+// afterwards are returned as well, until the logger is closed.
+func (l *JSONFileLogger) ReadLogs(cfg logger.ReadConfig) (io.ReadCloser, error) {
+	files, err := l.openLogFiles()
+	if err != nil {
+		return nil, err
+	}
+	// The logs are read up to the current end of the log file, the lines
+	// logged afterwards are followed from there
+	current := files[0]
+	size, err := current.Seek(0, os.SEEK_END)
+	if err != nil {
+		closeFiles(files)
+		return nil, err
+	}
+	section := io.NewSectionReader(current, 0, size)
+
+	var logs io.Reader
+	if cfg.Tail < 0 {
+		readers := []io.Reader{section}
+		for _, f := range files[1:] {
+			r, err := newFileReader(f)
+			if err != nil {
+				closeFiles(files)
+				return nil, err
+			}
+			readers = append([]io.Reader{r}, readers...)
+		}
+		logs = io.MultiReader(readers...)
+	} else {
+		lines, err := tailLogFiles(section, files[1:], cfg.Tail)
+		closeFiles(files[1:])
+		if err != nil {
+			current.Close()
+			return nil, err
+		}
+		buf := bytes.NewBuffer(nil)
+		for _, line := range lines {
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		logs, files = buf, files[:1]
+	}
+
+	if cfg.Follow {
+		return l.follow(logs, files, size), nil
+	}
+	return &multiReadCloser{Reader: logs, files: files}, nil
+}
+
+// tailLogFiles returns the last n lines of the log file current and of its
+// rotated files.
+func tailLogFiles(current io.ReadSeeker, rotated []*os.File, n int) ([][]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	lines, err := tailfile.TailFile(current, n)
+	if err != nil {
+		return nil, err
+	}
+	// The lines are taken from the most recent file first
+	for _, f := range rotated {
+		if len(lines) >= n {
+			break
+		}
+		var fileLines [][]byte
+		if isCompressed(f) {
+			fileLines, err = tailCompressedFile(f, n-len(lines))
+		} else {
+			fileLines, err = tailfile.TailFile(f, n-len(lines))
+		}
+		if err != nil {
+			return nil, err
+		}
+		lines = append(fileLines, lines...)
+	}
+	return lines, nil
+}
+
+func isCompressed(f *os.File) bool {
+	return strings.HasSuffix(f.Name(), ".gz")
+}
+
+// newFileReader returns a reader of the lines of the log file f.
+func newFileReader(f *os.File) (io.Reader, error) {
+	if isCompressed(f) {
+		return gzip.NewReader(f)
+	}
+	return f, nil
+}
+
+// tailCompressedFile returns the last n lines of the compressed log file f,
+// which is read through as it cannot be read backwards.
+func tailCompressedFile(f *os.File, n int) ([][]byte, error) {
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	var lines [][]byte
+	r := bufio.NewReader(zr)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if len(lines) == n {
+				lines = lines[1:]
+			}
+			lines = append(lines, bytes.TrimSuffix(line, []byte{'\n'}))
+		}
+		if err == io.EOF {
+			return lines, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// openLogFiles opens the log file and its rotated files, the most recent
+// first. A file rotated while they are opened is only returned once. A
+// rotated file is opened uncompressed while it is being compressed.
+func (l *JSONFileLogger) openLogFiles() ([]*os.File, error) {
+	f, err := os.Open(l.ctx.LogPath)
+	if err != nil {
+		return nil, err
+	}
+	files := []*os.File{f}
+	var infos []os.FileInfo
+	if fi, err := f.Stat(); err == nil {
+		infos = append(infos, fi)
+	}
+
+	for i := 1; i < l.maxFiles; i++ {
+		name := fmt.Sprintf("%s.%d", l.ctx.LogPath, i)
+		f, err := os.Open(name)
+		if os.IsNotExist(err) {
+			f, err = os.Open(name + ".gz")
+		}
+		if os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			closeFiles(files)
+			return nil, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			closeFiles(files)
+			return nil, err
+		}
+		seen := false
+		for _, info := range infos {
+			seen = seen || os.SameFile(fi, info)
+		}
+		if seen {
+			f.Close()
+			continue
+		}
+		files = append(files, f)
+		infos = append(infos, fi)
+	}
+	return files, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+type multiReadCloser struct {
+	io.Reader
+	files []*os.File
+}
+
+func (r *multiReadCloser) Close() error {
+	closeFiles(r.files)
+	return nil
+}
+
 func (l *JSONFileLogger) LogPath() string {
 	return l.ctx.LogPath
 }
 
-// Close closes underlying file
+// Close closes underlying file, once the last rotated file is compressed,
+// and stops the readers following the logs once they read all of them.
 func (l *JSONFileLogger) Close() error {
+	l.compressing.Wait()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-l.closed:
+	default:
+		close(l.closed)
+	}
 	return l.f.Close()
 }
 
@@ -1,6 +1,8 @@
 package jsonfilelog
 
 import (
+	"compress/gzip"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -37,6 +39,12 @@ func TestJSONFileLogger(t *testing.T) {
 	if err := l.Log(&logger.Message{ContainerID: cid, Line: []byte("line3"), Source: "src3"}); err != nil {
 		t.Fatal(err)
 	}
+	if err := l.Log(&logger.Message{ContainerID: cid, Line: []byte("long "), Source: "src1", Partial: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Log(&logger.Message{ContainerID: cid, Line: []byte("line4"), Source: "src1"}); err != nil {
+		t.Fatal(err)
+	}
 	res, err := ioutil.ReadFile(filename)
 	if err != nil {
 		t.Fatal(err)
@@ -44,6 +52,8 @@ func TestJSONFileLogger(t *testing.T) {
 	expected := `{"log":"line1\n","stream":"src1","time":"0001-01-01T00:00:00Z"}
 {"log":"line2\n","stream":"src2","time":"0001-01-01T00:00:00Z"}
 {"log":"line3\n","stream":"src3","time":"0001-01-01T00:00:00Z"}
+{"log":"long ","stream":"src1","time":"0001-01-01T00:00:00Z"}
+{"log":"line4\n","stream":"src1","time":"0001-01-01T00:00:00Z"}
 `
 
 	if string(res) != expected {
@@ -51,6 +61,264 @@ func TestJSONFileLogger(t *testing.T) {
 	}
 }
 
+func TestJSONFileLoggerWithOpts(t *testing.T) {
+	cid := "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
+	tmp, err := ioutil.TempDir("", "docker-logger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	filename := filepath.Join(tmp, "container.log")
+	// Each line is 64 bytes, so 2 lines fit in a file
+	ctx := logger.Context{
+		ContainerID: cid,
+		LogPath:     filename,
+		Config:      map[string]string{"max-size": "150", "max-file": "3"},
+	}
+	l, err := New(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 7; i++ {
+		if err := l.Log(&logger.Message{ContainerID: cid, Line: []byte(fmt.Sprintf("line%d", i)), Source: "src1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	line := func(i int) string {
+		return fmt.Sprintf(`{"log":"line%d\n","stream":"src1","time":"0001-01-01T00:00:00Z"}`+"\n", i)
+	}
+	expected := map[string]string{
+		filename:        line(6),
+		filename + ".1": line(4) + line(5),
+		filename + ".2": line(2) + line(3),
+	}
+	for name, content := range expected {
+		res, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(res) != content {
+			t.Fatalf("Wrong content of %s: %q, expected %q", name, res, content)
+		}
+	}
+	if _, err := os.Stat(filename + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("Expected only 3 log files, got %s.3: %v", filename, err)
+	}
+
+	reader := l.(logger.LogReader)
+	for tail, first := range map[int]int{-1: 2, 0: 7, 2: 5, 4: 3, 10: 2} {
+		r, err := reader.ReadLogs(logger.ReadConfig{Tail: tail})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := ""
+		for i := first; i < 7; i++ {
+			content += line(i)
+		}
+		if string(res) != content {
+			t.Fatalf("Wrong logs with tail %d: %q, expected %q", tail, res, content)
+		}
+	}
+}
+
+func TestJSONFileLoggerAttrs(t *testing.T) {
+	cid := "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
+	tmp, err := ioutil.TempDir("", "docker-logger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	filename := filepath.Join(tmp, "container.log")
+	l, err := New(logger.Context{
+		ContainerID:     cid,
+		LogPath:         filename,
+		ContainerLabels: map[string]string{"tier": "web", "other": "x"},
+		ContainerEnv:    []string{"APP=shop", "SECRET=s3cr3t"},
+		Config:          map[string]string{"labels": "tier", "env": "APP"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if err := l.Log(&logger.Message{ContainerID: cid, Line: []byte("line1"), Source: "src1"}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"log":"line1\n","stream":"src1","time":"0001-01-01T00:00:00Z","attrs":{"APP":"shop","tier":"web"}}` + "\n"
+	if string(res) != expected {
+		t.Fatalf("Wrong log content: %q, expected %q", res, expected)
+	}
+}
+
+func TestJSONFileLoggerCompress(t *testing.T) {
+	cid := "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
+	tmp, err := ioutil.TempDir("", "docker-logger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	filename := filepath.Join(tmp, "container.log")
+	l, err := New(logger.Context{
+		ContainerID: cid,
+		LogPath:     filename,
+		Config:      map[string]string{"max-size": "150", "max-file": "3", "compress": "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 7; i++ {
+		if err := l.Log(&logger.Message{ContainerID: cid, Line: []byte(fmt.Sprintf("line%d", i)), Source: "src1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.(*JSONFileLogger).compressing.Wait()
+
+	line := func(i int) string {
+		return fmt.Sprintf(`{"log":"line%d\n","stream":"src1","time":"0001-01-01T00:00:00Z"}`+"\n", i)
+	}
+	expected := map[string]string{
+		filename + ".1.gz": line(4) + line(5),
+		filename + ".2.gz": line(2) + line(3),
+	}
+	for name, content := range expected {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := ioutil.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(res) != content {
+			t.Fatalf("Wrong content of %s: %q, expected %q", name, res, content)
+		}
+	}
+	for _, name := range []string{filename + ".1", filename + ".2", filename + ".3.gz"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Fatalf("Expected no %s: %v", name, err)
+		}
+	}
+
+	reader := l.(logger.LogReader)
+	for tail, first := range map[int]int{-1: 2, 2: 5, 3: 4, 10: 2} {
+		r, err := reader.ReadLogs(logger.ReadConfig{Tail: tail})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := ""
+		for i := first; i < 7; i++ {
+			content += line(i)
+		}
+		if string(res) != content {
+			t.Fatalf("Wrong logs with tail %d: %q, expected %q", tail, res, content)
+		}
+	}
+}
+
+func TestJSONFileLoggerFollow(t *testing.T) {
+	cid := "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
+	tmp, err := ioutil.TempDir("", "docker-logger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	filename := filepath.Join(tmp, "container.log")
+	l, err := New(logger.Context{
+		ContainerID: cid,
+		LogPath:     filename,
+		Config:      map[string]string{"max-size": "150", "max-file": "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := func(i int) string {
+		return fmt.Sprintf(`{"log":"line%d\n","stream":"src1","time":"0001-01-01T00:00:00Z"}`+"\n", i)
+	}
+	log := func(i int) {
+		if err := l.Log(&logger.Message{ContainerID: cid, Line: []byte(fmt.Sprintf("line%d", i)), Source: "src1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		log(i)
+	}
+
+	r, err := l.(logger.LogReader).ReadLogs(logger.ReadConfig{Tail: 1, Follow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	received := make(chan string, 1)
+	go func() {
+		res, err := ioutil.ReadAll(r)
+		if err != nil {
+			received <- err.Error()
+		}
+		received <- string(res)
+	}()
+
+	// The lines are followed across the rotations of the file, until the
+	// logger is closed
+	for i := 3; i < 10; i++ {
+		log(i)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ""
+	for i := 2; i < 10; i++ {
+		expected += line(i)
+	}
+	select {
+	case res := <-received:
+		if res != expected {
+			t.Fatalf("Wrong logs %q, expected %q", res, expected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the end of the logs")
+	}
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	for _, cfg := range []map[string]string{nil, {"max-size": "10m"}, {"max-size": "1k", "max-file": "5"}, {"max-size": "1k", "max-file": "2", "compress": "true"}, {"compress": "false"}, {"labels": "tier", "env": "APP,VERSION"}} {
+		if err := ValidateLogOpt(cfg); err != nil {
+			t.Fatalf("%v: %v", cfg, err)
+		}
+	}
+	for _, cfg := range []map[string]string{{"max-size": "0"}, {"max-size": "ten"}, {"max-size": "1k", "max-file": "0"}, {"max-file": "2"}, {"syslog-tag": "web"}, {"max-size": "1k", "compress": "true"}, {"max-size": "1k", "max-file": "2", "compress": "yes please"}} {
+		if err := ValidateLogOpt(cfg); err == nil {
+			t.Fatalf("Expected an error validating %v", cfg)
+		}
+	}
+}
+
 func BenchmarkJSONFileLogger(b *testing.B) {
 	cid := "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
 	tmp, err := ioutil.TempDir("", "docker-logger-")
@@ -14,6 +14,7 @@ type Message struct {
 	Line        []byte
 	Source      string
 	Timestamp   time.Time
+	Partial     bool // The line continues in the next message of the source
 }
 
 // Logger is interface for docker logging drivers
@@ -23,3 +24,17 @@ type Logger interface {
 	Close() error
 	GetReader() (io.Reader, error)
 }
+
+// ReadConfig is the selection of the logs read back from a logging driver.
+type ReadConfig struct {
+	Since  time.Time // Drivers may return older logs, callers filter them
+	Tail   int       // Number of lines from the end, all of them if negative
+	Follow bool      // Return the lines logged until the logger is closed
+}
+
+// LogReader is implemented by the logging drivers which can read the logs
+// back, in the format of the json-file driver: one jsonlog.JSONLog object per
+// line.
+type LogReader interface {
+	ReadLogs(ReadConfig) (io.ReadCloser, error)
+}
@@ -0,0 +1,40 @@
+package logger
+
+import "sync"
+
+// MaxLineSize is the size up to which the partial messages of a line are
+// reassembled, longer lines are kept in several messages.
+const MaxLineSize = 1024 * 1024
+
+// Assembler reassembles the lines the copier splits in partial messages, for
+// the logging drivers sending a record per line.
+type Assembler struct {
+	mu      sync.Mutex
+	pending map[string]*Message // partial line by source
+}
+
+// Add adds msg to the line of its source, and returns the line when it is
+// complete or reached MaxLineSize, or nil.
+func (a *Assembler) Add(msg *Message) *Message {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p := a.pending[msg.Source]
+	if p == nil {
+		if !msg.Partial {
+			return msg
+		}
+		if a.pending == nil {
+			a.pending = make(map[string]*Message)
+		}
+		// The line of msg is only valid during the call to Log
+		p = &Message{ContainerID: msg.ContainerID, Source: msg.Source, Timestamp: msg.Timestamp}
+		a.pending[msg.Source] = p
+	}
+	p.Line = append(p.Line, msg.Line...)
+	p.Partial = msg.Partial
+	if p.Partial && len(p.Line) < MaxLineSize {
+		return nil
+	}
+	delete(a.pending, msg.Source)
+	return p
+}
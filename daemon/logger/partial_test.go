@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssembler(t *testing.T) {
+	var a Assembler
+	if m := a.Add(&Message{Source: "stdout", Line: []byte("whole")}); m == nil || string(m.Line) != "whole" {
+		t.Fatalf("Expected the whole line, got %+v", m)
+	}
+	if m := a.Add(&Message{Source: "stdout", Line: []byte("hel"), Partial: true}); m != nil {
+		t.Fatalf("Expected no line, got %+v", m)
+	}
+	if m := a.Add(&Message{Source: "stderr", Line: []byte("error")}); m == nil || string(m.Line) != "error" {
+		t.Fatalf("Expected the line of stderr, got %+v", m)
+	}
+	if m := a.Add(&Message{Source: "stdout", Line: []byte("lo")}); m == nil || string(m.Line) != "hello" || m.Partial {
+		t.Fatalf("Expected the reassembled line, got %+v", m)
+	}
+
+	// Lines are cut at MaxLineSize
+	part := []byte(strings.Repeat("a", MaxLineSize/2))
+	a.Add(&Message{Source: "stdout", Line: part, Partial: true})
+	m := a.Add(&Message{Source: "stdout", Line: part, Partial: true})
+	if m == nil || len(m.Line) != MaxLineSize || !m.Partial {
+		t.Fatalf("Expected a partial line of %d bytes, got %v", MaxLineSize, m)
+	}
+	if m := a.Add(&Message{Source: "stdout", Line: []byte("end")}); m == nil || string(m.Line) != "end" {
+		t.Fatalf("Expected the end of the line, got %+v", m)
+	}
+}
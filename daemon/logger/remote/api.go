@@ -0,0 +1,76 @@
+package remote
+
+import "time"
+
+// The types below are the JSON payloads of the LogDriver plugin protocol.
+// Every response may carry an error message in Err.
+
+type response struct {
+	Err string
+}
+
+func (r *response) getError() string {
+	return r.Err
+}
+
+type maybeError interface {
+	getError() string
+}
+
+// logInfo describes the container whose logs are sent to the plugin, Config
+// being the log opts of the container.
+type logInfo struct {
+	Config             map[string]string
+	ContainerID        string
+	ContainerName      string
+	ContainerImageID   string
+	ContainerImageName string
+	ContainerLabels    map[string]string
+	ContainerEnv       []string
+}
+
+// startLoggingRequest is sent to LogDriver.StartLogging. File is the FIFO
+// the daemon writes the log entries of the container to.
+type startLoggingRequest struct {
+	File string
+	Info logInfo
+}
+
+// stopLoggingRequest is sent to LogDriver.StopLogging once the daemon closed
+// the FIFO.
+type stopLoggingRequest struct {
+	File string
+}
+
+// capabilitiesResponse is returned by LogDriver.Capabilities.
+type capabilitiesResponse struct {
+	response
+	Cap capabilities
+}
+
+type capabilities struct {
+	// ReadLogs is whether the plugin implements LogDriver.ReadLogs
+	ReadLogs bool
+}
+
+// readLogsRequest is sent to LogDriver.ReadLogs, which streams the log
+// entries of the container in response.
+type readLogsRequest struct {
+	Info   logInfo
+	Config readConfig
+}
+
+type readConfig struct {
+	Since  time.Time
+	Tail   int
+	Follow bool
+}
+
+// logEntry is a line of the logs, written to the FIFO and streamed by
+// LogDriver.ReadLogs as a sequence of JSON objects.
+type logEntry struct {
+	Source   string
+	TimeNano int64
+	Line     []byte
+	Partial  bool
+}
@@ -0,0 +1,226 @@
+// Package remote implements logging drivers sending the logs of containers
+// to plugins implementing the LogDriver protocol.
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/pkg/jsonlog"
+	"github.com/docker/docker/pkg/plugins"
+	"github.com/docker/docker/pkg/stringid"
+)
+
+// PluginType is the name of the subsystem logging plugins implement.
+const PluginType = "LogDriver"
+
+// fifoDir is the directory of the FIFOs the logs are written to, plugins
+// run on the host and open them there.
+var fifoDir = "/run/docker/logging"
+
+type driver struct {
+	name         string
+	endpoint     *plugins.Client
+	capabilities capabilities
+}
+
+func init() {
+	plugins.Handle(PluginType, func(name string, client *plugins.Client) {
+		d := newDriver(name, client)
+		if err := logger.RegisterLogDriver(name, d.newLogger); err != nil {
+			logrus.Errorf("Error registering logging plugin %s: %v", name, err)
+			return
+		}
+		// The plugin checks the log opts when the container starts logging
+		if err := logger.RegisterLogOptValidator(name, func(map[string]string) error { return nil }); err != nil {
+			logrus.Errorf("Error registering logging plugin %s: %v", name, err)
+		}
+	})
+}
+
+// newDriver returns the driver of the plugin name, after asking the plugin
+// for its capabilities.
+func newDriver(name string, client *plugins.Client) *driver {
+	d := &driver{name: name, endpoint: client}
+	var res capabilitiesResponse
+	if err := d.call("Capabilities", nil, &res); err != nil {
+		logrus.Debugf("Logging plugin %s did not report its capabilities, assuming it cannot read logs: %v", name, err)
+		return d
+	}
+	d.capabilities = res.Cap
+	return d
+}
+
+// Load activates the logging plugin named name and returns its driver.
+func Load(name string) (logger.Creator, error) {
+	if _, err := plugins.Get(name, PluginType); err != nil {
+		return nil, err
+	}
+	return logger.GetLogDriver(name)
+}
+
+func (d *driver) call(methodName string, arg interface{}, retVal maybeError) error {
+	method := PluginType + "." + methodName
+	if err := d.endpoint.Call(method, arg, retVal); err != nil {
+		return err
+	}
+	if e := retVal.getError(); e != "" {
+		return fmt.Errorf("%s: %s", d.name, e)
+	}
+	return nil
+}
+
+// newLogger returns a logger of the container sending its logs to the
+// plugin. The plugin is asked to start logging when the first line is
+// logged, so that loggers only reading the logs back do not start a stream.
+func (d *driver) newLogger(ctx logger.Context) (logger.Logger, error) {
+	l := &pluginLogger{
+		driver: d,
+		info: logInfo{
+			Config:             ctx.Config,
+			ContainerID:        ctx.ContainerID,
+			ContainerName:      ctx.ContainerName,
+			ContainerImageID:   ctx.ContainerImageID,
+			ContainerImageName: ctx.ContainerImageName,
+			ContainerLabels:    ctx.ContainerLabels,
+			ContainerEnv:       ctx.ContainerEnv,
+		},
+	}
+	if d.capabilities.ReadLogs {
+		return &readingLogger{l}, nil
+	}
+	return l, nil
+}
+
+type pluginLogger struct {
+	driver *driver
+	info   logInfo
+
+	mu     sync.Mutex
+	file   string
+	fifo   *os.File
+	enc    *json.Encoder
+	closed bool
+}
+
+// start creates the FIFO of the logs and asks the plugin to read it.
+func (l *pluginLogger) start() error {
+	if l.closed {
+		return fmt.Errorf("%s: the logger is closed", l.driver.name)
+	}
+	if l.fifo != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(fifoDir, 0700); err != nil {
+		return err
+	}
+	file := filepath.Join(fifoDir, fmt.Sprintf("%s-%s", l.info.ContainerID, stringid.GenerateRandomID()[:12]))
+	if err := syscall.Mkfifo(file, 0600); err != nil {
+		return fmt.Errorf("error creating the FIFO of the logs: %v", err)
+	}
+	// The FIFO is opened for reading as well, so that opening it does not
+	// block until the plugin opens it
+	fifo, err := os.OpenFile(file, os.O_RDWR, 0600)
+	if err != nil {
+		os.Remove(file)
+		return err
+	}
+	if err := l.driver.call("StartLogging", &startLoggingRequest{File: file, Info: l.info}, &response{}); err != nil {
+		fifo.Close()
+		os.Remove(file)
+		return err
+	}
+	l.file, l.fifo, l.enc = file, fifo, json.NewEncoder(fifo)
+	return nil
+}
+
+func (l *pluginLogger) Log(msg *logger.Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.start(); err != nil {
+		return err
+	}
+	return l.enc.Encode(&logEntry{Source: msg.Source, TimeNano: msg.Timestamp.UnixNano(), Line: msg.Line, Partial: msg.Partial})
+}
+
+// Close closes the FIFO, the plugin reads the entries left in it, and tells
+// the plugin logging stopped.
+func (l *pluginLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if l.fifo == nil {
+		return nil
+	}
+	l.fifo.Close()
+	l.fifo = nil
+	defer os.Remove(l.file)
+	return l.driver.call("StopLogging", &stopLoggingRequest{File: l.file}, &response{})
+}
+
+func (l *pluginLogger) Name() string {
+	return l.driver.name
+}
+
+func (l *pluginLogger) GetReader() (io.Reader, error) {
+	return nil, logger.ReadLogsNotSupported
+}
+
+// readingLogger is the logger of the plugins which read logs back.
+type readingLogger struct {
+	*pluginLogger
+}
+
+// ReadLogs streams the logs of the container from the plugin, converted to
+// the format of the json-file driver.
+func (l *readingLogger) ReadLogs(cfg logger.ReadConfig) (io.ReadCloser, error) {
+	body, err := l.driver.endpoint.Stream(PluginType+".ReadLogs", &readLogsRequest{
+		Info:   l.info,
+		Config: readConfig{Since: cfg.Since, Tail: cfg.Tail, Follow: cfg.Follow},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(convertEntries(body, w))
+		body.Close()
+	}()
+	return r, nil
+}
+
+// convertEntries converts the log entries read from in to jsonlog.JSONLog
+// objects written to out.
+func convertEntries(in io.Reader, out io.Writer) error {
+	dec := json.NewDecoder(in)
+	enc := json.NewEncoder(out)
+	for {
+		var entry logEntry
+		if err := dec.Decode(&entry); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		l := &jsonlog.JSONLog{
+			Log:     string(entry.Line),
+			Stream:  entry.Source,
+			Created: time.Unix(0, entry.TimeNano).UTC(),
+		}
+		if !entry.Partial {
+			l.Log += "\n"
+		}
+		if err := enc.Encode(l); err != nil {
+			return err
+		}
+	}
+}
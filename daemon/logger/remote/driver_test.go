@@ -0,0 +1,186 @@
+package remote
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/pkg/jsonlog"
+	"github.com/docker/docker/pkg/plugins"
+)
+
+const cid = "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
+
+func setupPlugin(t *testing.T) (*http.ServeMux, *driver, func()) {
+	tmpdir, err := ioutil.TempDir("", "logging-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldFifoDir := fifoDir
+	fifoDir = tmpdir
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	client, err := plugins.NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &driver{name: "test", endpoint: client}
+	return mux, d, func() {
+		server.Close()
+		fifoDir = oldFifoDir
+		os.RemoveAll(tmpdir)
+	}
+}
+
+func handle(mux *http.ServeMux, method string, fn func(body []byte) interface{}) {
+	mux.HandleFunc("/"+PluginType+"."+method, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/vnd.docker.plugins.v1+json")
+		json.NewEncoder(w).Encode(fn(body))
+	})
+}
+
+func TestLog(t *testing.T) {
+	mux, d, teardown := setupPlugin(t)
+	defer teardown()
+
+	entries := make(chan logEntry, 2)
+	var started startLoggingRequest
+	handle(mux, "StartLogging", func(body []byte) interface{} {
+		if err := json.Unmarshal(body, &started); err != nil {
+			return response{Err: err.Error()}
+		}
+		f, err := os.Open(started.File)
+		if err != nil {
+			return response{Err: err.Error()}
+		}
+		go func() {
+			defer f.Close()
+			dec := json.NewDecoder(f)
+			for {
+				var entry logEntry
+				if err := dec.Decode(&entry); err != nil {
+					close(entries)
+					return
+				}
+				entries <- entry
+			}
+		}()
+		return response{}
+	})
+	stopped := make(chan string, 1)
+	handle(mux, "StopLogging", func(body []byte) interface{} {
+		var req stopLoggingRequest
+		json.Unmarshal(body, &req)
+		stopped <- req.File
+		return response{}
+	})
+
+	l, err := d.newLogger(logger.Context{ContainerID: cid, ContainerName: "/web", Config: map[string]string{"foo": "bar"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.(logger.LogReader); ok {
+		t.Fatal("Expected the logger of a plugin which cannot read logs not to be a LogReader")
+	}
+	now := time.Now()
+	if err := l.Log(&logger.Message{Line: []byte("hello"), Source: "stdout", Timestamp: now}); err != nil {
+		t.Fatal(err)
+	}
+	if started.Info.ContainerID != cid || started.Info.ContainerName != "/web" || started.Info.Config["foo"] != "bar" {
+		t.Fatalf("Wrong info %+v", started.Info)
+	}
+
+	select {
+	case entry := <-entries:
+		if entry.Source != "stdout" || string(entry.Line) != "hello" || entry.TimeNano != now.UnixNano() {
+			t.Fatalf("Wrong entry %+v", entry)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the entry")
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if file := <-stopped; file != started.File {
+		t.Fatalf("Expected StopLogging for %s, got %s", started.File, file)
+	}
+	if _, err := os.Stat(started.File); !os.IsNotExist(err) {
+		t.Fatalf("Expected the FIFO to be removed: %v", err)
+	}
+	if err := l.Log(&logger.Message{Line: []byte("late"), Timestamp: now}); err == nil {
+		t.Fatal("Expected an error logging to a closed logger")
+	}
+}
+
+func TestStartLoggingError(t *testing.T) {
+	mux, d, teardown := setupPlugin(t)
+	defer teardown()
+
+	handle(mux, "StartLogging", func([]byte) interface{} {
+		return response{Err: "unknown log opt"}
+	})
+	l, err := d.newLogger(logger.Context{ContainerID: cid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Log(&logger.Message{Line: []byte("hello"), Timestamp: time.Now()}); err == nil || !strings.Contains(err.Error(), "unknown log opt") {
+		t.Fatalf("Expected the error of the plugin, got %v", err)
+	}
+	if files, _ := ioutil.ReadDir(fifoDir); len(files) != 0 {
+		t.Fatalf("Expected the FIFO to be removed, found %d files", len(files))
+	}
+}
+
+func TestReadLogs(t *testing.T) {
+	mux, d, teardown := setupPlugin(t)
+	defer teardown()
+
+	handle(mux, "Capabilities", func([]byte) interface{} {
+		return capabilitiesResponse{Cap: capabilities{ReadLogs: true}}
+	})
+	created := time.Date(2015, 6, 24, 17, 35, 53, 0, time.UTC)
+	mux.HandleFunc("/"+PluginType+".ReadLogs", func(w http.ResponseWriter, r *http.Request) {
+		var req readLogsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Info.ContainerID != cid || req.Config.Tail != 1 {
+			http.Error(w, "wrong request", http.StatusBadRequest)
+			return
+		}
+		enc := json.NewEncoder(w)
+		enc.Encode(&logEntry{Source: "stderr", TimeNano: created.UnixNano(), Line: []byte("hello")})
+	})
+
+	l, err := newDriver(d.name, d.endpoint).newLogger(logger.Context{ContainerID: cid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, ok := l.(logger.LogReader)
+	if !ok {
+		t.Fatal("Expected the logger of a plugin which reads logs to be a LogReader")
+	}
+	r, err := reader.ReadLogs(logger.ReadConfig{Tail: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var entry jsonlog.JSONLog
+	if err := json.NewDecoder(r).Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Log != "hello\n" || entry.Stream != "stderr" || !entry.Created.Equal(created) {
+		t.Fatalf("Wrong entry %+v", entry)
+	}
+
+	if _, err := reader.ReadLogs(logger.ReadConfig{}); err == nil {
+		t.Fatal("Expected the error of the plugin")
+	}
+}
@@ -1,11 +1,17 @@
 package syslog
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log/syslog"
+	"net"
+	"net/url"
 	"os"
 	"path"
+	"strconv"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/logger"
@@ -13,37 +19,107 @@ import (
 
 const name = "syslog"
 
+// The formats of the messages.
+const (
+	formatRFC3164 = "rfc3164"
+	formatRFC5424 = "rfc5424"
+)
+
+var facilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
 type Syslog struct {
-	writer *syslog.Writer
+	writer    *writer
+	assembler logger.Assembler
 }
 
 func init() {
 	if err := logger.RegisterLogDriver(name, New); err != nil {
 		logrus.Fatal(err)
 	}
+	if err := logger.RegisterLogOptValidator(name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
 }
 
+// New creates a logger sending the logs of the container to the local syslog
+// daemon, or to the remote one of the syslog-address option.
 func New(ctx logger.Context) (logger.Logger, error) {
-	tag := ctx.ContainerID[:12]
-	log, err := syslog.New(syslog.LOG_DAEMON, fmt.Sprintf("%s/%s", path.Base(os.Args[0]), tag))
+	if err := ValidateLogOpt(ctx.Config); err != nil {
+		return nil, err
+	}
+	network, address, _ := parseAddress(ctx.Config["syslog-address"])
+	facility, _ := parseFacility(ctx.Config["syslog-facility"])
+
+	// syslog-tag is the deprecated name of the tag option
+	defaultTag := path.Base(os.Args[0]) + "/{{.ID}}"
+	if t := ctx.Config["syslog-tag"]; t != "" {
+		defaultTag = t
+	}
+	tag, err := logger.ParseLogTag(ctx, defaultTag)
 	if err != nil {
 		return nil, err
 	}
+	format := ctx.Config["syslog-format"]
+	if format == "" {
+		format = formatRFC3164
+	}
+
+	var tlsConfig *tls.Config
+	if network == "tcp+tls" {
+		if tlsConfig, err = parseTLSConfig(ctx.Config); err != nil {
+			return nil, err
+		}
+	}
 
-	return &Syslog{
-		writer: log,
-	}, nil
+	w := &writer{
+		network:   network,
+		address:   address,
+		tlsConfig: tlsConfig,
+		facility:  facility,
+		tag:       tag,
+		format:    format,
+		pid:       os.Getpid(),
+	}
+	w.hostname, _ = os.Hostname()
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return &Syslog{writer: w}, nil
 }
 
 func (s *Syslog) Log(msg *logger.Message) error {
+	if msg = s.assembler.Add(msg); msg == nil {
+		return nil
+	}
 	if msg.Source == "stderr" {
-		return s.writer.Err(string(msg.Line))
+		return s.writer.write(syslog.LOG_ERR, msg)
 	}
-	return s.writer.Info(string(msg.Line))
+	return s.writer.write(syslog.LOG_INFO, msg)
 }
 
 func (s *Syslog) Close() error {
-	return s.writer.Close()
+	return s.writer.close()
 }
 
 func (s *Syslog) Name() string {
@@ -53,3 +129,136 @@ func (s *Syslog) Name() string {
 func (s *Syslog) GetReader() (io.Reader, error) {
 	return nil, logger.ReadLogsNotSupported
 }
+
+// ValidateLogOpt checks the options of the syslog driver.
+func ValidateLogOpt(cfg map[string]string) error {
+	for key := range cfg {
+		switch key {
+		case "syslog-address":
+		case "syslog-facility":
+		case "tag", "syslog-tag":
+			if err := logger.ValidateLogTag(cfg[key]); err != nil {
+				return err
+			}
+		case "syslog-format":
+		case "syslog-tls-ca-cert":
+		case "syslog-tls-cert":
+		case "syslog-tls-key":
+		case "syslog-tls-skip-verify":
+		default:
+			return fmt.Errorf("unknown log opt '%s' for syslog log driver", key)
+		}
+	}
+
+	network, _, err := parseAddress(cfg["syslog-address"])
+	if err != nil {
+		return err
+	}
+	if _, err := parseFacility(cfg["syslog-facility"]); err != nil {
+		return err
+	}
+	switch cfg["syslog-format"] {
+	case "", formatRFC3164, formatRFC5424:
+	default:
+		return fmt.Errorf("invalid syslog format %s, expected %s or %s", cfg["syslog-format"], formatRFC3164, formatRFC5424)
+	}
+
+	if network != "tcp+tls" {
+		for _, key := range []string{"syslog-tls-ca-cert", "syslog-tls-cert", "syslog-tls-key", "syslog-tls-skip-verify"} {
+			if _, exists := cfg[key]; exists {
+				return fmt.Errorf("%s requires a tcp+tls syslog address", key)
+			}
+		}
+		return nil
+	}
+	if (cfg["syslog-tls-cert"] == "") != (cfg["syslog-tls-key"] == "") {
+		return fmt.Errorf("syslog-tls-cert and syslog-tls-key must be given together")
+	}
+	if v, exists := cfg["syslog-tls-skip-verify"]; exists {
+		if _, err := strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("invalid value for syslog-tls-skip-verify: %s", v)
+		}
+	}
+	return nil
+}
+
+// parseAddress parses a syslog address, [tcp|udp|tcp+tls]://host[:port] or
+// unix[gram]:///path. The network is empty for the local syslog daemon.
+func parseAddress(address string) (string, string, error) {
+	if address == "" {
+		return "", "", nil
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid syslog address %s: %v", address, err)
+	}
+
+	switch u.Scheme {
+	case "unix", "unixgram":
+		if !path.IsAbs(u.Path) {
+			return "", "", fmt.Errorf("invalid syslog address %s, the socket must be an absolute path", address)
+		}
+		return u.Scheme, u.Path, nil
+	case "tcp", "udp", "tcp+tls":
+		if u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return "", "", fmt.Errorf("invalid syslog address %s, expected %s://host[:port]", address, u.Scheme)
+		}
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			// The standard ports of RFC 5426 and RFC 5425
+			host, port = u.Host, "514"
+			if u.Scheme == "tcp+tls" {
+				port = "6514"
+			}
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return "", "", fmt.Errorf("invalid port %s in syslog address %s", port, address)
+		}
+		return u.Scheme, net.JoinHostPort(host, port), nil
+	default:
+		return "", "", fmt.Errorf("unsupported syslog address %s, the scheme must be tcp, udp, tcp+tls, unix or unixgram", address)
+	}
+}
+
+// parseFacility parses a facility, given by name or number, the daemon
+// facility being the default.
+func parseFacility(facility string) (syslog.Priority, error) {
+	if facility == "" {
+		return syslog.LOG_DAEMON, nil
+	}
+	if p, exists := facilities[facility]; exists {
+		return p, nil
+	}
+	n, err := strconv.Atoi(facility)
+	if err != nil || n < 0 || n > 23 {
+		return 0, fmt.Errorf("invalid syslog facility %s", facility)
+	}
+	return syslog.Priority(n << 3), nil
+}
+
+func parseTLSConfig(cfg map[string]string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS10}
+
+	if ca := cfg["syslog-tls-ca-cert"]; ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the syslog CA certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", ca)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certFile := cfg["syslog-tls-cert"]; certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, cfg["syslog-tls-key"])
+		if err != nil {
+			return nil, fmt.Errorf("error loading the syslog client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if v := cfg["syslog-tls-skip-verify"]; v != "" {
+		tlsConfig.InsecureSkipVerify, _ = strconv.ParseBool(v)
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,163 @@
+package syslog
+
+import (
+	"bufio"
+	"log/syslog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+)
+
+func TestParseAddress(t *testing.T) {
+	valid := map[string][2]string{
+		"":                          {"", ""},
+		"udp://1.2.3.4":             {"udp", "1.2.3.4:514"},
+		"tcp://example.com:1514":    {"tcp", "example.com:1514"},
+		"tcp+tls://example.com":     {"tcp+tls", "example.com:6514"},
+		"tcp+tls://[::1]:7514":      {"tcp+tls", "[::1]:7514"},
+		"unix:///dev/log":           {"unix", "/dev/log"},
+		"unixgram:///var/run/log":   {"unixgram", "/var/run/log"},
+		"udp://1.2.3.4/":            {"udp", "1.2.3.4:514"},
+		"tcp://logs.example.com:80": {"tcp", "logs.example.com:80"},
+	}
+	for address, expected := range valid {
+		network, addr, err := parseAddress(address)
+		if err != nil {
+			t.Fatalf("%s: %v", address, err)
+		}
+		if network != expected[0] || addr != expected[1] {
+			t.Fatalf("%s: expected %s %s, got %s %s", address, expected[0], expected[1], network, addr)
+		}
+	}
+
+	for _, address := range []string{"1.2.3.4", "http://1.2.3.4", "tcp://", "udp://1.2.3.4/path", "tcp://1.2.3.4:port", "unix://relative"} {
+		if _, _, err := parseAddress(address); err == nil {
+			t.Fatalf("Expected an error parsing %s", address)
+		}
+	}
+}
+
+func TestParseFacility(t *testing.T) {
+	valid := map[string]syslog.Priority{
+		"":       syslog.LOG_DAEMON,
+		"local3": syslog.LOG_LOCAL3,
+		"auth":   syslog.LOG_AUTH,
+		"1":      syslog.LOG_USER,
+	}
+	for facility, expected := range valid {
+		p, err := parseFacility(facility)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != expected {
+			t.Fatalf("%s: expected %d, got %d", facility, expected, p)
+		}
+	}
+	for _, facility := range []string{"local8", "24", "-1"} {
+		if _, err := parseFacility(facility); err == nil {
+			t.Fatalf("Expected an error parsing %s", facility)
+		}
+	}
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	valid := []map[string]string{
+		nil,
+		{"syslog-address": "udp://1.2.3.4", "syslog-facility": "local0", "syslog-tag": "web", "syslog-format": "rfc5424"},
+		{"syslog-address": "tcp+tls://1.2.3.4", "syslog-tls-ca-cert": "/ca.pem", "syslog-tls-cert": "/cert.pem", "syslog-tls-key": "/key.pem"},
+		{"syslog-address": "tcp+tls://1.2.3.4", "syslog-tls-skip-verify": "true"},
+		{"tag": "{{.Name}}/{{.ID}}"},
+	}
+	for _, cfg := range valid {
+		if err := ValidateLogOpt(cfg); err != nil {
+			t.Fatalf("%v: %v", cfg, err)
+		}
+	}
+
+	invalid := []map[string]string{
+		{"max-size": "10m"},
+		{"syslog-address": "http://1.2.3.4"},
+		{"syslog-facility": "local9"},
+		{"syslog-format": "rfc3339"},
+		{"tag": "{{.Unknown}}"},
+		{"syslog-address": "tcp://1.2.3.4", "syslog-tls-ca-cert": "/ca.pem"},
+		{"syslog-address": "tcp+tls://1.2.3.4", "syslog-tls-cert": "/cert.pem"},
+		{"syslog-address": "tcp+tls://1.2.3.4", "syslog-tls-skip-verify": "maybe"},
+	}
+	for _, cfg := range invalid {
+		if err := ValidateLogOpt(cfg); err == nil {
+			t.Fatalf("Expected an error validating %v", cfg)
+		}
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	msg := &logger.Message{
+		Line:      []byte("hello\n"),
+		Timestamp: time.Date(2015, 6, 24, 17, 35, 53, 123456000, time.UTC),
+	}
+	w := &writer{network: "udp", facility: syslog.LOG_LOCAL0, tag: "web", hostname: "host", pid: 42, format: formatRFC3164}
+	if s := string(w.formatMessage(syslog.LOG_ERR, msg)); s != "<131>Jun 24 17:35:53 host web[42]: hello\n" {
+		t.Fatalf("Wrong RFC 3164 message %q", s)
+	}
+	w.network = ""
+	if s := string(w.formatMessage(syslog.LOG_INFO, msg)); s != "<134>Jun 24 17:35:53 web[42]: hello\n" {
+		t.Fatalf("Wrong local RFC 3164 message %q", s)
+	}
+
+	w.format = formatRFC5424
+	w.network = "udp"
+	expected := "<134>1 2015-06-24T17:35:53.123456Z host web 42 - - hello"
+	if s := string(w.formatMessage(syslog.LOG_INFO, msg)); s != expected {
+		t.Fatalf("Wrong RFC 5424 message %q", s)
+	}
+	w.network = "tcp+tls"
+	if s := string(w.formatMessage(syslog.LOG_INFO, msg)); s != "56 "+expected {
+		t.Fatalf("Wrong framed RFC 5424 message %q", s)
+	}
+}
+
+func TestLogTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	cid := "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
+	s, err := New(logger.Context{
+		ContainerID: cid,
+		Config:      map[string]string{"syslog-address": "tcp://" + l.Addr().String(), "syslog-facility": "local1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Log(&logger.Message{ContainerID: cid, Line: []byte("hello"), Source: "stdout", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case line := <-received:
+		if !strings.HasPrefix(line, "<142>") || !strings.Contains(line, "/a7317399f3f8[") || !strings.HasSuffix(line, ": hello\n") {
+			t.Fatalf("Wrong message %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the message")
+	}
+}
@@ -0,0 +1,136 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/syslog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+)
+
+// The sockets of the local syslog daemon
+var localSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// writer sends messages to a syslog daemon, reconnecting once when sending
+// a message fails.
+type writer struct {
+	network   string // empty for the local syslog daemon
+	address   string
+	tlsConfig *tls.Config
+	facility  syslog.Priority
+	tag       string
+	format    string
+	hostname  string
+	pid       int
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (w *writer) connect() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.connectLocked()
+}
+
+func (w *writer) connectLocked() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch w.network {
+	case "":
+		conn, err = dialLocal()
+	case "tcp+tls":
+		conn, err = tls.Dial("tcp", w.address, w.tlsConfig)
+	default:
+		conn, err = net.Dial(w.network, w.address)
+	}
+	if err != nil {
+		return fmt.Errorf("error connecting to syslog: %v", err)
+	}
+	w.conn = conn
+	return nil
+}
+
+func dialLocal() (net.Conn, error) {
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range localSockets {
+			if conn, err := net.Dial(network, path); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unix syslog delivery error")
+}
+
+func (w *writer) write(severity syslog.Priority, msg *logger.Message) error {
+	data := w.formatMessage(severity, msg)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		if _, err := w.conn.Write(data); err == nil {
+			return nil
+		}
+	}
+	if err := w.connectLocked(); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(data)
+	return err
+}
+
+func (w *writer) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// formatMessage formats and frames a message. Over tcp, RFC 5424 messages
+// are framed with their length as in RFC 5425, the other messages end with
+// a newline.
+func (w *writer) formatMessage(severity syslog.Priority, msg *logger.Message) []byte {
+	timestamp := msg.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	line := strings.TrimSuffix(string(msg.Line), "\n")
+	priority := (w.facility & 0xf8) | (severity & 0x07)
+
+	if w.format != formatRFC5424 {
+		if w.network == "" {
+			// The local daemon adds the hostname itself
+			return []byte(fmt.Sprintf("<%d>%s %s[%d]: %s\n", priority, timestamp.Format(time.Stamp), w.tag, w.pid, line))
+		}
+		return []byte(fmt.Sprintf("<%d>%s %s %s[%d]: %s\n", priority, timestamp.Format(time.Stamp), w.hostname, w.tag, w.pid, line))
+	}
+
+	hostname := w.hostname
+	if hostname == "" {
+		hostname = "-"
+	}
+	data := fmt.Sprintf("<%d>1 %s %s %s %d - - %s", priority, timestamp.Format("2006-01-02T15:04:05.999999Z07:00"), hostname, w.tag, w.pid, line)
+	switch w.network {
+	case "tcp", "tcp+tls":
+		return []byte(fmt.Sprintf("%d %s", len(data), data))
+	case "udp", "unixgram":
+		return []byte(data)
+	default:
+		return []byte(data + "\n")
+	}
+}
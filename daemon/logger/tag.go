@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/docker/docker/pkg/stringid"
+)
+
+// ParseLogTag returns the tag of the logs of the container, the tag log opt
+// executed as a template against the context, or defaultTemplate when the
+// option is not set.
+func ParseLogTag(ctx Context, defaultTemplate string) (string, error) {
+	text := ctx.Config["tag"]
+	if text == "" {
+		text = defaultTemplate
+	}
+	tmpl, err := template.New("tag").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid log tag %s: %v", text, err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(buf, &ctx); err != nil {
+		return "", fmt.Errorf("invalid log tag %s: %v", text, err)
+	}
+	return buf.String(), nil
+}
+
+// ValidateLogTag checks the template of a tag, parsing it and executing it
+// against an empty context.
+func ValidateLogTag(text string) error {
+	_, err := ParseLogTag(Context{Config: map[string]string{"tag": text}}, "")
+	return err
+}
+
+// ID returns the container ID truncated to 12 characters.
+func (ctx *Context) ID() string {
+	return stringid.TruncateID(ctx.ContainerID)
+}
+
+// FullID returns the full container ID.
+func (ctx *Context) FullID() string {
+	return ctx.ContainerID
+}
+
+// Name returns the container name, without its leading slash.
+func (ctx *Context) Name() string {
+	return strings.TrimPrefix(ctx.ContainerName, "/")
+}
+
+// ImageID returns the ID of the image truncated to 12 characters.
+func (ctx *Context) ImageID() string {
+	return stringid.TruncateID(ctx.ContainerImageID)
+}
+
+// ImageFullID returns the full ID of the image.
+func (ctx *Context) ImageFullID() string {
+	return ctx.ContainerImageID
+}
+
+// ImageName returns the image of the container, as it was given when the
+// container was created.
+func (ctx *Context) ImageName() string {
+	return ctx.ContainerImageName
+}
+
+// Label returns the value of the label key of the container, empty when the
+// container has no such label.
+func (ctx *Context) Label(key string) string {
+	return ctx.ContainerLabels[key]
+}
@@ -0,0 +1,47 @@
+package logger
+
+import "testing"
+
+func TestParseLogTag(t *testing.T) {
+	ctx := Context{
+		ContainerID:        "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657",
+		ContainerName:      "/web",
+		ContainerImageID:   "82cdea7ab5b555f53c2adf8df75b5d1f6fa3c6fa8e0afef9c5ad96ac8b4a0a4c",
+		ContainerImageName: "nginx:latest",
+		ContainerLabels:    map[string]string{"com.example.service": "frontend"},
+		Config:             map[string]string{},
+	}
+	tags := map[string]string{
+		"":                                 "default-a7317399f3f8",
+		"{{.Name}}/{{.ID}}":                "web/a7317399f3f8",
+		"{{.FullID}}":                      ctx.ContainerID,
+		"{{.ImageName}}@{{.ImageID}}":      "nginx:latest@82cdea7ab5b5",
+		"{{.ImageFullID}}":                 ctx.ContainerImageID,
+		`{{.Label "com.example.service"}}`: "frontend",
+		`{{.Label "missing"}}`:             "",
+		"static":                           "static",
+	}
+	for text, expected := range tags {
+		ctx.Config["tag"] = text
+		tag, err := ParseLogTag(ctx, "default-{{.ID}}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tag != expected {
+			t.Fatalf("%s: expected %s, got %s", text, expected, tag)
+		}
+		if err := ValidateLogTag(text); err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+	}
+
+	for _, text := range []string{"{{.Name", "{{.Unknown}}", "{{.Label}}"} {
+		ctx.Config["tag"] = text
+		if _, err := ParseLogTag(ctx, ""); err == nil {
+			t.Fatalf("Expected an error parsing %s", text)
+		}
+		if err := ValidateLogTag(text); err == nil {
+			t.Fatalf("Expected an error validating %s", text)
+		}
+	}
+}
@@ -1,43 +1,41 @@
 package daemon
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
-	"os"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/docker/docker/daemon/logger/jsonfilelog"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/cache"
 	"github.com/docker/docker/pkg/jsonlog"
 	"github.com/docker/docker/pkg/stdcopy"
-	"github.com/docker/docker/pkg/tailfile"
 	"github.com/docker/docker/pkg/timeutils"
 )
 
 type ContainerLogsConfig struct {
 	Follow, Timestamps   bool
+	Details              bool // Show the attributes of the lines
 	Tail                 string
 	Since                time.Time
 	UseStdout, UseStderr bool
 	OutStream            io.Writer
+	// Multiplex the streams in extended frames, carrying the time of the lines
+	ExtendedFrames bool
 }
 
 func (daemon *Daemon) ContainerLogs(name string, config *ContainerLogsConfig) error {
-	var (
-		lines  = -1
-		format string
-	)
+	lines := -1
 	if !(config.UseStdout || config.UseStderr) {
 		return fmt.Errorf("You must choose at least one stream")
 	}
-	if config.Timestamps {
-		format = timeutils.RFC3339NanoFixed
-	}
 	if config.Tail == "" {
 		config.Tail = "all"
 	}
@@ -51,113 +49,176 @@ func (daemon *Daemon) ContainerLogs(name string, config *ContainerLogsConfig) er
 		outStream = config.OutStream
 		errStream io.Writer
 	)
-	if !container.Config.Tty {
+	if !container.Config.Tty && config.ExtendedFrames {
+		errStream = stdcopy.NewExtendedStdWriter(outStream, stdcopy.Stderr, true, "")
+		outStream = stdcopy.NewExtendedStdWriter(outStream, stdcopy.Stdout, true, "")
+	} else if !container.Config.Tty {
 		errStream = stdcopy.NewStdWriter(outStream, stdcopy.Stderr)
 		outStream = stdcopy.NewStdWriter(outStream, stdcopy.Stdout)
 	} else {
 		errStream = outStream
 	}
 
-	if container.LogDriverType() != jsonfilelog.Name {
-		return fmt.Errorf("\"logs\" endpoint is supported only for \"json-file\" logging driver")
+	if container.LogDriverType() == "none" {
+		return fmt.Errorf("\"logs\" endpoint is not supported when logging is disabled")
 	}
-	logDriver, err := container.getLogger()
-	cLog, err := logDriver.GetReader()
-	if err != nil {
-		logrus.Errorf("Error reading logs: %s", err)
-	} else {
-		// json-file driver
-		if config.Tail != "all" {
-			var err error
-			lines, err = strconv.Atoi(config.Tail)
-			if err != nil {
-				logrus.Errorf("Failed to parse tail %s, error: %v, show all logs", config.Tail, err)
-				lines = -1
-			}
+	// The logs of a running container are followed from its logger, which
+	// tells its readers when the container stopped and its logs are written
+	var (
+		reader logger.LogReader
+		follow bool
+	)
+	if config.Follow {
+		reader, follow = container.liveLogReader()
+	}
+	if !follow {
+		logDriver, err := container.getLogReader()
+		if err != nil {
+			return err
 		}
+		defer logDriver.Close()
+		var ok bool
+		if reader, ok = logDriver.(logger.LogReader); !ok {
+			return fmt.Errorf("\"logs\" endpoint is not supported for the %q logging driver with the local log cache disabled", container.LogDriverType())
+		}
+	}
 
-		if lines != 0 {
-			if lines > 0 {
-				f := cLog.(*os.File)
-				ls, err := tailfile.TailFile(f, lines)
-				if err != nil {
-					return err
-				}
-				tmp := bytes.NewBuffer([]byte{})
-				for _, l := range ls {
-					fmt.Fprintf(tmp, "%s\n", l)
-				}
-				cLog = tmp
-			}
+	// The attributes of the lines read back without them
+	var attrs map[string]string
+	if config.Details {
+		_, driverOpts := cache.SplitLogOpts(container.getLogConfig().Config)
+		ctx := container.loggerContext(driverOpts)
+		attrs = ctx.ExtraAttributes(nil)
+	}
 
-			dec := json.NewDecoder(cLog)
-			l := &jsonlog.JSONLog{}
-			for {
-				l.Reset()
-				if err := dec.Decode(l); err == io.EOF {
-					break
-				} else if err != nil {
-					logrus.Errorf("Error streaming logs: %s", err)
-					break
-				}
-				logLine := l.Log
-				if !config.Since.IsZero() && l.Created.Before(config.Since) {
-					continue
-				}
-				if config.Timestamps {
-					// format can be "" or time format, so here can't be error
-					logLine, _ = l.Format(format)
-				}
-				if l.Stream == "stdout" && config.UseStdout {
-					io.WriteString(outStream, logLine)
-				}
-				if l.Stream == "stderr" && config.UseStderr {
-					io.WriteString(errStream, logLine)
-				}
-			}
+	if config.Tail != "all" {
+		var err error
+		lines, err = strconv.Atoi(config.Tail)
+		if err != nil {
+			logrus.Errorf("Failed to parse tail %s, error: %v, show all logs", config.Tail, err)
+			lines = -1
 		}
 	}
+	if lines == 0 && !follow {
+		return nil
+	}
 
-	if config.Follow && container.IsRunning() {
-		chErr := make(chan error)
-		var stdoutPipe, stderrPipe io.ReadCloser
-
+	cLog, err := reader.ReadLogs(logger.ReadConfig{Since: config.Since, Tail: lines, Follow: follow})
+	if err != nil {
+		return err
+	}
+	defer cLog.Close()
+	if follow {
 		// write an empty chunk of data (this is to ensure that the
 		// HTTP Response is sent immediatly, even if the container has
 		// not yet produced any data)
 		outStream.Write(nil)
+	}
 
-		if config.UseStdout {
-			stdoutPipe = container.StdoutLogPipe()
-			go func() {
-				logrus.Debug("logs: stdout stream begin")
-				chErr <- jsonlog.WriteLog(stdoutPipe, outStream, format, config.Since)
-				logrus.Debug("logs: stdout stream end")
-			}()
-		}
-		if config.UseStderr {
-			stderrPipe = container.StderrLogPipe()
-			go func() {
-				logrus.Debug("logs: stderr stream begin")
-				chErr <- jsonlog.WriteLog(stderrPipe, errStream, format, config.Since)
-				logrus.Debug("logs: stderr stream end")
-			}()
+	if err := writeLogs(cLog, outStream, errStream, config, attrs); err != nil && err != io.ErrClosedPipe {
+		if e, ok := err.(*net.OpError); !ok || e.Err != syscall.EPIPE {
+			logrus.Errorf("Error streaming logs: %v", err)
 		}
+	}
+	return nil
+}
 
-		err = <-chErr
-		if stdoutPipe != nil {
-			stdoutPipe.Close()
+// writeLogs writes the lines of the logs read from src to stdout and stderr,
+// formatted for the config, attrs being the attributes of the lines read
+// without them. The lines logged in partial messages are reassembled, up to
+// logger.MaxLineSize.
+func writeLogs(src io.Reader, stdout, stderr io.Writer, config *ContainerLogsConfig, attrs map[string]string) error {
+	dec := json.NewDecoder(src)
+	partial := make(map[string]*jsonlog.JSONLog)
+	for {
+		l := &jsonlog.JSONLog{}
+		if err := dec.Decode(l); err != nil {
+			if err != io.EOF {
+				return err
+			}
+			// The end of the lines still being logged
+			for _, l := range partial {
+				if err := writeLogLine(l, stdout, stderr, config, attrs); err != nil {
+					return err
+				}
+			}
+			return nil
 		}
-		if stderrPipe != nil {
-			stderrPipe.Close()
+		if p := partial[l.Stream]; p != nil {
+			p.Log += l.Log
+			l = p
 		}
-		<-chErr // wait for 2nd goroutine to exit, otherwise bad things will happen
+		if !strings.HasSuffix(l.Log, "\n") && len(l.Log) < logger.MaxLineSize {
+			partial[l.Stream] = l
+			continue
+		}
+		delete(partial, l.Stream)
+		if err := writeLogLine(l, stdout, stderr, config, attrs); err != nil {
+			return err
+		}
+	}
+}
 
-		if err != nil && err != io.EOF && err != io.ErrClosedPipe {
-			if e, ok := err.(*net.OpError); ok && e.Err != syscall.EPIPE {
-				logrus.Errorf("error streaming logs: %v", err)
-			}
+// writeLogLine writes l to stdout or stderr, unless the config filters it.
+func writeLogLine(l *jsonlog.JSONLog, stdout, stderr io.Writer, config *ContainerLogsConfig, attrs map[string]string) error {
+	if !config.Since.IsZero() && l.Created.Before(config.Since) {
+		return nil
+	}
+	if l.Attrs == nil {
+		l.Attrs = attrs
+	}
+	var dst io.Writer
+	switch {
+	case l.Stream == "stdout" && config.UseStdout:
+		dst = stdout
+	case l.Stream == "stderr" && config.UseStderr:
+		dst = stderr
+	default:
+		return nil
+	}
+	return writeLogString(dst, formatLogLine(l, config), l.Created)
+}
+
+// frameWriter is implemented by the writers of the extended frames of
+// stdcopy, which carry the time of their data.
+type frameWriter interface {
+	WriteFrame(buf []byte, t time.Time) (int, error)
+}
+
+// writeLogString writes s, logged at t, to w, in a frame carrying t if w
+// writes extended frames.
+func writeLogString(w io.Writer, s string, t time.Time) error {
+	var err error
+	if fw, ok := w.(frameWriter); ok {
+		_, err = fw.WriteFrame([]byte(s), t)
+	} else {
+		_, err = io.WriteString(w, s)
+	}
+	return err
+}
+
+// formatLogLine returns the line l prefixed, as requested by the config,
+// with its timestamp and its attributes, as comma-separated key=value pairs
+// escaped like the values of a query string.
+func formatLogLine(l *jsonlog.JSONLog, config *ContainerLogsConfig) string {
+	var prefix []string
+	if config.Timestamps {
+		prefix = append(prefix, l.Created.Format(timeutils.RFC3339NanoFixed))
+	}
+	if config.Details {
+		keys := make([]string, 0, len(l.Attrs))
+		for k := range l.Attrs {
+			keys = append(keys, k)
 		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = url.QueryEscape(k) + "=" + url.QueryEscape(l.Attrs[k])
+		}
+		prefix = append(prefix, strings.Join(pairs, ","))
 	}
-	return nil
+	if len(prefix) == 0 {
+		return l.Log
+	}
+	return strings.Join(prefix, " ") + " " + l.Log
 }
@@ -0,0 +1,102 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/jsonlog"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func TestFormatLogLine(t *testing.T) {
+	l := &jsonlog.JSONLog{
+		Log:     "hello\n",
+		Stream:  "stdout",
+		Created: time.Date(2015, 6, 24, 17, 35, 53, 0, time.UTC),
+		Attrs:   map[string]string{"tier": "web", "com.example.service": "shop front"},
+	}
+	lines := []struct {
+		config   ContainerLogsConfig
+		expected string
+	}{
+		{ContainerLogsConfig{}, "hello\n"},
+		{ContainerLogsConfig{Timestamps: true}, "2015-06-24T17:35:53.000000000Z hello\n"},
+		{ContainerLogsConfig{Details: true}, "com.example.service=shop+front,tier=web hello\n"},
+		{ContainerLogsConfig{Timestamps: true, Details: true}, "2015-06-24T17:35:53.000000000Z com.example.service=shop+front,tier=web hello\n"},
+	}
+	for _, tt := range lines {
+		if line := formatLogLine(l, &tt.config); line != tt.expected {
+			t.Fatalf("%+v: expected %q, got %q", tt.config, tt.expected, line)
+		}
+	}
+}
+
+func TestWriteLogs(t *testing.T) {
+	src := `{"log":"old\n","stream":"stdout","time":"2015-06-24T17:35:52Z"}
+{"log":"new\n","stream":"stdout","time":"2015-06-24T17:35:54Z"}
+{"log":"error\n","stream":"stderr","time":"2015-06-24T17:35:55Z","attrs":{"tier":"db"}}
+`
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	config := &ContainerLogsConfig{Details: true, UseStdout: true, UseStderr: true, Since: time.Date(2015, 6, 24, 17, 35, 53, 0, time.UTC)}
+	if err := writeLogs(strings.NewReader(src), stdout, stderr, config, map[string]string{"tier": "web"}); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "tier=web new\n" || stderr.String() != "tier=db error\n" {
+		t.Fatalf("Wrong logs %q and %q", stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	config = &ContainerLogsConfig{UseStderr: true}
+	if err := writeLogs(strings.NewReader(src), stdout, stderr, config, nil); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.Len() != 0 || stderr.String() != "error\n" {
+		t.Fatalf("Wrong logs %q and %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestWriteLogsPartial(t *testing.T) {
+	src := `{"log":"hel","stream":"stdout","time":"2015-06-24T17:35:52Z"}
+{"log":"error\n","stream":"stderr","time":"2015-06-24T17:35:53Z"}
+{"log":"lo\n","stream":"stdout","time":"2015-06-24T17:35:54Z"}
+{"log":"unfinished","stream":"stdout","time":"2015-06-24T17:35:55Z"}
+`
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	config := &ContainerLogsConfig{Timestamps: true, UseStdout: true, UseStderr: true}
+	if err := writeLogs(strings.NewReader(src), stdout, stderr, config, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := "2015-06-24T17:35:52.000000000Z hello\n2015-06-24T17:35:55.000000000Z unfinished"
+	if stdout.String() != expected || stderr.String() != "2015-06-24T17:35:53.000000000Z error\n" {
+		t.Fatalf("Wrong logs %q and %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestWriteLogsExtendedFrames(t *testing.T) {
+	src := `{"log":"hello\n","stream":"stdout","time":"2015-06-24T17:35:52Z"}
+{"log":"error\n","stream":"stderr","time":"2015-06-24T17:35:55Z"}
+`
+	var output bytes.Buffer
+	stdout := stdcopy.NewExtendedStdWriter(&output, stdcopy.Stdout, true, "")
+	stderr := stdcopy.NewExtendedStdWriter(&output, stdcopy.Stderr, true, "")
+	config := &ContainerLogsConfig{UseStdout: true, UseStderr: true, ExtendedFrames: true}
+	if err := writeLogs(strings.NewReader(src), stdout, stderr, config, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var frames []string
+	if _, err := stdcopy.StdCopyFrames(&output, func(f *stdcopy.Frame) error {
+		frames = append(frames, fmt.Sprintf("%d %s %s", f.Fd, f.Time.UTC().Format(time.RFC3339), f.Data))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"1 2015-06-24T17:35:52Z hello\n", "2 2015-06-24T17:35:55Z error\n"}
+	if len(frames) != len(expected) || frames[0] != expected[0] || frames[1] != expected[1] {
+		t.Fatalf("Expected the frames %q, got %q", expected, frames)
+	}
+}
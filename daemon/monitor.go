@@ -107,6 +107,13 @@ func (m *containerMonitor) Start() error {
 		afterRun bool
 	)
 
+	// remove the container once it is stopped for good, if it was asked to
+	defer func() {
+		if afterRun && m.container.hostConfig.AutoRemove {
+			go m.container.daemon.autoRemove(m.container)
+		}
+	}()
+
 	// ensure that when the monitor finally exits we release the networking and unmount the rootfs
 	defer func() {
 		if afterRun {
@@ -254,6 +261,14 @@ func (m *containerMonitor) callback(processConfig *execdriver.ProcessConfig, pid
 
 	m.container.setRunning(pid)
 
+	if err := m.container.setupBandwidth(pid); err != nil {
+		logrus.Errorf("%s: Error limiting network bandwidth: %s", m.container.ID, err)
+	}
+
+	if m.container.SeccompAudit {
+		m.container.daemon.seccompAuditor.watch(m.container, pid)
+	}
+
 	// signal that the process has started
 	// close channel only if not closed
 	select {
@@ -277,6 +292,10 @@ func (m *containerMonitor) resetContainer(lock bool) {
 		defer container.Unlock()
 	}
 
+	if container.SeccompAudit {
+		container.daemon.seccompAuditor.unwatch(container)
+	}
+
 	if container.Config.OpenStdin {
 		if err := container.stdin.Close(); err != nil {
 			logrus.Errorf("%s: Error close stdin: %s", container.ID, err)
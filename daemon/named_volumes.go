@@ -0,0 +1,154 @@
+package daemon
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/directory"
+	"github.com/docker/docker/pkg/parsers/filters"
+	"github.com/docker/docker/volumes"
+)
+
+// VolumeCreate creates the volume name, stored by driver with options.
+func (daemon *Daemon) VolumeCreate(name, driver string, options, labels map[string]string) (*types.Volume, error) {
+	v, err := daemon.volumes.Create(name, driver, options, labels)
+	if err != nil {
+		return nil, err
+	}
+	daemon.EventsService.Log("create", v.Name, "volume:"+v.Driver)
+	return daemon.volumeResource(v), nil
+}
+
+// VolumeRm removes the volume name. Volumes used by containers cannot be
+// removed.
+func (daemon *Daemon) VolumeRm(name string) error {
+	v, err := daemon.volumes.GetByName(name)
+	if err != nil {
+		return err
+	}
+	if err := daemon.volumes.DeleteByName(name); err != nil {
+		return err
+	}
+	daemon.EventsService.Log("destroy", v.Name, "volume:"+v.Driver)
+	return nil
+}
+
+// Volumes returns all the volumes which are not bind mounts.
+func (daemon *Daemon) Volumes() []*types.Volume {
+	vols := daemon.volumes.List()
+	list := make([]*types.Volume, 0, len(vols))
+	for _, v := range vols {
+		list = append(list, daemon.volumeResource(v))
+	}
+	return list
+}
+
+// VolumeInspect returns the volume name.
+func (daemon *Daemon) VolumeInspect(name string) (*types.Volume, error) {
+	v, err := daemon.volumes.GetByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return daemon.volumeResource(v), nil
+}
+
+// namedVolume returns the volume name, creating it with the default driver
+// when it does not exist.
+func (daemon *Daemon) namedVolume(name, driver string, options, labels map[string]string) (*volumes.Volume, error) {
+	if v, err := daemon.volumes.GetByName(name); err == nil {
+		if driver != "" && driver != v.Driver {
+			return nil, fmt.Errorf("volume %s already exists with the driver %s", name, v.Driver)
+		}
+		return v, nil
+	}
+	v, err := daemon.volumes.Create(name, driver, options, labels)
+	if err != nil {
+		return nil, err
+	}
+	daemon.EventsService.Log("create", v.Name, "volume:"+v.Driver)
+	return v, nil
+}
+
+func (daemon *Daemon) volumeResource(v *volumes.Volume) *types.Volume {
+	mountpoint, err := daemon.volumes.Mountpoint(v)
+	if err != nil {
+		logrus.Errorf("Error getting the mountpoint of volume %s: %v", v.Name, err)
+	}
+	return &types.Volume{
+		Name:       v.Name,
+		Driver:     v.Driver,
+		Mountpoint: mountpoint,
+		Options:    v.Options,
+		Labels:     v.Labels,
+		Containers: v.Containers(),
+	}
+}
+
+var acceptedVolumePruneFilterTags = map[string]struct{}{
+	"label": {},
+	"until": {},
+}
+
+// VolumesPrune removes the volumes not used by any container, matching the
+// label and until filters.
+func (daemon *Daemon) VolumesPrune(pruneFilters filters.Args) (*types.VolumesPruneReport, error) {
+	for name := range pruneFilters {
+		if _, ok := acceptedVolumePruneFilterTags[name]; !ok {
+			return nil, fmt.Errorf("Invalid filter '%s'", name)
+		}
+	}
+	until, err := pruneUntil(pruneFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.VolumesPruneReport{VolumesDeleted: []string{}}
+	for _, v := range daemon.volumes.List() {
+		if len(v.Containers()) > 0 || !pruneFilters.MatchKVList("label", v.Labels) {
+			continue
+		}
+		if !until.IsZero() && !v.CreatedAt.Before(until) {
+			continue
+		}
+
+		var size int64
+		if !v.IsExternal() {
+			if size, err = directory.Size(v.Path); err != nil {
+				logrus.Debugf("Error getting the size of volume %s: %v", v.Name, err)
+			}
+		}
+		if err := daemon.volumes.DeleteByName(v.Name); err != nil {
+			// The volume may have been used or removed in the meantime
+			logrus.Debugf("Error pruning volume %s: %v", v.Name, err)
+			continue
+		}
+		daemon.EventsService.Log("destroy", v.Name, "volume:"+v.Driver)
+		report.VolumesDeleted = append(report.VolumesDeleted, v.Name)
+		report.SpaceReclaimed += uint64(size)
+	}
+	return report, nil
+}
+
+// pruneUntil returns the time given by the until filter, either a Unix
+// timestamp or a duration before now. It returns the zero time when the
+// filter is not set.
+func pruneUntil(pruneFilters filters.Args) (time.Time, error) {
+	values := pruneFilters["until"]
+	if len(values) == 0 {
+		return time.Time{}, nil
+	}
+	if len(values) > 1 {
+		return time.Time{}, fmt.Errorf("Only one until filter is allowed")
+	}
+	if d, err := time.ParseDuration(values[0]); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	ts, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid until filter '%s', expected a timestamp or a duration", values[0])
+	}
+	return time.Unix(ts, 0), nil
+}
@@ -0,0 +1,37 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/parsers/filters"
+)
+
+func TestPruneUntil(t *testing.T) {
+	until, err := pruneUntil(filters.Args{})
+	if err != nil || !until.IsZero() {
+		t.Fatalf("Expected no until filter, got %v, %v", until, err)
+	}
+
+	until, err = pruneUntil(filters.Args{"until": {"1433808000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !until.Equal(time.Unix(1433808000, 0)) {
+		t.Fatalf("Expected the time of the timestamp, got %v", until)
+	}
+
+	until, err = pruneUntil(filters.Args{"until": {"24h"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(until); d < 24*time.Hour || d > 25*time.Hour {
+		t.Fatalf("Expected a day ago, got %v", until)
+	}
+
+	for _, values := range [][]string{{"yesterday"}, {"24h", "48h"}} {
+		if _, err := pruneUntil(filters.Args{"until": values}); err == nil {
+			t.Fatalf("Expected an error for until %v", values)
+		}
+	}
+}
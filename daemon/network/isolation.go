@@ -0,0 +1,141 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/iptables"
+)
+
+const (
+	// ICCOption is the network option enabling the communication between
+	// the containers of the network, "true" by default.
+	ICCOption = "com.docker.network.icc"
+	// ExternalOption is the network option enabling the communication
+	// between the containers of the network and the outside world, "true"
+	// by default.
+	ExternalOption = "com.docker.network.external"
+
+	// IsolationChain is the iptables chain of the filter table isolating
+	// the user-defined networks from each other.
+	IsolationChain = "DOCKER-ISOLATION"
+)
+
+// boolOption returns the value of the boolean option name of the network
+// options, def when it is not set.
+func boolOption(options map[string]string, name string, def bool) (bool, error) {
+	v, ok := options[name]
+	if !ok {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %s for option %s, expected true or false", v, name)
+	}
+	return b, nil
+}
+
+func validateIsolationOptions(options map[string]string) error {
+	for _, name := range []string{ICCOption, ExternalOption} {
+		if _, err := boolOption(options, name, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isolationRules returns the rules of the isolation chain for networks.
+// Traffic between two networks is dropped, as well as the traffic within a
+// network with ICC disabled and the traffic between a network with external
+// access disabled and anything else. Only the networks with a subnet can be
+// isolated.
+func isolationRules(networks []*Network) [][]string {
+	var subnets []*Network
+	for _, n := range networks {
+		if n.Subnet != "" {
+			subnets = append(subnets, n)
+		}
+	}
+	sort.Sort(byName(subnets))
+
+	var rules [][]string
+	for _, n := range subnets {
+		if icc, _ := boolOption(n.Options, ICCOption, true); !icc {
+			rules = append(rules, []string{"-s", n.Subnet, "-d", n.Subnet, "-j", "DROP"})
+		}
+		if external, _ := boolOption(n.Options, ExternalOption, true); !external {
+			rules = append(rules,
+				[]string{"-s", n.Subnet, "!", "-d", n.Subnet, "-j", "DROP"},
+				[]string{"!", "-s", n.Subnet, "-d", n.Subnet, "-j", "DROP"})
+			continue
+		}
+		for _, other := range subnets {
+			if other != n {
+				rules = append(rules, []string{"-s", n.Subnet, "-d", other.Subnet, "-j", "DROP"})
+			}
+		}
+	}
+	return append(rules, []string{"-j", "RETURN"})
+}
+
+// EnableIsolation programs the isolation chain for the networks of the store,
+// and keeps it up to date as networks come and go.
+func (s *Store) EnableIsolation() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.isolation = true
+	iptables.OnReloaded(func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if err := s.programIsolation(); err != nil {
+			logrus.Errorf("Error isolating networks: %v", err)
+		}
+	})
+	return s.programIsolation()
+}
+
+// updateIsolation reprograms the isolation chain after the networks of the
+// store changed. It must be called with the lock of the store held.
+func (s *Store) updateIsolation() {
+	if !s.isolation {
+		return
+	}
+	if err := s.programIsolation(); err != nil {
+		logrus.Errorf("Error isolating networks: %v", err)
+	}
+}
+
+func (s *Store) programIsolation() error {
+	if _, err := iptables.Raw("-n", "-L", IsolationChain); err != nil {
+		if _, err := iptables.Raw("-N", IsolationChain); err != nil {
+			return err
+		}
+	}
+	jump := []string{"-j", IsolationChain}
+	if !iptables.Exists(iptables.Filter, "FORWARD", jump...) {
+		if output, err := iptables.Raw(append([]string{"-I", "FORWARD"}, jump...)...); err != nil {
+			return err
+		} else if len(output) != 0 {
+			return iptables.ChainError{Chain: "FORWARD", Output: output}
+		}
+	}
+
+	if _, err := iptables.Raw("-F", IsolationChain); err != nil {
+		return err
+	}
+	networks := make([]*Network, 0, len(s.networks))
+	for _, n := range s.networks {
+		networks = append(networks, n)
+	}
+	for _, rule := range isolationRules(networks) {
+		if output, err := iptables.Raw(append([]string{"-A", IsolationChain}, rule...)...); err != nil {
+			return err
+		} else if len(output) != 0 {
+			return iptables.ChainError{Chain: IsolationChain, Output: output}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package network
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver"
+)
+
+func TestIsolationRules(t *testing.T) {
+	networks := []*Network{
+		{Name: "b", Subnet: "10.2.0.0/16"},
+		{Name: "a", Subnet: "10.1.0.0/16", Options: map[string]string{ICCOption: "false"}},
+		{Name: "c", Subnet: "10.3.0.0/16", Options: map[string]string{ExternalOption: "false"}},
+		{Name: "d"},
+	}
+
+	expected := [][]string{
+		{"-s", "10.1.0.0/16", "-d", "10.1.0.0/16", "-j", "DROP"},
+		{"-s", "10.1.0.0/16", "-d", "10.2.0.0/16", "-j", "DROP"},
+		{"-s", "10.1.0.0/16", "-d", "10.3.0.0/16", "-j", "DROP"},
+		{"-s", "10.2.0.0/16", "-d", "10.1.0.0/16", "-j", "DROP"},
+		{"-s", "10.2.0.0/16", "-d", "10.3.0.0/16", "-j", "DROP"},
+		{"-s", "10.3.0.0/16", "!", "-d", "10.3.0.0/16", "-j", "DROP"},
+		{"!", "-s", "10.3.0.0/16", "-d", "10.3.0.0/16", "-j", "DROP"},
+		{"-j", "RETURN"},
+	}
+	if rules := isolationRules(networks); !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Expected rules %v, got %v", expected, rules)
+	}
+}
+
+func TestIsolationOptions(t *testing.T) {
+	s, root := newTestStore(t)
+	defer os.RemoveAll(root)
+
+	for _, name := range []string{ICCOption, ExternalOption} {
+		config := &networkdriver.NetworkConfig{Options: map[string]string{name: "maybe"}}
+		if _, err := s.Create("foo", "fake", config); err == nil {
+			t.Fatalf("Expected an error creating a network with an invalid %s option", name)
+		}
+	}
+	config := &networkdriver.NetworkConfig{Options: map[string]string{ICCOption: "false", ExternalOption: "true"}}
+	if _, err := s.Create("foo", "fake", config); err != nil {
+		t.Fatal(err)
+	}
+}
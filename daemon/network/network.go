@@ -0,0 +1,484 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/docker/pkg/stringid"
+)
+
+// Network is a user-defined network provided by a network driver.
+type Network struct {
+	ID         string
+	Name       string
+	Driver     string
+	Subnet     string
+	Gateway    string
+	SubnetIPv6 string
+	MTU        int
+	Options    map[string]string
+
+	endpoints   map[string]*Endpoint
+	peers       map[string]*Endpoint
+	scope       string
+	subnet      *net.IPNet
+	subnetIPv6  *net.IPNet
+	ipAllocator *ipallocator.IPAllocator
+	configPath  string
+	store       *Store
+	lock        sync.Mutex
+}
+
+// Endpoint is the attachment of a container to a network.
+type Endpoint struct {
+	ID          string
+	NetworkID   string
+	ContainerID string
+	// Name is the name of the container, by which the other containers of
+	// the network can reach it.
+	Name        string
+	Interface   networkdriver.EndpointInterface
+	Gateway     string
+	GatewayIPv6 string
+	// SandboxKey is the path of the network namespace the endpoint joined.
+	SandboxKey string
+	// IfaceName is the name of the interface in the sandbox.
+	IfaceName string
+	// DeviceRoute is set when the default routes through the interface
+	// have no next hop for the address families without a gateway.
+	DeviceRoute bool `json:",omitempty"`
+}
+
+// Pool is a subnet the addresses of the endpoints of a network are allocated
+// from, and its usage.
+type Pool struct {
+	Subnet  string
+	Gateway string
+	// Allocated is the number of addresses in use, the gateway included,
+	// out of the Capacity of the subnet.
+	Allocated int
+	Capacity  uint64
+}
+
+// initIPAM sets up address management for the network's subnets, if any,
+// reserving the gateway of the IPv4 subnet.
+func (n *Network) initIPAM() error {
+	if n.Subnet == "" {
+		if n.Gateway != "" {
+			return fmt.Errorf("gateway %s given without a subnet", n.Gateway)
+		}
+		if n.SubnetIPv6 != "" {
+			return fmt.Errorf("IPv6 subnet %s given without an IPv4 subnet", n.SubnetIPv6)
+		}
+		return nil
+	}
+
+	_, subnet, err := net.ParseCIDR(n.Subnet)
+	if err != nil {
+		return err
+	}
+	if subnet.IP.To4() == nil {
+		return fmt.Errorf("subnet %s is not an IPv4 subnet", n.Subnet)
+	}
+	n.subnet = subnet
+	n.ipAllocator = ipallocator.New()
+
+	var gw net.IP
+	if n.Gateway != "" {
+		if gw = net.ParseIP(n.Gateway); gw == nil {
+			return fmt.Errorf("invalid gateway ip %s", n.Gateway)
+		}
+		if !subnet.Contains(gw) {
+			return fmt.Errorf("gateway ip %s must be part of the subnet %s", n.Gateway, n.Subnet)
+		}
+	}
+	if gw, err = n.ipAllocator.RequestIP(subnet, gw); err != nil {
+		return err
+	}
+	n.Gateway = gw.String()
+
+	if n.SubnetIPv6 != "" {
+		_, subnetIPv6, err := net.ParseCIDR(n.SubnetIPv6)
+		if err != nil {
+			return err
+		}
+		if subnetIPv6.IP.To4() != nil {
+			return fmt.Errorf("subnet %s is not an IPv6 subnet", n.SubnetIPv6)
+		}
+		n.subnetIPv6 = subnetIPv6
+	}
+	return nil
+}
+
+// ValidateAddresses checks that the IPv4 address ip and the IPv6 address ip6
+// requested for an endpoint, either of which may be empty, belong to the
+// subnets of the network.
+func (n *Network) ValidateAddresses(ip, ip6 string) error {
+	if ip != "" {
+		if _, err := parseAddress(ip, n.Subnet, false); err != nil {
+			return err
+		}
+	}
+	if ip6 != "" {
+		if _, err := parseAddress(ip6, n.SubnetIPv6, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseAddress parses the address addr requested from the subnet cidr.
+func parseAddress(addr, cidr string, ipv6 bool) (net.IP, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil || (ip.To4() == nil) != ipv6 {
+		if ipv6 {
+			return nil, fmt.Errorf("invalid IPv6 address %s", addr)
+		}
+		return nil, fmt.Errorf("invalid IPv4 address %s", addr)
+	}
+	if cidr == "" {
+		return nil, fmt.Errorf("cannot request address %s from a network without a subnet", addr)
+	}
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if !subnet.Contains(ip) {
+		return nil, fmt.Errorf("address %s is not part of the subnet %s", addr, cidr)
+	}
+	return ip, nil
+}
+
+// requestIP allocates the address requested by the endpoint option name from
+// subnet, or else the next free address of subnet.
+func (n *Network) requestIP(subnet *net.IPNet, options map[string]string, name string) (net.IP, error) {
+	var ip net.IP
+	if addr := options[name]; addr != "" {
+		var err error
+		if ip, err = parseAddress(addr, subnet.String(), name == networkdriver.IPv6AddressOption); err != nil {
+			return nil, err
+		}
+	}
+	ip, err := n.ipAllocator.RequestIP(subnet, ip)
+	if err == ipallocator.ErrIPAlreadyAllocated {
+		return nil, fmt.Errorf("address %s is already in use on network %s", options[name], n.Name)
+	}
+	return ip, err
+}
+
+func (n *Network) config() *networkdriver.NetworkConfig {
+	return &networkdriver.NetworkConfig{
+		Subnet:     n.Subnet,
+		Gateway:    n.Gateway,
+		SubnetIPv6: n.SubnetIPv6,
+		MTU:        n.MTU,
+		Options:    n.Options,
+	}
+}
+
+// mtu returns the MTU of the interface of an endpoint joined as described by
+// join: the MTU of the network, or the one required by the driver, or the
+// default MTU of the daemon.
+func (n *Network) mtu(join *networkdriver.JoinInfo) int {
+	switch {
+	case n.MTU > 0:
+		return n.MTU
+	case join.MTU > 0:
+		return join.MTU
+	}
+	return n.store.defaultMTU
+}
+
+// Scope returns the scope of the network's driver, networkdriver.LocalScope
+// or networkdriver.GlobalScope.
+func (n *Network) Scope() string {
+	return n.scope
+}
+
+// Pools returns the address pools of the network. There are none when the
+// network has no subnet, its driver allocating the addresses.
+func (n *Network) Pools() []Pool {
+	var pools []Pool
+	if n.subnet != nil {
+		allocated, capacity := n.ipAllocator.Usage(n.subnet)
+		pools = append(pools, Pool{
+			Subnet:    n.Subnet,
+			Gateway:   n.Gateway,
+			Allocated: allocated,
+			Capacity:  capacity,
+		})
+	}
+	if n.subnetIPv6 != nil {
+		allocated, capacity := n.ipAllocator.Usage(n.subnetIPv6)
+		pools = append(pools, Pool{
+			Subnet:    n.SubnetIPv6,
+			Allocated: allocated,
+			Capacity:  capacity,
+		})
+	}
+	return pools
+}
+
+// Endpoints returns the endpoints currently attached to the network,
+// including the ones on other hosts for networks of global scope.
+func (n *Network) Endpoints() []*Endpoint {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	endpoints := make([]*Endpoint, 0, len(n.endpoints)+len(n.peers))
+	for _, ep := range n.endpoints {
+		endpoints = append(endpoints, ep)
+	}
+	for id, ep := range n.peers {
+		if _, ok := n.endpoints[id]; !ok {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	return endpoints
+}
+
+// Attach connects the container containerID named name to the network: it
+// creates an endpoint, allocating its addresses unless the driver manages
+// them, and moves the interface of the endpoint into the network namespace
+// at sandboxKey. The default route of the namespace goes through the
+// gateway of the endpoint.
+func (n *Network) Attach(containerID, name, sandboxKey string, options map[string]string) (*Endpoint, error) {
+	return n.attach(containerID, name, sandboxKey, options, true)
+}
+
+// Connect attaches the container containerID named name to the network like
+// Attach, as an additional network of a namespace which already has a
+// default route: no route is set through the gateway of the endpoint.
+func (n *Network) Connect(containerID, name, sandboxKey string, options map[string]string) (*Endpoint, error) {
+	return n.attach(containerID, name, sandboxKey, options, false)
+}
+
+func (n *Network) attach(containerID, name, sandboxKey string, options map[string]string, defaultRoute bool) (ep *Endpoint, err error) {
+	d, err := n.store.driver(n.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	ep = &Endpoint{
+		ID:          stringid.GenerateRandomID(),
+		NetworkID:   n.ID,
+		ContainerID: containerID,
+		Name:        name,
+		SandboxKey:  sandboxKey,
+	}
+
+	var iface *networkdriver.EndpointInterface
+	if n.subnet != nil {
+		var ip net.IP
+		ip, err = n.requestIP(n.subnet, options, networkdriver.IPv4AddressOption)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				n.ipAllocator.ReleaseIP(n.subnet, ip)
+			}
+		}()
+		ones, _ := n.subnet.Mask.Size()
+		iface = &networkdriver.EndpointInterface{
+			Address:    fmt.Sprintf("%s/%d", ip, ones),
+			MacAddress: options[networkdriver.MacAddressOption],
+		}
+		if iface.MacAddress == "" {
+			iface.MacAddress = networkdriver.GenerateMacAddr(ip).String()
+		}
+
+		if n.subnetIPv6 != nil {
+			var ip6 net.IP
+			ip6, err = n.requestIP(n.subnetIPv6, options, networkdriver.IPv6AddressOption)
+			if err != nil {
+				return nil, err
+			}
+			defer func() {
+				if err != nil {
+					n.ipAllocator.ReleaseIP(n.subnetIPv6, ip6)
+				}
+			}()
+			ones, _ := n.subnetIPv6.Mask.Size()
+			iface.AddressIPv6 = fmt.Sprintf("%s/%d", ip6, ones)
+		} else if addr := options[networkdriver.IPv6AddressOption]; addr != "" {
+			return nil, fmt.Errorf("cannot request address %s from network %s without an IPv6 subnet", addr, n.Name)
+		}
+	}
+
+	// The deferred cleanups run after ep is reset by the returns below.
+	epID := ep.ID
+	returned, err := d.CreateEndpoint(n.ID, epID, iface, options)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if err := d.DeleteEndpoint(n.ID, epID); err != nil {
+				logrus.Warnf("Error deleting endpoint %s after failed attach: %v", epID, err)
+			}
+		}
+	}()
+	if iface == nil {
+		if returned == nil {
+			return nil, fmt.Errorf("driver %s did not allocate an interface for endpoint %s", n.Driver, ep.ID)
+		}
+		iface = returned
+	}
+	ep.Interface = *iface
+
+	join, err := d.Join(n.ID, ep.ID, sandboxKey, options)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if err := d.Leave(n.ID, epID); err != nil {
+				logrus.Warnf("Error leaving endpoint %s after failed attach: %v", epID, err)
+			}
+		}
+	}()
+	ep.Gateway = join.Gateway
+	ep.GatewayIPv6 = join.GatewayIPv6
+	ep.DeviceRoute = join.DeviceRoute
+
+	sbIface := &sandbox.Interface{
+		SrcName:     join.SrcName,
+		DstPrefix:   join.DstPrefix,
+		Address:     ep.Interface.Address,
+		AddressIPv6: ep.Interface.AddressIPv6,
+		MacAddress:  ep.Interface.MacAddress,
+		MTU:         n.mtu(join),
+	}
+	if defaultRoute {
+		sbIface.Gateway = join.Gateway
+		sbIface.GatewayIPv6 = join.GatewayIPv6
+		sbIface.DeviceRoute = join.DeviceRoute
+	}
+	ep.IfaceName, err = sandbox.AddInterface(sandboxKey, sbIface)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := n.store.publishEndpoint(n, ep); err != nil {
+		sandbox.RemoveInterface(sandboxKey, ep.IfaceName)
+		return nil, err
+	}
+
+	n.lock.Lock()
+	n.endpoints[ep.ID] = ep
+	n.lock.Unlock()
+	n.store.notify(n)
+	return ep, nil
+}
+
+// Restore records the endpoint ep, attached before the daemon restarted, as
+// attached to the network again.
+func (n *Network) Restore(ep *Endpoint) error {
+	if n.subnet != nil {
+		ip, _, err := net.ParseCIDR(ep.Interface.Address)
+		if err != nil {
+			return err
+		}
+		if _, err := n.ipAllocator.RequestIP(n.subnet, ip); err != nil {
+			return err
+		}
+	}
+	if n.subnetIPv6 != nil && ep.Interface.AddressIPv6 != "" {
+		ip, _, err := net.ParseCIDR(ep.Interface.AddressIPv6)
+		if err != nil {
+			return err
+		}
+		if _, err := n.ipAllocator.RequestIP(n.subnetIPv6, ip); err != nil {
+			return err
+		}
+	}
+
+	if err := n.store.publishEndpoint(n, ep); err != nil {
+		return err
+	}
+
+	n.lock.Lock()
+	n.endpoints[ep.ID] = ep
+	n.lock.Unlock()
+	return nil
+}
+
+// Detach disconnects the endpoint ep from the network, removing its
+// interface from the sandbox unless the sandbox is being destroyed.
+func (n *Network) Detach(ep *Endpoint, removeInterface bool) error {
+	n.lock.Lock()
+	if _, ok := n.endpoints[ep.ID]; !ok {
+		n.lock.Unlock()
+		return fmt.Errorf("endpoint %s is not attached to network %s", ep.ID, n.Name)
+	}
+	delete(n.endpoints, ep.ID)
+	n.lock.Unlock()
+	defer n.store.notify(n)
+
+	if err := n.store.unpublishEndpoint(n, ep); err != nil {
+		logrus.Warnf("Error unpublishing endpoint %s: %v", ep.ID, err)
+	}
+
+	if removeInterface && ep.IfaceName != "" {
+		if err := sandbox.RemoveInterface(ep.SandboxKey, ep.IfaceName); err != nil {
+			logrus.Warnf("Error removing interface %s of endpoint %s: %v", ep.IfaceName, ep.ID, err)
+		}
+	}
+
+	d, err := n.store.driver(n.Driver)
+	if err != nil {
+		return err
+	}
+	if err := d.Leave(n.ID, ep.ID); err != nil {
+		logrus.Warnf("Error leaving endpoint %s: %v", ep.ID, err)
+	}
+	if err := d.DeleteEndpoint(n.ID, ep.ID); err != nil {
+		return err
+	}
+
+	if n.subnet != nil {
+		if ip, _, err := net.ParseCIDR(ep.Interface.Address); err == nil {
+			n.ipAllocator.ReleaseIP(n.subnet, ip)
+		}
+	}
+	if n.subnetIPv6 != nil {
+		if ip, _, err := net.ParseCIDR(ep.Interface.AddressIPv6); err == nil {
+			n.ipAllocator.ReleaseIP(n.subnetIPv6, ip)
+		}
+	}
+	return nil
+}
+
+func (n *Network) toDisk() error {
+	if err := os.MkdirAll(n.configPath, 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(n.configPath, "config.json"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(n); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (n *Network) fromDisk() error {
+	f, err := os.Open(filepath.Join(n.configPath, "config.json"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewDecoder(f).Decode(n)
+}
@@ -16,4 +16,32 @@ type Settings struct {
 	PortMapping            map[string]map[string]string // Deprecated
 	Ports                  nat.PortMap
 	HairpinMode            bool
+	NetworkID              string
+	EndpointID             string
+	SandboxKey             string
+	// IfaceName is the name of the interface of the endpoint EndpointID in
+	// the sandbox, and DeviceRoute its Endpoint.DeviceRoute.
+	IfaceName   string `json:",omitempty"`
+	DeviceRoute bool   `json:",omitempty"`
+	// Connected are the endpoints of the networks the running container was
+	// connected to in addition to its own.
+	Connected []*Endpoint
+	// PortStatus is the state of the forwarding of the published ports, in
+	// the order of their bindings in Ports. It is only reported by inspect.
+	PortStatus map[nat.Port][]PortStatus `json:",omitempty"`
+}
+
+// PortStatus is the state of the forwarding of a published port.
+type PortStatus struct {
+	HostIp   string
+	HostPort string
+	// Iptables reports whether the iptables rule forwarding the port is in
+	// place.
+	Iptables bool
+	// Proxy is the state of the userland proxy forwarding the port,
+	// "running" or "exited", empty when there is none.
+	Proxy string `json:",omitempty"`
+	// Reflection reports whether the other containers can reach the port
+	// through the address of the host.
+	Reflection bool
 }
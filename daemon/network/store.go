@@ -0,0 +1,481 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/remote"
+	"github.com/docker/docker/pkg/kvstore"
+	"github.com/docker/docker/pkg/stringid"
+)
+
+const validNetworkNameChars = `[a-zA-Z0-9][a-zA-Z0-9_.-]`
+
+var validNetworkNamePattern = regexp.MustCompile(`^` + validNetworkNameChars + `+$`)
+
+const (
+	// kvPrefix is the root of the keys networks of global scope are shared
+	// under in the key-value store.
+	kvPrefix = "docker/network/v1.0"
+	// syncInterval is how often networks of global scope and their
+	// endpoints are refreshed from the key-value store.
+	syncInterval = 5 * time.Second
+
+	// minMTU is the smallest MTU an IPv4 host must accept, maxMTU the
+	// largest MTU of a Linux interface.
+	minMTU = 68
+	maxMTU = 65535
+)
+
+// Store keeps track of the user-defined networks of the daemon.
+type Store struct {
+	configPath string
+	kv         kvstore.Store
+	defaultMTU int
+	networks   map[string]*Network
+	onChange   func(*Network)
+	isolation  bool
+	lock       sync.Mutex
+}
+
+// NewStore returns a store keeping the configuration of its networks under
+// configPath, restoring the networks previously created there. Networks of
+// global scope are shared through kv, which may be nil if the daemon is not
+// part of a cluster. The interfaces of the networks which set no MTU get
+// defaultMTU, unless their driver requires another one.
+func NewStore(configPath string, kv kvstore.Store, defaultMTU int) (*Store, error) {
+	abspath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(abspath, 0700); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+
+	s := &Store{
+		configPath: abspath,
+		kv:         kv,
+		defaultMTU: defaultMTU,
+		networks:   make(map[string]*Network),
+	}
+	if err := s.restore(); err != nil {
+		return nil, err
+	}
+
+	if kv != nil {
+		if err := s.sync(); err != nil {
+			logrus.Errorf("Error loading networks from the key-value store: %v", err)
+		}
+		go func() {
+			for range time.Tick(syncInterval) {
+				if err := s.sync(); err != nil {
+					logrus.Debugf("Error syncing networks with the key-value store: %v", err)
+				}
+			}
+		}()
+	}
+	return s, nil
+}
+
+func (s *Store) restore() error {
+	dir, err := ioutil.ReadDir(s.configPath)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range dir {
+		n := &Network{
+			ID:         v.Name(),
+			configPath: filepath.Join(s.configPath, v.Name()),
+			endpoints:  make(map[string]*Endpoint),
+			scope:      networkdriver.LocalScope,
+			store:      s,
+		}
+		if err := n.fromDisk(); err != nil {
+			logrus.Debugf("Error restoring network %s: %v", v.Name(), err)
+			continue
+		}
+		if err := n.initIPAM(); err != nil {
+			logrus.Debugf("Error restoring network %s: %v", v.Name(), err)
+			continue
+		}
+		s.networks[n.ID] = n
+	}
+	return nil
+}
+
+// OnEndpointsChange registers fn to be called whenever endpoints are attached
+// to or detached from a network, on this host or, for networks of global
+// scope, on another one.
+func (s *Store) OnEndpointsChange(fn func(*Network)) {
+	s.lock.Lock()
+	s.onChange = fn
+	s.lock.Unlock()
+}
+
+func (s *Store) notify(n *Network) {
+	s.lock.Lock()
+	fn := s.onChange
+	s.lock.Unlock()
+	if fn != nil {
+		go fn(n)
+	}
+}
+
+// driver returns the network driver registered under name, activating the
+// network plugin of that name if no such driver is registered yet.
+func (s *Store) driver(name string) (networkdriver.Driver, error) {
+	if d, err := networkdriver.GetDriver(name); err == nil {
+		return d, nil
+	}
+	return remote.Load(name)
+}
+
+// Create creates a network named name, provided by the driver named driver.
+func (s *Store) Create(name, driver string, config *networkdriver.NetworkConfig) (*Network, error) {
+	if !validNetworkNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("Invalid network name (%s), only %s are allowed", name, validNetworkNameChars)
+	}
+	switch name {
+	case "bridge", "host", "none", "default", "container":
+		return nil, fmt.Errorf("%s is a reserved network name", name)
+	}
+	if config == nil {
+		config = &networkdriver.NetworkConfig{}
+	}
+	if config.MTU != 0 && (config.MTU < minMTU || config.MTU > maxMTU) {
+		return nil, fmt.Errorf("invalid MTU %d, it must be between %d and %d", config.MTU, minMTU, maxMTU)
+	}
+	if err := validateIsolationOptions(config.Options); err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, n := range s.networks {
+		if n.Name == name {
+			return nil, fmt.Errorf("network with name %s already exists", name)
+		}
+	}
+
+	d, err := s.driver(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	id := stringid.GenerateRandomID()
+	n := &Network{
+		ID:         id,
+		Name:       name,
+		Driver:     driver,
+		Subnet:     config.Subnet,
+		Gateway:    config.Gateway,
+		SubnetIPv6: config.SubnetIPv6,
+		MTU:        config.MTU,
+		Options:    config.Options,
+		endpoints:  make(map[string]*Endpoint),
+		scope:      networkdriver.Scope(d),
+		configPath: filepath.Join(s.configPath, id),
+		store:      s,
+	}
+
+	if n.scope == networkdriver.GlobalScope {
+		if err := s.createGlobal(n, d); err != nil {
+			return nil, err
+		}
+		s.networks[n.ID] = n
+		s.updateIsolation()
+		return n, nil
+	}
+
+	if err := n.initIPAM(); err != nil {
+		return nil, err
+	}
+
+	if err := d.CreateNetwork(n.ID, n.config()); err != nil {
+		return nil, err
+	}
+	if err := n.toDisk(); err != nil {
+		if err := d.DeleteNetwork(n.ID); err != nil {
+			logrus.Warnf("Error deleting network %s: %v", n.ID, err)
+		}
+		os.RemoveAll(n.configPath)
+		return nil, err
+	}
+
+	s.networks[n.ID] = n
+	s.updateIsolation()
+	return n, nil
+}
+
+// createGlobal creates the network n of global scope and shares it through
+// the key-value store. The driver manages the addresses of its endpoints.
+func (s *Store) createGlobal(n *Network, d networkdriver.Driver) error {
+	if s.kv == nil {
+		return fmt.Errorf("%s networks require a key-value store, see the --kv-store option of the daemon", n.Driver)
+	}
+	if n.Subnet != "" {
+		if _, _, err := net.ParseCIDR(n.Subnet); err != nil {
+			return err
+		}
+	}
+	if n.Gateway != "" && net.ParseIP(n.Gateway) == nil {
+		return fmt.Errorf("invalid gateway ip %s", n.Gateway)
+	}
+
+	pairs, err := s.kv.List(path.Join(kvPrefix, "network"))
+	if err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		var other Network
+		if err := json.Unmarshal(p.Value, &other); err == nil && other.Name == n.Name {
+			return fmt.Errorf("network with name %s already exists", n.Name)
+		}
+	}
+
+	if err := d.CreateNetwork(n.ID, n.config()); err != nil {
+		return err
+	}
+	b, err := json.Marshal(n)
+	if err == nil {
+		err = s.kv.AtomicCreate(path.Join(kvPrefix, "network", n.ID), b)
+	}
+	if err != nil {
+		if err := d.DeleteNetwork(n.ID); err != nil {
+			logrus.Warnf("Error deleting network %s: %v", n.ID, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// sync refreshes the networks of global scope, and their endpoints, from
+// the key-value store.
+func (s *Store) sync() error {
+	pairs, err := s.kv.List(path.Join(kvPrefix, "network"))
+	if err != nil {
+		return err
+	}
+
+	var (
+		shared  = make(map[string]bool, len(pairs))
+		changed []*Network
+		updated = false
+	)
+	s.lock.Lock()
+	for _, p := range pairs {
+		n := &Network{}
+		if err := json.Unmarshal(p.Value, n); err != nil {
+			logrus.Debugf("Error decoding network %s: %v", p.Key, err)
+			continue
+		}
+		shared[n.ID] = true
+		if _, ok := s.networks[n.ID]; ok {
+			continue
+		}
+
+		d, err := s.driver(n.Driver)
+		if err != nil {
+			logrus.Errorf("Error loading driver of network %s: %v", n.Name, err)
+			continue
+		}
+		if err := d.CreateNetwork(n.ID, n.config()); err != nil {
+			logrus.Errorf("Error creating network %s: %v", n.Name, err)
+			continue
+		}
+		n.endpoints = make(map[string]*Endpoint)
+		n.scope = networkdriver.GlobalScope
+		n.store = s
+		s.networks[n.ID] = n
+		updated = true
+	}
+
+	for id, n := range s.networks {
+		if n.scope != networkdriver.GlobalScope {
+			continue
+		}
+		if !shared[id] {
+			n.lock.Lock()
+			attached := len(n.endpoints)
+			n.lock.Unlock()
+			if attached > 0 {
+				continue
+			}
+			if d, err := s.driver(n.Driver); err == nil {
+				if err := d.DeleteNetwork(n.ID); err != nil {
+					logrus.Warnf("Error deleting network %s: %v", n.Name, err)
+				}
+			}
+			delete(s.networks, id)
+			updated = true
+			continue
+		}
+		if s.syncEndpoints(n) {
+			changed = append(changed, n)
+		}
+	}
+	if updated {
+		s.updateIsolation()
+	}
+	s.lock.Unlock()
+
+	for _, n := range changed {
+		s.notify(n)
+	}
+	return nil
+}
+
+// syncEndpoints refreshes the endpoints of the network n of global scope
+// from the key-value store, and reports whether they changed.
+func (s *Store) syncEndpoints(n *Network) bool {
+	pairs, err := s.kv.List(path.Join(kvPrefix, "endpoint", n.ID))
+	if err != nil {
+		logrus.Debugf("Error listing endpoints of network %s: %v", n.Name, err)
+		return false
+	}
+
+	peers := make(map[string]*Endpoint, len(pairs))
+	for _, p := range pairs {
+		ep := &Endpoint{}
+		if err := json.Unmarshal(p.Value, ep); err != nil {
+			logrus.Debugf("Error decoding endpoint %s: %v", p.Key, err)
+			continue
+		}
+		peers[ep.ID] = ep
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	changed := len(peers) != len(n.peers)
+	for id := range peers {
+		if _, ok := n.peers[id]; !ok {
+			changed = true
+		}
+	}
+	n.peers = peers
+	return changed
+}
+
+// publishEndpoint shares the endpoint ep of the network n of global scope
+// with the other hosts.
+func (s *Store) publishEndpoint(n *Network, ep *Endpoint) error {
+	if n.scope != networkdriver.GlobalScope {
+		return nil
+	}
+	b, err := json.Marshal(ep)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(path.Join(kvPrefix, "endpoint", n.ID, ep.ID), b)
+}
+
+func (s *Store) unpublishEndpoint(n *Network, ep *Endpoint) error {
+	if n.scope != networkdriver.GlobalScope {
+		return nil
+	}
+	return s.kv.Delete(path.Join(kvPrefix, "endpoint", n.ID, ep.ID))
+}
+
+// Get returns the network whose name, ID or ID prefix is nameOrID.
+func (s *Store) Get(nameOrID string) (*Network, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.get(nameOrID)
+}
+
+func (s *Store) get(nameOrID string) (*Network, error) {
+	if n, ok := s.networks[nameOrID]; ok {
+		return n, nil
+	}
+	for _, n := range s.networks {
+		if n.Name == nameOrID {
+			return n, nil
+		}
+	}
+
+	var found *Network
+	for id, n := range s.networks {
+		if !strings.HasPrefix(id, nameOrID) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("network ID %s is ambiguous", nameOrID)
+		}
+		found = n
+	}
+	if found == nil || nameOrID == "" {
+		return nil, fmt.Errorf("no such network: %s", nameOrID)
+	}
+	return found, nil
+}
+
+// List returns all networks sorted by name.
+func (s *Store) List() []*Network {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	networks := make([]*Network, 0, len(s.networks))
+	for _, n := range s.networks {
+		networks = append(networks, n)
+	}
+	sort.Sort(byName(networks))
+	return networks
+}
+
+// Remove deletes the network nameOrID. Networks with attached endpoints
+// cannot be removed.
+func (s *Store) Remove(nameOrID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n, err := s.get(nameOrID)
+	if err != nil {
+		return err
+	}
+	if eps := n.Endpoints(); len(eps) > 0 {
+		return fmt.Errorf("network %s has active endpoints", n.Name)
+	}
+
+	d, err := s.driver(n.Driver)
+	if err != nil {
+		return err
+	}
+	if n.scope == networkdriver.GlobalScope {
+		if err := s.kv.Delete(path.Join(kvPrefix, "network", n.ID)); err != nil {
+			return err
+		}
+		s.kv.Delete(path.Join(kvPrefix, "endpoint", n.ID))
+	}
+	if err := d.DeleteNetwork(n.ID); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(n.configPath); err != nil {
+		return err
+	}
+	delete(s.networks, n.ID)
+	s.updateIsolation()
+	return nil
+}
+
+type byName []*Network
+
+func (n byName) Len() int           { return len(n) }
+func (n byName) Less(i, j int) bool { return n[i].Name < n[j].Name }
+func (n byName) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
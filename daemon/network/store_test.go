@@ -0,0 +1,425 @@
+package network
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/pkg/kvstore"
+)
+
+type fakeDriver struct {
+	networks map[string]*networkdriver.NetworkConfig
+}
+
+func (d *fakeDriver) CreateNetwork(nid string, config *networkdriver.NetworkConfig) error {
+	d.networks[nid] = config
+	return nil
+}
+
+func (d *fakeDriver) DeleteNetwork(nid string) error {
+	delete(d.networks, nid)
+	return nil
+}
+
+func (d *fakeDriver) CreateEndpoint(nid, eid string, iface *networkdriver.EndpointInterface, options map[string]string) (*networkdriver.EndpointInterface, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeleteEndpoint(nid, eid string) error {
+	return nil
+}
+
+func (d *fakeDriver) Join(nid, eid, sandboxKey string, options map[string]string) (*networkdriver.JoinInfo, error) {
+	return &networkdriver.JoinInfo{}, nil
+}
+
+func (d *fakeDriver) Leave(nid, eid string) error {
+	return nil
+}
+
+func (d *fakeDriver) Type() string {
+	return "fake"
+}
+
+type fakeGlobalDriver struct {
+	fakeDriver
+}
+
+func (d *fakeGlobalDriver) Scope() string {
+	return networkdriver.GlobalScope
+}
+
+type fakeFailingDriver struct {
+	fakeDriver
+}
+
+func (d *fakeFailingDriver) Join(nid, eid, sandboxKey string, options map[string]string) (*networkdriver.JoinInfo, error) {
+	return nil, errors.New("join failed")
+}
+
+var (
+	testDriver        = &fakeDriver{networks: make(map[string]*networkdriver.NetworkConfig)}
+	testGlobalDriver  = &fakeGlobalDriver{fakeDriver{networks: make(map[string]*networkdriver.NetworkConfig)}}
+	testFailingDriver = &fakeFailingDriver{fakeDriver{networks: make(map[string]*networkdriver.NetworkConfig)}}
+)
+
+func init() {
+	if err := networkdriver.RegisterDriver("fake", testDriver); err != nil {
+		panic(err)
+	}
+	if err := networkdriver.RegisterDriver("fakeglobal", testGlobalDriver); err != nil {
+		panic(err)
+	}
+	if err := networkdriver.RegisterDriver("fakefailing", testFailingDriver); err != nil {
+		panic(err)
+	}
+}
+
+func newTestStore(t *testing.T) (*Store, string) {
+	root, err := ioutil.TempDir("", "docker-networks-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStore(root, nil, 1500)
+	if err != nil {
+		os.RemoveAll(root)
+		t.Fatal(err)
+	}
+	return s, root
+}
+
+func TestStoreCreate(t *testing.T) {
+	s, root := newTestStore(t)
+	defer os.RemoveAll(root)
+
+	n, err := s.Create("foo", "fake", &networkdriver.NetworkConfig{Subnet: "10.10.0.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Gateway != "10.10.0.1" {
+		t.Fatalf("Expected gateway 10.10.0.1, got %s", n.Gateway)
+	}
+	config, ok := testDriver.networks[n.ID]
+	if !ok {
+		t.Fatal("Expected the network to be created by the driver")
+	}
+	if config.Subnet != "10.10.0.0/24" || config.Gateway != "10.10.0.1" {
+		t.Fatalf("Unexpected driver config %+v", config)
+	}
+
+	if _, err := s.Create("foo", "fake", nil); err == nil {
+		t.Fatal("Expected an error creating a network with a duplicate name")
+	}
+	for _, name := range []string{"", "bridge", "host", "-foo", "foo/bar"} {
+		if _, err := s.Create(name, "fake", nil); err == nil {
+			t.Fatalf("Expected an error creating a network named %q", name)
+		}
+	}
+	if _, err := s.Create("bar", "fake", &networkdriver.NetworkConfig{Subnet: "10.10.0.0/24", Gateway: "10.20.0.1"}); err == nil {
+		t.Fatal("Expected an error creating a network with a gateway outside its subnet")
+	}
+	if _, err := s.Create("bar", "fake", &networkdriver.NetworkConfig{Gateway: "10.20.0.1"}); err == nil {
+		t.Fatal("Expected an error creating a network with a gateway and no subnet")
+	}
+}
+
+func TestStoreMTU(t *testing.T) {
+	s, root := newTestStore(t)
+	defer os.RemoveAll(root)
+
+	for _, mtu := range []int{-1, 67, 65536} {
+		if _, err := s.Create("foo", "fake", &networkdriver.NetworkConfig{MTU: mtu}); err == nil {
+			t.Fatalf("Expected an error creating a network with MTU %d", mtu)
+		}
+	}
+
+	n, err := s.Create("foo", "fake", &networkdriver.NetworkConfig{MTU: 1400})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config := testDriver.networks[n.ID]; config.MTU != 1400 {
+		t.Fatalf("Expected the driver to get MTU 1400, got %d", config.MTU)
+	}
+	if mtu := n.mtu(&networkdriver.JoinInfo{MTU: 1450}); mtu != 1400 {
+		t.Fatalf("Expected the MTU of the network to take precedence, got %d", mtu)
+	}
+
+	n, err = s.Create("bar", "fake", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mtu := n.mtu(&networkdriver.JoinInfo{MTU: 1450}); mtu != 1450 {
+		t.Fatalf("Expected the MTU required by the driver, got %d", mtu)
+	}
+	if mtu := n.mtu(&networkdriver.JoinInfo{}); mtu != 1500 {
+		t.Fatalf("Expected the default MTU, got %d", mtu)
+	}
+}
+
+func TestStoreStaticAddresses(t *testing.T) {
+	s, root := newTestStore(t)
+	defer os.RemoveAll(root)
+
+	for _, config := range []*networkdriver.NetworkConfig{
+		{SubnetIPv6: "2001:db8::/64"},
+		{Subnet: "2001:db8::/64"},
+		{Subnet: "10.10.0.0/24", SubnetIPv6: "10.20.0.0/24"},
+	} {
+		if _, err := s.Create("bar", "fake", config); err == nil {
+			t.Fatalf("Expected an error creating a network with %+v", config)
+		}
+	}
+
+	n, err := s.Create("foo", "fake", &networkdriver.NetworkConfig{Subnet: "10.10.0.0/24", SubnetIPv6: "2001:db8::/64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.ValidateAddresses("10.10.0.5", "2001:db8::5"); err != nil {
+		t.Fatal(err)
+	}
+	for _, addrs := range [][2]string{
+		{"10.20.0.5", ""},
+		{"2001:db8::5", ""},
+		{"", "2001:db9::5"},
+		{"", "10.10.0.5"},
+		{"foo", ""},
+	} {
+		if err := n.ValidateAddresses(addrs[0], addrs[1]); err == nil {
+			t.Fatalf("Expected an error validating %v", addrs)
+		}
+	}
+
+	options := map[string]string{
+		networkdriver.IPv4AddressOption: "10.10.0.5",
+		networkdriver.IPv6AddressOption: "2001:db8::5",
+	}
+	if ip, err := n.requestIP(n.subnet, options, networkdriver.IPv4AddressOption); err != nil || ip.String() != "10.10.0.5" {
+		t.Fatalf("Expected address 10.10.0.5, got %s (%v)", ip, err)
+	}
+	if ip, err := n.requestIP(n.subnetIPv6, options, networkdriver.IPv6AddressOption); err != nil || ip.String() != "2001:db8::5" {
+		t.Fatalf("Expected address 2001:db8::5, got %s (%v)", ip, err)
+	}
+	if _, err := n.requestIP(n.subnet, options, networkdriver.IPv4AddressOption); err == nil {
+		t.Fatal("Expected an error requesting an address in use")
+	}
+	if _, err := n.requestIP(n.subnet, map[string]string{networkdriver.IPv4AddressOption: n.Gateway}, networkdriver.IPv4AddressOption); err == nil {
+		t.Fatal("Expected an error requesting the gateway address")
+	}
+	if ip, err := n.requestIP(n.subnet, nil, networkdriver.IPv4AddressOption); err != nil || ip.String() == "10.10.0.5" {
+		t.Fatalf("Expected a dynamic address other than 10.10.0.5, got %s (%v)", ip, err)
+	}
+	pools := n.Pools()
+	if len(pools) != 2 {
+		t.Fatalf("Expected 2 pools, got %+v", pools)
+	}
+	if p := pools[0]; p.Subnet != "10.10.0.0/24" || p.Gateway != n.Gateway || p.Allocated != 3 || p.Capacity != 254 {
+		t.Fatalf("Unexpected IPv4 pool %+v", p)
+	}
+	if p := pools[1]; p.Subnet != "2001:db8::/64" || p.Allocated != 1 || p.Capacity != 1<<64-2 {
+		t.Fatalf("Unexpected IPv6 pool %+v", p)
+	}
+
+	s, err = NewStore(root, nil, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err = s.Get("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if n.SubnetIPv6 != "2001:db8::/64" || n.subnetIPv6 == nil {
+		t.Fatalf("Expected the IPv6 subnet to be restored, got %+v", n)
+	}
+}
+
+func TestStoreAttachReleasesAddresses(t *testing.T) {
+	s, root := newTestStore(t)
+	defer os.RemoveAll(root)
+
+	n, err := s.Create("foo", "fakefailing", &networkdriver.NetworkConfig{Subnet: "10.10.0.0/24", SubnetIPv6: "2001:db8::/64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := map[string]string{
+		networkdriver.IPv4AddressOption: "10.10.0.5",
+		networkdriver.IPv6AddressOption: "2001:db8::5",
+	}
+	if _, err := n.Attach("c1", "c1", "", options); err == nil {
+		t.Fatal("Expected an error attaching through a failing driver")
+	}
+	if ip, err := n.requestIP(n.subnet, options, networkdriver.IPv4AddressOption); err != nil || ip.String() != "10.10.0.5" {
+		t.Fatalf("Expected the address 10.10.0.5 to be released, got %s (%v)", ip, err)
+	}
+	if ip, err := n.requestIP(n.subnetIPv6, options, networkdriver.IPv6AddressOption); err != nil || ip.String() != "2001:db8::5" {
+		t.Fatalf("Expected the address 2001:db8::5 to be released, got %s (%v)", ip, err)
+	}
+
+	n, err = s.Create("bar", "fake", &networkdriver.NetworkConfig{Subnet: "10.20.0.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options = map[string]string{
+		networkdriver.IPv4AddressOption: "10.20.0.5",
+		networkdriver.IPv6AddressOption: "2001:db8::5",
+	}
+	if _, err := n.Attach("c1", "c1", "", options); err == nil {
+		t.Fatal("Expected an error requesting an IPv6 address without an IPv6 subnet")
+	}
+	if ip, err := n.requestIP(n.subnet, options, networkdriver.IPv4AddressOption); err != nil || ip.String() != "10.20.0.5" {
+		t.Fatalf("Expected the address 10.20.0.5 to be released, got %s (%v)", ip, err)
+	}
+}
+
+func TestStoreGetAndRemove(t *testing.T) {
+	s, root := newTestStore(t)
+	defer os.RemoveAll(root)
+
+	n, err := s.Create("foo", "fake", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{"foo", n.ID, n.ID[:12]} {
+		found, err := s.Get(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != n {
+			t.Fatalf("Expected to find network %s by %s", n.ID, ref)
+		}
+	}
+	if _, err := s.Get("bar"); err == nil {
+		t.Fatal("Expected an error getting a non-existing network")
+	}
+
+	if err := s.Remove("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("foo"); err == nil {
+		t.Fatal("Expected the network to be removed")
+	}
+	if _, ok := testDriver.networks[n.ID]; ok {
+		t.Fatal("Expected the network to be deleted by the driver")
+	}
+
+	n, err = s.Create("foo", "fake", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.endpoints["ep"] = &Endpoint{ID: "ep"}
+	if err := s.Remove("foo"); err == nil {
+		t.Fatal("Expected an error removing a network with endpoints")
+	}
+}
+
+func TestStoreRestore(t *testing.T) {
+	s, root := newTestStore(t)
+	defer os.RemoveAll(root)
+
+	if _, err := s.Create("foo", "fake", &networkdriver.NetworkConfig{Subnet: "10.10.0.0/24", Options: map[string]string{"a": "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create("bar", "fake", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewStore(root, nil, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	networks := s.List()
+	if len(networks) != 2 {
+		t.Fatalf("Expected 2 networks, got %d", len(networks))
+	}
+	if networks[0].Name != "bar" || networks[1].Name != "foo" {
+		t.Fatalf("Expected networks sorted by name, got %s, %s", networks[0].Name, networks[1].Name)
+	}
+	foo := networks[1]
+	if foo.Subnet != "10.10.0.0/24" || foo.Gateway != "10.10.0.1" || foo.Options["a"] != "b" {
+		t.Fatalf("Unexpected restored network %+v", foo)
+	}
+	if foo.ipAllocator == nil {
+		t.Fatal("Expected address management to be restored")
+	}
+}
+
+func TestStoreGlobalNetworks(t *testing.T) {
+	kv := kvstore.NewMemoryStore()
+	s1, root1 := newTestStore(t)
+	defer os.RemoveAll(root1)
+	s1.kv = kv
+	s2, root2 := newTestStore(t)
+	defer os.RemoveAll(root2)
+	s2.kv = kv
+
+	local, root := newTestStore(t)
+	defer os.RemoveAll(root)
+	if _, err := local.Create("foo", "fakeglobal", nil); err == nil {
+		t.Fatal("Expected an error creating a global network without a key-value store")
+	}
+
+	n, err := s1.Create("foo", "fakeglobal", &networkdriver.NetworkConfig{Subnet: "10.20.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Scope() != networkdriver.GlobalScope || n.ipAllocator != nil {
+		t.Fatal("Expected a global network with addresses managed by its driver")
+	}
+	if _, err := s1.Create("foo", "fake", nil); err == nil {
+		t.Fatal("Expected an error creating a network with a duplicate name")
+	}
+
+	if err := s2.sync(); err != nil {
+		t.Fatal(err)
+	}
+	shared, err := s2.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared.ID != n.ID || shared.Subnet != "10.20.0.0/16" || shared.Scope() != networkdriver.GlobalScope {
+		t.Fatalf("Unexpected shared network %+v", shared)
+	}
+	if _, err := s2.Create("foo", "fakeglobal", nil); err == nil {
+		t.Fatal("Expected an error creating a network with a duplicate name on another host")
+	}
+
+	ep := &Endpoint{ID: "ep", NetworkID: n.ID, Name: "web"}
+	ep.Interface.Address = "10.20.0.2/16"
+	if err := n.Restore(ep); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan *Network, 1)
+	s2.OnEndpointsChange(func(n *Network) { changed <- n })
+	if err := s2.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if (<-changed).ID != n.ID {
+		t.Fatal("Expected to be notified of the new endpoint")
+	}
+	eps := shared.Endpoints()
+	if len(eps) != 1 || eps[0].Name != "web" || eps[0].Interface.Address != "10.20.0.2/16" {
+		t.Fatalf("Unexpected endpoints %v", eps)
+	}
+	if err := s2.Remove("foo"); err == nil {
+		t.Fatal("Expected an error removing a network with endpoints on another host")
+	}
+
+	if err := n.Detach(ep, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.Remove("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s1.Get("foo"); err == nil {
+		t.Fatal("Expected the network to be removed from all hosts")
+	}
+}
@@ -17,11 +17,14 @@ import (
 	"github.com/docker/docker/daemon/network"
 	"github.com/docker/docker/daemon/networkdriver"
 	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+	"github.com/docker/docker/daemon/networkdriver/portallocator"
 	"github.com/docker/docker/daemon/networkdriver/portmapper"
 	"github.com/docker/docker/nat"
 	"github.com/docker/docker/pkg/iptables"
+	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/parsers/kernel"
 	"github.com/docker/docker/pkg/resolvconf"
+	"github.com/docker/docker/pkg/rootless"
 	"github.com/docker/libcontainer/netlink"
 )
 
@@ -84,7 +87,9 @@ var (
 	gatewayIPv6       net.IP
 	portMapper        *portmapper.PortMapper
 	once              sync.Once
+	localRouting      sync.Once
 	hairpinMode       bool
+	natReflection     bool
 
 	defaultBindingIP  = net.ParseIP("0.0.0.0")
 	currentInterfaces = ifaces{c: make(map[string]*networkInterface)}
@@ -111,6 +116,13 @@ type Config struct {
 	DefaultGatewayIPv4          string
 	DefaultGatewayIPv6          string
 	InterContainerCommunication bool
+	// PortRange is the range host ports are picked from when publishing
+	// ports without a host port, e.g. 30000-40000. The ephemeral port range
+	// of the kernel is used when empty.
+	PortRange string
+	// EnableNATReflection lets containers reach the ports published by
+	// other containers through the addresses of the host.
+	EnableNATReflection bool
 }
 
 func InitDriver(config *Config) error {
@@ -130,11 +142,30 @@ func InitDriver(config *Config) error {
 
 	initPortMapper()
 
+	// a daemon running rootless has its own network namespace, the ports
+	// of the host are forwarded to it
+	if f := rootless.NewPortForwarder(); f != nil {
+		portMapper.SetHostForwarder(f)
+	}
+
+	// Never hand out host ports bound by other processes.
+	portMapper.Allocator.Probe = portallocator.ProbePort
+	if config.PortRange != "" {
+		begin, end, err := parsers.ParsePortRange(config.PortRange)
+		if err != nil {
+			return fmt.Errorf("Invalid port range %s: %v", config.PortRange, err)
+		}
+		if err := portMapper.Allocator.SetRange(int(begin), int(end)); err != nil {
+			return fmt.Errorf("Invalid port range %s: %v", config.PortRange, err)
+		}
+	}
+
 	if config.DefaultIp != nil {
 		defaultBindingIP = config.DefaultIp
 	}
 
 	hairpinMode = !config.EnableUserlandProxy
+	natReflection = config.EnableNATReflection
 
 	bridgeIface = config.Iface
 	usingDefaultBridge := false
@@ -239,12 +270,14 @@ func InitDriver(config *Config) error {
 
 	// Configure iptables for link support
 	if config.EnableIptables {
-		if err := setupIPTables(addrv4, config.InterContainerCommunication, config.EnableIpMasq); err != nil {
+		if err := setupIPTables(addrv4, config.InterContainerCommunication, config.EnableIpMasq, config.EnableNATReflection); err != nil {
 			logrus.Errorf("Error configuring iptables: %s", err)
 			return err
 		}
 		// call this on Firewalld reload
-		iptables.OnReloaded(func() { setupIPTables(addrv4, config.InterContainerCommunication, config.EnableIpMasq) })
+		iptables.OnReloaded(func() {
+			setupIPTables(addrv4, config.InterContainerCommunication, config.EnableIpMasq, config.EnableNATReflection)
+		})
 	}
 
 	if config.EnableIpForward {
@@ -265,11 +298,7 @@ func InitDriver(config *Config) error {
 	}
 
 	if hairpinMode {
-		// Enable loopback adresses routing
-		sysPath := filepath.Join("/proc/sys/net/ipv4/conf", bridgeIface, "route_localnet")
-		if err := ioutil.WriteFile(sysPath, []byte{'1', '\n'}, 0644); err != nil {
-			logrus.Warnf("Unable to enable local routing for hairpin mode: %v", err)
-		}
+		enableLocalRouting()
 	}
 
 	// We can always try removing the iptables
@@ -342,7 +371,7 @@ func InitDriver(config *Config) error {
 	return nil
 }
 
-func setupIPTables(addr net.Addr, icc, ipmasq bool) error {
+func setupIPTables(addr net.Addr, icc, ipmasq, reflection bool) error {
 	// Enable NAT
 
 	if ipmasq {
@@ -410,6 +439,10 @@ func setupIPTables(addr net.Addr, icc, ipmasq bool) error {
 		}
 	}
 
+	if err := setupNATReflection(addr, reflection); err != nil {
+		return err
+	}
+
 	// Accept incoming packets for existing connections
 	existingArgs := []string{"-o", bridgeIface, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"}
 
@@ -423,6 +456,40 @@ func setupIPTables(addr net.Addr, icc, ipmasq bool) error {
 	return nil
 }
 
+// setupNATReflection adds or removes the rules letting containers reach the
+// ports published by other containers through the addresses of the host.
+// Such connections are destination NATed back to the bridge: they are
+// accepted even without inter-container communication, and masqueraded so
+// that the replies go back through the host.
+func setupNATReflection(addr net.Addr, enable bool) error {
+	var (
+		natArgs    = []string{"-s", addr.String(), "-o", bridgeIface, "-m", "conntrack", "--ctstate", "DNAT", "-j", "MASQUERADE"}
+		acceptArgs = []string{"-i", bridgeIface, "-o", bridgeIface, "-m", "conntrack", "--ctstate", "DNAT", "-j", "ACCEPT"}
+	)
+
+	if !enable {
+		iptables.Raw(append([]string{"-t", string(iptables.Nat), "-D", "POSTROUTING"}, natArgs...)...)
+		iptables.Raw(append([]string{"-D", "FORWARD"}, acceptArgs...)...)
+		return nil
+	}
+
+	if !iptables.Exists(iptables.Nat, "POSTROUTING", natArgs...) {
+		if output, err := iptables.Raw(append([]string{"-t", string(iptables.Nat), "-I", "POSTROUTING"}, natArgs...)...); err != nil {
+			return fmt.Errorf("Unable to enable NAT reflection: %s", err)
+		} else if len(output) != 0 {
+			return iptables.ChainError{Chain: "POSTROUTING", Output: output}
+		}
+	}
+	if !iptables.Exists(iptables.Filter, "FORWARD", acceptArgs...) {
+		if output, err := iptables.Raw(append([]string{"-I", "FORWARD"}, acceptArgs...)...); err != nil {
+			return fmt.Errorf("Unable to enable NAT reflection: %s", err)
+		} else if len(output) != 0 {
+			return iptables.ChainError{Chain: "FORWARD reflection", Output: output}
+		}
+	}
+	return nil
+}
+
 func RequestPort(ip net.IP, proto string, port int) (int, error) {
 	initPortMapper()
 	return portMapper.Allocator.RequestPort(ip, proto, port)
@@ -557,31 +624,6 @@ func createBridgeIface(name string) error {
 	return netlink.CreateBridge(name, setBridgeMacAddr)
 }
 
-// Generate a IEEE802 compliant MAC address from the given IP address.
-//
-// The generator is guaranteed to be consistent: the same IP will always yield the same
-// MAC address. This is to avoid ARP cache issues.
-func generateMacAddr(ip net.IP) net.HardwareAddr {
-	hw := make(net.HardwareAddr, 6)
-
-	// The first byte of the MAC address has to comply with these rules:
-	// 1. Unicast: Set the least-significant bit to 0.
-	// 2. Address is locally administered: Set the second-least-significant bit (U/L) to 1.
-	// 3. As "small" as possible: The veth address has to be "smaller" than the bridge address.
-	hw[0] = 0x02
-
-	// The first 24 bits of the MAC represent the Organizationally Unique Identifier (OUI).
-	// Since this address is locally administered, we can do whatever we want as long as
-	// it doesn't conflict with other addresses.
-	hw[1] = 0x42
-
-	// Insert the IP address into the last 32 bits of the MAC address.
-	// This is a simple way to guarantee the address will be consistent and unique.
-	copy(hw[2:], ip.To4())
-
-	return hw
-}
-
 func linkLocalIPv6FromMac(mac string) (string, error) {
 	hx := strings.Replace(mac, ":", "", -1)
 	hw, err := hex.DecodeString(hx)
@@ -612,7 +654,7 @@ func Allocate(id, requestedMac, requestedIP, requestedIPv6 string) (*network.Set
 
 	// If no explicit mac address was given, generate one from the IP address.
 	if mac, err = net.ParseMAC(requestedMac); err != nil {
-		mac = generateMacAddr(ip)
+		mac = networkdriver.GenerateMacAddr(ip)
 	}
 
 	if globalIPv6Network != nil {
@@ -663,7 +705,7 @@ func Allocate(id, requestedMac, requestedIP, requestedIPv6 string) (*network.Set
 		Bridge:               bridgeIface,
 		IPPrefixLen:          maskSize,
 		LinkLocalIPv6Address: localIPv6.String(),
-		HairpinMode:          hairpinMode,
+		HairpinMode:          hairpinMode || natReflection,
 	}
 
 	if globalIPv6Network != nil {
@@ -706,20 +748,94 @@ func Release(id string) {
 	}
 }
 
+// enableLocalRouting enables the routing of the loopback addresses to the
+// bridge, needed to reach the ports published without the userland proxy
+// through them.
+func enableLocalRouting() {
+	localRouting.Do(func() {
+		sysPath := filepath.Join("/proc/sys/net/ipv4/conf", bridgeIface, "route_localnet")
+		if err := ioutil.WriteFile(sysPath, []byte{'1', '\n'}, 0644); err != nil {
+			logrus.Warnf("Unable to enable local routing: %v", err)
+		}
+	})
+}
+
+// useProxy reports whether the proto port published by binding is forwarded
+// by a userland proxy, or by iptables rules only. There is no userland proxy
+// for SCTP.
+func useProxy(proto string, binding nat.PortBinding) bool {
+	return !hairpinMode && !binding.NoProxy && proto != "sctp"
+}
+
+// hostBindingIP returns the host ip a port binding is published on, falling
+// back to the daemon's default binding ip.
+func hostBindingIP(proto string, binding nat.PortBinding) (net.IP, error) {
+	if binding.HostIp == "" {
+		return defaultBindingIP, nil
+	}
+	ip := net.ParseIP(binding.HostIp)
+	if ip == nil {
+		return nil, fmt.Errorf("Bad parameter: invalid host ip %s", binding.HostIp)
+	}
+	// Without the userland proxy nothing would forward IPv6 connections
+	// since iptables rules are only programmed for IPv4.
+	if ip.To4() == nil && !useProxy(proto, binding) {
+		return nil, fmt.Errorf("Bad parameter: publishing on IPv6 host ip %s requires the userland proxy", binding.HostIp)
+	}
+	return ip, nil
+}
+
+// PortStatus returns the state of the forwarding of the host port published
+// by binding for a proto port of a container.
+func PortStatus(proto string, binding nat.PortBinding) (network.PortStatus, error) {
+	status := network.PortStatus{HostIp: binding.HostIp, HostPort: binding.HostPort}
+	if portMapper == nil {
+		return status, fmt.Errorf("port mapping is not initialized")
+	}
+	ip := net.ParseIP(binding.HostIp)
+	port, err := strconv.Atoi(binding.HostPort)
+	if ip == nil || err != nil {
+		return status, fmt.Errorf("invalid port binding %s:%s", binding.HostIp, binding.HostPort)
+	}
+
+	var host net.Addr
+	switch proto {
+	case "tcp":
+		host = &net.TCPAddr{IP: ip, Port: port}
+	case "udp":
+		host = &net.UDPAddr{IP: ip, Port: port}
+	case "sctp":
+		host = &portmapper.SCTPAddr{IP: ip, Port: port}
+	default:
+		return status, fmt.Errorf("unsupported address type %s", proto)
+	}
+	s, err := portMapper.Status(host)
+	if err != nil {
+		return status, err
+	}
+
+	status.Iptables = s.Iptables
+	if s.Proxy {
+		status.Proxy = "exited"
+		if s.ProxyRunning {
+			status.Proxy = "running"
+		}
+	}
+	status.Reflection = natReflection && s.Iptables && !ip.IsLoopback()
+	return status, nil
+}
+
 // Allocate an external port and map it to the interface
 func AllocatePort(id string, port nat.Port, binding nat.PortBinding) (nat.PortBinding, error) {
 	var (
-		ip            = defaultBindingIP
 		proto         = port.Proto()
 		containerPort = port.Int()
 		network       = currentInterfaces.Get(id)
 	)
 
-	if binding.HostIp != "" {
-		ip = net.ParseIP(binding.HostIp)
-		if ip == nil {
-			return nat.PortBinding{}, fmt.Errorf("Bad parameter: invalid host ip %s", binding.HostIp)
-		}
+	ip, err := hostBindingIP(proto, binding)
+	if err != nil {
+		return nat.PortBinding{}, err
 	}
 
 	// host ip, proto, and host port
@@ -729,6 +845,8 @@ func AllocatePort(id string, port nat.Port, binding nat.PortBinding) (nat.PortBi
 		container = &net.TCPAddr{IP: network.IP, Port: containerPort}
 	case "udp":
 		container = &net.UDPAddr{IP: network.IP, Port: containerPort}
+	case "sctp":
+		container = &portmapper.SCTPAddr{IP: network.IP, Port: containerPort}
 	default:
 		return nat.PortBinding{}, fmt.Errorf("unsupported address type %s", proto)
 	}
@@ -740,16 +858,16 @@ func AllocatePort(id string, port nat.Port, binding nat.PortBinding) (nat.PortBi
 	// yields.
 	//
 
-	var (
-		host net.Addr
-		err  error
-	)
+	var host net.Addr
 	hostPort, err := nat.ParsePort(binding.HostPort)
 	if err != nil {
 		return nat.PortBinding{}, err
 	}
+	if !useProxy(proto, binding) {
+		enableLocalRouting()
+	}
 	for i := 0; i < MaxAllocatedPortAttempts; i++ {
-		if host, err = portMapper.Map(container, ip, hostPort, !hairpinMode); err == nil {
+		if host, err = portMapper.Map(container, ip, hostPort, useProxy(proto, binding)); err == nil {
 			break
 		}
 		// There is no point in immediately retrying to map an explicitly
@@ -769,14 +887,75 @@ func AllocatePort(id string, port nat.Port, binding nat.PortBinding) (nat.PortBi
 
 	switch netAddr := host.(type) {
 	case *net.TCPAddr:
-		return nat.PortBinding{HostIp: netAddr.IP.String(), HostPort: strconv.Itoa(netAddr.Port)}, nil
+		return nat.PortBinding{HostIp: netAddr.IP.String(), HostPort: strconv.Itoa(netAddr.Port), NoProxy: binding.NoProxy}, nil
 	case *net.UDPAddr:
-		return nat.PortBinding{HostIp: netAddr.IP.String(), HostPort: strconv.Itoa(netAddr.Port)}, nil
+		return nat.PortBinding{HostIp: netAddr.IP.String(), HostPort: strconv.Itoa(netAddr.Port), NoProxy: binding.NoProxy}, nil
+	case *portmapper.SCTPAddr:
+		return nat.PortBinding{HostIp: netAddr.IP.String(), HostPort: strconv.Itoa(netAddr.Port), NoProxy: binding.NoProxy}, nil
 	default:
 		return nat.PortBinding{}, fmt.Errorf("unsupported address type %T", netAddr)
 	}
 }
 
+// Allocate a contiguous block of external ports and map it to a list of
+// consecutive container ports sharing the same protocol. If binding has no
+// host port the block is taken from the dynamic port range.
+func AllocatePortRange(id string, ports []nat.Port, binding nat.PortBinding) ([]nat.PortBinding, error) {
+	if len(ports) == 0 {
+		return nil, nil
+	}
+	var (
+		proto   = ports[0].Proto()
+		network = currentInterfaces.Get(id)
+	)
+
+	ip, err := hostBindingIP(proto, binding)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]net.Addr, len(ports))
+	for i, port := range ports {
+		switch proto := port.Proto(); proto {
+		case "tcp":
+			containers[i] = &net.TCPAddr{IP: network.IP, Port: port.Int()}
+		case "udp":
+			containers[i] = &net.UDPAddr{IP: network.IP, Port: port.Int()}
+		case "sctp":
+			containers[i] = &portmapper.SCTPAddr{IP: network.IP, Port: port.Int()}
+		default:
+			return nil, fmt.Errorf("unsupported address type %s", proto)
+		}
+	}
+
+	hostPort, err := nat.ParsePort(binding.HostPort)
+	if err != nil {
+		return nil, err
+	}
+	if !useProxy(proto, binding) {
+		enableLocalRouting()
+	}
+	hosts, err := portMapper.MapRange(containers, ip, hostPort, useProxy(proto, binding))
+	if err != nil {
+		return nil, err
+	}
+
+	network.PortMappings = append(network.PortMappings, hosts...)
+
+	bindings := make([]nat.PortBinding, len(hosts))
+	for i, host := range hosts {
+		switch netAddr := host.(type) {
+		case *net.TCPAddr:
+			bindings[i] = nat.PortBinding{HostIp: netAddr.IP.String(), HostPort: strconv.Itoa(netAddr.Port), NoProxy: binding.NoProxy}
+		case *net.UDPAddr:
+			bindings[i] = nat.PortBinding{HostIp: netAddr.IP.String(), HostPort: strconv.Itoa(netAddr.Port), NoProxy: binding.NoProxy}
+		case *portmapper.SCTPAddr:
+			bindings[i] = nat.PortBinding{HostIp: netAddr.IP.String(), HostPort: strconv.Itoa(netAddr.Port), NoProxy: binding.NoProxy}
+		}
+	}
+	return bindings, nil
+}
+
 //TODO: should it return something more than just an error?
 func LinkContainers(action, parentIP, childIP string, ports []nat.Port, ignoreErrors bool) error {
 	var nfAction iptables.Action
@@ -75,6 +75,54 @@ func TestHostnameFormatChecking(t *testing.T) {
 	}
 }
 
+func TestHostBindingIP(t *testing.T) {
+	defer func(mode bool) { hairpinMode = mode }(hairpinMode)
+	hairpinMode = false
+
+	if ip, err := hostBindingIP("tcp", nat.PortBinding{}); err != nil || !ip.Equal(defaultBindingIP) {
+		t.Fatalf("Expected default binding ip %s, got %s (%v)", defaultBindingIP, ip, err)
+	}
+	if ip, err := hostBindingIP("tcp", nat.PortBinding{HostIp: "192.168.1.10"}); err != nil || ip.String() != "192.168.1.10" {
+		t.Fatalf("Expected host ip 192.168.1.10, got %s (%v)", ip, err)
+	}
+	if ip, err := hostBindingIP("tcp", nat.PortBinding{HostIp: "::1"}); err != nil || ip.String() != "::1" {
+		t.Fatalf("Expected host ip ::1, got %s (%v)", ip, err)
+	}
+	if _, err := hostBindingIP("tcp", nat.PortBinding{HostIp: "::1", NoProxy: true}); err == nil {
+		t.Fatal("Publishing an IPv6 host ip port without proxy should fail")
+	}
+
+	hairpinMode = true
+	if _, err := hostBindingIP("tcp", nat.PortBinding{HostIp: "::1"}); err == nil {
+		t.Fatal("Publishing on an IPv6 host ip without the userland proxy should fail")
+	}
+
+	hairpinMode = false
+	if _, err := hostBindingIP("sctp", nat.PortBinding{HostIp: "::1"}); err == nil {
+		t.Fatal("Publishing an IPv6 host ip sctp port should fail")
+	}
+}
+
+func TestUseProxy(t *testing.T) {
+	defer func(mode bool) { hairpinMode = mode }(hairpinMode)
+
+	hairpinMode = false
+	if !useProxy("tcp", nat.PortBinding{}) {
+		t.Fatal("Expected ports to be proxied by default")
+	}
+	if useProxy("tcp", nat.PortBinding{NoProxy: true}) {
+		t.Fatal("Expected a port published with noproxy not to be proxied")
+	}
+	if useProxy("sctp", nat.PortBinding{}) {
+		t.Fatal("Expected sctp ports not to be proxied")
+	}
+
+	hairpinMode = true
+	if useProxy("tcp", nat.PortBinding{}) {
+		t.Fatal("Expected no port to be proxied in hairpin mode")
+	}
+}
+
 func newInterfaceAllocation(t *testing.T, globalIPv6 *net.IPNet, requestedMac, requestedIP, requestedIPv6 string, expectFail bool) *network.Settings {
 	// set IPv6 global if given
 	if globalIPv6 != nil {
@@ -149,22 +197,6 @@ func TestIPv6InterfaceAllocationRequest(t *testing.T) {
 	_ = newInterfaceAllocation(t, subnet, "", "", expectedIP, true)
 }
 
-func TestMacAddrGeneration(t *testing.T) {
-	ip := net.ParseIP("192.168.0.1")
-	mac := generateMacAddr(ip).String()
-
-	// Should be consistent.
-	if generateMacAddr(ip).String() != mac {
-		t.Fatal("Inconsistent MAC address")
-	}
-
-	// Should be unique.
-	ip2 := net.ParseIP("192.168.0.2")
-	if generateMacAddr(ip2).String() == mac {
-		t.Fatal("Non-unique MAC address")
-	}
-}
-
 func TestLinkContainers(t *testing.T) {
 	// Init driver
 	if err := InitDriver(new(Config)); err != nil {
@@ -0,0 +1,144 @@
+package networkdriver
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// MacAddressOption is the endpoint option holding the MAC address
+	// requested for the endpoint.
+	MacAddressOption = "com.docker.network.endpoint.macaddress"
+	// IPv4AddressOption is the endpoint option holding the IPv4 address
+	// requested for the endpoint.
+	IPv4AddressOption = "com.docker.network.endpoint.ipv4address"
+	// IPv6AddressOption is the endpoint option holding the IPv6 address
+	// requested for the endpoint.
+	IPv6AddressOption = "com.docker.network.endpoint.ipv6address"
+)
+
+// Driver is implemented by the providers of user-defined networks. Every
+// container attached to a network gets an endpoint on it, which is joined to
+// the container's network namespace (its sandbox) when the container starts.
+type Driver interface {
+	// CreateNetwork sets up the network nid.
+	CreateNetwork(nid string, config *NetworkConfig) error
+	// DeleteNetwork tears down the network nid.
+	DeleteNetwork(nid string) error
+	// CreateEndpoint creates the endpoint eid on network nid. iface holds the
+	// addresses the daemon allocated for the endpoint, if any. When iface is
+	// empty, address management is delegated to the driver which must return
+	// the interface it allocated. Otherwise the returned interface is nil and
+	// drivers whose interfaces cannot have a MAC address of their own clear
+	// iface.MacAddress.
+	CreateEndpoint(nid, eid string, iface *EndpointInterface, options map[string]string) (*EndpointInterface, error)
+	// DeleteEndpoint removes the endpoint eid from network nid.
+	DeleteEndpoint(nid, eid string) error
+	// Join makes the endpoint eid available to the sandbox found at
+	// sandboxKey, the path of a network namespace.
+	Join(nid, eid, sandboxKey string, options map[string]string) (*JoinInfo, error)
+	// Leave detaches the endpoint eid from its sandbox.
+	Leave(nid, eid string) error
+	// Type returns the name the driver is registered with.
+	Type() string
+}
+
+const (
+	// LocalScope is the scope of the drivers whose networks are local to
+	// the host.
+	LocalScope = "local"
+	// GlobalScope is the scope of the drivers whose networks span several
+	// hosts. These networks are shared between the daemons through their
+	// key-value store and the driver allocates the addresses of their
+	// endpoints, since the allocation must be coordinated across hosts.
+	GlobalScope = "global"
+)
+
+// ScopedDriver is implemented by drivers which are not of local scope.
+type ScopedDriver interface {
+	Driver
+	// Scope returns LocalScope or GlobalScope.
+	Scope() string
+}
+
+// Scope returns the scope of the driver d.
+func Scope(d Driver) string {
+	if sd, ok := d.(ScopedDriver); ok {
+		return sd.Scope()
+	}
+	return LocalScope
+}
+
+// NetworkConfig holds the configuration of a network as passed to its driver.
+type NetworkConfig struct {
+	// Subnet is the IPv4 subnet in CIDR notation the daemon allocates
+	// endpoint addresses from. It is empty when the driver manages
+	// addressing itself.
+	Subnet string
+	// Gateway is the IPv4 gateway of Subnet.
+	Gateway string
+	// SubnetIPv6 is the IPv6 subnet in CIDR notation the daemon allocates
+	// endpoint IPv6 addresses from, if any.
+	SubnetIPv6 string
+	// MTU is the MTU of the interfaces of the network. Zero leaves it to
+	// the driver, or to the daemon default.
+	MTU int
+	// Options are the driver specific options given at network creation.
+	Options map[string]string
+}
+
+// EndpointInterface holds the addresses of an endpoint.
+type EndpointInterface struct {
+	// Address is the IPv4 address in CIDR notation.
+	Address string
+	// AddressIPv6 is the IPv6 address in CIDR notation.
+	AddressIPv6 string
+	MacAddress  string
+}
+
+// JoinInfo describes the interface a driver prepared for a sandbox.
+type JoinInfo struct {
+	// SrcName is the name of the host interface to move into the sandbox.
+	SrcName string
+	// DstPrefix is the prefix of the interface name inside the sandbox,
+	// a numeric suffix is appended to it, e.g. "eth" gives "eth0".
+	DstPrefix   string
+	Gateway     string
+	GatewayIPv6 string
+	// MTU is the MTU the driver requires for the interface, when the
+	// network does not set one, e.g. to leave room for encapsulation.
+	MTU int
+	// DeviceRoute routes the traffic of the address families without a
+	// gateway straight out of the interface, when the host routes the
+	// traffic of the endpoint itself.
+	DeviceRoute bool
+}
+
+var drivers = struct {
+	sync.Mutex
+	m map[string]Driver
+}{m: make(map[string]Driver)}
+
+// RegisterDriver makes a network driver available under name.
+func RegisterDriver(name string, d Driver) error {
+	drivers.Lock()
+	defer drivers.Unlock()
+
+	if _, ok := drivers.m[name]; ok {
+		return fmt.Errorf("networkdriver: driver named '%s' is already registered", name)
+	}
+	drivers.m[name] = d
+	return nil
+}
+
+// GetDriver returns the network driver registered under name.
+func GetDriver(name string) (Driver, error) {
+	drivers.Lock()
+	defer drivers.Unlock()
+
+	d, ok := drivers.m[name]
+	if !ok {
+		return nil, fmt.Errorf("networkdriver: no driver named '%s' is registered", name)
+	}
+	return d, nil
+}
@@ -2,6 +2,7 @@ package ipallocator
 
 import (
 	"errors"
+	"math"
 	"math/big"
 	"net"
 	"sync"
@@ -110,6 +111,28 @@ func (a *IPAllocator) ReleaseIP(network *net.IPNet, ip net.IP) error {
 	return nil
 }
 
+// Usage returns the number of addresses allocated from network and the
+// number of addresses it can hand out in total, which saturates at
+// math.MaxUint64 for the largest IPv6 networks.
+func (a *IPAllocator) Usage(network *net.IPNet) (int, uint64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	allocated, ok := a.allocatedIPs[network.String()]
+	if !ok {
+		allocated = newAllocatedMap(network)
+	}
+	size := big.NewInt(0).Sub(allocated.end, allocated.begin)
+	size.Add(size, big.NewInt(1))
+	if size.Sign() < 0 {
+		return len(allocated.p), 0
+	}
+	if size.BitLen() > 64 {
+		return len(allocated.p), math.MaxUint64
+	}
+	return len(allocated.p), size.Uint64()
+}
+
 func (allocated *allocatedMap) checkIP(ip net.IP) (net.IP, error) {
 	if _, ok := allocated.p[ip.String()]; ok {
 		return nil, ErrIPAlreadyAllocated
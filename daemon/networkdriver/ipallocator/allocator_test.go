@@ -2,6 +2,7 @@ package ipallocator
 
 import (
 	"fmt"
+	"math"
 	"math/big"
 	"net"
 	"testing"
@@ -664,6 +665,32 @@ func TestAllocateFromRange(t *testing.T) {
 	}
 }
 
+func TestUsage(t *testing.T) {
+	a := New()
+	network := &net.IPNet{
+		IP:   []byte{192, 168, 0, 1},
+		Mask: []byte{255, 255, 255, 0},
+	}
+
+	if allocated, capacity := a.Usage(network); allocated != 0 || capacity != 254 {
+		t.Fatalf("Expected 0 of 254 addresses allocated, got %d of %d", allocated, capacity)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := a.RequestIP(network, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.ReleaseIP(network, net.IPv4(192, 168, 0, 2))
+	if allocated, capacity := a.Usage(network); allocated != 2 || capacity != 254 {
+		t.Fatalf("Expected 2 of 254 addresses allocated, got %d of %d", allocated, capacity)
+	}
+
+	_, network6, _ := net.ParseCIDR("2001:db8::/48")
+	if _, capacity := a.Usage(network6); capacity != math.MaxUint64 {
+		t.Fatalf("Expected the capacity of %s to saturate, got %d", network6, capacity)
+	}
+}
+
 func assertIPEquals(t *testing.T, ip1, ip2 net.IP) {
 	if !ip1.Equal(ip2) {
 		t.Fatalf("Expected IP %s, got %s", ip1, ip2)
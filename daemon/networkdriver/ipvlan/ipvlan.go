@@ -0,0 +1,206 @@
+// Package ipvlan implements a network driver attaching containers directly to
+// a network of the host through ipvlan devices on one of its interfaces, the
+// parent. Unlike macvlan devices, ipvlan devices share the MAC address of
+// their parent, so they work on networks which only accept the MAC addresses
+// they know of the host, such as the ones of most cloud providers.
+//
+// In l2 mode the endpoints are part of the network of the parent and reach
+// the outside through the gateway of the network. In l3 mode the parent
+// routes the traffic of the endpoints: the endpoints have no gateway and the
+// rest of the network must route the subnet of the network to the host.
+package ipvlan
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/networkdriver"
+)
+
+const (
+	// DriverName is the name the ipvlan driver is registered with.
+	DriverName = "ipvlan"
+	// ParentOption is the network option naming the host interface the
+	// endpoints are attached to. By default it is the interface of the
+	// default route.
+	ParentOption = "com.docker.network.ipvlan.parent"
+	// ModeOption is the network option setting the ipvlan mode of the
+	// network, ModeL2 or ModeL3. The default is ModeL2.
+	ModeOption = "com.docker.network.ipvlan.mode"
+
+	// ModeL2 attaches the endpoints to the layer 2 network of the parent.
+	ModeL2 = "l2"
+	// ModeL3 routes the traffic of the endpoints through the parent.
+	ModeL3 = "l3"
+)
+
+// linker sets up the network devices of the driver on the host.
+type linker interface {
+	// parentMTU returns the MTU of the interface parent, failing when it
+	// does not exist.
+	parentMTU(parent string) (int, error)
+	createLink(name, parent, mode string) error
+	deleteLink(name string) error
+}
+
+type network struct {
+	id      string
+	parent  string
+	mode    string
+	gateway string
+	mtu     int
+}
+
+type driver struct {
+	links    linker
+	networks map[string]*network
+	sync.Mutex
+}
+
+// Init registers the ipvlan driver.
+func Init() error {
+	return networkdriver.RegisterDriver(DriverName, newDriver(newLinker()))
+}
+
+func newDriver(links linker) *driver {
+	return &driver{
+		links:    links,
+		networks: make(map[string]*network),
+	}
+}
+
+func (d *driver) network(nid string) (*network, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	n, ok := d.networks[nid]
+	if !ok {
+		return nil, fmt.Errorf("ipvlan: no such network %s", nid)
+	}
+	return n, nil
+}
+
+// parent returns the parent interface set in options, or the interface of
+// the default route.
+func parent(options map[string]string) (string, error) {
+	if p := options[ParentOption]; p != "" {
+		return p, nil
+	}
+	iface, err := networkdriver.GetDefaultRouteIface()
+	if err != nil {
+		return "", fmt.Errorf("ipvlan: unable to find the parent interface, set the %s option: %v", ParentOption, err)
+	}
+	return iface.Name, nil
+}
+
+func (d *driver) CreateNetwork(nid string, config *networkdriver.NetworkConfig) error {
+	if config.Subnet == "" {
+		return fmt.Errorf("ipvlan: networks require a subnet")
+	}
+	mode := config.Options[ModeOption]
+	switch mode {
+	case "":
+		mode = ModeL2
+	case ModeL2, ModeL3:
+	default:
+		return fmt.Errorf("ipvlan: invalid mode %s, it must be %s or %s", mode, ModeL2, ModeL3)
+	}
+	p, err := parent(config.Options)
+	if err != nil {
+		return err
+	}
+	mtu, err := d.links.parentMTU(p)
+	if err != nil {
+		return fmt.Errorf("ipvlan: invalid parent interface %s: %v", p, err)
+	}
+	if config.MTU > mtu {
+		return fmt.Errorf("ipvlan: MTU %d exceeds the MTU %d of the parent interface %s", config.MTU, mtu, p)
+	}
+
+	n := &network{
+		id:     nid,
+		parent: p,
+		mode:   mode,
+		mtu:    mtu,
+	}
+	if mode == ModeL2 {
+		n.gateway = config.Gateway
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	for _, other := range d.networks {
+		if other.parent == p && other.mode != mode {
+			return fmt.Errorf("ipvlan: parent interface %s is already used in %s mode by network %s", p, other.mode, other.id)
+		}
+	}
+	d.networks[nid] = n
+	return nil
+}
+
+func (d *driver) DeleteNetwork(nid string) error {
+	d.Lock()
+	delete(d.networks, nid)
+	d.Unlock()
+	return nil
+}
+
+func (d *driver) CreateEndpoint(nid, eid string, iface *networkdriver.EndpointInterface, options map[string]string) (*networkdriver.EndpointInterface, error) {
+	if iface == nil {
+		return nil, fmt.Errorf("ipvlan: the daemon must allocate the addresses of the endpoints")
+	}
+	if _, err := d.network(nid); err != nil {
+		return nil, err
+	}
+	if mac := options[networkdriver.MacAddressOption]; mac != "" {
+		return nil, fmt.Errorf("ipvlan: cannot set MAC address %s, endpoints share the MAC address of the parent interface", mac)
+	}
+	iface.MacAddress = ""
+	return nil, nil
+}
+
+func (d *driver) DeleteEndpoint(nid, eid string) error {
+	return nil
+}
+
+func (d *driver) Join(nid, eid, sandboxKey string, options map[string]string) (*networkdriver.JoinInfo, error) {
+	n, err := d.network(nid)
+	if err != nil {
+		return nil, err
+	}
+
+	name := linkName(eid)
+	if err := d.links.createLink(name, n.parent, n.mode); err != nil {
+		return nil, err
+	}
+	return &networkdriver.JoinInfo{
+		SrcName:     name,
+		DstPrefix:   "eth",
+		Gateway:     n.gateway,
+		MTU:         n.mtu,
+		DeviceRoute: n.mode == ModeL3,
+	}, nil
+}
+
+// Leave deletes the device of the endpoint if it is still on the host. Once
+// moved to the sandbox, it is deleted along with the sandbox.
+func (d *driver) Leave(nid, eid string) error {
+	name := linkName(eid)
+	if err := d.links.deleteLink(name); err != nil {
+		logrus.Debugf("ipvlan: error deleting %s: %v", name, err)
+	}
+	return nil
+}
+
+func linkName(eid string) string {
+	return "ipvl" + eid[:7]
+}
+
+func (d *driver) Type() string {
+	return DriverName
+}
+
+func (d *driver) Scope() string {
+	return networkdriver.LocalScope
+}
@@ -0,0 +1,136 @@
+package ipvlan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver"
+)
+
+// fakeLinker records the devices created by the driver.
+type fakeLinker struct {
+	parents map[string]int
+	links   map[string]string
+}
+
+func newFakeLinker() *fakeLinker {
+	return &fakeLinker{
+		parents: map[string]int{"eth0": 1500},
+		links:   make(map[string]string),
+	}
+}
+
+func (l *fakeLinker) parentMTU(parent string) (int, error) {
+	mtu, ok := l.parents[parent]
+	if !ok {
+		return 0, fmt.Errorf("no such interface %s", parent)
+	}
+	return mtu, nil
+}
+
+func (l *fakeLinker) createLink(name, parent, mode string) error {
+	l.links[name] = parent + " " + mode
+	return nil
+}
+
+func (l *fakeLinker) deleteLink(name string) error {
+	if _, ok := l.links[name]; !ok {
+		return fmt.Errorf("no such interface %s", name)
+	}
+	delete(l.links, name)
+	return nil
+}
+
+const (
+	testNetworkID  = "0a0b0c0d0e0f0a0b0c0d0e0f0a0b0c0d0e0f0a0b0c0d0e0f0a0b0c0d0e0f0a0b"
+	testEndpointID = "1a2b3c4d5e6f1a2b3c4d5e6f1a2b3c4d5e6f1a2b3c4d5e6f1a2b3c4d5e6f1a2b"
+)
+
+func TestCreateNetwork(t *testing.T) {
+	d := newDriver(newFakeLinker())
+
+	for _, config := range []*networkdriver.NetworkConfig{
+		{Options: map[string]string{ParentOption: "eth0"}},
+		{Subnet: "10.0.0.0/24", Options: map[string]string{ParentOption: "eth1"}},
+		{Subnet: "10.0.0.0/24", Options: map[string]string{ParentOption: "eth0", ModeOption: "l4"}},
+		{Subnet: "10.0.0.0/24", MTU: 9000, Options: map[string]string{ParentOption: "eth0"}},
+	} {
+		if err := d.CreateNetwork(testNetworkID, config); err == nil {
+			t.Fatalf("Expected an error creating a network with %+v", config)
+		}
+	}
+
+	config := &networkdriver.NetworkConfig{Subnet: "10.0.0.0/24", Gateway: "10.0.0.1", Options: map[string]string{ParentOption: "eth0"}}
+	if err := d.CreateNetwork(testNetworkID, config); err != nil {
+		t.Fatal(err)
+	}
+	n, err := d.network(testNetworkID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.mode != ModeL2 || n.gateway != "10.0.0.1" || n.mtu != 1500 {
+		t.Fatalf("Expected an l2 network through 10.0.0.1 with the MTU of the parent, got %+v", n)
+	}
+
+	config = &networkdriver.NetworkConfig{Subnet: "10.0.1.0/24", Options: map[string]string{ParentOption: "eth0", ModeOption: ModeL3}}
+	if err := d.CreateNetwork("other", config); err == nil {
+		t.Fatal("Expected an error mixing modes on a parent interface")
+	}
+
+	if err := d.DeleteNetwork(testNetworkID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.network(testNetworkID); err == nil {
+		t.Fatal("Expected the network to be deleted")
+	}
+}
+
+func TestJoin(t *testing.T) {
+	links := newFakeLinker()
+	d := newDriver(links)
+
+	for _, mode := range []string{ModeL2, ModeL3} {
+		config := &networkdriver.NetworkConfig{Subnet: "10.0.0.0/24", Gateway: "10.0.0.1", Options: map[string]string{ParentOption: "eth0", ModeOption: mode}}
+		if err := d.CreateNetwork(testNetworkID, config); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := d.CreateEndpoint(testNetworkID, testEndpointID, nil, nil); err == nil {
+			t.Fatal("Expected an error creating an endpoint without addresses")
+		}
+		iface := &networkdriver.EndpointInterface{Address: "10.0.0.2/24", MacAddress: "02:42:0a:00:00:02"}
+		if _, err := d.CreateEndpoint(testNetworkID, testEndpointID, iface, map[string]string{networkdriver.MacAddressOption: iface.MacAddress}); err == nil {
+			t.Fatal("Expected an error requesting a MAC address")
+		}
+		if _, err := d.CreateEndpoint(testNetworkID, testEndpointID, iface, nil); err != nil {
+			t.Fatal(err)
+		}
+		if iface.MacAddress != "" {
+			t.Fatalf("Expected the MAC address to be cleared, got %s", iface.MacAddress)
+		}
+
+		join, err := d.Join(testNetworkID, testEndpointID, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if links.links[join.SrcName] != "eth0 "+mode {
+			t.Fatalf("Expected an %s device on eth0, got %v", mode, links.links)
+		}
+		if mode == ModeL2 && (join.Gateway != "10.0.0.1" || join.DeviceRoute) {
+			t.Fatalf("Expected a route through the gateway in l2 mode, got %+v", join)
+		}
+		if mode == ModeL3 && (join.Gateway != "" || !join.DeviceRoute) {
+			t.Fatalf("Expected a route through the device in l3 mode, got %+v", join)
+		}
+
+		if err := d.Leave(testNetworkID, testEndpointID); err != nil {
+			t.Fatal(err)
+		}
+		if len(links.links) != 0 {
+			t.Fatalf("Expected the device to be deleted, got %v", links.links)
+		}
+		if err := d.DeleteNetwork(testNetworkID); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package ipvlan
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	"github.com/docker/libcontainer/netlink"
+)
+
+// hostLinker manages the devices with netlink. ipvlan devices are not
+// supported by the netlink package, they are created with the ip command of
+// iproute2.
+type hostLinker struct{}
+
+func newLinker() linker {
+	return hostLinker{}
+}
+
+func run(name string, args ...string) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("ipvlan: %s not found, iproute2 is required: %v", name, err)
+	}
+	if output, err := exec.Command(path, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ipvlan: %s %s failed: %s (%v)", name, strings.Join(args, " "), strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+func (hostLinker) parentMTU(parent string) (int, error) {
+	iface, err := net.InterfaceByName(parent)
+	if err != nil {
+		return 0, err
+	}
+	return iface.MTU, nil
+}
+
+func (hostLinker) createLink(name, parent, mode string) error {
+	// Remove the device left over by a previous run of the daemon
+	netlink.NetworkLinkDel(name)
+
+	return run("ip", "link", "add", "link", parent, "name", name, "type", "ipvlan", "mode", mode)
+}
+
+func (hostLinker) deleteLink(name string) error {
+	return netlink.NetworkLinkDel(name)
+}
@@ -0,0 +1,25 @@
+// +build !linux
+
+package ipvlan
+
+import "errors"
+
+var errNotSupported = errors.New("ipvlan: networks are not supported on this platform")
+
+type unsupportedLinker struct{}
+
+func newLinker() linker {
+	return unsupportedLinker{}
+}
+
+func (unsupportedLinker) parentMTU(parent string) (int, error) {
+	return 0, errNotSupported
+}
+
+func (unsupportedLinker) createLink(name, parent, mode string) error {
+	return errNotSupported
+}
+
+func (unsupportedLinker) deleteLink(name string) error {
+	return errNotSupported
+}
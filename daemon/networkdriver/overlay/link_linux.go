@@ -0,0 +1,131 @@
+package overlay
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/docker/libcontainer/netlink"
+)
+
+// hostLinker manages the devices with netlink. VXLAN devices and their
+// forwarding entries are not supported by the netlink package, they are
+// managed with the ip and bridge commands of iproute2.
+type hostLinker struct{}
+
+func newLinker() linker {
+	return hostLinker{}
+}
+
+func run(name string, args ...string) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("overlay: %s not found, iproute2 is required: %v", name, err)
+	}
+	if output, err := exec.Command(path, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("overlay: %s %s failed: %s (%v)", name, strings.Join(args, " "), strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+func linkUp(name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	return netlink.NetworkLinkUp(iface)
+}
+
+func (hostLinker) createNetwork(bridge, vxlan string, vni uint32, local net.IP) error {
+	// Remove the devices left over by a previous run of the daemon
+	netlink.NetworkLinkDel(vxlan)
+	netlink.NetworkLinkDel(bridge)
+
+	if err := netlink.CreateBridge(bridge, true); err != nil {
+		return err
+	}
+	err := run("ip", "link", "add", vxlan, "type", "vxlan",
+		"id", strconv.FormatUint(uint64(vni), 10),
+		"dstport", strconv.Itoa(vxlanPort),
+		"local", local.String(),
+		"nolearning")
+	if err != nil {
+		netlink.NetworkLinkDel(bridge)
+		return err
+	}
+
+	vxIface, err := net.InterfaceByName(vxlan)
+	if err == nil {
+		var brIface *net.Interface
+		if brIface, err = net.InterfaceByName(bridge); err == nil {
+			err = netlink.NetworkSetMaster(vxIface, brIface)
+		}
+	}
+	if err == nil {
+		if err = linkUp(vxlan); err == nil {
+			err = linkUp(bridge)
+		}
+	}
+	if err != nil {
+		netlink.NetworkLinkDel(vxlan)
+		netlink.NetworkLinkDel(bridge)
+		return err
+	}
+	return nil
+}
+
+func (hostLinker) deleteNetwork(bridge, vxlan string) error {
+	if err := netlink.NetworkLinkDel(vxlan); err != nil {
+		return err
+	}
+	return netlink.NetworkLinkDel(bridge)
+}
+
+func (hostLinker) createVeth(hostName, peerName, bridge string, mtu int) error {
+	if err := netlink.NetworkCreateVethPair(hostName, peerName, 0); err != nil {
+		return err
+	}
+
+	err := func() error {
+		for _, name := range []string{hostName, peerName} {
+			iface, err := net.InterfaceByName(name)
+			if err != nil {
+				return err
+			}
+			if err := netlink.NetworkSetMTU(iface, mtu); err != nil {
+				return err
+			}
+		}
+		host, err := net.InterfaceByName(hostName)
+		if err != nil {
+			return err
+		}
+		brIface, err := net.InterfaceByName(bridge)
+		if err != nil {
+			return err
+		}
+		if err := netlink.NetworkSetMaster(host, brIface); err != nil {
+			return err
+		}
+		return netlink.NetworkLinkUp(host)
+	}()
+	if err != nil {
+		netlink.NetworkLinkDel(hostName)
+		return err
+	}
+	return nil
+}
+
+func (hostLinker) deleteLink(name string) error {
+	return netlink.NetworkLinkDel(name)
+}
+
+func (hostLinker) addPeer(vxlan, mac string, vtep net.IP) error {
+	return run("bridge", "fdb", "append", mac, "dev", vxlan, "dst", vtep.String(), "self", "permanent")
+}
+
+func (hostLinker) deletePeer(vxlan, mac string, vtep net.IP) error {
+	return run("bridge", "fdb", "del", mac, "dev", vxlan, "dst", vtep.String(), "self")
+}
@@ -0,0 +1,40 @@
+// +build !linux
+
+package overlay
+
+import (
+	"errors"
+	"net"
+)
+
+var errNotSupported = errors.New("overlay: networks are not supported on this platform")
+
+type unsupportedLinker struct{}
+
+func newLinker() linker {
+	return unsupportedLinker{}
+}
+
+func (unsupportedLinker) createNetwork(bridge, vxlan string, vni uint32, local net.IP) error {
+	return errNotSupported
+}
+
+func (unsupportedLinker) deleteNetwork(bridge, vxlan string) error {
+	return errNotSupported
+}
+
+func (unsupportedLinker) createVeth(hostName, peerName, bridge string, mtu int) error {
+	return errNotSupported
+}
+
+func (unsupportedLinker) deleteLink(name string) error {
+	return errNotSupported
+}
+
+func (unsupportedLinker) addPeer(vxlan, mac string, vtep net.IP) error {
+	return errNotSupported
+}
+
+func (unsupportedLinker) deletePeer(vxlan, mac string, vtep net.IP) error {
+	return errNotSupported
+}
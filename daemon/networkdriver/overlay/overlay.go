@@ -0,0 +1,459 @@
+// Package overlay implements a network driver providing networks which span
+// several hosts. Each network is a bridge on every host, connected to the
+// bridges of the other hosts through a VXLAN tunnel. Endpoints are published
+// in the key-value store shared by the daemons so that every host knows
+// which host to tunnel the traffic of an endpoint to.
+package overlay
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/pkg/kvstore"
+)
+
+const (
+	// DriverName is the name the overlay driver is registered with.
+	DriverName = "overlay"
+	// VNIOption is the network option setting the VXLAN network identifier
+	// of a network. By default it is derived from the network ID.
+	VNIOption = "com.docker.network.overlay.vni"
+
+	kvPrefix  = "docker/network/v1.0/overlay"
+	vxlanPort = 4789
+	// vxlanMTU is the default MTU of the endpoints, leaving room for the
+	// 50 bytes of VXLAN encapsulation on a 1500 bytes underlay.
+	vxlanMTU     = 1450
+	syncInterval = 5 * time.Second
+)
+
+// linker sets up the network devices of the driver on the host.
+type linker interface {
+	createNetwork(bridge, vxlan string, vni uint32, local net.IP) error
+	deleteNetwork(bridge, vxlan string) error
+	createVeth(hostName, peerName, bridge string, mtu int) error
+	deleteLink(name string) error
+	addPeer(vxlan, mac string, vtep net.IP) error
+	deletePeer(vxlan, mac string, vtep net.IP) error
+}
+
+// peer is the record of an endpoint in the key-value store.
+type peer struct {
+	Address    string
+	MacAddress string
+	VTEP       string
+}
+
+type network struct {
+	id      string
+	subnet  *net.IPNet
+	gateway net.IP
+	mtu     int
+	vni     uint32
+	bridge  string
+	vxlan   string
+	peers   map[string]*peer
+	vteps   map[string]int
+}
+
+type driver struct {
+	kv        kvstore.Store
+	advertise net.IP
+	links     linker
+	networks  map[string]*network
+	sync.Mutex
+}
+
+// Init registers the overlay driver. Endpoints are published in kv and the
+// other hosts tunnel their traffic to the address advertise. When advertise
+// is empty, the address of the interface of the default route is used.
+func Init(kv kvstore.Store, advertise string) error {
+	ip, err := advertiseAddr(advertise)
+	if err != nil {
+		return err
+	}
+	d := newDriver(kv, ip, newLinker())
+	if err := networkdriver.RegisterDriver(DriverName, d); err != nil {
+		return err
+	}
+	go func() {
+		for range time.Tick(syncInterval) {
+			d.syncPeers()
+		}
+	}()
+	return nil
+}
+
+func newDriver(kv kvstore.Store, advertise net.IP, links linker) *driver {
+	return &driver{
+		kv:        kv,
+		advertise: advertise,
+		links:     links,
+		networks:  make(map[string]*network),
+	}
+}
+
+func advertiseAddr(advertise string) (net.IP, error) {
+	if advertise != "" {
+		ip := net.ParseIP(advertise)
+		if ip == nil || ip.To4() == nil {
+			return nil, fmt.Errorf("overlay: invalid advertise address %s", advertise)
+		}
+		return ip, nil
+	}
+
+	iface, err := networkdriver.GetDefaultRouteIface()
+	if err != nil {
+		return nil, fmt.Errorf("overlay: unable to find the address to advertise, use --cluster-advertise: %v", err)
+	}
+	addr, _, err := networkdriver.GetIfaceAddr(iface.Name)
+	if err != nil {
+		return nil, fmt.Errorf("overlay: unable to find the address to advertise, use --cluster-advertise: %v", err)
+	}
+	return addr.(*net.IPNet).IP, nil
+}
+
+// vni returns the VXLAN network identifier of the network nid.
+func vni(nid string, options map[string]string) (uint32, error) {
+	if v, ok := options[VNIOption]; ok {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || n == 0 || n >= 1<<24 {
+			return 0, fmt.Errorf("overlay: invalid VXLAN network identifier %s", v)
+		}
+		return uint32(n), nil
+	}
+
+	b, err := hex.DecodeString(nid[:6])
+	if err != nil {
+		return 0, err
+	}
+	n := binary.BigEndian.Uint32(append([]byte{0}, b...))
+	if n == 0 {
+		n = 1
+	}
+	return n, nil
+}
+
+func (d *driver) network(nid string) (*network, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	n, ok := d.networks[nid]
+	if !ok {
+		return nil, fmt.Errorf("overlay: no such network %s", nid)
+	}
+	return n, nil
+}
+
+func (d *driver) CreateNetwork(nid string, config *networkdriver.NetworkConfig) error {
+	if config.Subnet == "" {
+		return fmt.Errorf("overlay: networks require a subnet")
+	}
+	_, subnet, err := net.ParseCIDR(config.Subnet)
+	if err != nil {
+		return err
+	}
+	if subnet.IP.To4() == nil || config.SubnetIPv6 != "" {
+		return fmt.Errorf("overlay: only IPv4 subnets are supported")
+	}
+	vni, err := vni(nid, config.Options)
+	if err != nil {
+		return err
+	}
+
+	n := &network{
+		id:     nid,
+		subnet: subnet,
+		mtu:    config.MTU,
+		vni:    vni,
+		bridge: "ov-" + nid[:12],
+		vxlan:  "vx-" + nid[:12],
+		peers:  make(map[string]*peer),
+		vteps:  make(map[string]int),
+	}
+	if config.Gateway != "" {
+		n.gateway = net.ParseIP(config.Gateway)
+	}
+	if n.mtu == 0 {
+		n.mtu = vxlanMTU
+	}
+
+	if err := d.links.createNetwork(n.bridge, n.vxlan, n.vni, d.advertise); err != nil {
+		return err
+	}
+
+	d.Lock()
+	d.networks[nid] = n
+	d.Unlock()
+	return nil
+}
+
+func (d *driver) DeleteNetwork(nid string) error {
+	d.Lock()
+	n, ok := d.networks[nid]
+	delete(d.networks, nid)
+	d.Unlock()
+	if !ok {
+		return nil
+	}
+	return d.links.deleteNetwork(n.bridge, n.vxlan)
+}
+
+func (d *driver) CreateEndpoint(nid, eid string, iface *networkdriver.EndpointInterface, options map[string]string) (*networkdriver.EndpointInterface, error) {
+	if iface != nil {
+		return nil, fmt.Errorf("overlay: the driver allocates the addresses of its endpoints")
+	}
+	n, err := d.network(nid)
+	if err != nil {
+		return nil, err
+	}
+
+	if addr := options[networkdriver.IPv6AddressOption]; addr != "" {
+		return nil, fmt.Errorf("overlay: cannot request IPv6 address %s, only IPv4 is supported", addr)
+	}
+	var ip net.IP
+	if addr := options[networkdriver.IPv4AddressOption]; addr != "" {
+		ip, err = d.requestIP(n, eid, addr)
+	} else {
+		ip, err = d.allocateIP(n, eid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ones, _ := n.subnet.Mask.Size()
+	iface = &networkdriver.EndpointInterface{
+		Address:    fmt.Sprintf("%s/%d", ip, ones),
+		MacAddress: options[networkdriver.MacAddressOption],
+	}
+	if iface.MacAddress == "" {
+		iface.MacAddress = networkdriver.GenerateMacAddr(ip).String()
+	}
+
+	b, err := json.Marshal(&peer{
+		Address:    iface.Address,
+		MacAddress: iface.MacAddress,
+		VTEP:       d.advertise.String(),
+	})
+	if err == nil {
+		err = d.kv.Put(path.Join(kvPrefix, nid, "peer", eid), b)
+	}
+	if err != nil {
+		d.kv.Delete(path.Join(kvPrefix, nid, "ip", ip.String()))
+		return nil, err
+	}
+	return iface, nil
+}
+
+// allocateIP reserves the first free address of the network n for the
+// endpoint eid. Addresses are reserved in the key-value store, so that they
+// are unique across hosts.
+func (d *driver) allocateIP(n *network, eid string) (net.IP, error) {
+	first, last := networkdriver.NetworkRange(n.subnet)
+	ip := nextIP(first)
+	for ; !ip.Equal(last); ip = nextIP(ip) {
+		if ip.Equal(n.gateway) {
+			continue
+		}
+		err := d.kv.AtomicCreate(path.Join(kvPrefix, n.id, "ip", ip.String()), []byte(eid))
+		if err == nil {
+			return ip, nil
+		}
+		if err != kvstore.ErrKeyExists {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("overlay: no available ip addresses on network %s", n.id)
+}
+
+// requestIP reserves the address addr of the network n for the endpoint eid.
+func (d *driver) requestIP(n *network, eid, addr string) (net.IP, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() == nil || !n.subnet.Contains(ip) {
+		return nil, fmt.Errorf("overlay: address %s is not part of the subnet %s", addr, n.subnet)
+	}
+	ip = ip.To4()
+	if first, last := networkdriver.NetworkRange(n.subnet); ip.Equal(first) || ip.Equal(last) || ip.Equal(n.gateway) {
+		return nil, fmt.Errorf("overlay: address %s is reserved on network %s", addr, n.id)
+	}
+	err := d.kv.AtomicCreate(path.Join(kvPrefix, n.id, "ip", ip.String()), []byte(eid))
+	if err == kvstore.ErrKeyExists {
+		return nil, fmt.Errorf("overlay: address %s is already in use on network %s", addr, n.id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ip, nil
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+func (d *driver) DeleteEndpoint(nid, eid string) error {
+	key := path.Join(kvPrefix, nid, "peer", eid)
+	pair, err := d.kv.Get(key)
+	if err == kvstore.ErrKeyNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var p peer
+	if err := json.Unmarshal(pair.Value, &p); err == nil {
+		if ip, _, err := net.ParseCIDR(p.Address); err == nil {
+			d.kv.Delete(path.Join(kvPrefix, nid, "ip", ip.String()))
+		}
+	}
+	return d.kv.Delete(key)
+}
+
+func (d *driver) Join(nid, eid, sandboxKey string, options map[string]string) (*networkdriver.JoinInfo, error) {
+	n, err := d.network(nid)
+	if err != nil {
+		return nil, err
+	}
+
+	hostName, peerName := vethNames(eid)
+	if err := d.links.createVeth(hostName, peerName, n.bridge, n.mtu); err != nil {
+		return nil, err
+	}
+	return &networkdriver.JoinInfo{
+		SrcName:   peerName,
+		DstPrefix: "eth",
+		MTU:       n.mtu,
+	}, nil
+}
+
+func (d *driver) Leave(nid, eid string) error {
+	hostName, _ := vethNames(eid)
+	if err := d.links.deleteLink(hostName); err != nil {
+		logrus.Debugf("overlay: error deleting %s: %v", hostName, err)
+	}
+	return nil
+}
+
+func vethNames(eid string) (string, string) {
+	return "veov" + eid[:7], "vpov" + eid[:7]
+}
+
+func (d *driver) Type() string {
+	return DriverName
+}
+
+func (d *driver) Scope() string {
+	return networkdriver.GlobalScope
+}
+
+// syncPeers programs the tunnels to the endpoints on other hosts.
+func (d *driver) syncPeers() {
+	d.Lock()
+	networks := make([]*network, 0, len(d.networks))
+	for _, n := range d.networks {
+		networks = append(networks, n)
+	}
+	d.Unlock()
+
+	for _, n := range networks {
+		if err := d.syncNetworkPeers(n); err != nil {
+			logrus.Debugf("overlay: error syncing peers of network %s: %v", n.id, err)
+		}
+	}
+}
+
+func (d *driver) syncNetworkPeers(n *network) error {
+	pairs, err := d.kv.List(path.Join(kvPrefix, n.id, "peer"))
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]*peer, len(pairs))
+	for _, pair := range pairs {
+		p := &peer{}
+		if err := json.Unmarshal(pair.Value, p); err != nil {
+			continue
+		}
+		if p.VTEP == d.advertise.String() {
+			continue
+		}
+		current[path.Base(pair.Key)] = p
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	for eid, p := range n.peers {
+		if _, ok := current[eid]; ok {
+			continue
+		}
+		if err := d.removePeer(n, p); err != nil {
+			logrus.Debugf("overlay: error removing peer %s: %v", eid, err)
+		}
+		delete(n.peers, eid)
+	}
+	for eid, p := range current {
+		if _, ok := n.peers[eid]; ok {
+			continue
+		}
+		if err := d.addPeer(n, p); err != nil {
+			logrus.Debugf("overlay: error adding peer %s: %v", eid, err)
+			continue
+		}
+		n.peers[eid] = p
+	}
+	return nil
+}
+
+// zeroMac is the forwarding entry flooding broadcast and unknown traffic,
+// ARP requests in particular, to every host with endpoints on the network.
+const zeroMac = "00:00:00:00:00:00"
+
+func (d *driver) addPeer(n *network, p *peer) error {
+	vtep := net.ParseIP(p.VTEP)
+	if vtep == nil {
+		return fmt.Errorf("invalid VTEP %s", p.VTEP)
+	}
+	if err := d.links.addPeer(n.vxlan, p.MacAddress, vtep); err != nil {
+		return err
+	}
+	if n.vteps[p.VTEP] == 0 {
+		if err := d.links.addPeer(n.vxlan, zeroMac, vtep); err != nil {
+			d.links.deletePeer(n.vxlan, p.MacAddress, vtep)
+			return err
+		}
+	}
+	n.vteps[p.VTEP]++
+	return nil
+}
+
+func (d *driver) removePeer(n *network, p *peer) error {
+	vtep := net.ParseIP(p.VTEP)
+	if vtep == nil {
+		return fmt.Errorf("invalid VTEP %s", p.VTEP)
+	}
+	n.vteps[p.VTEP]--
+	if n.vteps[p.VTEP] <= 0 {
+		delete(n.vteps, p.VTEP)
+		if err := d.links.deletePeer(n.vxlan, zeroMac, vtep); err != nil {
+			logrus.Debugf("overlay: error removing flood entry for %s: %v", p.VTEP, err)
+		}
+	}
+	return d.links.deletePeer(n.vxlan, p.MacAddress, vtep)
+}
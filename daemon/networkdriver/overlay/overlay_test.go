@@ -0,0 +1,185 @@
+package overlay
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/pkg/kvstore"
+)
+
+// fakeLinker records the forwarding entries programmed by the driver.
+type fakeLinker struct {
+	fdb map[string]bool
+}
+
+func newFakeLinker() *fakeLinker {
+	return &fakeLinker{fdb: make(map[string]bool)}
+}
+
+func (l *fakeLinker) createNetwork(bridge, vxlan string, vni uint32, local net.IP) error {
+	return nil
+}
+
+func (l *fakeLinker) deleteNetwork(bridge, vxlan string) error {
+	return nil
+}
+
+func (l *fakeLinker) createVeth(hostName, peerName, bridge string, mtu int) error {
+	return nil
+}
+
+func (l *fakeLinker) deleteLink(name string) error {
+	return nil
+}
+
+func (l *fakeLinker) addPeer(vxlan, mac string, vtep net.IP) error {
+	l.fdb[fmt.Sprintf("%s %s %s", vxlan, mac, vtep)] = true
+	return nil
+}
+
+func (l *fakeLinker) deletePeer(vxlan, mac string, vtep net.IP) error {
+	delete(l.fdb, fmt.Sprintf("%s %s %s", vxlan, mac, vtep))
+	return nil
+}
+
+const testNetworkID = "0a0b0c0d0e0f0a0b0c0d0e0f0a0b0c0d0e0f0a0b0c0d0e0f0a0b0c0d0e0f0a0b"
+
+func TestVNI(t *testing.T) {
+	n, err := vni(testNetworkID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0x0a0b0c {
+		t.Fatalf("Expected the VNI to be derived from the network ID, got %d", n)
+	}
+
+	n, err = vni(testNetworkID, map[string]string{VNIOption: "42"})
+	if err != nil || n != 42 {
+		t.Fatalf("Expected VNI 42, got %d (%v)", n, err)
+	}
+	for _, v := range []string{"0", "16777216", "foo"} {
+		if _, err := vni(testNetworkID, map[string]string{VNIOption: v}); err == nil {
+			t.Fatalf("Expected an error for VNI %s", v)
+		}
+	}
+}
+
+func TestCreateNetwork(t *testing.T) {
+	d := newDriver(kvstore.NewMemoryStore(), net.ParseIP("192.168.0.1"), newFakeLinker())
+
+	if err := d.CreateNetwork(testNetworkID, &networkdriver.NetworkConfig{}); err == nil {
+		t.Fatal("Expected an error creating a network without a subnet")
+	}
+	if err := d.CreateNetwork(testNetworkID, &networkdriver.NetworkConfig{Subnet: "2001:db8::/64"}); err == nil {
+		t.Fatal("Expected an error creating a network with an IPv6 subnet")
+	}
+	if err := d.CreateNetwork(testNetworkID, &networkdriver.NetworkConfig{Subnet: "10.0.0.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DeleteNetwork(testNetworkID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.network(testNetworkID); err == nil {
+		t.Fatal("Expected the network to be deleted")
+	}
+}
+
+func TestEndpointsAcrossHosts(t *testing.T) {
+	kv := kvstore.NewMemoryStore()
+	links1, links2 := newFakeLinker(), newFakeLinker()
+	d1 := newDriver(kv, net.ParseIP("192.168.0.1"), links1)
+	d2 := newDriver(kv, net.ParseIP("192.168.0.2"), links2)
+
+	config := &networkdriver.NetworkConfig{Subnet: "10.0.0.0/24", Gateway: "10.0.0.1"}
+	for _, d := range []*driver{d1, d2} {
+		if err := d.CreateNetwork(testNetworkID, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := d1.CreateEndpoint(testNetworkID, "ep1", &networkdriver.EndpointInterface{Address: "10.0.0.5/24"}, nil); err == nil {
+		t.Fatal("Expected an error creating an endpoint with an address")
+	}
+	iface1, err := d1.CreateEndpoint(testNetworkID, "ep1", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iface1.Address != "10.0.0.2/24" {
+		t.Fatalf("Expected the gateway to be skipped, got %s", iface1.Address)
+	}
+	iface2, err := d2.CreateEndpoint(testNetworkID, "ep2", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iface2.Address != "10.0.0.3/24" {
+		t.Fatalf("Expected addresses to be unique across hosts, got %s", iface2.Address)
+	}
+
+	d1.syncPeers()
+	vxlan := "vx-" + testNetworkID[:12]
+	for _, entry := range []string{
+		fmt.Sprintf("%s %s 192.168.0.2", vxlan, iface2.MacAddress),
+		fmt.Sprintf("%s %s 192.168.0.2", vxlan, zeroMac),
+	} {
+		if !links1.fdb[entry] {
+			t.Fatalf("Expected forwarding entry %q, got %v", entry, links1.fdb)
+		}
+	}
+	if len(links1.fdb) != 2 {
+		t.Fatalf("Expected no forwarding entry to the local endpoint, got %v", links1.fdb)
+	}
+
+	if err := d2.DeleteEndpoint(testNetworkID, "ep2"); err != nil {
+		t.Fatal(err)
+	}
+	d1.syncPeers()
+	if len(links1.fdb) != 0 {
+		t.Fatalf("Expected the forwarding entries to be removed, got %v", links1.fdb)
+	}
+
+	iface3, err := d2.CreateEndpoint(testNetworkID, "ep3", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iface3.Address != "10.0.0.3/24" {
+		t.Fatalf("Expected the released address to be reused, got %s", iface3.Address)
+	}
+}
+
+func TestRequestedAddress(t *testing.T) {
+	kv := kvstore.NewMemoryStore()
+	d1 := newDriver(kv, net.ParseIP("192.168.0.1"), newFakeLinker())
+	d2 := newDriver(kv, net.ParseIP("192.168.0.2"), newFakeLinker())
+
+	config := &networkdriver.NetworkConfig{Subnet: "10.0.0.0/24", Gateway: "10.0.0.1"}
+	for _, d := range []*driver{d1, d2} {
+		if err := d.CreateNetwork(testNetworkID, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	iface, err := d1.CreateEndpoint(testNetworkID, "ep1", nil, map[string]string{networkdriver.IPv4AddressOption: "10.0.0.10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iface.Address != "10.0.0.10/24" {
+		t.Fatalf("Expected the requested address, got %s", iface.Address)
+	}
+	for _, addr := range []string{"10.0.0.10", "10.0.0.1", "10.0.0.255", "10.0.1.10", "foo"} {
+		if _, err := d2.CreateEndpoint(testNetworkID, "ep2", nil, map[string]string{networkdriver.IPv4AddressOption: addr}); err == nil {
+			t.Fatalf("Expected an error requesting address %s", addr)
+		}
+	}
+	if _, err := d2.CreateEndpoint(testNetworkID, "ep2", nil, map[string]string{networkdriver.IPv6AddressOption: "2001:db8::10"}); err == nil {
+		t.Fatal("Expected an error requesting an IPv6 address")
+	}
+
+	if err := d1.DeleteEndpoint(testNetworkID, "ep1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d2.CreateEndpoint(testNetworkID, "ep2", nil, map[string]string{networkdriver.IPv4AddressOption: "10.0.0.10"}); err != nil {
+		t.Fatalf("Expected the released address to be available: %v", err)
+	}
+}
@@ -21,6 +21,7 @@ type ipMapping map[string]protoMap
 var (
 	ErrAllPortsAllocated = errors.New("all ports are allocated")
 	ErrUnknownProtocol   = errors.New("unknown protocol")
+	ErrInvalidPortRange  = errors.New("invalid port range")
 	defaultIP            = net.ParseIP("0.0.0.0")
 )
 
@@ -52,12 +53,46 @@ func (e ErrPortAlreadyAllocated) Error() string {
 	return fmt.Sprintf("Bind for %s:%d failed: port is already allocated", e.ip, e.port)
 }
 
+// ErrPortInUse is returned for host ports bound by a process the allocator
+// did not hand them to.
+type ErrPortInUse struct {
+	ip      string
+	port    int
+	process string
+}
+
+func (e ErrPortInUse) IP() string {
+	return e.ip
+}
+
+func (e ErrPortInUse) Port() int {
+	return e.port
+}
+
+// Process describes the process holding the port, empty when unknown.
+func (e ErrPortInUse) Process() string {
+	return e.process
+}
+
+func (e ErrPortInUse) Error() string {
+	process := e.process
+	if process == "" {
+		process = "another process"
+	}
+	return fmt.Sprintf("Bind for %s:%d failed: port is already in use by %s", e.ip, e.port, process)
+}
+
 type (
 	PortAllocator struct {
 		mutex sync.Mutex
 		ipMap ipMapping
 		Begin int
 		End   int
+		// Probe, when set, checks that a port about to be allocated is not
+		// bound on the host by a process the allocator knows nothing about.
+		// Such ports are skipped when looking for a free port and rejected
+		// when requested explicitly.
+		Probe func(ip net.IP, proto string, port int) error
 	}
 	portMap struct {
 		p          map[int]struct{}
@@ -97,6 +132,24 @@ func getDynamicPortRange() (start int, end int, err error) {
 	return start, end, nil
 }
 
+// SetRange sets the range dynamic ports are allocated from to begin-end.
+func (p *PortAllocator) SetRange(begin, end int) error {
+	if begin < 1 || end > 65535 || begin > end {
+		return ErrInvalidPortRange
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.Begin, p.End = begin, end
+	for _, protomap := range p.ipMap {
+		for _, pm := range protomap {
+			pm.begin, pm.end, pm.last = begin, end, end
+		}
+	}
+	return nil
+}
+
 // RequestPort requests new port from global ports pool for specified ip and proto.
 // If port is 0 it returns first free port. Otherwise it cheks port availability
 // in pool and return that port or error if port is already busy.
@@ -104,39 +157,66 @@ func (p *PortAllocator) RequestPort(ip net.IP, proto string, port int) (int, err
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	if proto != "tcp" && proto != "udp" {
+	if proto != "tcp" && proto != "udp" && proto != "sctp" {
 		return 0, ErrUnknownProtocol
 	}
 
-	if ip == nil {
-		ip = defaultIP
-	}
-	ipstr := ip.String()
-	protomap, ok := p.ipMap[ipstr]
-	if !ok {
-		protomap = protoMap{
-			"tcp": p.newPortMap(),
-			"udp": p.newPortMap(),
-		}
-
-		p.ipMap[ipstr] = protomap
-	}
-	mapping := protomap[proto]
+	ipstr, mapping := p.getPortMap(ip, proto)
 	if port > 0 {
-		if _, ok := mapping.p[port]; !ok {
-			mapping.p[port] = struct{}{}
-			return port, nil
+		if _, ok := mapping.p[port]; ok {
+			return 0, NewErrPortAlreadyAllocated(ipstr, port)
+		}
+		if err := p.probe(ip, proto, port); err != nil {
+			return 0, err
 		}
-		return 0, NewErrPortAlreadyAllocated(ipstr, port)
+		mapping.p[port] = struct{}{}
+		return port, nil
 	}
 
-	port, err := mapping.findPort()
+	port, err := mapping.findPort(p.free(ip, proto))
 	if err != nil {
 		return 0, err
 	}
 	return port, nil
 }
 
+// RequestPortRange requests count contiguous ports from global ports pool for
+// specified ip and proto. If port is 0 it returns the first port of a free block
+// within the dynamic port range. Otherwise it checks that every port of the
+// block starting at port is available. Either the whole block is allocated or
+// none of it is.
+func (p *PortAllocator) RequestPortRange(ip net.IP, proto string, port, count int) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if proto != "tcp" && proto != "udp" && proto != "sctp" {
+		return 0, ErrUnknownProtocol
+	}
+	if count < 1 || port < 0 || port+count-1 > 65535 {
+		return 0, ErrInvalidPortRange
+	}
+
+	ipstr, mapping := p.getPortMap(ip, proto)
+	if port > 0 {
+		for i := port; i < port+count; i++ {
+			if _, ok := mapping.p[i]; ok {
+				return 0, NewErrPortAlreadyAllocated(ipstr, i)
+			}
+		}
+		for i := port; i < port+count; i++ {
+			if err := p.probe(ip, proto, i); err != nil {
+				return 0, err
+			}
+		}
+		for i := port; i < port+count; i++ {
+			mapping.p[i] = struct{}{}
+		}
+		return port, nil
+	}
+
+	return mapping.findPortRange(count, p.free(ip, proto))
+}
+
 // ReleasePort releases port from global ports pool for specified ip and proto.
 func (p *PortAllocator) ReleasePort(ip net.IP, proto string, port int) error {
 	p.mutex.Lock()
@@ -153,6 +233,45 @@ func (p *PortAllocator) ReleasePort(ip net.IP, proto string, port int) error {
 	return nil
 }
 
+// ReleasePortRange releases count contiguous ports starting at port from
+// global ports pool for specified ip and proto.
+func (p *PortAllocator) ReleasePortRange(ip net.IP, proto string, port, count int) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if ip == nil {
+		ip = defaultIP
+	}
+	protomap, ok := p.ipMap[ip.String()]
+	if !ok {
+		return nil
+	}
+	for i := port; i < port+count; i++ {
+		delete(protomap[proto].p, i)
+	}
+	return nil
+}
+
+// getPortMap returns the port map for ip and proto, creating it if needed.
+// The caller must hold the mutex.
+func (p *PortAllocator) getPortMap(ip net.IP, proto string) (string, *portMap) {
+	if ip == nil {
+		ip = defaultIP
+	}
+	ipstr := ip.String()
+	protomap, ok := p.ipMap[ipstr]
+	if !ok {
+		protomap = protoMap{
+			"tcp":  p.newPortMap(),
+			"udp":  p.newPortMap(),
+			"sctp": p.newPortMap(),
+		}
+
+		p.ipMap[ipstr] = protomap
+	}
+	return ipstr, protomap[proto]
+}
+
 func (p *PortAllocator) newPortMap() *portMap {
 	return &portMap{
 		p:     map[int]struct{}{},
@@ -170,7 +289,26 @@ func (p *PortAllocator) ReleaseAll() error {
 	return nil
 }
 
-func (pm *portMap) findPort() (int, error) {
+// probe checks port with the Probe of the allocator, if any. The caller must
+// hold the mutex.
+func (p *PortAllocator) probe(ip net.IP, proto string, port int) error {
+	if p.Probe == nil {
+		return nil
+	}
+	if ip == nil {
+		ip = defaultIP
+	}
+	return p.Probe(ip, proto, port)
+}
+
+// free returns whether a port not allocated yet is free on the host.
+func (p *PortAllocator) free(ip net.IP, proto string) func(int) bool {
+	return func(port int) bool {
+		return p.probe(ip, proto, port) == nil
+	}
+}
+
+func (pm *portMap) findPort(free func(int) bool) (int, error) {
 	port := pm.last
 	for i := 0; i <= pm.end-pm.begin; i++ {
 		port++
@@ -178,7 +316,7 @@ func (pm *portMap) findPort() (int, error) {
 			port = pm.begin
 		}
 
-		if _, ok := pm.p[port]; !ok {
+		if _, ok := pm.p[port]; !ok && free(port) {
 			pm.p[port] = struct{}{}
 			pm.last = port
 			return port, nil
@@ -186,3 +324,36 @@ func (pm *portMap) findPort() (int, error) {
 	}
 	return 0, ErrAllPortsAllocated
 }
+
+// findPortRange looks for count contiguous free ports, starting the search
+// after the last allocated port like findPort does. Blocks never wrap around
+// the end of the range.
+func (pm *portMap) findPortRange(count int, free func(int) bool) (int, error) {
+	size := pm.end - pm.begin + 1
+	if count > size {
+		return 0, ErrAllPortsAllocated
+	}
+	start := pm.last
+	for i := 0; i < size; i++ {
+		start++
+		if start > pm.end-count+1 {
+			start = pm.begin
+		}
+
+		available := true
+		for port := start; port < start+count; port++ {
+			if _, ok := pm.p[port]; ok || !free(port) {
+				available = false
+				break
+			}
+		}
+		if available {
+			for port := start; port < start+count; port++ {
+				pm.p[port] = struct{}{}
+			}
+			pm.last = start + count - 1
+			return start, nil
+		}
+	}
+	return 0, ErrAllPortsAllocated
+}
@@ -93,6 +93,27 @@ func TestUnknowProtocol(t *testing.T) {
 	}
 }
 
+func TestRequestSCTPPort(t *testing.T) {
+	p := New()
+
+	if port, err := p.RequestPort(defaultIP, "sctp", 36412); err != nil || port != 36412 {
+		t.Fatalf("Expected sctp port 36412, got %d (%v)", port, err)
+	}
+	if _, err := p.RequestPort(defaultIP, "sctp", 36412); err == nil {
+		t.Fatal("Expected an error requesting an allocated sctp port")
+	}
+	// protocols have separate port pools
+	if _, err := p.RequestPort(defaultIP, "tcp", 36412); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ReleasePort(defaultIP, "sctp", 36412); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.RequestPortRange(defaultIP, "sctp", 36412, 2); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestAllocateAllPorts(t *testing.T) {
 	p := New()
 
@@ -237,3 +258,175 @@ func TestNoDuplicateBPR(t *testing.T) {
 		t.Fatalf("Acquire(0) allocated the same port twice: %d", port)
 	}
 }
+
+func TestRequestPortRange(t *testing.T) {
+	p := New()
+
+	port, err := p.RequestPortRange(defaultIP, "tcp", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := p.Begin; port != expected {
+		t.Fatalf("Expected port %d got %d", expected, port)
+	}
+
+	for i := port; i < port+10; i++ {
+		if _, err := p.RequestPort(defaultIP, "tcp", i); err == nil {
+			t.Fatalf("Port %d should be allocated as part of the range", i)
+		}
+	}
+
+	// the next dynamic block must start after the previous one
+	next, err := p.RequestPortRange(defaultIP, "tcp", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := port + 10; next != expected {
+		t.Fatalf("Expected port %d got %d", expected, next)
+	}
+
+	if err := p.ReleasePortRange(defaultIP, "tcp", port, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.RequestPort(defaultIP, "tcp", port+5); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestSpecificPortRange(t *testing.T) {
+	p := New()
+
+	if _, err := p.RequestPort(defaultIP, "udp", 8005); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := p.RequestPortRange(defaultIP, "udp", 8000, 10)
+	if _, ok := err.(ErrPortAlreadyAllocated); !ok {
+		t.Fatalf("Expected ErrPortAlreadyAllocated got %v", err)
+	}
+
+	// a failed request must not leave part of the range allocated
+	if _, err := p.RequestPort(defaultIP, "udp", 8000); err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := p.RequestPortRange(defaultIP, "udp", 8010, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 8010 {
+		t.Fatalf("Expected port 8010 got %d", port)
+	}
+
+	if _, err := p.RequestPortRange(defaultIP, "udp", 65530, 10); err != ErrInvalidPortRange {
+		t.Fatalf("Expected error %s got %v", ErrInvalidPortRange, err)
+	}
+}
+
+func TestRequestPortRangeSkipsAllocatedPorts(t *testing.T) {
+	p := New()
+
+	if _, err := p.RequestPort(defaultIP, "tcp", p.Begin+2); err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := p.RequestPortRange(defaultIP, "tcp", 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := p.Begin + 3; port != expected {
+		t.Fatalf("Expected port %d got %d", expected, port)
+	}
+
+	if _, err := p.RequestPortRange(defaultIP, "tcp", 0, p.End-p.Begin); err != ErrAllPortsAllocated {
+		t.Fatalf("Expected error %s got %v", ErrAllPortsAllocated, err)
+	}
+}
+
+func TestSetRange(t *testing.T) {
+	p := New()
+
+	if _, err := p.RequestPort(defaultIP, "tcp", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetRange(20000, 20001); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []int{20000, 20001} {
+		port, err := p.RequestPort(defaultIP, "tcp", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if port != expected {
+			t.Fatalf("Expected port %d got %d", expected, port)
+		}
+	}
+	if _, err := p.RequestPort(defaultIP, "tcp", 0); err != ErrAllPortsAllocated {
+		t.Fatalf("Expected error %s got %v", ErrAllPortsAllocated, err)
+	}
+
+	for _, r := range [][2]int{{0, 100}, {100, 65536}, {200, 100}} {
+		if err := p.SetRange(r[0], r[1]); err != ErrInvalidPortRange {
+			t.Fatalf("Expected error %s for range %v got %v", ErrInvalidPortRange, r, err)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	p := New()
+	if err := p.SetRange(20000, 20010); err != nil {
+		t.Fatal(err)
+	}
+	p.Probe = func(ip net.IP, proto string, port int) error {
+		if port == 20000 || port == 20003 {
+			return ErrPortInUse{ip: ip.String(), port: port}
+		}
+		return nil
+	}
+
+	port, err := p.RequestPort(defaultIP, "tcp", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 20001 {
+		t.Fatalf("Expected port 20001 got %d", port)
+	}
+
+	if port, err = p.RequestPortRange(defaultIP, "tcp", 0, 3); err != nil {
+		t.Fatal(err)
+	}
+	if port != 20004 {
+		t.Fatalf("Expected port 20004 got %d", port)
+	}
+
+	if _, err := p.RequestPort(defaultIP, "tcp", 20003); err == nil {
+		t.Fatal("Expected an error requesting a port in use")
+	} else if _, ok := err.(ErrPortInUse); !ok {
+		t.Fatalf("Expected ErrPortInUse got %v", err)
+	}
+	if _, err := p.RequestPortRange(defaultIP, "tcp", 19999, 2); err == nil {
+		t.Fatal("Expected an error requesting a range with a port in use")
+	}
+	if _, err := p.RequestPort(defaultIP, "tcp", 19999); err != nil {
+		t.Fatalf("Expected a failed range request to leave its ports free, got %v", err)
+	}
+}
+
+func TestProbePort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	err = ProbePort(net.ParseIP("127.0.0.1"), "tcp", port)
+	if _, ok := err.(ErrPortInUse); !ok {
+		t.Fatalf("Expected ErrPortInUse got %v", err)
+	}
+
+	l.Close()
+	if err := ProbePort(net.ParseIP("127.0.0.1"), "tcp", port); err != nil {
+		t.Fatalf("Expected port %d to be free, got %v", port, err)
+	}
+}
@@ -0,0 +1,48 @@
+package portallocator
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// ProbePort checks that port is free on the host by binding it on ip. Ports
+// bound by another process make it return ErrPortInUse naming that process
+// when it can be found. SCTP ports are not probed.
+func ProbePort(ip net.IP, proto string, port int) error {
+	addr := net.JoinHostPort(ip.String(), strconv.Itoa(port))
+
+	var err error
+	switch proto {
+	case "tcp":
+		var l net.Listener
+		if l, err = net.Listen("tcp", addr); err == nil {
+			l.Close()
+		}
+	case "udp":
+		var c net.PacketConn
+		if c, err = net.ListenPacket("udp", addr); err == nil {
+			c.Close()
+		}
+	default:
+		return nil
+	}
+	// Other failures, such as a host ip which is not local, are left to the
+	// mapping of the port to report.
+	if err == nil || !isAddrInUse(err) {
+		return nil
+	}
+	return ErrPortInUse{ip: ip.String(), port: port, process: portOwner(proto, port)}
+}
+
+func isAddrInUse(err error) bool {
+	opErr, ok := err.(*net.OpError)
+	if !ok {
+		return false
+	}
+	if sysErr, ok := opErr.Err.(*os.SyscallError); ok {
+		return sysErr.Err == syscall.EADDRINUSE
+	}
+	return opErr.Err == syscall.EADDRINUSE
+}
@@ -0,0 +1,88 @@
+package portallocator
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the state of listening sockets in /proc/net/tcp.
+const tcpListen = "0A"
+
+// portOwner describes the process holding the socket bound to port, or
+// returns an empty string when it can't be found.
+func portOwner(proto string, port int) string {
+	inodes := make(map[string]bool)
+	for _, table := range []string{proto, proto + "6"} {
+		data, err := ioutil.ReadFile(filepath.Join("/proc/net", table))
+		if err != nil {
+			continue
+		}
+		for _, inode := range socketInodes(data, proto, port) {
+			inodes[inode] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return ""
+	}
+
+	procs, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return ""
+	}
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", proc.Name(), "fd")
+		dir, err := os.Open(fdDir)
+		if err != nil {
+			continue
+		}
+		fds, _ := dir.Readdirnames(-1)
+		dir.Close()
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+				comm, _ := ioutil.ReadFile(filepath.Join("/proc", proc.Name(), "comm"))
+				return fmt.Sprintf("%s (pid %d)", strings.TrimSpace(string(comm)), pid)
+			}
+		}
+	}
+	return ""
+}
+
+// socketInodes returns the inodes of the sockets bound to port in data, the
+// content of a /proc/net socket table of proto. Only listening sockets are
+// considered for tcp.
+func socketInodes(data []byte, proto string, port int) []string {
+	var inodes []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// skip the header
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		local := fields[1]
+		p, err := strconv.ParseUint(local[strings.LastIndex(local, ":")+1:], 16, 16)
+		if err != nil || int(p) != port {
+			continue
+		}
+		if proto == "tcp" && fields[3] != tcpListen {
+			continue
+		}
+		inodes = append(inodes, fields[9])
+	}
+	return inodes
+}
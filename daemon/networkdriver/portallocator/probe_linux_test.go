@@ -0,0 +1,24 @@
+package portallocator
+
+import (
+	"reflect"
+	"testing"
+)
+
+const tcpTable = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21435 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 0100007F:A2C4 01 00000000:00000000 00:00000000 00000000     0        0 0 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:0035 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 19807 1 0000000000000000 100 0 0 10 0
+`
+
+func TestSocketInodes(t *testing.T) {
+	if inodes := socketInodes([]byte(tcpTable), "tcp", 8080); !reflect.DeepEqual(inodes, []string{"21435"}) {
+		t.Fatalf("Expected the listening socket on port 8080, got %v", inodes)
+	}
+	if inodes := socketInodes([]byte(tcpTable), "udp", 8080); len(inodes) != 2 {
+		t.Fatalf("Expected every udp socket on port 8080, got %v", inodes)
+	}
+	if inodes := socketInodes([]byte(tcpTable), "tcp", 80); len(inodes) != 0 {
+		t.Fatalf("Expected no socket on port 80, got %v", inodes)
+	}
+}
@@ -0,0 +1,7 @@
+// +build !linux
+
+package portallocator
+
+func portOwner(proto string, port int) string {
+	return ""
+}
@@ -16,6 +16,12 @@ type mapping struct {
 	userlandProxy UserlandProxy
 	host          net.Addr
 	container     net.Addr
+	// forwardID is the forward of the host port by the host forwarder, if
+	// any.
+	forwardID int
+	// hairpin is set when the port is not proxied and the iptables chain
+	// does not make it reachable from the host and the containers.
+	hairpin bool
 }
 
 var NewProxy = NewProxyCommand
@@ -24,12 +30,25 @@ var (
 	ErrUnknownBackendAddressType = errors.New("unknown container address type not supported")
 	ErrPortMappedForIP           = errors.New("port is already mapped to ip")
 	ErrPortNotMapped             = errors.New("port is not mapped")
+	ErrMixedProtocols            = errors.New("port range mixes protocols")
 )
 
+// HostForwarder forwards the ports of the host to the network namespace of
+// the daemon, when the daemon does not run in the network namespace of the
+// host.
+type HostForwarder interface {
+	// AddForward forwards hostPort of hostIP to port in the namespace of the
+	// daemon, and returns the id of the forward.
+	AddForward(proto string, hostIP net.IP, hostPort, port int) (int, error)
+	// RemoveForward removes the forward id.
+	RemoveForward(id int) error
+}
+
 type PortMapper struct {
-	chain *iptables.Chain
+	chain     *iptables.Chain
+	forwarder HostForwarder
 
-	// udp:ip:port
+	// ip:port/proto
 	currentMappings map[string]*mapping
 	lock            sync.Mutex
 
@@ -51,88 +70,159 @@ func (pm *PortMapper) SetIptablesChain(c *iptables.Chain) {
 	pm.chain = c
 }
 
+// SetHostForwarder makes the port mapper forward the host ports to the
+// network namespace of the daemon with f, where they are bound on every
+// address.
+func (pm *PortMapper) SetHostForwarder(f HostForwarder) {
+	pm.forwarder = f
+}
+
+// bindIP returns the address the host ports of hostIP are bound on in the
+// network namespace of the daemon.
+func (pm *PortMapper) bindIP(hostIP net.IP) net.IP {
+	if pm.forwarder == nil {
+		return hostIP
+	}
+	if hostIP != nil && hostIP.To4() == nil {
+		return net.IPv6unspecified
+	}
+	return net.IPv4zero
+}
+
 func (pm *PortMapper) Map(container net.Addr, hostIP net.IP, hostPort int, useProxy bool) (host net.Addr, err error) {
 	pm.lock.Lock()
 	defer pm.lock.Unlock()
 
-	var (
-		m                 *mapping
-		proto             string
-		allocatedHostPort int
-	)
+	proto, err := getProto(container)
+	if err != nil {
+		return nil, err
+	}
 
-	switch container.(type) {
-	case *net.TCPAddr:
-		proto = "tcp"
-		if allocatedHostPort, err = pm.Allocator.RequestPort(hostIP, proto, hostPort); err != nil {
-			return nil, err
-		}
+	allocatedHostPort, err := pm.Allocator.RequestPort(hostIP, proto, hostPort)
+	if err != nil {
+		return nil, err
+	}
 
-		m = &mapping{
-			proto:     proto,
-			host:      &net.TCPAddr{IP: hostIP, Port: allocatedHostPort},
-			container: container,
+	// release the allocated port on any further error during return.
+	defer func() {
+		if err != nil {
+			pm.Allocator.ReleasePort(hostIP, proto, allocatedHostPort)
 		}
+	}()
 
-		if useProxy {
-			m.userlandProxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.TCPAddr).IP, container.(*net.TCPAddr).Port)
-		}
-	case *net.UDPAddr:
-		proto = "udp"
-		if allocatedHostPort, err = pm.Allocator.RequestPort(hostIP, proto, hostPort); err != nil {
-			return nil, err
-		}
+	return pm.mapAllocated(container, proto, hostIP, allocatedHostPort, useProxy)
+}
 
-		m = &mapping{
-			proto:     proto,
-			host:      &net.UDPAddr{IP: hostIP, Port: allocatedHostPort},
-			container: container,
-		}
+// MapRange maps a list of container addresses sharing the same protocol to a
+// contiguous block of host ports. If hostPort is 0 the block is allocated from
+// the dynamic port range. Either all containers are mapped or none of them.
+func (pm *PortMapper) MapRange(containers []net.Addr, hostIP net.IP, hostPort int, useProxy bool) (hosts []net.Addr, err error) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
 
-		if useProxy {
-			m.userlandProxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.UDPAddr).IP, container.(*net.UDPAddr).Port)
+	if len(containers) == 0 {
+		return nil, nil
+	}
+
+	proto, err := getProto(containers[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, container := range containers[1:] {
+		if p, err := getProto(container); err != nil {
+			return nil, err
+		} else if p != proto {
+			return nil, ErrMixedProtocols
 		}
-	default:
-		return nil, ErrUnknownBackendAddressType
 	}
 
-	// release the allocated port on any further error during return.
-	defer func() {
+	firstHostPort, err := pm.Allocator.RequestPortRange(hostIP, proto, hostPort, len(containers))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, container := range containers {
+		host, err := pm.mapAllocated(container, proto, hostIP, firstHostPort+i, useProxy)
 		if err != nil {
-			pm.Allocator.ReleasePort(hostIP, proto, allocatedHostPort)
+			// undo the mappings done so far and give back the whole block.
+			for _, h := range hosts {
+				pm.unmap(h, false)
+			}
+			pm.Allocator.ReleasePortRange(hostIP, proto, firstHostPort, len(containers))
+			return nil, err
 		}
-	}()
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// mapAllocated programs the forwarding of an already allocated host port to
+// container. The caller must hold the lock and is responsible for releasing
+// the host port on error.
+func (pm *PortMapper) mapAllocated(container net.Addr, proto string, hostIP net.IP, allocatedHostPort int, useProxy bool) (net.Addr, error) {
+	m := &mapping{
+		proto:     proto,
+		container: container,
+	}
+
+	if pm.forwarder != nil && proto == "sctp" {
+		return nil, fmt.Errorf("sctp ports can't be forwarded from the host")
+	}
+	containerIP, containerPort := getIPAndPort(container)
+	bindIP := pm.bindIP(hostIP)
+	switch proto {
+	case "tcp":
+		m.host = &net.TCPAddr{IP: hostIP, Port: allocatedHostPort}
+	case "udp":
+		m.host = &net.UDPAddr{IP: hostIP, Port: allocatedHostPort}
+	case "sctp":
+		m.host = &SCTPAddr{IP: hostIP, Port: allocatedHostPort}
+	}
+	if useProxy {
+		m.userlandProxy = NewProxy(proto, bindIP, allocatedHostPort, containerIP, containerPort)
+	} else {
+		m.hairpin = pm.chain != nil && !pm.chain.HairpinMode
+	}
 
 	key := getKey(m.host)
 	if _, exists := pm.currentMappings[key]; exists {
 		return nil, ErrPortMappedForIP
 	}
 
-	containerIP, containerPort := getIPAndPort(m.container)
-	if err := pm.forward(iptables.Append, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort); err != nil {
+	if err := pm.forward(iptables.Append, m.proto, bindIP, allocatedHostPort, containerIP.String(), containerPort); err != nil {
 		return nil, err
 	}
 
-	cleanup := func() error {
-		// need to undo the iptables rules before we return
-		if m.userlandProxy != nil {
-			m.userlandProxy.Stop()
-		}
-		pm.forward(iptables.Delete, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort)
-		if err := pm.Allocator.ReleasePort(hostIP, m.proto, allocatedHostPort); err != nil {
-			return err
+	if m.hairpin {
+		if err := pm.hairpin(iptables.Append, m.proto, bindIP, allocatedHostPort, containerIP.String(), containerPort); err != nil {
+			pm.hairpin(iptables.Delete, m.proto, bindIP, allocatedHostPort, containerIP.String(), containerPort)
+			pm.forward(iptables.Delete, m.proto, bindIP, allocatedHostPort, containerIP.String(), containerPort)
+			return nil, err
 		}
-
-		return nil
 	}
 
 	if m.userlandProxy != nil {
 		if err := m.userlandProxy.Start(); err != nil {
-			if err := cleanup(); err != nil {
-				return nil, fmt.Errorf("Error during port allocation cleanup: %v", err)
+			// need to undo the iptables rules before we return
+			m.userlandProxy.Stop()
+			pm.forward(iptables.Delete, m.proto, bindIP, allocatedHostPort, containerIP.String(), containerPort)
+			return nil, err
+		}
+	}
+
+	if pm.forwarder != nil {
+		id, err := pm.forwarder.AddForward(proto, hostIP, allocatedHostPort, allocatedHostPort)
+		if err != nil {
+			if m.userlandProxy != nil {
+				m.userlandProxy.Stop()
+			}
+			if m.hairpin {
+				pm.hairpin(iptables.Delete, m.proto, bindIP, allocatedHostPort, containerIP.String(), containerPort)
 			}
+			pm.forward(iptables.Delete, m.proto, bindIP, allocatedHostPort, containerIP.String(), containerPort)
 			return nil, err
 		}
+		m.forwardID = id
 	}
 
 	pm.currentMappings[key] = m
@@ -145,16 +235,57 @@ func (pm *PortMapper) ReMapAll() {
 	for _, data := range pm.currentMappings {
 		containerIP, containerPort := getIPAndPort(data.container)
 		hostIP, hostPort := getIPAndPort(data.host)
-		if err := pm.forward(iptables.Append, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
+		if err := pm.forward(iptables.Append, data.proto, pm.bindIP(hostIP), hostPort, containerIP.String(), containerPort); err != nil {
 			logrus.Errorf("Error on iptables add: %s", err)
 		}
 	}
 }
 
+// Status is the state of the forwarding of a mapped host port.
+type Status struct {
+	// Iptables reports whether the iptables rule forwarding the port is in
+	// place.
+	Iptables bool
+	// Proxy reports whether the port is forwarded by a userland proxy, and
+	// ProxyRunning whether the proxy is still running.
+	Proxy        bool
+	ProxyRunning bool
+}
+
+// Status returns the state of the forwarding of the mapped host address.
+func (pm *PortMapper) Status(host net.Addr) (Status, error) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+
+	data, exists := pm.currentMappings[getKey(host)]
+	if !exists {
+		return Status{}, ErrPortNotMapped
+	}
+
+	var s Status
+	if data.userlandProxy != nil {
+		s.Proxy = true
+		s.ProxyRunning = data.userlandProxy.Running()
+	}
+	hostIP, hostPort := getIPAndPort(data.host)
+	hostIP = pm.bindIP(hostIP)
+	if pm.chain != nil && hostIP.To4() != nil {
+		containerIP, containerPort := getIPAndPort(data.container)
+		s.Iptables = pm.chain.ForwardExists(hostIP, hostPort, data.proto, containerIP.String(), containerPort)
+	}
+	return s, nil
+}
+
 func (pm *PortMapper) Unmap(host net.Addr) error {
 	pm.lock.Lock()
 	defer pm.lock.Unlock()
 
+	return pm.unmap(host, true)
+}
+
+// unmap tears down the mapping for host, optionally releasing the host port.
+// The caller must hold the lock.
+func (pm *PortMapper) unmap(host net.Addr, release bool) error {
 	key := getKey(host)
 	data, exists := pm.currentMappings[key]
 	if !exists {
@@ -165,29 +296,59 @@ func (pm *PortMapper) Unmap(host net.Addr) error {
 		data.userlandProxy.Stop()
 	}
 
+	if pm.forwarder != nil {
+		if err := pm.forwarder.RemoveForward(data.forwardID); err != nil {
+			logrus.Errorf("Error removing the forward of %s: %s", key, err)
+		}
+	}
+
 	delete(pm.currentMappings, key)
 
 	containerIP, containerPort := getIPAndPort(data.container)
 	hostIP, hostPort := getIPAndPort(data.host)
-	if err := pm.forward(iptables.Delete, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
+	if data.hairpin {
+		if err := pm.hairpin(iptables.Delete, data.proto, pm.bindIP(hostIP), hostPort, containerIP.String(), containerPort); err != nil {
+			logrus.Errorf("Error on iptables delete: %s", err)
+		}
+	}
+	if err := pm.forward(iptables.Delete, data.proto, pm.bindIP(hostIP), hostPort, containerIP.String(), containerPort); err != nil {
 		logrus.Errorf("Error on iptables delete: %s", err)
 	}
 
+	if !release {
+		return nil
+	}
 	switch a := host.(type) {
 	case *net.TCPAddr:
 		return pm.Allocator.ReleasePort(a.IP, "tcp", a.Port)
 	case *net.UDPAddr:
 		return pm.Allocator.ReleasePort(a.IP, "udp", a.Port)
+	case *SCTPAddr:
+		return pm.Allocator.ReleasePort(a.IP, "sctp", a.Port)
 	}
 	return nil
 }
 
+func getProto(a net.Addr) (string, error) {
+	switch a.(type) {
+	case *net.TCPAddr:
+		return "tcp", nil
+	case *net.UDPAddr:
+		return "udp", nil
+	case *SCTPAddr:
+		return "sctp", nil
+	}
+	return "", ErrUnknownBackendAddressType
+}
+
 func getKey(a net.Addr) string {
 	switch t := a.(type) {
 	case *net.TCPAddr:
 		return fmt.Sprintf("%s:%d/%s", t.IP.String(), t.Port, "tcp")
 	case *net.UDPAddr:
 		return fmt.Sprintf("%s:%d/%s", t.IP.String(), t.Port, "udp")
+	case *SCTPAddr:
+		return fmt.Sprintf("%s:%d/%s", t.IP.String(), t.Port, "sctp")
 	}
 	return ""
 }
@@ -198,6 +359,8 @@ func getIPAndPort(a net.Addr) (net.IP, int) {
 		return t.IP, t.Port
 	case *net.UDPAddr:
 		return t.IP, t.Port
+	case *SCTPAddr:
+		return t.IP, t.Port
 	}
 	return nil, 0
 }
@@ -206,5 +369,19 @@ func (pm *PortMapper) forward(action iptables.Action, proto string, sourceIP net
 	if pm.chain == nil {
 		return nil
 	}
+	// iptables only knows about IPv4, connections to an IPv6 host ip are
+	// forwarded by the userland proxy alone.
+	if sourceIP != nil && sourceIP.To4() == nil {
+		return nil
+	}
 	return pm.chain.Forward(action, sourceIP, sourcePort, proto, containerIP, containerPort)
 }
+
+// hairpin programs the rules making a port forwarded without the userland
+// proxy reachable from the host and the containers.
+func (pm *PortMapper) hairpin(action iptables.Action, proto string, sourceIP net.IP, sourcePort int, containerIP string, containerPort int) error {
+	if sourceIP != nil && sourceIP.To4() == nil {
+		return nil
+	}
+	return pm.chain.Hairpin(action, sourceIP, sourcePort, proto, containerIP, containerPort)
+}
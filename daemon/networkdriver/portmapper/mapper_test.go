@@ -1,6 +1,7 @@
 package portmapper
 
 import (
+	"fmt"
 	"net"
 	"testing"
 
@@ -96,6 +97,39 @@ func TestGetTCPKey(t *testing.T) {
 	}
 }
 
+func TestGetSCTPKey(t *testing.T) {
+	addr := &SCTPAddr{IP: net.ParseIP("192.168.1.5"), Port: 36412}
+
+	key := getKey(addr)
+
+	if expected := "192.168.1.5:36412/sctp"; key != expected {
+		t.Fatalf("expected key %s got %s", expected, key)
+	}
+}
+
+func TestMapSCTPPort(t *testing.T) {
+	pm := New()
+	hostIP := net.ParseIP("192.168.0.1")
+	container := &SCTPAddr{IP: net.ParseIP("172.16.0.1"), Port: 36412}
+
+	host, err := pm.Map(container, hostIP, 36412, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host.Network() != "sctp" || host.String() != "192.168.0.1:36412" {
+		t.Fatalf("Incorrect mapping result: got %s:%s", host.String(), host.Network())
+	}
+	if _, err := pm.Map(container, hostIP, 36412, false); err == nil {
+		t.Fatal("Port is in use - mapping should have failed")
+	}
+	if err := pm.Unmap(host); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.Allocator.RequestPort(hostIP, "sctp", 36412); err != nil {
+		t.Fatalf("Expected the sctp port to be released: %v", err)
+	}
+}
+
 func TestGetUDPIPAndPort(t *testing.T) {
 	addr := &net.UDPAddr{IP: net.ParseIP("192.168.1.5"), Port: 53}
 
@@ -147,3 +181,131 @@ func TestMapAllPortsSingleInterface(t *testing.T) {
 		hosts = []net.Addr{}
 	}
 }
+
+func TestMapPortRange(t *testing.T) {
+	pm := New()
+	hostIP := net.ParseIP("192.168.0.1")
+
+	var containers []net.Addr
+	for i := 0; i < 5; i++ {
+		containers = append(containers, &net.UDPAddr{IP: net.ParseIP("172.16.0.1"), Port: 5060 + i})
+	}
+
+	hosts, err := pm.MapRange(containers, hostIP, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != len(containers) {
+		t.Fatalf("Expected %d mappings got %d", len(containers), len(hosts))
+	}
+	_, first := getIPAndPort(hosts[0])
+	for i, host := range hosts {
+		if _, port := getIPAndPort(host); port != first+i {
+			t.Fatalf("Expected contiguous host ports, got %d at index %d", port, i)
+		}
+	}
+
+	// overlapping the last port of the previous range must fail and map nothing
+	if _, err := pm.MapRange(containers, hostIP, first+4, true); err == nil {
+		t.Fatal("Port range is in use - mapping should have failed")
+	}
+	if host, err := pm.Map(containers[0], hostIP, first+5, true); err != nil {
+		t.Fatalf("Failed mapping should have released its ports: %s", err)
+	} else if err := pm.Unmap(host); err != nil {
+		t.Fatal(err)
+	}
+
+	mixed := []net.Addr{containers[0], &net.TCPAddr{IP: net.ParseIP("172.16.0.1"), Port: 5061}}
+	if _, err := pm.MapRange(mixed, hostIP, 0, true); err != ErrMixedProtocols {
+		t.Fatalf("Expected error %s got %v", ErrMixedProtocols, err)
+	}
+
+	for _, host := range hosts {
+		if err := pm.Unmap(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	pm := New()
+	hostIP := net.ParseIP("192.168.0.1")
+	withProxy := &net.TCPAddr{IP: hostIP, Port: 80}
+	withoutProxy := &net.TCPAddr{IP: hostIP, Port: 81}
+
+	if _, err := pm.Map(&net.TCPAddr{IP: net.ParseIP("172.16.0.1"), Port: 80}, hostIP, 80, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.Map(&net.TCPAddr{IP: net.ParseIP("172.16.0.1"), Port: 81}, hostIP, 81, false); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := pm.Status(withProxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Proxy || !s.ProxyRunning || s.Iptables {
+		t.Fatalf("Expected a running proxy without iptables rules, got %+v", s)
+	}
+	if s, err = pm.Status(withoutProxy); err != nil {
+		t.Fatal(err)
+	}
+	if s.Proxy || s.ProxyRunning {
+		t.Fatalf("Expected no proxy, got %+v", s)
+	}
+
+	if err := pm.Unmap(withProxy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.Status(withProxy); err != ErrPortNotMapped {
+		t.Fatalf("Expected ErrPortNotMapped for an unmapped port, got %v", err)
+	}
+}
+
+type testForwarder struct {
+	forwards map[int]string
+	nextID   int
+}
+
+func (f *testForwarder) AddForward(proto string, hostIP net.IP, hostPort, port int) (int, error) {
+	f.nextID++
+	f.forwards[f.nextID] = fmt.Sprintf("%s:%d/%s->%d", hostIP, hostPort, proto, port)
+	return f.nextID, nil
+}
+
+func (f *testForwarder) RemoveForward(id int) error {
+	delete(f.forwards, id)
+	return nil
+}
+
+func TestMapWithHostForwarder(t *testing.T) {
+	pm := New()
+	f := &testForwarder{forwards: make(map[int]string)}
+	pm.SetHostForwarder(f)
+
+	hostIP := net.ParseIP("192.168.0.1")
+	host, err := pm.Map(&net.TCPAddr{IP: net.ParseIP("172.16.0.1"), Port: 80}, hostIP, 8080, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host.String() != "192.168.0.1:8080" {
+		t.Fatalf("Expected the host address to be kept, got %s", host)
+	}
+	if len(f.forwards) != 1 || f.forwards[1] != "192.168.0.1:8080/tcp->8080" {
+		t.Fatalf("Unexpected forwards %v", f.forwards)
+	}
+	if ip := pm.bindIP(hostIP); !ip.Equal(net.IPv4zero) {
+		t.Fatalf("Expected the port to be bound on every address, got %s", ip)
+	}
+
+	if _, err := pm.Map(&SCTPAddr{IP: net.ParseIP("172.16.0.1"), Port: 80}, hostIP, 8080, true); err == nil {
+		t.Fatal("Expected sctp ports not to be forwarded")
+	}
+
+	if err := pm.Unmap(host); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.forwards) != 0 {
+		t.Fatalf("Expected the forward to be removed, got %v", f.forwards)
+	}
+}
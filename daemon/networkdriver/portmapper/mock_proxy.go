@@ -16,3 +16,7 @@ func (p *mockProxyCommand) Start() error {
 func (p *mockProxyCommand) Stop() error {
 	return nil
 }
+
+func (p *mockProxyCommand) Running() bool {
+	return true
+}
@@ -1,6 +1,7 @@
 package portmapper
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -26,6 +27,8 @@ func init() {
 type UserlandProxy interface {
 	Start() error
 	Stop() error
+	// Running reports whether the proxy is still forwarding connections.
+	Running() bool
 }
 
 // proxyCommand wraps an exec.Cmd to run the userland TCP and UDP
@@ -150,6 +153,21 @@ func (p *proxyCommand) Start() error {
 	}
 }
 
+// Running reports whether the proxy process is alive. The process is only
+// waited for by Stop, so an exited proxy remains as a zombie until then.
+func (p *proxyCommand) Running() bool {
+	if p.cmd.Process == nil || p.cmd.ProcessState != nil {
+		return false
+	}
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", p.cmd.Process.Pid))
+	if err != nil {
+		return false
+	}
+	// The state follows the command name, which is in parentheses
+	i := bytes.LastIndex(stat, []byte(")"))
+	return i >= 0 && i+2 < len(stat) && stat[i+2] != 'Z'
+}
+
 func (p *proxyCommand) Stop() error {
 	if p.cmd.Process != nil {
 		if err := p.cmd.Process.Signal(os.Interrupt); err != nil {
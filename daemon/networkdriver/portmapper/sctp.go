@@ -0,0 +1,22 @@
+package portmapper
+
+import (
+	"net"
+	"strconv"
+)
+
+// SCTPAddr represents the address of an SCTP end point. The net package has
+// no SCTP support, SCTP ports are forwarded by iptables rules only.
+type SCTPAddr struct {
+	IP   net.IP
+	Port int
+}
+
+// Network returns the address's network name, "sctp".
+func (a *SCTPAddr) Network() string {
+	return "sctp"
+}
+
+func (a *SCTPAddr) String() string {
+	return net.JoinHostPort(a.IP.String(), strconv.Itoa(a.Port))
+}
@@ -0,0 +1,97 @@
+package remote
+
+import "github.com/docker/docker/daemon/networkdriver"
+
+// The types below are the JSON payloads of the NetworkDriver plugin
+// protocol. Every response may carry an error message in Err.
+
+type response struct {
+	Err string
+}
+
+func (r *response) getError() string {
+	return r.Err
+}
+
+type maybeError interface {
+	getError() string
+}
+
+// createNetworkRequest is sent to NetworkDriver.CreateNetwork.
+type createNetworkRequest struct {
+	NetworkID  string
+	Subnet     string
+	Gateway    string
+	SubnetIPv6 string
+	MTU        int
+	Options    map[string]string
+}
+
+// deleteNetworkRequest is sent to NetworkDriver.DeleteNetwork.
+type deleteNetworkRequest struct {
+	NetworkID string
+}
+
+// createEndpointRequest is sent to NetworkDriver.CreateEndpoint. Interface
+// is nil when address management is delegated to the plugin.
+type createEndpointRequest struct {
+	NetworkID  string
+	EndpointID string
+	Interface  *endpointInterface
+	Options    map[string]string
+}
+
+type endpointInterface struct {
+	Address     string
+	AddressIPv6 string
+	MacAddress  string
+}
+
+type createEndpointResponse struct {
+	response
+	Interface *endpointInterface
+}
+
+// deleteEndpointRequest is sent to NetworkDriver.DeleteEndpoint.
+type deleteEndpointRequest struct {
+	NetworkID  string
+	EndpointID string
+}
+
+// joinRequest is sent to NetworkDriver.Join.
+type joinRequest struct {
+	NetworkID  string
+	EndpointID string
+	SandboxKey string
+	Options    map[string]string
+}
+
+type interfaceName struct {
+	SrcName   string
+	DstPrefix string
+}
+
+type joinResponse struct {
+	response
+	InterfaceName *interfaceName
+	Gateway       string
+	GatewayIPv6   string
+	MTU           int
+}
+
+// leaveRequest is sent to NetworkDriver.Leave.
+type leaveRequest struct {
+	NetworkID  string
+	EndpointID string
+}
+
+func (i *endpointInterface) toDriver() *networkdriver.EndpointInterface {
+	if i == nil {
+		return nil
+	}
+	return &networkdriver.EndpointInterface{
+		Address:     i.Address,
+		AddressIPv6: i.AddressIPv6,
+		MacAddress:  i.MacAddress,
+	}
+}
@@ -0,0 +1,139 @@
+// Package remote implements a network driver proxying every operation to a
+// plugin implementing the NetworkDriver protocol.
+package remote
+
+import (
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/pkg/plugins"
+)
+
+// PluginType is the name of the subsystem network plugins implement.
+const PluginType = "NetworkDriver"
+
+type driver struct {
+	name     string
+	endpoint *plugins.Client
+}
+
+func init() {
+	plugins.Handle(PluginType, func(name string, client *plugins.Client) {
+		if err := networkdriver.RegisterDriver(name, newDriver(name, client)); err != nil {
+			logrus.Errorf("Error registering network driver plugin %s: %v", name, err)
+		}
+	})
+}
+
+func newDriver(name string, client *plugins.Client) networkdriver.Driver {
+	return &driver{name: name, endpoint: client}
+}
+
+// Load activates the network plugin named name and returns its driver.
+func Load(name string) (networkdriver.Driver, error) {
+	if _, err := plugins.Get(name, PluginType); err != nil {
+		return nil, err
+	}
+	return networkdriver.GetDriver(name)
+}
+
+func (d *driver) call(methodName string, arg interface{}, retVal maybeError) error {
+	method := PluginType + "." + methodName
+	if err := d.endpoint.Call(method, arg, retVal); err != nil {
+		return err
+	}
+	if e := retVal.getError(); e != "" {
+		return fmt.Errorf("remote: %s", e)
+	}
+	return nil
+}
+
+func (d *driver) CreateNetwork(nid string, config *networkdriver.NetworkConfig) error {
+	create := &createNetworkRequest{
+		NetworkID:  nid,
+		Subnet:     config.Subnet,
+		Gateway:    config.Gateway,
+		SubnetIPv6: config.SubnetIPv6,
+		MTU:        config.MTU,
+		Options:    config.Options,
+	}
+	return d.call("CreateNetwork", create, &response{})
+}
+
+func (d *driver) DeleteNetwork(nid string) error {
+	return d.call("DeleteNetwork", &deleteNetworkRequest{NetworkID: nid}, &response{})
+}
+
+func (d *driver) CreateEndpoint(nid, eid string, iface *networkdriver.EndpointInterface, options map[string]string) (*networkdriver.EndpointInterface, error) {
+	create := &createEndpointRequest{
+		NetworkID:  nid,
+		EndpointID: eid,
+		Options:    options,
+	}
+	if iface != nil {
+		create.Interface = &endpointInterface{
+			Address:     iface.Address,
+			AddressIPv6: iface.AddressIPv6,
+			MacAddress:  iface.MacAddress,
+		}
+	}
+
+	var res createEndpointResponse
+	if err := d.call("CreateEndpoint", create, &res); err != nil {
+		return nil, err
+	}
+
+	if iface != nil && res.Interface != nil {
+		return nil, fmt.Errorf("remote: %s returned an interface for endpoint %s which already has one", d.name, eid)
+	}
+	if iface == nil && (res.Interface == nil || res.Interface.Address == "") {
+		return nil, fmt.Errorf("remote: %s returned no address for endpoint %s", d.name, eid)
+	}
+	return res.Interface.toDriver(), nil
+}
+
+func (d *driver) DeleteEndpoint(nid, eid string) error {
+	remove := &deleteEndpointRequest{
+		NetworkID:  nid,
+		EndpointID: eid,
+	}
+	return d.call("DeleteEndpoint", remove, &response{})
+}
+
+func (d *driver) Join(nid, eid, sandboxKey string, options map[string]string) (*networkdriver.JoinInfo, error) {
+	join := &joinRequest{
+		NetworkID:  nid,
+		EndpointID: eid,
+		SandboxKey: sandboxKey,
+		Options:    options,
+	}
+
+	var res joinResponse
+	if err := d.call("Join", join, &res); err != nil {
+		return nil, err
+	}
+
+	if res.InterfaceName == nil || res.InterfaceName.SrcName == "" {
+		return nil, fmt.Errorf("remote: %s returned no interface for endpoint %s", d.name, eid)
+	}
+	return &networkdriver.JoinInfo{
+		SrcName:     res.InterfaceName.SrcName,
+		DstPrefix:   res.InterfaceName.DstPrefix,
+		Gateway:     res.Gateway,
+		GatewayIPv6: res.GatewayIPv6,
+		MTU:         res.MTU,
+	}, nil
+}
+
+func (d *driver) Leave(nid, eid string) error {
+	leave := &leaveRequest{
+		NetworkID:  nid,
+		EndpointID: eid,
+	}
+	return d.call("Leave", leave, &response{})
+}
+
+func (d *driver) Type() string {
+	return d.name
+}
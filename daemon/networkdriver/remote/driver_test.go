@@ -0,0 +1,134 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/pkg/plugins"
+)
+
+func handle(t *testing.T, mux *http.ServeMux, method string, h func(map[string]interface{}) interface{}) {
+	mux.HandleFunc(fmt.Sprintf("/%s.%s", PluginType, method), func(w http.ResponseWriter, r *http.Request) {
+		var ask map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&ask); err != nil {
+			t.Fatal(err)
+		}
+		answer := h(ask)
+		if err := json.NewEncoder(w).Encode(&answer); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func setupPlugin(t *testing.T) (*http.ServeMux, networkdriver.Driver, func()) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	client, err := plugins.NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mux, newDriver("test", client), server.Close
+}
+
+func TestRemoteDriver(t *testing.T) {
+	mux, d, cleanup := setupPlugin(t)
+	defer cleanup()
+
+	handle(t, mux, "CreateNetwork", func(msg map[string]interface{}) interface{} {
+		if msg["NetworkID"] != "dummy" || msg["Subnet"] != "10.0.0.0/24" {
+			return map[string]interface{}{"Err": "unexpected request"}
+		}
+		return map[string]interface{}{}
+	})
+	handle(t, mux, "CreateEndpoint", func(msg map[string]interface{}) interface{} {
+		if msg["Interface"] != nil {
+			return map[string]interface{}{}
+		}
+		// the daemon delegated address management to us
+		return map[string]interface{}{
+			"Interface": map[string]interface{}{
+				"Address":    "10.0.0.3/24",
+				"MacAddress": "ab:cd:ef:ee:ee:ee",
+			},
+		}
+	})
+	handle(t, mux, "Join", func(msg map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"Gateway": "10.0.0.1",
+			"InterfaceName": map[string]interface{}{
+				"SrcName":   "vethsrc",
+				"DstPrefix": "eth",
+			},
+		}
+	})
+	for _, method := range []string{"Leave", "DeleteEndpoint", "DeleteNetwork"} {
+		handle(t, mux, method, func(msg map[string]interface{}) interface{} {
+			return map[string]string{}
+		})
+	}
+
+	if err := d.CreateNetwork("dummy", &networkdriver.NetworkConfig{Subnet: "10.0.0.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.CreateNetwork("other", &networkdriver.NetworkConfig{}); err == nil || !strings.Contains(err.Error(), "unexpected request") {
+		t.Fatalf("Expected the plugin error to be returned, got %v", err)
+	}
+
+	iface, err := d.CreateEndpoint("dummy", "ep1", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iface == nil || iface.Address != "10.0.0.3/24" || iface.MacAddress != "ab:cd:ef:ee:ee:ee" {
+		t.Fatalf("Unexpected interface returned by the plugin: %v", iface)
+	}
+
+	iface, err = d.CreateEndpoint("dummy", "ep2", &networkdriver.EndpointInterface{Address: "10.0.0.4/24"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iface != nil {
+		t.Fatalf("Expected no interface for an endpoint with daemon allocated addresses, got %v", iface)
+	}
+
+	join, err := d.Join("dummy", "ep1", "/var/run/docker/netns/sandbox", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if join.SrcName != "vethsrc" || join.DstPrefix != "eth" || join.Gateway != "10.0.0.1" {
+		t.Fatalf("Unexpected join info: %v", join)
+	}
+
+	if err := d.Leave("dummy", "ep1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DeleteEndpoint("dummy", "ep1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DeleteNetwork("dummy"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoteDriverMissingInterface(t *testing.T) {
+	mux, d, cleanup := setupPlugin(t)
+	defer cleanup()
+
+	handle(t, mux, "CreateEndpoint", func(msg map[string]interface{}) interface{} {
+		return map[string]interface{}{}
+	})
+	handle(t, mux, "Join", func(msg map[string]interface{}) interface{} {
+		return map[string]interface{}{}
+	})
+
+	if _, err := d.CreateEndpoint("dummy", "ep1", nil, nil); err == nil {
+		t.Fatal("Expected an error when the plugin returns no address for a delegated endpoint")
+	}
+	if _, err := d.Join("dummy", "ep1", "/var/run/docker/netns/sandbox", nil); err == nil {
+		t.Fatal("Expected an error when the plugin returns no interface on join")
+	}
+}
@@ -0,0 +1,34 @@
+package sandbox
+
+import "fmt"
+
+// Bandwidth shapes the traffic of an interface in one direction. Rate is
+// guaranteed and Ceil is the most it can borrow up to when the link is
+// idle, both in bits per second. A zero Rate leaves the traffic unshaped and
+// a zero Ceil defaults to Rate.
+type Bandwidth struct {
+	Rate int64
+	Ceil int64
+}
+
+// htbCommands returns the arguments of the tc commands shaping the egress of
+// dev to b with an HTB qdisc: a root class capped at the ceiling and a leaf
+// class, which all the traffic goes to, guaranteed the rate.
+func htbCommands(dev string, b Bandwidth) [][]string {
+	if b.Rate <= 0 {
+		return nil
+	}
+	ceil := b.Ceil
+	if ceil < b.Rate {
+		ceil = b.Rate
+	}
+	return [][]string{
+		{"qdisc", "replace", "dev", dev, "root", "handle", "1:", "htb", "default", "10"},
+		{"class", "replace", "dev", dev, "parent", "1:", "classid", "1:1", "htb", "rate", bits(ceil), "ceil", bits(ceil)},
+		{"class", "replace", "dev", dev, "parent", "1:1", "classid", "1:10", "htb", "rate", bits(b.Rate), "ceil", bits(ceil)},
+	}
+}
+
+func bits(rate int64) string {
+	return fmt.Sprintf("%dbit", rate)
+}
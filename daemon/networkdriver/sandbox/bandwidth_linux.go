@@ -0,0 +1,92 @@
+package sandbox
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// SetBandwidth shapes the traffic of the veth interface name in the network
+// namespace at path. The egress of the container is shaped on the interface
+// itself and its ingress on the egress of its peer in the namespace of the
+// daemon.
+func SetBandwidth(path, name string, egress, ingress Bandwidth) error {
+	ns, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+
+	var peer int
+	if err := withNamespace(ns, func() error {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return err
+		}
+		if ingress.Rate > 0 {
+			if peer, err = peerIndex(iface.Index); err != nil {
+				return err
+			}
+		}
+		// tc is forked from the thread locked in the namespace and runs
+		// in it.
+		return tc(htbCommands(name, egress))
+	}); err != nil {
+		return err
+	}
+
+	if ingress.Rate <= 0 {
+		return nil
+	}
+	iface, err := net.InterfaceByIndex(peer)
+	if err != nil {
+		return fmt.Errorf("Unable to find the peer of %s: %v", name, err)
+	}
+	return tc(htbCommands(iface.Name, ingress))
+}
+
+func tc(commands [][]string) error {
+	for _, args := range commands {
+		if out, err := exec.Command("tc", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("tc %s failed: %s (%v)", strings.Join(args, " "), strings.TrimSpace(string(out)), err)
+		}
+	}
+	return nil
+}
+
+// peerIndex returns the index of the peer of the veth interface at index in
+// the current namespace.
+func peerIndex(index int) (int, error) {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETLINK, syscall.AF_UNSPEC)
+	if err != nil {
+		return 0, err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWLINK || len(m.Data) < syscall.SizeofIfInfomsg {
+			continue
+		}
+		info := (*syscall.IfInfomsg)(unsafe.Pointer(&m.Data[0]))
+		if int(info.Index) != index {
+			continue
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(&m)
+		if err != nil {
+			return 0, err
+		}
+		for _, attr := range attrs {
+			if attr.Attr.Type == syscall.IFLA_LINK && len(attr.Value) >= 4 {
+				return int(*(*uint32)(unsafe.Pointer(&attr.Value[0]))), nil
+			}
+		}
+		break
+	}
+	return 0, fmt.Errorf("Interface %d is not a veth", index)
+}
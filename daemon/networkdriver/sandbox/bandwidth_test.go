@@ -0,0 +1,29 @@
+package sandbox
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHtbCommands(t *testing.T) {
+	if cmds := htbCommands("eth0", Bandwidth{}); cmds != nil {
+		t.Fatalf("Expected no commands without a rate, got %v", cmds)
+	}
+
+	expected := [][]string{
+		{"qdisc", "replace", "dev", "eth0", "root", "handle", "1:", "htb", "default", "10"},
+		{"class", "replace", "dev", "eth0", "parent", "1:", "classid", "1:1", "htb", "rate", "20000000bit", "ceil", "20000000bit"},
+		{"class", "replace", "dev", "eth0", "parent", "1:1", "classid", "1:10", "htb", "rate", "10000000bit", "ceil", "20000000bit"},
+	}
+	if cmds := htbCommands("eth0", Bandwidth{Rate: 10000000, Ceil: 20000000}); !reflect.DeepEqual(cmds, expected) {
+		t.Fatalf("Expected %v, got %v", expected, cmds)
+	}
+
+	cmds := htbCommands("veth1", Bandwidth{Rate: 1000})
+	if len(cmds) != 3 {
+		t.Fatalf("Expected 3 commands, got %v", cmds)
+	}
+	if leaf := cmds[2]; leaf[len(leaf)-3] != "1000bit" || leaf[len(leaf)-1] != "1000bit" {
+		t.Fatalf("Expected the ceiling to default to the rate, got %v", leaf)
+	}
+}
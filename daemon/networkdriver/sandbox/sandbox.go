@@ -0,0 +1,28 @@
+// Package sandbox manages the network namespaces containers attached to
+// user-defined networks run in. A sandbox is a network namespace bind
+// mounted on a file so that it can be set up before, and outlive, the
+// processes of the container.
+package sandbox
+
+// Interface describes a host interface to move into a sandbox.
+type Interface struct {
+	// SrcName is the name of the interface on the host.
+	SrcName string
+	// DstPrefix is the prefix of the interface name in the sandbox, a
+	// numeric suffix is appended to get the first free name.
+	DstPrefix string
+	// Address and AddressIPv6 are in CIDR notation.
+	Address     string
+	AddressIPv6 string
+	MacAddress  string
+	MTU         int
+	// Gateway and GatewayIPv6 are set as default routes through the
+	// interface when not empty.
+	Gateway     string
+	GatewayIPv6 string
+	// DeviceRoute sets default routes through the interface itself, without
+	// a next hop, for the address families which have no gateway.
+	DeviceRoute bool
+}
+
+const defaultDstPrefix = "eth"
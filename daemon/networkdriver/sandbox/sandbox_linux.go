@@ -0,0 +1,253 @@
+package sandbox
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/system"
+)
+
+// Create creates a new network namespace with its loopback interface up and
+// bind mounts it at path.
+func Create(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE|os.O_EXCL, 0)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origns, err := os.Open(threadNsPath())
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	defer origns.Close()
+
+	if err := syscall.Unshare(syscall.CLONE_NEWNET); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("Unable to create network namespace: %v", err)
+	}
+	defer restoreNamespace(origns)
+
+	if err := syscall.Mount(threadNsPath(), path, "bind", syscall.MS_BIND, ""); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("Unable to bind mount network namespace on %s: %v", path, err)
+	}
+
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		return err
+	}
+	return netlink.NetworkLinkUp(lo)
+}
+
+// Destroy releases the network namespace mounted at path. The interfaces
+// still in it are destroyed along with it once no process uses it anymore.
+func Destroy(path string) error {
+	if err := syscall.Unmount(path, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// AddInterface moves the host interface i.SrcName into the network namespace
+// at path, which can be a sandbox or the namespace of a running process such
+// as /proc/<pid>/ns/net, and configures it. It returns the name the
+// interface got in the namespace.
+func AddInterface(path string, i *Interface) (string, error) {
+	iface, err := net.InterfaceByName(i.SrcName)
+	if err != nil {
+		return "", fmt.Errorf("Unable to find interface %s: %v", i.SrcName, err)
+	}
+
+	ns, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer ns.Close()
+
+	if err := netlink.NetworkSetNsFd(iface, int(ns.Fd())); err != nil {
+		return "", fmt.Errorf("Unable to move interface %s to namespace %s: %v", i.SrcName, path, err)
+	}
+
+	var name string
+	err = withNamespace(ns, func() error {
+		var err error
+		name, err = configureInterface(i)
+		return err
+	})
+	return name, err
+}
+
+// RemoveInterface deletes the interface named name from the network namespace
+// at path. Deleting one end of a veth pair deletes its peer as well.
+func RemoveInterface(path, name string) error {
+	ns, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+
+	return withNamespace(ns, func() error {
+		return netlink.NetworkLinkDel(name)
+	})
+}
+
+func configureInterface(i *Interface) (string, error) {
+	iface, err := net.InterfaceByName(i.SrcName)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := i.DstPrefix
+	if prefix == "" {
+		prefix = defaultDstPrefix
+	}
+	name, err := nextInterfaceName(prefix)
+	if err != nil {
+		return "", err
+	}
+	if err := netlink.NetworkChangeName(iface, name); err != nil {
+		return "", err
+	}
+	if iface, err = net.InterfaceByName(name); err != nil {
+		return "", err
+	}
+
+	if i.MacAddress != "" {
+		if err := netlink.NetworkSetMacAddress(iface, i.MacAddress); err != nil {
+			return "", err
+		}
+	}
+	if i.MTU > 0 {
+		if err := netlink.NetworkSetMTU(iface, i.MTU); err != nil {
+			return "", err
+		}
+	}
+	for _, addr := range []string{i.Address, i.AddressIPv6} {
+		if addr == "" {
+			continue
+		}
+		ip, ipNet, err := net.ParseCIDR(addr)
+		if err != nil {
+			return "", err
+		}
+		if err := netlink.NetworkLinkAddIp(iface, ip, ipNet); err != nil {
+			return "", fmt.Errorf("Unable to set address %s on %s: %v", addr, name, err)
+		}
+	}
+	if err := netlink.NetworkLinkUp(iface); err != nil {
+		return "", err
+	}
+	if err := setDefaultRoutes(name, i); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// SetDefaultRoutes sets the default routes of the network namespace at path
+// through the gateways of i, or through the interface named name itself
+// when i.DeviceRoute is set, once the interface they went through is gone.
+func SetDefaultRoutes(path, name string, i *Interface) error {
+	ns, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+
+	return withNamespace(ns, func() error {
+		return setDefaultRoutes(name, i)
+	})
+}
+
+func setDefaultRoutes(name string, i *Interface) error {
+	for _, gw := range []string{i.Gateway, i.GatewayIPv6} {
+		if gw == "" {
+			continue
+		}
+		if err := netlink.AddDefaultGw(gw, name); err != nil {
+			return fmt.Errorf("Unable to set gateway %s on %s: %v", gw, name, err)
+		}
+	}
+	if i.DeviceRoute {
+		routes := []struct{ addr, gw, dst string }{
+			{i.Address, i.Gateway, "0.0.0.0/0"},
+			{i.AddressIPv6, i.GatewayIPv6, "::/0"},
+		}
+		for _, r := range routes {
+			if r.addr == "" || r.gw != "" {
+				continue
+			}
+			if err := netlink.AddRoute(r.dst, "", "", name); err != nil {
+				return fmt.Errorf("Unable to set default route on %s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// nextInterfaceName returns the first name made of prefix and a number which
+// is not in use in the current namespace.
+func nextInterfaceName(prefix string) (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+	used := make(map[string]bool, len(ifaces))
+	for _, iface := range ifaces {
+		if strings.HasPrefix(iface.Name, prefix) {
+			used[iface.Name] = true
+		}
+	}
+	for i := 0; ; i++ {
+		if name := fmt.Sprintf("%s%d", prefix, i); !used[name] {
+			return name, nil
+		}
+	}
+}
+
+// withNamespace runs fn in the network namespace ns. The calling goroutine is
+// locked to its thread for the duration of fn.
+func withNamespace(ns *os.File, fn func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origns, err := os.Open(threadNsPath())
+	if err != nil {
+		return err
+	}
+	defer origns.Close()
+
+	if err := system.Setns(ns.Fd(), syscall.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("Unable to enter network namespace: %v", err)
+	}
+	defer restoreNamespace(origns)
+
+	return fn()
+}
+
+func restoreNamespace(origns *os.File) {
+	if err := system.Setns(origns.Fd(), syscall.CLONE_NEWNET); err != nil {
+		logrus.Errorf("Unable to restore the network namespace of the daemon: %v", err)
+	}
+}
+
+func threadNsPath() string {
+	return fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), syscall.Gettid())
+}
@@ -0,0 +1,32 @@
+// +build !linux
+
+package sandbox
+
+import "errors"
+
+// ErrNotSupported is returned on platforms without network namespaces.
+var ErrNotSupported = errors.New("network sandboxes are not supported on this platform")
+
+func Create(path string) error {
+	return ErrNotSupported
+}
+
+func Destroy(path string) error {
+	return ErrNotSupported
+}
+
+func AddInterface(path string, i *Interface) (string, error) {
+	return "", ErrNotSupported
+}
+
+func RemoveInterface(path, name string) error {
+	return ErrNotSupported
+}
+
+func SetDefaultRoutes(path, name string, i *Interface) error {
+	return ErrNotSupported
+}
+
+func SetBandwidth(path, name string, egress, ingress Bandwidth) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,480 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/daemon/network"
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/docker/pkg/etchosts"
+)
+
+// sandboxDir holds the network namespaces of the containers attached to
+// user-defined networks.
+const sandboxDir = "/var/run/docker/netns"
+
+// NetworkCreate creates a network named name provided by driver.
+func (daemon *Daemon) NetworkCreate(name, driver string, config *networkdriver.NetworkConfig) (*network.Network, error) {
+	if driver == "" {
+		return nil, fmt.Errorf("a network driver is required")
+	}
+	n, err := daemon.networks.Create(name, driver, config)
+	if err != nil {
+		return nil, err
+	}
+	daemon.EventsService.Log("create", n.ID, "network:"+n.Driver)
+	return n, nil
+}
+
+// NetworkRm removes the network nameOrID.
+func (daemon *Daemon) NetworkRm(nameOrID string) error {
+	n, err := daemon.networks.Get(nameOrID)
+	if err != nil {
+		return err
+	}
+	if err := daemon.networks.Remove(n.ID); err != nil {
+		return err
+	}
+	daemon.EventsService.Log("destroy", n.ID, "network:"+n.Driver)
+	return nil
+}
+
+// NetworkConnect connects the running container containerName to the network
+// nameOrID, in addition to the networks it is already attached to.
+func (daemon *Daemon) NetworkConnect(nameOrID, containerName string) error {
+	n, err := daemon.networks.Get(nameOrID)
+	if err != nil {
+		return err
+	}
+	container, err := daemon.Get(containerName)
+	if err != nil {
+		return err
+	}
+	if err := container.connectNetwork(n); err != nil {
+		return err
+	}
+	daemon.EventsService.Log("connect", n.ID, "network:"+n.Driver)
+	return nil
+}
+
+// NetworkDisconnect disconnects the running container containerName from the
+// network nameOrID it was connected to with NetworkConnect.
+func (daemon *Daemon) NetworkDisconnect(nameOrID, containerName string) error {
+	n, err := daemon.networks.Get(nameOrID)
+	if err != nil {
+		return err
+	}
+	container, err := daemon.Get(containerName)
+	if err != nil {
+		return err
+	}
+	if err := container.disconnectNetwork(n); err != nil {
+		return err
+	}
+	daemon.EventsService.Log("disconnect", n.ID, "network:"+n.Driver)
+	return nil
+}
+
+// Networks returns all user-defined networks.
+func (daemon *Daemon) Networks() []*types.NetworkResource {
+	networks := daemon.networks.List()
+	list := make([]*types.NetworkResource, 0, len(networks))
+	for _, n := range networks {
+		list = append(list, networkResource(n))
+	}
+	return list
+}
+
+// NetworkInspect returns the network whose name, ID or ID prefix is
+// nameOrID.
+func (daemon *Daemon) NetworkInspect(nameOrID string) (*types.NetworkResource, error) {
+	n, err := daemon.networks.Get(nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	return networkResource(n), nil
+}
+
+func networkResource(n *network.Network) *types.NetworkResource {
+	r := &types.NetworkResource{
+		ID:         n.ID,
+		Name:       n.Name,
+		Driver:     n.Driver,
+		Scope:      n.Scope(),
+		Subnet:     n.Subnet,
+		Gateway:    n.Gateway,
+		SubnetIPv6: n.SubnetIPv6,
+		MTU:        n.MTU,
+		Options:    n.Options,
+		IPAM:       []types.NetworkPool{},
+		Endpoints:  []types.NetworkEndpoint{},
+	}
+	for _, p := range n.Pools() {
+		r.IPAM = append(r.IPAM, types.NetworkPool{
+			Subnet:    p.Subnet,
+			Gateway:   p.Gateway,
+			Allocated: p.Allocated,
+			Capacity:  p.Capacity,
+		})
+	}
+	for _, ep := range n.Endpoints() {
+		r.Endpoints = append(r.Endpoints, types.NetworkEndpoint{
+			ID:            ep.ID,
+			ContainerID:   ep.ContainerID,
+			ContainerName: ep.Name,
+			Address:       ep.Interface.Address,
+			AddressIPv6:   ep.Interface.AddressIPv6,
+			MacAddress:    ep.Interface.MacAddress,
+			Gateway:       ep.Gateway,
+			GatewayIPv6:   ep.GatewayIPv6,
+		})
+	}
+	return r
+}
+
+// allocateUserNetwork creates the sandbox of the container and attaches it
+// to the user-defined network named by its network mode.
+func (container *Container) allocateUserNetwork() (err error) {
+	n, err := container.daemon.networks.Get(string(container.hostConfig.NetworkMode))
+	if err != nil {
+		return err
+	}
+
+	sandboxKey := filepath.Join(sandboxDir, container.ID)
+	if err := sandbox.Create(sandboxKey); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if err := sandbox.Destroy(sandboxKey); err != nil {
+				logrus.Warnf("Error destroying sandbox of %s: %v", container.ID, err)
+			}
+		}
+	}()
+
+	options := make(map[string]string)
+	if container.Config.MacAddress != "" {
+		options[networkdriver.MacAddressOption] = container.Config.MacAddress
+	}
+	if container.hostConfig.IPAddress != "" {
+		options[networkdriver.IPv4AddressOption] = container.hostConfig.IPAddress
+	}
+	if container.hostConfig.IPv6Address != "" {
+		options[networkdriver.IPv6AddressOption] = container.hostConfig.IPv6Address
+	}
+	ep, err := n.Attach(container.ID, container.Name[1:], sandboxKey, options)
+	if err != nil {
+		return err
+	}
+
+	settings := &network.Settings{}
+	if err := setEndpoint(settings, ep); err != nil {
+		n.Detach(ep, false)
+		return err
+	}
+	container.NetworkSettings = settings
+	return nil
+}
+
+// setEndpoint records ep as the endpoint of the user-defined network of the
+// container in its network settings.
+func setEndpoint(settings *network.Settings, ep *network.Endpoint) error {
+	ip, ones, err := splitAddress(ep.Interface.Address)
+	if err != nil {
+		return err
+	}
+	ip6, ones6, err := splitAddress(ep.Interface.AddressIPv6)
+	if err != nil {
+		return err
+	}
+	settings.NetworkID = ep.NetworkID
+	settings.EndpointID = ep.ID
+	settings.SandboxKey = ep.SandboxKey
+	settings.IfaceName = ep.IfaceName
+	settings.DeviceRoute = ep.DeviceRoute
+	settings.MacAddress = ep.Interface.MacAddress
+	settings.Gateway = ep.Gateway
+	settings.IPv6Gateway = ep.GatewayIPv6
+	settings.IPAddress, settings.IPPrefixLen = ip, ones
+	settings.GlobalIPv6Address, settings.GlobalIPv6PrefixLen = ip6, ones6
+	return nil
+}
+
+// splitAddress returns the address and the prefix length of an address in
+// CIDR notation, which may be empty.
+func splitAddress(addr string) (string, int, error) {
+	if addr == "" {
+		return "", 0, nil
+	}
+	ip, ipNet, err := net.ParseCIDR(addr)
+	if err != nil {
+		return "", 0, err
+	}
+	ones, _ := ipNet.Mask.Size()
+	return ip.String(), ones, nil
+}
+
+// setupBandwidth shapes the traffic of the primary interface of the
+// container, in the network namespace of its process pid, to the limits of
+// its host config. The shaping goes away along with the interface.
+func (container *Container) setupBandwidth(pid int) error {
+	hostConfig := container.hostConfig
+	if hostConfig.EgressRate == 0 && hostConfig.IngressRate == 0 {
+		return nil
+	}
+	if container.Config.NetworkDisabled || !hostConfig.NetworkMode.IsPrivate() {
+		return nil
+	}
+	return sandbox.SetBandwidth(fmt.Sprintf("/proc/%d/ns/net", pid), "eth0",
+		sandbox.Bandwidth{Rate: hostConfig.EgressRate, Ceil: hostConfig.EgressCeil},
+		sandbox.Bandwidth{Rate: hostConfig.IngressRate, Ceil: hostConfig.IngressCeil})
+}
+
+// endpoint returns the endpoint of the container on its user-defined network
+// as recorded in its network settings.
+func (container *Container) endpoint() *network.Endpoint {
+	settings := container.NetworkSettings
+	ep := &network.Endpoint{
+		ID:          settings.EndpointID,
+		NetworkID:   settings.NetworkID,
+		ContainerID: container.ID,
+		Name:        container.Name[1:],
+		SandboxKey:  settings.SandboxKey,
+		IfaceName:   settings.IfaceName,
+		Gateway:     settings.Gateway,
+		GatewayIPv6: settings.IPv6Gateway,
+		DeviceRoute: settings.DeviceRoute,
+	}
+	ep.Interface.MacAddress = settings.MacAddress
+	if settings.IPAddress != "" {
+		ep.Interface.Address = fmt.Sprintf("%s/%d", settings.IPAddress, settings.IPPrefixLen)
+	}
+	if settings.GlobalIPv6Address != "" {
+		ep.Interface.AddressIPv6 = fmt.Sprintf("%s/%d", settings.GlobalIPv6Address, settings.GlobalIPv6PrefixLen)
+	}
+	return ep
+}
+
+// releaseUserNetwork detaches the container from its user-defined network
+// and destroys its sandbox.
+func (container *Container) releaseUserNetwork() {
+	settings := container.NetworkSettings
+	if settings.EndpointID != "" {
+		n, err := container.daemon.networks.Get(settings.NetworkID)
+		if err != nil {
+			logrus.Errorf("Error releasing network of %s: %v", container.ID, err)
+		} else if err := n.Detach(container.endpoint(), false); err != nil {
+			logrus.Errorf("Error releasing network of %s: %v", container.ID, err)
+		}
+	}
+	if settings.SandboxKey != "" {
+		if err := sandbox.Destroy(settings.SandboxKey); err != nil {
+			logrus.Errorf("Error destroying sandbox of %s: %v", container.ID, err)
+		}
+	}
+}
+
+// restoreUserNetwork records the endpoint of a container still running
+// after a daemon restart on its user-defined network.
+func (container *Container) restoreUserNetwork() error {
+	n, err := container.daemon.networks.Get(container.NetworkSettings.NetworkID)
+	if err != nil {
+		return err
+	}
+	return n.Restore(container.endpoint())
+}
+
+// connectNetwork attaches the running container to the network n with a new
+// interface. Containers on the default bridge are connected through the
+// network namespace of their process.
+func (container *Container) connectNetwork(n *network.Network) error {
+	container.Lock()
+	defer container.Unlock()
+
+	if !container.Running {
+		return fmt.Errorf("Container %s is not running", container.ID)
+	}
+	if container.Config.NetworkDisabled || !container.hostConfig.NetworkMode.IsPrivate() {
+		return fmt.Errorf("Container %s does not have its own network stack", container.ID)
+	}
+	settings := container.NetworkSettings
+	if container.attachedTo(n.ID) {
+		return fmt.Errorf("Container %s is already attached to network %s", container.ID, n.Name)
+	}
+
+	sandboxKey := settings.SandboxKey
+	if sandboxKey == "" {
+		sandboxKey = fmt.Sprintf("/proc/%d/ns/net", container.Pid)
+	}
+	ep, err := n.Connect(container.ID, container.Name[1:], sandboxKey, nil)
+	if err != nil {
+		return err
+	}
+	settings.Connected = append(settings.Connected, ep)
+	if err := container.toDisk(); err != nil {
+		logrus.Errorf("Error saving container %s: %v", container.ID, err)
+	}
+	return container.buildHostsFiles(settings.IPAddress)
+}
+
+// disconnectNetwork removes the interface of the running container on the
+// network n. When n is the network the container was started on, the first
+// network it was connected to becomes its own and gets its default routes.
+func (container *Container) disconnectNetwork(n *network.Network) error {
+	container.Lock()
+	defer container.Unlock()
+
+	settings := container.NetworkSettings
+	if settings.NetworkID == n.ID {
+		return container.disconnectOwnNetwork(n)
+	}
+	for i, ep := range settings.Connected {
+		if ep.NetworkID != n.ID {
+			continue
+		}
+		if err := n.Detach(ep, true); err != nil {
+			return err
+		}
+		settings.Connected = append(settings.Connected[:i], settings.Connected[i+1:]...)
+		if err := container.toDisk(); err != nil {
+			logrus.Errorf("Error saving container %s: %v", container.ID, err)
+		}
+		return container.buildHostsFiles(settings.IPAddress)
+	}
+	return fmt.Errorf("Container %s is not connected to network %s", container.ID, n.Name)
+}
+
+// disconnectOwnNetwork removes the interface of the running container on the
+// network n it was started on, and moves its default routes and the address
+// of its hostname to the first network it was connected to. The caller must
+// hold the lock of the container.
+func (container *Container) disconnectOwnNetwork(n *network.Network) error {
+	settings := container.NetworkSettings
+	if len(settings.Connected) == 0 {
+		return fmt.Errorf("Container %s cannot be disconnected from network %s, its only network", container.ID, n.Name)
+	}
+	next := settings.Connected[0]
+	if err := n.Detach(container.endpoint(), true); err != nil {
+		return err
+	}
+	if err := setEndpoint(settings, next); err != nil {
+		return err
+	}
+	settings.Connected = settings.Connected[1:]
+	if err := container.toDisk(); err != nil {
+		logrus.Errorf("Error saving container %s: %v", container.ID, err)
+	}
+
+	// The default routes went away with the interface they went through.
+	if err := sandbox.SetDefaultRoutes(next.SandboxKey, next.IfaceName, &sandbox.Interface{
+		Address:     next.Interface.Address,
+		AddressIPv6: next.Interface.AddressIPv6,
+		Gateway:     next.Gateway,
+		GatewayIPv6: next.GatewayIPv6,
+		DeviceRoute: next.DeviceRoute,
+	}); err != nil {
+		return err
+	}
+	return container.buildHostsFiles(settings.IPAddress)
+}
+
+// attachedTo reports whether the container is attached to the network nid,
+// as its own network or a connected one.
+func (container *Container) attachedTo(nid string) bool {
+	settings := container.NetworkSettings
+	if settings == nil {
+		return false
+	}
+	if settings.NetworkID == nid {
+		return true
+	}
+	for _, ep := range settings.Connected {
+		if ep.NetworkID == nid {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseConnectedNetworks detaches the container from the networks it was
+// connected to. Their interfaces go away with the network namespace.
+func (container *Container) releaseConnectedNetworks() {
+	for _, ep := range container.NetworkSettings.Connected {
+		n, err := container.daemon.networks.Get(ep.NetworkID)
+		if err != nil {
+			logrus.Errorf("Error releasing network of %s: %v", container.ID, err)
+		} else if err := n.Detach(ep, false); err != nil {
+			logrus.Errorf("Error releasing network of %s: %v", container.ID, err)
+		}
+	}
+	container.NetworkSettings.Connected = nil
+}
+
+// restoreConnectedNetworks records the endpoints of a container still running
+// after a daemon restart on the networks it was connected to.
+func (container *Container) restoreConnectedNetworks() error {
+	for _, ep := range container.NetworkSettings.Connected {
+		n, err := container.daemon.networks.Get(ep.NetworkID)
+		if err != nil {
+			return err
+		}
+		if err := n.Restore(ep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// networkHosts returns the records of the other containers of the
+// user-defined networks of the container, by which it reaches them by name.
+func (container *Container) networkHosts() []etchosts.Record {
+	settings := container.NetworkSettings
+	if settings == nil {
+		return nil
+	}
+	own := make(map[string]bool)
+	var nids []string
+	if settings.NetworkID != "" {
+		own[settings.EndpointID] = true
+		nids = append(nids, settings.NetworkID)
+	}
+	for _, ep := range settings.Connected {
+		own[ep.ID] = true
+		nids = append(nids, ep.NetworkID)
+	}
+
+	var records []etchosts.Record
+	for _, nid := range nids {
+		n, err := container.daemon.networks.Get(nid)
+		if err != nil {
+			continue
+		}
+		for _, ep := range n.Endpoints() {
+			if own[ep.ID] || ep.Name == "" || ep.Interface.Address == "" {
+				continue
+			}
+			ip := strings.SplitN(ep.Interface.Address, "/", 2)[0]
+			records = append(records, etchosts.Record{Hosts: ep.Name, IP: ip})
+		}
+	}
+	return records
+}
+
+// updateNetworkHosts rebuilds the hosts file of the running containers
+// attached to the network n when its endpoints change.
+func (daemon *Daemon) updateNetworkHosts(n *network.Network) {
+	for _, container := range daemon.List() {
+		container.Lock()
+		if container.Running && container.attachedTo(n.ID) {
+			if err := container.buildHostsFiles(container.NetworkSettings.IPAddress); err != nil {
+				logrus.Errorf("Error updating /etc/hosts of %s: %v", container.ID, err)
+			}
+		}
+		container.Unlock()
+	}
+}
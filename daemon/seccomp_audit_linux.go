@@ -0,0 +1,176 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libcontainer/seccomp"
+)
+
+const (
+	auditSeccompType = "type=1326"  // AUDIT_SECCOMP
+	auditSeccompLog  = "0x7ffc0000" // SECCOMP_RET_LOG
+)
+
+// seccompAuditor reports the syscalls the containers with the seccomp-audit
+// security option make outside of their seccomp profile, from the records
+// the kernel logs for them to /dev/kmsg when auditd doesn't read them.
+type seccompAuditor struct {
+	sync.Mutex
+	started bool
+	// containers are the audited containers, by their pid namespace
+	containers map[string]*auditedContainer
+}
+
+type auditedContainer struct {
+	container *Container
+	syscalls  map[string]bool // the syscalls already reported
+}
+
+// seccompRecord is an audit record of a syscall logged by a seccomp filter.
+type seccompRecord struct {
+	pid     int
+	arch    uint32
+	syscall uint32
+	comm    string
+}
+
+// watch starts auditing the syscalls of container, the process of which is
+// pid.
+func (a *seccompAuditor) watch(container *Container, pid int) {
+	ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", pid))
+	if err != nil {
+		logrus.Errorf("%s: Error auditing the syscalls: %v", container.ID, err)
+		return
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	if !a.started {
+		kmsg, err := os.Open("/dev/kmsg")
+		if err != nil {
+			logrus.Errorf("%s: Error auditing the syscalls: %v", container.ID, err)
+			return
+		}
+		// skip the records logged before
+		if _, err := kmsg.Seek(0, os.SEEK_END); err != nil {
+			kmsg.Close()
+			logrus.Errorf("%s: Error auditing the syscalls: %v", container.ID, err)
+			return
+		}
+		a.containers = make(map[string]*auditedContainer)
+		a.started = true
+		go a.read(kmsg)
+	}
+	a.containers[ns] = &auditedContainer{container: container, syscalls: make(map[string]bool)}
+}
+
+// unwatch stops auditing the syscalls of container.
+func (a *seccompAuditor) unwatch(container *Container) {
+	a.Lock()
+	defer a.Unlock()
+	for ns, c := range a.containers {
+		if c.container == container {
+			delete(a.containers, ns)
+		}
+	}
+}
+
+// read reports the syscalls of the records of kmsg, each read of which
+// returns a record.
+func (a *seccompAuditor) read(kmsg *os.File) {
+	defer kmsg.Close()
+	buf := make([]byte, 8192)
+	for {
+		n, err := kmsg.Read(buf)
+		if err != nil {
+			// EPIPE tells records were overwritten before being read
+			if pathErr, ok := err.(*os.PathError); ok && pathErr.Err == syscall.EPIPE {
+				continue
+			}
+			logrus.Errorf("Error reading the seccomp audit records: %v", err)
+			return
+		}
+		if r, ok := parseSeccompRecord(string(buf[:n])); ok {
+			a.report(r)
+		}
+	}
+}
+
+func (a *seccompAuditor) report(r *seccompRecord) {
+	ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", r.pid))
+	if err != nil {
+		// the process exited already
+		return
+	}
+	name := seccomp.SyscallName(r.arch, r.syscall)
+	if name == "" {
+		name = strconv.FormatUint(uint64(r.syscall), 10)
+	}
+
+	a.Lock()
+	c, exists := a.containers[ns]
+	if !exists || c.syscalls[name] {
+		a.Unlock()
+		return
+	}
+	c.syscalls[name] = true
+	a.Unlock()
+
+	logrus.Warnf("%s: %s made the syscall %s, which the seccomp profile of the container doesn't allow", c.container.ID, r.comm, name)
+	c.container.LogEvent("seccomp: " + name)
+}
+
+// parseSeccompRecord returns the record of the message of kmsg if it is one
+// of a syscall logged by a seccomp filter, e.g. "5,1234,5678,-;audit:
+// type=1326 audit(1697000000.123:42): auid=4294967295 uid=0 gid=0
+// ses=4294967295 pid=1234 comm="strace" exe="/usr/bin/strace" sig=0
+// arch=c000003e syscall=101 compat=0 ip=0x7f0000000000 code=0x7ffc0000".
+func parseSeccompRecord(msg string) (*seccompRecord, bool) {
+	i := strings.Index(msg, ";")
+	if i == -1 {
+		return nil, false
+	}
+	fields := strings.Fields(msg[i+1:])
+	if len(fields) < 2 || fields[0] != "audit:" || fields[1] != auditSeccompType {
+		return nil, false
+	}
+
+	var (
+		r         seccompRecord
+		logged    bool
+		hasPid    bool
+		hasArch   bool
+		hasNumber bool
+	)
+	for _, f := range fields[2:] {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "pid":
+			pid, err := strconv.Atoi(kv[1])
+			r.pid, hasPid = pid, err == nil
+		case "comm":
+			r.comm = strings.Trim(kv[1], `"`)
+		case "arch":
+			arch, err := strconv.ParseUint(kv[1], 16, 32)
+			r.arch, hasArch = uint32(arch), err == nil
+		case "syscall":
+			nr, err := strconv.ParseUint(kv[1], 10, 32)
+			r.syscall, hasNumber = uint32(nr), err == nil
+		case "code":
+			logged = kv[1] == auditSeccompLog
+		}
+	}
+	if !logged || !hasPid || !hasArch || !hasNumber {
+		return nil, false
+	}
+	return &r, true
+}
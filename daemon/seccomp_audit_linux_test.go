@@ -0,0 +1,26 @@
+package daemon
+
+import "testing"
+
+func TestParseSeccompRecord(t *testing.T) {
+	r, ok := parseSeccompRecord(`5,1234,5678,-;audit: type=1326 audit(1697000000.123:42): auid=4294967295 uid=0 gid=0 ses=4294967295 pid=4321 comm="strace" exe="/usr/bin/strace" sig=0 arch=c000003e syscall=101 compat=0 ip=0x7f0000000000 code=0x7ffc0000` + "\n")
+	if !ok {
+		t.Fatal("Expected the record to be parsed")
+	}
+	if r.pid != 4321 || r.comm != "strace" || r.arch != 0xc000003e || r.syscall != 101 {
+		t.Fatalf("Unexpected record %#v", r)
+	}
+
+	for _, msg := range []string{
+		// killed rather than logged
+		`5,1234,5678,-;audit: type=1326 audit(1697000000.123:42): pid=4321 comm="strace" sig=31 arch=c000003e syscall=101 compat=0 code=0x0`,
+		`5,1234,5678,-;audit: type=1400 audit(1697000000.123:42): apparmor="DENIED" operation="mount" pid=4321`,
+		`6,1235,5679,-;docker0: port 1(veth2dbb5e2) entered disabled state`,
+		`5,1234,5678,-;audit: type=1326 audit(1697000000.123:42): pid=4321 arch=c000003e code=0x7ffc0000`,
+		``,
+	} {
+		if _, ok := parseSeccompRecord(msg); ok {
+			t.Fatalf("Expected %q not to be parsed", msg)
+		}
+	}
+}
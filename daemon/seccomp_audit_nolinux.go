@@ -0,0 +1,11 @@
+// +build !linux
+
+package daemon
+
+// seccompAuditor does nothing, the syscalls can't be filtered on this
+// platform.
+type seccompAuditor struct{}
+
+func (a *seccompAuditor) watch(container *Container, pid int) {}
+
+func (a *seccompAuditor) unwatch(container *Container) {}
@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/daemon/secrets"
+	"github.com/docker/docker/runconfig"
+)
+
+// secretsDir is where the secrets of a container are mounted in it.
+const secretsDir = "/run/secrets"
+
+// SecretCreate registers the secret name holding data.
+func (daemon *Daemon) SecretCreate(name string, data []byte) (*types.SecretCreateResponse, error) {
+	s, err := daemon.secrets.Create(name, data)
+	if err != nil {
+		return nil, err
+	}
+	daemon.EventsService.Log("create", s.ID, "secret")
+	return &types.SecretCreateResponse{ID: s.ID}, nil
+}
+
+// SecretRm removes the secret nameOrID, unless a container uses it.
+func (daemon *Daemon) SecretRm(nameOrID string) error {
+	s, err := daemon.secrets.Get(nameOrID)
+	if err != nil {
+		return err
+	}
+	if containers := daemon.secretContainers(s); len(containers) > 0 {
+		return fmt.Errorf("secret %s is in use by container %s", s.Name, containers[0])
+	}
+	if err := daemon.secrets.Remove(s.ID); err != nil {
+		return err
+	}
+	daemon.EventsService.Log("destroy", s.ID, "secret")
+	return nil
+}
+
+// Secrets returns the secrets of the daemon, without their data.
+func (daemon *Daemon) Secrets() []*types.Secret {
+	list := []*types.Secret{}
+	for _, s := range daemon.secrets.List() {
+		list = append(list, daemon.secretResource(s))
+	}
+	return list
+}
+
+// SecretInspect returns the secret whose name, ID or ID prefix is
+// nameOrID, without its data.
+func (daemon *Daemon) SecretInspect(nameOrID string) (*types.Secret, error) {
+	s, err := daemon.secrets.Get(nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	return daemon.secretResource(s), nil
+}
+
+func (daemon *Daemon) secretResource(s *secrets.Secret) *types.Secret {
+	return &types.Secret{
+		ID:         s.ID,
+		Name:       s.Name,
+		Created:    s.Created,
+		Size:       s.Size,
+		Containers: daemon.secretContainers(s),
+	}
+}
+
+// secretContainers returns the IDs of the containers using the secret s.
+func (daemon *Daemon) secretContainers(s *secrets.Secret) []string {
+	ids := []string{}
+	for _, c := range daemon.List() {
+		for _, name := range c.hostConfig.Secrets {
+			if used, err := daemon.secrets.Get(name); err == nil && used.ID == s.ID {
+				ids = append(ids, c.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// verifySecrets checks the secrets of hostConfig exist and that none of its
+// tmpfs mounts and volumes hides them.
+func (daemon *Daemon) verifySecrets(hostConfig *runconfig.HostConfig, tmpfs map[string]string, mounts []*volumeMount) error {
+	if len(hostConfig.Secrets) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, name := range hostConfig.Secrets {
+		s, err := daemon.secrets.Get(name)
+		if err != nil {
+			return err
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("Duplicate secret %s", s.Name)
+		}
+		seen[s.Name] = true
+	}
+	conflict := fmt.Errorf("Conflicting mounts on %s, it holds the secrets of the container", secretsDir)
+	if _, exists := tmpfs[secretsDir]; exists {
+		return conflict
+	}
+	for _, mnt := range mounts {
+		if mnt.containerPath == secretsDir {
+			return conflict
+		}
+	}
+	return nil
+}
+
+// secretsPath returns the directory of the host where the tmpfs holding the
+// secrets of the container is mounted.
+func (container *Container) secretsPath() (string, error) {
+	return container.GetRootResourcePath("secrets")
+}
+
+// secretsMount returns the read-only mount of the secrets of the container
+// on /run/secrets, none if it has no secret.
+func (container *Container) secretsMount() ([]execdriver.Mount, error) {
+	if len(container.hostConfig.Secrets) == 0 {
+		return nil, nil
+	}
+	source, err := container.secretsPath()
+	if err != nil {
+		return nil, err
+	}
+	return []execdriver.Mount{{Source: source, Destination: secretsDir, Writable: false, Private: true}}, nil
+}
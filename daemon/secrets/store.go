@@ -0,0 +1,205 @@
+// Package secrets keeps the secrets registered with the daemon, which are
+// exposed to the containers naming them as files of a tmpfs.
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/stringid"
+)
+
+const validSecretNameChars = `[a-zA-Z0-9][a-zA-Z0-9_.-]`
+
+var validSecretNamePattern = regexp.MustCompile(`^` + validSecretNameChars + `+$`)
+
+// MaxSize is the largest secret the store accepts, in bytes.
+const MaxSize = 500 * 1024
+
+const (
+	configFile = "config.json"
+	dataFile   = "data"
+)
+
+// Secret is a secret registered with the daemon. Its data is only read
+// when it is exposed to a container.
+type Secret struct {
+	ID      string
+	Name    string
+	Created time.Time
+	Size    int
+
+	path string
+}
+
+// Store keeps the secrets of the daemon on disk, readable by root only.
+type Store struct {
+	path    string
+	secrets map[string]*Secret
+	lock    sync.Mutex
+}
+
+// NewStore returns a store keeping its secrets under path, restoring the
+// secrets previously created there.
+func NewStore(path string) (*Store, error) {
+	abspath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(abspath, 0700); err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		path:    abspath,
+		secrets: make(map[string]*Secret),
+	}
+	dir, err := ioutil.ReadDir(abspath)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range dir {
+		secret := &Secret{path: filepath.Join(abspath, fi.Name())}
+		b, err := ioutil.ReadFile(filepath.Join(secret.path, configFile))
+		if err == nil {
+			err = json.Unmarshal(b, secret)
+		}
+		if err != nil {
+			logrus.Debugf("Error restoring secret %s: %v", fi.Name(), err)
+			continue
+		}
+		s.secrets[secret.ID] = secret
+	}
+	return s, nil
+}
+
+// Create registers the secret name holding data.
+func (s *Store) Create(name string, data []byte) (*Secret, error) {
+	if !validSecretNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("Invalid secret name (%s), only %s are allowed", name, validSecretNameChars)
+	}
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("secret %s is larger than %d bytes", name, MaxSize)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, secret := range s.secrets {
+		if secret.Name == name {
+			return nil, fmt.Errorf("secret with name %s already exists", name)
+		}
+	}
+
+	id := stringid.GenerateRandomID()
+	secret := &Secret{
+		ID:      id,
+		Name:    name,
+		Created: time.Now().UTC(),
+		Size:    len(data),
+		path:    filepath.Join(s.path, id),
+	}
+	if err := secret.toDisk(data); err != nil {
+		os.RemoveAll(secret.path)
+		return nil, err
+	}
+	s.secrets[id] = secret
+	return secret, nil
+}
+
+func (secret *Secret) toDisk(data []byte) error {
+	if err := os.Mkdir(secret.path, 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(secret.path, dataFile), data, 0600); err != nil {
+		return err
+	}
+	b, err := json.Marshal(secret)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(secret.path, configFile), b, 0600)
+}
+
+// Data returns the content of the secret.
+func (secret *Secret) Data() ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(secret.path, dataFile))
+}
+
+// Get returns the secret whose name, ID or ID prefix is nameOrID.
+func (s *Store) Get(nameOrID string) (*Secret, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.get(nameOrID)
+}
+
+func (s *Store) get(nameOrID string) (*Secret, error) {
+	if secret, ok := s.secrets[nameOrID]; ok {
+		return secret, nil
+	}
+	for _, secret := range s.secrets {
+		if secret.Name == nameOrID {
+			return secret, nil
+		}
+	}
+
+	var found *Secret
+	for id, secret := range s.secrets {
+		if !strings.HasPrefix(id, nameOrID) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("secret ID %s is ambiguous", nameOrID)
+		}
+		found = secret
+	}
+	if found == nil || nameOrID == "" {
+		return nil, fmt.Errorf("no such secret: %s", nameOrID)
+	}
+	return found, nil
+}
+
+// List returns the secrets of the store, sorted by name.
+func (s *Store) List() []*Secret {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	secrets := make([]*Secret, 0, len(s.secrets))
+	for _, secret := range s.secrets {
+		secrets = append(secrets, secret)
+	}
+	sort.Sort(byName(secrets))
+	return secrets
+}
+
+// Remove deletes the secret nameOrID and its data.
+func (s *Store) Remove(nameOrID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	secret, err := s.get(nameOrID)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(secret.path); err != nil {
+		return err
+	}
+	delete(s.secrets, secret.ID)
+	return nil
+}
+
+type byName []*Secret
+
+func (b byName) Len() int           { return len(b) }
+func (b byName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byName) Less(i, j int) bool { return b[i].Name < b[j].Name }
@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreCreateGetRemove(t *testing.T) {
+	root, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	s, err := NewStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := s.Create("db-password", []byte("s3cr3t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Size != 6 {
+		t.Fatalf("Expected a size of 6, got %d", secret.Size)
+	}
+	fi, err := os.Stat(filepath.Join(root, secret.ID, dataFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("Expected the data to be only readable by its owner, got %v", fi.Mode())
+	}
+
+	if _, err := s.Create("db-password", []byte("other")); err == nil {
+		t.Fatal("Expected an error for a duplicate name")
+	}
+	for _, name := range []string{"", ".hidden", "../escape", "a/b"} {
+		if _, err := s.Create(name, nil); err == nil {
+			t.Fatalf("Expected an error for the name %q", name)
+		}
+	}
+	if _, err := s.Create("big", make([]byte, MaxSize+1)); err == nil {
+		t.Fatal("Expected an error for a secret larger than MaxSize")
+	}
+
+	for _, nameOrID := range []string{"db-password", secret.ID, secret.ID[:12]} {
+		got, err := s.Get(nameOrID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != secret {
+			t.Fatalf("Expected the secret for %s", nameOrID)
+		}
+	}
+
+	// a new store restores the secrets
+	s, err = NewStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := s.List()
+	if len(list) != 1 || list[0].Name != "db-password" || list[0].ID != secret.ID {
+		t.Fatalf("Unexpected secrets %v", list)
+	}
+	data, err := list[0].Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "s3cr3t" {
+		t.Fatalf("Expected the data s3cr3t, got %q", data)
+	}
+
+	if err := s.Remove("db-password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("db-password"); err == nil || !strings.Contains(err.Error(), "no such secret") {
+		t.Fatalf("Expected no such secret, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, secret.ID)); !os.IsNotExist(err) {
+		t.Fatalf("Expected the secret to be removed from disk, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+// +build linux
+
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/mount"
+	"github.com/docker/libcontainer/label"
+)
+
+// setupSecrets mounts a tmpfs holding the secrets of the container, one
+// file by secret name, so that their data is never written to disk.
+func (container *Container) setupSecrets() error {
+	if len(container.hostConfig.Secrets) == 0 {
+		return nil
+	}
+	dir, err := container.secretsPath()
+	if err != nil {
+		return err
+	}
+	// a tmpfs left behind by a daemon which died holds stale secrets
+	container.unmountSecrets()
+
+	rootUID, rootGID := 0, 0
+	if container.daemon.usernsRemapped(container.hostConfig) {
+		if rootUID, rootGID, err = idtools.GetRootUIDGID(container.daemon.uidMaps, container.daemon.gidMaps); err != nil {
+			return err
+		}
+	}
+	if err := idtools.MkdirAllAs(dir, 0700, rootUID, rootGID); err != nil {
+		return err
+	}
+	// The tmpfs is bound read-only in the container, which can't clear the
+	// flags of the mount in a user namespace: it is left without nodev,
+	// noexec and nosuid, none of which matters on read-only files of data.
+	options := fmt.Sprintf("mode=0755,uid=%d,gid=%d", rootUID, rootGID)
+	if err := mount.Mount("tmpfs", dir, "tmpfs", label.FormatMountLabel(options, container.GetMountLabel())); err != nil {
+		return fmt.Errorf("Error mounting the secrets of %s: %v", container.ID, err)
+	}
+
+	for _, name := range container.hostConfig.Secrets {
+		s, err := container.daemon.secrets.Get(name)
+		if err != nil {
+			return err
+		}
+		data, err := s.Data()
+		if err != nil {
+			return fmt.Errorf("Error reading secret %s: %v", s.Name, err)
+		}
+		path := filepath.Join(dir, s.Name)
+		if err := ioutil.WriteFile(path, data, 0444); err != nil {
+			return err
+		}
+		if err := os.Chown(path, rootUID, rootGID); err != nil {
+			return err
+		}
+	}
+	return mount.ForceMount("tmpfs", dir, "tmpfs", "remount,ro")
+}
+
+// unmountSecrets unmounts the tmpfs holding the secrets of the container,
+// discarding them.
+func (container *Container) unmountSecrets() {
+	dir, err := container.secretsPath()
+	if err != nil {
+		return
+	}
+	if mounted, _ := mount.Mounted(dir); mounted {
+		if err := mount.Unmount(dir); err != nil {
+			logrus.Errorf("%v: Failed to unmount secrets: %v", container.ID, err)
+			return
+		}
+	}
+	os.Remove(dir)
+}
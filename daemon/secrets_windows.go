@@ -0,0 +1,17 @@
+// +build windows
+
+package daemon
+
+import "fmt"
+
+// setupSecrets returns an error if the container has secrets, which need a
+// tmpfs.
+func (container *Container) setupSecrets() error {
+	if len(container.hostConfig.Secrets) > 0 {
+		return fmt.Errorf("Secrets are not supported on this platform")
+	}
+	return nil
+}
+
+func (container *Container) unmountSecrets() {
+}
@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/libcontainer/user"
+)
+
+// parseRemappedRoot returns the names of the user and group of
+// --userns-remap=USER[:GROUP], given by name or ID. The group defaults to
+// the name of the user.
+func parseRemappedRoot(usergrp string) (string, string, error) {
+	var username, groupname string
+
+	parts := strings.SplitN(usergrp, ":", 2)
+	if parts[0] == "" {
+		return "", "", fmt.Errorf("Invalid --userns-remap %q: no user given", usergrp)
+	}
+	if uid, err := strconv.Atoi(parts[0]); err == nil {
+		u, err := user.LookupUid(uid)
+		if err != nil {
+			return "", "", fmt.Errorf("Invalid --userns-remap: no user with the ID %d: %v", uid, err)
+		}
+		username = u.Name
+	} else {
+		u, err := user.LookupUser(parts[0])
+		if err != nil {
+			return "", "", fmt.Errorf("Invalid --userns-remap: no user %s: %v", parts[0], err)
+		}
+		username = u.Name
+	}
+
+	groupname = username
+	if len(parts) == 2 && parts[1] != "" {
+		if gid, err := strconv.Atoi(parts[1]); err == nil {
+			g, err := user.LookupGid(gid)
+			if err != nil {
+				return "", "", fmt.Errorf("Invalid --userns-remap: no group with the ID %d: %v", gid, err)
+			}
+			groupname = g.Name
+		} else {
+			g, err := user.LookupGroup(parts[1])
+			if err != nil {
+				return "", "", fmt.Errorf("Invalid --userns-remap: no group %s: %v", parts[1], err)
+			}
+			groupname = g.Name
+		}
+	}
+	return username, groupname, nil
+}
+
+// setupRemappedRoot returns the mappings of the IDs of the containers to the
+// subordinate IDs of the user and group of --userns-remap, none without it.
+func setupRemappedRoot(config *Config) ([]idtools.IDMap, []idtools.IDMap, error) {
+	if config.RemappedRoot == "" {
+		return nil, nil, nil
+	}
+	if config.ExecDriver != "native" {
+		return nil, nil, fmt.Errorf("--userns-remap is only supported by the native exec driver")
+	}
+	if _, err := os.Stat("/proc/self/ns/user"); err != nil {
+		return nil, nil, fmt.Errorf("--userns-remap needs a kernel with user namespaces: %v", err)
+	}
+	username, groupname, err := parseRemappedRoot(config.RemappedRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	uidMaps, gidMaps, err := idtools.CreateIDMappings(username, groupname)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Can't remap the root of the containers to %s:%s: %v", username, groupname, err)
+	}
+	return uidMaps, gidMaps, nil
+}
+
+// setupRemappedRootDir returns the directory of the daemon state when the
+// root of the containers is remapped to rootUID and rootGID: a directory
+// of root owned by them, so that the processes of the containers can reach
+// their files, and which keeps the images and containers of each mapping
+// apart.
+func setupRemappedRootDir(root string, rootUID, rootGID int) (string, error) {
+	// the processes of the containers need to walk through the root
+	if err := os.Chmod(root, 0711); err != nil {
+		return "", err
+	}
+	remappedRoot := filepath.Join(root, fmt.Sprintf("%d.%d", rootUID, rootGID))
+	if err := idtools.MkdirAllAs(remappedRoot, 0700, rootUID, rootGID); err != nil {
+		return "", err
+	}
+	// an existing directory may have been left with other owners
+	if err := os.Chown(remappedRoot, rootUID, rootGID); err != nil {
+		return "", err
+	}
+	return remappedRoot, nil
+}
+
+// IDMappings returns the mappings of the user and group IDs of the
+// containers to the ones of the host, none unless the daemon runs with
+// --userns-remap.
+func (daemon *Daemon) IDMappings() ([]idtools.IDMap, []idtools.IDMap) {
+	return daemon.uidMaps, daemon.gidMaps
+}
+
+// idMappings returns the mappings of the user and group IDs of the container
+// of hostConfig to the ones of the host, none unless it runs with the root
+// remapped.
+func (daemon *Daemon) idMappings(hostConfig *runconfig.HostConfig) ([]idtools.IDMap, []idtools.IDMap) {
+	if !daemon.usernsRemapped(hostConfig) {
+		return nil, nil
+	}
+	return daemon.uidMaps, daemon.gidMaps
+}
+
+// usernsRemapped returns whether the container of hostConfig runs with the
+// root remapped in a user namespace.
+func (daemon *Daemon) usernsRemapped(hostConfig *runconfig.HostConfig) bool {
+	return daemon.uidMaps != nil && (hostConfig == nil || !hostConfig.UsernsMode.IsHost())
+}
+
+// verifyUsernsConfig rejects the settings which can't be applied to a
+// container running in a user namespace.
+func (daemon *Daemon) verifyUsernsConfig(hostConfig *runconfig.HostConfig) error {
+	if !daemon.usernsRemapped(hostConfig) {
+		return nil
+	}
+	const hint = "with --userns-remap, use --userns=host to run the container without remapping"
+	switch {
+	case hostConfig.Privileged:
+		return fmt.Errorf("Privileged mode is incompatible with user namespaces, %s", hint)
+	case hostConfig.NetworkMode.IsHost():
+		return fmt.Errorf("The host network stack is incompatible with user namespaces, %s", hint)
+	case hostConfig.NetworkMode.IsContainer(), hostConfig.NetworkMode.IsUserDefined():
+		// the namespaces of the user namespace of the daemon or of another
+		// container can't be joined
+		return fmt.Errorf("Joining the network stack of %s is incompatible with user namespaces, %s", hostConfig.NetworkMode, hint)
+	case hostConfig.PidMode.IsHost():
+		return fmt.Errorf("The host PID namespace is incompatible with user namespaces, %s", hint)
+	case hostConfig.IpcMode.IsHost():
+		return fmt.Errorf("The host IPC namespace is incompatible with user namespaces, %s", hint)
+	case hostConfig.IpcMode.IsContainer():
+		return fmt.Errorf("Joining the IPC namespace of %s is incompatible with user namespaces, %s", hostConfig.IpcMode, hint)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/tarsum"
+	"github.com/docker/docker/volumes"
+)
+
+// VolumeExport streams the contents of the volume name to out as a tar
+// archive and returns the tarsum of the archive.
+func (daemon *Daemon) VolumeExport(name string, out io.Writer) (string, error) {
+	v, err := daemon.volumes.GetByName(name)
+	if err != nil {
+		return "", err
+	}
+	path, err := daemon.volumes.Mount(v)
+	if err != nil {
+		return "", fmt.Errorf("error mounting volume %s: %v", v.Name, err)
+	}
+	defer daemon.unmountVolume(v)
+
+	data, err := archive.TarWithOptions(path, &archive.TarOptions{
+		Compression: archive.Uncompressed,
+		UIDMaps:     daemon.uidMaps,
+		GIDMaps:     daemon.gidMaps,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", name, err)
+	}
+	defer data.Close()
+
+	ts, err := tarsum.NewTarSum(data, true, tarsum.Version1)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, ts); err != nil {
+		return "", fmt.Errorf("%s: %s", name, err)
+	}
+	daemon.EventsService.Log("export", v.Name, "volume:"+v.Driver)
+	return ts.Sum(nil), nil
+}
+
+// VolumeImport extracts the tar archive in, which may be compressed, into
+// the volume name and returns the tarsum of the uncompressed archive. When
+// checksum is not empty, the archive is only extracted if its tarsum
+// matches. Volumes used by running containers cannot be restored.
+func (daemon *Daemon) VolumeImport(name string, in io.Reader, checksum string) (string, error) {
+	v, err := daemon.volumes.GetByName(name)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range v.Containers() {
+		if c, err := daemon.Get(id); err == nil && c.IsRunning() {
+			return "", fmt.Errorf("volume %s is used by the running container %s", v.Name, c.ID)
+		}
+	}
+
+	// The archive is checked before any of it is extracted
+	tmp, err := ioutil.TempFile("", "docker-volume-import-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	data, err := archive.DecompressStream(in)
+	if err != nil {
+		return "", err
+	}
+	defer data.Close()
+	var ts tarsum.TarSum
+	if checksum != "" {
+		ts, err = tarsum.NewVerifiedTarSum(data, checksum)
+	} else {
+		ts, err = tarsum.NewTarSum(data, true, tarsum.Version1)
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tmp, ts); err != nil {
+		if mismatch, ok := err.(tarsum.MismatchError); ok {
+			return "", fmt.Errorf("checksum mismatch for the archive of volume %s: expected %s, got %s", v.Name, mismatch.Expected, mismatch.Actual)
+		}
+		return "", fmt.Errorf("error reading the archive of volume %s: %v", v.Name, err)
+	}
+	sum := ts.Sum(nil)
+	if _, err := tmp.Seek(0, 0); err != nil {
+		return "", err
+	}
+
+	path, err := daemon.volumes.Mount(v)
+	if err != nil {
+		return "", fmt.Errorf("error mounting volume %s: %v", v.Name, err)
+	}
+	defer daemon.unmountVolume(v)
+
+	if err := chrootarchive.Untar(tmp, path, &archive.TarOptions{UIDMaps: daemon.uidMaps, GIDMaps: daemon.gidMaps}); err != nil {
+		return "", fmt.Errorf("error extracting the archive of volume %s: %v", v.Name, err)
+	}
+	daemon.EventsService.Log("import", v.Name, "volume:"+v.Driver)
+	return sum, nil
+}
+
+func (daemon *Daemon) unmountVolume(v *volumes.Volume) {
+	if err := daemon.volumes.Unmount(v); err != nil {
+		logrus.Errorf("error unmounting volume %s: %v", v.Name, err)
+	}
+}
@@ -13,14 +13,31 @@ import (
 	"github.com/docker/docker/pkg/chrootarchive"
 	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/volumes"
+	"github.com/docker/libcontainer/label"
 )
 
 type volumeMount struct {
 	containerPath string
 	hostPath      string
-	writable      bool
-	copyData      bool
-	from          string
+	// name is the name of the named volume to mount, created on first use
+	// with driver, driverOpts and labels
+	name       string
+	driver     string
+	driverOpts map[string]string
+	labels     map[string]string
+	writable   bool
+	copyData   bool
+	from       string
+	// propagation is the propagation mode of a bind mount, if any
+	propagation string
+	// recursive is set when a read-only bind mount also makes the mounts
+	// of the host below hostPath read-only
+	recursive bool
+	// relabel is z or Z when the content of the mount is relabeled with the
+	// mount label of the container, shared or private to the container
+	relabel string
 }
 
 func (container *Container) prepareVolumes() error {
@@ -65,20 +82,15 @@ func (container *Container) createVolumes() error {
 	}
 
 	// Get all the bind mounts
-	// track bind paths separately due to #10618
-	bindPaths := make(map[string]struct{})
-	for _, spec := range container.hostConfig.Binds {
-		mnt, err := parseBindMountSpec(spec)
-		if err != nil {
-			return err
-		}
-
-		// #10618
-		if _, exists := bindPaths[mnt.containerPath]; exists {
-			return fmt.Errorf("Duplicate volume mount %s", mnt.containerPath)
+	binds, err := parseMounts(container.hostConfig)
+	if err != nil {
+		return err
+	}
+	for _, mnt := range binds {
+		// skip anonymous volumes which were already created
+		if _, exists := container.Volumes[mnt.containerPath]; exists && mnt.name == "" && mnt.hostPath == "" {
+			continue
 		}
-
-		bindPaths[mnt.containerPath] = struct{}{}
 		mounts[mnt.containerPath] = mnt
 	}
 
@@ -99,7 +111,8 @@ func (container *Container) createVolumes() error {
 		}
 
 		for _, mnt := range c.volumeMounts() {
-			mnt.writable = mnt.writable && (mode == "rw")
+			mnt.writable = mnt.writable && mode.writable
+			mnt.relabel = mode.relabel
 			mnt.from = cID
 			mounts[mnt.containerPath] = mnt
 		}
@@ -111,12 +124,51 @@ func (container *Container) createVolumes() error {
 			return err
 		}
 
+		if mnt.name != "" {
+			v, err := container.daemon.namedVolume(mnt.name, mnt.driver, mnt.driverOpts, mnt.labels)
+			if err != nil {
+				return err
+			}
+			caps, err := container.daemon.volumes.Capabilities(v)
+			if err != nil {
+				return err
+			}
+			if caps.NoCopy {
+				mnt.copyData = false
+			}
+			// Volumes of drivers are mounted to be populated, and then
+			// every time the container starts
+			path, err := container.daemon.volumes.Mount(v)
+			if err != nil {
+				return fmt.Errorf("error mounting volume %s: %v", v.Name, err)
+			}
+			defer func() {
+				if err := container.daemon.volumes.Unmount(v); err != nil {
+					logrus.Errorf("error unmounting volume %s: %v", v.Name, err)
+				}
+			}()
+			mnt.hostPath = path
+		}
+
 		// Create the actual volume
-		v, err := container.daemon.volumes.FindOrCreateVolume(mnt.hostPath, mnt.writable)
+		var v *volumes.Volume
+		if mnt.hostPath == "" {
+			// Anonymous volumes are labeled with the container they are
+			// created for
+			v, err = container.daemon.volumes.Create("", "", nil, map[string]string{volumes.ContainerLabel: container.ID})
+		} else {
+			v, err = container.daemon.volumes.FindOrCreateVolume(mnt.hostPath, mnt.writable)
+		}
 		if err != nil {
 			return err
 		}
 
+		if mnt.relabel != "" {
+			if err := label.Relabel(v.Path, container.MountLabel, mnt.relabel); err != nil {
+				return fmt.Errorf("error relabeling %s: %v", v.Path, err)
+			}
+		}
+
 		container.VolumesRW[mnt.containerPath] = mnt.writable
 		container.Volumes[mnt.containerPath] = v.Path
 		v.AddContainer(container.ID)
@@ -133,6 +185,35 @@ func (container *Container) createVolumes() error {
 	return nil
 }
 
+// mountDriverVolumes mounts the volumes of volume drivers, and the local
+// volumes with a mount of their own, used by the container, updating their
+// paths if the drivers mounted them elsewhere.
+func (container *Container) mountDriverVolumes() error {
+	for dest, path := range container.Volumes {
+		v := container.daemon.volumes.Get(path)
+		if v == nil || v.IsBindMount {
+			continue
+		}
+		mountpoint, err := container.daemon.volumes.Mount(v)
+		if err != nil {
+			return fmt.Errorf("error mounting volume %s: %v", v.Name, err)
+		}
+		container.mountedVolumes = append(container.mountedVolumes, v)
+		container.Volumes[dest] = mountpoint
+	}
+	return nil
+}
+
+// unmountDriverVolumes unmounts the volumes mounted by mountDriverVolumes.
+func (container *Container) unmountDriverVolumes() {
+	for _, v := range container.mountedVolumes {
+		if err := container.daemon.volumes.Unmount(v); err != nil {
+			logrus.Errorf("%v: Failed to unmount volume %s: %v", container.ID, v.Name, err)
+		}
+	}
+	container.mountedVolumes = nil
+}
+
 // sortedVolumeMounts returns the list of container volume mount points sorted in lexicographic order
 func (container *Container) sortedVolumeMounts() []string {
 	var mountPaths []string
@@ -184,10 +265,86 @@ func (container *Container) derefVolumes() {
 	}
 }
 
+// parseMounts returns the bind mounts and volumes of the container, those of
+// the -v specifications followed by those of --mount.
+func parseMounts(hostConfig *runconfig.HostConfig) ([]*volumeMount, error) {
+	var mounts []*volumeMount
+	// track bind paths separately due to #10618
+	bindPaths := make(map[string]struct{})
+	add := func(mnt *volumeMount) error {
+		// #10618
+		if _, exists := bindPaths[mnt.containerPath]; exists {
+			return fmt.Errorf("Duplicate volume mount %s", mnt.containerPath)
+		}
+		bindPaths[mnt.containerPath] = struct{}{}
+		mounts = append(mounts, mnt)
+		return nil
+	}
+
+	for _, spec := range hostConfig.Binds {
+		mnt, err := parseBindMountSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(mnt); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range hostConfig.Mounts {
+		if m.Type == runconfig.MountTypeTmpfs {
+			continue
+		}
+		mnt, err := parseMount(m)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(mnt); err != nil {
+			return nil, err
+		}
+	}
+	return mounts, nil
+}
+
+// parseMount converts a bind or volume mount specified with --mount.
+func parseMount(m runconfig.Mount) (*volumeMount, error) {
+	mnt := &volumeMount{
+		containerPath: filepath.Clean(m.Target),
+		writable:      !m.ReadOnly,
+		relabel:       m.Relabel,
+	}
+	switch m.Type {
+	case runconfig.MountTypeBind:
+		mnt.hostPath = filepath.Clean(m.Source)
+		if o := m.BindOptions; o != nil {
+			if o.Propagation != "" && !propagationModes[o.Propagation] {
+				return nil, fmt.Errorf("invalid mount on %s: invalid propagation mode %s", m.Target, o.Propagation)
+			}
+			mnt.propagation = o.Propagation
+			mnt.recursive = o.ReadOnlyRecursive
+		}
+	case runconfig.MountTypeVolume:
+		if m.Source != "" && !volumes.ValidName(m.Source) {
+			return nil, fmt.Errorf("invalid mount on %s: invalid volume name %s", m.Target, m.Source)
+		}
+		mnt.name = m.Source
+		mnt.copyData = true
+		if o := m.VolumeOptions; o != nil {
+			mnt.copyData = !o.NoCopy
+			mnt.driver = o.Driver
+			mnt.driverOpts = o.DriverOpts
+			mnt.labels = o.Labels
+		}
+	default:
+		return nil, fmt.Errorf("invalid mount on %s: unsupported mount type %q", m.Target, m.Type)
+	}
+	return mnt, nil
+}
+
 func parseBindMountSpec(spec string) (*volumeMount, error) {
 	arr := strings.Split(spec, ":")
 
 	mnt := &volumeMount{}
+	nocopy := false
 	switch len(arr) {
 	case 2:
 		mnt.hostPath = arr[0]
@@ -196,13 +353,38 @@ func parseBindMountSpec(spec string) (*volumeMount, error) {
 	case 3:
 		mnt.hostPath = arr[0]
 		mnt.containerPath = arr[1]
-		mnt.writable = validMountMode(arr[2]) && arr[2] == "rw"
+		mode, err := parseMountMode(arr[2])
+		if err != nil {
+			return nil, err
+		}
+		mnt.writable = mode.writable
+		mnt.propagation = mode.propagation
+		mnt.relabel = mode.relabel
+		mnt.recursive = mode.recursive
+		nocopy = mode.nocopy
 	default:
 		return nil, fmt.Errorf("Invalid volume specification: %s", spec)
 	}
 
 	if !filepath.IsAbs(mnt.hostPath) {
-		return nil, fmt.Errorf("cannot bind mount volume: %s volume paths must be absolute.", mnt.hostPath)
+		if !volumes.ValidName(mnt.hostPath) {
+			return nil, fmt.Errorf("cannot bind mount volume: %s volume paths must be absolute.", mnt.hostPath)
+		}
+		if mnt.propagation != "" {
+			return nil, fmt.Errorf("invalid volume specification: %s, propagation modes only apply to host directories", spec)
+		}
+		if mnt.recursive {
+			return nil, fmt.Errorf("invalid volume specification: %s, rro only applies to host directories", spec)
+		}
+		// Named volumes are populated with the contents of the image,
+		// unless the mode says otherwise
+		mnt.name, mnt.hostPath = mnt.hostPath, ""
+		mnt.copyData = !nocopy
+		mnt.containerPath = filepath.Clean(mnt.containerPath)
+		return mnt, nil
+	}
+	if nocopy {
+		return nil, fmt.Errorf("invalid volume specification: %s, nocopy only applies to named volumes", spec)
 	}
 
 	mnt.hostPath = filepath.Clean(mnt.hostPath)
@@ -210,25 +392,75 @@ func parseBindMountSpec(spec string) (*volumeMount, error) {
 	return mnt, nil
 }
 
-func parseVolumesFromSpec(spec string) (string, string, error) {
+// parseVolumesFromSpec parses a volumes-from specification, the container
+// optionally followed by a colon and a mode overriding those of its
+// volumes: at most one of rw and ro, and at most one of z and Z.
+func parseVolumesFromSpec(spec string) (string, *mountMode, error) {
 	specParts := strings.SplitN(spec, ":", 2)
-	if len(specParts) == 0 {
-		return "", "", fmt.Errorf("malformed volumes-from specification: %s", spec)
+	if specParts[0] == "" {
+		return "", nil, fmt.Errorf("malformed volumes-from specification: %s", spec)
 	}
 
-	var (
-		id   = specParts[0]
-		mode = "rw"
-	)
+	id, mode := specParts[0], &mountMode{writable: true}
 	if len(specParts) == 2 {
-		mode = specParts[1]
-		if !validMountMode(mode) {
-			return "", "", fmt.Errorf("invalid mode for volumes-from: %s", mode)
+		var err error
+		mode, err = parseMountMode(specParts[1])
+		if err != nil || mode.recursive || mode.propagation != "" || mode.nocopy {
+			return "", nil, fmt.Errorf("invalid mode for volumes-from: %s", specParts[1])
 		}
 	}
 	return id, mode, nil
 }
 
+// propagationModes are the propagation modes of bind mounts.
+var propagationModes = map[string]bool{
+	"private":  true,
+	"rprivate": true,
+	"slave":    true,
+	"rslave":   true,
+	"shared":   true,
+	"rshared":  true,
+}
+
+// mountMode is the mode of a bind mount.
+type mountMode struct {
+	writable bool
+	// recursive is set by rro, a read-only mode which also applies to the
+	// mounts below the host directory
+	recursive   bool
+	propagation string
+	// nocopy is set when a named volume is not to be populated with the
+	// contents of the image
+	nocopy bool
+	// relabel is the SELinux relabeling option, z or Z, if any
+	relabel string
+}
+
+// parseMountMode parses the mode of a bind mount, a comma separated list of
+// at most one of rw, ro and rro, the default being rw, of at most one
+// propagation mode, of nocopy, and of at most one of z and Z.
+func parseMountMode(spec string) (*mountMode, error) {
+	mode := &mountMode{writable: true}
+	rwSet := false
+	for _, m := range strings.Split(spec, ",") {
+		switch {
+		case validMountMode(m) && !rwSet:
+			mode.writable, rwSet = m == "rw", true
+		case m == "rro" && !rwSet:
+			mode.writable, mode.recursive, rwSet = false, true, true
+		case propagationModes[m] && mode.propagation == "":
+			mode.propagation = m
+		case m == "nocopy" && !mode.nocopy:
+			mode.nocopy = true
+		case (m == "z" || m == "Z") && mode.relabel == "":
+			mode.relabel = m
+		default:
+			return nil, fmt.Errorf("invalid mode for volume: %s", spec)
+		}
+	}
+	return mode, nil
+}
+
 func validMountMode(mode string) bool {
 	validModes := map[string]bool{
 		"rw": true,
@@ -255,25 +487,88 @@ func (container *Container) specialMounts() []execdriver.Mount {
 func (container *Container) setupMounts() error {
 	mounts := []execdriver.Mount{}
 
+	binds, err := container.bindMounts()
+	if err != nil {
+		return err
+	}
+
 	// Mount user specified volumes
 	// Note, these are not private because you may want propagation of (un)mounts from host
 	// volumes. For instance if you use -v /usr:/usr and the host later mounts /usr/share you
 	// want this new mount in the container
 	// These mounts must be ordered based on the length of the path that it is being mounted to (lexicographic)
 	for _, path := range container.sortedVolumeMounts() {
-		mounts = append(mounts, execdriver.Mount{
+		mnt := execdriver.Mount{
 			Source:      container.Volumes[path],
 			Destination: path,
 			Writable:    container.VolumesRW[path],
-		})
+		}
+		bind, isBind := binds[path]
+		if isBind {
+			mnt.Propagation = bind.propagation
+		}
+		mounts = append(mounts, mnt)
+
+		// A read-only bind mount leaves the mounts it holds writable,
+		// each of them is bound again read-only over itself
+		if isBind && bind.recursive && !mnt.Writable {
+			submounts, err := submountsOf(mnt.Source)
+			if err != nil {
+				return fmt.Errorf("error making %s recursively read-only: %v", path, err)
+			}
+			for _, submount := range submounts {
+				mounts = append(mounts, execdriver.Mount{
+					Source:      submount.source,
+					Destination: filepath.Join(path, submount.path),
+					Writable:    false,
+				})
+			}
+		}
 	}
 
 	mounts = append(mounts, container.specialMounts()...)
 
+	secrets, err := container.secretsMount()
+	if err != nil {
+		return err
+	}
+	mounts = append(mounts, secrets...)
+
 	container.command.Mounts = mounts
 	return nil
 }
 
+// submount is a mount of the host found below the source of a bind mount,
+// at path relative to that source.
+type submount struct {
+	source string
+	path   string
+}
+
+// bindMounts returns the bind mounts and volumes of the container by
+// container path, once their propagation modes are checked against the
+// mounts of the host.
+func (container *Container) bindMounts() (map[string]*volumeMount, error) {
+	mounts, err := parseMounts(container.hostConfig)
+	if err != nil {
+		return nil, err
+	}
+	binds := make(map[string]*volumeMount)
+	for _, mnt := range mounts {
+		if mnt.propagation != "" {
+			if err := checkPropagation(mnt.hostPath, mnt.propagation); err != nil {
+				return nil, err
+			}
+		}
+		binds[mnt.containerPath] = mnt
+	}
+	return binds, nil
+}
+
+// volumeMounts returns the volumes of the container, to be mounted in
+// another with --volumes-from. Volumes are resolved through the repository
+// by name rather than by the path they had in the container, which may be
+// the outdated mountpoint of a volume driver.
 func (container *Container) volumeMounts() map[string]*volumeMount {
 	mounts := make(map[string]*volumeMount)
 
@@ -284,7 +579,13 @@ func (container *Container) volumeMounts() map[string]*volumeMount {
 			logrus.Debugf("reference by container %s to non-existent volume path %s", container.ID, path)
 			continue
 		}
-		mounts[containerPath] = &volumeMount{hostPath: path, containerPath: containerPath, writable: container.VolumesRW[containerPath]}
+		mnt := &volumeMount{containerPath: containerPath, writable: container.VolumesRW[containerPath]}
+		if v.IsBindMount || v.Name == "" {
+			mnt.hostPath = path
+		} else {
+			mnt.name, mnt.driver = v.Name, v.Driver
+		}
+		mounts[containerPath] = mnt
 	}
 
 	return mounts
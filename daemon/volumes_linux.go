@@ -3,8 +3,13 @@
 package daemon
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/pkg/system"
 )
 
@@ -22,3 +27,86 @@ func copyOwnership(source, destination string) error {
 
 	return os.Chmod(destination, os.FileMode(stat.Mode()))
 }
+
+// checkPropagation returns an error if the mount of the host holding source
+// cannot propagate mounts as required by the propagation mode of a bind
+// mount of source: shared modes need a shared mount, slave modes a shared
+// or slave one.
+func checkPropagation(source, propagation string) error {
+	var required []string
+	switch propagation {
+	case "shared", "rshared":
+		required = []string{"shared:"}
+	case "slave", "rslave":
+		required = []string{"shared:", "master:"}
+	default:
+		return nil
+	}
+
+	source, err := filepath.EvalSymlinks(source)
+	if err != nil {
+		return err
+	}
+	mounts, err := mount.GetMounts()
+	if err != nil {
+		return err
+	}
+	m := hostMountOf(source, mounts)
+	if m == nil {
+		return fmt.Errorf("no mount of the host holds %s", source)
+	}
+	for _, tag := range required {
+		if strings.Contains(m.Optional, tag) {
+			return nil
+		}
+	}
+	kind := "shared"
+	if len(required) > 1 {
+		kind = "shared or slave"
+	}
+	return fmt.Errorf("cannot use %s propagation for %s: it is on mount %s, which is not a %s mount", propagation, source, m.Mountpoint, kind)
+}
+
+// hostMountOf returns the mount among mounts which holds path.
+func hostMountOf(path string, mounts []*mount.MountInfo) *mount.MountInfo {
+	var found *mount.MountInfo
+	for _, m := range mounts {
+		if !strings.HasPrefix(path+"/", strings.TrimSuffix(m.Mountpoint, "/")+"/") {
+			continue
+		}
+		if found == nil || len(m.Mountpoint) >= len(found.Mountpoint) {
+			found = m
+		}
+	}
+	return found
+}
+
+// submountsOf returns the mounts of the host below source, parents first.
+func submountsOf(source string) ([]submount, error) {
+	source, err := filepath.EvalSymlinks(source)
+	if err != nil {
+		return nil, err
+	}
+	mounts, err := mount.GetMounts()
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSuffix(source, "/") + "/"
+	var mountpoints []string
+	for _, m := range mounts {
+		if strings.HasPrefix(m.Mountpoint, prefix) {
+			mountpoints = append(mountpoints, m.Mountpoint)
+		}
+	}
+	sort.Strings(mountpoints)
+
+	var submounts []submount
+	for i, mp := range mountpoints {
+		// Stacked mounts share a mountpoint, the top one is bound
+		if i > 0 && mountpoints[i-1] == mp {
+			continue
+		}
+		submounts = append(submounts, submount{source: mp, path: strings.TrimPrefix(mp, prefix)})
+	}
+	return submounts, nil
+}
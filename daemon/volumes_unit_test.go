@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/docker/runconfig"
+)
+
+func TestParseBindMountSpec(t *testing.T) {
+	mnt, err := parseBindMountSpec("/srv/data:/data:ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnt.hostPath != "/srv/data" || mnt.containerPath != "/data" || mnt.writable || mnt.name != "" {
+		t.Fatalf("Expected a read-only bind mount of /srv/data, got %+v", mnt)
+	}
+
+	mnt, err = parseBindMountSpec("data:/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnt.name != "data" || mnt.hostPath != "" || !mnt.writable || !mnt.copyData {
+		t.Fatalf("Expected the named volume data, got %+v", mnt)
+	}
+
+	mnt, err = parseBindMountSpec("/srv/data:/data:ro,rslave")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnt.writable || mnt.propagation != "rslave" {
+		t.Fatalf("Expected a read-only rslave bind mount, got %+v", mnt)
+	}
+
+	mnt, err = parseBindMountSpec("/srv/data:/data:shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mnt.writable || mnt.propagation != "shared" {
+		t.Fatalf("Expected a read-write shared bind mount, got %+v", mnt)
+	}
+
+	mnt, err = parseBindMountSpec("data:/data:ro,nocopy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnt.name != "data" || mnt.writable || mnt.copyData {
+		t.Fatalf("Expected the read-only named volume data without copy, got %+v", mnt)
+	}
+
+	mnt, err = parseBindMountSpec("/srv/data:/data:Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mnt.writable || mnt.relabel != "Z" {
+		t.Fatalf("Expected a read-write bind mount relabeled private, got %+v", mnt)
+	}
+
+	mnt, err = parseBindMountSpec("data:/data:ro,z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnt.name != "data" || mnt.writable || mnt.relabel != "z" {
+		t.Fatalf("Expected the read-only named volume data relabeled shared, got %+v", mnt)
+	}
+
+	mnt, err = parseBindMountSpec("/srv/data:/data:rro,rslave")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnt.writable || !mnt.recursive || mnt.propagation != "rslave" {
+		t.Fatalf("Expected a recursively read-only rslave bind mount, got %+v", mnt)
+	}
+
+	for _, spec := range []string{"./data:/data", "/data", "data:/data:rw:z", "/srv/data:/data:rw,ro", "/srv/data:/data:ro,rro", "data:/data:rro", "/srv/data:/data:shared,slave", "/srv/data:/data:z,Z", "/srv/data:/data:y", "data:/data:rshared", "/srv/data:/data:nocopy", "data:/data:nocopy,nocopy"} {
+		if _, err := parseBindMountSpec(spec); err == nil {
+			t.Fatalf("Expected an error parsing %s", spec)
+		}
+	}
+}
+
+func TestParseMounts(t *testing.T) {
+	hostConfig := &runconfig.HostConfig{
+		Binds: []string{"/srv/data:/data:ro"},
+		Mounts: []runconfig.Mount{
+			{Type: runconfig.MountTypeBind, Source: "/srv/logs/", Target: "/logs", BindOptions: &runconfig.BindOptions{Propagation: "rshared"}},
+			{Type: runconfig.MountTypeVolume, Source: "cache", Target: "/cache", ReadOnly: true, VolumeOptions: &runconfig.VolumeOptions{NoCopy: true, Driver: "local"}},
+			{Type: runconfig.MountTypeVolume, Target: "/scratch"},
+			{Type: runconfig.MountTypeTmpfs, Target: "/run"},
+		},
+	}
+	mounts, err := parseMounts(hostConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mounts) != 4 {
+		t.Fatalf("Expected the tmpfs mount to be skipped, got %d mounts", len(mounts))
+	}
+	if mnt := mounts[1]; mnt.hostPath != "/srv/logs" || mnt.containerPath != "/logs" || !mnt.writable || mnt.recursive || mnt.propagation != "rshared" {
+		t.Fatalf("Expected a bind mount of /srv/logs, got %+v", mnt)
+	}
+	if mnt := mounts[2]; mnt.name != "cache" || mnt.hostPath != "" || mnt.writable || mnt.copyData || mnt.driver != "local" {
+		t.Fatalf("Expected the named volume cache, got %+v", mnt)
+	}
+	if mnt := mounts[3]; mnt.name != "" || mnt.hostPath != "" || !mnt.writable || !mnt.copyData {
+		t.Fatalf("Expected an anonymous volume, got %+v", mnt)
+	}
+
+	recursive := &runconfig.HostConfig{
+		Mounts: []runconfig.Mount{{Type: runconfig.MountTypeBind, Source: "/srv/logs/", Target: "/logs", ReadOnly: true, BindOptions: &runconfig.BindOptions{Propagation: "rshared", ReadOnlyRecursive: true}}},
+	}
+	if mounts, err = parseMounts(recursive); err != nil {
+		t.Fatal(err)
+	}
+	if mnt := mounts[0]; mnt.hostPath != "/srv/logs" || mnt.writable || !mnt.recursive || mnt.propagation != "rshared" {
+		t.Fatalf("Expected a recursively read-only bind mount of /srv/logs, got %+v", mnt)
+	}
+
+	hostConfig.Mounts = append(hostConfig.Mounts, runconfig.Mount{Type: runconfig.MountTypeVolume, Source: "other", Target: "/data/"})
+	if _, err := parseMounts(hostConfig); err == nil {
+		t.Fatal("Expected an error for a mount on the path of a bind mount")
+	}
+	hostConfig.Mounts = []runconfig.Mount{{Type: runconfig.MountTypeBind, Source: "/srv", Target: "/srv", BindOptions: &runconfig.BindOptions{Propagation: "shared,private"}}}
+	if _, err := parseMounts(hostConfig); err == nil {
+		t.Fatal("Expected an error for an invalid propagation mode")
+	}
+}
+
+func TestParseVolumesFromSpec(t *testing.T) {
+	id, mode, err := parseVolumesFromSpec("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "data" || !mode.writable || mode.relabel != "" {
+		t.Fatalf("Expected the volumes of data with their own mode, got %s %+v", id, mode)
+	}
+
+	id, mode, err = parseVolumesFromSpec("data:ro,z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "data" || mode.writable || mode.relabel != "z" {
+		t.Fatalf("Expected the volumes of data read-only and relabeled shared, got %s %+v", id, mode)
+	}
+
+	for _, spec := range []string{"", ":ro", "data:", "data:rw,ro", "data:rro", "data:rshared", "data:nocopy", "data:ro:z"} {
+		if _, _, err := parseVolumesFromSpec(spec); err == nil {
+			t.Fatalf("Expected an error parsing %s", spec)
+		}
+	}
+}
@@ -2,7 +2,17 @@
 
 package daemon
 
+import "fmt"
+
 // Not supported on Windows
 func copyOwnership(source, destination string) error {
 	return nil
 }
+
+func checkPropagation(source, propagation string) error {
+	return fmt.Errorf("propagation mode %s is not supported on Windows", propagation)
+}
+
+func submountsOf(source string) ([]submount, error) {
+	return nil, fmt.Errorf("recursive read-only mounts are not supported on Windows")
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/docker/docker/pkg/homedir"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/pidfile"
+	"github.com/docker/docker/pkg/rootless"
 	"github.com/docker/docker/pkg/signal"
 	"github.com/docker/docker/pkg/system"
 	"github.com/docker/docker/pkg/timeutils"
@@ -79,6 +80,29 @@ func migrateKey() (err error) {
 	return nil
 }
 
+// setRootlessDefaults points the paths of the daemon which are not set on the
+// command line to directories of the user, for a daemon running rootless.
+func setRootlessDefaults() error {
+	if daemonCfg.RemappedRoot != "" {
+		return fmt.Errorf("--rootless and --userns-remap can't be used together")
+	}
+	runtimeDir, err := rootless.RuntimeDir()
+	if err != nil {
+		return err
+	}
+	if !flag.IsSet("g") && !flag.IsSet("-graph") {
+		daemonCfg.Root = rootless.DataDir()
+	}
+	if !flag.IsSet("p") && !flag.IsSet("-pidfile") {
+		daemonCfg.Pidfile = filepath.Join(runtimeDir, "docker.pid")
+	}
+	if !flag.IsSet("H") && !flag.IsSet("-host") {
+		flHosts = []string{"unix://" + filepath.Join(runtimeDir, "docker.sock")}
+	}
+	*flTrustKey = filepath.Join(rootless.ConfigDir(), defaultTrustKeyFile)
+	return nil
+}
+
 func mainDaemon() {
 	if flag.NArg() != 0 {
 		flag.Usage()
@@ -87,6 +111,23 @@ func mainDaemon() {
 
 	logrus.SetFormatter(&logrus.TextFormatter{TimestampFormat: timeutils.RFC3339NanoFixed})
 
+	if daemonCfg.Rootless {
+		if err := setRootlessDefaults(); err != nil {
+			logrus.Fatalf("Error starting daemon: %v", err)
+		}
+		// the daemon runs in the namespaces set up by its re-execution
+		if !rootless.IsChild() {
+			status, err := rootless.Run()
+			if err != nil {
+				logrus.Fatalf("Error starting daemon: %v", err)
+			}
+			os.Exit(status)
+		}
+		if err := rootless.Setup(); err != nil {
+			logrus.Fatalf("Error starting daemon: %v", err)
+		}
+	}
+
 	var pfile *pidfile.PidFile
 	if daemonCfg.Pidfile != "" {
 		pf, err := pidfile.New(daemonCfg.Pidfile)
@@ -102,16 +143,18 @@ func mainDaemon() {
 	}
 
 	serverConfig := &apiserver.ServerConfig{
-		Logging:     true,
-		EnableCors:  daemonCfg.EnableCors,
-		CorsHeaders: daemonCfg.CorsHeaders,
-		Version:     dockerversion.VERSION,
-		SocketGroup: daemonCfg.SocketGroup,
-		Tls:         *flTls,
-		TlsVerify:   *flTlsVerify,
-		TlsCa:       *flCa,
-		TlsCert:     *flCert,
-		TlsKey:      *flKey,
+		Logging:              true,
+		EnableCors:           daemonCfg.EnableCors,
+		CorsHeaders:          daemonCfg.CorsHeaders,
+		Version:              dockerversion.VERSION,
+		SocketGroup:          daemonCfg.SocketGroup,
+		Tls:                  *flTls,
+		TlsVerify:            *flTlsVerify,
+		TlsCa:                *flCa,
+		TlsCert:              *flCert,
+		TlsKey:               *flKey,
+		TlsCrl:               daemonCfg.TlsCrl,
+		AuthorizationPlugins: daemonCfg.AuthorizationPlugins,
 	}
 
 	api := apiserver.New(serverConfig)
@@ -129,8 +172,10 @@ func mainDaemon() {
 		serveAPIWait <- nil
 	}()
 
-	if err := migrateKey(); err != nil {
-		logrus.Fatal(err)
+	if !daemonCfg.Rootless {
+		if err := migrateKey(); err != nil {
+			logrus.Fatal(err)
+		}
 	}
 	daemonCfg.TrustKeyPath = *flTrustKey
 
@@ -58,6 +58,13 @@ func main() {
 		os.Setenv("DEBUG", "1")
 	}
 
+	if !*flDaemon && *flEndpoint != "" {
+		if err := setEndpointFlags(*flEndpoint); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
 	if len(flHosts) == 0 {
 		defaultHost := os.Getenv("DOCKER_HOST")
 		if defaultHost == "" || *flDaemon {
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/docker/cliconfig"
+	"github.com/docker/docker/opts"
+	"github.com/docker/docker/pkg/homedir"
+	flag "github.com/docker/docker/pkg/mflag"
+)
+
+// setEndpointFlags sets the host and TLS flags to the ones of the endpoint
+// name of the client config file, in place of DOCKER_HOST, DOCKER_TLS_VERIFY
+// and DOCKER_CERT_PATH. The flags set on the command line take precedence.
+func setEndpointFlags(name string) error {
+	configFile, err := cliconfig.Load(filepath.Join(homedir.Get(), ".docker"))
+	if err != nil {
+		return err
+	}
+	endpoint, ok := configFile.Endpoints[name]
+	if !ok {
+		return fmt.Errorf("No endpoint %q in %s", name, configFile.Filename())
+	}
+
+	if len(flHosts) == 0 {
+		host, err := opts.ValidateHost(endpoint.Host)
+		if err != nil {
+			return fmt.Errorf("Invalid host of endpoint %q: %v", name, err)
+		}
+		flHosts = append(flHosts, host)
+	}
+
+	if !flag.IsSet("-tls") {
+		*flTls = endpoint.TLS
+	}
+	if !flag.IsSet("-tlsverify") {
+		*flTlsVerify = endpoint.TLSVerify
+	}
+
+	certPath := endpoint.CertPath
+	if certPath == "" {
+		certPath = filepath.Join(homedir.Get(), ".docker")
+	}
+	for _, f := range []struct {
+		value      *string
+		name, file string
+	}{
+		{flCa, "-tlscacert", defaultCaFile},
+		{flCert, "-tlscert", defaultCertFile},
+		{flKey, "-tlskey", defaultKeyFile},
+	} {
+		if !flag.IsSet(f.name) {
+			*f.value = filepath.Join(certPath, f.file)
+		}
+	}
+	return nil
+}
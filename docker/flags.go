@@ -31,6 +31,7 @@ var (
 		{"attach", "Attach to a running container"},
 		{"build", "Build an image from a Dockerfile"},
 		{"commit", "Create a new image from a container's changes"},
+		{"completion", "Output a shell completion script"},
 		{"cp", "Copy files/folders from a container's filesystem to the host path"},
 		{"create", "Create a new container"},
 		{"diff", "Inspect changes on a container's filesystem"},
@@ -47,6 +48,7 @@ var (
 		{"login", "Register or log in to a Docker registry server"},
 		{"logout", "Log out from a Docker registry server"},
 		{"logs", "Fetch the logs of a container"},
+		{"network", "Manage networks"},
 		{"port", "Lookup the public-facing port that is NAT-ed to PRIVATE_PORT"},
 		{"pause", "Pause all processes within a container"},
 		{"ps", "List containers"},
@@ -59,13 +61,16 @@ var (
 		{"run", "Run a command in a new container"},
 		{"save", "Save an image to a tar archive"},
 		{"search", "Search for an image on the Docker Hub"},
+		{"secret", "Manage secrets"},
 		{"start", "Start a stopped container"},
 		{"stats", "Display a stream of a containers' resource usage statistics"},
 		{"stop", "Stop a running container"},
+		{"system", "Manage Docker"},
 		{"tag", "Tag an image into a repository"},
 		{"top", "Lookup the running processes of a container"},
 		{"unpause", "Unpause a paused container"},
 		{"version", "Show the Docker version information"},
+		{"volume", "Manage volumes"},
 		{"wait", "Block until a container stops, then print its exit code"},
 	}
 )
@@ -92,6 +97,7 @@ var (
 	flTls       = flag.Bool([]string{"-tls"}, false, "Use TLS; implied by --tlsverify")
 	flHelp      = flag.Bool([]string{"h", "-help"}, false, "Print usage")
 	flTlsVerify = flag.Bool([]string{"-tlsverify"}, dockerTlsVerify, "Use TLS and verify the remote")
+	flEndpoint  = flag.String([]string{"-endpoint"}, os.Getenv("DOCKER_ENDPOINT"), "Daemon endpoint of the config file to connect to")
 
 	// these are initialized in init() below since their default values depend on dockerCertPath which isn't fully initialized until init() runs
 	flTrustKey *string
@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/docker/distribution/digest"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/registry"
+	"github.com/docker/docker/trust"
+	"github.com/docker/libtrust"
+)
+
+// policyScope returns the name of the repository of repoInfo including its
+// registry, which the scopes of the image policy match.
+func policyScope(repoInfo *registry.RepositoryInfo) string {
+	return repoInfo.Index.Name + "/" + repoInfo.RemoteName
+}
+
+// manifestProvenance returns the provenance of the image of the manifest
+// pulled from the repository scope, whose signatures are already verified.
+func manifestProvenance(scope string, manifestBytes []byte, verified bool) (*trust.Provenance, error) {
+	sig, err := libtrust.ParsePrettySignature(manifestBytes, "signatures")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing payload: %s", err)
+	}
+	keys, err := sig.Verify()
+	if err != nil {
+		return nil, fmt.Errorf("error verifying payload: %s", err)
+	}
+	payload, err := sig.Payload()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving payload: %s", err)
+	}
+	dgst, err := digest.FromBytes(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	prov := &trust.Provenance{
+		Repository: scope,
+		Digest:     dgst.String(),
+		Trusted:    verified,
+	}
+	for _, key := range keys {
+		prov.Signers = append(prov.Signers, key.KeyID())
+	}
+	return prov, nil
+}
+
+// checkPullPolicy returns an error if the image policy rejects the images of
+// the repository scope, or the image of provenance prov pulled from it.
+func (store *TagStore) checkPullPolicy(name, scope string, prov *trust.Provenance) error {
+	if store.policy == nil {
+		return nil
+	}
+	if err := store.policy.Requirements(scope).Check(prov); err != nil {
+		return trust.PolicyError{Name: name, Reason: err.Error()}
+	}
+	return nil
+}
+
+// pullRequiresManifest returns whether the image policy only accepts the
+// images of the repository scope pulled with a manifest.
+func (store *TagStore) pullRequiresManifest(scope string) bool {
+	return store.policy != nil && !store.policy.Requirements(scope).IsEmpty()
+}
+
+// CheckImagePolicy returns an error if the image policy of the daemon
+// rejects running img, referred to by name. The requirements of the
+// repository of name apply, or of the repositories img was pulled from if
+// name is an image ID.
+func (store *TagStore) CheckImagePolicy(name string, img *image.Image) error {
+	if store.policy == nil {
+		return nil
+	}
+	store.Lock()
+	provs := store.Provenance[img.ID]
+	store.Unlock()
+
+	var reqs []*trust.Requirements
+	repoName, _ := parsers.ParseRepositoryTag(name)
+	if repo, err := store.Get(repoName); err == nil && repo != nil {
+		repoInfo, err := store.registryService.ResolveRepository(repoName)
+		if err != nil {
+			return err
+		}
+		reqs = append(reqs, store.policy.Requirements(policyScope(repoInfo)))
+	} else if len(provs) > 0 {
+		for _, prov := range provs {
+			reqs = append(reqs, store.policy.Requirements(prov.Repository))
+		}
+	} else {
+		reqs = append(reqs, &store.policy.Default)
+	}
+
+	// each requirement must be met by one of the provenances of the image
+	for _, r := range reqs {
+		err := r.Check(nil)
+		for _, prov := range provs {
+			if err = r.Check(prov); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return trust.PolicyError{Name: name, Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// setProvenance records prov as a provenance of the image id, replacing any
+// previous one from the same repository.
+func (store *TagStore) setProvenance(id string, prov *trust.Provenance) error {
+	store.Lock()
+	defer store.Unlock()
+	if err := store.reload(); err != nil {
+		return err
+	}
+	if store.Provenance == nil {
+		store.Provenance = make(map[string][]*trust.Provenance)
+	}
+	provs := []*trust.Provenance{prov}
+	for _, p := range store.Provenance[id] {
+		if p.Repository != prov.Repository {
+			provs = append(provs, p)
+		}
+	}
+	store.Provenance[id] = provs
+	return store.save()
+}
+
+// deleteProvenance forgets the provenances of the image id.
+func (store *TagStore) deleteProvenance(id string) error {
+	store.Lock()
+	defer store.Unlock()
+	if err := store.reload(); err != nil {
+		return err
+	}
+	if _, exists := store.Provenance[id]; !exists {
+		return nil
+	}
+	delete(store.Provenance, id)
+	return store.save()
+}
@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/docker/distribution/digest"
+	"github.com/docker/docker/registry"
+	"github.com/docker/docker/trust"
+	"github.com/docker/docker/utils"
+	"github.com/docker/libtrust"
+)
+
+func TestManifestProvenance(t *testing.T) {
+	key, err := libtrust.GenerateECP256PrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte(`{"name": "prod/app", "tag": "latest", "schemaVersion": 1}`)
+	js, err := libtrust.NewJSONSignature(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := js.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	manifestBytes, err := js.PrettySignature("signatures")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prov, err := manifestProvenance("registry.example.com/prod/app", manifestBytes, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dgst, _ := digest.FromBytes(payload)
+	if prov.Repository != "registry.example.com/prod/app" || prov.Digest != dgst.String() || prov.Trusted {
+		t.Fatalf("Unexpected provenance %v", prov)
+	}
+	if len(prov.Signers) != 1 || prov.Signers[0] != key.KeyID() {
+		t.Fatalf("Expected the manifest to be signed by %s, got %v", key.KeyID(), prov.Signers)
+	}
+}
+
+func TestCheckImagePolicy(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+	store.registryService = registry.NewService(nil)
+
+	img, err := store.LookupImage(testPrivateImageName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CheckImagePolicy(testPrivateImageName, img); err != nil {
+		t.Fatalf("Expected any image to run without a policy, got %v", err)
+	}
+
+	store.policy = &trust.Policy{
+		Repositories: map[string]*trust.Requirements{
+			"127.0.0.1:8000": {Digests: []string{testPrivateImageDigest}},
+		},
+	}
+	if err := store.CheckImagePolicy(testPrivateImageName, img); err == nil || !strings.Contains(err.Error(), "no signed manifest") {
+		t.Fatalf("Expected an image without provenance to be rejected, got %v", err)
+	}
+
+	prov := &trust.Provenance{Repository: "127.0.0.1:8000/privateapp", Digest: testPrivateImageDigest}
+	if err := store.setProvenance(img.ID, prov); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{testPrivateImageName, testPrivateImageIDShort} {
+		if err := store.CheckImagePolicy(name, img); err != nil {
+			t.Fatalf("Expected %s to be accepted, got %v", name, err)
+		}
+	}
+
+	// the policy applies to the repository the image is run as
+	if err := store.Tag("127.0.0.1:8000/other", "", img.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	store.policy.Repositories["127.0.0.1:8000/other"] = &trust.Requirements{Reject: true}
+	if err := store.CheckImagePolicy("127.0.0.1:8000/other", img); err == nil {
+		t.Fatal("Expected an image of a rejected repository to be rejected")
+	}
+
+	if err := store.setProvenance(testOfficialImageID, &trust.Provenance{Repository: "docker.io/library/myapp"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteAll(testOfficialImageID); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := store.Provenance[testOfficialImageID]; exists {
+		t.Fatal("Expected the provenance of a deleted image to be forgotten")
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/docker/docker/pkg/streamformatter"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/registry"
+	"github.com/docker/docker/trust"
 	"github.com/docker/docker/utils"
 )
 
@@ -42,6 +43,11 @@ func (s *TagStore) Pull(image string, tag string, imagePullConfig *ImagePullConf
 		return err
 	}
 
+	scope := policyScope(repoInfo)
+	if s.policy != nil && s.policy.Requirements(scope).Reject {
+		return s.checkPullPolicy(repoInfo.CanonicalName, scope, nil)
+	}
+
 	c, err := s.poolAdd("pull", utils.ImageReference(repoInfo.LocalName, tag))
 	if err != nil {
 		if c != nil {
@@ -79,6 +85,8 @@ func (s *TagStore) Pull(image string, tag string, imagePullConfig *ImagePullConf
 		if err := s.pullV2Repository(r, imagePullConfig.OutStream, repoInfo, tag, sf); err == nil {
 			s.eventsService.Log("pull", logName, "")
 			return nil
+		} else if _, ok := err.(trust.PolicyError); ok {
+			return err
 		} else if err != registry.ErrDoesNotExist && err != ErrV2RegistryUnavailable {
 			logrus.Errorf("Error from V2 registry: %s", err)
 		}
@@ -86,6 +94,11 @@ func (s *TagStore) Pull(image string, tag string, imagePullConfig *ImagePullConf
 		logrus.Debug("image does not exist on v2 registry, falling back to v1")
 	}
 
+	// the images of v1 registries have no manifest to check
+	if s.pullRequiresManifest(scope) {
+		return s.checkPullPolicy(repoInfo.CanonicalName, scope, nil)
+	}
+
 	logrus.Debugf("pulling v1 repository with local name %q", repoInfo.LocalName)
 	if err = s.pullRepository(r, imagePullConfig.OutStream, repoInfo, tag, sf); err != nil {
 		return err
@@ -424,6 +437,16 @@ func (s *TagStore) pullV2Tag(r *registry.Session, out io.Writer, endpoint *regis
 	if verified {
 		logrus.Printf("Image manifest for %s has been verified", utils.ImageReference(repoInfo.CanonicalName, tag))
 	}
+
+	// check the image policy before any layer is pulled
+	prov, err := manifestProvenance(policyScope(repoInfo), manifestBytes, verified)
+	if err != nil {
+		return false, err
+	}
+	if err := s.checkPullPolicy(utils.ImageReference(repoInfo.CanonicalName, tag), prov.Repository, prov); err != nil {
+		return false, err
+	}
+
 	out.Write(sf.FormatStatus(tag, "Pulling from %s", repoInfo.CanonicalName))
 
 	downloads := make([]downloadInfo, len(manifest.FSLayers))
@@ -499,6 +522,12 @@ func (s *TagStore) pullV2Tag(r *registry.Session, out io.Writer, endpoint *regis
 
 				if !verifier.Verified() {
 					logrus.Infof("Image verification failed: checksum mismatch for %q", di.digest.String())
+					if s.pullRequiresManifest(prov.Repository) {
+						return trust.PolicyError{
+							Name:   utils.ImageReference(repoInfo.CanonicalName, tag),
+							Reason: fmt.Sprintf("the layer %s does not match its digest in the manifest", di.digest),
+						}
+					}
 					verified = false
 				}
 
@@ -591,5 +620,10 @@ func (s *TagStore) pullV2Tag(r *registry.Session, out io.Writer, endpoint *regis
 		}
 	}
 
+	prov.Trusted = verified
+	if err = s.setProvenance(downloads[0].img.ID, prov); err != nil {
+		return false, err
+	}
+
 	return tagUpdated, nil
 }
@@ -35,7 +35,10 @@ type TagStore struct {
 	path         string
 	graph        *Graph
 	Repositories map[string]Repository
-	trustKey     libtrust.PrivateKey
+	// Provenance maps the IDs of the pulled images to where they were
+	// pulled from, for the image policy.
+	Provenance map[string][]*trust.Provenance `json:",omitempty"`
+	trustKey   libtrust.PrivateKey
 	sync.Mutex
 	// FIXME: move push/pull-related fields
 	// to a helper type
@@ -44,6 +47,7 @@ type TagStore struct {
 	registryService *registry.Service
 	eventsService   *events.Events
 	trustService    *trust.TrustStore
+	policy          *trust.Policy
 }
 
 type Repository map[string]string
@@ -72,6 +76,7 @@ type TagStoreConfig struct {
 	Registry *registry.Service
 	Events   *events.Events
 	Trust    *trust.TrustStore
+	Policy   *trust.Policy
 }
 
 func NewTagStore(path string, cfg *TagStoreConfig) (*TagStore, error) {
@@ -90,6 +95,7 @@ func NewTagStore(path string, cfg *TagStoreConfig) (*TagStore, error) {
 		registryService: cfg.Registry,
 		eventsService:   cfg.Events,
 		trustService:    cfg.Trust,
+		policy:          cfg.Policy,
 	}
 	// Load the json file if it exists, otherwise create it.
 	if err := store.reload(); os.IsNotExist(err) {
@@ -185,6 +191,9 @@ func (store *TagStore) ImageName(id string) string {
 }
 
 func (store *TagStore) DeleteAll(id string) error {
+	if err := store.deleteProvenance(id); err != nil {
+		return err
+	}
 	names, exists := store.ByID()[id]
 	if !exists || len(names) == 0 {
 		return nil
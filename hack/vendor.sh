@@ -11,8 +11,9 @@ clone() {
 	vcs=$1
 	pkg=$2
 	rev=$3
+	url=$4
 
-	pkg_url=https://$pkg
+	pkg_url=${url:-https://$pkg}
 	target_dir=src/$pkg
 
 	echo -n "$pkg @ $rev: "
@@ -55,6 +56,24 @@ clone git github.com/tchap/go-patricia v2.1.0
 clone hg code.google.com/p/go.net 84a4013f96e0
 clone hg code.google.com/p/gosqlite 74691fb6f837
 
+# only the hashes of golang.org/x/crypto, and the cpu features they use
+clone git golang.org/x/crypto v0.11.0 https://go.googlesource.com/crypto
+clone git golang.org/x/sys v0.10.0 https://go.googlesource.com/sys
+mv src/golang.org/x/crypto/blake2b tmp-blake2b
+mv src/golang.org/x/crypto/sha3 tmp-sha3
+mv src/golang.org/x/crypto/{LICENSE,PATENTS} .
+rm -rf src/golang.org/x/crypto
+mkdir -p src/golang.org/x/crypto
+mv tmp-blake2b src/golang.org/x/crypto/blake2b
+mv tmp-sha3 src/golang.org/x/crypto/sha3
+mv LICENSE PATENTS src/golang.org/x/crypto/
+mv src/golang.org/x/sys/cpu tmp-cpu
+mv src/golang.org/x/sys/{LICENSE,PATENTS} .
+rm -rf src/golang.org/x/sys
+mkdir -p src/golang.org/x/sys
+mv tmp-cpu src/golang.org/x/sys/cpu
+mv LICENSE PATENTS src/golang.org/x/sys/
+
 # get distribution packages
 clone git github.com/docker/distribution d957768537c5af40e4f4cd96871f7b2bde9e2923
 mv src/github.com/docker/distribution/digest tmp-digest
@@ -66,6 +85,11 @@ mkdir -p src/github.com/docker/distribution/registry
 mv tmp-api src/github.com/docker/distribution/registry/api
 
 clone git github.com/docker/libcontainer 90f8aa670f1f424041059060c7c63fe4dee2e441
+# the changes to libcontainer which aren't merged upstream yet
+for patch in ../hack/vendor-patches/libcontainer/*.patch; do
+	echo "github.com/docker/libcontainer: apply $(basename "$patch")"
+	patch -d src/github.com/docker/libcontainer -p1 -s < "$patch"
+done
 # libcontainer deps (see src/github.com/docker/libcontainer/update-vendor.sh)
 clone git github.com/coreos/go-systemd v2
 clone git github.com/godbus/dbus v2
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/go-check/check"
 )
 
@@ -82,3 +84,31 @@ func (s *DockerSuite) TestLogsApiFollowEmptyOutput(c *check.C) {
 		c.Fatalf("HTTP response was not immediate (elapsed %.1fs)", elapsed)
 	}
 }
+
+func (s *DockerSuite) TestLogsApiExtendedFrames(c *check.C) {
+	out, _ := dockerCmd(c, "run", "-d", "busybox", "echo", "hello")
+	id := strings.TrimSpace(out)
+	dockerCmd(c, "wait", id)
+
+	// the frames are plain unless the extended ones are asked for, whatever
+	// the version of the API
+	for _, endpoint := range []string{"/containers/%s/logs?stdout=1", "/v1.19/containers/%s/logs?stdout=1"} {
+		status, body, err := sockRequest("GET", fmt.Sprintf(endpoint, id), nil)
+		c.Assert(err, check.IsNil)
+		c.Assert(status, check.Equals, http.StatusOK)
+		c.Assert(len(body) > stdcopy.StdWriterPrefixLen, check.Equals, true)
+		c.Assert(body[stdcopy.StdWriterFlagsIndex], check.Equals, byte(0))
+		c.Assert(string(body[stdcopy.StdWriterPrefixLen:]), check.Equals, "hello\n")
+	}
+
+	status, body, err := sockRequest("GET", fmt.Sprintf("/containers/%s/logs?stdout=1&extendedframes=1", id), nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(status, check.Equals, http.StatusOK)
+	c.Assert(len(body) > stdcopy.StdWriterPrefixLen, check.Equals, true)
+	c.Assert(body[stdcopy.StdWriterFlagsIndex]&stdcopy.FlagTimestamp, check.Equals, stdcopy.FlagTimestamp)
+
+	var stdout bytes.Buffer
+	_, err = stdcopy.StdCopy(&stdout, ioutil.Discard, bytes.NewReader(body))
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "hello\n")
+}
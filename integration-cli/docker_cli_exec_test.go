@@ -5,6 +5,7 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -659,3 +660,90 @@ func (s *DockerSuite) TestExecWithPrivileged(c *check.C) {
 	}
 
 }
+
+func (s *DockerSuite) TestExecWithCapAddAndDrop(c *check.C) {
+
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--name", "parent", "--cap-drop=ALL", "busybox", "top")
+	if out, _, err := runCommandWithOutput(runCmd); err != nil {
+		c.Fatal(out, err)
+	}
+
+	cmd := exec.Command(dockerBinary, "exec", "--cap-add=MKNOD", "parent", "sh", "-c", "mknod /tmp/sda b 8 0 && echo ok")
+	out, _, err := runCommandWithOutput(cmd)
+	if err != nil {
+		c.Fatal(err, out)
+	}
+	if actual := strings.TrimSpace(out); actual != "ok" {
+		c.Fatalf("exec mknod in --cap-drop=ALL container with --cap-add=MKNOD failed: %v, output: %q", err, out)
+	}
+
+	// the capabilities are only added to the exec'd command
+	cmd = exec.Command(dockerBinary, "exec", "parent", "sh", "-c", "mknod /tmp/sdb b 8 0")
+	out, _, err = runCommandWithOutput(cmd)
+	if err == nil || !strings.Contains(out, "Operation not permitted") {
+		c.Fatalf("exec mknod in --cap-drop=ALL container without --cap-add should fail, output: %q", out)
+	}
+
+	runCmd = exec.Command(dockerBinary, "run", "-d", "--name", "withcaps", "busybox", "top")
+	if out, _, err := runCommandWithOutput(runCmd); err != nil {
+		c.Fatal(out, err)
+	}
+	cmd = exec.Command(dockerBinary, "exec", "--cap-drop=MKNOD", "withcaps", "sh", "-c", "mknod /tmp/sdc b 8 0")
+	out, _, err = runCommandWithOutput(cmd)
+	if err == nil || !strings.Contains(out, "Operation not permitted") {
+		c.Fatalf("exec mknod with --cap-drop=MKNOD should fail, output: %q", out)
+	}
+
+	cmd = exec.Command(dockerBinary, "exec", "--cap-add=NOTACAP", "parent", "true")
+	out, _, err = runCommandWithOutput(cmd)
+	if err == nil || !strings.Contains(out, "Unknown capability") {
+		c.Fatalf("exec with an unknown capability should fail, output: %q", out)
+	}
+
+}
+
+func (s *DockerSuite) TestExecWithCapAddPtrace(c *check.C) {
+	testRequires(c, NativeExecDriver, SameHostDaemon, Seccomp)
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		c.Skip("go is required to build the ptrace fixture")
+	}
+
+	// busybox has no strace, a static binary attaches to the process instead
+	tmp, err := ioutil.TempDir("", "exec-ptrace")
+	if err != nil {
+		c.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	buildCmd := exec.Command(goBinary, "build", "-o", filepath.Join(tmp, "ptrace"), "./fixtures/ptrace")
+	buildCmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+	if out, _, err := runCommandWithOutput(buildCmd); err != nil {
+		c.Fatal(out, err)
+	}
+
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--name", "traced", "-v", tmp+":/fixtures:ro", "busybox", "top")
+	if out, _, err := runCommandWithOutput(runCmd); err != nil {
+		c.Fatal(out, err)
+	}
+
+	// the syscalls of the added capabilities are allowed by the default
+	// seccomp profile of the exec'd process
+	for _, flag := range []string{"--cap-add=SYS_PTRACE", "--privileged"} {
+		cmd := exec.Command(dockerBinary, "exec", flag, "traced", "/fixtures/ptrace", "1")
+		out, _, err := runCommandWithOutput(cmd)
+		if err != nil {
+			c.Fatalf("exec ptrace with %s failed: %v, output: %q", flag, err, out)
+		}
+		if actual := strings.TrimSpace(out); actual != "ok" {
+			c.Fatalf("exec ptrace with %s failed, output: %q", flag, out)
+		}
+	}
+
+	// the processes with the capabilities of the container aren't
+	cmd := exec.Command(dockerBinary, "exec", "traced", "/fixtures/ptrace", "1")
+	out, _, err := runCommandWithOutput(cmd)
+	if err == nil || !strings.Contains(out, "operation not permitted") {
+		c.Fatalf("exec ptrace without --cap-add=SYS_PTRACE should fail, output: %q", out)
+	}
+
+}
@@ -1136,6 +1136,19 @@ func (s *DockerSuite) TestRunUnprivilegedCannotMount(c *check.C) {
 	}
 }
 
+// The default seccomp profile allows the syscalls of the capabilities added
+func (s *DockerSuite) TestRunCapAddSysAdminCanMount(c *check.C) {
+	cmd := exec.Command(dockerBinary, "run", "--cap-add=SYS_ADMIN", "busybox", "sh", "-c", "mount -t tmpfs none /tmp && umount /tmp && echo ok")
+	out, _, err := runCommandWithOutput(cmd)
+	if err != nil {
+		c.Fatal(err, out)
+	}
+
+	if actual := strings.Trim(out, "\r\n"); actual != "ok" {
+		c.Fatalf("expected output ok received %s", actual)
+	}
+}
+
 func (s *DockerSuite) TestRunSysNotWritableInNonPrivilegedContainers(c *check.C) {
 	cmd := exec.Command(dockerBinary, "run", "busybox", "touch", "/sys/kernel/profiling")
 	if code, err := runCommand(cmd); err == nil || code == 0 {
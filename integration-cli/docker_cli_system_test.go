@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/go-check/check"
+)
+
+func (s *DockerSuite) TestSystemDf(c *check.C) {
+	dockerCmd(c, "run", "--name", "dftest", "-v", "dfdata:/data", "busybox", "sh", "-c", "echo hello > /data/file")
+	defer dockerCmd(c, "volume", "rm", "dfdata")
+
+	out, _ := dockerCmd(c, "system", "df")
+	for _, s := range []string{"TYPE", "RECLAIMABLE", "Images", "Containers", "Local Volumes"} {
+		if !strings.Contains(out, s) {
+			c.Fatalf("Expected %q in the output of docker system df:\n%s", s, out)
+		}
+	}
+
+	out, _ = dockerCmd(c, "system", "df", "--format", "{{json .}}")
+	var du types.DiskUsage
+	if err := json.Unmarshal([]byte(out), &du); err != nil {
+		c.Fatal(err)
+	}
+	if du.Images.TotalCount < 1 || du.Images.Active < 1 || du.Images.Size <= 0 {
+		c.Fatalf("Expected the busybox image to be used, got %+v", du.Images)
+	}
+	if du.Containers.TotalCount < 1 || du.Containers.Reclaimable > du.Containers.Size {
+		c.Fatalf("Expected the stopped container, got %+v", du.Containers)
+	}
+	if du.Volumes.TotalCount < 1 || du.Volumes.Size < int64(len("hello\n")) {
+		c.Fatalf("Expected the size of the volume, got %+v", du.Volumes)
+	}
+
+	// the volume is reclaimable once the container is removed
+	dockerCmd(c, "rm", "dftest")
+	out, _ = dockerCmd(c, "system", "df", "--format", "{{.Volumes.Reclaimable}}")
+	if strings.TrimSpace(out) == "0" {
+		c.Fatal("Expected the unused volume to be reclaimable")
+	}
+}
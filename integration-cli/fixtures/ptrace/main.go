@@ -0,0 +1,42 @@
+// +build linux
+
+// ptrace attaches to the process of the pid given as argument and detaches
+// from it, printing "ok" once it traced it, like strace -p does.
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: ptrace PID")
+		os.Exit(2)
+	}
+	pid, err := strconv.Atoi(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// the tracer is the thread which attached
+	runtime.LockOSThread()
+	if err := syscall.PtraceAttach(pid); err != nil {
+		fmt.Fprintf(os.Stderr, "ptrace attach: %v\n", err)
+		os.Exit(1)
+	}
+	var status syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &status, syscall.WALL, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "wait: %v\n", err)
+		os.Exit(1)
+	}
+	if err := syscall.PtraceDetach(pid); err != nil {
+		fmt.Fprintf(os.Stderr, "ptrace detach: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
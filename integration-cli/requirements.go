@@ -89,6 +89,13 @@ var (
 		},
 		"Test requires underlying root filesystem not be backed by overlay.",
 	}
+	Seccomp = TestRequirement{
+		func() bool {
+			cmd := exec.Command("grep", "^Seccomp:", "/proc/self/status")
+			return cmd.Run() == nil
+		},
+		"Test requires seccomp support in the kernel.",
+	}
 )
 
 // testRequires checks if the environment satisfies the requirements
@@ -20,6 +20,9 @@ const (
 type PortBinding struct {
 	HostIp   string
 	HostPort string
+	// NoProxy publishes the port with iptables rules only, without a
+	// userland proxy.
+	NoProxy bool `json:",omitempty"`
 }
 
 type PortMap map[Port][]PortBinding
@@ -79,7 +82,7 @@ func SplitProtoPort(rawPort string) (string, string) {
 }
 
 func validateProto(proto string) bool {
-	for _, availableProto := range []string{"tcp", "udp"} {
+	for _, availableProto := range []string{"tcp", "udp", "sctp"} {
 		if availableProto == proto {
 			return true
 		}
@@ -98,10 +101,34 @@ func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding,
 	for _, rawPort := range ports {
 		proto := "tcp"
 
+		// Options follow the port spec, e.g. 8080:80/tcp,noproxy
+		var noProxy bool
+		if i := strings.Index(rawPort, ","); i != -1 {
+			for _, opt := range strings.Split(rawPort[i+1:], ",") {
+				switch opt {
+				case "noproxy":
+					noProxy = true
+				default:
+					return nil, nil, fmt.Errorf("Invalid port option: %s", opt)
+				}
+			}
+			rawPort = rawPort[:i]
+		}
+
 		if i := strings.LastIndex(rawPort, "/"); i != -1 {
 			proto = rawPort[i+1:]
 			rawPort = rawPort[:i]
 		}
+		// IPv6 host ips contain colons and must be enclosed in brackets,
+		// e.g. [::1]:80:80 or [::1]::80
+		var bracketedIp string
+		if strings.HasPrefix(rawPort, "[") {
+			i := strings.Index(rawPort, "]:")
+			if i == -1 || len(strings.Split(rawPort[i+2:], ":")) != 2 {
+				return nil, nil, fmt.Errorf("Invalid format to parse.  %s should match template %s", rawPort, PortSpecTemplate)
+			}
+			bracketedIp, rawPort = rawPort[1:i], rawPort[i+1:]
+		}
 		if !strings.Contains(rawPort, ":") {
 			rawPort = fmt.Sprintf("::%s", rawPort)
 		} else if len(strings.Split(rawPort, ":")) == 2 {
@@ -118,6 +145,9 @@ func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding,
 			rawIp         = parts["ip"]
 			hostPort      = parts["hostPort"]
 		)
+		if bracketedIp != "" {
+			rawIp = bracketedIp
+		}
 
 		if rawIp != "" && net.ParseIP(rawIp) == nil {
 			return nil, nil, fmt.Errorf("Invalid ip address: %s", rawIp)
@@ -139,6 +169,15 @@ func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding,
 			}
 		}
 
+		// A single host port paired with a range of container ports is the
+		// start of a contiguous host range of the same size.
+		if hostPort != "" && startHostPort == endHostPort && endPort != startPort {
+			endHostPort = startHostPort + (endPort - startPort)
+			if endHostPort > 65535 {
+				return nil, nil, fmt.Errorf("Invalid hostPort: %s, range exceeds 65535", hostPort)
+			}
+		}
+
 		if hostPort != "" && (endPort-startPort) != (endHostPort-startHostPort) {
 			return nil, nil, fmt.Errorf("Invalid ranges specified for container and host Ports: %s and %s", containerPort, hostPort)
 		}
@@ -160,6 +199,7 @@ func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding,
 			binding := PortBinding{
 				HostIp:   rawIp,
 				HostPort: hostPort,
+				NoProxy:  noProxy,
 			}
 			bslice, exists := bindings[port]
 			if !exists {
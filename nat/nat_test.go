@@ -1,6 +1,7 @@
 package nat
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -200,6 +201,26 @@ func TestParsePortSpecs(t *testing.T) {
 	}
 }
 
+func TestParsePortSpecsIPv6HostIp(t *testing.T) {
+	_, bindingMap, err := ParsePortSpecs([]string{"[::1]:8080:80/tcp", "[2001:db8::1]::53/udp"})
+	if err != nil {
+		t.Fatalf("Error while processing ParsePortSpecs: %s", err)
+	}
+
+	if bindings := bindingMap["80/tcp"]; len(bindings) != 1 || bindings[0].HostIp != "::1" || bindings[0].HostPort != "8080" {
+		t.Fatalf("Expected a single binding to [::1]:8080, got %v", bindings)
+	}
+	if bindings := bindingMap["53/udp"]; len(bindings) != 1 || bindings[0].HostIp != "2001:db8::1" || bindings[0].HostPort != "" {
+		t.Fatalf("Expected a single binding to [2001:db8::1], got %v", bindings)
+	}
+
+	for _, spec := range []string{"[::1]:80", "[::1:80:80", "[::1]80:80", "[fe80::zz]:80:80"} {
+		if _, _, err := ParsePortSpecs([]string{spec}); err == nil {
+			t.Fatalf("Received no error while parsing invalid spec %s", spec)
+		}
+	}
+}
+
 func TestParsePortSpecsWithRange(t *testing.T) {
 	var (
 		portMap    map[Port]struct{}
@@ -285,9 +306,68 @@ func TestParsePortSpecsWithRange(t *testing.T) {
 		}
 	}
 
+	portMap, bindingMap, err = ParsePortSpecs([]string{"8000:1234-1236/tcp"})
+
+	if err != nil {
+		t.Fatalf("Error while processing ParsePortSpecs: %s", err)
+	}
+
+	for i, port := range []Port{"1234/tcp", "1235/tcp", "1236/tcp"} {
+		if _, ok := portMap[port]; !ok {
+			t.Fatalf("%s was not parsed properly", port)
+		}
+		bindings := bindingMap[port]
+		if expected := fmt.Sprintf("%d", 8000+i); len(bindings) != 1 || bindings[0].HostPort != expected {
+			t.Fatalf("Expect single binding to host port %s for %s but found %v", expected, port, bindings)
+		}
+	}
+
+	_, _, err = ParsePortSpecs([]string{"65534:1234-1236/tcp"})
+
+	if err == nil {
+		t.Fatal("Received no error while trying to parse a host port range exceeding 65535")
+	}
+
 	_, _, err = ParsePortSpecs([]string{"localhost:1234-1236:1234-1236/tcp"})
 
 	if err == nil {
 		t.Fatal("Received no error while trying to parse a hostname instead of ip")
 	}
 }
+
+func TestParsePortSpecsNoProxy(t *testing.T) {
+	_, bindingMap, err := ParsePortSpecs([]string{"8080:80,noproxy", "53:53/udp,noproxy", "8000-8001:8000-8001/tcp,noproxy", "443:443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, port := range []Port{"80/tcp", "53/udp", "8000/tcp", "8001/tcp"} {
+		if b := bindingMap[port]; len(b) != 1 || !b[0].NoProxy {
+			t.Fatalf("Expected %s to be published without proxy, got %v", port, b)
+		}
+	}
+	if b := bindingMap["443/tcp"]; len(b) != 1 || b[0].NoProxy || b[0].HostPort != "443" {
+		t.Fatalf("Unexpected binding of 443/tcp %v", b)
+	}
+
+	if _, _, err := ParsePortSpecs([]string{"8080:80/tcp,proxyless"}); err == nil {
+		t.Fatal("Expected an error for an unknown port option")
+	}
+}
+
+func TestParsePortSpecsSCTP(t *testing.T) {
+	portMap, bindingMap, err := ParsePortSpecs([]string{"36412:36412/sctp", "2905-2906/sctp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, port := range []Port{"36412/sctp", "2905/sctp", "2906/sctp"} {
+		if _, ok := portMap[port]; !ok {
+			t.Fatalf("%s was not parsed properly", port)
+		}
+	}
+	if b := bindingMap["36412/sctp"]; len(b) != 1 || b[0].HostPort != "36412" {
+		t.Fatalf("Unexpected binding of 36412/sctp %v", b)
+	}
+	if p := Port("36412/sctp"); p.Proto() != "sctp" || p.Int() != 36412 {
+		t.Fatalf("Unexpected proto %s or port %d", p.Proto(), p.Int())
+	}
+}
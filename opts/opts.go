@@ -48,6 +48,10 @@ func DnsSearchListVar(values *[]string, names []string, usage string) {
 	flag.Var(newListOptsRef(values, ValidateDnsSearch), names, usage)
 }
 
+func DnsOptionListVar(values *[]string, names []string, usage string) {
+	flag.Var(newListOptsRef(values, ValidateDnsOption), names, usage)
+}
+
 func IPVar(value *net.IP, names []string, defaultValue, usage string) {
 	flag.Var(NewIpOpt(value, defaultValue), names, usage)
 }
@@ -176,13 +180,14 @@ func newMapOpt(values map[string]string, validator ValidatorFctType) *MapOpts {
 type ValidatorFctType func(val string) (string, error)
 type ValidatorFctListType func(val string) ([]string, error)
 
+// ValidateLogOpts checks that val is a key=value log opt, the logging driver
+// validates the option itself.
 func ValidateLogOpts(val string) (string, error) {
-	allowedKeys := map[string]string{}
-	vals := strings.Split(val, "=")
-	if allowedKeys[vals[0]] != "" {
-		return val, nil
+	vals := strings.SplitN(val, "=", 2)
+	if len(vals) != 2 || vals[0] == "" {
+		return "", fmt.Errorf("%s is not a valid log opt, expected key=value", val)
 	}
-	return "", fmt.Errorf("%s is not a valid log opt", vals[0])
+	return val, nil
 }
 
 func ValidateAttach(val string) (string, error) {
@@ -260,6 +265,14 @@ func ValidateDnsSearch(val string) (string, error) {
 	return validateDomain(val)
 }
 
+// Validates an option for resolvconf options configuration, e.g. ndots:2
+func ValidateDnsOption(val string) (string, error) {
+	if val = strings.TrimSpace(val); val == "" || strings.ContainsAny(val, " \t") {
+		return "", fmt.Errorf("%q is not a valid DNS option", val)
+	}
+	return val, nil
+}
+
 func validateDomain(val string) (string, error) {
 	if alphaRegexp.FindString(val) == "" {
 		return "", fmt.Errorf("%s is not a valid domain", val)
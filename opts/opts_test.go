@@ -147,6 +147,34 @@ func TestValidateDnsSearch(t *testing.T) {
 	}
 }
 
+func TestValidateDnsOption(t *testing.T) {
+	valid := []string{
+		`ndots:2`,
+		`timeout:3`,
+		`rotate`,
+		` debug `,
+	}
+
+	invalid := []string{
+		``,
+		` `,
+		`ndots: 2`,
+		"ndots:2\ttimeout:3",
+	}
+
+	for _, option := range valid {
+		if ret, err := ValidateDnsOption(option); err != nil || ret == "" {
+			t.Fatalf("ValidateDnsOption(`"+option+"`) got %s %s", ret, err)
+		}
+	}
+
+	for _, option := range invalid {
+		if ret, err := ValidateDnsOption(option); err == nil || ret != "" {
+			t.Fatalf("ValidateDnsOption(`"+option+"`) got %s %s", ret, err)
+		}
+	}
+}
+
 func TestValidateExtraHosts(t *testing.T) {
 	valid := []string{
 		`myhost:192.168.0.1`,
@@ -19,6 +19,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/fileutils"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/pools"
 	"github.com/docker/docker/pkg/promise"
 	"github.com/docker/docker/pkg/system"
@@ -34,6 +35,10 @@ type (
 		Compression     Compression
 		NoLchown        bool
 		Name            string
+		// UIDMaps and GIDMaps map the IDs in the archive, which are the
+		// ones of a container, to the IDs of the files of the host.
+		UIDMaps []idtools.IDMap
+		GIDMaps []idtools.IDMap
 	}
 
 	// Archiver allows the reuse of most utility functions of this package
@@ -58,6 +63,7 @@ const (
 	Bzip2
 	Gzip
 	Xz
+	Zstd
 )
 
 func IsArchive(header []byte) bool {
@@ -75,6 +81,7 @@ func DetectCompression(source []byte) Compression {
 		Bzip2: {0x42, 0x5A, 0x68},
 		Gzip:  {0x1F, 0x8B, 0x08},
 		Xz:    {0xFD, 0x37, 0x7A, 0x58, 0x5A, 0x00},
+		Zstd:  {0x28, 0xB5, 0x2F, 0xFD},
 	} {
 		if len(source) < len(m) {
 			logrus.Debugf("Len too short")
@@ -93,6 +100,12 @@ func xzDecompress(archive io.Reader) (io.ReadCloser, error) {
 	return CmdStream(exec.Command(args[0], args[1:]...), archive)
 }
 
+func zstdDecompress(archive io.Reader) (io.ReadCloser, error) {
+	args := []string{"zstd", "-d", "-c", "-q"}
+
+	return CmdStream(exec.Command(args[0], args[1:]...), archive)
+}
+
 func DecompressStream(archive io.Reader) (io.ReadCloser, error) {
 	p := pools.BufioReader32KPool
 	buf := p.Get(archive)
@@ -122,8 +135,16 @@ func DecompressStream(archive io.Reader) (io.ReadCloser, error) {
 		if err != nil {
 			return nil, err
 		}
-		readBufWrapper := p.NewReadCloserWrapper(buf, xzReader)
-		return readBufWrapper, nil
+		// buf isn't put back into the pool, the command may still be
+		// reading it once its output is closed
+		return xzReader, nil
+	case Zstd:
+		zstdReader, err := zstdDecompress(buf)
+		if err != nil {
+			return nil, err
+		}
+		// like xz, buf isn't put back into the pool
+		return zstdReader, nil
 	default:
 		return nil, fmt.Errorf("Unsupported compression format %s", (&compression).Extension())
 	}
@@ -140,8 +161,8 @@ func CompressStream(dest io.WriteCloser, compression Compression) (io.WriteClose
 		gzWriter := gzip.NewWriter(dest)
 		writeBufWrapper := p.NewWriteCloserWrapper(buf, gzWriter)
 		return writeBufWrapper, nil
-	case Bzip2, Xz:
-		// archive/bzip2 does not support writing, and there is no xz support at all
+	case Bzip2, Xz, Zstd:
+		// archive/bzip2 does not support writing, and there is no xz nor zstd support at all
 		// However, this is not a problem as docker only currently generates gzipped tars
 		return nil, fmt.Errorf("Unsupported compression format %s", (&compression).Extension())
 	default:
@@ -159,6 +180,8 @@ func (compression *Compression) Extension() string {
 		return "tar.gz"
 	case Xz:
 		return "tar.xz"
+	case Zstd:
+		return "tar.zst"
 	}
 	return ""
 }
@@ -169,6 +192,10 @@ type tarAppender struct {
 
 	// for hardlink mapping
 	SeenFiles map[uint64]string
+
+	// for the ownership of the files of a container in a user namespace
+	UIDMaps []idtools.IDMap
+	GIDMaps []idtools.IDMap
 }
 
 // canonicalTarName provides a platform-independent and consistent posix-style
@@ -186,34 +213,44 @@ func canonicalTarName(name string, isDir bool) (string, error) {
 	return name, nil
 }
 
-func (ta *tarAppender) addTarFile(path, name string) error {
+// tarHeader returns the header of the file at path, named name in the
+// archive. A regular file already seen through another of its hard links is
+// given a TypeLink header.
+func (ta *tarAppender) tarHeader(path, name string) (*tar.Header, error) {
 	fi, err := os.Lstat(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	link := ""
 	if fi.Mode()&os.ModeSymlink != 0 {
 		if link, err = os.Readlink(path); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	hdr, err := tar.FileInfoHeader(fi, link)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	hdr.Mode = int64(chmodTarEntry(os.FileMode(hdr.Mode)))
 
 	name, err = canonicalTarName(name, fi.IsDir())
 	if err != nil {
-		return fmt.Errorf("tar: cannot canonicalize path: %v", err)
+		return nil, fmt.Errorf("tar: cannot canonicalize path: %v", err)
 	}
 	hdr.Name = name
 
 	nlink, inode, err := setHeaderForSpecialDevice(hdr, ta, name, fi.Sys())
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if hdr.Uid, err = idtools.ToContainer(hdr.Uid, ta.UIDMaps); err != nil {
+		return nil, err
+	}
+	if hdr.Gid, err = idtools.ToContainer(hdr.Gid, ta.GIDMaps); err != nil {
+		return nil, err
 	}
 
 	// if it's a regular file and has more than 1 link,
@@ -236,6 +273,15 @@ func (ta *tarAppender) addTarFile(path, name string) error {
 		hdr.Xattrs["security.capability"] = string(capability)
 	}
 
+	return hdr, nil
+}
+
+func (ta *tarAppender) addTarFile(path, name string) error {
+	hdr, err := ta.tarHeader(path, name)
+	if err != nil {
+		return err
+	}
+
 	if err := ta.TarWriter.WriteHeader(hdr); err != nil {
 		return err
 	}
@@ -278,13 +324,14 @@ func createTarFile(path, extractDir string, hdr *tar.Header, reader io.Reader, L
 			}
 		}
 
-	case tar.TypeReg, tar.TypeRegA:
-		// Source is regular file
+	case tar.TypeReg, tar.TypeRegA, tar.TypeGNUSparse:
+		// Source is regular file, or a sparse one read with its holes
+		// filled with zeros
 		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, hdrInfo.Mode())
 		if err != nil {
 			return err
 		}
-		if _, err := io.Copy(file, reader); err != nil {
+		if _, err := pools.Copy(file, reader); err != nil {
 			file.Close()
 			return err
 		}
@@ -391,7 +438,7 @@ func Tar(path string, compression Compression) (io.ReadCloser, error) {
 // paths are included in `options.IncludeFiles` (if non-nil) or not in `options.ExcludePatterns`.
 func TarWithOptions(srcPath string, options *TarOptions) (io.ReadCloser, error) {
 
-	patterns, patDirs, exceptions, err := fileutils.CleanPatterns(options.ExcludePatterns)
+	pm, err := fileutils.NewPatternMatcher(options.ExcludePatterns)
 
 	if err != nil {
 		return nil, err
@@ -409,6 +456,8 @@ func TarWithOptions(srcPath string, options *TarOptions) (io.ReadCloser, error)
 			TarWriter: tar.NewWriter(compressWriter),
 			Buffer:    pools.BufioWriter32KPool.Get(nil),
 			SeenFiles: make(map[uint64]string),
+			UIDMaps:   options.UIDMaps,
+			GIDMaps:   options.GIDMaps,
 		}
 		// this buffer is needed for the duration of this piped stream
 		defer pools.BufioWriter32KPool.Put(ta.Buffer)
@@ -417,84 +466,128 @@ func TarWithOptions(srcPath string, options *TarOptions) (io.ReadCloser, error)
 		// during e.g. a diff operation the container can continue
 		// mutating the filesystem and we can see transient errors
 		// from this
+		walkTarFiles(srcPath, options, pm, func(filePath, relFilePath string) error {
+			if err := ta.addTarFile(filePath, relFilePath); err != nil {
+				logrus.Debugf("Can't add file %s to tar: %s", filePath, err)
+			}
+			return nil
+		})
 
-		if options.IncludeFiles == nil {
-			options.IncludeFiles = []string{"."}
+		// Make sure to check the error on Close.
+		if err := ta.TarWriter.Close(); err != nil {
+			logrus.Debugf("Can't close tar writer: %s", err)
+		}
+		if err := compressWriter.Close(); err != nil {
+			logrus.Debugf("Can't close compress writer: %s", err)
 		}
+		if err := pipeWriter.Close(); err != nil {
+			logrus.Debugf("Can't close pipe writer: %s", err)
+		}
+	}()
 
-		seen := make(map[string]bool)
+	return pipeReader, nil
+}
 
-		var renamedRelFilePath string // For when tar.Options.Name is set
-		for _, include := range options.IncludeFiles {
-			filepath.Walk(filepath.Join(srcPath, include), func(filePath string, f os.FileInfo, err error) error {
-				if err != nil {
-					logrus.Debugf("Tar: Can't stat file %s to tar: %s", srcPath, err)
-					return nil
-				}
+// WalkTarWithOptions walks the files TarWithOptions archives from the
+// directory at `srcPath`, in the order they are archived, calling `fn` with
+// their paths and the headers they are archived with, without archiving
+// them. The files which can't be archived are skipped as they are by
+// TarWithOptions, and an error returned by `fn` stops the walk and is
+// returned. `options.Compression` is ignored.
+func WalkTarWithOptions(srcPath string, options *TarOptions, fn func(path string, hdr *tar.Header) error) error {
+	pm, err := fileutils.NewPatternMatcher(options.ExcludePatterns)
+	if err != nil {
+		return err
+	}
 
-				relFilePath, err := filepath.Rel(srcPath, filePath)
-				if err != nil || (relFilePath == "." && f.IsDir()) {
-					// Error getting relative path OR we are looking
-					// at the root path. Skip in both situations.
-					return nil
-				}
+	ta := &tarAppender{
+		SeenFiles: make(map[uint64]string),
+		UIDMaps:   options.UIDMaps,
+		GIDMaps:   options.GIDMaps,
+	}
+	return walkTarFiles(srcPath, options, pm, func(filePath, relFilePath string) error {
+		hdr, err := ta.tarHeader(filePath, relFilePath)
+		if err != nil {
+			logrus.Debugf("Can't add file %s to tar: %s", filePath, err)
+			return nil
+		}
+		return fn(filePath, hdr)
+	})
+}
 
-				skip := false
-
-				// If "include" is an exact match for the current file
-				// then even if there's an "excludePatterns" pattern that
-				// matches it, don't skip it. IOW, assume an explicit 'include'
-				// is asking for that file no matter what - which is true
-				// for some files, like .dockerignore and Dockerfile (sometimes)
-				if include != relFilePath {
-					skip, err = fileutils.OptimizedMatches(relFilePath, patterns, patDirs)
-					if err != nil {
-						logrus.Debugf("Error matching %s", relFilePath, err)
-						return err
-					}
-				}
+// walkTarFiles walks the files of the directory at srcPath TarWithOptions
+// archives, calling add with their paths and the names they are archived
+// with. An error returned by add stops the walk and is returned.
+func walkTarFiles(srcPath string, options *TarOptions, pm *fileutils.PatternMatcher, add func(filePath, relFilePath string) error) error {
+	if options.IncludeFiles == nil {
+		options.IncludeFiles = []string{"."}
+	}
 
-				if skip {
-					if !exceptions && f.IsDir() {
-						return filepath.SkipDir
-					}
-					return nil
-				}
+	seen := make(map[string]bool)
+	var addErr error
 
-				if seen[relFilePath] {
-					return nil
-				}
-				seen[relFilePath] = true
+	var renamedRelFilePath string // For when tar.Options.Name is set
+	for _, include := range options.IncludeFiles {
+		if addErr != nil {
+			break
+		}
+		filepath.Walk(filepath.Join(srcPath, include), func(filePath string, f os.FileInfo, err error) error {
+			if err != nil {
+				logrus.Debugf("Tar: Can't stat file %s to tar: %s", srcPath, err)
+				return nil
+			}
 
-				// Rename the base resource
-				if options.Name != "" && filePath == srcPath+"/"+filepath.Base(relFilePath) {
-					renamedRelFilePath = relFilePath
-				}
-				// Set this to make sure the items underneath also get renamed
-				if options.Name != "" {
-					relFilePath = strings.Replace(relFilePath, renamedRelFilePath, options.Name, 1)
+			relFilePath, err := filepath.Rel(srcPath, filePath)
+			if err != nil || (relFilePath == "." && f.IsDir()) {
+				// Error getting relative path OR we are looking
+				// at the root path. Skip in both situations.
+				return nil
+			}
+
+			skip := false
+
+			// If "include" is an exact match for the current file
+			// then even if there's an "excludePatterns" pattern that
+			// matches it, don't skip it. IOW, assume an explicit 'include'
+			// is asking for that file no matter what - which is true
+			// for some files, like .dockerignore and Dockerfile (sometimes)
+			if include != relFilePath {
+				skip, err = pm.Matches(relFilePath)
+				if err != nil {
+					logrus.Debugf("Error matching %s", relFilePath, err)
+					return err
 				}
+			}
 
-				if err := ta.addTarFile(filePath, relFilePath); err != nil {
-					logrus.Debugf("Can't add file %s to tar: %s", filePath, err)
+			if skip {
+				if !pm.Exceptions() && f.IsDir() {
+					return filepath.SkipDir
 				}
 				return nil
-			})
-		}
+			}
 
-		// Make sure to check the error on Close.
-		if err := ta.TarWriter.Close(); err != nil {
-			logrus.Debugf("Can't close tar writer: %s", err)
-		}
-		if err := compressWriter.Close(); err != nil {
-			logrus.Debugf("Can't close compress writer: %s", err)
-		}
-		if err := pipeWriter.Close(); err != nil {
-			logrus.Debugf("Can't close pipe writer: %s", err)
-		}
-	}()
+			if seen[relFilePath] {
+				return nil
+			}
+			seen[relFilePath] = true
 
-	return pipeReader, nil
+			// Rename the base resource
+			if options.Name != "" && filePath == srcPath+"/"+filepath.Base(relFilePath) {
+				renamedRelFilePath = relFilePath
+			}
+			// Set this to make sure the items underneath also get renamed
+			if options.Name != "" {
+				relFilePath = strings.Replace(relFilePath, renamedRelFilePath, options.Name, 1)
+			}
+
+			if err := add(filePath, relFilePath); err != nil {
+				addErr = err
+				return err
+			}
+			return nil
+		})
+	}
+	return addErr
 }
 
 func Unpack(decompressedArchive io.Reader, dest string, options *TarOptions) error {
@@ -520,6 +613,10 @@ loop:
 		// This keeps "../" as-is, but normalizes "/../" to "/"
 		hdr.Name = filepath.Clean(hdr.Name)
 
+		if err := remapIDs(hdr, options); err != nil {
+			return err
+		}
+
 		for _, exclude := range options.ExcludePatterns {
 			if strings.HasPrefix(hdr.Name, exclude) {
 				continue loop
@@ -583,6 +680,15 @@ loop:
 	return nil
 }
 
+// remapIDs sets the owner of hdr to the host IDs of its container IDs.
+func remapIDs(hdr *tar.Header, options *TarOptions) (err error) {
+	if hdr.Uid, err = idtools.ToHost(hdr.Uid, options.UIDMaps); err != nil {
+		return err
+	}
+	hdr.Gid, err = idtools.ToHost(hdr.Gid, options.GIDMaps)
+	return err
+}
+
 // Untar reads a stream of bytes from `archive`, parses it as a tar archive,
 // and unpacks it into the directory at `dest`.
 // The archive may be compressed with one of the following algorithms:
@@ -706,7 +812,7 @@ func (archiver *Archiver) CopyFileWithTar(src, dst string) (err error) {
 		if err := tw.WriteHeader(hdr); err != nil {
 			return err
 		}
-		if _, err := io.Copy(tw, srcF); err != nil {
+		if _, err := pools.Copy(tw, srcF); err != nil {
 			return err
 		}
 		return nil
@@ -764,7 +870,7 @@ func CmdStream(cmd *exec.Cmd, input io.Reader) (io.ReadCloser, error) {
 	}()
 	// Copy stdout to the returned pipe
 	go func() {
-		_, err := io.Copy(pipeW, stdout)
+		_, err := pools.Copy(pipeW, stdout)
 		if err != nil {
 			pipeW.CloseWithError(err)
 		}
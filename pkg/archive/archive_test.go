@@ -88,6 +88,31 @@ func TestDecompressStreamXz(t *testing.T) {
 	}
 }
 
+func TestDecompressStreamZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd is not installed")
+	}
+	cmd := exec.Command("/bin/sh", "-c", "echo hello > /tmp/archive && zstd -q -f --rm /tmp/archive")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Fail to create an archive file for test : %s.", output)
+	}
+	archive, err := os.Open("/tmp/archive.zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	r, err := DecompressStream(archive)
+	if err != nil {
+		t.Fatalf("Failed to decompress a zstd file.")
+	}
+	defer r.Close()
+	content, err := ioutil.ReadAll(r)
+	if err != nil || string(content) != "hello\n" {
+		t.Fatalf("Expected the zstd file to decompress to %q, got %q (%v)", "hello\n", content, err)
+	}
+}
+
 func TestCompressStreamXzUnsuported(t *testing.T) {
 	dest, err := os.Create("/tmp/dest")
 	if err != nil {
@@ -157,6 +182,13 @@ func TestExtensionXz(t *testing.T) {
 		t.Fatalf("The extension of a bzip2 archive should be 'tar.xz'")
 	}
 }
+func TestExtensionZstd(t *testing.T) {
+	compression := Zstd
+	output := compression.Extension()
+	if output != "tar.zst" {
+		t.Fatalf("The extension of a zstd archive should be 'tar.zst'")
+	}
+}
 
 func TestCmdStreamLargeStderr(t *testing.T) {
 	cmd := exec.Command("/bin/sh", "-c", "dd if=/dev/zero bs=1k count=1000 of=/dev/stderr; echo hello")
@@ -268,6 +300,42 @@ func TestUntarPath(t *testing.T) {
 	}
 }
 
+func TestUntarPathGNUSparse(t *testing.T) {
+	tmpFolder, err := ioutil.TempDir("", "docker-archive-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpFolder)
+	srcFile := path.Join(tmpFolder, "sparse")
+	tarFile := path.Join(tmpFolder, "sparse.tar")
+	// a file of 1MB with data in the middle of holes, archived as a
+	// sparse file of the old GNU format
+	cmd := exec.Command("/bin/sh", "-c", "truncate -s 1M "+srcFile+
+		" && printf hello | dd of="+srcFile+" bs=1 seek=524288 conv=notrunc 2>/dev/null"+
+		" && tar --sparse --format=gnu -C "+tmpFolder+" -cf "+tarFile+" sparse")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("Can't create a GNU sparse tar: %s, %s", err, out)
+	}
+	destFolder := path.Join(tmpFolder, "dest")
+	if err := os.MkdirAll(destFolder, 0740); err != nil {
+		t.Fatal(err)
+	}
+	if err := UntarPath(tarFile, destFolder); err != nil {
+		t.Fatalf("UntarPath shouldn't throw an error, %s.", err)
+	}
+	expected, err := ioutil.ReadFile(srcFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(path.Join(destFolder, "sparse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, expected) {
+		t.Fatalf("Expected the %d bytes of the sparse file, got %d different ones", len(expected), len(content))
+	}
+}
+
 // Do the same test as above but with the destination as file, it should fail
 func TestUntarPathWithDestinationFile(t *testing.T) {
 	tmpFolder, err := ioutil.TempDir("", "docker-archive-test")
@@ -3,8 +3,15 @@
 package archive
 
 import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
+
+	"github.com/docker/docker/pkg/idtools"
 )
 
 func TestCanonicalTarNameForPath(t *testing.T) {
@@ -58,3 +65,71 @@ func TestChmodTarEntry(t *testing.T) {
 		}
 	}
 }
+
+func TestTarUntarWithIDMaps(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("Changing the owner of files needs root")
+	}
+	tmp, err := ioutil.TempDir("", "docker-test-idmaps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755, Uid: 0, Gid: 0},
+		{Name: "dir/file", Typeflag: tar.TypeReg, Mode: 0644, Uid: 1000, Gid: 50},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+
+	maps := []idtools.IDMap{{ContainerID: 0, HostID: 100000, Size: 65536}}
+	options := &TarOptions{UIDMaps: maps, GIDMaps: maps}
+	if err := Untar(buf, tmp, options); err != nil {
+		t.Fatal(err)
+	}
+	for name, ids := range map[string][2]uint32{"dir": {100000, 100000}, "dir/file": {101000, 100050}} {
+		fi, err := os.Lstat(filepath.Join(tmp, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		st := fi.Sys().(*syscall.Stat_t)
+		if st.Uid != ids[0] || st.Gid != ids[1] {
+			t.Fatalf("Expected %s owned by %d:%d, got %d:%d", name, ids[0], ids[1], st.Uid, st.Gid)
+		}
+	}
+
+	rc, err := TarWithOptions(tmp, &TarOptions{UIDMaps: maps, GIDMaps: maps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		if hdr.Name == "dir/file" && (hdr.Uid != 1000 || hdr.Gid != 50) {
+			t.Fatalf("Expected dir/file archived as 1000:50, got %d:%d", hdr.Uid, hdr.Gid)
+		}
+		if hdr.Name == "dir/" && (hdr.Uid != 0 || hdr.Gid != 0) {
+			t.Fatalf("Expected dir archived as 0:0, got %d:%d", hdr.Uid, hdr.Gid)
+		}
+	}
+
+	buf.Reset()
+	tw = tar.NewWriter(buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "unmapped", Typeflag: tar.TypeReg, Mode: 0644, Uid: 70000}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	if err := Untar(buf, tmp, options); err == nil {
+		t.Fatal("Expected an error for an unmapped ID")
+	}
+}
@@ -14,7 +14,13 @@ import (
 	"github.com/docker/docker/pkg/system"
 )
 
-func UnpackLayer(dest string, layer ArchiveReader) (size int64, err error) {
+// UnpackLayer unpacks the uncompressed diff layer into dest, with the IDs
+// of the files mapped by options.
+func UnpackLayer(dest string, layer ArchiveReader, options *TarOptions) (size int64, err error) {
+	if options == nil {
+		options = &TarOptions{}
+	}
+
 	tr := tar.NewReader(layer)
 	trBuf := pools.BufioReader32KPool.Get(tr)
 	defer pools.BufioReader32KPool.Put(trBuf)
@@ -40,6 +46,10 @@ func UnpackLayer(dest string, layer ArchiveReader) (size int64, err error) {
 		// Normalize name, for safety and for a simple is-root check
 		hdr.Name = filepath.Clean(hdr.Name)
 
+		if err := remapIDs(hdr, options); err != nil {
+			return 0, err
+		}
+
 		if !strings.HasSuffix(hdr.Name, "/") {
 			// Not the root directory, ensure that the parent directory exists.
 			// This happened in some tests where an image had a tarfile without any
@@ -164,5 +174,5 @@ func ApplyLayer(dest string, layer ArchiveReader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return UnpackLayer(dest, layer)
+	return UnpackLayer(dest, layer, nil)
 }
@@ -0,0 +1,43 @@
+package authorization
+
+// PluginType is the name of the subsystem authorization plugins implement.
+const PluginType = "AuthZPlugin"
+
+const (
+	requestMethod  = PluginType + ".AuthZReq"
+	responseMethod = PluginType + ".AuthZRes"
+)
+
+// Request is the JSON payload sent to AuthZPlugin.AuthZReq before the daemon
+// handles a request to its API, and to AuthZPlugin.AuthZRes, along with the
+// response, once it handled it.
+type Request struct {
+	// User is the common name of the TLS certificate of the client, empty
+	// when the client did not authenticate.
+	User string `json:",omitempty"`
+	// UserAuthNMethod is how User authenticated, "TLS".
+	UserAuthNMethod string `json:",omitempty"`
+
+	RequestMethod string
+	RequestURI    string
+	// RequestBody is the body of JSON requests up to maxBodySize, empty for
+	// the others.
+	RequestBody []byte `json:",omitempty"`
+	// RequestHeaders are the headers of the request, but the registry
+	// credentials.
+	RequestHeaders map[string]string `json:",omitempty"`
+
+	ResponseStatusCode int `json:",omitempty"`
+	// ResponseBody is the body of JSON responses up to maxBodySize, empty
+	// for the others and for the streamed ones.
+	ResponseBody    []byte            `json:",omitempty"`
+	ResponseHeaders map[string]string `json:",omitempty"`
+}
+
+// Response is the decision of a plugin. Msg explains a denial to the client,
+// Err reports a failure of the plugin, which denies the request too.
+type Response struct {
+	Allow bool
+	Msg   string `json:",omitempty"`
+	Err   string `json:",omitempty"`
+}
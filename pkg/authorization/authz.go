@@ -0,0 +1,126 @@
+// Package authorization checks the requests to the daemon API, and their
+// responses, with authorization plugins.
+//
+// Each plugin is asked in turn whether the daemon handles a request, then
+// whether the client gets the response: the first plugin denying either
+// wins, and a plugin failing denies the request.
+package authorization
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+)
+
+// maxBodySize is the size of the largest body of a request or response sent
+// to the plugins.
+const maxBodySize = 1024 * 1024
+
+// ErrDenied prefixes the errors of the requests and responses denied by a
+// plugin.
+const ErrDenied = "authorization denied by plugin"
+
+// Ctx checks a request to the daemon API, and its response, with the
+// authorization plugins.
+type Ctx struct {
+	plugins []Plugin
+	req     *Request
+}
+
+// NewCtx returns the context checking r with plugins. user is the client
+// sending r, authenticated with authNMethod, both empty for anonymous
+// clients.
+func NewCtx(plugins []Plugin, user, authNMethod string, r *http.Request) *Ctx {
+	return &Ctx{
+		plugins: plugins,
+		req: &Request{
+			User:            user,
+			UserAuthNMethod: authNMethod,
+			RequestMethod:   r.Method,
+			RequestURI:      r.RequestURI,
+			RequestHeaders:  headers(r.Header),
+		},
+	}
+}
+
+// AuthZRequest returns an error if a plugin denies the request r. The JSON
+// body of r is read to be sent to the plugins, and replaced for the handler
+// of the request.
+func (ctx *Ctx) AuthZRequest(r *http.Request) error {
+	if r.Body != nil && isJSON(r.Header) {
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err != nil {
+			return err
+		}
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		if len(body) <= maxBodySize {
+			ctx.req.RequestBody = body
+		}
+	}
+
+	for _, p := range ctx.plugins {
+		res, err := p.AuthZRequest(ctx.req)
+		if err := check(p, res, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AuthZResponse returns an error if a plugin denies the response of the
+// request, of the given status and header. body is nil for the responses
+// which are not JSON, too large or streamed.
+func (ctx *Ctx) AuthZResponse(status int, header http.Header, body []byte) error {
+	ctx.req.ResponseStatusCode = status
+	ctx.req.ResponseHeaders = headers(header)
+	if isJSON(header) {
+		ctx.req.ResponseBody = body
+	}
+
+	for _, p := range ctx.plugins {
+		res, err := p.AuthZResponse(ctx.req)
+		if err := check(p, res, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns the error of a plugin p which answered res and err, if it
+// failed or denied the request.
+func check(p Plugin, res *Response, err error) error {
+	if err != nil {
+		return fmt.Errorf("plugin %s failed with error: %v", p.Name(), err)
+	}
+	if res.Err != "" {
+		return fmt.Errorf("plugin %s failed with error: %s", p.Name(), res.Err)
+	}
+	if !res.Allow {
+		return fmt.Errorf("%s %s: %s", ErrDenied, p.Name(), res.Msg)
+	}
+	return nil
+}
+
+// headers returns the first value of each header of h, leaving out the
+// registry credentials.
+func headers(h http.Header) map[string]string {
+	m := make(map[string]string)
+	for k := range h {
+		if k == "X-Registry-Auth" || k == "X-Registry-Config" {
+			continue
+		}
+		m[k] = h.Get(k)
+	}
+	return m
+}
+
+func isJSON(h http.Header) bool {
+	mimetype, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && mimetype == "application/json"
+}
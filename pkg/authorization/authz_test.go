@@ -0,0 +1,161 @@
+package authorization
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/pkg/plugins"
+)
+
+// setupPlugin returns a plugin answering its requests with decide, and the
+// requests it received.
+func setupPlugin(t *testing.T, decide func(method string, req *Request) *Response) (Plugin, *[]Request, func()) {
+	var received []Request
+	mux := http.NewServeMux()
+	for _, method := range []string{requestMethod, responseMethod} {
+		method := method
+		mux.HandleFunc("/"+method, func(w http.ResponseWriter, r *http.Request) {
+			var req Request
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			received = append(received, req)
+			w.Header().Set("Content-Type", "application/vnd.docker.plugins.v1+json")
+			json.NewEncoder(w).Encode(decide(method, &req))
+		})
+	}
+	server := httptest.NewServer(mux)
+	client, err := plugins.NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authorizationPlugin{name: "test", client: client}, &received, server.Close
+}
+
+func TestAuthZRequest(t *testing.T) {
+	p, received, teardown := setupPlugin(t, func(method string, req *Request) *Response {
+		if strings.Contains(string(req.RequestBody), `"Privileged":true`) {
+			return &Response{Msg: "privileged containers are not allowed"}
+		}
+		return &Response{Allow: true}
+	})
+	defer teardown()
+
+	newRequest := func(body string) *http.Request {
+		r, err := http.NewRequest("POST", "/v1.19/containers/create", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RequestURI = "/v1.19/containers/create"
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-Registry-Auth", "credentials")
+		return r
+	}
+
+	r := newRequest(`{"Image":"busybox"}`)
+	ctx := NewCtx([]Plugin{p}, "alice", "TLS", r)
+	if err := ctx.AuthZRequest(r); err != nil {
+		t.Fatal(err)
+	}
+	req := (*received)[0]
+	if req.User != "alice" || req.UserAuthNMethod != "TLS" || req.RequestMethod != "POST" || req.RequestURI != "/v1.19/containers/create" {
+		t.Fatalf("Unexpected request %+v", req)
+	}
+	if string(req.RequestBody) != `{"Image":"busybox"}` {
+		t.Fatalf("Unexpected request body %q", req.RequestBody)
+	}
+	if _, exists := req.RequestHeaders["X-Registry-Auth"]; exists || req.RequestHeaders["Content-Type"] != "application/json" {
+		t.Fatalf("Unexpected request headers %v", req.RequestHeaders)
+	}
+	// the handler still reads the body
+	if body, _ := ioutil.ReadAll(r.Body); string(body) != `{"Image":"busybox"}` {
+		t.Fatalf("Expected the body to be left for the handler, got %q", body)
+	}
+
+	r = newRequest(`{"Image":"busybox","HostConfig":{"Privileged":true}}`)
+	err := NewCtx([]Plugin{p}, "", "", r).AuthZRequest(r)
+	if err == nil || !strings.HasPrefix(err.Error(), ErrDenied) || !strings.Contains(err.Error(), "privileged containers are not allowed") {
+		t.Fatalf("Expected the request to be denied, got %v", err)
+	}
+
+	// larger bodies are left out
+	large := `{"Image":"` + strings.Repeat("a", maxBodySize) + `"}`
+	r = newRequest(large)
+	if err := NewCtx([]Plugin{p}, "", "", r).AuthZRequest(r); err != nil {
+		t.Fatal(err)
+	}
+	if body := (*received)[len(*received)-1].RequestBody; body != nil {
+		t.Fatalf("Expected no body for a large request, got %d bytes", len(body))
+	}
+	if body, _ := ioutil.ReadAll(r.Body); string(body) != large {
+		t.Fatalf("Expected the whole body to be left for the handler, got %d bytes", len(body))
+	}
+}
+
+func TestAuthZRequestFailure(t *testing.T) {
+	p, _, teardown := setupPlugin(t, func(method string, req *Request) *Response {
+		return &Response{Allow: true, Err: "policy unavailable"}
+	})
+	defer teardown()
+
+	r, _ := http.NewRequest("GET", "/info", nil)
+	if err := NewCtx([]Plugin{p}, "", "", r).AuthZRequest(r); err == nil || !strings.Contains(err.Error(), "policy unavailable") {
+		t.Fatalf("Expected a failing plugin to deny the request, got %v", err)
+	}
+}
+
+func TestResponseModifier(t *testing.T) {
+	p, received, teardown := setupPlugin(t, func(method string, req *Request) *Response {
+		if method == responseMethod && strings.Contains(string(req.ResponseBody), "secret") {
+			return &Response{Msg: "no secrets"}
+		}
+		return &Response{Allow: true}
+	})
+	defer teardown()
+
+	onError := func(w http.ResponseWriter, err error) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+	}
+	respond := func(body string, flush bool) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", "/info", nil)
+		w := httptest.NewRecorder()
+		rm := NewCtx([]Plugin{p}, "", "", r).NewResponseModifier(w, onError)
+		rm.Header().Set("Content-Type", "application/json")
+		rm.WriteHeader(http.StatusCreated)
+		if flush {
+			rm.Flush()
+		}
+		rm.Write([]byte(body))
+		rm.Finish()
+		return w
+	}
+
+	w := respond(`{"Name":"public"}`, false)
+	if w.Code != http.StatusCreated || w.Body.String() != `{"Name":"public"}` || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected response %d %q %v", w.Code, w.Body, w.Header())
+	}
+	req := (*received)[len(*received)-1]
+	if req.ResponseStatusCode != http.StatusCreated || string(req.ResponseBody) != `{"Name":"public"}` {
+		t.Fatalf("Unexpected response sent to the plugin %+v", req)
+	}
+
+	w = respond(`{"Name":"secret"}`, false)
+	if w.Code != http.StatusForbidden || bytes.Contains(w.Body.Bytes(), []byte(`"Name"`)) {
+		t.Fatalf("Expected the response to be denied, got %d %q", w.Code, w.Body)
+	}
+
+	// streamed responses are checked as they start, without their body
+	w = respond(`{"Name":"secret"}`, true)
+	if w.Code != http.StatusCreated || w.Body.String() != `{"Name":"secret"}` || !w.Flushed {
+		t.Fatalf("Unexpected streamed response %d %q", w.Code, w.Body)
+	}
+	if req := (*received)[len(*received)-1]; req.ResponseBody != nil {
+		t.Fatalf("Expected no body for a streamed response, got %q", req.ResponseBody)
+	}
+}
@@ -0,0 +1,73 @@
+package authorization
+
+import (
+	"sync"
+
+	"github.com/docker/docker/pkg/plugins"
+)
+
+// Plugin allows or denies the requests to the daemon API and their
+// responses.
+type Plugin interface {
+	// Name returns the name of the plugin.
+	Name() string
+	// AuthZRequest decides whether the daemon handles the request.
+	AuthZRequest(*Request) (*Response, error)
+	// AuthZResponse decides whether the response is sent to the client.
+	AuthZResponse(*Request) (*Response, error)
+}
+
+// NewPlugins returns the authorization plugins named names, each activated
+// the first time it is asked about a request, so that the daemon can start
+// before them.
+func NewPlugins(names []string) []Plugin {
+	plugins := make([]Plugin, len(names))
+	for i, name := range names {
+		plugins[i] = &authorizationPlugin{name: name}
+	}
+	return plugins
+}
+
+type authorizationPlugin struct {
+	name   string
+	client *plugins.Client
+	lock   sync.Mutex
+}
+
+func (a *authorizationPlugin) Name() string {
+	return a.name
+}
+
+func (a *authorizationPlugin) AuthZRequest(req *Request) (*Response, error) {
+	return a.call(requestMethod, req)
+}
+
+func (a *authorizationPlugin) AuthZResponse(req *Request) (*Response, error) {
+	return a.call(responseMethod, req)
+}
+
+func (a *authorizationPlugin) call(method string, req *Request) (*Response, error) {
+	client, err := a.getClient()
+	if err != nil {
+		return nil, err
+	}
+	res := &Response{}
+	if err := client.Call(method, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (a *authorizationPlugin) getClient() (*plugins.Client, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.client == nil {
+		p, err := plugins.Get(a.name, PluginType)
+		if err != nil {
+			return nil, err
+		}
+		a.client = p.Client
+	}
+	return a.client, nil
+}
@@ -0,0 +1,141 @@
+package authorization
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ResponseModifier holds back the response to a request until the
+// authorization plugins allow it.
+//
+// Whole responses are checked with their body. Responses streamed with
+// Flush, or larger than maxBodySize, are checked without their body before
+// they start. Hijacked connections, such as those of attach, are not
+// checked once the request is allowed.
+type ResponseModifier struct {
+	w       http.ResponseWriter
+	ctx     *Ctx
+	onError func(http.ResponseWriter, error)
+
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	sent     bool  // whether the response is allowed and written through
+	hijacked bool  // whether the connection was hijacked by the handler
+	err      error // the error of the response, once denied
+}
+
+// NewResponseModifier returns a response writer holding back the response to
+// the request of ctx written to w. onError writes the error denying the
+// response to w instead.
+func (ctx *Ctx) NewResponseModifier(w http.ResponseWriter, onError func(http.ResponseWriter, error)) *ResponseModifier {
+	return &ResponseModifier{
+		w:       w,
+		ctx:     ctx,
+		onError: onError,
+		header:  make(http.Header),
+	}
+}
+
+// Header returns the header of the response.
+func (rm *ResponseModifier) Header() http.Header {
+	return rm.header
+}
+
+// WriteHeader sets the status code of the response.
+func (rm *ResponseModifier) WriteHeader(status int) {
+	if rm.sent {
+		rm.w.WriteHeader(status)
+		return
+	}
+	rm.status = status
+}
+
+// Write adds b to the body of the response, sending the response first when
+// it grows larger than maxBodySize.
+func (rm *ResponseModifier) Write(b []byte) (int, error) {
+	if rm.err != nil {
+		return 0, rm.err
+	}
+	if !rm.sent && rm.body.Len()+len(b) > maxBodySize {
+		if err := rm.send(false); err != nil {
+			return 0, err
+		}
+	}
+	if rm.sent {
+		return rm.w.Write(b)
+	}
+	return rm.body.Write(b)
+}
+
+// Flush sends the response, the handler streaming it, then flushes it.
+func (rm *ResponseModifier) Flush() {
+	if err := rm.send(false); err != nil {
+		return
+	}
+	if f, ok := rm.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify returns a channel receiving true when the client goes away.
+func (rm *ResponseModifier) CloseNotify() <-chan bool {
+	if n, ok := rm.w.(http.CloseNotifier); ok {
+		return n.CloseNotify()
+	}
+	return nil
+}
+
+// Hijack hands the connection over to the handler.
+func (rm *ResponseModifier) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rm.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the connection cannot be hijacked")
+	}
+	rm.hijacked = true
+	return hijacker.Hijack()
+}
+
+// Finish sends the response once the handler returned.
+func (rm *ResponseModifier) Finish() {
+	if !rm.hijacked {
+		rm.send(true)
+	}
+}
+
+// send writes the response through once the plugins allow it, with its body
+// if complete, writing the error denying it otherwise.
+func (rm *ResponseModifier) send(complete bool) error {
+	if rm.sent || rm.err != nil {
+		return rm.err
+	}
+	status := rm.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	var body []byte
+	if complete {
+		body = rm.body.Bytes()
+	}
+	if err := rm.ctx.AuthZResponse(status, rm.header, body); err != nil {
+		rm.err = err
+		rm.onError(rm.w, err)
+		return err
+	}
+
+	rm.sent = true
+	for k, v := range rm.header {
+		rm.w.Header()[k] = v
+	}
+	rm.w.WriteHeader(status)
+	if rm.body.Len() > 0 {
+		if _, err := rm.w.Write(rm.body.Bytes()); err != nil {
+			return err
+		}
+		rm.body.Reset()
+	}
+	return nil
+}
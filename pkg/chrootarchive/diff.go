@@ -23,6 +23,13 @@ func applyLayer() {
 	runtime.LockOSThread()
 	flag.Parse()
 
+	var options *archive.TarOptions
+
+	// read the options from the pipe "ExtraFiles"
+	if err := json.NewDecoder(os.NewFile(3, "options")).Decode(&options); err != nil {
+		fatal(err)
+	}
+
 	if err := chroot(flag.Arg(0)); err != nil {
 		fatal(err)
 	}
@@ -36,7 +43,7 @@ func applyLayer() {
 	}
 
 	os.Setenv("TMPDIR", tmpDir)
-	size, err := archive.UnpackLayer("/", os.Stdin)
+	size, err := archive.UnpackLayer("/", os.Stdin, options)
 	os.RemoveAll(tmpDir)
 	if err != nil {
 		fatal(err)
@@ -52,7 +59,16 @@ func applyLayer() {
 	os.Exit(0)
 }
 
+// ApplyLayer parses a diff in the standard layer format from `layer`, and
+// applies it to the directory `dest` in a chroot. Returns the size in bytes
+// of the contents of the layer.
 func ApplyLayer(dest string, layer archive.ArchiveReader) (size int64, err error) {
+	return ApplyLayerWithOptions(dest, layer, nil)
+}
+
+// ApplyLayerWithOptions applies the diff `layer` to the directory `dest`
+// like ApplyLayer, with the IDs of the files mapped by options.
+func ApplyLayerWithOptions(dest string, layer archive.ArchiveReader, options *archive.TarOptions) (size int64, err error) {
 	dest = filepath.Clean(dest)
 	decompressed, err := archive.DecompressStream(layer)
 	if err != nil {
@@ -61,13 +77,35 @@ func ApplyLayer(dest string, layer archive.ArchiveReader) (size int64, err error
 
 	defer decompressed.Close()
 
+	if options == nil {
+		options = &archive.TarOptions{}
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("ApplyLayer pipe failure: %v", err)
+	}
+	defer r.Close()
+
 	cmd := reexec.Command("docker-applyLayer", dest)
 	cmd.Stdin = decompressed
+	cmd.ExtraFiles = append(cmd.ExtraFiles, r)
 
 	outBuf, errBuf := new(bytes.Buffer), new(bytes.Buffer)
 	cmd.Stdout, cmd.Stderr = outBuf, errBuf
 
-	if err = cmd.Run(); err != nil {
+	if err = cmd.Start(); err != nil {
+		w.Close()
+		return 0, fmt.Errorf("ApplyLayer error on re-exec cmd: %v", err)
+	}
+	// write the options to the pipe for the applyLayer exec to read
+	if err := json.NewEncoder(w).Encode(options); err != nil {
+		w.Close()
+		cmd.Wait()
+		return 0, fmt.Errorf("ApplyLayer json encode to pipe failed: %v", err)
+	}
+	w.Close()
+
+	if err = cmd.Wait(); err != nil {
 		return 0, fmt.Errorf("ApplyLayer %s stdout: %s stderr: %s", err, outBuf, errBuf)
 	}
 
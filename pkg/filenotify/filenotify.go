@@ -0,0 +1,49 @@
+// Package filenotify watches files for changes, with inotify when the host
+// supports it, or by polling them otherwise.
+package filenotify
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/go-fsnotify/fsnotify"
+)
+
+// FileWatcher watches files for changes.
+type FileWatcher interface {
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Add(name string) error
+	Remove(name string) error
+	Close() error
+}
+
+// New returns a watcher using inotify, or a watcher polling the files when
+// inotify is not available, e.g. when the instances are exhausted.
+func New() FileWatcher {
+	w, err := NewEventWatcher()
+	if err != nil {
+		logrus.Debugf("Watching files by polling them: %v", err)
+		return NewPollingWatcher()
+	}
+	return w
+}
+
+// NewEventWatcher returns a watcher using inotify.
+func NewEventWatcher() (FileWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &eventWatcher{w}, nil
+}
+
+type eventWatcher struct {
+	*fsnotify.Watcher
+}
+
+func (w *eventWatcher) Events() <-chan fsnotify.Event {
+	return w.Watcher.Events
+}
+
+func (w *eventWatcher) Errors() <-chan error {
+	return w.Watcher.Errors
+}
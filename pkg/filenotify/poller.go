@@ -0,0 +1,153 @@
+package filenotify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-fsnotify/fsnotify"
+)
+
+// pollInterval is the interval between the checks of the watched files.
+var pollInterval = 200 * time.Millisecond
+
+var errPollerClosed = errors.New("the poller is closed")
+
+// poller watches files by checking their size and modification time at
+// regular intervals.
+type poller struct {
+	mu      sync.Mutex
+	watches map[string]chan struct{}
+	events  chan fsnotify.Event
+	errors  chan error
+	closed  bool
+}
+
+// NewPollingWatcher returns a watcher polling the watched files.
+func NewPollingWatcher() FileWatcher {
+	return &poller{
+		watches: make(map[string]chan struct{}),
+		events:  make(chan fsnotify.Event),
+		errors:  make(chan error),
+	}
+}
+
+func (w *poller) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+func (w *poller) Errors() <-chan error {
+	return w.errors
+}
+
+// Add starts watching the file name, which must exist.
+func (w *poller) Add(name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errPollerClosed
+	}
+	if _, exists := w.watches[name]; exists {
+		return fmt.Errorf("%s is already watched", name)
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	w.watches[name] = stop
+	go w.watch(name, fi, stop)
+	return nil
+}
+
+// Remove stops watching the file name.
+func (w *poller) Remove(name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stop, exists := w.watches[name]
+	if !exists {
+		return fmt.Errorf("%s is not watched", name)
+	}
+	close(stop)
+	delete(w.watches, name)
+	return nil
+}
+
+// Close stops watching all the files.
+func (w *poller) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	for name, stop := range w.watches {
+		close(stop)
+		delete(w.watches, name)
+	}
+	return nil
+}
+
+// watch sends the changes of the file name until stop is closed. A file
+// replaced by another one under the same name is reported as renamed, and
+// the new one is watched.
+func (w *poller) watch(name string, last os.FileInfo, stop chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		fi, err := os.Stat(name)
+		var op fsnotify.Op
+		switch {
+		case os.IsNotExist(err):
+			if last == nil {
+				continue
+			}
+			op = fsnotify.Remove
+		case err != nil:
+			if !w.send(stop, nil, err) {
+				return
+			}
+			continue
+		case last == nil:
+			op = fsnotify.Create
+		case !os.SameFile(fi, last):
+			op = fsnotify.Rename
+		case fi.Size() != last.Size() || !fi.ModTime().Equal(last.ModTime()):
+			op = fsnotify.Write
+		case fi.Mode() != last.Mode():
+			op = fsnotify.Chmod
+		default:
+			continue
+		}
+		last = fi
+		if !w.send(stop, &fsnotify.Event{Name: name, Op: op}, nil) {
+			return
+		}
+	}
+}
+
+// send sends the event or the error, unless stop is closed first.
+func (w *poller) send(stop chan struct{}, event *fsnotify.Event, err error) bool {
+	if event != nil {
+		select {
+		case w.events <- *event:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+	select {
+	case w.errors <- err:
+		return true
+	case <-stop:
+		return false
+	}
+}
@@ -0,0 +1,80 @@
+package filenotify
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-fsnotify/fsnotify"
+)
+
+func init() {
+	pollInterval = 10 * time.Millisecond
+}
+
+func assertEvent(t *testing.T, w FileWatcher, op fsnotify.Op) {
+	select {
+	case e := <-w.Events():
+		if e.Op != op {
+			t.Fatalf("Expected %v, got %v", op, e)
+		}
+	case err := <-w.Errors():
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timeout waiting for %v", op)
+	}
+}
+
+func TestPollerEvents(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "poller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	name := filepath.Join(tmpdir, "log")
+	if err := ioutil.WriteFile(name, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewPollingWatcher()
+	defer w.Close()
+	if err := w.Add(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(name); err == nil {
+		t.Fatal("Expected an error watching a file twice")
+	}
+
+	if err := ioutil.WriteFile(name, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	assertEvent(t, w, fsnotify.Write)
+
+	if err := os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+	assertEvent(t, w, fsnotify.Remove)
+	if err := ioutil.WriteFile(name, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	assertEvent(t, w, fsnotify.Create)
+
+	if err := w.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Remove(name); err == nil {
+		t.Fatal("Expected an error removing a file not watched")
+	}
+}
+
+func TestPollerClose(t *testing.T) {
+	w := NewPollingWatcher()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(os.Args[0]); err == nil {
+		t.Fatal("Expected an error adding a file to a closed poller")
+	}
+}
@@ -59,19 +59,12 @@ func CleanPatterns(patterns []string) ([]string, [][]string, bool, error) {
 // Matches returns true if file matches any of the patterns
 // and isn't excluded by any of the subsequent patterns.
 func Matches(file string, patterns []string) (bool, error) {
-	file = filepath.Clean(file)
-
-	if file == "." {
-		// Don't let them exclude everything, kind of silly.
-		return false, nil
-	}
-
-	patterns, patDirs, _, err := CleanPatterns(patterns)
+	pm, err := NewPatternMatcher(patterns)
 	if err != nil {
 		return false, err
 	}
 
-	return OptimizedMatches(file, patterns, patDirs)
+	return pm.Matches(file)
 }
 
 // Matches is basically the same as fileutils.Matches() but optimized for archive.go.
@@ -117,6 +110,50 @@ func OptimizedMatches(file string, patterns []string, patDirs [][]string) (bool,
 	return matched, nil
 }
 
+// PatternMatcher matches paths against a list of patterns with the
+// semantics of .dockerignore: a path matches the last pattern it or one of
+// its parent directories matches, and the patterns prefixed with ! are
+// exceptions to the ones before them. The patterns are cleaned once, when
+// the matcher is created.
+type PatternMatcher struct {
+	patterns   []string
+	patDirs    [][]string
+	exceptions bool
+}
+
+// NewPatternMatcher returns a PatternMatcher of the patterns, which are
+// cleaned like by CleanPatterns.
+func NewPatternMatcher(patterns []string) (*PatternMatcher, error) {
+	cleaned, patDirs, exceptions, err := CleanPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &PatternMatcher{patterns: cleaned, patDirs: patDirs, exceptions: exceptions}, nil
+}
+
+// Matches returns true if file matches the patterns of pm, like Matches.
+func (pm *PatternMatcher) Matches(file string) (bool, error) {
+	file = filepath.Clean(file)
+
+	if file == "." {
+		// Don't let them exclude everything, kind of silly.
+		return false, nil
+	}
+
+	return OptimizedMatches(file, pm.patterns, pm.patDirs)
+}
+
+// Exceptions returns true if some of the patterns of pm are exceptions, in
+// which case the files of a directory which matches may not all match.
+func (pm *PatternMatcher) Exceptions() bool {
+	return pm.exceptions
+}
+
+// Patterns returns the cleaned patterns of pm.
+func (pm *PatternMatcher) Patterns() []string {
+	return pm.patterns
+}
+
 func CopyFile(src, dst string) (int64, error) {
 	cleanSrc := filepath.Clean(src)
 	cleanDst := filepath.Clean(dst)
@@ -355,3 +355,48 @@ func TestCleanPatternsFolderSplit(t *testing.T) {
 		t.Errorf("expected first element in dirs slice to be config, got %v", dirs[0][1])
 	}
 }
+
+func TestPatternMatcher(t *testing.T) {
+	pm, err := NewPatternMatcher([]string{" .git ", "", "node_modules/", "*.md", "!README.md", "docs/*/build/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pm.Exceptions() {
+		t.Fatal("Expected the matcher to have exceptions")
+	}
+	if patterns := pm.Patterns(); len(patterns) != 5 || patterns[0] != ".git" || patterns[1] != "node_modules" {
+		t.Fatalf("Expected the cleaned patterns, got %v", patterns)
+	}
+	for file, expected := range map[string]bool{
+		".":                     false,
+		".git":                  true,
+		".git/HEAD":             true,
+		".gitignore":            false,
+		"node_modules/a/b.js":   true,
+		"src/node_modules/a.js": false,
+		"CHANGELOG.md":          true,
+		"README.md":             false,
+		"docs/api/build/a.html": true,
+		"docs/build/a.html":     false,
+		"./src/../CHANGELOG.md": true,
+	} {
+		match, err := pm.Matches(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match != expected {
+			t.Errorf("%s: expected the match %v, got %v", file, expected, match)
+		}
+	}
+
+	pm, err = NewPatternMatcher([]string{"*.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pm.Exceptions() {
+		t.Fatal("Expected the matcher not to have exceptions")
+	}
+	if _, err := NewPatternMatcher([]string{"!"}); err == nil {
+		t.Fatal("Expected an error for a single exclamation point")
+	}
+}
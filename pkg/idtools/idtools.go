@@ -0,0 +1,164 @@
+// Package idtools maps the user and group IDs of containers running in a user
+// namespace to the IDs of the host, and creates directories owned by them.
+package idtools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IDMap maps a range of IDs of a container, starting at ContainerID, to the
+// range of the same size of the host starting at HostID.
+type IDMap struct {
+	ContainerID int `json:"container_id"`
+	HostID      int `json:"host_id"`
+	Size        int `json:"size"`
+}
+
+// subordinate ranges of the users and groups, as given to usermod(8)
+var (
+	subuidFile = "/etc/subuid"
+	subgidFile = "/etc/subgid"
+)
+
+// ToHost returns the host ID of the container ID contID. Without any
+// mapping, the IDs are the same.
+func ToHost(contID int, idMap []IDMap) (int, error) {
+	if idMap == nil {
+		return contID, nil
+	}
+	for _, m := range idMap {
+		if contID >= m.ContainerID && contID < m.ContainerID+m.Size {
+			return m.HostID + (contID - m.ContainerID), nil
+		}
+	}
+	return -1, fmt.Errorf("Container ID %d cannot be mapped to a host ID", contID)
+}
+
+// ToContainer returns the container ID of the host ID hostID. Without any
+// mapping, the IDs are the same.
+func ToContainer(hostID int, idMap []IDMap) (int, error) {
+	if idMap == nil {
+		return hostID, nil
+	}
+	for _, m := range idMap {
+		if hostID >= m.HostID && hostID < m.HostID+m.Size {
+			return m.ContainerID + (hostID - m.HostID), nil
+		}
+	}
+	return -1, fmt.Errorf("Host ID %d cannot be mapped to a container ID", hostID)
+}
+
+// GetRootUIDGID returns the host IDs of the root user and group of the
+// containers, which are 0 without any mapping.
+func GetRootUIDGID(uidMap, gidMap []IDMap) (int, int, error) {
+	uid, err := ToHost(0, uidMap)
+	if err != nil {
+		return -1, -1, err
+	}
+	gid, err := ToHost(0, gidMap)
+	if err != nil {
+		return -1, -1, err
+	}
+	return uid, gid, nil
+}
+
+// CreateIDMappings returns the mappings of the IDs of the containers to the
+// subordinate IDs of the user username and of the group groupname, read
+// from /etc/subuid and /etc/subgid. The ranges are stacked from the ID 0
+// of the containers.
+func CreateIDMappings(username, groupname string) ([]IDMap, []IDMap, error) {
+	subuidRanges, err := parseSubidFile(subuidFile, username)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(subuidRanges) == 0 {
+		return nil, nil, fmt.Errorf("No subordinate user IDs found for %s in %s", username, subuidFile)
+	}
+	subgidRanges, err := parseSubidFile(subgidFile, groupname)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(subgidRanges) == 0 {
+		return nil, nil, fmt.Errorf("No subordinate group IDs found for %s in %s", groupname, subgidFile)
+	}
+	return createIDMap(subuidRanges), createIDMap(subgidRanges), nil
+}
+
+type subIDRange struct {
+	Start  int
+	Length int
+}
+
+type ranges []subIDRange
+
+func (r ranges) Len() int           { return len(r) }
+func (r ranges) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r ranges) Less(i, j int) bool { return r[i].Start < r[j].Start }
+
+func createIDMap(subidRanges ranges) []IDMap {
+	sort.Sort(subidRanges)
+	idMap := []IDMap{}
+	containerID := 0
+	for _, r := range subidRanges {
+		idMap = append(idMap, IDMap{
+			ContainerID: containerID,
+			HostID:      r.Start,
+			Size:        r.Length,
+		})
+		containerID += r.Length
+	}
+	return idMap
+}
+
+// parseSubidFile returns the ranges of the lines "NAME:START:LENGTH" of the
+// file path for name.
+func parseSubidFile(path, name string) (ranges, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rangeList ranges
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.Split(text, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("Invalid line %q in %s", text, path)
+		}
+		if parts[0] != name {
+			continue
+		}
+		start, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid start of range %q in %s", text, path)
+		}
+		length, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid length of range %q in %s", text, path)
+		}
+		rangeList = append(rangeList, subIDRange{start, length})
+	}
+	return rangeList, s.Err()
+}
+
+// MkdirAllAs creates the directory path and its missing parents, like
+// os.MkdirAll, and sets the owner of the ones it created.
+func MkdirAllAs(path string, mode os.FileMode, ownerUID, ownerGID int) error {
+	return mkdirAs(path, mode, ownerUID, ownerGID, true)
+}
+
+// MkdirAs creates the directory path, or sets the owner of the existing
+// directory, like os.Mkdir.
+func MkdirAs(path string, mode os.FileMode, ownerUID, ownerGID int) error {
+	return mkdirAs(path, mode, ownerUID, ownerGID, false)
+}
@@ -0,0 +1,86 @@
+package idtools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestToHostToContainer(t *testing.T) {
+	idMap := []IDMap{
+		{ContainerID: 0, HostID: 100000, Size: 1000},
+		{ContainerID: 1000, HostID: 300000, Size: 65536},
+	}
+	for contID, hostID := range map[int]int{0: 100000, 999: 100999, 1000: 300000, 66535: 365535} {
+		id, err := ToHost(contID, idMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != hostID {
+			t.Fatalf("Expected host ID %d for %d, got %d", hostID, contID, id)
+		}
+		if id, err = ToContainer(hostID, idMap); err != nil {
+			t.Fatal(err)
+		}
+		if id != contID {
+			t.Fatalf("Expected container ID %d for %d, got %d", contID, hostID, id)
+		}
+	}
+	if _, err := ToHost(66536, idMap); err == nil {
+		t.Fatal("Expected an error for an unmapped container ID")
+	}
+	if _, err := ToContainer(0, idMap); err == nil {
+		t.Fatal("Expected an error for an unmapped host ID")
+	}
+	if id, err := ToHost(42, nil); err != nil || id != 42 {
+		t.Fatalf("Expected the same ID without mapping, got %d, %v", id, err)
+	}
+}
+
+func TestCreateIDMappings(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "idtools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	defer func(uidFile, gidFile string) { subuidFile, subgidFile = uidFile, gidFile }(subuidFile, subgidFile)
+	subuidFile = filepath.Join(tmp, "subuid")
+	subgidFile = filepath.Join(tmp, "subgid")
+	if err := ioutil.WriteFile(subuidFile, []byte("other:100000:65536\ndockremap:300000:1000\n# comment\ndockremap:200000:65536\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(subgidFile, []byte("dockremap:400000:65536\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	uidMap, gidMap, err := CreateIDMappings("dockremap", "dockremap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []IDMap{{0, 200000, 65536}, {65536, 300000, 1000}}
+	if len(uidMap) != len(expected) || uidMap[0] != expected[0] || uidMap[1] != expected[1] {
+		t.Fatalf("Expected uid mappings %v, got %v", expected, uidMap)
+	}
+	if len(gidMap) != 1 || gidMap[0] != (IDMap{0, 400000, 65536}) {
+		t.Fatalf("Unexpected gid mappings %v", gidMap)
+	}
+	uid, gid, err := GetRootUIDGID(uidMap, gidMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid != 200000 || gid != 400000 {
+		t.Fatalf("Expected root 200000:400000, got %d:%d", uid, gid)
+	}
+
+	if _, _, err := CreateIDMappings("nobody", "dockremap"); err == nil {
+		t.Fatal("Expected an error for a user without subordinate IDs")
+	}
+	if err := ioutil.WriteFile(subgidFile, []byte("dockremap:400000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := CreateIDMappings("dockremap", "dockremap"); err == nil {
+		t.Fatal("Expected an error for an invalid subgid file")
+	}
+}
@@ -0,0 +1,44 @@
+// +build !windows
+
+package idtools
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func mkdirAs(path string, mode os.FileMode, ownerUID, ownerGID int, mkAll bool) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	if mkAll {
+		// the missing directories, from the deepest one
+		for p := path; ; p = filepath.Dir(p) {
+			if _, err := os.Stat(p); err == nil || !os.IsNotExist(err) {
+				break
+			}
+			paths = append(paths, p)
+			if p == filepath.Dir(p) {
+				break
+			}
+		}
+		if err := os.MkdirAll(path, mode); err != nil && !os.IsExist(err) {
+			return err
+		}
+	} else {
+		paths = []string{path}
+		if err := os.Mkdir(path, mode); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+
+	for _, p := range paths {
+		if err := os.Chown(p, ownerUID, ownerGID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
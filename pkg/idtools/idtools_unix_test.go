@@ -0,0 +1,43 @@
+// +build !windows
+
+package idtools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestMkdirAllAs(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("Changing the owner of files needs root")
+	}
+	tmp, err := ioutil.TempDir("", "idtools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := MkdirAllAs(filepath.Join(tmp, "a", "b"), 0755, 100, 200); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"a", "a/b"} {
+		fi, err := os.Stat(filepath.Join(tmp, p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		st := fi.Sys().(*syscall.Stat_t)
+		if st.Uid != 100 || st.Gid != 200 {
+			t.Fatalf("Expected %s owned by 100:200, got %d:%d", p, st.Uid, st.Gid)
+		}
+	}
+	fi, err := os.Stat(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := fi.Sys().(*syscall.Stat_t); st.Uid != 0 {
+		t.Fatalf("Expected the existing parent to keep its owner, got %d", st.Uid)
+	}
+}
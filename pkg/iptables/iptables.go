@@ -37,6 +37,9 @@ type Chain struct {
 	Name   string
 	Bridge string
 	Table  Table
+	// HairpinMode is set when the chain is reached by the connections to
+	// the loopback addresses of the host, for all the ports it forwards.
+	HairpinMode bool
 }
 
 type ChainError struct {
@@ -63,9 +66,10 @@ func initCheck() error {
 
 func NewChain(name, bridge string, table Table, hairpinMode bool) (*Chain, error) {
 	c := &Chain{
-		Name:   name,
-		Bridge: bridge,
-		Table:  table,
+		Name:        name,
+		Bridge:      bridge,
+		Table:       table,
+		HairpinMode: hairpinMode,
 	}
 
 	if string(c.Table) == "" {
@@ -129,8 +133,9 @@ func RemoveExistingChain(name string, table Table) error {
 	return c.Remove()
 }
 
-// Add forwarding rule to 'filter' table and corresponding nat rule to 'nat' table
-func (c *Chain) Forward(action Action, ip net.IP, port int, proto, destAddr string, destPort int) error {
+// dnatRule returns the nat rule forwarding the proto port of ip to destPort
+// of destAddr.
+func dnatRule(ip net.IP, port int, proto, destAddr string, destPort int) []string {
 	daddr := ip.String()
 	if ip.IsUnspecified() {
 		// iptables interprets "0.0.0.0" as "0.0.0.0/32", whereas we
@@ -138,12 +143,18 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, destAddr stri
 		// value" by both iptables and ip6tables.
 		daddr = "0/0"
 	}
-	if output, err := Raw("-t", string(Nat), string(action), c.Name,
+	return []string{
 		"-p", proto,
 		"-d", daddr,
 		"--dport", strconv.Itoa(port),
 		"-j", "DNAT",
-		"--to-destination", net.JoinHostPort(destAddr, strconv.Itoa(destPort))); err != nil {
+		"--to-destination", net.JoinHostPort(destAddr, strconv.Itoa(destPort))}
+}
+
+// Add forwarding rule to 'filter' table and corresponding nat rule to 'nat' table
+func (c *Chain) Forward(action Action, ip net.IP, port int, proto, destAddr string, destPort int) error {
+	if output, err := Raw(append([]string{"-t", string(Nat), string(action), c.Name},
+		dnatRule(ip, port, proto, destAddr, destPort)...)...); err != nil {
 		return err
 	} else if len(output) != 0 {
 		return ChainError{Chain: "FORWARD", Output: output}
@@ -175,6 +186,58 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, destAddr stri
 	return nil
 }
 
+// Hairpin adds the rules making the port forwarded by Forward for the same
+// arguments reachable from the host through its loopback addresses, and from
+// the containers of the bridge, when the chain is not in hairpin mode.
+// Connections from the loopback addresses are only routed to the bridge when
+// route_localnet is enabled on it.
+func (c *Chain) Hairpin(action Action, ip net.IP, port int, proto, destAddr string, destPort int) error {
+	if ip.IsUnspecified() || ip.IsLoopback() {
+		if output, err := Raw("-t", string(Nat), string(action), "OUTPUT",
+			"-p", proto,
+			"-d", "127.0.0.0/8",
+			"--dport", strconv.Itoa(port),
+			"-j", c.Name); err != nil {
+			return err
+		} else if len(output) != 0 {
+			return ChainError{Chain: "OUTPUT", Output: output}
+		}
+
+		if output, err := Raw("-t", string(Nat), string(action), "POSTROUTING",
+			"-p", proto,
+			"-d", destAddr,
+			"--dport", strconv.Itoa(destPort),
+			"-o", c.Bridge,
+			"-m", "addrtype", "--src-type", "LOCAL",
+			"-j", "MASQUERADE"); err != nil {
+			return err
+		} else if len(output) != 0 {
+			return ChainError{Chain: "POSTROUTING", Output: output}
+		}
+	}
+
+	if output, err := Raw("-t", string(Filter), string(action), c.Name,
+		"-i", c.Bridge,
+		"-o", c.Bridge,
+		"-p", proto,
+		"-d", destAddr,
+		"--dport", strconv.Itoa(destPort),
+		"-m", "conntrack", "--ctstate", "DNAT",
+		"-j", "ACCEPT"); err != nil {
+		return err
+	} else if len(output) != 0 {
+		return ChainError{Chain: "FORWARD", Output: output}
+	}
+
+	return nil
+}
+
+// ForwardExists reports whether the nat rule added by Forward for the same
+// arguments is in place.
+func (c *Chain) ForwardExists(ip net.IP, port int, proto, destAddr string, destPort int) bool {
+	return Exists(Nat, c.Name, dnatRule(ip, port, proto, destAddr, destPort)...)
+}
+
 // Add reciprocal ACCEPT rule for two supplied IP addresses.
 // Traffic is allowed from ip1 to ip2 and vice-versa
 func (c *Chain) Link(action Action, ip1, ip2 net.IP, port int, proto string) error {
@@ -51,6 +51,9 @@ func TestForward(t *testing.T) {
 	if !Exists(natChain.Table, natChain.Name, dnatRule...) {
 		t.Fatalf("DNAT rule does not exist")
 	}
+	if !natChain.ForwardExists(ip, port, proto, dstAddr, dstPort) {
+		t.Fatalf("ForwardExists does not find the DNAT rule")
+	}
 
 	filterRule := []string{
 		"!", "-i", filterChain.Bridge,
@@ -78,6 +81,60 @@ func TestForward(t *testing.T) {
 	}
 }
 
+func TestHairpin(t *testing.T) {
+	ip := net.ParseIP("0.0.0.0")
+	port := 1235
+	dstAddr := "172.17.0.1"
+	dstPort := 4322
+	proto := "tcp"
+
+	if err := filterChain.Hairpin(Append, ip, port, proto, dstAddr, dstPort); err != nil {
+		t.Fatal(err)
+	}
+
+	outputRule := []string{
+		"-p", proto,
+		"-d", "127.0.0.0/8",
+		"--dport", strconv.Itoa(port),
+		"-j", filterChain.Name,
+	}
+	if !Exists(Nat, "OUTPUT", outputRule...) {
+		t.Fatalf("OUTPUT rule does not exist")
+	}
+
+	masqRule := []string{
+		"-p", proto,
+		"-d", dstAddr,
+		"--dport", strconv.Itoa(dstPort),
+		"-o", filterChain.Bridge,
+		"-m", "addrtype", "--src-type", "LOCAL",
+		"-j", "MASQUERADE",
+	}
+	if !Exists(Nat, "POSTROUTING", masqRule...) {
+		t.Fatalf("MASQUERADE rule does not exist")
+	}
+
+	filterRule := []string{
+		"-i", filterChain.Bridge,
+		"-o", filterChain.Bridge,
+		"-p", proto,
+		"-d", dstAddr,
+		"--dport", strconv.Itoa(dstPort),
+		"-m", "conntrack", "--ctstate", "DNAT",
+		"-j", "ACCEPT",
+	}
+	if !Exists(filterChain.Table, filterChain.Name, filterRule...) {
+		t.Fatalf("filter rule does not exist")
+	}
+
+	if err := filterChain.Hairpin(Delete, ip, port, proto, dstAddr, dstPort); err != nil {
+		t.Fatal(err)
+	}
+	if Exists(Nat, "OUTPUT", outputRule...) || Exists(Nat, "POSTROUTING", masqRule...) {
+		t.Fatalf("Hairpin rules were not deleted")
+	}
+}
+
 func TestLink(t *testing.T) {
 	var err error
 
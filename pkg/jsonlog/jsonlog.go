@@ -8,9 +8,10 @@ import (
 )
 
 type JSONLog struct {
-	Log     string    `json:"log,omitempty"`
-	Stream  string    `json:"stream,omitempty"`
-	Created time.Time `json:"time"`
+	Log     string            `json:"log,omitempty"`
+	Stream  string            `json:"stream,omitempty"`
+	Created time.Time         `json:"time"`
+	Attrs   map[string]string `json:"attrs,omitempty"` // Labels and env of the container
 }
 
 func (jl *JSONLog) Format(format string) (string, error) {
@@ -28,6 +29,7 @@ func (jl *JSONLog) Reset() {
 	jl.Log = ""
 	jl.Stream = ""
 	jl.Created = time.Time{}
+	jl.Attrs = nil
 }
 
 func WriteLog(src io.Reader, dst io.Writer, format string, since time.Time) error {
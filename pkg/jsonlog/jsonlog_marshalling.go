@@ -57,6 +57,7 @@ package jsonlog
 
 import (
 	"bytes"
+	"encoding/json"
 	"unicode/utf8"
 
 	"github.com/docker/docker/pkg/timeutils"
@@ -107,6 +108,14 @@ func (mj *JSONLog) MarshalJSONBuf(buf *bytes.Buffer) error {
 		return err
 	}
 	buf.WriteString(timestamp)
+	if len(mj.Attrs) != 0 {
+		attrs, err := json.Marshal(mj.Attrs)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(`,"attrs":`)
+		buf.Write(attrs)
+	}
 	buf.WriteString(`}`)
 	return nil
 }
@@ -39,6 +39,36 @@ func TestWriteLog(t *testing.T) {
 	}
 }
 
+func TestMarshalAttrs(t *testing.T) {
+	created := time.Date(2015, 6, 24, 17, 35, 53, 0, time.UTC)
+	jl := &JSONLog{Log: "hello\n", Stream: "stdout", Created: created, Attrs: map[string]string{"tier": "web", "APP": "shop"}}
+	m, err := jl.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"log":"hello\n","stream":"stdout","time":"2015-06-24T17:35:53Z","attrs":{"APP":"shop","tier":"web"}}`
+	if string(m) != expected {
+		t.Fatalf("Wrong JSON %s, expected %s", m, expected)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	jlb := &JSONLogBytes{Log: []byte("hello\n"), Stream: "stdout", Created: `"2015-06-24T17:35:53Z"`, RawAttrs: json.RawMessage(`{"APP":"shop","tier":"web"}`)}
+	if err := jlb.MarshalJSONBuf(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Fatalf("Wrong JSON %s, expected %s", buf, expected)
+	}
+
+	var decoded JSONLog
+	if err := json.Unmarshal(m, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Attrs["tier"] != "web" || !decoded.Created.Equal(created) {
+		t.Fatalf("Wrong decoded log %+v", decoded)
+	}
+}
+
 func BenchmarkWriteLog(b *testing.B) {
 	var buf bytes.Buffer
 	e := json.NewEncoder(&buf)
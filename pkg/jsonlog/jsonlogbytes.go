@@ -2,16 +2,18 @@ package jsonlog
 
 import (
 	"bytes"
+	"encoding/json"
 	"unicode/utf8"
 )
 
 // JSONLogBytes is based on JSONLog.
 // It allows marshalling JSONLog from Log as []byte
-// and an already marshalled Created timestamp.
+// and an already marshalled Created timestamp and Attrs.
 type JSONLogBytes struct {
-	Log     []byte `json:"log,omitempty"`
-	Stream  string `json:"stream,omitempty"`
-	Created string `json:"time"`
+	Log      []byte          `json:"log,omitempty"`
+	Stream   string          `json:"stream,omitempty"`
+	Created  string          `json:"time"`
+	RawAttrs json.RawMessage `json:"attrs,omitempty"`
 }
 
 // MarshalJSONBuf is based on the same method from JSONLog
@@ -45,6 +47,10 @@ func (mj *JSONLogBytes) MarshalJSONBuf(buf *bytes.Buffer) error {
 	}
 	buf.WriteString(`"time":`)
 	buf.WriteString(mj.Created)
+	if len(mj.RawAttrs) != 0 {
+		buf.WriteString(`,"attrs":`)
+		buf.Write(mj.RawAttrs)
+	}
 	buf.WriteString(`}`)
 	return nil
 }
@@ -26,6 +26,8 @@ type JSONProgress struct {
 	Current    int   `json:"current,omitempty"`
 	Total      int   `json:"total,omitempty"`
 	Start      int64 `json:"start,omitempty"`
+	Speed      int64 `json:"speed,omitempty"` // Bytes per second
+	ETA        int64 `json:"eta,omitempty"`   // Seconds left
 }
 
 func (p *JSONProgress) String() string {
@@ -33,6 +35,7 @@ func (p *JSONProgress) String() string {
 		width       = 200
 		pbBox       string
 		numbersBox  string
+		speedBox    string
 		timeLeftBox string
 	)
 
@@ -63,7 +66,15 @@ func (p *JSONProgress) String() string {
 	}
 	numbersBox = fmt.Sprintf("%8v/%v", current, total)
 
-	if p.Current > 0 && p.Start > 0 && percentage < 50 {
+	if p.Speed > 0 && width > 50 {
+		speedBox = fmt.Sprintf(" %s/s", units.HumanSize(float64(p.Speed)))
+	}
+
+	if p.ETA > 0 && percentage < 50 {
+		if width > 50 {
+			timeLeftBox = " " + (time.Duration(p.ETA) * time.Second).String()
+		}
+	} else if p.Current > 0 && p.Start > 0 && percentage < 50 {
 		fromStart := time.Now().UTC().Sub(time.Unix(int64(p.Start), 0))
 		perEntry := fromStart / time.Duration(p.Current)
 		left := time.Duration(p.Total-p.Current) * perEntry
@@ -73,7 +84,7 @@ func (p *JSONProgress) String() string {
 			timeLeftBox = " " + left.String()
 		}
 	}
-	return pbBox + numbersBox + timeLeftBox
+	return pbBox + numbersBox + speedBox + timeLeftBox
 }
 
 type JSONMessage struct {
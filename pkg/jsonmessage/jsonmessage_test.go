@@ -35,4 +35,10 @@ func TestProgress(t *testing.T) {
 	if jp4.String() != expected {
 		t.Fatalf("Expected %q, got %q", expected, jp4.String())
 	}
+
+	expected = "[=========================>                         ] 2.048 kB/4.096 kB 1.024 kB/s 2s"
+	jp5 := JSONProgress{Current: 2048, Total: 4096, Speed: 1024, ETA: 2}
+	if jp5.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, jp5.String())
+	}
 }
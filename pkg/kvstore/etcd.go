@@ -0,0 +1,153 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const etcdTimeout = 10 * time.Second
+
+// etcd error codes, see https://github.com/coreos/etcd/blob/master/Documentation/errorcode.md
+const (
+	etcdKeyNotFound = 100
+	etcdNodeExist   = 105
+)
+
+func init() {
+	Register("etcd", newEtcdStore)
+}
+
+// etcdStore talks to etcd through its v2 keys API.
+type etcdStore struct {
+	endpoint string
+	client   *http.Client
+}
+
+type etcdNode struct {
+	Key   string      `json:"key"`
+	Value string      `json:"value"`
+	Dir   bool        `json:"dir"`
+	Nodes []*etcdNode `json:"nodes"`
+}
+
+type etcdResponse struct {
+	Node      *etcdNode `json:"node"`
+	ErrorCode int       `json:"errorCode"`
+	Message   string    `json:"message"`
+}
+
+func newEtcdStore(addr string) (Store, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("kvstore: etcd address is required")
+	}
+	return &etcdStore{
+		endpoint: "http://" + strings.TrimSuffix(addr, "/") + "/v2/keys/",
+		client:   &http.Client{Timeout: etcdTimeout},
+	}, nil
+}
+
+func (s *etcdStore) do(method, key string, query, form url.Values) (*etcdResponse, error) {
+	u := s.endpoint + normalize(key)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body *strings.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	} else {
+		body = strings.NewReader("")
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var r etcdResponse
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("kvstore: invalid response from etcd (%s): %s", resp.Status, b)
+	}
+
+	switch r.ErrorCode {
+	case 0:
+		return &r, nil
+	case etcdKeyNotFound:
+		return nil, ErrKeyNotFound
+	case etcdNodeExist:
+		return nil, ErrKeyExists
+	default:
+		return nil, fmt.Errorf("kvstore: etcd error %d: %s", r.ErrorCode, r.Message)
+	}
+}
+
+func (s *etcdStore) Get(key string) (*KVPair, error) {
+	r, err := s.do("GET", key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &KVPair{Key: normalize(r.Node.Key), Value: []byte(r.Node.Value)}, nil
+}
+
+func (s *etcdStore) Put(key string, value []byte) error {
+	_, err := s.do("PUT", key, nil, url.Values{"value": {string(value)}})
+	return err
+}
+
+func (s *etcdStore) AtomicCreate(key string, value []byte) error {
+	_, err := s.do("PUT", key, url.Values{"prevExist": {"false"}}, url.Values{"value": {string(value)}})
+	return err
+}
+
+func (s *etcdStore) Delete(key string) error {
+	_, err := s.do("DELETE", key, url.Values{"recursive": {"true"}}, nil)
+	if err == ErrKeyNotFound {
+		return nil
+	}
+	return err
+}
+
+func (s *etcdStore) List(prefix string) ([]*KVPair, error) {
+	r, err := s.do("GET", prefix, url.Values{"recursive": {"true"}}, nil)
+	if err == ErrKeyNotFound {
+		return []*KVPair{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := []*KVPair{}
+	var walk func(n *etcdNode)
+	walk = func(n *etcdNode) {
+		if !n.Dir {
+			pairs = append(pairs, &KVPair{Key: normalize(n.Key), Value: []byte(n.Value)})
+			return
+		}
+		for _, child := range n.Nodes {
+			walk(child)
+		}
+	}
+	if r.Node.Dir {
+		walk(r.Node)
+	}
+	sort.Sort(byKey(pairs))
+	return pairs, nil
+}
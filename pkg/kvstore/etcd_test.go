@@ -0,0 +1,86 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeEtcd implements the subset of the etcd v2 keys API used by etcdStore
+// on top of a MemoryStore.
+func fakeEtcd(t *testing.T) *httptest.Server {
+	mem := NewMemoryStore()
+	writeError := func(w http.ResponseWriter, status, code int) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(&etcdResponse{ErrorCode: code, Message: http.StatusText(status)})
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/v2/keys/")
+		r.ParseForm()
+
+		switch r.Method {
+		case "GET":
+			if r.Form.Get("recursive") == "true" {
+				pairs, _ := mem.List(key)
+				if len(pairs) == 0 {
+					writeError(w, http.StatusNotFound, etcdKeyNotFound)
+					return
+				}
+				dir := &etcdNode{Key: "/" + key, Dir: true}
+				for _, p := range pairs {
+					dir.Nodes = append(dir.Nodes, &etcdNode{Key: "/" + p.Key, Value: string(p.Value)})
+				}
+				json.NewEncoder(w).Encode(&etcdResponse{Node: dir})
+				return
+			}
+			pair, err := mem.Get(key)
+			if err != nil {
+				writeError(w, http.StatusNotFound, etcdKeyNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(&etcdResponse{Node: &etcdNode{Key: "/" + key, Value: string(pair.Value)}})
+		case "PUT":
+			value := []byte(r.PostForm.Get("value"))
+			if r.URL.Query().Get("prevExist") == "false" {
+				if err := mem.AtomicCreate(key, value); err != nil {
+					writeError(w, http.StatusPreconditionFailed, etcdNodeExist)
+					return
+				}
+			} else {
+				mem.Put(key, value)
+			}
+			json.NewEncoder(w).Encode(&etcdResponse{Node: &etcdNode{Key: "/" + key, Value: string(value)}})
+		case "DELETE":
+			if pairs, _ := mem.List(key); len(pairs) == 0 {
+				if _, err := mem.Get(key); err != nil {
+					writeError(w, http.StatusNotFound, etcdKeyNotFound)
+					return
+				}
+			}
+			mem.Delete(key)
+			json.NewEncoder(w).Encode(&etcdResponse{Node: &etcdNode{Key: "/" + key}})
+		default:
+			t.Errorf("Unexpected method %s", r.Method)
+		}
+	}))
+}
+
+func TestEtcdStore(t *testing.T) {
+	server := fakeEtcd(t)
+	defer server.Close()
+
+	s, err := New("etcd://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+}
+
+func TestEtcdStoreRequiresAddress(t *testing.T) {
+	if _, err := New("etcd://"); err == nil {
+		t.Fatal("Expected an error opening etcd without an address")
+	}
+}
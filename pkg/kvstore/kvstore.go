@@ -0,0 +1,84 @@
+// Package kvstore provides access to the key-value stores daemons use to
+// share state, such as the networks spanning several hosts, with each
+// other.
+//
+// Backends register themselves under a URL scheme; a store is opened with
+// New("etcd://10.0.0.1:2379").
+package kvstore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrKeyNotFound is returned when a key does not exist in the store.
+	ErrKeyNotFound = errors.New("Key not found in store")
+	// ErrKeyExists is returned by AtomicCreate when the key already exists.
+	ErrKeyExists = errors.New("Key already exists in store")
+)
+
+// KVPair is a key and the value stored at it.
+type KVPair struct {
+	Key   string
+	Value []byte
+}
+
+// Store is a key-value store. Keys are slash separated paths, such as
+// "docker/network/v1.0/network/<id>".
+type Store interface {
+	// Get returns the value stored at key.
+	Get(key string) (*KVPair, error)
+	// Put stores value at key, replacing any previous value.
+	Put(key string, value []byte) error
+	// AtomicCreate stores value at key only if the key does not exist yet.
+	AtomicCreate(key string, value []byte) error
+	// Delete removes key, and all keys below it.
+	Delete(key string) error
+	// List returns all the keys, with values, below prefix. It returns an
+	// empty list when there are none.
+	List(prefix string) ([]*KVPair, error)
+}
+
+// InitFunc opens a store at addr, the URL of the store without its scheme.
+type InitFunc func(addr string) (Store, error)
+
+var backends = struct {
+	sync.Mutex
+	m map[string]InitFunc
+}{m: make(map[string]InitFunc)}
+
+// Register makes a store backend available under the URL scheme name.
+func Register(name string, fn InitFunc) error {
+	backends.Lock()
+	defer backends.Unlock()
+
+	if _, ok := backends.m[name]; ok {
+		return fmt.Errorf("kvstore: backend named '%s' is already registered", name)
+	}
+	backends.m[name] = fn
+	return nil
+}
+
+// New opens the store at url, e.g. "etcd://10.0.0.1:2379".
+func New(url string) (Store, error) {
+	parts := strings.SplitN(url, "://", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("kvstore: invalid store address %s, expected <backend>://<address>", url)
+	}
+
+	backends.Lock()
+	fn, ok := backends.m[parts[0]]
+	backends.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("kvstore: no backend named '%s' is registered", parts[0])
+	}
+	return fn(parts[1])
+}
+
+// normalize strips the leading and trailing slashes of key.
+func normalize(key string) string {
+	return strings.Trim(key, "/")
+}
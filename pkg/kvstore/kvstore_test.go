@@ -0,0 +1,76 @@
+package kvstore
+
+import (
+	"testing"
+)
+
+func testStore(t *testing.T, s Store) {
+	if _, err := s.Get("docker/foo"); err != ErrKeyNotFound {
+		t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	if err := s.Put("docker/foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	pair, err := s.Get("/docker/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.Key != "docker/foo" || string(pair.Value) != "bar" {
+		t.Fatalf("Unexpected pair %s=%s", pair.Key, pair.Value)
+	}
+
+	if err := s.AtomicCreate("docker/foo", []byte("baz")); err != ErrKeyExists {
+		t.Fatalf("Expected ErrKeyExists, got %v", err)
+	}
+	if err := s.AtomicCreate("docker/dir/a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("docker/dir/sub/b", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+
+	pairs, err := s.List("docker/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 2 || pairs[0].Key != "docker/dir/a" || pairs[1].Key != "docker/dir/sub/b" {
+		t.Fatalf("Unexpected list %v", pairs)
+	}
+	if pairs, err := s.List("docker/none"); err != nil || len(pairs) != 0 {
+		t.Fatalf("Expected an empty list, got %v, %v", pairs, err)
+	}
+
+	if err := s.Delete("docker/dir"); err != nil {
+		t.Fatal(err)
+	}
+	if pairs, err := s.List("docker/dir"); err != nil || len(pairs) != 0 {
+		t.Fatalf("Expected the directory to be deleted, got %v, %v", pairs, err)
+	}
+	if _, err := s.Get("docker/foo"); err != nil {
+		t.Fatalf("Expected docker/foo to be kept, got %v", err)
+	}
+	if err := s.Delete("docker/none"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestNew(t *testing.T) {
+	s, err := New("memory://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*MemoryStore); !ok {
+		t.Fatalf("Expected a memory store, got %T", s)
+	}
+
+	for _, url := range []string{"", "memory", "unknown://foo"} {
+		if _, err := New(url); err == nil {
+			t.Fatalf("Expected an error opening %q", url)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package kvstore
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+func init() {
+	Register("memory", func(addr string) (Store, error) {
+		return NewMemoryStore(), nil
+	})
+}
+
+// MemoryStore is a store local to the process. It lets a single daemon use
+// the features relying on a store, and is used in tests.
+type MemoryStore struct {
+	sync.Mutex
+	data map[string][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Get(key string) (*KVPair, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	key = normalize(key)
+	value, ok := s.data[key]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return &KVPair{Key: key, Value: value}, nil
+}
+
+func (s *MemoryStore) Put(key string, value []byte) error {
+	s.Lock()
+	s.data[normalize(key)] = value
+	s.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) AtomicCreate(key string, value []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key = normalize(key)
+	if _, ok := s.data[key]; ok {
+		return ErrKeyExists
+	}
+	s.data[key] = value
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key = normalize(key)
+	for k := range s.data {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			delete(s.data, k)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) List(prefix string) ([]*KVPair, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	prefix = normalize(prefix) + "/"
+	pairs := []*KVPair{}
+	for k, v := range s.data {
+		if strings.HasPrefix(k, prefix) {
+			pairs = append(pairs, &KVPair{Key: k, Value: v})
+		}
+	}
+	sort.Sort(byKey(pairs))
+	return pairs, nil
+}
+
+type byKey []*KVPair
+
+func (p byKey) Len() int           { return len(p) }
+func (p byKey) Less(i, j int) bool { return p[i].Key < p[j].Key }
+func (p byKey) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
@@ -1,9 +1,53 @@
 package mount
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
+// mountFlags maps fstab type mount options to the mount() flag they set or
+// clear.
+var mountFlags = map[string]struct {
+	clear bool
+	flag  int
+}{
+	"defaults":      {false, 0},
+	"ro":            {false, RDONLY},
+	"rw":            {true, RDONLY},
+	"suid":          {true, NOSUID},
+	"nosuid":        {false, NOSUID},
+	"dev":           {true, NODEV},
+	"nodev":         {false, NODEV},
+	"exec":          {true, NOEXEC},
+	"noexec":        {false, NOEXEC},
+	"sync":          {false, SYNCHRONOUS},
+	"async":         {true, SYNCHRONOUS},
+	"dirsync":       {false, DIRSYNC},
+	"remount":       {false, REMOUNT},
+	"mand":          {false, MANDLOCK},
+	"nomand":        {true, MANDLOCK},
+	"atime":         {true, NOATIME},
+	"noatime":       {false, NOATIME},
+	"diratime":      {true, NODIRATIME},
+	"nodiratime":    {false, NODIRATIME},
+	"bind":          {false, BIND},
+	"rbind":         {false, RBIND},
+	"unbindable":    {false, UNBINDABLE},
+	"runbindable":   {false, RUNBINDABLE},
+	"private":       {false, PRIVATE},
+	"rprivate":      {false, RPRIVATE},
+	"shared":        {false, SHARED},
+	"rshared":       {false, RSHARED},
+	"slave":         {false, SLAVE},
+	"rslave":        {false, RSLAVE},
+	"relatime":      {false, RELATIME},
+	"norelatime":    {true, RELATIME},
+	"strictatime":   {false, STRICTATIME},
+	"nostrictatime": {true, STRICTATIME},
+}
+
 // Parse fstab type mount options into mount() flags
 // and device specific data
 func parseOptions(options string) (int, string) {
@@ -12,50 +56,11 @@ func parseOptions(options string) (int, string) {
 		data []string
 	)
 
-	flags := map[string]struct {
-		clear bool
-		flag  int
-	}{
-		"defaults":      {false, 0},
-		"ro":            {false, RDONLY},
-		"rw":            {true, RDONLY},
-		"suid":          {true, NOSUID},
-		"nosuid":        {false, NOSUID},
-		"dev":           {true, NODEV},
-		"nodev":         {false, NODEV},
-		"exec":          {true, NOEXEC},
-		"noexec":        {false, NOEXEC},
-		"sync":          {false, SYNCHRONOUS},
-		"async":         {true, SYNCHRONOUS},
-		"dirsync":       {false, DIRSYNC},
-		"remount":       {false, REMOUNT},
-		"mand":          {false, MANDLOCK},
-		"nomand":        {true, MANDLOCK},
-		"atime":         {true, NOATIME},
-		"noatime":       {false, NOATIME},
-		"diratime":      {true, NODIRATIME},
-		"nodiratime":    {false, NODIRATIME},
-		"bind":          {false, BIND},
-		"rbind":         {false, RBIND},
-		"unbindable":    {false, UNBINDABLE},
-		"runbindable":   {false, RUNBINDABLE},
-		"private":       {false, PRIVATE},
-		"rprivate":      {false, RPRIVATE},
-		"shared":        {false, SHARED},
-		"rshared":       {false, RSHARED},
-		"slave":         {false, SLAVE},
-		"rslave":        {false, RSLAVE},
-		"relatime":      {false, RELATIME},
-		"norelatime":    {true, RELATIME},
-		"strictatime":   {false, STRICTATIME},
-		"nostrictatime": {true, STRICTATIME},
-	}
-
 	for _, o := range strings.Split(options, ",") {
 		// If the option does not exist in the flags table or the flag
 		// is not supported on the platform,
 		// then it is a data value for a specific fs type
-		if f, exists := flags[o]; exists && f.flag != 0 {
+		if f, exists := mountFlags[o]; exists && f.flag != 0 {
 			if f.clear {
 				flag &= ^f.flag
 			} else {
@@ -67,3 +72,61 @@ func parseOptions(options string) (int, string) {
 	}
 	return flag, strings.Join(data, ",")
 }
+
+var tmpfsSizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG%]?$`)
+
+// nonTmpfsOptions are the mount options which do not apply to tmpfs mounts.
+var nonTmpfsOptions = map[string]bool{
+	"remount":     true,
+	"bind":        true,
+	"rbind":       true,
+	"unbindable":  true,
+	"runbindable": true,
+	"private":     true,
+	"rprivate":    true,
+	"shared":      true,
+	"rshared":     true,
+	"slave":       true,
+	"rslave":      true,
+}
+
+// ParseTmpfsOptions parses the fstab type options of a tmpfs mount, e.g.
+// "rw,size=64m,mode=1777", into mount() flags and tmpfs data, rejecting the
+// options which do not apply to tmpfs.
+func ParseTmpfsOptions(options string) (int, string, error) {
+	if options == "" {
+		return 0, "", nil
+	}
+	var data []string
+	for _, o := range strings.Split(options, ",") {
+		if _, exists := mountFlags[o]; exists {
+			if nonTmpfsOptions[o] {
+				return 0, "", fmt.Errorf("invalid tmpfs option %q", o)
+			}
+			continue
+		}
+		opt := strings.SplitN(o, "=", 2)
+		if len(opt) != 2 {
+			return 0, "", fmt.Errorf("invalid tmpfs option %q", o)
+		}
+		switch opt[0] {
+		case "size", "nr_blocks", "nr_inodes":
+			if !tmpfsSizePattern.MatchString(opt[1]) {
+				return 0, "", fmt.Errorf("invalid tmpfs option %q", o)
+			}
+		case "mode":
+			if _, err := strconv.ParseUint(opt[1], 8, 32); err != nil {
+				return 0, "", fmt.Errorf("invalid tmpfs option %q, the mode must be octal", o)
+			}
+		case "uid", "gid":
+			if _, err := strconv.ParseUint(opt[1], 10, 32); err != nil {
+				return 0, "", fmt.Errorf("invalid tmpfs option %q", o)
+			}
+		default:
+			return 0, "", fmt.Errorf("invalid tmpfs option %q", o)
+		}
+		data = append(data, o)
+	}
+	flags, _ := parseOptions(options)
+	return flags, strings.Join(data, ","), nil
+}
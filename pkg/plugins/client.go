@@ -0,0 +1,145 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	versionMimetype = "application/vnd.docker.plugins.v1+json"
+	defaultTimeOut  = 30
+)
+
+// Client is an HTTP client speaking the plugin protocol, a JSON payload
+// POSTed to /<Interface>.<Method>.
+type Client struct {
+	http *http.Client
+	addr string
+}
+
+// NewClient returns a client for the plugin listening at addr, e.g.
+// unix:///run/docker/plugins/weave.sock or tcp://localhost:8080.
+func NewClient(addr string) (*Client, error) {
+	protoAndAddr := strings.SplitN(addr, "://", 2)
+	if len(protoAndAddr) != 2 {
+		return nil, fmt.Errorf("Invalid plugin address: %s", addr)
+	}
+	tr := &http.Transport{}
+	configureTransport(tr, protoAndAddr[0], protoAndAddr[1])
+	return &Client{&http.Client{Transport: tr}, protoAndAddr[1]}, nil
+}
+
+// Call invokes serviceMethod on the plugin with args encoded as JSON and
+// decodes the response into ret. Connection failures are retried with an
+// exponential backoff for up to 30 seconds.
+func (c *Client) Call(serviceMethod string, args interface{}, ret interface{}) error {
+	var buf bytes.Buffer
+	if args != nil {
+		if err := json.NewEncoder(&buf).Encode(args); err != nil {
+			return err
+		}
+	}
+
+	body, err := c.callWithRetry(serviceMethod, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if ret == nil {
+		return nil
+	}
+	return json.NewDecoder(body).Decode(ret)
+}
+
+// Stream invokes serviceMethod on the plugin with args encoded as JSON and
+// returns the body of the response, for methods streaming their response.
+// The caller must close the body.
+func (c *Client) Stream(serviceMethod string, args interface{}) (io.ReadCloser, error) {
+	var buf bytes.Buffer
+	if args != nil {
+		if err := json.NewEncoder(&buf).Encode(args); err != nil {
+			return nil, err
+		}
+	}
+	return c.callWithRetry(serviceMethod, buf.Bytes())
+}
+
+func (c *Client) callWithRetry(serviceMethod string, data []byte) (io.ReadCloser, error) {
+	var (
+		retries int
+		start   = time.Now()
+	)
+
+	for {
+		req, err := http.NewRequest("POST", "/"+serviceMethod, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Accept", versionMimetype)
+		req.URL.Scheme = "http"
+		req.URL.Host = c.addr
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			timeOff := backoff(retries)
+			if abort(start, timeOff) {
+				return nil, err
+			}
+			retries++
+			logrus.Warnf("Unable to connect to plugin: %s, retrying in %v", c.addr, timeOff)
+			time.Sleep(timeOff)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			remoteErr, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return nil, fmt.Errorf("Plugin Error: %s: %s", serviceMethod, err)
+			}
+			return nil, fmt.Errorf("Plugin Error: %s: %s", serviceMethod, strings.TrimSpace(string(remoteErr)))
+		}
+		return resp.Body, nil
+	}
+}
+
+func backoff(retries int) time.Duration {
+	b, max := 1, defaultTimeOut
+	for b < max && retries > 0 {
+		b *= 2
+		retries--
+	}
+	if b > max {
+		b = max
+	}
+	return time.Duration(b) * time.Second
+}
+
+func abort(start time.Time, timeOff time.Duration) bool {
+	return timeOff+time.Since(start) > time.Duration(defaultTimeOut)*time.Second
+}
+
+func configureTransport(tr *http.Transport, proto, addr string) {
+	// Why 32? See https://github.com/docker/docker/pull/8035.
+	timeout := 32 * time.Second
+	if proto == "unix" {
+		// No need for compression in local communications.
+		tr.DisableCompression = true
+		tr.Dial = func(_, _ string) (net.Conn, error) {
+			return net.DialTimeout(proto, addr, timeout)
+		}
+	} else {
+		tr.Proxy = http.ProxyFromEnvironment
+		tr.Dial = (&net.Dialer{Timeout: timeout}).Dial
+	}
+}
@@ -0,0 +1,140 @@
+package plugins
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupRemotePluginServer() (*http.ServeMux, *httptest.Server) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	return mux, server
+}
+
+func TestNewClientInvalidAddr(t *testing.T) {
+	if _, err := NewClient("localhost:8080"); err == nil {
+		t.Fatal("Expected an error for an address without protocol")
+	}
+}
+
+func TestEchoInputOutput(t *testing.T) {
+	mux, server := setupRemotePluginServer()
+	defer server.Close()
+
+	m := Manifest{[]string{"VolumeDriver", "NetworkDriver"}}
+
+	mux.HandleFunc("/Test.Echo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Fatalf("Expected POST, got %s\n", r.Method)
+		}
+
+		header := w.Header()
+		header.Set("Content-Type", versionMimetype)
+
+		io.Copy(w, r.Body)
+	})
+
+	c, err := NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output Manifest
+	if err := c.Call("Test.Echo", m, &output); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(output.Implements) != 2 || output.Implements[1] != "NetworkDriver" {
+		t.Fatalf("Expected %v, was %v\n", m, output)
+	}
+}
+
+func TestStream(t *testing.T) {
+	mux, server := setupRemotePluginServer()
+	defer server.Close()
+
+	mux.HandleFunc("/Test.Echo", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+		io.WriteString(w, "more\n")
+	})
+
+	c, err := NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := c.Stream("Test.Echo", map[string]string{"Name": "data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	output, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != "{\"Name\":\"data\"}\nmore\n" {
+		t.Fatalf("Wrong stream %q", output)
+	}
+}
+
+func TestRemoteError(t *testing.T) {
+	mux, server := setupRemotePluginServer()
+	defer server.Close()
+
+	mux.HandleFunc("/Test.Fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such network", http.StatusInternalServerError)
+	})
+
+	c, err := NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Call("Test.Fail", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "no such network") {
+		t.Fatalf("Expected the remote error to be returned, got %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		retries    int
+		expTimeOff time.Duration
+	}{
+		{0, time.Duration(1)},
+		{1, time.Duration(2)},
+		{2, time.Duration(4)},
+		{4, time.Duration(16)},
+		{6, time.Duration(30)},
+		{10, time.Duration(30)},
+	}
+
+	for _, c := range cases {
+		s := c.expTimeOff * time.Second
+		if d := backoff(c.retries); d != s {
+			t.Fatalf("Retry %v, expected %v, was %v\n", c.retries, s, d)
+		}
+	}
+}
+
+func TestAbortRetry(t *testing.T) {
+	cases := []struct {
+		timeOff  time.Duration
+		expAbort bool
+	}{
+		{time.Duration(1), false},
+		{time.Duration(2), false},
+		{time.Duration(10), false},
+		{time.Duration(20), false},
+		{time.Duration(30), true},
+	}
+
+	for _, c := range cases {
+		s := c.timeOff * time.Second
+		if a := abort(time.Now(), s); a != c.expAbort {
+			t.Fatalf("Duration %v, expected %v, was %v\n", c.timeOff, s, a)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when no plugin with the requested name is
+	// installed.
+	ErrNotFound = errors.New("Plugin not found")
+
+	socketsPath = "/run/docker/plugins"
+	specsPaths  = []string{"/etc/docker/plugins", "/usr/lib/docker/plugins"}
+)
+
+// Registry finds installed plugins.
+type Registry interface {
+	Plugins() ([]*Plugin, error)
+	Plugin(name string) (*Plugin, error)
+}
+
+// LocalRegistry finds plugins installed on the local host, either as a
+// unix socket in /run/docker/plugins or as a spec file holding the plugin
+// address in /etc/docker/plugins or /usr/lib/docker/plugins.
+type LocalRegistry struct{}
+
+func newLocalRegistry() LocalRegistry {
+	return LocalRegistry{}
+}
+
+// Plugins returns all the plugins installed on the host.
+func (l LocalRegistry) Plugins() ([]*Plugin, error) {
+	var (
+		plugins []*Plugin
+		seen    = make(map[string]struct{})
+	)
+
+	patterns := []string{filepath.Join(socketsPath, "*.sock")}
+	for _, p := range specsPaths {
+		patterns = append(patterns, filepath.Join(p, "*.spec"))
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			name := strings.TrimSuffix(filepath.Base(m), filepath.Ext(m))
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			p, err := l.Plugin(name)
+			if err != nil {
+				continue
+			}
+			seen[name] = struct{}{}
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins, nil
+}
+
+// Plugin returns the plugin named name. Sockets take precedence over spec
+// files.
+func (l LocalRegistry) Plugin(name string) (*Plugin, error) {
+	socketPath := filepath.Join(socketsPath, name+".sock")
+	if fi, err := os.Stat(socketPath); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		return newLocalPlugin(name, "unix://"+socketPath), nil
+	}
+
+	for _, p := range specsPaths {
+		specPath := filepath.Join(p, name+".spec")
+		if _, err := os.Stat(specPath); err == nil {
+			return readPluginInfo(name, specPath)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func readPluginInfo(name, path string) (*Plugin, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	addr := strings.TrimSpace(string(content))
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "unix" && u.Scheme != "tcp" {
+		return nil, fmt.Errorf("Unknown protocol for plugin %s: %s", name, addr)
+	}
+
+	return newLocalPlugin(name, addr), nil
+}
@@ -0,0 +1,107 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupLocalRegistry(t *testing.T) (string, func()) {
+	tmpdir, err := ioutil.TempDir("", "docker-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSockets, oldSpecs := socketsPath, specsPaths
+	socketsPath = filepath.Join(tmpdir, "run")
+	specsPaths = []string{filepath.Join(tmpdir, "etc")}
+	for _, p := range []string{socketsPath, specsPaths[0]} {
+		if err := os.MkdirAll(p, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tmpdir, func() {
+		socketsPath, specsPaths = oldSockets, oldSpecs
+		os.RemoveAll(tmpdir)
+	}
+}
+
+func TestUnknownLocalPath(t *testing.T) {
+	_, cleanup := setupLocalRegistry(t)
+	defer cleanup()
+
+	if _, err := newLocalRegistry().Plugin("unknown"); err != ErrNotFound {
+		t.Fatalf("Expected %v, got %v", ErrNotFound, err)
+	}
+}
+
+func TestLocalSocket(t *testing.T) {
+	_, cleanup := setupLocalRegistry(t)
+	defer cleanup()
+
+	l, err := net.Listen("unix", filepath.Join(socketsPath, "echo.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// a spec file with the same name is shadowed by the socket
+	if err := ioutil.WriteFile(filepath.Join(specsPaths[0], "echo.spec"), []byte("tcp://localhost:8080"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newLocalRegistry().Plugin("echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "unix://" + filepath.Join(socketsPath, "echo.sock"); p.Addr != expected {
+		t.Fatalf("Expected plugin address %s, got %s", expected, p.Addr)
+	}
+	if p.Name != "echo" {
+		t.Fatalf("Expected plugin name echo, got %s", p.Name)
+	}
+}
+
+func TestFileSpecPlugin(t *testing.T) {
+	_, cleanup := setupLocalRegistry(t)
+	defer cleanup()
+
+	cases := []struct {
+		name string
+		addr string
+		fail bool
+	}{
+		{"echo", "unix://var/lib/docker/plugins/echo.sock", false},
+		{"foo", "tcp://localhost:8080", false},
+		{"bar", "localhost:8080", true}, // unknown protocol
+	}
+
+	for _, c := range cases {
+		if err := ioutil.WriteFile(filepath.Join(specsPaths[0], c.name+".spec"), []byte(c.addr+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		p, err := newLocalRegistry().Plugin(c.name)
+		if c.fail {
+			if err == nil {
+				t.Fatalf("Expected an error reading spec for %s", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Addr != c.addr {
+			t.Fatalf("Expected plugin addr %s, got %s", c.addr, p.Addr)
+		}
+	}
+
+	plugins, err := newLocalRegistry().Plugins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != 2 {
+		t.Fatalf("Expected 2 valid plugins, got %d", len(plugins))
+	}
+}
@@ -0,0 +1,129 @@
+// Package plugins provides discovery of and communication with out of process
+// extensions to the daemon.
+//
+// A plugin is an HTTP server listening on a unix socket or a TCP address.
+// When a plugin is first requested the daemon activates it by POSTing to
+// /Plugin.Activate; the plugin answers with a manifest listing the
+// subsystems it implements, e.g. {"Implements": ["NetworkDriver"]}.
+// Subsystems register a handler with Handle to be told about every
+// activated plugin implementing them.
+package plugins
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// ErrNotImplements is returned when a plugin does not implement the
+// requested subsystem.
+var ErrNotImplements = errors.New("Plugin does not implement the requested driver")
+
+type plugins struct {
+	sync.Mutex
+	plugins map[string]*Plugin
+}
+
+var (
+	storage          = plugins{plugins: make(map[string]*Plugin)}
+	extpointHandlers = make(map[string]func(string, *Client))
+)
+
+// Manifest lists what a plugin implements.
+type Manifest struct {
+	// Implements is a list of the subsystems this plugin implements,
+	// e.g. "NetworkDriver".
+	Implements []string
+}
+
+// Plugin is an activated plugin.
+type Plugin struct {
+	Name     string    `json:"-"`
+	Client   *Client   `json:"-"`
+	Manifest *Manifest `json:"-"`
+	Addr     string
+}
+
+func newLocalPlugin(name, addr string) *Plugin {
+	return &Plugin{
+		Name: name,
+		Addr: addr,
+	}
+}
+
+func (p *Plugin) activate() error {
+	c, err := NewClient(p.Addr)
+	if err != nil {
+		return err
+	}
+	p.Client = c
+
+	m := new(Manifest)
+	if err := p.Client.Call("Plugin.Activate", nil, m); err != nil {
+		return err
+	}
+
+	logrus.Debugf("%s's manifest: %v", p.Name, m)
+	p.Manifest = m
+
+	for _, iface := range m.Implements {
+		handler, handled := extpointHandlers[iface]
+		if !handled {
+			continue
+		}
+		handler(p.Name, p.Client)
+	}
+	return nil
+}
+
+func load(name string) (*Plugin, error) {
+	registry := newLocalRegistry()
+	pl, err := registry.Plugin(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := pl.activate(); err != nil {
+		return nil, err
+	}
+	return pl, nil
+}
+
+func get(name string) (*Plugin, error) {
+	storage.Lock()
+	defer storage.Unlock()
+	pl, ok := storage.plugins[name]
+	if ok {
+		return pl, nil
+	}
+	pl, err := load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Debugf("Plugin: %v", pl)
+	storage.plugins[name] = pl
+	return pl, nil
+}
+
+// Get returns the plugin named name, activating it if needed, provided it
+// implements the subsystem imp.
+func Get(name, imp string) (*Plugin, error) {
+	pl, err := get(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, driver := range pl.Manifest.Implements {
+		logrus.Debugf("%s implements: %s", name, driver)
+		if driver == imp {
+			return pl, nil
+		}
+	}
+	return nil, ErrNotImplements
+}
+
+// Handle registers fn to be called with the name and client of every
+// activated plugin implementing the subsystem iface.
+func Handle(iface string, fn func(string, *Client)) {
+	extpointHandlers[iface] = fn
+}
@@ -11,6 +11,8 @@ package pools
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"io"
 	"sync"
 
@@ -22,9 +24,36 @@ var (
 	BufioReader32KPool *BufioReaderPool
 	// Pool which returns bufio.Writer with a 32K buffer
 	BufioWriter32KPool *BufioWriterPool
+
+	// Pool which returns 4K buffers
+	Buffer4KPool *BufferPool
+	// Pool which returns 32K buffers
+	Buffer32KPool *BufferPool
+	// Pool which returns 256K buffers
+	Buffer256KPool *BufferPool
+
+	// the buffer pools by increasing size
+	bufferPools []*BufferPool
+
+	// Pool which returns gzip.Reader
+	GzipReaders *GzipReaderPool
+	// Pool which returns gzip.Writer with the default compression
+	GzipWriters *GzipWriterPool
+	// Pool which returns bytes.Buffer
+	BytesBuffers *BytesBufferPool
+)
+
+const (
+	// the largest bytes.Buffer put back into BytesBuffers, not to hold
+	// on to the memory of the few large ones
+	maxPooledBytesBuffer = 1024 * 1024
 )
 
-const buffer32K = 32 * 1024
+const (
+	buffer4K   = 4 * 1024
+	buffer32K  = 32 * 1024
+	buffer256K = 256 * 1024
+)
 
 type BufioReaderPool struct {
 	pool sync.Pool
@@ -33,6 +62,13 @@ type BufioReaderPool struct {
 func init() {
 	BufioReader32KPool = newBufioReaderPoolWithSize(buffer32K)
 	BufioWriter32KPool = newBufioWriterPoolWithSize(buffer32K)
+	Buffer4KPool = newBufferPoolWithSize(buffer4K)
+	Buffer32KPool = newBufferPoolWithSize(buffer32K)
+	Buffer256KPool = newBufferPoolWithSize(buffer256K)
+	bufferPools = []*BufferPool{Buffer4KPool, Buffer32KPool, Buffer256KPool}
+	GzipReaders = &GzipReaderPool{}
+	GzipWriters = &GzipWriterPool{}
+	BytesBuffers = &BytesBufferPool{}
 }
 
 // newBufioReaderPoolWithSize is unexported because new pools should be
@@ -107,3 +143,176 @@ func (bufPool *BufioWriterPool) NewWriteCloserWrapper(buf *bufio.Writer, w io.Wr
 		return nil
 	})
 }
+
+type BufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+// newBufferPoolWithSize is unexported because new pools should be
+// added here to be shared where required.
+func newBufferPoolWithSize(size int) *BufferPool {
+	bufPool := &BufferPool{size: size}
+	bufPool.pool.New = func() interface{} { return make([]byte, size) }
+	return bufPool
+}
+
+// Get returns a buffer whose length is the size of the pool.
+func (bufPool *BufferPool) Get() []byte {
+	return bufPool.pool.Get().([]byte)
+}
+
+// Put puts the buffer back into the pool. Buffers whose capacity isn't the
+// size of the pool are dropped.
+func (bufPool *BufferPool) Put(buf []byte) {
+	if cap(buf) != bufPool.size {
+		return
+	}
+	bufPool.pool.Put(buf[:bufPool.size])
+}
+
+// GetBuffer returns a buffer of length size from the smallest pool whose
+// buffers are large enough, or a new buffer if size is larger than those of
+// all the pools.
+func GetBuffer(size int) []byte {
+	for _, bufPool := range bufferPools {
+		if size <= bufPool.size {
+			return bufPool.Get()[:size]
+		}
+	}
+	return make([]byte, size)
+}
+
+// PutBuffer puts a buffer returned by GetBuffer back into its pool.
+func PutBuffer(buf []byte) {
+	for _, bufPool := range bufferPools {
+		if cap(buf) == bufPool.size {
+			bufPool.Put(buf)
+			return
+		}
+	}
+}
+
+// Copy is io.Copy with a buffer of Buffer32KPool.
+func Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	// io.Copy doesn't need a buffer for them
+	if wt, ok := src.(io.WriterTo); ok {
+		return wt.WriteTo(dst)
+	}
+	if rt, ok := dst.(io.ReaderFrom); ok {
+		return rt.ReadFrom(src)
+	}
+
+	buf := Buffer32KPool.Get()
+	defer Buffer32KPool.Put(buf)
+	for {
+		nr, er := src.Read(buf)
+		if nr > 0 {
+			nw, ew := dst.Write(buf[0:nr])
+			if nw > 0 {
+				written += int64(nw)
+			}
+			if ew != nil {
+				err = ew
+				break
+			}
+			if nr != nw {
+				err = io.ErrShortWrite
+				break
+			}
+		}
+		if er == io.EOF {
+			break
+		}
+		if er != nil {
+			err = er
+			break
+		}
+	}
+	return written, err
+}
+
+type GzipReaderPool struct {
+	pool sync.Pool
+}
+
+// Get returns a gzip.Reader which reads from r, which is reset rather than
+// allocated if the pool has one. Like gzip.NewReader, it reads the header
+// of the stream, and it buffers r unless it is a flate.Reader.
+func (gzPool *GzipReaderPool) Get(r io.Reader) (*gzip.Reader, error) {
+	if gz, ok := gzPool.pool.Get().(*gzip.Reader); ok {
+		if err := gz.Reset(r); err != nil {
+			gzPool.pool.Put(gz)
+			return nil, err
+		}
+		return gz, nil
+	}
+	return gzip.NewReader(r)
+}
+
+// Put puts the gzip.Reader back into the pool.
+func (gzPool *GzipReaderPool) Put(gz *gzip.Reader) {
+	gzPool.pool.Put(gz)
+}
+
+// NewReadCloser returns the decompressed stream of the gzip stream read
+// from r, which is read through a bufio.Reader of BufioReader32KPool.
+// Closing it closes its gzip.Reader and puts it and the bufio.Reader back
+// into their pools.
+func (gzPool *GzipReaderPool) NewReadCloser(r io.Reader) (io.ReadCloser, error) {
+	buf := BufioReader32KPool.Get(r)
+	gz, err := gzPool.Get(buf)
+	if err != nil {
+		BufioReader32KPool.Put(buf)
+		return nil, err
+	}
+	return ioutils.NewReadCloserWrapper(gz, func() error {
+		err := gz.Close()
+		gzPool.Put(gz)
+		BufioReader32KPool.Put(buf)
+		return err
+	}), nil
+}
+
+type GzipWriterPool struct {
+	pool sync.Pool
+}
+
+// Get returns a gzip.Writer which writes to w with the default
+// compression.
+func (gzPool *GzipWriterPool) Get(w io.Writer) *gzip.Writer {
+	if gz, ok := gzPool.pool.Get().(*gzip.Writer); ok {
+		gz.Reset(w)
+		return gz
+	}
+	return gzip.NewWriter(w)
+}
+
+// Put puts the gzip.Writer back into the pool. It should be closed, its
+// pending data is dropped.
+func (gzPool *GzipWriterPool) Put(gz *gzip.Writer) {
+	gz.Reset(nil)
+	gzPool.pool.Put(gz)
+}
+
+type BytesBufferPool struct {
+	pool sync.Pool
+}
+
+// Get returns an empty bytes.Buffer.
+func (bufPool *BytesBufferPool) Get() *bytes.Buffer {
+	if buf, ok := bufPool.pool.Get().(*bytes.Buffer); ok {
+		return buf
+	}
+	return bytes.NewBuffer(nil)
+}
+
+// Put empties the bytes.Buffer and puts it back into the pool, unless it
+// has grown too large to be held on to.
+func (bufPool *BytesBufferPool) Put(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBytesBuffer {
+		return
+	}
+	buf.Reset()
+	bufPool.pool.Put(buf)
+}
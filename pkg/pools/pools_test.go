@@ -0,0 +1,139 @@
+package pools
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestGetBuffer(t *testing.T) {
+	for _, c := range []struct {
+		size     int
+		capacity int
+	}{
+		{0, buffer4K},
+		{100, buffer4K},
+		{buffer4K, buffer4K},
+		{buffer4K + 1, buffer32K},
+		{buffer32K, buffer32K},
+		{buffer32K + 8, buffer256K},
+		{buffer256K, buffer256K},
+		{buffer256K + 1, buffer256K + 1},
+	} {
+		buf := GetBuffer(c.size)
+		if len(buf) != c.size || cap(buf) != c.capacity {
+			t.Fatalf("Expected a buffer of length %d and capacity %d, got %d and %d", c.size, c.capacity, len(buf), cap(buf))
+		}
+		PutBuffer(buf)
+	}
+}
+
+func TestBufferPoolPut(t *testing.T) {
+	// a buffer of another size must not be returned by the pool
+	Buffer32KPool.Put(make([]byte, buffer4K))
+	if buf := Buffer32KPool.Get(); len(buf) != buffer32K {
+		t.Fatalf("Expected a buffer of length %d, got %d", buffer32K, len(buf))
+	}
+
+	buf := Buffer4KPool.Get()
+	Buffer4KPool.Put(buf[:10])
+	if buf := Buffer4KPool.Get(); len(buf) != buffer4K {
+		t.Fatalf("Expected a buffer of length %d, got %d", buffer4K, len(buf))
+	}
+}
+
+func TestGzipPools(t *testing.T) {
+	// the readers and writers put back are reused for other streams
+	for _, data := range []string{"docker", strings.Repeat("gzip", 10000), ""} {
+		var compressed bytes.Buffer
+		gzW := GzipWriters.Get(&compressed)
+		if _, err := gzW.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err := gzW.Close(); err != nil {
+			t.Fatal(err)
+		}
+		GzipWriters.Put(gzW)
+
+		r, err := GzipReaders.NewReadCloser(&compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decompressed, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if string(decompressed) != data {
+			t.Fatalf("Expected %d bytes to be decompressed, got %d", len(data), len(decompressed))
+		}
+	}
+
+	if _, err := GzipReaders.NewReadCloser(strings.NewReader("not gzip")); err == nil {
+		t.Fatal("Expected reading a stream which isn't gzip to fail")
+	}
+}
+
+func TestBytesBufferPool(t *testing.T) {
+	buf := BytesBuffers.Get()
+	buf.WriteString("docker")
+	BytesBuffers.Put(buf)
+	if buf := BytesBuffers.Get(); buf.Len() != 0 {
+		t.Fatalf("Expected an empty buffer, got %q", buf.String())
+	}
+
+	// a large buffer isn't held on to
+	large := bytes.NewBuffer(make([]byte, 0, maxPooledBytesBuffer+1))
+	BytesBuffers.Put(large)
+	if buf := BytesBuffers.Get(); buf == large {
+		t.Fatal("Expected a large buffer not to be put back into the pool")
+	}
+}
+
+// onlyReader and onlyWriter hide the WriterTo of a reader and the ReaderFrom
+// of a writer from Copy.
+type onlyReader struct {
+	io.Reader
+}
+
+type onlyWriter struct {
+	io.Writer
+}
+
+func TestCopy(t *testing.T) {
+	data := strings.Repeat("docker", 100000)
+	var dst bytes.Buffer
+	n, err := Copy(onlyWriter{&dst}, onlyReader{strings.NewReader(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) || dst.String() != data {
+		t.Fatalf("Expected %d bytes to be copied, got %d", len(data), n)
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	data := bytes.Repeat([]byte("docker"), 100000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Copy(onlyWriter{ioutil.Discard}, onlyReader{bytes.NewReader(data)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIoCopy(b *testing.B) {
+	data := bytes.Repeat([]byte("docker"), 100000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := io.Copy(onlyWriter{ioutil.Discard}, onlyReader{bytes.NewReader(data)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
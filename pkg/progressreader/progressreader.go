@@ -2,6 +2,7 @@ package progressreader
 
 import (
 	"io"
+	"time"
 
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/docker/pkg/streamformatter"
@@ -18,12 +19,16 @@ type Config struct {
 	NewLines   bool
 	ID         string
 	Action     string
+	start      time.Time // Time of the first read
 }
 
 func New(newReader Config) *Config {
 	return &newReader
 }
 func (config *Config) Read(p []byte) (n int, err error) {
+	if config.start.IsZero() {
+		config.start = time.Now()
+	}
 	read, err := config.In.Read(p)
 	config.Current += read
 	updateEvery := 1024 * 512 //512kB
@@ -34,7 +39,7 @@ func (config *Config) Read(p []byte) (n int, err error) {
 		}
 	}
 	if config.Current-config.LastUpdate > updateEvery || err != nil {
-		config.Out.Write(config.Formatter.FormatProgress(config.ID, config.Action, &jsonmessage.JSONProgress{Current: config.Current, Total: config.Size}))
+		config.Out.Write(config.Formatter.FormatProgress(config.ID, config.Action, config.progress()))
 		config.LastUpdate = config.Current
 	}
 	// Send newline when complete
@@ -45,6 +50,22 @@ func (config *Config) Read(p []byte) (n int, err error) {
 }
 func (config *Config) Close() error {
 	config.Current = config.Size
-	config.Out.Write(config.Formatter.FormatProgress(config.ID, config.Action, &jsonmessage.JSONProgress{Current: config.Current, Total: config.Size}))
+	config.Out.Write(config.Formatter.FormatProgress(config.ID, config.Action, config.progress()))
 	return config.In.Close()
 }
+
+// progress returns the progress of the reading, with the average speed since
+// the first read and the time left at that speed, once reading for a second.
+func (config *Config) progress() *jsonmessage.JSONProgress {
+	p := &jsonmessage.JSONProgress{Current: config.Current, Total: config.Size}
+	if config.start.IsZero() {
+		return p
+	}
+	if elapsed := time.Since(config.start); elapsed >= time.Second {
+		p.Speed = int64(float64(config.Current)/elapsed.Seconds() + 0.5)
+		if p.Speed > 0 && config.Size > config.Current {
+			p.ETA = int64(config.Size-config.Current) / p.Speed
+		}
+	}
+	return p
+}
@@ -0,0 +1,28 @@
+package progressreader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	config := &Config{Size: 3000, Current: 1000}
+	if p := config.progress(); p.Speed != 0 || p.ETA != 0 {
+		t.Fatalf("Expected no speed before reading, got %+v", p)
+	}
+
+	config.start = time.Now().Add(-500 * time.Millisecond)
+	if p := config.progress(); p.Speed != 0 || p.ETA != 0 {
+		t.Fatalf("Expected no speed during the first second, got %+v", p)
+	}
+
+	config.start = time.Now().Add(-10 * time.Second)
+	if p := config.progress(); p.Current != 1000 || p.Total != 3000 || p.Speed != 100 || p.ETA != 20 {
+		t.Fatalf("Expected 100 B/s and 20s left, got %+v", p)
+	}
+
+	config.Current = config.Size
+	if p := config.progress(); p.Speed != 300 || p.ETA != 0 {
+		t.Fatalf("Expected 300 B/s and no time left, got %+v", p)
+	}
+}
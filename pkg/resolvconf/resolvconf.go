@@ -30,6 +30,7 @@ var (
 	nsIPv6Regexp      = regexp.MustCompile(`(?m)^nameserver\s+` + ipv6Address + `\s*\n*`)
 	nsRegexp          = regexp.MustCompile(`^\s*nameserver\s*((` + ipv4Address + `)|(` + ipv6Address + `))\s*$`)
 	searchRegexp      = regexp.MustCompile(`^\s*search\s*(([^\s]+\s*)*)$`)
+	optionsRegexp     = regexp.MustCompile(`^\s*options\s*(([^\s]+\s*)*)$`)
 )
 
 var lastModified struct {
@@ -165,23 +166,43 @@ func GetSearchDomains(resolvConf []byte) []string {
 	return domains
 }
 
-// Build writes a configuration file to path containing a "nameserver" entry
-// for every element in dns, and a "search" entry for every element in
-// dnsSearch.
-func Build(path string, dns, dnsSearch []string) error {
+// GetOptions returns options (if any) listed in /etc/resolv.conf
+// If more than one options line is encountered, only the contents of the last
+// one is returned.
+func GetOptions(resolvConf []byte) []string {
+	options := []string{}
+	for _, line := range getLines(resolvConf, []byte("#")) {
+		match := optionsRegexp.FindSubmatch(line)
+		if match == nil {
+			continue
+		}
+		options = strings.Fields(string(match[1]))
+	}
+	return options
+}
+
+// Generate returns a configuration containing a "nameserver" entry for every
+// element in dns, a "search" entry for every element in dnsSearch and an
+// "options" entry for every element in dnsOptions.
+func Generate(dns, dnsSearch, dnsOptions []string) []byte {
 	content := bytes.NewBuffer(nil)
 	for _, dns := range dns {
-		if _, err := content.WriteString("nameserver " + dns + "\n"); err != nil {
-			return err
-		}
+		content.WriteString("nameserver " + dns + "\n")
 	}
 	if len(dnsSearch) > 0 {
 		if searchString := strings.Join(dnsSearch, " "); strings.Trim(searchString, " ") != "." {
-			if _, err := content.WriteString("search " + searchString + "\n"); err != nil {
-				return err
-			}
+			content.WriteString("search " + searchString + "\n")
 		}
 	}
+	if len(dnsOptions) > 0 {
+		content.WriteString("options " + strings.Join(dnsOptions, " ") + "\n")
+	}
+	return content.Bytes()
+}
 
-	return ioutil.WriteFile(path, content.Bytes(), 0644)
+// Build writes a configuration file to path containing a "nameserver" entry
+// for every element in dns, a "search" entry for every element in
+// dnsSearch and an "options" entry for every element in dnsOptions.
+func Build(path string, dns, dnsSearch, dnsOptions []string) error {
+	return ioutil.WriteFile(path, Generate(dns, dnsSearch, dnsOptions), 0644)
 }
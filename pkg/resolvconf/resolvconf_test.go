@@ -96,6 +96,30 @@ nameserver 4.30.20.100`: {"foo.example.com", "example.com"},
 	}
 }
 
+func TestGetOptions(t *testing.T) {
+	for resolv, result := range map[string][]string{
+		`options opt1`:                    {"opt1"},
+		`options opt1 # ignored`:          {"opt1"},
+		` 	  options 	 opt1 	  `:         {"opt1"},
+		`options ndots:2 timeout:3 rotate`: {"ndots:2", "timeout:3", "rotate"},
+		``:                                {},
+		`# ignored`:                       {},
+		`nameserver 1.2.3.4
+options opt1`: {"opt1"},
+		`nameserver 1.2.3.4
+options dup1
+options ndots:2 timeout:3`: {"ndots:2", "timeout:3"},
+		`nameserver 1.2.3.4
+options opt1 opt2
+search example.com`: {"opt1", "opt2"},
+	} {
+		test := GetOptions([]byte(resolv))
+		if !strSlicesEqual(test, result) {
+			t.Fatalf("Wrong options string {%s} should be %v. Input: %s", test, result, resolv)
+		}
+	}
+}
+
 func strSlicesEqual(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -117,7 +141,7 @@ func TestBuild(t *testing.T) {
 	}
 	defer os.Remove(file.Name())
 
-	err = Build(file.Name(), []string{"ns1", "ns2", "ns3"}, []string{"search1"})
+	err = Build(file.Name(), []string{"ns1", "ns2", "ns3"}, []string{"search1"}, []string{"opt1"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -127,7 +151,7 @@ func TestBuild(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if expected := "nameserver ns1\nnameserver ns2\nnameserver ns3\nsearch search1\n"; !bytes.Contains(content, []byte(expected)) {
+	if expected := "nameserver ns1\nnameserver ns2\nnameserver ns3\nsearch search1\noptions opt1\n"; !bytes.Contains(content, []byte(expected)) {
 		t.Fatalf("Expected to find '%s' got '%s'", expected, content)
 	}
 }
@@ -139,7 +163,7 @@ func TestBuildWithZeroLengthDomainSearch(t *testing.T) {
 	}
 	defer os.Remove(file.Name())
 
-	err = Build(file.Name(), []string{"ns1", "ns2", "ns3"}, []string{"."})
+	err = Build(file.Name(), []string{"ns1", "ns2", "ns3"}, []string{"."}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
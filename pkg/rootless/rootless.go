@@ -0,0 +1,59 @@
+// Package rootless runs the daemon as an unprivileged user.
+//
+// The daemon re-executes itself as the root of user, mount and network
+// namespaces of its own, where the user is mapped to root, and runs the
+// containers in these namespaces. The network namespace is connected to the
+// network of the host by slirp4netns, when installed.
+package rootless
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/pkg/homedir"
+)
+
+var (
+	// enabled is whether the daemon runs rootless, set once Setup succeeded.
+	enabled bool
+	// slirpAPI is the API socket of the slirp4netns of the daemon, if any.
+	slirpAPI string
+)
+
+// Enabled returns whether the daemon runs rootless, in the namespaces set up
+// by Run.
+func Enabled() bool {
+	return enabled
+}
+
+// RuntimeDir returns the directory of the sockets and state of a daemon
+// running rootless, $XDG_RUNTIME_DIR.
+func RuntimeDir() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return "", errors.New("XDG_RUNTIME_DIR needs to be set to run the daemon rootless")
+	}
+	return dir, nil
+}
+
+// DataDir returns the default root of a daemon running rootless,
+// $XDG_DATA_HOME/docker.
+func DataDir() string {
+	return filepath.Join(xdgDir("XDG_DATA_HOME", ".local/share"), "docker")
+}
+
+// ConfigDir returns the directory of the configuration of a daemon running
+// rootless, $XDG_CONFIG_HOME/docker.
+func ConfigDir() string {
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "docker")
+}
+
+// xdgDir returns the directory of the environment variable env, def under
+// the home directory by default.
+func xdgDir(env, def string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	return filepath.Join(homedir.Get(), def)
+}
@@ -0,0 +1,357 @@
+// +build linux
+
+package rootless
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/mount"
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/user"
+)
+
+const (
+	// childEnv marks the daemon re-executed by Run in its namespaces.
+	childEnv = "_DOCKER_ROOTLESS_CHILD"
+	// setupEnv holds the setup of the namespaces, once the daemon executed
+	// itself again with its IDs mapped.
+	setupEnv = "_DOCKER_ROOTLESS_SETUP"
+	// syncFd is the pipe on which Run sends the setup of the namespaces to
+	// the daemon.
+	syncFd = 3
+)
+
+// setup is sent by Run to the daemon once its namespaces are set up.
+type setup struct {
+	Slirp4netnsAPI string
+}
+
+// IsChild returns whether the process is the daemon re-executed by Run.
+func IsChild() bool {
+	return os.Getenv(childEnv) != ""
+}
+
+// Run re-executes the process with the same arguments as the root of new
+// user, mount and network namespaces, and returns its exit status once it
+// exited. The signals received meanwhile are forwarded to it.
+func Run() (int, error) {
+	if os.Geteuid() == 0 {
+		return 0, errors.New("--rootless runs the daemon as an unprivileged user, not as root")
+	}
+	runtimeDir, err := RuntimeDir()
+	if err != nil {
+		return 0, err
+	}
+	stateDir := filepath.Join(runtimeDir, "docker")
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return 0, err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	cmd := exec.Command("/proc/self/exe", os.Args[1:]...)
+	cmd.Args[0] = os.Args[0]
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), childEnv+"=1")
+	cmd.ExtraFiles = []*os.File{r}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET,
+		Pdeathsig:  syscall.SIGKILL,
+	}
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return 0, fmt.Errorf("Could not create the namespaces of the daemon: %v", err)
+	}
+	r.Close()
+
+	// the daemon waits for the setup until w is closed
+	s, slirp, err := setupNamespaces(cmd.Process.Pid, stateDir)
+	if err == nil {
+		err = json.NewEncoder(w).Encode(s)
+	}
+	w.Close()
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return 0, err
+	}
+	if slirp != nil {
+		defer func() {
+			slirp.Process.Kill()
+			slirp.Wait()
+		}()
+	}
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1)
+	go func() {
+		for sig := range sigc {
+			cmd.Process.Signal(sig)
+		}
+	}()
+	err = cmd.Wait()
+	signal.Stop(sigc)
+	close(sigc)
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return 0, err
+	}
+	status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if status.Signaled() {
+		return 128 + int(status.Signal()), nil
+	}
+	return status.ExitStatus(), nil
+}
+
+// setupNamespaces maps the IDs of the user namespace of the process pid and
+// connects its network namespace to the network of the host. It returns the
+// setup to send to the process, and the slirp4netns started, if any.
+func setupNamespaces(pid int, stateDir string) (*setup, *exec.Cmd, error) {
+	if err := writeIDMaps(pid); err != nil {
+		return nil, nil, err
+	}
+	s := &setup{}
+	socket := filepath.Join(stateDir, "slirp4netns.sock")
+	slirp, err := startSlirp(pid, socket)
+	if err != nil {
+		return nil, nil, err
+	}
+	if slirp != nil {
+		s.Slirp4netnsAPI = socket
+	}
+	return s, slirp, nil
+}
+
+// writeIDMaps maps root in the user namespace of the process pid to the user,
+// and the other IDs to the subordinate IDs of the user in /etc/subuid and
+// /etc/subgid through newuidmap and newgidmap. Without them, only root is
+// mapped.
+func writeIDMaps(pid int) error {
+	uid, gid := os.Getuid(), os.Getgid()
+	uidMaps, gidMaps, err := subIDMaps(uid, gid)
+	if err != nil {
+		logrus.Warnf("Only root is mapped in the containers, which can't switch to other users nor use files owned by them: %v", err)
+		return writeRootMaps(pid, uid, gid)
+	}
+	if err := newIDMap("newuidmap", pid, uid, uidMaps); err != nil {
+		return err
+	}
+	return newIDMap("newgidmap", pid, gid, gidMaps)
+}
+
+// subIDMaps returns the subordinate IDs of the user uid and the group gid.
+func subIDMaps(uid, gid int) ([]idtools.IDMap, []idtools.IDMap, error) {
+	for _, helper := range []string{"newuidmap", "newgidmap"} {
+		if _, err := exec.LookPath(helper); err != nil {
+			return nil, nil, fmt.Errorf("%s is not installed", helper)
+		}
+	}
+	u, err := user.LookupUid(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := user.LookupGid(gid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return idtools.CreateIDMappings(u.Name, g.Name)
+}
+
+// newIDMap maps root in the user namespace of the process pid to id, and the
+// IDs from 1 to the ranges of maps, with helper.
+func newIDMap(helper string, pid, id int, maps []idtools.IDMap) error {
+	args := []string{strconv.Itoa(pid), "0", strconv.Itoa(id), "1"}
+	for _, m := range maps {
+		args = append(args, strconv.Itoa(m.ContainerID+1), strconv.Itoa(m.HostID), strconv.Itoa(m.Size))
+	}
+	if out, err := exec.Command(helper, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %v: %s", helper, err, out)
+	}
+	return nil
+}
+
+// writeRootMaps maps root in the user namespace of the process pid to the
+// user uid and the group gid, which needs no privileges.
+func writeRootMaps(pid, uid, gid int) error {
+	proc := filepath.Join("/proc", strconv.Itoa(pid))
+	if err := ioutil.WriteFile(filepath.Join(proc, "uid_map"), []byte(fmt.Sprintf("0 %d 1\n", uid)), 0); err != nil {
+		return fmt.Errorf("Could not write the UID map of the daemon: %v", err)
+	}
+	// an unprivileged process can map its group once setgroups is denied
+	if err := ioutil.WriteFile(filepath.Join(proc, "setgroups"), []byte("deny"), 0); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(proc, "gid_map"), []byte(fmt.Sprintf("0 %d 1\n", gid)), 0); err != nil {
+		return fmt.Errorf("Could not write the GID map of the daemon: %v", err)
+	}
+	return nil
+}
+
+// startSlirp connects the network namespace of the process pid to the network
+// of the host with slirp4netns, serving its API on socket. It returns nil if
+// slirp4netns is not installed.
+func startSlirp(pid int, socket string) (*exec.Cmd, error) {
+	if _, err := exec.LookPath("slirp4netns"); err != nil {
+		logrus.Warn("slirp4netns is not installed: the containers can't reach the network, and their ports can't be published")
+		return nil, nil
+	}
+	os.Remove(socket)
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	cmd := exec.Command("slirp4netns", "--configure", "--mtu=65520", "--disable-host-loopback",
+		"--api-socket", socket, "--ready-fd=3", strconv.Itoa(pid), "tap0")
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{w}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+	if err := cmd.Start(); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("Could not start slirp4netns: %v", err)
+	}
+	w.Close()
+	// slirp4netns writes to the ready fd once tap0 is configured
+	if _, err := r.Read(make([]byte, 1)); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, errors.New("slirp4netns failed to set up the network of the daemon")
+	}
+	return cmd, nil
+}
+
+// Setup finishes setting up the namespaces of the daemon re-executed by Run.
+// It must be called before the daemon mounts anything.
+func Setup() error {
+	data := os.Getenv(setupEnv)
+	if data == "" {
+		f := os.NewFile(syncFd, "rootless-sync")
+		var s setup
+		err := json.NewDecoder(f).Decode(&s)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("Could not set up the namespaces of the daemon: %v", err)
+		}
+		// the capabilities of the daemon in its user namespace were
+		// computed as it was executed, before its IDs were mapped: it
+		// executes itself again to get them
+		b, err := json.Marshal(&s)
+		if err != nil {
+			return err
+		}
+		os.Setenv(setupEnv, string(b))
+		return syscall.Exec("/proc/self/exe", os.Args, os.Environ())
+	}
+	var s setup
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return fmt.Errorf("Could not set up the namespaces of the daemon: %v", err)
+	}
+	os.Unsetenv(childEnv)
+	os.Unsetenv(setupEnv)
+	if os.Geteuid() != 0 {
+		return errors.New("The daemon is not mapped to root in its user namespace")
+	}
+	runtimeDir, err := RuntimeDir()
+	if err != nil {
+		return err
+	}
+
+	// the mounts of the daemon stay in its mount namespace
+	if err := mount.MakeRPrivate("/"); err != nil {
+		return fmt.Errorf("Could not make the mounts of the daemon private: %v", err)
+	}
+	// the sysfs of the host shows the network interfaces of the host
+	if err := syscall.Mount("sysfs", "/sys", "sysfs", 0, ""); err != nil {
+		return fmt.Errorf("Could not mount the sysfs of the daemon: %v", err)
+	}
+	// the daemon writes its state to /run/docker, owned by root on the host
+	binds := map[string]string{"docker": filepath.Join(runtimeDir, "docker")}
+	if err := copyUp("/run", binds); err != nil {
+		return err
+	}
+	if fi, err := os.Lstat("/var/run"); err == nil && fi.Mode()&os.ModeSymlink == 0 {
+		if err := copyUp("/var/run", binds); err != nil {
+			return err
+		}
+	}
+
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		return err
+	}
+	if err := netlink.NetworkLinkUp(lo); err != nil {
+		return fmt.Errorf("Could not bring up the loopback of the daemon: %v", err)
+	}
+
+	enabled = true
+	slirpAPI = s.Slirp4netnsAPI
+	return nil
+}
+
+// copyUp replaces dir by a tmpfs writable by the daemon, where each entry of
+// dir is a symbolic link to the original one, under dir/.ro, except the
+// entries of binds which are bind mounts of the directories they map to.
+func copyUp(dir string, binds map[string]string) error {
+	tmp, err := ioutil.TempDir("", "docker-rootless")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	if err := syscall.Mount("tmpfs", tmp, "tmpfs", 0, "mode=755"); err != nil {
+		return fmt.Errorf("Could not copy up %s: %v", dir, err)
+	}
+	fail := func(err error) error {
+		syscall.Unmount(tmp, syscall.MNT_DETACH)
+		return fmt.Errorf("Could not copy up %s: %v", dir, err)
+	}
+
+	ro := filepath.Join(tmp, ".ro")
+	if err := os.Mkdir(ro, 0755); err != nil {
+		return fail(err)
+	}
+	if err := syscall.Mount(dir, ro, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fail(err)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fail(err)
+	}
+	for _, e := range entries {
+		if _, ok := binds[e.Name()]; ok {
+			continue
+		}
+		if err := os.Symlink(filepath.Join(dir, ".ro", e.Name()), filepath.Join(tmp, e.Name())); err != nil {
+			return fail(err)
+		}
+	}
+	for name, src := range binds {
+		if err := os.MkdirAll(src, 0700); err != nil {
+			return fail(err)
+		}
+		target := filepath.Join(tmp, name)
+		if err := os.Mkdir(target, 0700); err != nil {
+			return fail(err)
+		}
+		if err := syscall.Mount(src, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fail(err)
+		}
+	}
+	if err := syscall.Mount(tmp, dir, "", syscall.MS_MOVE, ""); err != nil {
+		return fail(err)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+// +build !linux
+
+package rootless
+
+import "errors"
+
+var errUnsupported = errors.New("The daemon can only run rootless on linux")
+
+// IsChild returns whether the process is the daemon re-executed by Run.
+func IsChild() bool {
+	return false
+}
+
+// Run is not supported on this platform.
+func Run() (int, error) {
+	return 0, errUnsupported
+}
+
+// Setup is not supported on this platform.
+func Setup() error {
+	return errUnsupported
+}
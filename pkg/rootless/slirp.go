@@ -0,0 +1,111 @@
+package rootless
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// slirpGuestAddr is the address of the network namespace of the daemon on
+// the network of slirp4netns.
+const slirpGuestAddr = "10.0.2.100"
+
+// PortForwarder forwards the ports of the host to the network namespace of
+// the daemon, to publish the ports of its containers.
+type PortForwarder interface {
+	// AddForward forwards hostPort of hostIP to port in the namespace, and
+	// returns the id of the forward.
+	AddForward(proto string, hostIP net.IP, hostPort, port int) (int, error)
+	// RemoveForward removes the forward id.
+	RemoveForward(id int) error
+}
+
+// NewPortForwarder returns the forwarder of the ports of the daemon, or nil
+// if the daemon does not run rootless with slirp4netns.
+func NewPortForwarder() PortForwarder {
+	if !enabled || slirpAPI == "" {
+		return nil
+	}
+	return &slirpClient{socket: slirpAPI}
+}
+
+// slirpClient forwards ports through the API of slirp4netns.
+type slirpClient struct {
+	socket string
+}
+
+type slirpRequest struct {
+	Execute   string      `json:"execute"`
+	Arguments interface{} `json:"arguments"`
+}
+
+type slirpHostFwd struct {
+	Proto     string `json:"proto"`
+	HostAddr  string `json:"host_addr"`
+	HostPort  int    `json:"host_port"`
+	GuestAddr string `json:"guest_addr"`
+	GuestPort int    `json:"guest_port"`
+}
+
+type slirpResponse struct {
+	Return struct {
+		ID int `json:"id"`
+	} `json:"return"`
+	Error *struct {
+		Desc string `json:"desc"`
+	} `json:"error"`
+}
+
+func (c *slirpClient) AddForward(proto string, hostIP net.IP, hostPort, port int) (int, error) {
+	if proto != "tcp" && proto != "udp" {
+		return 0, fmt.Errorf("%s ports can't be published by a daemon running rootless", proto)
+	}
+	addr := "0.0.0.0"
+	if hostIP != nil && !hostIP.IsUnspecified() {
+		addr = hostIP.String()
+	}
+	resp, err := c.call(&slirpRequest{
+		Execute: "add_hostfwd",
+		Arguments: &slirpHostFwd{
+			Proto:     proto,
+			HostAddr:  addr,
+			HostPort:  hostPort,
+			GuestAddr: slirpGuestAddr,
+			GuestPort: port,
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Could not forward %s:%d/%s: %v", addr, hostPort, proto, err)
+	}
+	return resp.Return.ID, nil
+}
+
+func (c *slirpClient) RemoveForward(id int) error {
+	_, err := c.call(&slirpRequest{
+		Execute:   "remove_hostfwd",
+		Arguments: map[string]int{"id": id},
+	})
+	return err
+}
+
+// call sends req to slirp4netns, which serves one request per connection.
+func (c *slirpClient) call(req *slirpRequest) (*slirpResponse, error) {
+	conn, err := net.Dial("unix", c.socket)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	conn.(*net.UnixConn).CloseWrite()
+	var resp slirpResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, errors.New(resp.Error.Desc)
+	}
+	return &resp, nil
+}
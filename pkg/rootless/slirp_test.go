@@ -0,0 +1,83 @@
+package rootless
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveSlirp serves the API of slirp4netns on socket, answering each request
+// with respond.
+func serveSlirp(t *testing.T, socket string, respond func(req map[string]interface{}) string) net.Listener {
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			// slirp4netns reads the request until the client shuts down
+			// its side of the connection
+			data, _ := ioutil.ReadAll(conn)
+			var req map[string]interface{}
+			json.Unmarshal(data, &req)
+			conn.Write([]byte(respond(req)))
+			conn.Close()
+		}
+	}()
+	return l
+}
+
+func TestSlirpClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-slirp-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var requests []map[string]interface{}
+	l := serveSlirp(t, filepath.Join(dir, "slirp4netns.sock"), func(req map[string]interface{}) string {
+		requests = append(requests, req)
+		args := req["arguments"].(map[string]interface{})
+		if req["execute"] == "add_hostfwd" && args["host_port"].(float64) == 80 {
+			return `{"error":{"desc":"bad request: add_hostfwd: slirp_add_hostfwd failed"}}`
+		}
+		return `{"return":{"id":7}}`
+	})
+	defer l.Close()
+
+	c := &slirpClient{socket: filepath.Join(dir, "slirp4netns.sock")}
+	id, err := c.AddForward("tcp", nil, 8080, 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 7 {
+		t.Fatalf("Expected the forward 7, got %d", id)
+	}
+	args := requests[0]["arguments"].(map[string]interface{})
+	if requests[0]["execute"] != "add_hostfwd" || args["proto"] != "tcp" || args["host_addr"] != "0.0.0.0" ||
+		args["guest_addr"] != slirpGuestAddr || args["guest_port"].(float64) != 8080 {
+		t.Fatalf("Unexpected request %v", requests[0])
+	}
+
+	if _, err := c.AddForward("udp", net.ParseIP("127.0.0.1"), 80, 80); err == nil || !strings.Contains(err.Error(), "slirp_add_hostfwd failed") {
+		t.Fatalf("Expected the error of slirp4netns, got %v", err)
+	}
+	if _, err := c.AddForward("sctp", nil, 8080, 8080); err == nil {
+		t.Fatal("Expected sctp not to be forwarded")
+	}
+
+	if err := c.RemoveForward(7); err != nil {
+		t.Fatal(err)
+	}
+	if last := requests[len(requests)-1]; last["execute"] != "remove_hostfwd" || last["arguments"].(map[string]interface{})["id"].(float64) != 7 {
+		t.Fatalf("Unexpected request %v", last)
+	}
+}
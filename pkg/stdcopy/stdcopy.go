@@ -4,14 +4,28 @@ import (
 	"encoding/binary"
 	"errors"
 	"io"
+	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/pools"
 )
 
 const (
 	StdWriterPrefixLen = 8
 	StdWriterFdIndex   = 0
 	StdWriterSizeIndex = 4
+
+	// The flags of the extended frames, which carry after their header the
+	// fields they flag, in their order
+	StdWriterFlagsIndex = 1
+	// The length of the identifier of the stream, if flagged
+	StdWriterStreamIDLenIndex = 2
+
+	FlagTimestamp byte = 1 << 0 // the time of the frame, in nanoseconds since the epoch, on 8 bytes
+	FlagStreamID  byte = 1 << 1 // the identifier of the stream of the frame
+
+	timestampLen      = 8
+	maxStreamIDLength = 255
 )
 
 type StdType [StdWriterPrefixLen]byte
@@ -26,20 +40,50 @@ type StdWriter struct {
 	io.Writer
 	prefix  StdType
 	sizeBuf []byte
+
+	// The header and the fields of the extended frames, nil for the others
+	header     []byte
+	timestamps bool
 }
 
 func (w *StdWriter) Write(buf []byte) (n int, err error) {
-	var n1, n2 int
 	if w == nil || w.Writer == nil {
 		return 0, errors.New("Writer not instanciated")
 	}
-	binary.BigEndian.PutUint32(w.prefix[4:], uint32(len(buf)))
-	n1, err = w.Writer.Write(w.prefix[:])
+	if w.header != nil {
+		var t time.Time
+		if w.timestamps {
+			t = time.Now()
+		}
+		return w.WriteFrame(buf, t)
+	}
+	return w.write(w.prefix[:], buf)
+}
+
+// WriteFrame writes buf in a frame carrying the time t, if the writer is an
+// extended one writing timestamps.
+func (w *StdWriter) WriteFrame(buf []byte, t time.Time) (n int, err error) {
+	if w == nil || w.Writer == nil {
+		return 0, errors.New("Writer not instanciated")
+	}
+	if w.header == nil {
+		return w.write(w.prefix[:], buf)
+	}
+	if w.timestamps {
+		binary.BigEndian.PutUint64(w.header[StdWriterPrefixLen:], uint64(t.UnixNano()))
+	}
+	return w.write(w.header, buf)
+}
+
+func (w *StdWriter) write(header, buf []byte) (n int, err error) {
+	var n1, n2 int
+	binary.BigEndian.PutUint32(header[StdWriterSizeIndex:], uint32(len(buf)))
+	n1, err = w.Writer.Write(header)
 	if err != nil {
-		n = n1 - StdWriterPrefixLen
+		n = n1 - len(header)
 	} else {
 		n2, err = w.Writer.Write(buf)
-		n = n1 + n2 - StdWriterPrefixLen
+		n = n1 + n2 - len(header)
 	}
 	if n < 0 {
 		n = 0
@@ -61,8 +105,45 @@ func NewStdWriter(w io.Writer, t StdType) *StdWriter {
 	}
 }
 
+// NewExtendedStdWriter instanciates a new Writer encapsulating its writes
+// in extended frames, which carry the time of the writes if `timestamps`,
+// and `streamID`, truncated to 255 bytes, unless it is empty. StdCopy reads
+// them, but older clients don't, so they must only be sent to the clients
+// asking for them, with the `extendedframes` query parameter.
+func NewExtendedStdWriter(w io.Writer, t StdType, timestamps bool, streamID string) *StdWriter {
+	if len(streamID) > maxStreamIDLength {
+		streamID = streamID[:maxStreamIDLength]
+	}
+	header := make([]byte, StdWriterPrefixLen, StdWriterPrefixLen+timestampLen+len(streamID))
+	copy(header, t[:])
+	if timestamps {
+		header[StdWriterFlagsIndex] |= FlagTimestamp
+		header = header[:len(header)+timestampLen]
+	}
+	if streamID != "" {
+		header[StdWriterFlagsIndex] |= FlagStreamID
+		header[StdWriterStreamIDLenIndex] = byte(len(streamID))
+		header = append(header, streamID...)
+	}
+	w2 := NewStdWriter(w, t)
+	w2.header = header
+	w2.timestamps = timestamps
+	return w2
+}
+
 var ErrInvalidStdHeader = errors.New("Unrecognized input header")
 
+// Frame is a frame demultiplexed by StdCopyFrames.
+type Frame struct {
+	// The stream of the frame: 0 for stdin, 1 for stdout and 2 for stderr
+	Fd byte
+	// The time the frame was written, zero if it doesn't carry it
+	Time time.Time
+	// The identifier of the stream of the frame, empty if it doesn't carry it
+	StreamID []byte
+	Data     []byte
+}
+
 // StdCopy is a modified version of io.Copy.
 //
 // StdCopy will demultiplex `src`, assuming that it contains two streams,
@@ -74,95 +155,133 @@ var ErrInvalidStdHeader = errors.New("Unrecognized input header")
 //
 // `written` will hold the total number of bytes written to `dstout` and `dsterr`.
 func StdCopy(dstout, dsterr io.Writer, src io.Reader) (written int64, err error) {
+	return StdCopyFrames(src, func(f *Frame) error {
+		// stdin is written on stdout
+		out := dstout
+		if f.Fd == 2 {
+			out = dsterr
+		}
+		nw, err := out.Write(f.Data)
+		if err != nil {
+			logrus.Debugf("Error writing frame: %s", err)
+			return err
+		}
+		// If the frame has not been fully written: error
+		if nw != len(f.Data) {
+			logrus.Debugf("Error Short Write: (%d on %d)", nw, len(f.Data))
+			return io.ErrShortWrite
+		}
+		return nil
+	})
+}
+
+// StdCopyFrames demultiplexes `src` like StdCopy, calling `fn` with its
+// frames rather than writing them. The frame and its slices are only valid
+// during the call, and are reused for the next frames.
+func StdCopyFrames(src io.Reader, fn func(*Frame) error) (written int64, err error) {
 	var (
-		buf       = make([]byte, 32*1024+StdWriterPrefixLen+1)
+		buf       = pools.GetBuffer(32*1024 + StdWriterPrefixLen + 1)
 		bufLen    = len(buf)
-		nr, nw    int
-		er, ew    error
-		out       io.Writer
+		nr        int
+		er        error
+		frame     Frame
+		headerLen int
 		frameSize int
 	)
 
-	for {
-		// Make sure we have at least a full header
-		for nr < StdWriterPrefixLen {
+	// buf may be replaced by a larger one for a frame
+	defer func() { pools.PutBuffer(buf) }()
+
+	// readAtLeast reads src into buf until it holds n bytes, extending buf if
+	// necessary. It returns false at EOF.
+	readAtLeast := func(n int) (bool, error) {
+		if n > bufLen {
+			buf = append(buf, make([]byte, n-bufLen+1)...)
+			bufLen = len(buf)
+		}
+		for nr < n {
 			var nr2 int
 			nr2, er = src.Read(buf[nr:])
 			nr += nr2
 			if er == io.EOF {
-				if nr < StdWriterPrefixLen {
-					logrus.Debugf("Corrupted prefix: %v", buf[:nr])
-					return written, nil
+				if nr < n {
+					logrus.Debugf("Corrupted frame: %v", buf[:nr])
+					return false, nil
 				}
 				break
 			}
 			if er != nil {
-				logrus.Debugf("Error reading header: %s", er)
-				return 0, er
+				logrus.Debugf("Error reading frame: %s", er)
+				return false, er
+			}
+		}
+		return true, nil
+	}
+
+	for {
+		// Make sure we have at least a full header
+		if ok, err := readAtLeast(StdWriterPrefixLen); !ok {
+			if err != nil {
+				return 0, err
 			}
+			return written, nil
 		}
 
 		// Check the first byte to know where to write
 		switch buf[StdWriterFdIndex] {
-		case 0:
-			fallthrough
-		case 1:
-			// Write on stdout
-			out = dstout
-		case 2:
-			// Write on stderr
-			out = dsterr
+		case 0, 1, 2:
+			frame.Fd = buf[StdWriterFdIndex]
 		default:
 			logrus.Debugf("Error selecting output fd: (%d)", buf[StdWriterFdIndex])
 			return 0, ErrInvalidStdHeader
 		}
 
+		// Retrieve the fields of the extended frames
+		flags := buf[StdWriterFlagsIndex]
+		headerLen = StdWriterPrefixLen
+		if flags&FlagTimestamp != 0 {
+			headerLen += timestampLen
+		}
+		streamIDLen := 0
+		if flags&FlagStreamID != 0 {
+			streamIDLen = int(buf[StdWriterStreamIDLenIndex])
+			headerLen += streamIDLen
+		}
+		if headerLen > StdWriterPrefixLen {
+			if ok, err := readAtLeast(headerLen); !ok {
+				if err != nil {
+					return 0, err
+				}
+				return written, nil
+			}
+		}
+
 		// Retrieve the size of the frame
 		frameSize = int(binary.BigEndian.Uint32(buf[StdWriterSizeIndex : StdWriterSizeIndex+4]))
-		logrus.Debugf("framesize: %d", frameSize)
-
-		// Check if the buffer is big enough to read the frame.
-		// Extend it if necessary.
-		if frameSize+StdWriterPrefixLen > bufLen {
-			logrus.Debugf("Extending buffer cap by %d (was %d)", frameSize+StdWriterPrefixLen-bufLen+1, len(buf))
-			buf = append(buf, make([]byte, frameSize+StdWriterPrefixLen-bufLen+1)...)
-			bufLen = len(buf)
-		}
 
 		// While the amount of bytes read is less than the size of the frame + header, we keep reading
-		for nr < frameSize+StdWriterPrefixLen {
-			var nr2 int
-			nr2, er = src.Read(buf[nr:])
-			nr += nr2
-			if er == io.EOF {
-				if nr < frameSize+StdWriterPrefixLen {
-					logrus.Debugf("Corrupted frame: %v", buf[StdWriterPrefixLen:nr])
-					return written, nil
-				}
-				break
-			}
-			if er != nil {
-				logrus.Debugf("Error reading frame: %s", er)
-				return 0, er
+		if ok, err := readAtLeast(headerLen + frameSize); !ok {
+			if err != nil {
+				return 0, err
 			}
+			return written, nil
 		}
 
-		// Write the retrieved frame (without header)
-		nw, ew = out.Write(buf[StdWriterPrefixLen : frameSize+StdWriterPrefixLen])
-		if ew != nil {
-			logrus.Debugf("Error writing frame: %s", ew)
-			return 0, ew
+		// Pass the retrieved frame (without header), buf being final
+		frame.Time = time.Time{}
+		if flags&FlagTimestamp != 0 {
+			frame.Time = time.Unix(0, int64(binary.BigEndian.Uint64(buf[StdWriterPrefixLen:])))
 		}
-		// If the frame has not been fully written: error
-		if nw != frameSize {
-			logrus.Debugf("Error Short Write: (%d on %d)", nw, frameSize)
-			return 0, io.ErrShortWrite
+		frame.StreamID = buf[headerLen-streamIDLen : headerLen]
+		frame.Data = buf[headerLen : headerLen+frameSize]
+		if err := fn(&frame); err != nil {
+			return 0, err
 		}
-		written += int64(nw)
+		written += int64(frameSize)
 
 		// Move the rest of the buffer to the beginning
-		copy(buf, buf[frameSize+StdWriterPrefixLen:])
+		copy(buf, buf[headerLen+frameSize:nr])
 		// Move the index
-		nr -= frameSize + StdWriterPrefixLen
+		nr -= headerLen + frameSize
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewStdWriter(t *testing.T) {
@@ -83,3 +84,94 @@ func BenchmarkWrite(b *testing.B) {
 		}
 	}
 }
+
+func TestStdCopyWithLargeFrame(t *testing.T) {
+	var input bytes.Buffer
+	data := bytes.Repeat([]byte("a"), 512*1024)
+	if _, err := NewStdWriter(&input, Stderr).Write(data); err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	written, err := StdCopy(ioutil.Discard, &stderr, &input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != int64(len(data)) || !bytes.Equal(stderr.Bytes(), data) {
+		t.Fatalf("Expected StdCopy to write the %d bytes of the frame, got %d", len(data), written)
+	}
+}
+
+func TestStdCopyFrames(t *testing.T) {
+	var input bytes.Buffer
+	now := time.Unix(1430000000, 123456789)
+	if _, err := NewStdWriter(&input, Stdout).Write([]byte("plain")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewExtendedStdWriter(&input, Stderr, true, "").WriteFrame([]byte("timed"), now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewExtendedStdWriter(&input, Stdout, true, "exec1").WriteFrame(bytes.Repeat([]byte("x"), 100000), now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewExtendedStdWriter(&input, Stderr, false, "exec2").Write([]byte("identified")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		fd       byte
+		time     time.Time
+		streamID string
+		data     string
+	}{
+		{1, time.Time{}, "", "plain"},
+		{2, now, "", "timed"},
+		{1, now, "exec1", strings.Repeat("x", 100000)},
+		{2, time.Time{}, "exec2", "identified"},
+	}
+	i := 0
+	_, err := StdCopyFrames(bytes.NewReader(input.Bytes()), func(f *Frame) error {
+		if i >= len(expected) {
+			t.Fatalf("Unexpected frame %v", f)
+		}
+		e := expected[i]
+		if f.Fd != e.fd || !f.Time.Equal(e.time) || string(f.StreamID) != e.streamID || string(f.Data) != e.data {
+			t.Fatalf("Expected frame %d to be %d %v %q of %d bytes, got %d %v %q of %d bytes", i, e.fd, e.time, e.streamID, len(e.data), f.Fd, f.Time, f.StreamID, len(f.Data))
+		}
+		i++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != len(expected) {
+		t.Fatalf("Expected %d frames, got %d", len(expected), i)
+	}
+
+	// StdCopy skips the fields of the extended frames
+	var stdout, stderr bytes.Buffer
+	if _, err := StdCopy(&stdout, &stderr, &input); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "plain"+strings.Repeat("x", 100000) || stderr.String() != "timedidentified" {
+		t.Fatalf("Unexpected output %q and %q", stdout.String(), stderr.String())
+	}
+}
+
+func BenchmarkStdCopy(b *testing.B) {
+	var input bytes.Buffer
+	w := NewStdWriter(&input, Stdout)
+	data := bytes.Repeat([]byte("Test line for testing stdcopy performance\n"), 100)
+	for i := 0; i < 100; i++ {
+		if _, err := w.Write(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.SetBytes(int64(input.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := StdCopy(ioutil.Discard, ioutil.Discard, bytes.NewReader(input.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
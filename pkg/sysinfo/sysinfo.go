@@ -63,6 +63,29 @@ func New(quiet bool) *SysInfo {
 		}
 	}
 
+	sysInfo.checkHost()
+
+	// Check if Devices cgroup is mounted, it is hard requirement for container security.
+	if _, err := cgroups.FindCgroupMountpoint("devices"); err != nil {
+		logrus.Fatalf("Error mounting devices cgroup: %v", err)
+	}
+
+	return sysInfo
+}
+
+// NewWithoutCgroups returns a new SysInfo for a daemon which can't use the
+// cgroups, such as a daemon running rootless, where no limit is supported.
+func NewWithoutCgroups(quiet bool) *SysInfo {
+	sysInfo := &SysInfo{}
+	if !quiet {
+		logrus.Warn("The cgroups can't be used: the resources of the containers can't be limited")
+	}
+	sysInfo.checkHost()
+	return sysInfo
+}
+
+// checkHost detects the features which don't depend on the cgroups.
+func (sysInfo *SysInfo) checkHost() {
 	// Checek if ipv4_forward is disabled.
 	if data, err := ioutil.ReadFile("/proc/sys/net/ipv4/ip_forward"); os.IsNotExist(err) {
 		sysInfo.IPv4ForwardingDisabled = true
@@ -80,11 +103,4 @@ func New(quiet bool) *SysInfo {
 	} else {
 		sysInfo.AppArmor = true
 	}
-
-	// Check if Devices cgroup is mounted, it is hard requirement for container security.
-	if _, err := cgroups.FindCgroupMountpoint("devices"); err != nil {
-		logrus.Fatalf("Error mounting devices cgroup: %v", err)
-	}
-
-	return sysInfo
 }
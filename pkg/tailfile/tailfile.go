@@ -3,6 +3,7 @@ package tailfile
 import (
 	"bytes"
 	"errors"
+	"io"
 	"os"
 )
 
@@ -11,8 +12,10 @@ const blockSize = 1024
 var eol = []byte("\n")
 var ErrNonPositiveLinesNumber = errors.New("Lines number must be positive")
 
-//TailFile returns last n lines of file f
-func TailFile(f *os.File, n int) ([][]byte, error) {
+// TailFile returns last n lines of file f. The file is read backwards from
+// its end, block by block, until it holds enough lines, so that the cost
+// does not depend on the size of the file.
+func TailFile(f io.ReadSeeker, n int) ([][]byte, error) {
 	if n <= 0 {
 		return nil, ErrNonPositiveLinesNumber
 	}
@@ -20,38 +23,33 @@ func TailFile(f *os.File, n int) ([][]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	block := -1
-	var data []byte
-	var cnt int
-	for {
-		var b []byte
-		step := int64(block * blockSize)
-		left := size + step // how many bytes to beginning
-		if left < 0 {
-			if _, err := f.Seek(0, os.SEEK_SET); err != nil {
-				return nil, err
-			}
-			b = make([]byte, blockSize+left)
-			if _, err := f.Read(b); err != nil {
-				return nil, err
-			}
-			data = append(b, data...)
-			break
-		} else {
-			b = make([]byte, blockSize)
-			if _, err := f.Seek(step, os.SEEK_END); err != nil {
-				return nil, err
-			}
-			if _, err := f.Read(b); err != nil {
-				return nil, err
-			}
-			data = append(b, data...)
+
+	// The blocks read, the last one of the file first
+	var (
+		blocks [][]byte
+		cnt    int
+		left   = size
+	)
+	for left > 0 && cnt <= n {
+		step := int64(blockSize)
+		if left < step {
+			step = left
 		}
-		cnt += bytes.Count(b, eol)
-		if cnt > n {
-			break
+		left -= step
+		if _, err := f.Seek(left, os.SEEK_SET); err != nil {
+			return nil, err
+		}
+		b := make([]byte, step)
+		if _, err := io.ReadFull(f, b); err != nil {
+			return nil, err
 		}
-		block--
+		blocks = append(blocks, b)
+		cnt += bytes.Count(b, eol)
+	}
+
+	data := make([]byte, 0, int64(len(blocks))*blockSize)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		data = append(data, blocks[i]...)
 	}
 	lines := bytes.Split(data, eol)
 	if n < len(lines) {
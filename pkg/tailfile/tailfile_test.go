@@ -1,6 +1,9 @@
 package tailfile
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -127,6 +130,28 @@ truncated line`)
 	}
 }
 
+func TestTailReaderManyBlocks(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(buf, "line %d\n", i)
+	}
+	data := buf.Bytes()
+	// The lines written after the section are not read
+	r := io.NewSectionReader(bytes.NewReader(append(data, "later\n"...)), 0, int64(len(data)))
+	res, err := TailFile(r, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 500 {
+		t.Fatalf("Expected 500 lines, got %d", len(res))
+	}
+	for i, l := range res {
+		if expected := fmt.Sprintf("line %d", 500+i); string(l) != expected {
+			t.Fatalf("Expected line %s, got %s", expected, l)
+		}
+	}
+}
+
 func BenchmarkTail(b *testing.B) {
 	f, err := ioutil.TempFile("", "tail-test")
 	if err != nil {
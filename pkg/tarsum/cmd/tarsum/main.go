@@ -0,0 +1,212 @@
+// Command tarsum computes, verifies and compares the tarsums of the tars on
+// disk, e.g. of the layers of images, which may be compressed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/tarsum"
+)
+
+func usage(stderr io.Writer) int {
+	fmt.Fprintf(stderr, `Usage: %s <command> [flags] <file>...
+
+Commands:
+  sum [-version tarsum.v1] [-hash sha256] [-json file] <file>...
+        print the tarsums of the tars
+  verify -expect <tarsum> [-json file] <file>
+        verify that the tar has the expected tarsum
+  manifest [-version tarsum.v1] [-hash sha256] <file>
+        print the JSON manifest of the sums of the entries of the tar
+  diff [-version tarsum.v1] [-hash sha256] <file> <file>
+        print the files added (A), deleted (D) or changed (C) from the
+        first tar to the second one, either of which may be a manifest
+        file ending with .json
+
+The file "-" is the standard input. The tars may be compressed.
+`, os.Args[0])
+	return 2
+}
+
+func fatal(stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "tarsum: %v\n", err)
+	return 2
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command of args, printing its output to stdout and its errors
+// to stderr, and returns its exit status: 1 if a tar doesn't have the
+// expected tarsum or the tars differ, 2 on errors.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) < 1 {
+		return usage(stderr)
+	}
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { usage(stderr) }
+	version := flags.String("version", "tarsum.v1", "the version of the tarsums")
+	hashName := flags.String("hash", "sha256", "the hash of the tarsums, e.g. sha512, sha3-256 or blake2b-256")
+	jsonFile := flags.String("json", "", "the file of the image json summed with the layer")
+	expect := flags.String("expect", "", "the expected tarsum")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	label := *version + "+" + *hashName
+
+	var extra []byte
+	if *jsonFile != "" {
+		var err error
+		if extra, err = ioutil.ReadFile(*jsonFile); err != nil {
+			return fatal(stderr, err)
+		}
+	}
+
+	switch args[0] {
+	case "sum":
+		if flags.NArg() < 1 {
+			return usage(stderr)
+		}
+		for _, name := range flags.Args() {
+			ts, err := sumFile(name, label)
+			if err != nil {
+				return fatal(stderr, err)
+			}
+			fmt.Fprintf(stdout, "%s  %s\n", ts.Sum(extra), name)
+		}
+	case "verify":
+		if flags.NArg() != 1 || *expect == "" {
+			return usage(stderr)
+		}
+		if _, err := tarsum.ParseTarSumInfo(*expect); err != nil {
+			return fatal(stderr, err)
+		}
+		ts, err := sumFile(flags.Arg(0), *expect)
+		if err != nil {
+			return fatal(stderr, err)
+		}
+		if sum := ts.Sum(extra); sum != *expect {
+			fmt.Fprintf(stderr, "%s: %v\n", flags.Arg(0), tarsum.MismatchError{Expected: *expect, Actual: sum})
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s: OK\n", flags.Arg(0))
+	case "manifest":
+		if flags.NArg() != 1 {
+			return usage(stderr)
+		}
+		ts, err := sumFile(flags.Arg(0), label)
+		if err != nil {
+			return fatal(stderr, err)
+		}
+		if err := tarsum.WriteManifest(ts, stdout); err != nil {
+			return fatal(stderr, err)
+		}
+	case "diff":
+		if flags.NArg() != 2 {
+			return usage(stderr)
+		}
+		a, err := manifestFile(flags.Arg(0), label)
+		if err != nil {
+			return fatal(stderr, err)
+		}
+		b, err := manifestFile(flags.Arg(1), label)
+		if err != nil {
+			return fatal(stderr, err)
+		}
+		added, removed, modified, err := tarsum.Diff(a, b)
+		if err != nil {
+			return fatal(stderr, err)
+		}
+		var changes byName
+		for _, c := range []struct {
+			paths []string
+			kind  string
+		}{{added, "A"}, {removed, "D"}, {modified, "C"}} {
+			for _, path := range c.paths {
+				changes = append(changes, c.kind+" "+path)
+			}
+		}
+		if len(changes) > 0 {
+			sort.Sort(changes)
+			for _, change := range changes {
+				fmt.Fprintln(stdout, change)
+			}
+			return 1
+		}
+	default:
+		fmt.Fprintf(stderr, "Unknown command %s\n", args[0])
+		return usage(stderr)
+	}
+	return 0
+}
+
+// openFile opens the file of the name, or the standard input if it is "-".
+func openFile(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(name)
+}
+
+// sumFile returns the TarSum of the tar of the file of the name, of the
+// version and hash of the label, read to its end.
+func sumFile(name, label string) (tarsum.TarSum, error) {
+	f, err := openFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := archive.DecompressStream(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	ts, err := tarsum.NewTarSumForLabel(r, true, label)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return ts, nil
+}
+
+// manifestFile returns the manifest of the file of the name, which is read
+// if its name ends with .json, or else of its tar, with the version and
+// hash of the label.
+func manifestFile(name, label string) (tarsum.Manifest, error) {
+	f, err := openFile(name)
+	if err != nil {
+		return tarsum.Manifest{}, err
+	}
+	defer f.Close()
+	if strings.HasSuffix(name, ".json") {
+		return tarsum.ReadManifest(f)
+	}
+	r, err := archive.DecompressStream(f)
+	if err != nil {
+		return tarsum.Manifest{}, err
+	}
+	defer r.Close()
+	m, err := tarsum.NewManifest(r, label)
+	if err != nil {
+		return m, fmt.Errorf("%s: %v", name, err)
+	}
+	return m, nil
+}
+
+// byName sorts the changes by the names of their files.
+type byName []string
+
+func (c byName) Len() int           { return len(c) }
+func (c byName) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byName) Less(i, j int) bool { return c[i][2:] < c[j][2:] }
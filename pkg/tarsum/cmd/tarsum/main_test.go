@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	layer   = "../../testdata/mtime/layer.tar"
+	touched = "../../testdata/mtime/touched.tar"
+
+	layerSum   = "tarsum.v2+sha256:a33aa98fad5aea295e1a935e9a706e94c6002414caa37b5adcc988b6a20aafa7"
+	touchedSum = "tarsum.v2+sha256:bc713303f1a77d7a8d73e3b67e369aa074913ebc8bd3df706e9dfa8d2c8e416e"
+)
+
+func TestRun(t *testing.T) {
+	for _, c := range []struct {
+		args   []string
+		status int
+		stdout string
+		stderr string // a part of the errors
+	}{
+		{[]string{"sum", "-version", "tarsum.v2", layer, touched}, 0, layerSum + "  " + layer + "\n" + touchedSum + "  " + touched + "\n", ""},
+		{[]string{"verify", "-expect", layerSum, layer}, 0, layer + ": OK\n", ""},
+		{[]string{"verify", "-expect", layerSum, touched}, 1, "", "tarsum mismatch: expected " + layerSum + ", got " + touchedSum},
+		{[]string{"verify", "-expect", "sha256:00", layer}, 2, "", "tarsum: "},
+		{[]string{"diff", "-version", "tarsum.v2", layer, touched}, 1, "C etc/hostname\n", ""},
+		{[]string{"diff", "-version", "tarsum.v2", layer, layer}, 0, "", ""},
+		// the mtimes aren't summed by tarsum.v1
+		{[]string{"diff", layer, touched}, 0, "", ""},
+		{[]string{"diff", layer}, 2, "", "Usage:"},
+		{[]string{"unknown"}, 2, "", "Unknown command unknown"},
+		{nil, 2, "", "Usage:"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if status := run(c.args, &stdout, &stderr); status != c.status {
+			t.Fatalf("%v: expected the status %d, got %d (%s)", c.args, c.status, status, stderr.String())
+		}
+		if stdout.String() != c.stdout {
+			t.Fatalf("%v: expected the output %q, got %q", c.args, c.stdout, stdout.String())
+		}
+		if !strings.Contains(stderr.String(), c.stderr) {
+			t.Fatalf("%v: expected the errors to contain %q, got %q", c.args, c.stderr, stderr.String())
+		}
+	}
+}
+
+func TestRunDiffManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tarsum-cmd-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var manifest, stderr bytes.Buffer
+	if status := run([]string{"manifest", "-version", "tarsum.v2", layer}, &manifest, &stderr); status != 0 {
+		t.Fatalf("Expected the manifest to be written, got %d (%s)", status, stderr.String())
+	}
+	manifestFile := filepath.Join(dir, "layer.json")
+	if err := ioutil.WriteFile(manifestFile, manifest.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	if status := run([]string{"diff", "-version", "tarsum.v2", manifestFile, touched}, &stdout, &stderr); status != 1 || stdout.String() != "C etc/hostname\n" {
+		t.Fatalf("Expected etc/hostname to be changed, got %d %q (%s)", status, stdout.String(), stderr.String())
+	}
+}
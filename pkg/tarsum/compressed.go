@@ -0,0 +1,97 @@
+package tarsum
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// SetCompressedHash makes the TarSum ts, which may be a VerifiedTarSum, hash
+// the bytes read from its reader, before they are decompressed, with tHash,
+// or DefaultTHash if nil, e.g. to digest the compressed blob of a layer
+// pushed as its tarsum is computed rather than to read the blob twice. The
+// bytes after the end of the tar, e.g. the end of its gzip stream, are read
+// and hashed once the tar is read. It must be set before ts is read.
+func SetCompressedHash(ts TarSum, tHash THash) error {
+	t, err := unreadTarSum(ts)
+	if err != nil {
+		return err
+	}
+	if tHash == nil {
+		tHash = DefaultTHash
+	}
+	t.compressedTHash = tHash
+	t.compressedHash = &lockedHash{Hash: tHash.Hash()}
+	return nil
+}
+
+// CompressedTarSum is a TarSum which digests the blob its tar is read from,
+// as the TarSums returned by NewTarSum and NewVerifiedTarSum do once
+// SetCompressedHash is called.
+type CompressedTarSum interface {
+	TarSum
+	CompressedSum() string
+}
+
+// CompressedSum returns the digest of the bytes read from the reader of the
+// TarSum ts, see the CompressedSum method of the TarSums, or "" if ts isn't
+// a CompressedTarSum.
+func CompressedSum(ts TarSum) string {
+	c, ok := ts.(CompressedTarSum)
+	if !ok {
+		return ""
+	}
+	return c.CompressedSum()
+}
+
+// CompressedSum returns the digest of the bytes read from the reader of ts,
+// of the whole blob once ts is read, e.g. "sha256:{hex}", or "" if ts
+// doesn't hash them, see SetCompressedHash.
+func (ts *tarSum) CompressedSum() string {
+	if ts.compressedHash == nil {
+		return ""
+	}
+	return ts.compressedTHash.Name() + ":" + hex.EncodeToString(ts.compressedHash.Sum(nil))
+}
+
+// CompressedSum returns the digest of the blob of the TarSum of v, "" if it
+// isn't a CompressedTarSum.
+func (v *VerifiedTarSum) CompressedSum() string {
+	return CompressedSum(v.TarSum)
+}
+
+// hashCompressedRest reads and hashes the rest of the blob of ts after the
+// end of its tar, if ts hashes it. The blob decompressed by a command is
+// read to its end by the goroutine feeding it, see finish.
+func (ts *tarSum) hashCompressedRest() error {
+	if ts.compressedHash == nil || ts.compressed == nil {
+		return nil
+	}
+	var err error
+	if !ts.decompressing {
+		_, err = io.Copy(ioutil.Discard, ts.compressed)
+	}
+	ts.compressed = nil
+	return err
+}
+
+// lockedHash is the hash of the blob of a TarSum, which the goroutine feeding
+// the command decompressing the blob writes to.
+type lockedHash struct {
+	mu sync.Mutex
+	hash.Hash
+}
+
+func (h *lockedHash) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.Hash.Write(p)
+}
+
+func (h *lockedHash) Sum(b []byte) []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.Hash.Sum(b)
+}
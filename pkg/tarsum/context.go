@@ -0,0 +1,96 @@
+package tarsum
+
+import (
+	"io"
+)
+
+// Context is the part of a context.Context of golang.org/x/net/context
+// which cancels a TarSum.
+type Context interface {
+	Done() <-chan struct{}
+	Err() error
+}
+
+// NewTarSumWithContext creates a new TarSum like NewTarSum, which is
+// cancelled with ctx. Once ctx is done, the reads of the TarSum return
+// ctx.Err(), even if a read of r is blocked, and its gzip reader and writer
+// are released. A blocked read of r is left to return, or to fail once the
+// caller closes r.
+func NewTarSumWithContext(ctx Context, r io.Reader, dc bool, v Version) (TarSum, error) {
+	headerSelector, err := GetTarHeaderSelector(v)
+	if err != nil {
+		return nil, err
+	}
+	ts := &tarSum{
+		Reader:             &contextReader{ctx: ctx, r: r},
+		DisableCompression: dc,
+		tarSumVersion:      v,
+		headerSelector:     headerSelector,
+		tHash:              DefaultTHash,
+		ctx:                ctx,
+	}
+	err = ts.initTarSum()
+	return ts, err
+}
+
+// cancelled returns the error of the context of ts if it is done, releasing
+// the readers and writers of ts.
+func (ts *tarSum) cancelled() error {
+	if ts.ctx == nil {
+		return nil
+	}
+	select {
+	case <-ts.ctx.Done():
+	default:
+		return nil
+	}
+	if ts.decompressed != nil {
+		ts.decompressed.Close()
+		ts.decompressed = nil
+	}
+	ts.tarR = nil
+	ts.tarW = nil
+	ts.writer = nil
+	ts.bufTar = nil
+	ts.bufWriter = nil
+	return ts.ctx.Err()
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+// contextReader is a reader of r whose reads return the error of ctx once
+// it is done, even if a read of r is blocked.
+type contextReader struct {
+	ctx Context
+	r   io.Reader
+	// buf is the buffer of the reads of r, which a cancelled read may still
+	// write to, so that it is never used again once ctx is done
+	buf []byte
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	select {
+	case <-cr.ctx.Done():
+		return 0, cr.ctx.Err()
+	default:
+	}
+	if len(cr.buf) < len(p) {
+		cr.buf = make([]byte, len(p))
+	}
+	buf := cr.buf[:len(p)]
+	done := make(chan readResult, 1)
+	go func() {
+		n, err := cr.r.Read(buf)
+		done <- readResult{n, err}
+	}()
+	select {
+	case res := <-done:
+		copy(p, buf[:res.n])
+		return res.n, res.err
+	case <-cr.ctx.Done():
+		return 0, cr.ctx.Err()
+	}
+}
@@ -0,0 +1,56 @@
+package tarsum
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diff returns the paths of the entries added, removed and modified from the
+// Manifest a to the Manifest b, sorted, e.g. to report the files changed in
+// a layer whose tarsum changed without extracting its tars. The manifests of
+// tars are returned by NewManifest. An entry is modified if its digest
+// changed, the entries of the same path being compared in the order of the
+// tars. It returns an error if the manifests aren't of the same TarSum
+// version and hash, whose digests can't be compared.
+func Diff(a, b Manifest) (added, removed, modified []string, err error) {
+	aInfo, err := ParseTarSumInfo(a.TarSum)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bInfo, err := ParseTarSumInfo(b.TarSum)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if aInfo.Algorithm() != bInfo.Algorithm() {
+		return nil, nil, nil, fmt.Errorf("the manifests of %s and %s tarsums can't be compared", aInfo.Algorithm(), bInfo.Algorithm())
+	}
+
+	aDigests, bDigests := entryDigests(a), entryDigests(b)
+	for path, digests := range bDigests {
+		if aPathDigests, ok := aDigests[path]; !ok {
+			added = append(added, path)
+		} else if strings.Join(digests, " ") != strings.Join(aPathDigests, " ") {
+			modified = append(modified, path)
+		}
+	}
+	for path := range aDigests {
+		if _, ok := bDigests[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+	return added, removed, modified, nil
+}
+
+// entryDigests returns the digests of the entries of m by path, in the order
+// of m.
+func entryDigests(m Manifest) map[string][]string {
+	digests := map[string][]string{}
+	for _, entry := range m.Entries {
+		digests[entry.Path] = append(digests[entry.Path], entry.Digest)
+	}
+	return digests
+}
@@ -0,0 +1,78 @@
+package tarsum
+
+import (
+	"crypto/hmac"
+	"fmt"
+)
+
+// A tarsum is a digest of the form {algorithm}:{hex}, like the digests of
+// the digest package of docker/distribution and of OCI, its algorithm being
+// {tarsum_version}+{hash_name}, e.g. "tarsum.v1+sha256", which is valid in
+// them.
+
+// Algorithm returns the algorithm of the tarsum info, as a digest, e.g.
+// "tarsum.v1+sha256".
+func (info TarSumInfo) Algorithm() string {
+	return info.Version.String() + "+" + info.HashName
+}
+
+// Digest returns the tarsum of the files written whole, like TarSum, so that
+// a Hash is used like the Digester of a digest.
+func (h *Hash) Digest() string {
+	return h.TarSum()
+}
+
+// DigestVerifier verifies that the tar written to it has the expected
+// tarsum. It is a Verifier of the digest package of docker/distribution,
+// which verifies the tar as it is written, without a goroutine reading it
+// back.
+type DigestVerifier struct {
+	h        *Hash
+	expected string
+	err      error
+}
+
+// NewDigestVerifier returns the DigestVerifier of the tarsum expected, e.g.
+// "tarsum.v1+sha256:{hex}", whose version and hash are the ones of the
+// tarsum. The tarsums of HMACs can't be verified, they need a key.
+func NewDigestVerifier(expected string) (*DigestVerifier, error) {
+	info, err := ParseTarSumInfo(expected)
+	if err != nil {
+		return nil, err
+	}
+	if isHMAC(info.HashName) {
+		return nil, fmt.Errorf("the TarSum hash %q needs a key, see NewVerifiedTarSumHMAC", info.HashName)
+	}
+	tHash, err := GetTHash(info.HashName)
+	if err != nil {
+		return nil, err
+	}
+	h, err := NewHash(info.Version, tHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &DigestVerifier{h: h, expected: expected}, nil
+}
+
+// Write writes the bytes of the uncompressed tar. It returns an error if
+// the tar is invalid, after which the tar isn't verified.
+func (v *DigestVerifier) Write(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	v.h.Write(p)
+	if v.err = v.h.Err(); v.err != nil {
+		return 0, v.err
+	}
+	return len(p), nil
+}
+
+// Verified returns whether the tar written is whole, up to its end, and
+// has the expected tarsum.
+func (v *DigestVerifier) Verified() bool {
+	if v.err != nil || !v.h.ended {
+		return false
+	}
+	// compared in constant time, like the sums of VerifiedTarSums
+	return hmac.Equal([]byte(v.h.TarSum()), []byte(v.expected))
+}
@@ -0,0 +1,43 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync/atomic"
+)
+
+// Event is the JSON line written by a TarSum for each entry of its tar once
+// it is hashed, see SetEvents.
+type Event struct {
+	Path   string `json:"path"` // the name of the entry, without "./" and a trailing "/"
+	Size   int64  `json:"size"`
+	Digest string `json:"digest"` // the hex sum of the headers and the content of the entry
+	Bytes  int64  `json:"bytes"`  // the bytes of the tar read, uncompressed
+}
+
+// SetEvents makes the TarSum ts, which may be a VerifiedTarSum, write an
+// Event to w for each entry of its tar as it is hashed, one JSON document
+// per line, e.g. for a CI system to tail the verification of a layer. The
+// reads of ts fail if w does. It must be set before ts is read.
+func SetEvents(ts TarSum, w io.Writer) error {
+	t, err := unreadTarSum(ts)
+	if err != nil {
+		return err
+	}
+	t.events = json.NewEncoder(w)
+	return nil
+}
+
+// addSum adds the sum of the entry of hdr, whose headers and content are
+// hashed, to the sums of ts.
+func (ts *tarSum) addSum(hdr *tar.Header) error {
+	sum := hex.EncodeToString(ts.h.Sum(nil))
+	ts.sums = append(ts.sums, fileInfoSum{name: ts.currentFile, sum: sum, pos: ts.fileCounter, size: hdr.Size, mode: hdr.Mode})
+	ts.fileCounter++
+	if ts.events == nil {
+		return nil
+	}
+	return ts.events.Encode(Event{Path: ts.currentFile, Size: hdr.Size, Digest: sum, Bytes: atomic.LoadInt64(&ts.stats.UncompressedBytes)})
+}
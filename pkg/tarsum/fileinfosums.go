@@ -16,6 +16,8 @@ type fileInfoSum struct {
 	name string
 	sum  string
 	pos  int64
+	size int64 // the size of the file, of its header
+	mode int64 // the mode of the file, of its header
 }
 
 func (fis fileInfoSum) Name() string {
@@ -51,6 +53,17 @@ func (fis FileInfoSums) GetAllFile(name string) FileInfoSums {
 	return f
 }
 
+// GetFileAtPos returns the FileInfoSumInterface at position pos in the tar,
+// whatever the order of fis
+func (fis FileInfoSums) GetFileAtPos(pos int64) FileInfoSumInterface {
+	for i := range fis {
+		if fis[i].Pos() == pos {
+			return fis[i]
+		}
+	}
+	return nil
+}
+
 func contains(s []string, e string) bool {
 	for _, a := range s {
 		if a == e {
@@ -43,3 +43,14 @@ func TestSortFileInfoSums(t *testing.T) {
 		t.Errorf("Expected %d, got %d", 4, gotFis.Pos())
 	}
 }
+
+func TestGetFileAtPos(t *testing.T) {
+	fis := newFileInfoSums()
+	fis.SortBySums()
+	if f := fis.GetFileAtPos(4); f == nil || f.Name() != "dup1" || f.Sum() != "deadbeef0" {
+		t.Errorf("Expected the first dup1 at position 4, got %v", f)
+	}
+	if f := fis.GetFileAtPos(6); f != nil {
+		t.Errorf("Expected no file at position 6, got %v", f)
+	}
+}
@@ -0,0 +1,258 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+)
+
+// blockSize is the size of the blocks of a tar.
+const blockSize = 512
+
+// Hash is a hash.Hash of the tarsum of the uncompressed tar written to it,
+// so that a tar can be summed by code written against hash.Hash, e.g. the
+// io.MultiWriter of its other digests. Its Sum is the digest of the tarsum
+// of the files whose entries have been written whole, the tar needn't be
+// written to its end.
+//
+// The tar is parsed as it is written: the headers of its entries are
+// buffered until they are whole, and the contents of the files are hashed
+// as they are written, except the ones of the sparse files which are
+// buffered whole. Like the ones of other hashes, Write never returns an
+// error; the first error parsing the tar is returned by Err, after which the
+// bytes written are ignored.
+type Hash struct {
+	v     Version
+	tHash THash
+	extra []byte
+	vs    *versionSum
+
+	buf       []byte      // the bytes of the headers of the next entry
+	hdr       *tar.Header // the header of the file whose contents are written
+	remaining int64       // the bytes of the contents of hdr to be written
+	padding   int64       // the bytes padding the contents to the next block
+	pos       int64       // the position of the next file in the tar
+	ended     bool        // the end of the tar was written
+	err       error
+}
+
+// NewHash returns the Hash of the tarsum of version v and hash tHash, or
+// DefaultTHash if nil, of the tar written to it and of extra, like
+// TarSum.Sum.
+func NewHash(v Version, tHash THash, extra []byte) (*Hash, error) {
+	headerSelector, err := GetTarHeaderSelector(v)
+	if err != nil {
+		return nil, err
+	}
+	if tHash == nil {
+		tHash = DefaultTHash
+	}
+	h := &Hash{v: v, tHash: tHash, extra: extra}
+	h.vs = &versionSum{headerSelector: headerSelector, headersOnly: !hashesContents(v)}
+	h.Reset()
+	return h, nil
+}
+
+// Write writes the bytes of the tar. It never returns an error, see Err.
+// The bytes written after the end of the tar are ignored.
+func (h *Hash) Write(p []byte) (int, error) {
+	if h.err == nil && !h.ended {
+		h.err = h.parse(p)
+	}
+	return len(p), nil
+}
+
+// Err returns the error of the tar written, if it is invalid. The sums are
+// the ones of the files written whole before it.
+func (h *Hash) Err() error {
+	return h.err
+}
+
+// Sum appends the digest of the tarsum of the files written whole to b. It
+// doesn't change the state of the Hash.
+func (h *Hash) Sum(b []byte) []byte {
+	return append(b, digestFileInfoSums(h.GetSums(), h.extra, h.tHash)...)
+}
+
+// TarSum returns the tarsum of the files written whole, e.g.
+// "tarsum.v1+sha256:{hex}".
+func (h *Hash) TarSum() string {
+	return sumFileInfoSums(h.GetSums(), h.extra, h.v, h.tHash)
+}
+
+// GetSums returns the sums of the files written whole.
+func (h *Hash) GetSums() FileInfoSums {
+	return append(FileInfoSums(nil), h.vs.sums...)
+}
+
+// Reset resets the Hash to its initial state.
+func (h *Hash) Reset() {
+	*h = Hash{
+		v:     h.v,
+		tHash: h.tHash,
+		extra: h.extra,
+		vs:    &versionSum{headerSelector: h.vs.headerSelector, h: h.tHash.Hash(), headersOnly: h.vs.headersOnly},
+	}
+}
+
+// Size returns the size of the digest, the one of its hash.
+func (h *Hash) Size() int {
+	return h.tHash.Hash().Size()
+}
+
+// BlockSize returns the size of the blocks of a tar.
+func (h *Hash) BlockSize() int {
+	return blockSize
+}
+
+// parse sums the files of the bytes p of the tar, which follow the ones
+// written before.
+func (h *Hash) parse(p []byte) error {
+	for len(p) > 0 && !h.ended {
+		switch {
+		case h.hdr != nil:
+			n := int64(len(p))
+			if n > h.remaining {
+				n = h.remaining
+			}
+			if !h.vs.headersOnly {
+				h.vs.h.Write(p[:n])
+			}
+			p = p[n:]
+			if h.remaining -= n; h.remaining == 0 {
+				h.endFile()
+			}
+		case h.padding > 0:
+			n := int64(len(p))
+			if n > h.padding {
+				n = h.padding
+			}
+			p = p[n:]
+			h.padding -= n
+		default:
+			rest, err := h.parseHeader(p)
+			if err != nil {
+				return err
+			}
+			p = rest
+		}
+	}
+	return nil
+}
+
+// parseHeader parses the headers of the next entry of the tar, from the
+// bytes buffered and then p, and starts the sum of its file. It returns the
+// bytes of p which follow them, or none until they are written whole. Only
+// the bytes of the headers are buffered, the ones of the entries after them
+// are parsed in p: p is appended to the buffer in chunks doubling its size,
+// so that the headers written in many small writes are parsed in linear
+// time.
+func (h *Hash) parseHeader(p []byte) ([]byte, error) {
+	if len(h.buf) == 0 {
+		n, err := h.header(p)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			h.buf = append(h.buf, p...)
+			return nil, nil
+		}
+		return p[n:], nil
+	}
+	for off := 0; off < len(p); {
+		chunk := len(h.buf)
+		if chunk > len(p)-off {
+			chunk = len(p) - off
+		}
+		h.buf = append(h.buf, p[off:off+chunk]...)
+		off += chunk
+		n, err := h.header(h.buf)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			// the bytes of the buffer after the headers are the last ones
+			// appended from p
+			rest := p[off-(len(h.buf)-n):]
+			h.buf = nil
+			return rest, nil
+		}
+	}
+	return nil, nil
+}
+
+// header parses the headers of the next entry of the tar at the start of b,
+// and starts the sum of its file. It returns the number of bytes of b they
+// take, or 0 until b holds them whole.
+func (h *Hash) header(b []byte) (int, error) {
+	if len(b) < blockSize {
+		return 0, nil
+	}
+	// the tar ends with two blocks of zeros
+	if isZeroBlock(b[:blockSize]) {
+		if len(b) < 2*blockSize {
+			return 0, nil
+		}
+		if isZeroBlock(b[blockSize : 2*blockSize]) {
+			h.ended = true
+			return 2 * blockSize, nil
+		}
+	}
+
+	r := bytes.NewReader(b)
+	tarR := tar.NewReader(r)
+	hdr, err := tarR.Next()
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	h.vs.begin(hdr)
+
+	if isSparse(hdr) {
+		// the size of the contents in the tar is known once they are read
+		contents := ioutil.Discard
+		if !h.vs.headersOnly {
+			contents = h.vs.h
+		}
+		if _, err := io.Copy(contents, tarR); err == io.ErrUnexpectedEOF {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+		n := int64(len(b) - r.Len())
+		padded := (n + blockSize - 1) / blockSize * blockSize
+		h.hdr = hdr
+		h.endFile()
+		h.padding = padded - n
+		return int(n), nil
+	}
+
+	n := len(b) - r.Len()
+	h.hdr = hdr
+	if isHeaderOnly(hdr) || hdr.Size == 0 {
+		h.endFile()
+		return n, nil
+	}
+	h.remaining = hdr.Size
+	h.padding = (blockSize - hdr.Size%blockSize) % blockSize
+	return n, nil
+}
+
+// endFile appends the sum of the file whose contents were written.
+func (h *Hash) endFile() {
+	h.vs.end(h.hdr, h.pos)
+	h.pos++
+	h.hdr = nil
+}
+
+func isZeroBlock(block []byte) bool {
+	for _, b := range block {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package tarsum
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// hmacPrefix prefixes the names of the HMAC hashes, e.g. "hmac-sha256".
+const hmacPrefix = "hmac-"
+
+// NewHMACTHash returns the HMAC of the hash tHash keyed with key, named
+// "hmac-{name}", e.g. "hmac-sha256". The tarsums of the TarSums of the HMAC,
+// e.g. "tarsum+hmac-sha256:{hex}", can't be computed, thus forged, by parties
+// which know the tar but not the key.
+func NewHMACTHash(tHash THash, key []byte) THash {
+	key = append([]byte(nil), key...)
+	return NewTHash(hmacPrefix+tHash.Name(), func() hash.Hash {
+		return hmac.New(tHash.Hash, key)
+	})
+}
+
+// isHMAC returns true if the hash name is the one of an HMAC, which can't be
+// used without its key.
+func isHMAC(name string) bool {
+	return strings.HasPrefix(name, hmacPrefix)
+}
+
+// getHMACTHash returns the HMAC keyed with key of the registered hash of the
+// HMAC named name.
+func getHMACTHash(name string, key []byte) (THash, error) {
+	if !isHMAC(name) {
+		return nil, fmt.Errorf("the TarSum hash %q is not an HMAC", name)
+	}
+	tHash, err := GetTHash(strings.TrimPrefix(name, hmacPrefix))
+	if err != nil {
+		return nil, err
+	}
+	return NewHMACTHash(tHash, key), nil
+}
+
+// NewVerifiedTarSumHMAC returns a TarSum of the tar read from r, which must
+// have the tarsum expected of an HMAC keyed with key, e.g.
+// "tarsum.v1+hmac-sha256:{hex}", like NewVerifiedTarSum.
+func NewVerifiedTarSumHMAC(r io.Reader, expected string, key []byte) (*VerifiedTarSum, error) {
+	info, err := ParseTarSumInfo(expected)
+	if err != nil {
+		return nil, err
+	}
+	tHash, err := getHMACTHash(info.HashName, key)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := NewTarSumHash(r, true, info.Version, tHash)
+	if err != nil {
+		return nil, err
+	}
+	return &VerifiedTarSum{TarSum: ts, r: r, expected: expected}, nil
+}
@@ -0,0 +1,120 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+)
+
+// Limits are the limits of the tar of a TarSum, which fails with a
+// LimitError once one is exceeded, e.g. to abort the sum of a decompression
+// bomb uploaded to a registry rather than to tie up its worker. A limit of
+// 0 is no limit.
+type Limits struct {
+	MaxEntries int64 // the entries of the tar
+	MaxBytes   int64 // the bytes of the tar, uncompressed
+	// MaxHeaderBytes limits the bytes of the headers of each entry, with
+	// the chain of PAX and GNU long name and link headers preceding it. It
+	// can't be less than the 1024 bytes of the 2 blocks ending the tar.
+	MaxHeaderBytes int64
+}
+
+// LimitError is returned by a TarSum whose tar exceeds one of its Limits.
+type LimitError struct {
+	Limit string // "entries", "bytes" or "header bytes"
+	Max   int64
+}
+
+func (e LimitError) Error() string {
+	return fmt.Sprintf("the tar exceeds the limit of %d %s", e.Max, e.Limit)
+}
+
+// SetLimits sets the limits of the tar of the TarSum ts, which may be a
+// VerifiedTarSum. They must be set before ts is read.
+func SetLimits(ts TarSum, limits Limits) error {
+	if limits.MaxEntries < 0 || limits.MaxBytes < 0 || limits.MaxHeaderBytes < 0 {
+		return fmt.Errorf("invalid TarSum limits %+v: the limits can't be negative", limits)
+	}
+	if limits.MaxHeaderBytes != 0 && limits.MaxHeaderBytes < 2*blockSize {
+		return fmt.Errorf("invalid TarSum limits %+v: the limit of the header bytes can't be less than %d", limits, 2*blockSize)
+	}
+	t, err := unreadTarSum(ts)
+	if err != nil {
+		return err
+	}
+	t.limits = limits
+	return nil
+}
+
+// next returns the header of the next entry of the tar read by tarR,
+// checking it against the limits and the strict entries of ts.
+func (ts *tarSum) next(tarR *tar.Reader) (*tar.Header, error) {
+	if ts.limited != nil {
+		ts.limited.beginHeader()
+	}
+	hdr, err := tarR.Next()
+	if ts.limited != nil {
+		ts.limited.headerEnd = -1
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ts.limits.MaxEntries > 0 && ts.fileCounter >= ts.limits.MaxEntries {
+		return nil, LimitError{Limit: "entries", Max: ts.limits.MaxEntries}
+	}
+	if err := ts.checkEntry(hdr); err != nil {
+		return nil, err
+	}
+	return hdr, nil
+}
+
+// limitReader fails with a LimitError once the bytes read from its Reader,
+// the uncompressed tar, exceed the limits, the ones of the whole tar or the
+// ones of the headers being read.
+type limitReader struct {
+	io.Reader
+	limits    Limits
+	n         int64
+	headerEnd int64 // the offset up to which the headers are read, or -1
+}
+
+func newLimitReader(r io.Reader, limits Limits) *limitReader {
+	return &limitReader{Reader: r, limits: limits, headerEnd: -1}
+}
+
+// beginHeader marks the headers of the next entry, which start after the
+// padding of the content of the entry read, as being read.
+func (r *limitReader) beginHeader() {
+	if r.limits.MaxHeaderBytes == 0 {
+		return
+	}
+	padding := -r.n & (blockSize - 1)
+	r.headerEnd = r.n + padding + r.limits.MaxHeaderBytes
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	end, limitErr := int64(-1), error(nil)
+	if r.limits.MaxBytes > 0 {
+		end, limitErr = r.limits.MaxBytes, LimitError{Limit: "bytes", Max: r.limits.MaxBytes}
+	}
+	if r.headerEnd >= 0 && (end < 0 || r.headerEnd < end) {
+		end, limitErr = r.headerEnd, LimitError{Limit: "header bytes", Max: r.limits.MaxHeaderBytes}
+	}
+	if end < 0 {
+		n, err := r.Reader.Read(p)
+		r.n += int64(n)
+		return n, err
+	}
+	// read a byte past the limit, to tell whether it is exceeded
+	if left := end - r.n + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	if r.n > end {
+		n -= int(r.n - end)
+		r.n = end
+		return n, limitErr
+	}
+	return n, err
+}
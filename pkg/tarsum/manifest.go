@@ -0,0 +1,136 @@
+package tarsum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Manifest is the JSON document of the sums of the entries of a tar, written
+// by WriteManifest, e.g. for the tools auditing the layers.
+type Manifest struct {
+	TarSum  string          `json:"tarsum"` // the tarsum of the tar, without extra
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry is an entry of a Manifest.
+type ManifestEntry struct {
+	Path   string `json:"path"` // the name of the entry, without "./" and a trailing "/"
+	Size   int64  `json:"size"`
+	Mode   int64  `json:"mode"`
+	Digest string `json:"digest"` // the hex sum of the headers and the content of the entry
+}
+
+// ManifestError is returned by VerifyManifest if the entries of the tar
+// aren't the ones of the manifest.
+type ManifestError struct {
+	Added   []string // the paths of the entries which aren't in the manifest
+	Deleted []string // the paths of the entries of the manifest which aren't in the tar
+	Changed []string // the paths of the entries whose sums aren't the ones of the manifest
+}
+
+func (e ManifestError) Error() string {
+	var changes []string
+	for _, c := range []struct {
+		paths []string
+		kind  string
+	}{{e.Added, "added"}, {e.Deleted, "deleted"}, {e.Changed, "changed"}} {
+		if len(c.paths) > 0 {
+			changes = append(changes, fmt.Sprintf("%s %s", c.kind, strings.Join(c.paths, ", ")))
+		}
+	}
+	return "the tar doesn't match its manifest: " + strings.Join(changes, "; ")
+}
+
+// ManifestTarSum is a TarSum which writes the Manifest of the entries of its
+// tar, as the TarSums returned by NewTarSum and NewVerifiedTarSum do.
+type ManifestTarSum interface {
+	TarSum
+	WriteManifest(w io.Writer) error
+}
+
+// WriteManifest writes the Manifest of the entries of the tar of the TarSum
+// ts to w, see the WriteManifest method of the TarSums, or returns an error
+// if ts isn't a ManifestTarSum.
+func WriteManifest(ts TarSum, w io.Writer) error {
+	m, ok := ts.(ManifestTarSum)
+	if !ok {
+		return errors.New("the manifest of the TarSum can't be written")
+	}
+	return m.WriteManifest(w)
+}
+
+// WriteManifest writes the Manifest of the entries of the tar of ts, which
+// must be read to its end, to w, in the order of the tar.
+func (ts *tarSum) WriteManifest(w io.Writer) error {
+	if !ts.finished {
+		return errors.New("the manifest of a TarSum is written once it is read")
+	}
+	return json.NewEncoder(w).Encode(ts.manifest())
+}
+
+// WriteManifest writes the Manifest of the entries of the tar of the TarSum
+// of v to w, see WriteManifest.
+func (v *VerifiedTarSum) WriteManifest(w io.Writer) error {
+	return WriteManifest(v.TarSum, w)
+}
+
+// manifest returns the Manifest of the entries of the tar read by ts.
+func (ts *tarSum) manifest() Manifest {
+	m := Manifest{TarSum: ts.Sum(nil), Entries: make([]ManifestEntry, 0, len(ts.sums))}
+	sums := append(FileInfoSums(nil), ts.sums...)
+	sums.SortByPos()
+	for _, fis := range sums {
+		fi := fis.(fileInfoSum)
+		m.Entries = append(m.Entries, ManifestEntry{Path: fi.name, Size: fi.size, Mode: fi.mode, Digest: fi.sum})
+	}
+	return m
+}
+
+// NewManifest reads the uncompressed tar from r and returns the Manifest of
+// its entries, with the TarSum version and hash of the label, e.g.
+// "tarsum.v1+sha256", like WriteManifest.
+func NewManifest(r io.Reader, label string) (Manifest, error) {
+	ts, err := NewTarSumForLabel(r, true, label)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		return Manifest{}, err
+	}
+	return ts.(*tarSum).manifest(), nil
+}
+
+// ReadManifest reads the Manifest written by WriteManifest from r.
+func ReadManifest(r io.Reader) (Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return m, fmt.Errorf("invalid tarsum manifest: %v", err)
+	}
+	if _, err := ParseTarSumInfo(m.TarSum); err != nil {
+		return m, fmt.Errorf("invalid tarsum manifest: %v", err)
+	}
+	return m, nil
+}
+
+// VerifyManifest reads the uncompressed tar from r and returns a
+// ManifestError if its entries aren't the ones of the manifest, with the
+// version and the hash of its tarsum. The entries of the same path are
+// compared in the order of the tar.
+func VerifyManifest(r io.Reader, manifest Manifest) error {
+	actual, err := NewManifest(r, manifest.TarSum)
+	if err != nil {
+		return err
+	}
+	added, removed, modified, err := Diff(manifest, actual)
+	if err != nil {
+		return err
+	}
+	if len(added) == 0 && len(removed) == 0 && len(modified) == 0 {
+		return nil
+	}
+	return ManifestError{Added: added, Deleted: removed, Changed: modified}
+}
@@ -0,0 +1,99 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"encoding/hex"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/pkg/pools"
+)
+
+// versionSum is the state of the sum of a version by SumVersions or a Hash.
+type versionSum struct {
+	headerSelector TarHeaderSelector
+	h              hash.Hash
+	sums           FileInfoSums
+	headersOnly    bool // the contents of the files aren't hashed, see VersionMeta
+}
+
+// SumVersions returns the tarsums of each of the versions of the
+// uncompressed tar read from r, and of extra like TarSum.Sum. The tar is
+// read once for all of them, which avoids reading and decompressing a layer
+// several times while migrating it to another version. The hash of the
+// sums is tHash, or DefaultTHash if nil.
+func SumVersions(r io.Reader, versions []Version, tHash THash, extra []byte) (map[Version]string, error) {
+	if tHash == nil {
+		tHash = DefaultTHash
+	}
+	vsums := make([]*versionSum, len(versions))
+	for i, v := range versions {
+		headerSelector, err := GetTarHeaderSelector(v)
+		if err != nil {
+			return nil, err
+		}
+		vsums[i] = &versionSum{headerSelector: headerSelector, h: tHash.Hash(), headersOnly: !hashesContents(v)}
+	}
+	if err := sumTarFiles(r, vsums); err != nil {
+		return nil, err
+	}
+
+	sums := make(map[Version]string, len(versions))
+	for i, v := range versions {
+		sums[v] = sumFileInfoSums(vsums[i].sums, extra, v, tHash)
+	}
+	return sums, nil
+}
+
+// sumTarFiles appends the sums of the files of the uncompressed tar read
+// from r to the ones of each of vsums.
+func sumTarFiles(r io.Reader, vsums []*versionSum) error {
+	var hashers []io.Writer
+	for _, vs := range vsums {
+		if !vs.headersOnly {
+			hashers = append(hashers, vs.h)
+		}
+	}
+	// the contents of the files are hashed for all the versions at once
+	contents := io.MultiWriter(hashers...)
+
+	tarR := tar.NewReader(r)
+	for pos := int64(0); ; pos++ {
+		hdr, err := tarR.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, vs := range vsums {
+			vs.begin(hdr)
+		}
+		// unless they are hashed, the contents are skipped by Next, which
+		// seeks over them if r is an io.Seeker
+		if len(hashers) > 0 {
+			if _, err := pools.Copy(contents, tarR); err != nil {
+				return err
+			}
+		}
+		for _, vs := range vsums {
+			vs.end(hdr, pos)
+		}
+	}
+}
+
+// begin starts the sum of the file of hdr, hashing its headers.
+func (vs *versionSum) begin(hdr *tar.Header) {
+	vs.h.Reset()
+	for _, elem := range vs.headerSelector.SelectHeaders(hdr) {
+		vs.h.Write([]byte(elem[0] + elem[1]))
+	}
+}
+
+// end appends the sum of the file of hdr, at the position pos in the tar,
+// once its contents are hashed.
+func (vs *versionSum) end(hdr *tar.Header, pos int64) {
+	name := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, "./"), "/")
+	vs.sums = append(vs.sums, fileInfoSum{name: name, sum: hex.EncodeToString(vs.h.Sum(nil)), pos: pos, size: hdr.Size, mode: hdr.Mode})
+}
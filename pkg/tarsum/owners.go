@@ -0,0 +1,33 @@
+package tarsum
+
+import (
+	"archive/tar"
+)
+
+// SetIgnoreOwners makes the TarSum ts, which may be a VerifiedTarSum, sum
+// the files of its tar as if they were owned by root, selecting their
+// headers with WithoutOwners, so that the same files built by root and by
+// a rootless user have the same sum. The sum is still labeled by the
+// version of ts, it is only verified by the TarSums ignoring the owners
+// too, unlike the ones of a version registered with WithoutOwners. It must
+// be set before ts is read.
+func SetIgnoreOwners(ts TarSum) error {
+	t, err := unreadTarSum(ts)
+	if err != nil {
+		return err
+	}
+	t.headerSelector = WithoutOwners(t.headerSelector)
+	return nil
+}
+
+// WithoutOwners returns the TarHeaderSelector selecting the headers of a
+// file with headerSelector, the uid and the gid of the file being zero and
+// its uname and gname empty.
+func WithoutOwners(headerSelector TarHeaderSelector) TarHeaderSelector {
+	return TarHeaderSelectFunc(func(h *tar.Header) [][2]string {
+		ownerless := *h
+		ownerless.Uid, ownerless.Gid = 0, 0
+		ownerless.Uname, ownerless.Gname = "", ""
+		return headerSelector.SelectHeaders(&ownerless)
+	})
+}
@@ -0,0 +1,221 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/pkg/pools"
+)
+
+// ReaderAtTarSum is the tarsum of an uncompressed tar read at random,
+// computed by NewTarSumForReaderAt.
+type ReaderAtTarSum struct {
+	sums  FileInfoSums
+	v     Version
+	tHash THash
+}
+
+// NewTarSumForReaderAt returns the TarSum of the size bytes of the
+// uncompressed tar of r, e.g. an *os.File of a layer store, with the version
+// v and the hash tHash, or DefaultTHash if nil. The entries of the tar are
+// indexed first, seeking over the contents of their files, which are then
+// hashed concurrently by workers goroutines, or GOMAXPROCS ones if workers
+// isn't positive, rather than by a single hash which is the bottleneck of
+// reading from fast disks. The sums are the ones of NewTarSum.
+func NewTarSumForReaderAt(r io.ReaderAt, size int64, v Version, tHash THash, workers int) (*ReaderAtTarSum, error) {
+	headerSelector, err := GetTarHeaderSelector(v)
+	if err != nil {
+		return nil, err
+	}
+	if tHash == nil {
+		tHash = DefaultTHash
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	s := &entrySummer{r: r, headerSelector: headerSelector, tHash: tHash, done: make(chan struct{})}
+
+	// the entries are sent to the workers as they are indexed
+	jobs := make(chan *entryJob, 2*workers)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			s.hashEntries(jobs)
+		}()
+	}
+	entries, err := s.index(io.NewSectionReader(r, 0, size), hashesContents(v), jobs)
+	close(jobs)
+	wg.Wait()
+	if err == nil {
+		err = s.err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &ReaderAtTarSum{sums: make(FileInfoSums, 0, len(entries)), v: v, tHash: tHash}
+	for pos, e := range entries {
+		name := strings.TrimSuffix(strings.TrimPrefix(e.hdr.Name, "./"), "/")
+		ts.sums = append(ts.sums, fileInfoSum{name: name, sum: e.sum, pos: int64(pos), size: e.hdr.Size, mode: e.hdr.Mode})
+	}
+	return ts, nil
+}
+
+// Sum returns the tarsum of the tar, and of extra like TarSum.Sum.
+func (ts *ReaderAtTarSum) Sum(extra []byte) string {
+	return sumFileInfoSums(ts.sums, extra, ts.v, ts.tHash)
+}
+
+// GetSums returns the sums of the files of the tar.
+func (ts *ReaderAtTarSum) GetSums() FileInfoSums {
+	return ts.sums
+}
+
+// Version returns the version of the tarsum.
+func (ts *ReaderAtTarSum) Version() Version {
+	return ts.v
+}
+
+// Hash returns the hash of the tarsum.
+func (ts *ReaderAtTarSum) Hash() THash {
+	return ts.tHash
+}
+
+// entryJob is an entry of a tar indexed by NewTarSumForReaderAt, whose sum
+// is set once it is hashed.
+type entryJob struct {
+	hdr    *tar.Header
+	offset int64 // of the contents of the file in the tar
+	size   int64 // of the contents hashed
+	sum    string
+}
+
+// entrySummer hashes the entries of a tar read from r, the first error of
+// which stops the others.
+type entrySummer struct {
+	r              io.ReaderAt
+	headerSelector TarHeaderSelector
+	tHash          THash
+
+	once sync.Once
+	done chan struct{} // closed on the first error
+	err  error
+}
+
+func (s *entrySummer) fail(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+// index reads the entries of the tar of sr, seeking over the contents of
+// their files, and sends them to jobs to be hashed. It returns the entries
+// in the order of the tar. The sparse files, whose contents aren't stored
+// whole at their offsets, are hashed as they are read, with their holes
+// filled with zeros.
+func (s *entrySummer) index(sr *io.SectionReader, contents bool, jobs chan<- *entryJob) ([]*entryJob, error) {
+	var (
+		entries []*entryJob
+		h       = s.tHash.Hash()
+		tarR    = tar.NewReader(sr)
+	)
+	for {
+		hdr, err := tarR.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// the headers are read whole by tar.Reader, sr is at the contents
+		offset, err := sr.Seek(0, os.SEEK_CUR)
+		if err != nil {
+			return nil, err
+		}
+		e := &entryJob{hdr: hdr, offset: offset}
+		entries = append(entries, e)
+		if contents && isSparse(hdr) {
+			if err := s.hashEntry(h, e, tarR); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if contents && !isHeaderOnly(hdr) {
+			e.size = hdr.Size
+		}
+		select {
+		case jobs <- e:
+		case <-s.done:
+			return nil, s.err
+		}
+	}
+}
+
+// hashEntries hashes the entries of jobs, until they are all hashed or one
+// of them fails.
+func (s *entrySummer) hashEntries(jobs <-chan *entryJob) {
+	h := s.tHash.Hash()
+	for e := range jobs {
+		select {
+		case <-s.done:
+			continue
+		default:
+		}
+		if err := s.hashEntry(h, e, io.NewSectionReader(s.r, e.offset, e.size)); err != nil {
+			s.fail(err)
+		}
+	}
+}
+
+// hashEntry sets the sum of the headers of e and of the contents of its
+// file read from r, with h.
+func (s *entrySummer) hashEntry(h hash.Hash, e *entryJob, r io.Reader) error {
+	h.Reset()
+	for _, elem := range s.headerSelector.SelectHeaders(e.hdr) {
+		if _, err := h.Write([]byte(elem[0] + elem[1])); err != nil {
+			return err
+		}
+	}
+	n, err := pools.Copy(h, r)
+	if err != nil {
+		return err
+	}
+	if e.size > 0 && n != e.size {
+		return io.ErrUnexpectedEOF
+	}
+	e.sum = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// isSparse returns whether hdr is the header of a sparse file, of the old
+// GNU format or of the PAX ones.
+func isSparse(hdr *tar.Header) bool {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for k := range hdr.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// isHeaderOnly returns whether the file of hdr has no contents in the tar,
+// whatever the size of its header, like tar.Reader reads it.
+func isHeaderOnly(hdr *tar.Header) bool {
+	switch hdr.Typeflag {
+	case tar.TypeLink, tar.TypeSymlink, tar.TypeChar, tar.TypeBlock, tar.TypeDir, tar.TypeFifo:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"encoding/hex"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/pools"
+)
+
+// PathTarSum is the tarsum of a directory tree, computed by
+// NewTarSumForPath.
+type PathTarSum struct {
+	sums  FileInfoSums
+	v     Version
+	tHash THash
+}
+
+// NewTarSumForPath returns the TarSum of the uncompressed tar
+// archive.TarWithOptions would archive from the directory at dir, with the
+// version v, computed from the headers and the files of the tree without
+// building the tar. The tree is archived with the options, which may be
+// nil to archive it whole, but their compression. Their ExcludePatterns
+// follow the semantics of .dockerignore, see fileutils.PatternMatcher, so
+// that e.g. the sum of a build context ignores the files it ignores.
+func NewTarSumForPath(dir string, v Version, options *archive.TarOptions) (*PathTarSum, error) {
+	headerSelector, err := GetTarHeaderSelector(v)
+	if err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = &archive.TarOptions{}
+	}
+	ts := &PathTarSum{v: v, tHash: DefaultTHash}
+	h := ts.tHash.Hash()
+	pos := int64(0)
+	err = archive.WalkTarWithOptions(dir, options, func(path string, hdr *tar.Header) error {
+		h.Reset()
+		for _, elem := range headerSelector.SelectHeaders(hdr) {
+			if _, err := h.Write([]byte(elem[0] + elem[1])); err != nil {
+				return err
+			}
+		}
+		// the files aren't even opened by VersionMeta
+		if hdr.Typeflag == tar.TypeReg && hashesContents(v) {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = pools.Copy(h, file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, "./"), "/")
+		ts.sums = append(ts.sums, fileInfoSum{name: name, sum: hex.EncodeToString(h.Sum(nil)), pos: pos, size: hdr.Size, mode: hdr.Mode})
+		pos++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Sum returns the tarsum of the tree, and of extra like TarSum.Sum.
+func (ts *PathTarSum) Sum(extra []byte) string {
+	return sumFileInfoSums(ts.sums, extra, ts.v, ts.tHash)
+}
+
+// GetSums returns the sums of the files of the tree.
+func (ts *PathTarSum) GetSums() FileInfoSums {
+	return ts.sums
+}
+
+// Version returns the version of the tarsum.
+func (ts *PathTarSum) Version() Version {
+	return ts.v
+}
+
+// Hash returns the hash of the tarsum.
+func (ts *PathTarSum) Hash() THash {
+	return ts.tHash
+}
@@ -0,0 +1,28 @@
+package tarsum
+
+import (
+	"github.com/docker/docker/pkg/pools"
+)
+
+// SetPooling sets whether the TarSum ts, which may be a VerifiedTarSum,
+// reuses the buffers and the gzip readers and writers of the TarSums read
+// before it, which cuts the allocations of summing many small tars. They
+// are reused unless it is disabled. It must be set before ts is read.
+func SetPooling(ts TarSum, pooled bool) error {
+	t, err := unreadTarSum(ts)
+	if err != nil {
+		return err
+	}
+	if t.pooled == pooled {
+		return nil
+	}
+	// the buffers and the writer taken by initTarSum are replaced
+	t.putWriter(t.writer)
+	if t.pooled {
+		pools.BytesBuffers.Put(t.bufTar)
+		pools.BytesBuffers.Put(t.bufWriter)
+	}
+	t.pooled = pooled
+	t.initBuffers()
+	return nil
+}
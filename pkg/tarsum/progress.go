@@ -0,0 +1,78 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"errors"
+)
+
+// ProgressFunc is called by a TarSum as it reads the entries of its tar:
+// once with the header of each entry, and then as the content of the entry
+// is read. bytesProcessed is the size of the content of the entry read,
+// totalBytes the one of all the entries read, uncompressed.
+type ProgressFunc func(hdr *tar.Header, bytesProcessed, totalBytes int64)
+
+// SetProgress sets the progress function of the TarSum ts, which may be a
+// VerifiedTarSum, e.g. to render the progress of the verification of a
+// large layer. It must be set before ts is read.
+func SetProgress(ts TarSum, progress ProgressFunc) error {
+	t, err := unreadTarSum(ts)
+	if err != nil {
+		return err
+	}
+	t.progress = progress
+	return nil
+}
+
+// unreadTarSum returns the tarSum of ts, which may be a VerifiedTarSum, to
+// set its options, which must be set before it is read.
+func unreadTarSum(ts TarSum) (*tarSum, error) {
+	t, ok := tarSumOf(ts)
+	if !ok {
+		return nil, errors.New("the options of the TarSum can't be set")
+	}
+	if t.tarR != nil || t.finished {
+		return nil, errors.New("the options of a TarSum must be set before it is read")
+	}
+	return t, nil
+}
+
+// tarSumOf returns the tarSum of ts, which may be a VerifiedTarSum, unless
+// ts is another implementation of TarSum.
+func tarSumOf(ts TarSum) (*tarSum, bool) {
+	if v, ok := ts.(*VerifiedTarSum); ok {
+		ts = v.TarSum
+	}
+	t, ok := ts.(*tarSum)
+	return t, ok
+}
+
+// progressEntry reports that the entry of hdr is read.
+func (ts *tarSum) progressEntry(hdr *tar.Header) {
+	if ts.progress == nil {
+		return
+	}
+	ts.progressHdr = hdr
+	ts.entryBytes = 0
+	ts.progress(hdr, 0, ts.totalBytes)
+}
+
+// progressed reports that n more bytes of the content of the current entry
+// are read.
+func (ts *tarSum) progressed(n int) {
+	if ts.progress == nil || ts.progressHdr == nil || n == 0 {
+		return
+	}
+	ts.entryBytes += int64(n)
+	ts.totalBytes += int64(n)
+	ts.progress(ts.progressHdr, ts.entryBytes, ts.totalBytes)
+}
+
+// progressWriter reports the content written to it as read by its TarSum.
+type progressWriter struct {
+	ts *tarSum
+}
+
+func (w progressWriter) Write(p []byte) (int, error) {
+	w.ts.progressed(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,100 @@
+package tarsum
+
+import (
+	"errors"
+	"hash"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the statistics of a TarSum, of the part of its tar read: all of
+// it once the TarSum is read to its end.
+type Stats struct {
+	Entries           int64         // the entries of the tar
+	UncompressedBytes int64         // the bytes of the tar
+	CompressedBytes   int64         // the bytes read, of the tar if it isn't compressed
+	ReadTime          time.Duration // the time spent reading and decompressing the tar
+	HashTime          time.Duration // the time spent hashing the entries
+}
+
+// CompressionRatio returns the ratio of the uncompressed bytes of the tar to
+// the compressed bytes read, or 0 if none was read.
+func (s Stats) CompressionRatio() float64 {
+	if s.CompressedBytes == 0 {
+		return 0
+	}
+	return float64(s.UncompressedBytes) / float64(s.CompressedBytes)
+}
+
+// StatsTarSum is a TarSum which reports its statistics, as the TarSums
+// returned by NewTarSum and NewVerifiedTarSum do.
+type StatsTarSum interface {
+	TarSum
+	Stats() Stats
+}
+
+// GetStats returns the statistics of the TarSum ts, or an error if it isn't
+// a StatsTarSum.
+func GetStats(ts TarSum) (Stats, error) {
+	s, ok := ts.(StatsTarSum)
+	if !ok {
+		return Stats{}, errors.New("the statistics of the TarSum can't be read")
+	}
+	return s.Stats(), nil
+}
+
+// Stats returns the statistics of the part of the tar of ts read. It may be
+// called while ts is read.
+func (ts *tarSum) Stats() Stats {
+	return Stats{
+		Entries:           int64(len(ts.sums)),
+		UncompressedBytes: atomic.LoadInt64(&ts.stats.UncompressedBytes),
+		CompressedBytes:   atomic.LoadInt64(&ts.stats.CompressedBytes),
+		ReadTime:          time.Duration(atomic.LoadInt64((*int64)(&ts.stats.ReadTime))),
+		HashTime:          ts.stats.HashTime,
+	}
+}
+
+// Stats returns the statistics of the TarSum of v, the zero Stats if it
+// isn't a StatsTarSum.
+func (v *VerifiedTarSum) Stats() Stats {
+	s, _ := GetStats(v.TarSum)
+	return s
+}
+
+// statsReader counts the bytes read from its Reader, and the time spent
+// reading them if d is not nil. The counts are updated atomically, the blob
+// of a TarSum may be read by the goroutine feeding the command decompressing
+// it.
+type statsReader struct {
+	io.Reader
+	n *int64
+	d *time.Duration
+}
+
+func (r *statsReader) Read(p []byte) (int, error) {
+	var start time.Time
+	if r.d != nil {
+		start = time.Now()
+	}
+	n, err := r.Reader.Read(p)
+	if r.d != nil {
+		atomic.AddInt64((*int64)(r.d), int64(time.Since(start)))
+	}
+	atomic.AddInt64(r.n, int64(n))
+	return n, err
+}
+
+// timedHash counts the time spent writing to its Hash.
+type timedHash struct {
+	hash.Hash
+	d *time.Duration
+}
+
+func (h *timedHash) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := h.Hash.Write(p)
+	*h.d += time.Since(start)
+	return n, err
+}
@@ -0,0 +1,79 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// EntryError is returned by a TarSum with strict entries, see
+// SetStrictEntries, if an entry of its tar is rejected.
+type EntryError struct {
+	Name   string // the name of the entry
+	Reason string
+}
+
+func (e EntryError) Error() string {
+	return fmt.Sprintf("invalid tar entry %q: %s", e.Name, e.Reason)
+}
+
+// SetStrictEntries makes the TarSum ts, which may be a VerifiedTarSum, fail
+// with an EntryError if its tar has an entry whose path is absolute, goes up
+// with "..", or is the one of another entry, a hard link whose target is
+// such a path, or a symbolic link whose target resolves out of the tar, which
+// the tarsums otherwise sum, e.g. to reject the tars which can't be
+// extracted as they are summed. The absolute targets of symbolic links
+// resolve from the root of the tar. It must be set before ts is read.
+func SetStrictEntries(ts TarSum) error {
+	t, err := unreadTarSum(ts)
+	if err != nil {
+		return err
+	}
+	t.entries = map[string]struct{}{}
+	return nil
+}
+
+// checkEntry returns an EntryError if ts has strict entries and rejects the
+// entry of hdr.
+func (ts *tarSum) checkEntry(hdr *tar.Header) error {
+	if ts.entries == nil {
+		return nil
+	}
+	if reason := checkPath(hdr.Name); reason != "" {
+		return EntryError{Name: hdr.Name, Reason: "the path " + reason}
+	}
+	name := path.Clean(hdr.Name)
+	switch hdr.Typeflag {
+	case tar.TypeLink:
+		if reason := checkPath(hdr.Linkname); reason != "" {
+			return EntryError{Name: hdr.Name, Reason: "the target of the link " + reason}
+		}
+	case tar.TypeSymlink:
+		target := hdr.Linkname
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(name), target)
+		}
+		if target == ".." || strings.HasPrefix(target, "../") {
+			return EntryError{Name: hdr.Name, Reason: "the target of the link resolves out of the tar"}
+		}
+	}
+	if _, exists := ts.entries[name]; exists {
+		return EntryError{Name: hdr.Name, Reason: "the path is the one of another entry"}
+	}
+	ts.entries[name] = struct{}{}
+	return nil
+}
+
+// checkPath returns why the path p of the tar is rejected, or "".
+func checkPath(p string) string {
+	if strings.HasPrefix(p, "/") {
+		return "is absolute"
+	}
+	for _, elem := range strings.Split(p, "/") {
+		if elem == ".." {
+			return "goes up out of the tar"
+		}
+	}
+	return ""
+}
@@ -4,20 +4,22 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
-	"crypto"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"hash"
 	"io"
+	"io/ioutil"
 	"strings"
-)
+	"sync"
 
-const (
-	buf8K  = 8 * 1024
-	buf16K = 16 * 1024
-	buf32K = 32 * 1024
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/pools"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
 )
 
 // NewTarSum creates a new interface for calculating a fixed time checksum of a
@@ -30,9 +32,23 @@ func NewTarSum(r io.Reader, dc bool, v Version) (TarSum, error) {
 	return NewTarSumHash(r, dc, v, DefaultTHash)
 }
 
+// NewTarSumAutoDetect creates a new TarSum of a tar which is decompressed if
+// it was compressed with gzip, bzip2, xz or zstd. The TarSum reads back the
+// uncompressed tar. The sum doesn't depend on the compression of the tar
+// read back, which NewTarSum disables with dc.
+func NewTarSumAutoDetect(r io.Reader, v Version) (TarSum, error) {
+	headerSelector, err := GetTarHeaderSelector(v)
+	if err != nil {
+		return nil, err
+	}
+	ts := &tarSum{Reader: r, DisableCompression: true, tarSumVersion: v, headerSelector: headerSelector, tHash: DefaultTHash, autoDetect: true}
+	err = ts.initTarSum()
+	return ts, err
+}
+
 // Create a new TarSum, providing a THash to use rather than the DefaultTHash
 func NewTarSumHash(r io.Reader, dc bool, v Version, tHash THash) (TarSum, error) {
-	headerSelector, err := getTarHeaderSelector(v)
+	headerSelector, err := GetTarHeaderSelector(v)
 	if err != nil {
 		return nil, err
 	}
@@ -41,8 +57,14 @@ func NewTarSumHash(r io.Reader, dc bool, v Version, tHash THash) (TarSum, error)
 	return ts, err
 }
 
-// Create a new TarSum using the provided TarSum version+hash label.
+// Create a new TarSum using the provided TarSum version+hash label, e.g.
+// "tarsum.v1+sha256". The label may be a whole tarsum, e.g.
+// "tarsum.dev+sha512:{hex}", whose digest is ignored. The hash is the one
+// registered with its name.
 func NewTarSumForLabel(r io.Reader, disableCompression bool, label string) (TarSum, error) {
+	if i := strings.Index(label, ":"); i != -1 {
+		label = label[:i]
+	}
 	parts := strings.SplitN(label, "+", 2)
 	if len(parts) != 2 {
 		return nil, errors.New("tarsum label string should be of the form: {tarsum_version}+{hash_name}")
@@ -50,17 +72,18 @@ func NewTarSumForLabel(r io.Reader, disableCompression bool, label string) (TarS
 
 	versionName, hashName := parts[0], parts[1]
 
-	version, ok := tarSumVersionsByName[versionName]
+	version, ok := versionByName(versionName)
 	if !ok {
 		return nil, fmt.Errorf("unknown TarSum version name: %q", versionName)
 	}
 
-	hashConfig, ok := standardHashConfigs[hashName]
-	if !ok {
-		return nil, fmt.Errorf("unknown TarSum hash name: %q", hashName)
+	if isHMAC(hashName) {
+		return nil, fmt.Errorf("the TarSum hash %q needs a key, see NewHMACTHash", hashName)
+	}
+	tHash, err := GetTHash(hashName)
+	if err != nil {
+		return nil, err
 	}
-
-	tHash := NewTHash(hashConfig.name, hashConfig.hash.New)
 
 	return NewTarSumHash(r, disableCompression, version, tHash)
 }
@@ -79,28 +102,45 @@ type TarSum interface {
 type tarSum struct {
 	io.Reader
 	tarR               *tar.Reader
+	decompressed       io.ReadCloser       // the decompressed tar if it was compressed
+	decompressing      bool                // the tar is decompressed by a command reading its blob in a goroutine
+	autoDetect         bool                // decompress the tar whatever its compression, not only zstd
+	pooled             bool                // the buffers and gzip readers and writers are reused, see SetPooling
+	progress           ProgressFunc        // reports the progress of the read of the tar if not nil
+	progressHdr        *tar.Header         // the header of the entry read
+	entryBytes         int64               // the bytes of the content of the entry read
+	totalBytes         int64               // the bytes of the contents of all the entries read
+	stats              Stats               // the statistics of the part of the tar read
+	entries            map[string]struct{} // the paths of the entries read if they are strict
+	limits             Limits              // the limits of the tar, see SetLimits
+	limited            *limitReader        // the reader of the tar if it has limits
+	compressed         io.Reader           // the reader of the blob if it is hashed
+	compressedTHash    THash               // hashes the blob if not nil, see SetCompressedHash
+	compressedHash     hash.Hash           // the hash of the part of the blob read
+	events             *json.Encoder       // writes the events of the entries if not nil, see SetEvents
+	ctx                Context             // cancels the TarSum if not nil
 	tarW               *tar.Writer
 	writer             writeCloseFlusher
 	bufTar             *bytes.Buffer
 	bufWriter          *bytes.Buffer
-	bufData            []byte
 	h                  hash.Hash
 	tHash              THash
 	sums               FileInfoSums
 	fileCounter        int64
 	currentFile        string
+	currentHeader      *tar.Header // the header of the entry read
 	finished           bool
 	first              bool
 	DisableCompression bool              // false by default. When false, the output gzip compressed.
 	tarSumVersion      Version           // this field is not exported so it can not be mutated during use
-	headerSelector     tarHeaderSelector // handles selecting and ordering headers for files in the archive
+	headerSelector     TarHeaderSelector // handles selecting and ordering headers for files in the archive
 }
 
-func (ts tarSum) Hash() THash {
+func (ts *tarSum) Hash() THash {
 	return ts.tHash
 }
 
-func (ts tarSum) Version() Version {
+func (ts *tarSum) Version() Version {
 	return ts.tarSumVersion
 }
 
@@ -115,19 +155,61 @@ func NewTHash(name string, h func() hash.Hash) THash {
 	return simpleTHash{n: name, h: h}
 }
 
-type tHashConfig struct {
-	name string
-	hash crypto.Hash
-}
-
 var (
-	// NOTE: DO NOT include MD5 or SHA1, which are considered insecure.
-	standardHashConfigs = map[string]tHashConfig{
-		"sha256": {name: "sha256", hash: crypto.SHA256},
-		"sha512": {name: "sha512", hash: crypto.SHA512},
+	tHashesMu sync.RWMutex
+	// NOTE: DO NOT register MD5 or SHA1 by default, which are considered
+	// insecure.
+	tHashes = map[string]THash{
+		"sha256":      NewTHash("sha256", sha256.New),
+		"sha512":      NewTHash("sha512", sha512.New),
+		"sha3-256":    NewTHash("sha3-256", sha3.New256),
+		"sha3-512":    NewTHash("sha3-512", sha3.New512),
+		"blake2b-256": NewTHash("blake2b-256", newBlake2b(blake2b.Size256)),
+		"blake2b-512": NewTHash("blake2b-512", newBlake2b(blake2b.Size)),
 	}
 )
 
+// newBlake2b returns the constructor of the unkeyed BLAKE2b hash of the
+// size, which can't fail.
+func newBlake2b(size int) func() hash.Hash {
+	return func() hash.Hash {
+		h, err := blake2b.New(size, nil)
+		if err != nil {
+			panic(err)
+		}
+		return h
+	}
+}
+
+// RegisterTHash registers the hash h with its name, with which it is then
+// used by the TarSums of labels and the tarsums parsed, e.g.
+// "tarsum.v1+{name}:{hex}". It panics if a hash is already registered with
+// the name, like the standard "sha256", "sha512", "sha3-256", "sha3-512",
+// "blake2b-256" and "blake2b-512". The name may not be prefixed with "hmac-",
+// which prefixes the names of the HMACs of the hashes, see NewHMACTHash.
+func RegisterTHash(name string, h func() hash.Hash) {
+	tHashesMu.Lock()
+	defer tHashesMu.Unlock()
+	if isHMAC(name) {
+		panic(fmt.Sprintf("tarsum: the names of the hashes prefixed with %q are the ones of their HMACs", hmacPrefix))
+	}
+	if _, exists := tHashes[name]; exists {
+		panic(fmt.Sprintf("tarsum: hash %q is already registered", name))
+	}
+	tHashes[name] = NewTHash(name, h)
+}
+
+// GetTHash returns the hash registered with the name.
+func GetTHash(name string) (THash, error) {
+	tHashesMu.RLock()
+	defer tHashesMu.RUnlock()
+	tHash, ok := tHashes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown TarSum hash name: %q", name)
+	}
+	return tHash, nil
+}
+
 // TarSum default is "sha256"
 var DefaultTHash = NewTHash("sha256", sha256.New)
 
@@ -140,7 +222,7 @@ func (sth simpleTHash) Name() string    { return sth.n }
 func (sth simpleTHash) Hash() hash.Hash { return sth.h() }
 
 func (ts *tarSum) encodeHeader(h *tar.Header) error {
-	for _, elem := range ts.headerSelector.selectHeaders(h) {
+	for _, elem := range ts.headerSelector.SelectHeaders(h) {
 		if _, err := ts.h.Write([]byte(elem[0] + elem[1])); err != nil {
 			return err
 		}
@@ -149,58 +231,79 @@ func (ts *tarSum) encodeHeader(h *tar.Header) error {
 }
 
 func (ts *tarSum) initTarSum() error {
-	ts.bufTar = bytes.NewBuffer([]byte{})
-	ts.bufWriter = bytes.NewBuffer([]byte{})
-	ts.tarR = tar.NewReader(ts.Reader)
-	ts.tarW = tar.NewWriter(ts.bufTar)
-	if !ts.DisableCompression {
-		ts.writer = gzip.NewWriter(ts.bufWriter)
-	} else {
-		ts.writer = &nopCloseFlusher{Writer: ts.bufWriter}
-	}
+	ts.pooled = true
+	ts.initBuffers()
 	if ts.tHash == nil {
 		ts.tHash = DefaultTHash
 	}
-	ts.h = ts.tHash.Hash()
+	ts.h = &timedHash{Hash: ts.tHash.Hash(), d: &ts.stats.HashTime}
 	ts.h.Reset()
 	ts.first = true
 	ts.sums = FileInfoSums{}
 	return nil
 }
 
+// initBuffers sets up the buffers and the writers of the tar read back,
+// taken from their pools if ts is pooled.
+func (ts *tarSum) initBuffers() {
+	if ts.pooled {
+		ts.bufTar = pools.BytesBuffers.Get()
+		ts.bufWriter = pools.BytesBuffers.Get()
+	} else {
+		ts.bufTar = bytes.NewBuffer([]byte{})
+		ts.bufWriter = bytes.NewBuffer([]byte{})
+	}
+	ts.tarW = tar.NewWriter(ts.bufTar)
+	ts.writer = ts.newWriter(ts.bufWriter)
+}
+
 func (ts *tarSum) Read(buf []byte) (int, error) {
+	if err := ts.cancelled(); err != nil {
+		return 0, err
+	}
 	if ts.finished {
-		return ts.bufWriter.Read(buf)
-	}
-	if len(ts.bufData) < len(buf) {
-		switch {
-		case len(buf) <= buf8K:
-			ts.bufData = make([]byte, buf8K)
-		case len(buf) <= buf16K:
-			ts.bufData = make([]byte, buf16K)
-		case len(buf) <= buf32K:
-			ts.bufData = make([]byte, buf32K)
-		default:
-			ts.bufData = make([]byte, len(buf))
+		if ts.bufWriter == nil {
+			return 0, io.EOF
 		}
+		n, err := ts.bufWriter.Read(buf)
+		if err == io.EOF {
+			ts.release()
+		}
+		return n, err
 	}
-	buf2 := ts.bufData[:len(buf)]
+	if ts.tarR == nil {
+		// the tar isn't read until it is, its writer may not be started yet
+		r, err := ts.tarReader()
+		if err != nil {
+			return 0, err
+		}
+		ts.tarR = tar.NewReader(r)
+	}
+	buf2 := pools.GetBuffer(len(buf))
+	defer pools.PutBuffer(buf2)
 
 	n, err := ts.tarR.Read(buf2)
 	if err != nil {
 		if err == io.EOF {
-			if _, err := ts.h.Write(buf2[:n]); err != nil {
+			if _, err := ts.contentHash().Write(buf2[:n]); err != nil {
+				return 0, err
+			}
+			ts.progressed(n)
+			// the last bytes of the entry may be read with io.EOF, they are
+			// written before the header of the next one
+			if _, err := ts.tarW.Write(buf2[:n]); err != nil {
 				return 0, err
 			}
 			if !ts.first {
-				ts.sums = append(ts.sums, fileInfoSum{name: ts.currentFile, sum: hex.EncodeToString(ts.h.Sum(nil)), pos: ts.fileCounter})
-				ts.fileCounter++
+				if err := ts.addSum(ts.currentHeader); err != nil {
+					return 0, err
+				}
 				ts.h.Reset()
 			} else {
 				ts.first = false
 			}
 
-			currentHeader, err := ts.tarR.Next()
+			currentHeader, err := ts.next(ts.tarR)
 			if err != nil {
 				if err == io.EOF {
 					if err := ts.tarW.Close(); err != nil {
@@ -212,19 +315,27 @@ func (ts *tarSum) Read(buf []byte) (int, error) {
 					if err := ts.writer.Close(); err != nil {
 						return 0, err
 					}
-					ts.finished = true
+					if err := ts.finish(); err != nil {
+						return 0, err
+					}
 					return n, nil
 				}
 				return n, err
 			}
 			ts.currentFile = strings.TrimSuffix(strings.TrimPrefix(currentHeader.Name, "./"), "/")
+			ts.currentHeader = currentHeader
+			ts.progressEntry(currentHeader)
 			if err := ts.encodeHeader(currentHeader); err != nil {
 				return 0, err
 			}
-			if err := ts.tarW.WriteHeader(currentHeader); err != nil {
-				return 0, err
+			if currentHeader.Typeflag == tar.TypeGNUSparse {
+				// the file is read with its holes filled with zeros, it is
+				// written as the regular file it is read as
+				regular := *currentHeader
+				regular.Typeflag = tar.TypeReg
+				currentHeader = &regular
 			}
-			if _, err := ts.tarW.Write(buf2[:n]); err != nil {
+			if err := ts.tarW.WriteHeader(currentHeader); err != nil {
 				return 0, err
 			}
 			ts.tarW.Flush()
@@ -239,9 +350,10 @@ func (ts *tarSum) Read(buf []byte) (int, error) {
 	}
 
 	// Filling the hash buffer
-	if _, err = ts.h.Write(buf2[:n]); err != nil {
+	if _, err = ts.contentHash().Write(buf2[:n]); err != nil {
 		return 0, err
 	}
+	ts.progressed(n)
 
 	// Filling the tar writter
 	if _, err = ts.tarW.Write(buf2[:n]); err != nil {
@@ -258,17 +370,222 @@ func (ts *tarSum) Read(buf []byte) (int, error) {
 	return ts.bufWriter.Read(buf)
 }
 
+// WriteTo writes the tar read back to w, like reading it to its end, the
+// files being hashed as they are copied to w rather than through the
+// buffers of Read. A tar which is already being read is copied with Read.
+func (ts *tarSum) WriteTo(w io.Writer) (int64, error) {
+	if ts.tarR != nil || ts.finished {
+		// hide WriteTo not to be called back by io.Copy
+		return io.Copy(w, struct{ io.Reader }{ts})
+	}
+	if err := ts.cancelled(); err != nil {
+		return 0, err
+	}
+	r, err := ts.tarReader()
+	if err != nil {
+		return 0, err
+	}
+	cw := &countingWriter{Writer: w}
+	out := ts.newWriter(cw)
+	tarR := tar.NewReader(r)
+	tarW := tar.NewWriter(out)
+	contents := io.MultiWriter(ts.contentHash(), tarW)
+	if ts.progress != nil {
+		contents = io.MultiWriter(ts.contentHash(), tarW, progressWriter{ts})
+	}
+	for {
+		if err := ts.cancelled(); err != nil {
+			return cw.n, err
+		}
+		hdr, err := ts.next(tarR)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return cw.n, err
+		}
+		ts.currentFile = strings.TrimSuffix(strings.TrimPrefix(hdr.Name, "./"), "/")
+		ts.progressEntry(hdr)
+		ts.h.Reset()
+		if err := ts.encodeHeader(hdr); err != nil {
+			return cw.n, err
+		}
+		if hdr.Typeflag == tar.TypeGNUSparse {
+			regular := *hdr
+			regular.Typeflag = tar.TypeReg
+			hdr = &regular
+		}
+		if err := tarW.WriteHeader(hdr); err != nil {
+			return cw.n, err
+		}
+		if _, err := pools.Copy(contents, tarR); err != nil {
+			return cw.n, err
+		}
+		if err := ts.addSum(hdr); err != nil {
+			return cw.n, err
+		}
+	}
+	if err := tarW.Close(); err != nil {
+		return cw.n, err
+	}
+	if err := out.Close(); err != nil {
+		return cw.n, err
+	}
+	ts.putWriter(out)
+	ts.h.Reset()
+	ts.first = false
+	if err := ts.finish(); err != nil {
+		return cw.n, err
+	}
+	ts.release()
+	return cw.n, nil
+}
+
+// contentHash returns the writer hashing the contents of the files, which
+// are read back but not hashed by VersionMeta.
+func (ts *tarSum) contentHash() io.Writer {
+	if !hashesContents(ts.tarSumVersion) {
+		return ioutil.Discard
+	}
+	return ts.h
+}
+
+// newWriter returns the writer of the tar read back to w, which compresses
+// it unless ts disables the compression.
+func (ts *tarSum) newWriter(w io.Writer) writeCloseFlusher {
+	if ts.DisableCompression {
+		return &nopCloseFlusher{Writer: w}
+	}
+	if ts.pooled {
+		return pools.GzipWriters.Get(w)
+	}
+	return gzip.NewWriter(w)
+}
+
+// putWriter puts the closed writer returned by newWriter back into its
+// pool.
+func (ts *tarSum) putWriter(w writeCloseFlusher) {
+	if gz, ok := w.(*gzip.Writer); ok && ts.pooled {
+		pools.GzipWriters.Put(gz)
+	}
+}
+
+// finish marks the tar as read to its end, hashing the rest of its blob if
+// ts hashes it, and releasing its reader, and the writers of the tar read
+// back which are done with. The output of the command decompressing the tar,
+// if any, is read to its end first: the goroutine feeding the command is
+// then done with the blob, and its statistics and hash are complete.
+func (ts *tarSum) finish() error {
+	var err error
+	if ts.decompressing && ts.decompressed != nil {
+		_, err = io.Copy(ioutil.Discard, ts.decompressed)
+	}
+	if err == nil {
+		err = ts.hashCompressedRest()
+	}
+	if ts.decompressed != nil {
+		ts.decompressed.Close()
+		ts.decompressed = nil
+	}
+	if ts.writer != nil {
+		ts.putWriter(ts.writer)
+		ts.writer = nil
+	}
+	if ts.pooled && ts.bufTar != nil {
+		pools.BytesBuffers.Put(ts.bufTar)
+	}
+	ts.bufTar = nil
+	ts.tarW = nil
+	ts.finished = true
+	return err
+}
+
+// release releases the buffer of the tar read back once it is read.
+func (ts *tarSum) release() {
+	if ts.pooled && ts.bufWriter != nil {
+		pools.BytesBuffers.Put(ts.bufWriter)
+	}
+	ts.bufWriter = nil
+}
+
+// tarReader returns the reader of the tar, which is decompressed if it was
+// compressed with zstd, or with any compression if ts auto detects it, so
+// that its sum is the one of the uncompressed tar, and limited by the limits
+// of ts.
+func (ts *tarSum) tarReader() (io.Reader, error) {
+	src := ts.Reader
+	if ts.compressedHash != nil {
+		src = io.TeeReader(src, ts.compressedHash)
+	}
+	src = &statsReader{Reader: src, n: &ts.stats.CompressedBytes}
+	if ts.compressedHash != nil {
+		ts.compressed = src
+	}
+	r, err := ts.decompressedReader(src)
+	if err != nil {
+		return nil, err
+	}
+	r = &statsReader{Reader: r, n: &ts.stats.UncompressedBytes, d: &ts.stats.ReadTime}
+	if ts.limits != (Limits{}) {
+		ts.limited = newLimitReader(r, ts.limits)
+		return ts.limited, nil
+	}
+	return r, nil
+}
+
+// decompressedReader returns the reader of the tar read from src, which is
+// decompressed like by tarReader.
+func (ts *tarSum) decompressedReader(src io.Reader) (io.Reader, error) {
+	// the longest magic number, the one of xz
+	magic := make([]byte, 6)
+	n, err := io.ReadFull(src, magic)
+	r := io.MultiReader(bytes.NewReader(magic[:n]), src)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	compression := archive.DetectCompression(magic[:n])
+	if compression == archive.Uncompressed || (compression != archive.Zstd && !ts.autoDetect) {
+		return r, nil
+	}
+	if compression == archive.Gzip && ts.pooled {
+		if ts.decompressed, err = pools.GzipReaders.NewReadCloser(r); err != nil {
+			return nil, err
+		}
+		return ts.decompressed, nil
+	}
+	ts.decompressed, err = archive.DecompressStream(r)
+	if err != nil {
+		return nil, err
+	}
+	// xz and zstd are decompressed by commands, which are fed the blob by a
+	// goroutine
+	ts.decompressing = compression == archive.Xz || compression == archive.Zstd
+	return ts.decompressed, nil
+}
+
 func (ts *tarSum) Sum(extra []byte) string {
-	ts.sums.SortBySums()
-	h := ts.tHash.Hash()
+	return sumFileInfoSums(ts.sums, extra, ts.Version(), ts.tHash)
+}
+
+// sumFileInfoSums returns the tarsum of version v and hash tHash of the
+// files of sums, sorting them, and of extra.
+func sumFileInfoSums(sums FileInfoSums, extra []byte, v Version, tHash THash) string {
+	checksum := v.String() + "+" + tHash.Name() + ":" + hex.EncodeToString(digestFileInfoSums(sums, extra, tHash))
+	return checksum
+}
+
+// digestFileInfoSums returns the digest of the tarsum of hash tHash of the
+// files of sums, sorting them, and of extra.
+func digestFileInfoSums(sums FileInfoSums, extra []byte, tHash THash) []byte {
+	sums.SortBySums()
+	h := tHash.Hash()
 	if extra != nil {
 		h.Write(extra)
 	}
-	for _, fis := range ts.sums {
+	for _, fis := range sums {
 		h.Write([]byte(fis.Sum()))
 	}
-	checksum := ts.Version().String() + "+" + ts.tHash.Name() + ":" + hex.EncodeToString(h.Sum(nil))
-	return checksum
+	return h.Sum(nil)
 }
 
 func (ts *tarSum) GetSums() FileInfoSums {
@@ -10,11 +10,23 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/system"
 )
 
 type testLayer struct {
@@ -36,8 +48,8 @@ var testLayers = []testLayer{
 	{
 		filename: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/layer.tar",
 		jsonfile: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/json",
-		version:  VersionDev,
-		tarsum:   "tarsum.dev+sha256:486b86e25c4db4551228154848bc4663b15dd95784b1588980f4ba1cb42e83e9"},
+		version:  Version1,
+		tarsum:   "tarsum.v1+sha256:486b86e25c4db4551228154848bc4663b15dd95784b1588980f4ba1cb42e83e9"},
 	{
 		filename: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/layer.tar",
 		jsonfile: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/json",
@@ -53,8 +65,70 @@ var testLayers = []testLayer{
 		// Tests next version of TarSum when xattrs are present
 		filename: "testdata/xattr/layer.tar",
 		jsonfile: "testdata/xattr/json",
+		version:  Version1,
+		tarsum:   "tarsum.v1+sha256:6235cd3a2afb7501bac541772a3d61a3634e95bc90bb39a4676e2cb98d08390d"},
+	{
+		// Tests every header field of Version2: a PAX tar with a long
+		// name, sub-second mtimes, xattrs, links, a device and an old
+		// regular file
+		filename: "testdata/v2/layer.tar",
+		jsonfile: "testdata/v2/json",
+		version:  Version2,
+		tarsum:   "tarsum.v2+sha256:88e8f2515a40556e9bfca9b758d5a468800fbb7d64a7395e4be10e957f47140f"},
+	{
+		filename: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/layer.tar",
+		jsonfile: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/json",
+		version:  VersionDev,
+		tarsum:   "tarsum.dev+sha256:d969ef0f25a362780cd3b52aafab134d6910f0b8d5dbc1d2b036dbc6ce6260fa"},
+	{
+		filename: "testdata/xattr/layer.tar",
+		jsonfile: "testdata/xattr/json",
+		version:  VersionDev,
+		tarsum:   "tarsum.dev+sha256:0d8e9d16334673d44703f6213801050124e84e28081f93a8ca866880512a4a2e"},
+	{
+		filename: "testdata/v2/layer.tar",
+		jsonfile: "testdata/v2/json",
+		version:  VersionDev,
+		tarsum:   "tarsum.dev+sha256:88e8f2515a40556e9bfca9b758d5a468800fbb7d64a7395e4be10e957f47140f"},
+	{
+		// Tests the values of the PAX headers: a long name and link
+		// name, ids and names too large for ustar, a sub-second mtime
+		filename: "testdata/pax/layer.tar",
+		jsonfile: "testdata/pax/json",
+		version:  VersionDev,
+		tarsum:   "tarsum.dev+sha256:2dcc7bf602d3b6e6b0ac4747e4f2e64e8db599feb53909775f325e022f321e89"},
+	{
+		// Tests a sparse file of the old GNU format, summed as the
+		// regular file it is read as
+		filename: "testdata/sparse/gnu-sparse.tar",
 		version:  VersionDev,
-		tarsum:   "tarsum.dev+sha256:6235cd3a2afb7501bac541772a3d61a3634e95bc90bb39a4676e2cb98d08390d"},
+		tarsum:   "tarsum.dev+sha256:6f28d19bb8a401bb94b83d4a23a65c23571802416fb602ab383eb4fa27dd45a8"},
+	{
+		// Tests that touching a file changes its sum with Version2, by a
+		// second or by a nanosecond of its PAX mtime, but not with Version1
+		filename: "testdata/mtime/layer.tar",
+		version:  Version2,
+		tarsum:   "tarsum.v2+sha256:a33aa98fad5aea295e1a935e9a706e94c6002414caa37b5adcc988b6a20aafa7"},
+	{
+		filename: "testdata/mtime/touched.tar",
+		version:  Version2,
+		tarsum:   "tarsum.v2+sha256:bc713303f1a77d7a8d73e3b67e369aa074913ebc8bd3df706e9dfa8d2c8e416e"},
+	{
+		filename: "testdata/mtime/touched-ns.tar",
+		version:  Version2,
+		tarsum:   "tarsum.v2+sha256:0167b635d7dcf75870d8ac3e76254484752f130ea94060b8e93884c63a5188d2"},
+	{
+		filename: "testdata/mtime/layer.tar",
+		version:  Version1,
+		tarsum:   "tarsum.v1+sha256:e7d3ecba3b454f27bc9d188cae20c01642478644e9774c0f312e01e2425e918b"},
+	{
+		filename: "testdata/mtime/touched.tar",
+		version:  Version1,
+		tarsum:   "tarsum.v1+sha256:e7d3ecba3b454f27bc9d188cae20c01642478644e9774c0f312e01e2425e918b"},
+	{
+		filename: "testdata/mtime/touched-ns.tar",
+		version:  Version1,
+		tarsum:   "tarsum.v1+sha256:e7d3ecba3b454f27bc9d188cae20c01642478644e9774c0f312e01e2425e918b"},
 	{
 		filename: "testdata/511136ea3c5a64f264b78b5433614aec563103b4d4702f3ba7d4d2698e22c158/layer.tar",
 		jsonfile: "testdata/511136ea3c5a64f264b78b5433614aec563103b4d4702f3ba7d4d2698e22c158/json",
@@ -103,6 +177,34 @@ var testLayers = []testLayer{
 		tarsum:  "tarsum+sha512:e9bfb90ca5a4dfc93c46ee061a5cf9837de6d2fdf82544d6460d3147290aecfabf7b5e415b9b6e72db9b8941f149d5d69fb17a394cbfaf2eac523bd9eae21855",
 		hash:    sha512Hash,
 	},
+	{
+		filename: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/layer.tar",
+		jsonfile: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/json",
+		version:  VersionDev,
+		tarsum:   "tarsum.dev+sha3-256:f9ccc78f532558c66e2389034efc3182a7267ea3b568f468a55767b97e1ff7d8",
+		hash:     sha3256Hash,
+	},
+	{
+		filename: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/layer.tar",
+		jsonfile: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/json",
+		version:  VersionDev,
+		tarsum:   "tarsum.dev+sha3-512:c56b5462ae4b8e6b0d7abefd65baa9c411262b95803e36f23e988ad17f5703cb1df38b4437363a49928e01e2c3000cf42694e49cb15e9e72315b45dd7f8c4896",
+		hash:     sha3512Hash,
+	},
+	{
+		filename: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/layer.tar",
+		jsonfile: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/json",
+		version:  VersionDev,
+		tarsum:   "tarsum.dev+blake2b-256:4a542753e140776f3db5a680d958f06b36472addeea0812fc738c5a1a4fa7fbd",
+		hash:     blake2b256Hash,
+	},
+	{
+		filename: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/layer.tar",
+		jsonfile: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/json",
+		version:  VersionDev,
+		tarsum:   "tarsum.dev+blake2b-512:5820fe552c56c6cc27dc553cdaa2b1bb0b365e9e87a5b7f8be754237d65a54d8e8159e8554d0f40f5ee08ea4d19f7fc2bbca872d811bff98e9ceabde9ae0e9d2",
+		hash:     blake2b512Hash,
+	},
 }
 
 type sizedOptions struct {
@@ -249,8 +351,107 @@ var (
 	sha224Hash = NewTHash("sha224", sha256.New224)
 	sha384Hash = NewTHash("sha384", sha512.New384)
 	sha512Hash = NewTHash("sha512", sha512.New)
+
+	sha3256Hash    = mustGetTHash("sha3-256")
+	sha3512Hash    = mustGetTHash("sha3-512")
+	blake2b256Hash = mustGetTHash("blake2b-256")
+	blake2b512Hash = mustGetTHash("blake2b-512")
 )
 
+func mustGetTHash(name string) THash {
+	tHash, err := GetTHash(name)
+	if err != nil {
+		panic(err)
+	}
+	return tHash
+}
+
+// TestTarSumZstd tests that the sum of a tar compressed with zstd is the
+// one of the uncompressed tar.
+func TestTarSumZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd is not installed")
+	}
+	tarBytes, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("zstd", "-c", "-q")
+	cmd.Stdin = bytes.NewReader(tarBytes)
+	zstdBytes, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sums := make([]string, 2)
+	for i, blob := range [][]byte{tarBytes, zstdBytes} {
+		ts, err := NewTarSum(bytes.NewReader(blob), true, Version1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+			t.Fatal(err)
+		}
+		if len(ts.GetSums()) != 10 {
+			t.Fatalf("Expected the sums of 10 files, got %d", len(ts.GetSums()))
+		}
+		// the zstd command is done with the blob once the tar is read
+		if stats, _ := GetStats(ts); stats.CompressedBytes != int64(len(blob)) {
+			t.Fatalf("Expected the %d bytes of the blob to be read, got %d", len(blob), stats.CompressedBytes)
+		}
+		sums[i] = ts.Sum(nil)
+	}
+	if sums[0] != sums[1] {
+		t.Fatalf("Expected the sum of the zstd tar to be %s, got %s", sums[0], sums[1])
+	}
+}
+
+func TestTarSumAutoDetect(t *testing.T) {
+	tarBytes, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gzipBuf bytes.Buffer
+	gzipW := gzip.NewWriter(&gzipBuf)
+	if _, err := gzipW.Write(tarBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipW.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archives := map[string][]byte{"tar": tarBytes, "gzip": gzipBuf.Bytes()}
+	for _, command := range []string{"xz", "zstd"} {
+		if _, err := exec.LookPath(command); err != nil {
+			continue
+		}
+		cmd := exec.Command(command, "-c", "-q")
+		cmd.Stdin = bytes.NewReader(tarBytes)
+		if archives[command], err = cmd.Output(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var expected string
+	for name, archive := range archives {
+		ts, err := NewTarSumAutoDetect(bytes.NewReader(archive), Version1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, err := ioutil.ReadAll(ts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(output, tarBytes) {
+			t.Fatalf("%s: expected the uncompressed tar to be read back", name)
+		}
+		if expected == "" {
+			expected = ts.Sum(nil)
+		} else if sum := ts.Sum(nil); sum != expected {
+			t.Fatalf("%s: expected the sum %s, got %s", name, expected, sum)
+		}
+	}
+}
+
 func TestTarSums(t *testing.T) {
 	for _, layer := range testLayers {
 		var (
@@ -329,6 +530,94 @@ func TestTarSums(t *testing.T) {
 	}
 }
 
+func TestNewTarSumForReaderAt(t *testing.T) {
+	for _, layer := range testLayers {
+		if layer.filename == "" || layer.gzip {
+			continue
+		}
+		fh, err := os.Open(layer.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fh.Close()
+		fi, err := fh.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var extra []byte
+		if layer.jsonfile != "" {
+			if extra, err = ioutil.ReadFile(layer.jsonfile); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, workers := range []int{1, 4, 0} {
+			ts, err := NewTarSumForReaderAt(fh, fi.Size(), layer.version, layer.hash, workers)
+			if err != nil {
+				t.Fatalf("%s: %v", layer.filename, err)
+			}
+			if sum := ts.Sum(extra); sum != layer.tarsum {
+				t.Errorf("%s: expected the sum %s with %d workers, got %s", layer.filename, layer.tarsum, workers, sum)
+			}
+		}
+	}
+
+	// the contents of the sparse files are read with their holes filled
+	// with zeros, like by NewTarSum
+	for _, filename := range []string{"gnu-sparse.tar", "pax-sparse-0.0.tar", "pax-sparse-0.1.tar", "pax-sparse-1.0.tar"} {
+		tarBytes, err := ioutil.ReadFile("testdata/sparse/" + filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions := []Version{Version1, Version2, VersionDev, VersionMeta}
+		expected, err := SumVersions(bytes.NewReader(tarBytes), versions, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range versions {
+			ts, err := NewTarSumForReaderAt(bytes.NewReader(tarBytes), int64(len(tarBytes)), v, nil, 2)
+			if err != nil {
+				t.Fatalf("%s: %v", filename, err)
+			}
+			if sum := ts.Sum(nil); sum != expected[v] {
+				t.Fatalf("%s: expected the sum %s, got %s", filename, expected[v], sum)
+			}
+		}
+	}
+
+	// the sums of the files are the ones of NewTarSum, in the order of the
+	// tar
+	tarBytes, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 64, size: 4096, isRand: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := NewTarSum(bytes.NewReader(tarBytes), true, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, expected); err != nil {
+		t.Fatal(err)
+	}
+	ts, err := NewTarSumForReaderAt(bytes.NewReader(tarBytes), int64(len(tarBytes)), Version1, nil, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := ts.Sum(nil); sum != expected.Sum(nil) {
+		t.Fatalf("Expected the sum %s, got %s", expected.Sum(nil), sum)
+	}
+	for i, fis := range ts.GetSums() {
+		if e := expected.GetSums()[i]; fis.Name() != e.Name() || fis.Sum() != e.Sum() || fis.Pos() != e.Pos() {
+			t.Fatalf("Expected the sum %d of %s to be %s, got %s of %s", i, e.Name(), e.Sum(), fis.Sum(), fis.Name())
+		}
+	}
+
+	// the truncated tars are invalid, whichever entry is truncated
+	for _, size := range []int64{int64(len(tarBytes)) / 2, int64(len(tarBytes)) - 4*blockSize - 100} {
+		if _, err := NewTarSumForReaderAt(bytes.NewReader(tarBytes), size, Version1, nil, 4); err == nil {
+			t.Fatalf("Expected the tar truncated to %d bytes to be invalid", size)
+		}
+	}
+}
+
 func TestIteration(t *testing.T) {
 	headerTests := []struct {
 		expectedSum string // TODO(vbatts) it would be nice to get individual sums of each
@@ -349,8 +638,8 @@ func TestIteration(t *testing.T) {
 			[]byte(""),
 		},
 		{
-			"tarsum.dev+sha256:6ffd43a1573a9913325b4918e124ee982a99c0f3cba90fc032a65f5e20bdd465",
-			VersionDev,
+			"tarsum.v1+sha256:6ffd43a1573a9913325b4918e124ee982a99c0f3cba90fc032a65f5e20bdd465",
+			Version1,
 			&tar.Header{
 				Name:     "file.txt",
 				Size:     0,
@@ -361,8 +650,8 @@ func TestIteration(t *testing.T) {
 			[]byte(""),
 		},
 		{
-			"tarsum.dev+sha256:b38166c059e11fb77bef30bf16fba7584446e80fcc156ff46d47e36c5305d8ef",
-			VersionDev,
+			"tarsum.v1+sha256:b38166c059e11fb77bef30bf16fba7584446e80fcc156ff46d47e36c5305d8ef",
+			Version1,
 			&tar.Header{
 				Name:     "another.txt",
 				Uid:      1000,
@@ -377,8 +666,8 @@ func TestIteration(t *testing.T) {
 			[]byte("test"),
 		},
 		{
-			"tarsum.dev+sha256:4cc2e71ac5d31833ab2be9b4f7842a14ce595ec96a37af4ed08f87bc374228cd",
-			VersionDev,
+			"tarsum.v1+sha256:4cc2e71ac5d31833ab2be9b4f7842a14ce595ec96a37af4ed08f87bc374228cd",
+			Version1,
 			&tar.Header{
 				Name:     "xattrs.txt",
 				Uid:      1000,
@@ -395,8 +684,8 @@ func TestIteration(t *testing.T) {
 			[]byte("test"),
 		},
 		{
-			"tarsum.dev+sha256:65f4284fa32c0d4112dd93c3637697805866415b570587e4fd266af241503760",
-			VersionDev,
+			"tarsum.v1+sha256:65f4284fa32c0d4112dd93c3637697805866415b570587e4fd266af241503760",
+			Version1,
 			&tar.Header{
 				Name:     "xattrs.txt",
 				Uid:      1000,
@@ -548,7 +837,70 @@ func Benchmark1kFilesTarGzip(b *testing.B) {
 	benchmarkTar(b, sizedOptions{1024, 1024, true, true}, true)
 }
 
+// this is 1024 1k files in the tar archive, hashed concurrently
+func Benchmark1kFilesTarReaderAt(b *testing.B) {
+	fh := sizedTar(sizedOptions{1024, 1024, true, true}).(*os.File)
+	defer os.Remove(fh.Name())
+	defer fh.Close()
+	fi, err := fh.Stat()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(1024 * 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ts, err := NewTarSumForReaderAt(fh, fi.Size(), Version0, nil, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		ts.Sum(nil)
+	}
+}
+
+// this is a single big file in the tar archive, read back with Read
+// rather than WriteTo
+func Benchmark1mbSingleFileTarRead(b *testing.B) {
+	benchmarkTarRead(b, sizedOptions{1, 1024 * 1024, true, true}, false, true)
+}
+
+// this is 1024 1k files in the tar archive, read back with Read rather
+// than WriteTo
+func Benchmark1kFilesTarRead(b *testing.B) {
+	benchmarkTarRead(b, sizedOptions{1024, 1024, true, true}, false, true)
+}
+
+// this is 16k 1.5k files in the tar archive, written to a Hash in a
+// single call
+func Benchmark16kFilesHash(b *testing.B) {
+	tarBytes, err := ioutil.ReadAll(sizedTar(sizedOptions{16 * 1024, 1536, true, false}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	h, err := NewHash(Version1, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(tarBytes)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Reset()
+		h.Write(tarBytes)
+		if h.Err() != nil {
+			b.Fatal(h.Err())
+		}
+		if len(h.GetSums()) != 16*1024 {
+			b.Fatalf("Expected the sums of %d files, got %d", 16*1024, len(h.GetSums()))
+		}
+	}
+}
+
 func benchmarkTar(b *testing.B, opts sizedOptions, isGzip bool) {
+	benchmarkTarRead(b, opts, isGzip, false)
+}
+
+func benchmarkTarRead(b *testing.B, opts sizedOptions, isGzip, read bool) {
 	var fh *os.File
 	tarReader := sizedTar(opts)
 	if br, ok := tarReader.(*os.File); ok {
@@ -565,8 +917,1901 @@ func benchmarkTar(b *testing.B, opts sizedOptions, isGzip bool) {
 			b.Error(err)
 			return
 		}
-		io.Copy(ioutil.Discard, ts)
+		var r io.Reader = ts
+		if read {
+			r = struct{ io.Reader }{ts}
+		}
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			b.Fatal(err)
+		}
 		ts.Sum(nil)
 		fh.Seek(0, 0)
 	}
 }
+
+func TestVerifiedTarSum(t *testing.T) {
+	tarBytes, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := NewTarSum(bytes.NewReader(tarBytes), true, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	sum := ts.Sum(nil)
+
+	v, err := NewVerifiedTarSum(bytes.NewReader(tarBytes), sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, v); err != nil {
+		t.Fatalf("Expected the tar to be verified, got %v", err)
+	}
+	if err := v.Close(); err != nil {
+		t.Fatalf("Expected the tar to be verified, got %v", err)
+	}
+
+	wrong := "tarsum.v1+sha256:" + hex.EncodeToString(make([]byte, 32))
+	v, err = NewVerifiedTarSum(bytes.NewReader(tarBytes), wrong)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.Copy(ioutil.Discard, v)
+	if mismatch, ok := err.(MismatchError); !ok || mismatch.Expected != wrong || mismatch.Actual != sum {
+		t.Fatalf("Expected a mismatch of %s and %s, got %v", wrong, sum, err)
+	}
+	if _, ok := v.Close().(MismatchError); !ok {
+		t.Fatal("Expected Close to return the mismatch")
+	}
+
+	v, err = NewVerifiedTarSum(bytes.NewReader(tarBytes), sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Close(); err == nil {
+		t.Fatal("Expected Close to fail if the tar wasn't read")
+	}
+
+	for _, invalid := range []string{"", "sha256:1234", "tarsum.v9+sha256:1234"} {
+		if _, err := NewVerifiedTarSum(bytes.NewReader(tarBytes), invalid); err == nil {
+			t.Fatalf("Expected the tarsum %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestSumVersions(t *testing.T) {
+	for _, layer := range []string{
+		"testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457",
+		"testdata/xattr",
+	} {
+		expected := map[Version]string{}
+		for _, l := range testLayers {
+			if l.filename == layer+"/layer.tar" && !l.gzip {
+				expected[l.version] = l.tarsum
+			}
+		}
+		jsonBytes, err := ioutil.ReadFile(layer + "/json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fh, err := os.Open(layer + "/layer.tar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sums, err := SumVersions(fh, []Version{Version0, Version1}, nil, jsonBytes)
+		fh.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sums) != 2 || sums[Version0] != expected[Version0] || sums[Version1] != expected[Version1] {
+			t.Fatalf("%s: expected the sums %v, got %v", layer, expected, sums)
+		}
+	}
+
+	// the fixture of Version2
+	jsonBytes, err := ioutil.ReadFile("testdata/v2/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh, err := os.Open("testdata/v2/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sums, err := SumVersions(fh, []Version{Version1, Version2}, nil, jsonBytes)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "tarsum.v2+sha256:88e8f2515a40556e9bfca9b758d5a468800fbb7d64a7395e4be10e957f47140f"
+	if sums[Version2] != expected {
+		t.Fatalf("Expected the sum %s, got %s", expected, sums[Version2])
+	}
+	if !strings.HasPrefix(sums[Version1], "tarsum.v1+sha256:") {
+		t.Fatalf("Expected a Version1 sum, got %s", sums[Version1])
+	}
+
+	// the sums of the files don't depend on their order
+	for i, expected := range []string{
+		"tarsum+sha256:08653904a68d3ab5c59e65ef58c49c1581caa3c34744f8d354b3f575ea04424a",
+		"tarsum+sha256:b51c13fbefe158b5ce420d2b930eef54c5cd55c50a2ee4abdddea8fa9f081e0d",
+	} {
+		fh, err := os.Open(fmt.Sprintf("testdata/collision/collision-%d.tar", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sums, err := SumVersions(fh, []Version{Version0}, nil, nil)
+		fh.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sums[Version0] != expected {
+			t.Fatalf("Expected the sum %s of collision-%d.tar, got %s", expected, i, sums[Version0])
+		}
+	}
+
+	if _, err := SumVersions(bytes.NewReader(nil), []Version{Version(42)}, nil, nil); err == nil {
+		t.Fatal("Expected an unknown version to be rejected")
+	}
+}
+
+// testContext is a Context cancelled by closing done.
+type testContext struct {
+	done chan struct{}
+}
+
+func (ctx *testContext) Done() <-chan struct{} { return ctx.done }
+
+func (ctx *testContext) Err() error {
+	select {
+	case <-ctx.done:
+		return errCancelled
+	default:
+		return nil
+	}
+}
+
+var errCancelled = fmt.Errorf("cancelled")
+
+func TestTarSumWithContext(t *testing.T) {
+	tarBytes, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := &testContext{done: make(chan struct{})}
+	ts, err := NewTarSumWithContext(ctx, bytes.NewReader(tarBytes), true, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := SumVersions(bytes.NewReader(tarBytes), []Version{Version1}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := ts.Sum(nil); sum != expected[Version1] {
+		t.Fatalf("Expected the sum %s, got %s", expected[Version1], sum)
+	}
+
+	// a read blocked on the tar returns once cancelled
+	r, w := io.Pipe()
+	defer w.Close()
+	ts, err = NewTarSumWithContext(ctx, r, false, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := ts.Read(make([]byte, 512))
+		errCh <- err
+	}()
+	close(ctx.done)
+	select {
+	case err := <-errCh:
+		if err != errCancelled {
+			t.Fatalf("Expected the read to be cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the read to return once cancelled")
+	}
+	if _, err := ts.Read(make([]byte, 512)); err != errCancelled {
+		t.Fatalf("Expected the reads to be cancelled, got %v", err)
+	}
+}
+
+// truncateTimes truncates the modification times of the files of dir to
+// the second, which newer tar writers round them to rather than truncate.
+func truncateTimes(t *testing.T, dir string) {
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ts := syscall.NsecToTimespec(fi.ModTime().Truncate(time.Second).UnixNano())
+		return system.LUtimesNano(path, []syscall.Timespec{ts, ts})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewTarSumForPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-tarsum-path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(dir+"/sub/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"file":         "hello",
+		"empty":        "",
+		"sub/file":     "world",
+		"sub/dir/file": "!",
+		"sub/ignored":  "ignored",
+	} {
+		if err := ioutil.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("file", dir+"/sub/symlink"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(dir+"/file", dir+"/sub/dir/hardlink"); err != nil {
+		t.Fatal(err)
+	}
+	truncateTimes(t, dir)
+
+	for _, options := range []*archive.TarOptions{
+		{},
+		{ExcludePatterns: []string{"sub/ignored"}},
+		{IncludeFiles: []string{"sub"}},
+	} {
+		for _, v := range []Version{Version0, Version1, VersionMeta} {
+			ts, err := NewTarSumForPath(dir, v, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := archive.TarWithOptions(dir, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sums, err := SumVersions(r, []Version{v}, nil, []byte("extra"))
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum := ts.Sum([]byte("extra")); sum != sums[v] {
+				t.Fatalf("%v %v: expected the sum of the tar %s, got %s", options, v, sums[v], sum)
+			}
+		}
+	}
+
+	ts, err := NewTarSumForPath(dir, Version1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.GetSums()) != 9 {
+		t.Fatalf("Expected the sums of 9 files, got %d", len(ts.GetSums()))
+	}
+	if ts.GetSums().GetFile("sub/dir/hardlink") == nil {
+		t.Fatal("Expected the sum of the hard link")
+	}
+}
+
+func TestNewTarSumForPathExcludePatterns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-tarsum-path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, d := range []string{".git", "node_modules/lib"} {
+		if err := os.MkdirAll(dir+"/"+d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("Dockerfile", "FROM busybox")
+	write(".git/HEAD", "ref: refs/heads/master")
+	write("node_modules/lib/index.js", "")
+	write("node_modules/lib/package.json", "{}")
+
+	options := &archive.TarOptions{ExcludePatterns: []string{".git", "node_modules", "!node_modules/lib/package.json"}}
+	sum := func() string {
+		truncateTimes(t, dir)
+		ts, err := NewTarSumForPath(dir, Version1, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := archive.TarWithOptions(dir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		sums, err := SumVersions(r, []Version{Version1}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sums[Version1] != ts.Sum(nil) {
+			t.Fatalf("Expected the sum of the tar %s, got %s", sums[Version1], ts.Sum(nil))
+		}
+		if len(ts.GetSums()) != 2 || ts.GetSums().GetFile("node_modules/lib/package.json") == nil {
+			t.Fatalf("Expected the sums of the Dockerfile and of the excepted file, got %v", ts.GetSums())
+		}
+		return ts.Sum(nil)
+	}
+
+	expected := sum()
+	// the excluded files don't change the sum
+	write(".git/HEAD", "ref: refs/heads/other")
+	write("node_modules/lib/index.js", "changed")
+	if s := sum(); s != expected {
+		t.Fatalf("Expected the sum %s not to change, got %s", expected, s)
+	}
+	// the excepted ones do
+	write("node_modules/lib/package.json", "{\"name\": \"lib\"}")
+	if s := sum(); s == expected {
+		t.Fatal("Expected the sum to change with the excepted file")
+	}
+
+	if _, err := NewTarSumForPath(dir, Version1, &archive.TarOptions{ExcludePatterns: []string{"!"}}); err == nil {
+		t.Fatal("Expected an error for an invalid pattern")
+	}
+}
+
+func TestTarSumPAXHeaders(t *testing.T) {
+	jsonBytes, err := ioutil.ReadFile("testdata/pax/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh, err := os.Open("testdata/pax/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+
+	ts, err := NewTarSum(fh, true, VersionDev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	expected := "tarsum.dev+sha256:2dcc7bf602d3b6e6b0ac4747e4f2e64e8db599feb53909775f325e022f321e89"
+	if sum := ts.Sum(jsonBytes); sum != expected {
+		t.Fatalf("Expected the sum %s, got %s", expected, sum)
+	}
+	// the name of the file is the one of its PAX header
+	longName := "dir/" + strings.Repeat("a", 150)
+	if ts.GetSums().GetFile(longName) == nil {
+		t.Fatalf("Expected the sum of %s, got %v", longName, ts.GetSums())
+	}
+
+	// the values of the PAX headers are summed by VersionDev, the
+	// sub-second mtime not by Version1
+	fh.Seek(0, 0)
+	sums, err := SumVersions(fh, []Version{Version1, VersionDev}, nil, jsonBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sums[VersionDev] != expected {
+		t.Fatalf("Expected the sum %s, got %s", expected, sums[VersionDev])
+	}
+	if strings.TrimPrefix(sums[Version1], "tarsum.v1") == strings.TrimPrefix(expected, "tarsum.dev") {
+		t.Fatal("Expected Version1 not to sum the mtimes")
+	}
+}
+
+func TestTarSumGNUSparse(t *testing.T) {
+	// the same sparse file, archived in the sparse formats of GNU tar, and
+	// as a regular file
+	sumsOf := func(filename string) map[Version]string {
+		fh, err := os.Open("testdata/sparse/" + filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fh.Close()
+		var r io.Reader = fh
+		if strings.HasSuffix(filename, ".gz") {
+			if r, err = gzip.NewReader(fh); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sums, err := SumVersions(r, []Version{Version2, VersionDev}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sums
+	}
+
+	expected := sumsOf("regular.tar.gz")
+	for _, filename := range []string{"gnu-sparse.tar", "pax-sparse-0.0.tar", "pax-sparse-0.1.tar", "pax-sparse-1.0.tar"} {
+		if sums := sumsOf(filename); sums[VersionDev] != expected[VersionDev] {
+			t.Fatalf("%s: expected the sum %s of the regular file, got %s", filename, expected[VersionDev], sums[VersionDev])
+		}
+	}
+	// Version2 sums the typeflag of the old GNU sparse files
+	if sums := sumsOf("gnu-sparse.tar"); sums[Version2] == expected[Version2] {
+		t.Fatal("Expected the Version2 sum of the GNU sparse file to be its own")
+	}
+}
+
+func TestTHashRegistry(t *testing.T) {
+	if _, err := GetTHash("sha384"); err != nil {
+		RegisterTHash("sha384", sha512.New384)
+	}
+	tHash, err := GetTHash("sha384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tHash.Name() != "sha384" || tHash.Hash().Size() != sha512.Size384 {
+		t.Fatalf("Expected the sha384 hash, got %s", tHash.Name())
+	}
+	if _, err := GetTHash("md5"); err == nil {
+		t.Fatal("Expected md5 not to be registered")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Expected registering sha256 again to panic")
+			}
+		}()
+		RegisterTHash("sha256", sha256.New)
+	}()
+
+	tarBytes, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := NewTarSumHash(bytes.NewReader(tarBytes), true, VersionDev, sha384Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	sum := ts.Sum(nil)
+	if _, _, _, err := ParseTarSum(sum); err != nil {
+		t.Fatalf("Expected the tarsum %s of a registered hash to parse, got %v", sum, err)
+	}
+
+	// the TarSum of a tarsum is the one of its version and hash
+	ts, err = NewTarSumForLabel(bytes.NewReader(tarBytes), true, sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Version() != VersionDev || ts.Hash().Name() != "sha384" {
+		t.Fatalf("Expected a %s+sha384 TarSum, got %s+%s", VersionDev, ts.Version(), ts.Hash().Name())
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	if actual := ts.Sum(nil); actual != sum {
+		t.Fatalf("Expected the tarsum %s, got %s", sum, actual)
+	}
+
+	v, err := NewVerifiedTarSum(bytes.NewReader(tarBytes), sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, v); err != nil {
+		t.Fatalf("Expected the tar to be verified, got %v", err)
+	}
+
+	for _, label := range []string{"tarsum.v1+md5", "tarsum.v1+md5:1234", "tarsum.v9+sha256", "sha256"} {
+		if _, err := NewTarSumForLabel(bytes.NewReader(tarBytes), true, label); err == nil {
+			t.Fatalf("Expected the label %q to be rejected", label)
+		}
+	}
+}
+
+func TestTHashGoldenSums(t *testing.T) {
+	for _, layer := range []struct {
+		dir    string
+		tarsum []string
+	}{
+		{
+			dir: "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457",
+			tarsum: []string{
+				"tarsum.dev+sha3-256:f9ccc78f532558c66e2389034efc3182a7267ea3b568f468a55767b97e1ff7d8",
+				"tarsum.dev+sha3-512:c56b5462ae4b8e6b0d7abefd65baa9c411262b95803e36f23e988ad17f5703cb1df38b4437363a49928e01e2c3000cf42694e49cb15e9e72315b45dd7f8c4896",
+				"tarsum.dev+blake2b-256:4a542753e140776f3db5a680d958f06b36472addeea0812fc738c5a1a4fa7fbd",
+				"tarsum.dev+blake2b-512:5820fe552c56c6cc27dc553cdaa2b1bb0b365e9e87a5b7f8be754237d65a54d8e8159e8554d0f40f5ee08ea4d19f7fc2bbca872d811bff98e9ceabde9ae0e9d2",
+			},
+		},
+		{
+			dir: "testdata/xattr",
+			tarsum: []string{
+				"tarsum.dev+sha3-256:722c3f906182e69ebbb510e9f40b7490f4ca4fee0058f20d85a980e44b24c7f3",
+				"tarsum.dev+sha3-512:fb9734a97561f1e8ac3e457b5b8875301591fe8a1459dc6764049162504f4886df950d55bf4d835adbec6791a9a9f4622802d9c7a02244b9e314b5c657098542",
+				"tarsum.dev+blake2b-256:cdf15de08aa8c2f57baafc47a96fedd4a6c448d52ec31e5ecac725bc4e0309d0",
+				"tarsum.dev+blake2b-512:40c75a4b047996b3b5c2b8eba1b3ab92f8e2a31107eb6eb6435a76bd2c2da1a5eb87cf505f1aa7626fc94998218d40567c8e8964bc02cf665de835ad4450eb9a",
+			},
+		},
+	} {
+		jsonBytes, err := ioutil.ReadFile(layer.dir + "/json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tarBytes, err := ioutil.ReadFile(layer.dir + "/layer.tar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range layer.tarsum {
+			info, err := ParseTarSumInfo(expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tHash, err := GetTHash(info.HashName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sums, err := SumVersions(bytes.NewReader(tarBytes), []Version{VersionDev}, tHash, jsonBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sums[VersionDev] != expected {
+				t.Fatalf("%s: expected the sum %s, got %s", layer.dir, expected, sums[VersionDev])
+			}
+		}
+	}
+}
+
+func TestHMACTHash(t *testing.T) {
+	key := []byte("secret")
+	tHash := NewHMACTHash(DefaultTHash, key)
+	if tHash.Name() != "hmac-sha256" {
+		t.Fatalf("Expected the hash hmac-sha256, got %s", tHash.Name())
+	}
+
+	layer := "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457"
+	jsonBytes, err := ioutil.ReadFile(layer + "/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	layerBytes, err := ioutil.ReadFile(layer + "/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []struct {
+		tHash  THash
+		tarsum string
+	}{
+		{tHash, "tarsum.dev+hmac-sha256:0d2170f8fc353535f6f19e3d8e822446a70eab8daeb01d1f8773e06ba6e02466"},
+		{NewHMACTHash(sha512Hash, key), "tarsum.dev+hmac-sha512:eaf48171a478be172a88a7974307e128b6f89ce1dded4eca2f4d9537e41fe698a090264794e3cc85d0fded1952f557df14d144e84e8fc31fa829eb52d60e7b29"},
+	} {
+		sums, err := SumVersions(bytes.NewReader(layerBytes), []Version{VersionDev}, expected.tHash, jsonBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sums[VersionDev] != expected.tarsum {
+			t.Fatalf("Expected the sum %s, got %s", expected.tarsum, sums[VersionDev])
+		}
+		if _, _, _, err := ParseTarSum(expected.tarsum); err != nil {
+			t.Fatalf("Expected the tarsum %s of an HMAC to parse, got %v", expected.tarsum, err)
+		}
+	}
+
+	tarBytes, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := NewTarSumHash(bytes.NewReader(tarBytes), true, Version1, tHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	sum := ts.Sum(nil)
+
+	v, err := NewVerifiedTarSumHMAC(bytes.NewReader(tarBytes), sum, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, v); err != nil {
+		t.Fatalf("Expected the tar to be verified, got %v", err)
+	}
+	// the sum of another key doesn't match
+	v, err = NewVerifiedTarSumHMAC(bytes.NewReader(tarBytes), sum, []byte("guess"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, v); err == nil {
+		t.Fatal("Expected the sum of another key not to match")
+	}
+
+	// the tarsums of HMACs can't be verified without their key
+	if _, err := NewVerifiedTarSum(bytes.NewReader(tarBytes), sum); err == nil {
+		t.Fatalf("Expected the tarsum %s not to be verified without its key", sum)
+	}
+	if _, err := NewTarSumForLabel(bytes.NewReader(tarBytes), true, "tarsum.v1+hmac-sha256"); err == nil {
+		t.Fatal("Expected the label of an HMAC to be rejected")
+	}
+	plain := "tarsum.v1+sha256:" + strings.SplitN(sum, ":", 2)[1]
+	if _, err := NewVerifiedTarSumHMAC(bytes.NewReader(tarBytes), plain, key); err == nil {
+		t.Fatalf("Expected the tarsum %s not to be the one of an HMAC", plain)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Expected registering a hash named like an HMAC to panic")
+			}
+		}()
+		RegisterTHash("hmac-md5", md5.New)
+	}()
+}
+
+func TestHash(t *testing.T) {
+	layer := "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457"
+	jsonBytes, err := ioutil.ReadFile(layer + "/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarBytes, err := ioutil.ReadFile(layer + "/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "tarsum.dev+sha256:d969ef0f25a362780cd3b52aafab134d6910f0b8d5dbc1d2b036dbc6ce6260fa"
+
+	h, err := NewHash(VersionDev, nil, jsonBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var _ hash.Hash = h
+	if h.Size() != sha256.Size || h.BlockSize() != 512 {
+		t.Fatalf("Expected the sizes of sha256 and tar blocks, got %d and %d", h.Size(), h.BlockSize())
+	}
+
+	// the tar is written in odd chunks along with its sha256
+	digest := sha256.New()
+	w := io.MultiWriter(h, digest)
+	for rest := tarBytes; len(rest) > 0; {
+		n := 333
+		if n > len(rest) {
+			n = len(rest)
+		}
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatal(err)
+		}
+		rest = rest[n:]
+		// the sum is the one of the files written whole
+		partial := h.GetSums()
+		if sum := h.TarSum(); sum != sumFileInfoSums(partial, jsonBytes, VersionDev, DefaultTHash) {
+			t.Fatalf("Expected the sum of %d files, got %s", len(partial), sum)
+		}
+	}
+	if sum := h.TarSum(); sum != expected {
+		t.Fatalf("Expected the sum %s, got %s", expected, sum)
+	}
+	if sum := "tarsum.dev+sha256:" + hex.EncodeToString(h.Sum(nil)); sum != expected {
+		t.Fatalf("Expected the digest of %s, got %s", expected, sum)
+	}
+	if prefix := []byte("prefix"); !bytes.HasPrefix(h.Sum(prefix), prefix) {
+		t.Fatal("Expected Sum to append the digest")
+	}
+	if sha := sha256.Sum256(tarBytes); !bytes.Equal(digest.Sum(nil), sha[:]) {
+		t.Fatal("Expected the other writer to be written the whole tar")
+	}
+
+	// the bytes written after the end of the tar are ignored
+	if _, err := h.Write(make([]byte, 10240)); err != nil {
+		t.Fatal(err)
+	}
+	if sum := h.TarSum(); sum != expected {
+		t.Fatalf("Expected the sum %s after the end of the tar, got %s", expected, sum)
+	}
+
+	// a reset Hash sums another tar
+	h.Reset()
+	if len(h.GetSums()) != 0 {
+		t.Fatal("Expected a reset Hash not to have any sums")
+	}
+	// reset while a tar is parsed
+	if _, err := h.Write(tarBytes[:1000]); err != nil {
+		t.Fatal(err)
+	}
+	h.Reset()
+	if _, err := h.Write(tarBytes); err != nil {
+		t.Fatal(err)
+	}
+	if sum := h.TarSum(); sum != expected {
+		t.Fatalf("Expected the sum %s after a reset, got %s", expected, sum)
+	}
+
+	// like the ones of other hashes, Write doesn't fail, the error of an
+	// invalid tar is kept
+	h.Reset()
+	if n, err := h.Write(bytes.Repeat([]byte("not a tar"), 100)); err != nil || n != 900 {
+		t.Fatalf("Expected the invalid tar to be written, got %d, %v", n, err)
+	}
+	if h.Err() == nil {
+		t.Fatal("Expected the error of the invalid tar")
+	}
+	h.Reset()
+	if h.Err() != nil {
+		t.Fatalf("Expected a reset Hash not to have an error, got %v", h.Err())
+	}
+}
+
+func TestHashSparse(t *testing.T) {
+	for _, filename := range []string{"gnu-sparse.tar", "pax-sparse-0.0.tar", "pax-sparse-0.1.tar", "pax-sparse-1.0.tar"} {
+		tarBytes, err := ioutil.ReadFile("testdata/sparse/" + filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sums, err := SumVersions(bytes.NewReader(tarBytes), []Version{VersionDev}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := NewHash(VersionDev, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the sparse files are buffered whole
+		for p := tarBytes; len(p) > 0; {
+			n := 100
+			if n > len(p) {
+				n = len(p)
+			}
+			h.Write(p[:n])
+			p = p[n:]
+		}
+		if h.Err() != nil {
+			t.Fatalf("%s: %v", filename, h.Err())
+		}
+		if sum := h.TarSum(); sum != sums[VersionDev] {
+			t.Fatalf("%s: expected the sum %s, got %s", filename, sums[VersionDev], sum)
+		}
+	}
+}
+
+func TestHashDropped(t *testing.T) {
+	tarBytes, err := ioutil.ReadFile("testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the Hashes of partial tars are dropped without leaving anything
+	// running
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		h, err := NewHash(VersionDev, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Write(tarBytes[:1000+i])
+		tw, err := NewTarSumWriter(ioutil.Discard, Version1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(tarBytes[:1000+i])
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("Expected no goroutine to be left, %d were", after-before)
+	}
+}
+
+func TestTarSumWriter(t *testing.T) {
+	// the tar is summed as a tar.Writer produces it
+	buf := bytes.NewBuffer(nil)
+	tw, err := NewTarSumWriter(buf, Version1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarW := tar.NewWriter(tw)
+	for i, content := range []string{"hello", strings.Repeat("x", 10000), ""} {
+		hdr := &tar.Header{Name: fmt.Sprintf("file-%d", i), Mode: 0644, Size: int64(len(content)), ModTime: time.Unix(1400000000, 0), Typeflag: tar.TypeReg}
+		if err := tarW.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarW.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarW.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := SumVersions(bytes.NewReader(buf.Bytes()), []Version{Version1}, nil, []byte("extra"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := tw.Sum([]byte("extra")); sum != sums[Version1] {
+		t.Fatalf("Expected the sum %s of the tar written, got %s", sums[Version1], sum)
+	}
+	if len(tw.GetSums()) != 3 || tw.Version() != Version1 || tw.Hash().Name() != "sha256" {
+		t.Fatalf("Unexpected sums %v of version %s and hash %s", tw.GetSums(), tw.Version(), tw.Hash().Name())
+	}
+
+	layer := "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457"
+	jsonBytes, err := ioutil.ReadFile(layer + "/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh, err := os.Open(layer + "/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	tw, err = NewTarSumWriter(ioutil.Discard, VersionDev, sha512Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(tw, fh); err != nil {
+		t.Fatal(err)
+	}
+	fh.Seek(0, 0)
+	sums, err = SumVersions(fh, []Version{VersionDev}, sha512Hash, jsonBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := tw.Sum(jsonBytes); sum != sums[VersionDev] {
+		t.Fatalf("Expected the sum %s, got %s", sums[VersionDev], sum)
+	}
+
+	if _, err := NewTarSumWriter(ioutil.Discard, Version(-1), nil); err == nil {
+		t.Fatal("Expected an unknown version to be rejected")
+	}
+}
+
+func TestTarSumWriteTo(t *testing.T) {
+	layer := "testdata/46af0962ab5afeb5ce6740d4d91652e69206fc991fd5328c1a94d364ad00e457"
+	jsonBytes, err := ioutil.ReadFile(layer + "/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarBytes, err := ioutil.ReadFile(layer + "/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "tarsum.dev+sha256:d969ef0f25a362780cd3b52aafab134d6910f0b8d5dbc1d2b036dbc6ce6260fa"
+
+	for _, dc := range []bool{true, false} {
+		ts, err := NewTarSum(bytes.NewReader(tarBytes), dc, VersionDev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := bytes.NewBuffer(nil)
+		n, err := ts.(io.WriterTo).WriteTo(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(buf.Len()) {
+			t.Fatalf("Expected %d bytes to be written, got %d", buf.Len(), n)
+		}
+		if sum := ts.Sum(jsonBytes); sum != expected {
+			t.Fatalf("Expected the sum %s, got %s", expected, sum)
+		}
+		if len(ts.GetSums()) != 9 {
+			t.Fatalf("Expected the sums of 9 files, got %d", len(ts.GetSums()))
+		}
+		// nothing is left to read
+		if m, err := ts.Read(make([]byte, 10)); m != 0 || err != io.EOF {
+			t.Fatalf("Expected EOF, got %d %v", m, err)
+		}
+
+		// the tar written has the same sum
+		var written io.Reader = buf
+		if !dc {
+			if written, err = gzip.NewReader(buf); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sums, err := SumVersions(written, []Version{VersionDev}, nil, jsonBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sums[VersionDev] != expected {
+			t.Fatalf("Expected the tar written to have the sum %s, got %s", expected, sums[VersionDev])
+		}
+	}
+
+	// the tar written is the one read back
+	sizedBytes, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := NewTarSum(bytes.NewReader(sizedBytes), true, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readBack, err := ioutil.ReadAll(struct{ io.Reader }{ts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := ts.Sum(nil)
+	ts, err = NewTarSum(bytes.NewReader(sizedBytes), true, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buf, ts); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), readBack) {
+		t.Fatal("Expected the tar written to be the one read back")
+	}
+	if ts.Sum(nil) != sum {
+		t.Fatalf("Expected the sum %s, got %s", sum, ts.Sum(nil))
+	}
+}
+
+// sizedTarBytes returns the bytes of a sizedTar, and their gzip
+// compressed bytes.
+func sizedTarBytes(t testing.TB, opts sizedOptions) ([]byte, []byte) {
+	tarBytes, err := ioutil.ReadAll(sizedTar(opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gzW := gzip.NewWriter(&buf)
+	gzW.Write(tarBytes)
+	gzW.Close()
+	return tarBytes, buf.Bytes()
+}
+
+func TestTarSumPooling(t *testing.T) {
+	// the sums of the TarSums reusing the buffers and gzip readers and
+	// writers of the ones before are the ones without pooling
+	for _, c := range []struct {
+		opts sizedOptions
+		read bool // read back with Read rather than WriteTo
+	}{
+		{sizedOptions{num: 10, size: 1000}, false},
+		{sizedOptions{num: 10}, true},
+	} {
+		tarBytes, gzBytes := sizedTarBytes(t, c.opts)
+		sums := map[bool][]string{}
+		for _, disabled := range []bool{true, false} {
+			for i := 0; i < 3; i++ {
+				for _, dc := range []bool{true, false} {
+					var ts TarSum
+					var err error
+					if dc {
+						// the compressed tar is decompressed
+						ts, err = NewTarSumAutoDetect(bytes.NewReader(gzBytes), Version1)
+					} else {
+						// the tar is read back compressed
+						ts, err = NewTarSum(bytes.NewReader(tarBytes), false, Version1)
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+					if err := SetPooling(ts, !disabled); err != nil {
+						t.Fatal(err)
+					}
+					var r io.Reader = ts
+					if c.read {
+						r = struct{ io.Reader }{ts}
+					}
+					var readBack bytes.Buffer
+					if _, err := io.Copy(&readBack, r); err != nil {
+						t.Fatal(err)
+					}
+					if !dc {
+						gzR, err := gzip.NewReader(&readBack)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if _, err := io.Copy(ioutil.Discard, gzR); err != nil {
+							t.Fatal(err)
+						}
+					}
+					sums[disabled] = append(sums[disabled], ts.Sum(nil))
+				}
+			}
+		}
+		for i, sum := range sums[false] {
+			if sum != sums[true][i] {
+				t.Fatalf("Expected the sum %s with and without pooling, got %s", sums[true][i], sum)
+			}
+		}
+	}
+}
+
+func BenchmarkSmallGzipLayers(b *testing.B) {
+	benchmarkSmallGzipLayers(b, false)
+}
+
+func BenchmarkSmallGzipLayersNoPooling(b *testing.B) {
+	benchmarkSmallGzipLayers(b, true)
+}
+
+// benchmarkSmallGzipLayers benchmarks the allocations of summing small
+// layers, compressed or read back compressed.
+func benchmarkSmallGzipLayers(b *testing.B, disabled bool) {
+	tarBytes, gzBytes := sizedTarBytes(b, sizedOptions{num: 10, size: 1000})
+
+	b.SetBytes(int64(len(tarBytes)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ts, err := NewTarSumAutoDetect(bytes.NewReader(gzBytes), Version1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := SetPooling(ts, !disabled); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+			b.Fatal(err)
+		}
+		ts.Sum(nil)
+
+		ts, err = NewTarSum(bytes.NewReader(tarBytes), false, Version1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := SetPooling(ts, !disabled); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+			b.Fatal(err)
+		}
+		ts.Sum(nil)
+	}
+}
+
+func TestTarSumProgress(t *testing.T) {
+	type progress struct {
+		name                       string
+		bytesProcessed, totalBytes int64
+	}
+	recorder := func(calls *[]progress) ProgressFunc {
+		return func(hdr *tar.Header, bytesProcessed, totalBytes int64) {
+			*calls = append(*calls, progress{hdr.Name, bytesProcessed, totalBytes})
+		}
+	}
+	// check checks that each of the entries of the tar is reported, and
+	// then its whole content
+	check := func(calls []progress, tarBytes []byte) {
+		tarR := tar.NewReader(bytes.NewReader(tarBytes))
+		total := int64(0)
+		for {
+			hdr, err := tarR.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(calls) == 0 || calls[0] != (progress{hdr.Name, 0, total}) {
+				t.Fatalf("Expected the entry %s to be reported after %d bytes, got %v", hdr.Name, total, calls)
+			}
+			calls = calls[1:]
+			for processed := int64(0); processed < hdr.Size; {
+				if len(calls) == 0 || calls[0].name != hdr.Name || calls[0].bytesProcessed <= processed || calls[0].totalBytes != total+calls[0].bytesProcessed {
+					t.Fatalf("Expected the progress of the content of %s after %d bytes, got %v", hdr.Name, processed, calls)
+				}
+				processed = calls[0].bytesProcessed
+				calls = calls[1:]
+			}
+			total += hdr.Size
+		}
+		if len(calls) != 0 {
+			t.Fatalf("Unexpected progress %v", calls)
+		}
+	}
+
+	// the tar is written to the destination
+	tarBytes, gzBytes := sizedTarBytes(t, sizedOptions{num: 10, size: 100000})
+	ts, err := NewTarSumAutoDetect(bytes.NewReader(gzBytes), Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []progress
+	if err := SetProgress(ts, recorder(&calls)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	check(calls, tarBytes)
+	if err := SetProgress(ts, recorder(&calls)); err == nil {
+		t.Fatal("Expected setting the progress of a TarSum read to fail")
+	}
+
+	// the tar is read back, to be verified
+	tarBytes, _ = sizedTarBytes(t, sizedOptions{num: 10})
+	ts, err = NewTarSum(bytes.NewReader(tarBytes), true, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifiedTarSum(bytes.NewReader(tarBytes), ts.Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls = nil
+	if err := SetProgress(v, recorder(&calls)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(v); err != nil {
+		t.Fatal(err)
+	}
+	check(calls, tarBytes)
+
+	if err := SetProgress(&VerifiedTarSum{TarSum: v}, recorder(&calls)); err == nil {
+		t.Fatal("Expected setting the progress of another TarSum to fail")
+	}
+}
+
+func TestTarSumStats(t *testing.T) {
+	tarBytes, gzBytes := sizedTarBytes(t, sizedOptions{num: 10, size: 100000})
+	ts, err := NewTarSumAutoDetect(bytes.NewReader(gzBytes), Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := GetStats(ts); err != nil || stats != (Stats{}) {
+		t.Fatalf("Expected no statistics before the tar is read, got %+v, %v", stats, err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := GetStats(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 10 {
+		t.Fatalf("Expected 10 entries, got %d", stats.Entries)
+	}
+	if stats.UncompressedBytes != int64(len(tarBytes)) || stats.CompressedBytes != int64(len(gzBytes)) {
+		t.Fatalf("Expected %d bytes compressed to %d, got %d and %d", len(tarBytes), len(gzBytes), stats.UncompressedBytes, stats.CompressedBytes)
+	}
+	if ratio := float64(len(tarBytes)) / float64(len(gzBytes)); stats.CompressionRatio() != ratio {
+		t.Fatalf("Expected the compression ratio %f, got %f", ratio, stats.CompressionRatio())
+	}
+	if stats.ReadTime <= 0 || stats.HashTime <= 0 {
+		t.Fatalf("Expected the time spent reading and hashing, got %+v", stats)
+	}
+
+	// the tar read back of a VerifiedTarSum isn't compressed
+	tarBytes, _ = sizedTarBytes(t, sizedOptions{num: 10})
+	v, err := NewVerifiedTarSum(bytes.NewReader(tarBytes), "tarsum.v1+sha256:"+hex.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(v)
+	if stats, err = GetStats(v); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 10 || stats.UncompressedBytes != int64(len(tarBytes)) || stats.CompressionRatio() != 1 {
+		t.Fatalf("Expected 10 entries of %d uncompressed bytes, got %+v", len(tarBytes), stats)
+	}
+	if s, ok := TarSum(v).(StatsTarSum); !ok || s.Stats() != stats {
+		t.Fatalf("Expected the VerifiedTarSum to be a StatsTarSum with %+v", stats)
+	}
+	if _, err := GetStats(struct{ TarSum }{v}); err == nil {
+		t.Fatal("Expected reading the statistics of another TarSum to fail")
+	}
+}
+
+// namesTar returns a tar of empty files, and of directories for the names
+// ending with a slash.
+func namesTar(t testing.TB, names ...string) []byte {
+	var buf bytes.Buffer
+	tarW := tar.NewWriter(&buf)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg}
+		if strings.HasSuffix(name, "/") {
+			hdr.Typeflag = tar.TypeDir
+		}
+		if err := tarW.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tarW.Close()
+	return buf.Bytes()
+}
+
+// linksTar returns a tar of an empty file a and of the link name to target
+// of typeflag.
+func linksTar(t testing.TB, typeflag byte, name, target string) []byte {
+	var buf bytes.Buffer
+	tarW := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "a", Mode: 0644, Typeflag: tar.TypeReg},
+		{Name: "dir/", Mode: 0755, Typeflag: tar.TypeDir},
+		{Name: name, Mode: 0777, Typeflag: typeflag, Linkname: target},
+	} {
+		if err := tarW.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tarW.Close()
+	return buf.Bytes()
+}
+
+func TestTarSumStrictEntries(t *testing.T) {
+	collision, err := ioutil.ReadFile("testdata/collision/collision-0.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		tarBytes []byte
+		rejected string
+		empty    bool // the files are empty, the tar can be read back
+	}{
+		{collision, "file", false},
+		{namesTar(t, "a", "dir/", "dir/b", "./dir/b"), "./dir/b", true},
+		{namesTar(t, "dir/", "dir/../../etc/passwd"), "dir/../../etc/passwd", true},
+		{namesTar(t, "/etc/passwd"), "/etc/passwd", true},
+		{namesTar(t, "a", "dir/", "dir/b", "dir/..b"), "", true},
+		{linksTar(t, tar.TypeLink, "dir/link", "/etc/passwd"), "dir/link", true},
+		{linksTar(t, tar.TypeLink, "dir/link", "dir/../../etc/passwd"), "dir/link", true},
+		{linksTar(t, tar.TypeLink, "dir/link", "a"), "", true},
+		{linksTar(t, tar.TypeSymlink, "dir/link", "../../etc/passwd"), "dir/link", true},
+		{linksTar(t, tar.TypeSymlink, "link", ".."), "link", true},
+		{linksTar(t, tar.TypeSymlink, "dir/link", "../a"), "", true},
+		{linksTar(t, tar.TypeSymlink, "dir/link", "/etc/passwd"), "", true},
+	} {
+		for _, read := range []bool{true, false} {
+			ts, err := NewTarSum(bytes.NewReader(c.tarBytes), true, Version1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := SetStrictEntries(ts); err != nil {
+				t.Fatal(err)
+			}
+			var r io.Reader = ts
+			if read {
+				r = struct{ io.Reader }{ts}
+			}
+			_, err = io.Copy(ioutil.Discard, r)
+			if c.rejected == "" {
+				if err != nil {
+					t.Fatalf("Expected the tar to be accepted, got %v", err)
+				}
+				continue
+			}
+			if entryErr, ok := err.(EntryError); !ok || entryErr.Name != c.rejected {
+				t.Fatalf("Expected the entry %s to be rejected, got %v", c.rejected, err)
+			}
+		}
+
+		// the tars are summed unless the entries are strict
+		if c.empty {
+			ts, err := NewTarSum(bytes.NewReader(c.tarBytes), true, Version1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestTarSumLimits(t *testing.T) {
+	entries := namesTar(t, "a", "b", "c")
+	longName := namesTar(t, "a", strings.Repeat("long/", 800)+"b")
+	_, gzBomb := sizedTarBytes(t, sizedOptions{num: 1, size: 1024 * 1024})
+
+	for _, c := range []struct {
+		tarBytes []byte
+		limits   Limits
+		exceeded string
+		empty    bool // the files are empty, the tar can be read back
+	}{
+		{entries, Limits{MaxEntries: 3}, "", true},
+		{entries, Limits{MaxEntries: 2}, "entries", true},
+		{entries, Limits{MaxBytes: int64(len(entries))}, "", true},
+		{entries, Limits{MaxBytes: int64(len(entries)) - 1}, "bytes", true},
+		{longName, Limits{MaxHeaderBytes: 2 * blockSize}, "header bytes", true},
+		{longName, Limits{MaxHeaderBytes: 16 * blockSize}, "", true},
+		{gzBomb, Limits{MaxBytes: 64 * 1024}, "bytes", false},
+		{gzBomb, Limits{MaxBytes: 2 * 1024 * 1024, MaxHeaderBytes: 2 * blockSize}, "", false},
+	} {
+		for _, read := range []bool{true, false} {
+			if read && !c.empty {
+				continue
+			}
+			ts, err := NewTarSumAutoDetect(bytes.NewReader(c.tarBytes), Version1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := SetLimits(ts, c.limits); err != nil {
+				t.Fatal(err)
+			}
+			var r io.Reader = ts
+			if read {
+				r = struct{ io.Reader }{ts}
+			}
+			_, err = io.Copy(ioutil.Discard, r)
+			if c.exceeded == "" {
+				if err != nil {
+					t.Fatalf("Expected the limits %+v to be kept, got %v", c.limits, err)
+				}
+				continue
+			}
+			if limitErr, ok := err.(LimitError); !ok || limitErr.Limit != c.exceeded {
+				t.Fatalf("Expected the limit of the %s of %+v to be exceeded, got %v", c.exceeded, c.limits, err)
+			}
+			if stats, _ := GetStats(ts); c.limits.MaxBytes > 0 && stats.UncompressedBytes > c.limits.MaxBytes+1 {
+				t.Fatalf("Expected the tar to be read up to its limit, %d bytes were read", stats.UncompressedBytes)
+			}
+		}
+	}
+
+	ts, err := NewTarSum(bytes.NewReader(entries), true, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, limits := range []Limits{{MaxEntries: -1}, {MaxHeaderBytes: blockSize}} {
+		if err := SetLimits(ts, limits); err == nil {
+			t.Fatalf("Expected the limits %+v to be invalid", limits)
+		}
+	}
+}
+
+func TestTarSumIgnoreOwners(t *testing.T) {
+	ownedTar := func(uid int, uname string) []byte {
+		var buf bytes.Buffer
+		tarW := tar.NewWriter(&buf)
+		for _, name := range []string{"dir/", "dir/file"} {
+			hdr := &tar.Header{Name: name, Mode: 0644, Uid: uid, Gid: uid, Uname: uname, Gname: uname, Typeflag: tar.TypeReg}
+			if strings.HasSuffix(name, "/") {
+				hdr.Typeflag = tar.TypeDir
+			}
+			if err := tarW.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tarW.Close()
+		return buf.Bytes()
+	}
+	rootTar, userTar := ownedTar(0, ""), ownedTar(100000, "builder")
+
+	for _, v := range []Version{Version0, Version1, Version2, VersionDev} {
+		sum := func(tarBytes []byte, ignoreOwners bool) string {
+			ts, err := NewTarSum(bytes.NewReader(tarBytes), true, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ignoreOwners {
+				if err := SetIgnoreOwners(ts); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+				t.Fatal(err)
+			}
+			return ts.Sum(nil)
+		}
+		rootSum := sum(rootTar, false)
+		if userSum := sum(userTar, true); userSum != rootSum {
+			t.Fatalf("%s: Expected the files of the user to be summed like the ones of root, %s, got %s", v, rootSum, userSum)
+		}
+		if sum(userTar, false) == rootSum {
+			t.Fatalf("%s: Expected the owners of the files to be summed", v)
+		}
+
+		ts, err := NewVerifiedTarSum(bytes.NewReader(userTar), rootSum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := SetIgnoreOwners(ts); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+			t.Fatalf("%s: Expected %s to be verified ignoring the owners, got %v", v, rootSum, err)
+		}
+	}
+}
+
+func TestTarSumCompressedSum(t *testing.T) {
+	tarBytes, gzBytes := sizedTarBytes(t, sizedOptions{num: 10})
+	// the blocks padding the tar after its end are hashed too
+	padded := append(append([]byte(nil), tarBytes...), make([]byte, 8*blockSize)...)
+	digest := func(tHash THash, blob []byte) string {
+		h := tHash.Hash()
+		h.Write(blob)
+		return tHash.Name() + ":" + hex.EncodeToString(h.Sum(nil))
+	}
+
+	cases := []struct {
+		blob  []byte
+		tHash THash
+	}{
+		{gzBytes, nil},
+		{padded, nil},
+		{gzBytes, sha512Hash},
+	}
+	// the blobs decompressed by commands are read by the goroutines feeding
+	// them, past the buffer of their first bytes and the end of the tar
+	randTar, _ := sizedTarBytes(t, sizedOptions{num: 4, size: 32 * 1024, isRand: true})
+	randTar = append(randTar, make([]byte, 64*blockSize)...)
+	for _, command := range []string{"xz", "zstd"} {
+		if _, err := exec.LookPath(command); err != nil {
+			continue
+		}
+		cmd := exec.Command(command, "-c", "-q")
+		cmd.Stdin = bytes.NewReader(randTar)
+		blob, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cases = append(cases, struct {
+			blob  []byte
+			tHash THash
+		}{blob, nil})
+	}
+
+	for _, c := range cases {
+		expected := digest(DefaultTHash, c.blob)
+		if c.tHash != nil {
+			expected = digest(c.tHash, c.blob)
+		}
+		for _, read := range []bool{true, false} {
+			ts, err := NewTarSumAutoDetect(bytes.NewReader(c.blob), Version1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := SetCompressedHash(ts, c.tHash); err != nil {
+				t.Fatal(err)
+			}
+			var r io.Reader = ts
+			if read {
+				r = struct{ io.Reader }{ts}
+			}
+			if _, err := io.Copy(ioutil.Discard, r); err != nil {
+				t.Fatal(err)
+			}
+			if sum := ts.(CompressedTarSum).CompressedSum(); sum != expected {
+				t.Fatalf("Expected the compressed sum %s, got %s", expected, sum)
+			}
+			if stats, _ := GetStats(ts); stats.CompressedBytes != int64(len(c.blob)) {
+				t.Fatalf("Expected the %d bytes of the blob to be read, got %d", len(c.blob), stats.CompressedBytes)
+			}
+		}
+	}
+
+	ts, err := NewTarSum(bytes.NewReader(tarBytes), true, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	if sum := CompressedSum(ts); sum != "" {
+		t.Fatalf("Expected no compressed sum, got %s", sum)
+	}
+
+	v, err := NewVerifiedTarSum(bytes.NewReader(tarBytes), "tarsum.v1+sha256:"+hex.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetCompressedHash(v, nil); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(v)
+	if sum, expected := CompressedSum(v), digest(DefaultTHash, tarBytes); sum != expected {
+		t.Fatalf("Expected the compressed sum %s of the VerifiedTarSum, got %s", expected, sum)
+	}
+	if sum := CompressedSum(struct{ TarSum }{v}); sum != "" {
+		t.Fatalf("Expected no compressed sum of another TarSum, got %s", sum)
+	}
+}
+
+func TestDigestVerifier(t *testing.T) {
+	// the Verifier of the digest package of docker/distribution
+	var _ interface {
+		io.Writer
+		Verified() bool
+	} = &DigestVerifier{}
+
+	layer, err := ioutil.ReadFile("testdata/mtime/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	touched, err := ioutil.ReadFile("testdata/mtime/touched.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1Sum := "tarsum.v1+sha256:e7d3ecba3b454f27bc9d188cae20c01642478644e9774c0f312e01e2425e918b"
+	v2Sum := "tarsum.v2+sha256:a33aa98fad5aea295e1a935e9a706e94c6002414caa37b5adcc988b6a20aafa7"
+
+	info, err := ParseTarSumInfo(v2Sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Algorithm() != "tarsum.v2+sha256" {
+		t.Fatalf("Expected the algorithm tarsum.v2+sha256, got %s", info.Algorithm())
+	}
+	h, err := NewHash(Version2, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write(layer)
+	if h.Digest() != v2Sum {
+		t.Fatalf("Expected the digest %s, got %s", v2Sum, h.Digest())
+	}
+
+	for _, c := range []struct {
+		tarBytes []byte
+		expected string
+		verified bool
+	}{
+		{layer, v1Sum, true},
+		{layer, v2Sum, true},
+		{touched, v1Sum, true},
+		{touched, v2Sum, false},
+		// the tar isn't whole
+		{layer[:2*blockSize], v2Sum, false},
+		{nil, v2Sum, false},
+	} {
+		v, err := NewDigestVerifier(c.expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// written in pieces, like by io.Copy
+		for p := c.tarBytes; len(p) > 0; {
+			n := 100
+			if n > len(p) {
+				n = len(p)
+			}
+			if _, err := v.Write(p[:n]); err != nil {
+				t.Fatal(err)
+			}
+			p = p[n:]
+		}
+		if v.Verified() != c.verified {
+			t.Fatalf("Expected the tar of %d bytes to be verified against %s: %v", len(c.tarBytes), c.expected, c.verified)
+		}
+	}
+
+	v, err := NewDigestVerifier(v2Sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Write(bytes.Repeat([]byte("x"), 2*blockSize)); err == nil {
+		t.Fatal("Expected an invalid tar to fail")
+	}
+	if v.Verified() {
+		t.Fatal("Expected an invalid tar not to be verified")
+	}
+
+	for _, expected := range []string{"sha256:" + strings.Repeat("0", 64), strings.Replace(v2Sum, "sha256", "hmac-sha256", 1)} {
+		if _, err := NewDigestVerifier(expected); err == nil {
+			t.Fatalf("Expected %s not to be verified", expected)
+		}
+	}
+}
+
+func TestTarSumManifest(t *testing.T) {
+	layer, err := ioutil.ReadFile("testdata/mtime/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	touched, err := ioutil.ReadFile("testdata/mtime/touched.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestOf := func(tarBytes []byte, v Version, read bool) []byte {
+		ts, err := NewTarSum(bytes.NewReader(tarBytes), true, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteManifest(ts, ioutil.Discard); err == nil {
+			t.Fatal("Expected the manifest of a TarSum not read not to be written")
+		}
+		var r io.Reader = ts
+		if read {
+			r = struct{ io.Reader }{ts}
+		}
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := ts.(ManifestTarSum).WriteManifest(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	manifestBytes := manifestOf(layer, Version2, false)
+	if read := manifestOf(layer, Version2, true); !bytes.Equal(read, manifestBytes) {
+		t.Fatalf("Expected the manifest %s, got %s", manifestBytes, read)
+	}
+	m, err := ReadManifest(bytes.NewReader(manifestBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Manifest{
+		TarSum: "tarsum.v2+sha256:a33aa98fad5aea295e1a935e9a706e94c6002414caa37b5adcc988b6a20aafa7",
+		Entries: []ManifestEntry{
+			{Path: "etc", Mode: 0755, Digest: "1d5ec2a977f0f1b550d9036923dacd431a71058cdfa206d823b71d60ccef9beb"},
+			{Path: "etc/hostname", Mode: 0644, Digest: "1b34368c2c0e32f4f6d093290505aeb266a11219e7351826c40c739e12f31447"},
+		},
+	}
+	if fmt.Sprintf("%+v", m) != fmt.Sprintf("%+v", expected) {
+		t.Fatalf("Expected the manifest %+v, got %+v", expected, m)
+	}
+
+	v, err := NewVerifiedTarSum(bytes.NewReader(layer), expected.TarSum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, v); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteManifest(v, &buf); err != nil || !bytes.Equal(buf.Bytes(), manifestBytes) {
+		t.Fatalf("Expected the manifest %s of the VerifiedTarSum, got %s (%v)", manifestBytes, buf.Bytes(), err)
+	}
+	if err := WriteManifest(struct{ TarSum }{v}, ioutil.Discard); err == nil {
+		t.Fatal("Expected the manifest of another TarSum not to be written")
+	}
+
+	if err := VerifyManifest(bytes.NewReader(layer), m); err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyManifest(bytes.NewReader(touched), m)
+	if e, ok := err.(ManifestError); !ok || len(e.Added)+len(e.Deleted) != 0 || len(e.Changed) != 1 || e.Changed[0] != "etc/hostname" {
+		t.Fatalf("Expected etc/hostname to be changed, got %v", err)
+	}
+	// the mtimes aren't summed by Version1
+	if m, err = ReadManifest(bytes.NewReader(manifestOf(layer, Version1, false))); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyManifest(bytes.NewReader(touched), m); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, err = ReadManifest(bytes.NewReader(manifestOf(namesTar(t, "a", "b", "dup", "dup"), Version1, false))); err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyManifest(bytes.NewReader(namesTar(t, "b", "c", "dup")), m)
+	if e, ok := err.(ManifestError); !ok || fmt.Sprint(e.Added, e.Deleted, e.Changed) != "[c] [a] [dup]" {
+		t.Fatalf("Expected c to be added, a deleted and dup changed, got %v", err)
+	}
+
+	if _, err := ReadManifest(strings.NewReader(`{"tarsum": "sha256:00", "entries": []}`)); err == nil {
+		t.Fatal("Expected a manifest without a tarsum to be invalid")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	manifestOf := func(label string, names ...string) Manifest {
+		m, err := NewManifest(bytes.NewReader(namesTar(t, names...)), label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	a := manifestOf("tarsum.v1+sha256", "dir/", "dir/a", "dir/b", "dup", "dup")
+	for _, c := range []struct {
+		b                        Manifest
+		added, removed, modified []string
+	}{
+		{a, nil, nil, nil},
+		{manifestOf("tarsum.v1+sha256", "dir/", "dir/b", "dir/c", "dup", "dup"), []string{"dir/c"}, []string{"dir/a"}, nil},
+		{manifestOf("tarsum.v1+sha256", "dir/", "dir/b", "dir/a/", "dup"), nil, nil, []string{"dir/a", "dup"}},
+		{manifestOf("tarsum.v1+sha256"), nil, []string{"dir", "dir/a", "dir/b", "dup"}, nil},
+	} {
+		added, removed, modified, err := Diff(a, c.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(added, removed, modified) != fmt.Sprint(c.added, c.removed, c.modified) {
+			t.Fatalf("Expected %v added, %v removed and %v modified, got %v, %v and %v", c.added, c.removed, c.modified, added, removed, modified)
+		}
+	}
+
+	// the digests of other versions and hashes can't be compared
+	for _, label := range []string{"tarsum.dev+sha256", "tarsum.v1+sha512"} {
+		if _, _, _, err := Diff(a, manifestOf(label, "dir/")); err == nil {
+			t.Fatalf("Expected the manifest of %s not to be compared to the one of tarsum.v1+sha256", label)
+		}
+	}
+}
+
+func TestTarSumEvents(t *testing.T) {
+	layer, err := ioutil.ReadFile("testdata/mtime/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Event{
+		{Path: "etc", Digest: "1d5ec2a977f0f1b550d9036923dacd431a71058cdfa206d823b71d60ccef9beb"},
+		{Path: "etc/hostname", Digest: "1b34368c2c0e32f4f6d093290505aeb266a11219e7351826c40c739e12f31447"},
+	}
+
+	for _, read := range []bool{true, false} {
+		ts, err := NewTarSum(bytes.NewReader(layer), true, Version2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := SetEvents(ts, &buf); err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = ts
+		if read {
+			r = struct{ io.Reader }{ts}
+		}
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != len(expected) {
+			t.Fatalf("Expected %d events, got %q", len(expected), buf.String())
+		}
+		bytesRead := int64(0)
+		for i, line := range lines {
+			var event Event
+			if err := json.Unmarshal([]byte(line), &event); err != nil {
+				t.Fatal(err)
+			}
+			if event.Path != expected[i].Path || event.Digest != expected[i].Digest || event.Size != 0 {
+				t.Fatalf("Expected the event %+v, got %+v", expected[i], event)
+			}
+			// the headers of the entry, and of its PAX header, are read
+			if event.Bytes < bytesRead+2*blockSize || event.Bytes > int64(len(layer)) {
+				t.Fatalf("Expected the bytes of the tar read after %d, got %d", bytesRead, event.Bytes)
+			}
+			bytesRead = event.Bytes
+		}
+	}
+
+	// the events are written as the entries are hashed
+	tarBytes, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 3, size: 1024}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := NewTarSum(bytes.NewReader(tarBytes), true, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	pw := writerFunc(func(p []byte) (int, error) {
+		var event Event
+		if err := json.Unmarshal(p, &event); err != nil {
+			return 0, err
+		}
+		if sums := ts.GetSums(); len(sums) != len(events)+1 || sums[len(events)].Sum() != event.Digest {
+			t.Fatalf("Expected the event of the entry hashed, got %+v", event)
+		}
+		events = append(events, event)
+		return len(p), nil
+	})
+	if err := SetEvents(ts, pw); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[2].Size != 1024 {
+		t.Fatalf("Expected the events of the 3 files of 1024 bytes, got %+v", events)
+	}
+	if stats, _ := GetStats(ts); events[2].Bytes != stats.UncompressedBytes-2*blockSize {
+		t.Fatalf("Expected the tar to be read up to its end, %d bytes, got %d", stats.UncompressedBytes-2*blockSize, events[2].Bytes)
+	}
+
+	// the reads fail with the writer of the events
+	ts, err = NewTarSum(bytes.NewReader(tarBytes), true, Version1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("closed")
+	if err := SetEvents(ts, writerFunc(func(p []byte) (int, error) { return 0, failed })); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != failed {
+		t.Fatalf("Expected the error of the writer of the events, got %v", err)
+	}
+}
+
+func TestVersionMeta(t *testing.T) {
+	layer, err := os.Open("testdata/v2/layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer layer.Close()
+	extra, err := ioutil.ReadFile("testdata/v2/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := NewTarSum(layer, true, VersionMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatal(err)
+	}
+	expected := "tarsum.meta+sha256:cb1a3b1ab138c741c5b2b2c88900df9f46905409527646a9514aba2bb71ebff0"
+	if sum := ts.Sum(extra); sum != expected {
+		t.Fatalf("Expected the sum %s, got %s", expected, sum)
+	}
+	if _, err := ParseTarSumInfo(expected); err != nil {
+		t.Fatal(err)
+	}
+
+	// the contents of the files aren't summed, unlike their sizes
+	zeros, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 2, size: 1024}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	random, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 2, size: 1024, isRand: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	larger, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 2, size: 2048}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := []Version{Version2, VersionMeta}
+	sums := make([]map[Version]string, 3)
+	for i, tarBytes := range [][]byte{zeros, random, larger} {
+		if sums[i], err = SumVersions(bytes.NewReader(tarBytes), versions, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sums[0][VersionMeta] != sums[1][VersionMeta] || sums[0][Version2] == sums[1][Version2] {
+		t.Fatalf("Expected the same metadata sums and different sums of the contents, got %v and %v", sums[0], sums[1])
+	}
+	if sums[0][VersionMeta] == sums[2][VersionMeta] {
+		t.Fatalf("Expected different metadata sums of files of different sizes, got %s", sums[0][VersionMeta])
+	}
+
+	// the contents are seeked over rather than read
+	f, err := ioutil.TempFile("", "tarsum-meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	large, err := ioutil.ReadAll(sizedTar(sizedOptions{num: 4, size: 1 << 20}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(large); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, os.SEEK_SET); err != nil {
+		t.Fatal(err)
+	}
+	r := &countingReadSeeker{ReadSeeker: f}
+	metaSums, err := SumVersions(r, []Version{VersionMeta}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.n >= 1<<20 {
+		t.Fatalf("Expected the contents of the files to be skipped, %d bytes read", r.n)
+	}
+	if sums, err := SumVersions(bytes.NewReader(large), versions, nil, nil); err != nil {
+		t.Fatal(err)
+	} else if sums[VersionMeta] != metaSums[VersionMeta] {
+		t.Fatalf("Expected the sum %s, got %s", sums[VersionMeta], metaSums[VersionMeta])
+	}
+}
+
+// countingReadSeeker counts the bytes read from its io.ReadSeeker.
+type countingReadSeeker struct {
+	io.ReadSeeker
+	n int64
+}
+
+func (r *countingReadSeeker) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,79 @@
+package tarsum
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MismatchError is returned by a VerifiedTarSum whose tar doesn't have the
+// expected tarsum.
+type MismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e MismatchError) Error() string {
+	return fmt.Sprintf("tarsum mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// VerifiedTarSum is a TarSum which verifies the tarsum of its tar once it
+// is read.
+type VerifiedTarSum struct {
+	TarSum
+	r        io.Reader
+	expected string
+	done     bool
+	err      error
+}
+
+// NewVerifiedTarSum returns a TarSum of the tar read from r, which must have
+// the tarsum expected, e.g. "tarsum.v1+sha256:{hex}". The version and hash
+// of the sum are the ones of expected, and the tar is read back uncompressed.
+// The tarsums of HMACs are verified by NewVerifiedTarSumHMAC.
+func NewVerifiedTarSum(r io.Reader, expected string) (*VerifiedTarSum, error) {
+	if _, err := ParseTarSumInfo(expected); err != nil {
+		return nil, err
+	}
+	ts, err := NewTarSumForLabel(r, true, expected)
+	if err != nil {
+		return nil, err
+	}
+	return &VerifiedTarSum{TarSum: ts, r: r, expected: expected}, nil
+}
+
+// Read reads the tar back, the final Read returning a MismatchError rather
+// than io.EOF if the tarsum of the tar isn't the expected one.
+func (v *VerifiedTarSum) Read(buf []byte) (int, error) {
+	if v.done {
+		return 0, v.err
+	}
+	n, err := v.TarSum.Read(buf)
+	if err == io.EOF {
+		v.done = true
+		v.err = io.EOF
+		// compared in constant time, not to leak the sums of HMACs
+		if sum := v.TarSum.Sum(nil); !hmac.Equal([]byte(sum), []byte(v.expected)) {
+			v.err = MismatchError{Expected: v.expected, Actual: sum}
+		}
+		return n, v.err
+	}
+	return n, err
+}
+
+// Close closes the reader of the tar if it is an io.Closer, and returns an
+// error if the tar wasn't read to its end, or if its tarsum isn't the
+// expected one.
+func (v *VerifiedTarSum) Close() error {
+	if closer, ok := v.r.(io.Closer); ok {
+		closer.Close()
+	}
+	if !v.done {
+		return errors.New("the tar was not read to its end, its tarsum isn't verified")
+	}
+	if v.err != io.EOF {
+		return v.err
+	}
+	return nil
+}
@@ -2,10 +2,13 @@ package tarsum
 
 import (
 	"archive/tar"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // versioning of the TarSum algorithm
@@ -19,6 +22,14 @@ const (
 	Version1
 	// NOTE: this variable will be either the latest or an unsettled next-version of the TarSum calculation
 	VersionDev
+	// Version2 sums all the fields of the headers of the files, in a
+	// canonical form.
+	Version2
+	// VersionMeta sums the headers of Version2 of the files, but not their
+	// contents, which are skipped, a cheap check of whether the list, the
+	// permissions or the sizes of the files changed before their contents
+	// are summed.
+	VersionMeta
 )
 
 // VersionLabelForChecksum returns the label for the given tarsum
@@ -33,8 +44,63 @@ func VersionLabelForChecksum(checksum string) string {
 	return checksum[:sepIndex]
 }
 
-// Get a list of all known tarsum Version
+// TarSumInfo is a tarsum parsed by ParseTarSumInfo.
+type TarSumInfo struct {
+	Version  Version
+	HashName string
+	Digest   string // the hex encoded digest
+}
+
+// String returns the tarsum of info, e.g. "tarsum.v1+sha256:{hex}".
+func (info TarSumInfo) String() string {
+	return info.Version.String() + "+" + info.HashName + ":" + info.Digest
+}
+
+// ParseTarSumInfo parses the tarsum s, of the form
+// {tarsum_version}+{hash_name}:{hex}. The version must be a known one and the
+// hash a registered one, or its HMAC, and the digest must be lower case hex of
+// the size of the hash.
+func ParseTarSumInfo(s string) (TarSumInfo, error) {
+	var info TarSumInfo
+	i := strings.Index(s, "+")
+	j := strings.Index(s, ":")
+	if i == -1 || j < i {
+		return info, fmt.Errorf("invalid tarsum %q, it should be of the form: {tarsum_version}+{hash_name}:{hex}", s)
+	}
+	versionName, hashName, digest := s[:i], s[i+1:j], s[j+1:]
+
+	version, ok := versionByName(versionName)
+	if !ok {
+		return info, fmt.Errorf("invalid tarsum %q: unknown TarSum version name: %q", s, versionName)
+	}
+	// the digest of the HMAC of a hash is the size of the hash
+	tHash, err := GetTHash(strings.TrimPrefix(hashName, hmacPrefix))
+	if err != nil {
+		return info, fmt.Errorf("invalid tarsum %q: %v", s, err)
+	}
+	if size := tHash.Hash().Size(); len(digest) != size*2 {
+		return info, fmt.Errorf("invalid tarsum %q: the %s digest should be %d hex characters long", s, hashName, size*2)
+	}
+	if _, err := hex.DecodeString(digest); err != nil || strings.ToLower(digest) != digest {
+		return info, fmt.Errorf("invalid tarsum %q: the digest should be lower case hex", s)
+	}
+	return TarSumInfo{Version: version, HashName: hashName, Digest: digest}, nil
+}
+
+// ParseTarSum parses the tarsum s like ParseTarSumInfo, returning its
+// fields.
+func ParseTarSum(s string) (v Version, hashName string, hexDigest string, err error) {
+	info, err := ParseTarSumInfo(s)
+	if err != nil {
+		return -1, "", "", err
+	}
+	return info.Version, info.HashName, info.Digest, nil
+}
+
+// Get a list of all known tarsum Version, including the registered ones
 func GetVersions() []Version {
+	versionsMu.RLock()
+	defer versionsMu.RUnlock()
 	v := []Version{}
 	for k := range tarSumVersions {
 		v = append(v, k)
@@ -43,32 +109,45 @@ func GetVersions() []Version {
 }
 
 var (
+	versionsMu     sync.RWMutex
 	tarSumVersions = map[Version]string{
-		Version0:   "tarsum",
-		Version1:   "tarsum.v1",
-		VersionDev: "tarsum.dev",
+		Version0:    "tarsum",
+		Version1:    "tarsum.v1",
+		Version2:    "tarsum.v2",
+		VersionDev:  "tarsum.dev",
+		VersionMeta: "tarsum.meta",
 	}
 	tarSumVersionsByName = map[string]Version{
-		"tarsum":     Version0,
-		"tarsum.v1":  Version1,
-		"tarsum.dev": VersionDev,
+		"tarsum":      Version0,
+		"tarsum.v1":   Version1,
+		"tarsum.v2":   Version2,
+		"tarsum.dev":  VersionDev,
+		"tarsum.meta": VersionMeta,
 	}
 )
 
 func (tsv Version) String() string {
+	versionsMu.RLock()
+	defer versionsMu.RUnlock()
 	return tarSumVersions[tsv]
 }
 
+// versionByName returns the Version of the name, e.g. "tarsum.v1".
+func versionByName(name string) (Version, bool) {
+	versionsMu.RLock()
+	defer versionsMu.RUnlock()
+	v, ok := tarSumVersionsByName[name]
+	return v, ok
+}
+
 // GetVersionFromTarsum returns the Version from the provided string
 func GetVersionFromTarsum(tarsum string) (Version, error) {
 	tsv := tarsum
 	if strings.Contains(tarsum, "+") {
 		tsv = strings.SplitN(tarsum, "+", 2)[0]
 	}
-	for v, s := range tarSumVersions {
-		if s == tsv {
-			return v, nil
-		}
+	if v, ok := versionByName(tsv); ok {
+		return v, nil
 	}
 	return -1, ErrNotVersion
 }
@@ -79,33 +158,39 @@ var (
 	ErrVersionNotImplemented = errors.New("TarSum Version is not yet implemented")
 )
 
-// tarHeaderSelector is the interface which different versions
+// TarHeaderSelector is the interface which different versions
 // of tarsum should use for selecting and ordering tar headers
-// for each item in the archive.
-type tarHeaderSelector interface {
-	selectHeaders(h *tar.Header) (orderedHeaders [][2]string)
+// for each item in the archive. Each selected header is a key and a value,
+// which are hashed one after the other, before the content of the item.
+type TarHeaderSelector interface {
+	SelectHeaders(h *tar.Header) (orderedHeaders [][2]string)
 }
 
-type tarHeaderSelectFunc func(h *tar.Header) (orderedHeaders [][2]string)
+// TarHeaderSelectFunc is a function used as a TarHeaderSelector.
+type TarHeaderSelectFunc func(h *tar.Header) (orderedHeaders [][2]string)
 
-func (f tarHeaderSelectFunc) selectHeaders(h *tar.Header) (orderedHeaders [][2]string) {
+// SelectHeaders calls f(h).
+func (f TarHeaderSelectFunc) SelectHeaders(h *tar.Header) (orderedHeaders [][2]string) {
 	return f(h)
 }
 
+// v0TarHeaderSelect selects the fields of the ustar header. The numbers are
+// formatted from their 64 bits, so that the sizes of the files over 2GB, of
+// PAX headers, aren't truncated on 32-bit platforms.
 func v0TarHeaderSelect(h *tar.Header) (orderedHeaders [][2]string) {
 	return [][2]string{
 		{"name", h.Name},
-		{"mode", strconv.Itoa(int(h.Mode))},
+		{"mode", strconv.FormatInt(h.Mode, 10)},
 		{"uid", strconv.Itoa(h.Uid)},
 		{"gid", strconv.Itoa(h.Gid)},
-		{"size", strconv.Itoa(int(h.Size))},
-		{"mtime", strconv.Itoa(int(h.ModTime.UTC().Unix()))},
+		{"size", strconv.FormatInt(h.Size, 10)},
+		{"mtime", strconv.FormatInt(h.ModTime.UTC().Unix(), 10)},
 		{"typeflag", string([]byte{h.Typeflag})},
 		{"linkname", h.Linkname},
 		{"uname", h.Uname},
 		{"gname", h.Gname},
-		{"devmajor", strconv.Itoa(int(h.Devmajor))},
-		{"devminor", strconv.Itoa(int(h.Devminor))},
+		{"devmajor", strconv.FormatInt(h.Devmajor, 10)},
+		{"devminor", strconv.FormatInt(h.Devminor, 10)},
 	}
 }
 
@@ -134,13 +219,127 @@ func v1TarHeaderSelect(h *tar.Header) (orderedHeaders [][2]string) {
 	return
 }
 
-var registeredHeaderSelectors = map[Version]tarHeaderSelectFunc{
-	Version0:   v0TarHeaderSelect,
-	Version1:   v1TarHeaderSelect,
-	VersionDev: v1TarHeaderSelect,
+// v2TarHeaderSelect selects all the fields of the header, in a canonical
+// form: the numbers are formatted from their 64 bits, the mtime keeps its
+// nanoseconds, and the regular files of old tars are typed like the others.
+// Each header is a PAX record, "{length} {key}={value}\n", so that no two
+// lists of headers are written alike, whatever the keys and the values of
+// their xattrs.
+func v2TarHeaderSelect(h *tar.Header) (orderedHeaders [][2]string) {
+	typeflag := h.Typeflag
+	if typeflag == tar.TypeRegA {
+		typeflag = tar.TypeReg
+		if strings.HasSuffix(h.Name, "/") {
+			typeflag = tar.TypeDir
+		}
+	}
+
+	xAttrKeys := make([]string, 0, len(h.Xattrs))
+	for k := range h.Xattrs {
+		xAttrKeys = append(xAttrKeys, k)
+	}
+	sort.Strings(xAttrKeys)
+
+	orderedHeaders = make([][2]string, 0, 12+len(xAttrKeys))
+	for _, elem := range [][2]string{
+		{"name", h.Name},
+		{"mode", strconv.FormatInt(h.Mode, 10)},
+		{"uid", strconv.FormatInt(int64(h.Uid), 10)},
+		{"gid", strconv.FormatInt(int64(h.Gid), 10)},
+		{"size", strconv.FormatInt(h.Size, 10)},
+		{"mtime", fmt.Sprintf("%d.%09d", h.ModTime.Unix(), h.ModTime.Nanosecond())},
+		{"typeflag", string([]byte{typeflag})},
+		{"linkname", h.Linkname},
+		{"uname", h.Uname},
+		{"gname", h.Gname},
+		{"devmajor", strconv.FormatInt(h.Devmajor, 10)},
+		{"devminor", strconv.FormatInt(h.Devminor, 10)},
+	} {
+		orderedHeaders = append(orderedHeaders, paxRecord(elem[0], elem[1]))
+	}
+	for _, k := range xAttrKeys {
+		orderedHeaders = append(orderedHeaders, paxRecord("SCHILY.xattr."+k, h.Xattrs[k]))
+	}
+
+	return
+}
+
+// paxRecord returns the PAX record of the key and the value, split in the
+// record up to the value and the value up to the end of the record.
+func paxRecord(key, value string) [2]string {
+	// the length of the record includes the digits of the length
+	size := len(key) + len(value) + 3 // ' ', '=' and '\n'
+	length := size + len(strconv.Itoa(size))
+	if len(strconv.Itoa(length)) > len(strconv.Itoa(size)) {
+		length++
+	}
+	return [2]string{strconv.Itoa(length) + " " + key + "=", value + "\n"}
+}
+
+// devTarHeaderSelect selects the headers of Version2, the GNU sparse files
+// being typed as the regular files they are read as, their holes filled
+// with zeros, so that their sums are the ones of the same files archived
+// in the PAX sparse formats or as regular files.
+func devTarHeaderSelect(h *tar.Header) (orderedHeaders [][2]string) {
+	if h.Typeflag == tar.TypeGNUSparse {
+		regular := *h
+		regular.Typeflag = tar.TypeReg
+		h = &regular
+	}
+	return v2TarHeaderSelect(h)
+}
+
+var (
+	registeredHeaderSelectors = map[Version]TarHeaderSelector{
+		Version0:    TarHeaderSelectFunc(v0TarHeaderSelect),
+		Version1:    TarHeaderSelectFunc(v1TarHeaderSelect),
+		Version2:    TarHeaderSelectFunc(v2TarHeaderSelect),
+		VersionDev:  TarHeaderSelectFunc(devTarHeaderSelect),
+		VersionMeta: TarHeaderSelectFunc(v2TarHeaderSelect),
+	}
+	// the Version of the next version registered
+	nextVersion = VersionMeta + 1
+)
+
+// RegisterVersion registers a version of TarSum whose headers are selected
+// by headerSelector, e.g. a variant of Version1 ignoring the uid and gid
+// of the files, with its name, e.g. "tarsum.rootless", which labels its
+// tarsums, e.g. "tarsum.rootless+sha256:{hex}". It returns the Version
+// with which its TarSums are created. It panics if the name is invalid, or
+// if a version is already registered with it, like the standard "tarsum",
+// "tarsum.v1", "tarsum.v2", "tarsum.dev" and "tarsum.meta".
+//
+// The sums of the version are the ones of its selector, which shouldn't
+// change once its tarsums are published.
+func RegisterVersion(name string, headerSelector TarHeaderSelector) Version {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	if name == "" || strings.ContainsAny(name, "+:") {
+		panic(fmt.Sprintf("tarsum: invalid version name %q, it can't be empty or contain '+' or ':'", name))
+	}
+	if _, exists := tarSumVersionsByName[name]; exists {
+		panic(fmt.Sprintf("tarsum: version %q is already registered", name))
+	}
+	v := nextVersion
+	nextVersion++
+	tarSumVersions[v] = name
+	tarSumVersionsByName[name] = v
+	registeredHeaderSelectors[v] = headerSelector
+	return v
+}
+
+// hashesContents returns whether the sums of the files of the version v
+// hash their contents, after their headers.
+func hashesContents(v Version) bool {
+	return v != VersionMeta
 }
 
-func getTarHeaderSelector(v Version) (tarHeaderSelector, error) {
+// GetTarHeaderSelector returns the TarHeaderSelector of the version v, e.g.
+// to select the headers of a registered version from the ones of a
+// standard one.
+func GetTarHeaderSelector(v Version) (TarHeaderSelector, error) {
+	versionsMu.RLock()
+	defer versionsMu.RUnlock()
 	headerSelector, ok := registeredHeaderSelectors[v]
 	if !ok {
 		return nil, ErrVersionNotImplemented
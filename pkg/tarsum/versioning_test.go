@@ -1,7 +1,13 @@
 package tarsum
 
 import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestVersion(t *testing.T) {
@@ -22,6 +28,19 @@ func TestVersion(t *testing.T) {
 	if v.String() != expected {
 		t.Errorf("expected %q, got %q", expected, v.String())
 	}
+
+	// the versions added later follow the existing ones
+	expected = "tarsum.v2"
+	v = 3
+	if v.String() != expected {
+		t.Errorf("expected %q, got %q", expected, v.String())
+	}
+
+	expected = "tarsum.meta"
+	v = 4
+	if v.String() != expected {
+		t.Errorf("expected %q, got %q", expected, v.String())
+	}
 }
 
 func TestGetVersion(t *testing.T) {
@@ -53,3 +72,236 @@ func TestGetVersion(t *testing.T) {
 		t.Fatalf("%q : %s", err, str)
 	}
 }
+
+func TestParseTarSum(t *testing.T) {
+	digest := "e58fcf7418d4390dec8e8fb69d88c06ec07039d651fedd3aa72af9972e7d046b"
+	for _, s := range []string{"tarsum+sha256:" + digest, "tarsum.v1+sha256:" + digest, "tarsum.v2+sha256:" + digest, "tarsum.dev+sha512:" + digest + digest} {
+		info, err := ParseTarSumInfo(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.String() != s {
+			t.Fatalf("Expected %q, got %q", s, info.String())
+		}
+	}
+
+	v, hashName, hexDigest, err := ParseTarSum("tarsum.v1+sha256:" + digest)
+	if err != nil || v != Version1 || hashName != "sha256" || hexDigest != digest {
+		t.Fatalf("Unexpected %v %q %q %v", v, hashName, hexDigest, err)
+	}
+
+	for _, s := range []string{
+		"",
+		"tarsum.v1",
+		"tarsum.v1+sha256",
+		"sha256:" + digest,
+		"tarsum.v3+sha256:" + digest,
+		"tarsum.v1+md5:" + digest,
+		"tarsum.v1+sha512:" + digest,
+		"tarsum.v1+sha256:" + digest[1:],
+		"tarsum.v1+sha256:" + strings.ToUpper(digest),
+		"tarsum.v1+sha256:" + digest[1:] + "g",
+		"tarsum.v1:sha256+" + digest,
+	} {
+		if _, _, _, err := ParseTarSum(s); err == nil {
+			t.Fatalf("Expected %q to be rejected", s)
+		}
+	}
+}
+
+func encodeHeaders(selector TarHeaderSelectFunc, h *tar.Header) string {
+	var encoded string
+	for _, elem := range selector(h) {
+		encoded += elem[0] + elem[1]
+	}
+	return encoded
+}
+
+func TestV2TarHeaderSelect(t *testing.T) {
+	h := &tar.Header{
+		Name:     "dir/file",
+		Mode:     0644,
+		Uid:      1000,
+		Gid:      100,
+		Size:     1 << 40,
+		ModTime:  time.Unix(1420070400, 123456789),
+		Typeflag: tar.TypeReg,
+		Linkname: "",
+		Uname:    "slartibartfast",
+		Gname:    "users",
+		Devmajor: 1,
+		Devminor: 3,
+		Xattrs: map[string]string{
+			"user.key2": "value2",
+			"user.key1": "value1",
+		},
+	}
+	expected := "17 name=dir/file\n" +
+		"12 mode=420\n" +
+		"12 uid=1000\n" +
+		"11 gid=100\n" +
+		"22 size=1099511627776\n" +
+		"30 mtime=1420070400.123456789\n" +
+		"14 typeflag=0\n" +
+		"13 linkname=\n" +
+		"24 uname=slartibartfast\n" +
+		"15 gname=users\n" +
+		"14 devmajor=1\n" +
+		"14 devminor=3\n" +
+		"33 SCHILY.xattr.user.key1=value1\n" +
+		"33 SCHILY.xattr.user.key2=value2\n"
+	if encoded := encodeHeaders(v2TarHeaderSelect, h); encoded != expected {
+		t.Fatalf("Expected the headers %q, got %q", expected, encoded)
+	}
+
+	// the regular files of old tars are typed like the others
+	old := *h
+	old.Typeflag = tar.TypeRegA
+	if encodeHeaders(v2TarHeaderSelect, &old) != expected {
+		t.Fatal("Expected the headers of an old regular file to be the ones of a regular file")
+	}
+	old.Name = "dir/"
+	dir := old
+	dir.Typeflag = tar.TypeDir
+	if encodeHeaders(v2TarHeaderSelect, &old) != encodeHeaders(v2TarHeaderSelect, &dir) {
+		t.Fatal("Expected the headers of an old directory to be the ones of a directory")
+	}
+
+	// the sub-second mtimes are summed
+	mtime := *h
+	mtime.ModTime = time.Unix(1420070400, 0)
+	if encodeHeaders(v2TarHeaderSelect, &mtime) == expected {
+		t.Fatal("Expected the nanoseconds of the mtime to be summed")
+	}
+
+	// the xattrs can't be mistaken for others, as they are with Version1
+	a := &tar.Header{Name: "file", Xattrs: map[string]string{"user.a": "bc"}}
+	b := &tar.Header{Name: "file", Xattrs: map[string]string{"user.ab": "c"}}
+	if encodeHeaders(v1TarHeaderSelect, a) != encodeHeaders(v1TarHeaderSelect, b) {
+		t.Fatal("Expected the headers of Version1 to be mistaken")
+	}
+	if encodeHeaders(v2TarHeaderSelect, a) == encodeHeaders(v2TarHeaderSelect, b) {
+		t.Fatal("Expected the headers of Version2 not to be mistaken")
+	}
+}
+
+func TestPaxRecord(t *testing.T) {
+	for _, test := range []struct {
+		key, value, expected string
+	}{
+		{"k", "v", "6 k=v\n"},
+		// 9 bytes without the length, which is then 10 bytes long
+		{"k", "vvvvv", "11 k=vvvvv\n"},
+		{"path", "/etc/hosts", "19 path=/etc/hosts\n"},
+		{"k", strings.Repeat("v", 93), "99 k=" + strings.Repeat("v", 93) + "\n"},
+		// 98 bytes without the length, which is then 3 bytes long
+		{"k", strings.Repeat("v", 94), "101 k=" + strings.Repeat("v", 94) + "\n"},
+	} {
+		record := paxRecord(test.key, test.value)
+		if record[0]+record[1] != test.expected {
+			t.Errorf("Expected the record %q, got %q", test.expected, record[0]+record[1])
+		}
+	}
+}
+
+func TestTarHeaderSelectLargeNumbers(t *testing.T) {
+	// the size of a file over 8GB, of a PAX header
+	h := &tar.Header{Name: "large", Size: 1 << 33, Devmajor: 1 << 32, Typeflag: tar.TypeReg}
+	for _, v := range []Version{Version0, Version1, Version2, VersionDev} {
+		selector, err := GetTarHeaderSelector(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, elem := range selector.SelectHeaders(h) {
+			switch {
+			case strings.Contains(elem[0], "size"):
+				if !strings.HasPrefix(elem[1], "8589934592") {
+					t.Errorf("%s: expected the size 8589934592, got %q", v, elem[1])
+				}
+			case strings.Contains(elem[0], "devmajor"):
+				if !strings.HasPrefix(elem[1], "4294967296") {
+					t.Errorf("%s: expected the devmajor 4294967296, got %q", v, elem[1])
+				}
+			}
+		}
+	}
+}
+
+func TestRegisterVersion(t *testing.T) {
+	v, err := GetVersionFromTarsum("tarsum.rootless")
+	if err != nil {
+		selector, err := GetTarHeaderSelector(Version1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v = RegisterVersion("tarsum.rootless", WithoutOwners(selector))
+	}
+	if v.String() != "tarsum.rootless" {
+		t.Fatalf("Expected the version tarsum.rootless, got %q", v)
+	}
+
+	ownedTar := func(uid int, uname string) []byte {
+		var buf bytes.Buffer
+		tarW := tar.NewWriter(&buf)
+		for _, name := range []string{"a", "b"} {
+			hdr := &tar.Header{Name: name, Mode: 0644, Uid: uid, Gid: uid, Uname: uname, Gname: uname, Typeflag: tar.TypeReg}
+			if err := tarW.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tarW.Close()
+		return buf.Bytes()
+	}
+	sum := func(tarBytes []byte, label string) string {
+		ts, err := NewTarSumForLabel(bytes.NewReader(tarBytes), true, label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+			t.Fatal(err)
+		}
+		return ts.Sum(nil)
+	}
+
+	// the sums of the files owned by users are the ones of the files owned
+	// by root, with the headers of Version1
+	rootTar, userTar := ownedTar(0, ""), ownedTar(1000, "user")
+	rootSum := sum(rootTar, "tarsum.v1+sha256")
+	userSum := sum(userTar, "tarsum.rootless+sha256")
+	if userSum != strings.Replace(rootSum, "tarsum.v1+", "tarsum.rootless+", 1) {
+		t.Fatalf("Expected the rootless sum of %s, got %s", rootSum, userSum)
+	}
+	if sum(userTar, "tarsum.v1+sha256") == rootSum {
+		t.Fatal("Expected the owners of the files to be summed by Version1")
+	}
+
+	info, err := ParseTarSumInfo(userSum)
+	if err != nil || info.Version != v {
+		t.Fatalf("Expected %s to parse to the version %s, got %+v, %v", userSum, v, info, err)
+	}
+	ts, err := NewVerifiedTarSum(bytes.NewReader(userTar), userSum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		t.Fatalf("Expected %s to be verified, got %v", userSum, err)
+	}
+	found := false
+	for _, version := range GetVersions() {
+		found = found || version == v
+	}
+	if !found {
+		t.Fatalf("Expected %s to be in the versions", v)
+	}
+
+	for _, name := range []string{"tarsum.v1", "tarsum.rootless", "tarsum+sha256", ""} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Expected registering the version %q to panic", name)
+				}
+			}()
+			RegisterVersion(name, TarHeaderSelectFunc(v1TarHeaderSelect))
+		}()
+	}
+}
@@ -0,0 +1,60 @@
+package tarsum
+
+import (
+	"io"
+)
+
+// TarSumWriter forwards the uncompressed tar written to it to another
+// writer and computes its tarsum, so that a tar can be summed as it is
+// produced, e.g. by the tar.Writer of an archive written to w.
+type TarSumWriter struct {
+	w     io.Writer
+	h     *Hash
+	v     Version
+	tHash THash
+}
+
+// NewTarSumWriter returns a TarSumWriter forwarding the tar to w and
+// computing its tarsum of version v and hash tHash, or DefaultTHash if nil.
+func NewTarSumWriter(w io.Writer, v Version, tHash THash) (*TarSumWriter, error) {
+	if tHash == nil {
+		tHash = DefaultTHash
+	}
+	h, err := NewHash(v, tHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &TarSumWriter{w: w, h: h, v: v, tHash: tHash}, nil
+}
+
+// Write writes p to the underlying writer, and then sums the bytes written.
+// It returns an error if the tar is invalid.
+func (tw *TarSumWriter) Write(p []byte) (int, error) {
+	n, err := tw.w.Write(p)
+	tw.h.Write(p[:n])
+	if herr := tw.h.Err(); herr != nil && err == nil {
+		err = herr
+	}
+	return n, err
+}
+
+// Sum returns the tarsum of the files written whole, and of extra like
+// TarSum.Sum.
+func (tw *TarSumWriter) Sum(extra []byte) string {
+	return sumFileInfoSums(tw.h.GetSums(), extra, tw.v, tw.tHash)
+}
+
+// GetSums returns the sums of the files written whole.
+func (tw *TarSumWriter) GetSums() FileInfoSums {
+	return tw.h.GetSums()
+}
+
+// Version returns the version of the tarsum.
+func (tw *TarSumWriter) Version() Version {
+	return tw.v
+}
+
+// Hash returns the hash of the tarsum.
+func (tw *TarSumWriter) Hash() THash {
+	return tw.tHash
+}
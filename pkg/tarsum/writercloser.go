@@ -20,3 +20,15 @@ func (n *nopCloseFlusher) Close() error {
 func (n *nopCloseFlusher) Flush() error {
 	return nil
 }
+
+// countingWriter counts the bytes written to its Writer.
+type countingWriter struct {
+	io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.n += int64(n)
+	return n, err
+}
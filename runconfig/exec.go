@@ -1,12 +1,15 @@
 package runconfig
 
 import (
+	"github.com/docker/docker/opts"
 	flag "github.com/docker/docker/pkg/mflag"
 )
 
 type ExecConfig struct {
 	User         string
 	Privileged   bool
+	CapAdd       []string
+	CapDrop      []string
 	Tty          bool
 	Container    string
 	AttachStdin  bool
@@ -23,9 +26,13 @@ func ParseExec(cmd *flag.FlagSet, args []string) (*ExecConfig, error) {
 		flDetach     = cmd.Bool([]string{"d", "-detach"}, false, "Detached mode: run command in the background")
 		flUser       = cmd.String([]string{"u", "-user"}, "", "Username or UID (format: <name|uid>[:<group|gid>])")
 		flPrivileged = cmd.Bool([]string{"-privileged"}, false, "Give extended privileges to the command")
+		flCapAdd     = opts.NewListOpts(nil)
+		flCapDrop    = opts.NewListOpts(nil)
 		execCmd      []string
 		container    string
 	)
+	cmd.Var(&flCapAdd, []string{"-cap-add"}, "Add Linux capabilities to the command")
+	cmd.Var(&flCapDrop, []string{"-cap-drop"}, "Drop Linux capabilities from the command")
 	cmd.Require(flag.Min, 2)
 	if err := cmd.ParseFlags(args, true); err != nil {
 		return nil, err
@@ -37,6 +44,8 @@ func ParseExec(cmd *flag.FlagSet, args []string) (*ExecConfig, error) {
 	execConfig := &ExecConfig{
 		User:       *flUser,
 		Privileged: *flPrivileged,
+		CapAdd:     flCapAdd.GetAll(),
+		CapDrop:    flCapDrop.GetAll(),
 		Tty:        *flTty,
 		Cmd:        execCmd,
 		Container:  container,
@@ -38,6 +38,13 @@ func (n NetworkMode) IsNone() bool {
 	return n == "none"
 }
 
+// IsUserDefined indicates whether the container is attached to a network
+// created with `docker network create`, in which case the mode is the name
+// of that network.
+func (n NetworkMode) IsUserDefined() bool {
+	return n != "" && !n.IsBridge() && n.IsPrivate()
+}
+
 type IpcMode string
 
 // IsPrivate indicates whether container use it's private ipc stack
@@ -97,6 +104,24 @@ func (n UTSMode) Valid() bool {
 	return true
 }
 
+// UsernsMode is the user namespace of a container: its own one, which
+// remaps its root when the daemon runs with --userns-remap, or the one of
+// the host with "host".
+type UsernsMode string
+
+func (n UsernsMode) IsHost() bool {
+	return n == "host"
+}
+
+func (n UsernsMode) Valid() bool {
+	switch n {
+	case "", "host":
+	default:
+		return false
+	}
+	return true
+}
+
 type PidMode string
 
 // IsPrivate indicates whether container use it's private pid stack
@@ -202,16 +227,28 @@ type HostConfig struct {
 	PublishAllPorts bool
 	Dns             []string
 	DnsSearch       []string
+	DnsOptions      []string
+	IPAddress       string // IPv4 address requested on a user-defined network
+	IPv6Address     string // IPv6 address requested on a user-defined network
+	EgressRate      int64  // Guaranteed outgoing bandwidth (in bits per second)
+	EgressCeil      int64  // Maximum outgoing bandwidth (in bits per second)
+	IngressRate     int64  // Guaranteed incoming bandwidth (in bits per second)
+	IngressCeil     int64  // Maximum incoming bandwidth (in bits per second)
 	ExtraHosts      []string
 	VolumesFrom     []string
 	Devices         []DeviceMapping
+	Tmpfs           map[string]string // Mount paths in the container to tmpfs mount options
+	Mounts          []Mount           // Mounts specified with --mount
+	Secrets         []string          // Names of the secrets exposed in /run/secrets
 	NetworkMode     NetworkMode
 	IpcMode         IpcMode
 	PidMode         PidMode
 	UTSMode         UTSMode
+	UsernsMode      UsernsMode
 	CapAdd          []string
 	CapDrop         []string
 	RestartPolicy   RestartPolicy
+	AutoRemove      bool // Remove the container and its anonymous volumes when it exits
 	SecurityOpt     []string
 	ReadonlyRootfs  bool
 	Ulimits         []*ulimit.Ulimit
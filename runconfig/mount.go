@@ -0,0 +1,276 @@
+package runconfig
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/units"
+)
+
+// The types of mounts.
+const (
+	MountTypeBind   = "bind"
+	MountTypeVolume = "volume"
+	MountTypeTmpfs  = "tmpfs"
+)
+
+// Mount is a filesystem mount of a container, as specified with --mount.
+type Mount struct {
+	Type     string // bind, volume or tmpfs
+	Source   string // Host path of a bind mount, name of a volume, empty for an anonymous volume
+	Target   string // Path of the mount in the container
+	ReadOnly bool
+	Relabel  string // SELinux relabeling of a bind mount or volume, z or Z
+
+	BindOptions   *BindOptions   `json:",omitempty"`
+	VolumeOptions *VolumeOptions `json:",omitempty"`
+	TmpfsOptions  *TmpfsOptions  `json:",omitempty"`
+}
+
+// BindOptions are the options of a bind mount.
+type BindOptions struct {
+	Propagation string
+	// ReadOnlyRecursive makes the mounts below the source read-only as
+	// well, it requires ReadOnly
+	ReadOnlyRecursive bool
+}
+
+// VolumeOptions are the options of a volume mount. The driver, its options
+// and the labels are used when the named volume is created on first use.
+type VolumeOptions struct {
+	NoCopy     bool // Do not populate the volume with the contents of the image
+	Driver     string
+	DriverOpts map[string]string
+	Labels     map[string]string
+}
+
+// TmpfsOptions are the options of a tmpfs mount.
+type TmpfsOptions struct {
+	SizeBytes int64
+	Mode      os.FileMode
+}
+
+// ParseMount parses a --mount specification, a comma separated list of
+// key=value fields, e.g. type=bind,source=/srv/data,target=/data,readonly.
+// Fields holding commas must be quoted like CSV fields. readonly=recursive
+// makes a bind mount read-only along with the mounts below its source.
+func ParseMount(spec string) (Mount, error) {
+	fields, err := csv.NewReader(strings.NewReader(spec)).Read()
+	if err != nil {
+		return Mount{}, fmt.Errorf("Invalid mount specification %s: %v", spec, err)
+	}
+
+	m := Mount{Type: MountTypeVolume}
+	for _, field := range fields {
+		kv := strings.SplitN(field, "=", 2)
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+
+		// Boolean fields may be given without a value
+		switch key {
+		case "readonly", "ro", "volume-nocopy":
+			value := true
+			if len(kv) == 2 && key != "volume-nocopy" && strings.ToLower(kv[1]) == "recursive" {
+				m.ReadOnly = true
+				bindOptions(&m).ReadOnlyRecursive = true
+				continue
+			}
+			if len(kv) == 2 {
+				if value, err = strconv.ParseBool(kv[1]); err != nil {
+					return Mount{}, fmt.Errorf("Invalid mount specification %s: invalid value for %s: %s", spec, key, kv[1])
+				}
+			}
+			if key == "volume-nocopy" {
+				volumeOptions(&m).NoCopy = value
+			} else {
+				m.ReadOnly = value
+			}
+			continue
+		}
+
+		if len(kv) != 2 {
+			return Mount{}, fmt.Errorf("Invalid mount specification %s: invalid field %q, expected key=value", spec, field)
+		}
+		value := kv[1]
+		switch key {
+		case "type":
+			m.Type = value
+		case "source", "src":
+			m.Source = value
+		case "target", "destination", "dst":
+			m.Target = value
+		case "relabel":
+			m.Relabel = value
+		case "bind-propagation":
+			bindOptions(&m).Propagation = value
+		case "volume-driver":
+			volumeOptions(&m).Driver = value
+		case "volume-opt", "volume-label":
+			opt := strings.SplitN(value, "=", 2)
+			if len(opt) != 2 || opt[0] == "" {
+				return Mount{}, fmt.Errorf("Invalid mount specification %s: invalid %s %q, expected key=value", spec, key, value)
+			}
+			o := volumeOptions(&m)
+			if key == "volume-opt" {
+				if o.DriverOpts == nil {
+					o.DriverOpts = make(map[string]string)
+				}
+				o.DriverOpts[opt[0]] = opt[1]
+			} else {
+				if o.Labels == nil {
+					o.Labels = make(map[string]string)
+				}
+				o.Labels[opt[0]] = opt[1]
+			}
+		case "tmpfs-size":
+			size, err := units.RAMInBytes(value)
+			if err != nil {
+				return Mount{}, fmt.Errorf("Invalid mount specification %s: invalid tmpfs-size %s", spec, value)
+			}
+			tmpfsOptions(&m).SizeBytes = size
+		case "tmpfs-mode":
+			mode, err := strconv.ParseUint(value, 8, 32)
+			if err != nil {
+				return Mount{}, fmt.Errorf("Invalid mount specification %s: invalid tmpfs-mode %s, the mode must be octal", spec, value)
+			}
+			tmpfsOptions(&m).Mode = os.FileMode(mode)
+		default:
+			return Mount{}, fmt.Errorf("Invalid mount specification %s: unknown field %q", spec, key)
+		}
+	}
+
+	if err := validateMount(m); err != nil {
+		return Mount{}, fmt.Errorf("Invalid mount specification %s: %v", spec, err)
+	}
+	return m, nil
+}
+
+func bindOptions(m *Mount) *BindOptions {
+	if m.BindOptions == nil {
+		m.BindOptions = &BindOptions{}
+	}
+	return m.BindOptions
+}
+
+func volumeOptions(m *Mount) *VolumeOptions {
+	if m.VolumeOptions == nil {
+		m.VolumeOptions = &VolumeOptions{}
+	}
+	return m.VolumeOptions
+}
+
+func tmpfsOptions(m *Mount) *TmpfsOptions {
+	if m.TmpfsOptions == nil {
+		m.TmpfsOptions = &TmpfsOptions{}
+	}
+	return m.TmpfsOptions
+}
+
+// ValidateMounts checks the mounts of a container, each mount being checked
+// against its type, and the mounts not sharing a target.
+func ValidateMounts(mounts []Mount) error {
+	targets := make(map[string]struct{})
+	for _, m := range mounts {
+		if err := validateMount(m); err != nil {
+			return fmt.Errorf("Invalid mount on %s: %v", m.Target, err)
+		}
+		target := filepath.Clean(m.Target)
+		if _, exists := targets[target]; exists {
+			return fmt.Errorf("Duplicate mount point %s", target)
+		}
+		targets[target] = struct{}{}
+	}
+	return nil
+}
+
+func validateMount(m Mount) error {
+	if m.Target == "" {
+		return fmt.Errorf("the target is required")
+	}
+	if !filepath.IsAbs(m.Target) {
+		return fmt.Errorf("the target %s must be an absolute path", m.Target)
+	}
+	if filepath.Clean(m.Target) == "/" {
+		return fmt.Errorf("cannot mount over the root of the container")
+	}
+	if m.Relabel != "" && m.Relabel != "z" && m.Relabel != "Z" {
+		return fmt.Errorf("invalid relabel %s, expected z or Z", m.Relabel)
+	}
+
+	switch m.Type {
+	case MountTypeBind:
+		if m.Source == "" {
+			return fmt.Errorf("the source is required for bind mounts")
+		}
+		if !filepath.IsAbs(m.Source) {
+			return fmt.Errorf("the source %s of a bind mount must be an absolute path", m.Source)
+		}
+		if m.VolumeOptions != nil || m.TmpfsOptions != nil {
+			return fmt.Errorf("only bind options apply to bind mounts")
+		}
+		if m.BindOptions != nil && m.BindOptions.ReadOnlyRecursive && !m.ReadOnly {
+			return fmt.Errorf("recursive read-only requires a read-only mount")
+		}
+	case MountTypeVolume:
+		if m.Source != "" && filepath.IsAbs(m.Source) {
+			return fmt.Errorf("the source %s of a volume must be a volume name, use type=bind for host directories", m.Source)
+		}
+		if m.BindOptions != nil || m.TmpfsOptions != nil {
+			return fmt.Errorf("only volume options apply to volumes")
+		}
+		if o := m.VolumeOptions; o != nil && m.Source == "" && (o.Driver != "" || len(o.DriverOpts) > 0 || len(o.Labels) > 0) {
+			return fmt.Errorf("the volume driver, its options and labels require a volume name")
+		}
+	case MountTypeTmpfs:
+		if m.Source != "" {
+			return fmt.Errorf("tmpfs mounts have no source")
+		}
+		if m.Relabel != "" {
+			return fmt.Errorf("tmpfs mounts cannot be relabeled")
+		}
+		if m.BindOptions != nil || m.VolumeOptions != nil {
+			return fmt.Errorf("only tmpfs options apply to tmpfs mounts")
+		}
+		if o := m.TmpfsOptions; o != nil && o.SizeBytes < 0 {
+			return fmt.Errorf("invalid tmpfs size %d", o.SizeBytes)
+		}
+	default:
+		return fmt.Errorf("unknown mount type %q", m.Type)
+	}
+	return nil
+}
+
+// tmpfsMountOptions returns the fstab type options of a tmpfs mount.
+func tmpfsMountOptions(m Mount) string {
+	var options []string
+	if m.ReadOnly {
+		options = append(options, "ro")
+	}
+	if o := m.TmpfsOptions; o != nil {
+		if o.SizeBytes > 0 {
+			options = append(options, fmt.Sprintf("size=%d", o.SizeBytes))
+		}
+		if o.Mode != 0 {
+			options = append(options, fmt.Sprintf("mode=%o", uint32(o.Mode)))
+		}
+	}
+	return strings.Join(options, ",")
+}
+
+// TmpfsMounts returns the tmpfs mounts of the container, those of Tmpfs and
+// the mounts of type tmpfs, by path in the container.
+func (hc *HostConfig) TmpfsMounts() map[string]string {
+	tmpfs := make(map[string]string, len(hc.Tmpfs))
+	for path, options := range hc.Tmpfs {
+		tmpfs[path] = options
+	}
+	for _, m := range hc.Mounts {
+		if m.Type == MountTypeTmpfs {
+			tmpfs[filepath.Clean(m.Target)] = tmpfsMountOptions(m)
+		}
+	}
+	return tmpfs
+}
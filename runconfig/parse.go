@@ -1,13 +1,19 @@
 package runconfig
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/docker/docker/nat"
 	"github.com/docker/docker/opts"
 	flag "github.com/docker/docker/pkg/mflag"
+	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/pkg/units"
@@ -20,6 +26,8 @@ var (
 	ErrConflictHostNetworkAndLinks      = fmt.Errorf("Conflicting options: --net=host can't be used with links. This would result in undefined behavior")
 	ErrConflictContainerNetworkAndMac   = fmt.Errorf("Conflicting options: --mac-address and the network mode (--net)")
 	ErrConflictNetworkHosts             = fmt.Errorf("Conflicting options: --add-host and the network mode (--net)")
+	ErrConflictNetworkAndIP             = fmt.Errorf("Conflicting options: --ip, --ip6 and the network mode (--net), a user-defined network is required")
+	ErrConflictNetworkAndBandwidth      = fmt.Errorf("Conflicting options: bandwidth limits and the network mode (--net)")
 )
 
 func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSet, error) {
@@ -31,6 +39,9 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		flEnv     = opts.NewListOpts(opts.ValidateEnv)
 		flLabels  = opts.NewListOpts(opts.ValidateEnv)
 		flDevices = opts.NewListOpts(opts.ValidatePath)
+		flTmpfs   = opts.NewListOpts(nil)
+		flMounts  = opts.NewListOpts(nil)
+		flSecrets = opts.NewListOpts(nil)
 
 		ulimits   = make(map[string]*ulimit.Ulimit)
 		flUlimits = opts.NewUlimitOpt(ulimits)
@@ -39,6 +50,7 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		flExpose      = opts.NewListOpts(nil)
 		flDns         = opts.NewListOpts(opts.ValidateIPAddress)
 		flDnsSearch   = opts.NewListOpts(opts.ValidateDnsSearch)
+		flDnsOptions  = opts.NewListOpts(opts.ValidateDnsOption)
 		flExtraHosts  = opts.NewListOpts(opts.ValidateExtraHost)
 		flVolumesFrom = opts.NewListOpts(nil)
 		flLxcOpts     = opts.NewListOpts(nil)
@@ -53,6 +65,7 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		flPrivileged      = cmd.Bool([]string{"#privileged", "-privileged"}, false, "Give extended privileges to this container")
 		flPidMode         = cmd.String([]string{"-pid"}, "", "PID namespace to use")
 		flUTSMode         = cmd.String([]string{"-uts"}, "", "UTS namespace to use")
+		flUsernsMode      = cmd.String([]string{"-userns"}, "", "User namespace to use")
 		flPublishAll      = cmd.Bool([]string{"P", "-publish-all"}, false, "Publish all exposed ports to random ports")
 		flStdin           = cmd.Bool([]string{"i", "-interactive"}, false, "Keep STDIN open even if not attached")
 		flTty             = cmd.Bool([]string{"t", "-tty"}, false, "Allocate a pseudo-TTY")
@@ -72,6 +85,12 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		flBlkioWeight     = cmd.Int64([]string{"-blkio-weight"}, 0, "Block IO (relative weight), between 10 and 1000")
 		flNetMode         = cmd.String([]string{"-net"}, "bridge", "Set the Network mode for the container")
 		flMacAddress      = cmd.String([]string{"-mac-address"}, "", "Container MAC address (e.g. 92:d0:c6:0a:29:33)")
+		flIPAddress       = cmd.String([]string{"-ip"}, "", "Container IPv4 address on a user-defined network (e.g. 172.30.100.104)")
+		flIPv6Address     = cmd.String([]string{"-ip6"}, "", "Container IPv6 address on a user-defined network (e.g. 2001:db8::33)")
+		flEgressRate      = cmd.String([]string{"-egress-rate"}, "", "Guaranteed outgoing bandwidth in bits per second (e.g. 10m)")
+		flEgressCeil      = cmd.String([]string{"-egress-ceil"}, "", "Maximum outgoing bandwidth in bits per second, defaults to the rate")
+		flIngressRate     = cmd.String([]string{"-ingress-rate"}, "", "Guaranteed incoming bandwidth in bits per second (e.g. 10m)")
+		flIngressCeil     = cmd.String([]string{"-ingress-ceil"}, "", "Maximum incoming bandwidth in bits per second, defaults to the rate")
 		flIpcMode         = cmd.String([]string{"-ipc"}, "", "IPC namespace to use")
 		flRestartPolicy   = cmd.String([]string{"-restart"}, "no", "Restart policy to apply when a container exits")
 		flReadonlyRootfs  = cmd.Bool([]string{"-read-only"}, false, "Mount the container's root filesystem as read only")
@@ -81,6 +100,9 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 
 	cmd.Var(&flAttach, []string{"a", "-attach"}, "Attach to STDIN, STDOUT or STDERR")
 	cmd.Var(&flVolumes, []string{"v", "-volume"}, "Bind mount a volume")
+	cmd.Var(&flTmpfs, []string{"-tmpfs"}, "Mount a tmpfs directory (e.g. /run:rw,size=64m,mode=1777)")
+	cmd.Var(&flMounts, []string{"-mount"}, "Attach a filesystem mount (e.g. type=bind,source=/srv,target=/srv,readonly)")
+	cmd.Var(&flSecrets, []string{"-secret"}, "Expose a secret in /run/secrets")
 	cmd.Var(&flLinks, []string{"#link", "-link"}, "Add link to another container")
 	cmd.Var(&flDevices, []string{"-device"}, "Add a host device to the container")
 	cmd.Var(&flLabels, []string{"l", "-label"}, "Set meta data on a container")
@@ -91,6 +113,7 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 	cmd.Var(&flExpose, []string{"#expose", "-expose"}, "Expose a port or a range of ports")
 	cmd.Var(&flDns, []string{"#dns", "-dns"}, "Set custom DNS servers")
 	cmd.Var(&flDnsSearch, []string{"-dns-search"}, "Set custom DNS search domains")
+	cmd.Var(&flDnsOptions, []string{"-dns-opt"}, "Set custom DNS options")
 	cmd.Var(&flExtraHosts, []string{"-add-host"}, "Add a custom host-to-IP mapping (host:ip)")
 	cmd.Var(&flVolumesFrom, []string{"#volumes-from", "-volumes-from"}, "Mount volumes from the specified container(s)")
 	cmd.Var(&flLxcOpts, []string{"#lxc-conf", "-lxc-conf"}, "Add custom lxc options")
@@ -148,6 +171,20 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		}
 	}
 
+	if (*flIPAddress != "" || *flIPv6Address != "") && !netMode.IsUserDefined() {
+		return nil, nil, cmd, ErrConflictNetworkAndIP
+	}
+	if *flIPAddress != "" {
+		if ip := net.ParseIP(*flIPAddress); ip == nil || ip.To4() == nil {
+			return nil, nil, cmd, fmt.Errorf("%s is not a valid IPv4 address", *flIPAddress)
+		}
+	}
+	if *flIPv6Address != "" {
+		if ip := net.ParseIP(*flIPv6Address); ip == nil || ip.To4() != nil {
+			return nil, nil, cmd, fmt.Errorf("%s is not a valid IPv6 address", *flIPv6Address)
+		}
+	}
+
 	// If neither -d or -a are set, attach to everything by default
 	if flAttach.Len() == 0 {
 		attachStdout = true
@@ -179,6 +216,21 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		}
 	}
 
+	var bandwidth [4]int64
+	for i, fl := range []*string{flEgressRate, flEgressCeil, flIngressRate, flIngressCeil} {
+		if *fl == "" {
+			continue
+		}
+		if !netMode.IsPrivate() {
+			return nil, nil, cmd, ErrConflictNetworkAndBandwidth
+		}
+		rate, err := units.FromHumanSize(*fl)
+		if err != nil {
+			return nil, nil, cmd, fmt.Errorf("Invalid bandwidth: %s", *fl)
+		}
+		bandwidth[i] = rate
+	}
+
 	var binds []string
 	// add any bind targets to the list of container volumes
 	for bind := range flVolumes.GetMap() {
@@ -260,6 +312,30 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		deviceMappings = append(deviceMappings, deviceMapping)
 	}
 
+	tmpfs := make(map[string]string)
+	for _, spec := range flTmpfs.GetAll() {
+		path, options, err := ParseTmpfs(spec)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		if _, exists := tmpfs[path]; exists {
+			return nil, nil, cmd, fmt.Errorf("Duplicate tmpfs mount %s", path)
+		}
+		tmpfs[path] = options
+	}
+
+	var mounts []Mount
+	for _, spec := range flMounts.GetAll() {
+		m, err := ParseMount(spec)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		mounts = append(mounts, m)
+	}
+	if err := ValidateMounts(mounts); err != nil {
+		return nil, nil, cmd, err
+	}
+
 	// collect all the environment variables for the container
 	envVariables, err := readKVStrings(flEnvFile.GetAll(), flEnv.GetAll())
 	if err != nil {
@@ -287,6 +363,11 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		return nil, nil, cmd, fmt.Errorf("--uts: invalid UTS mode")
 	}
 
+	usernsMode := UsernsMode(*flUsernsMode)
+	if !usernsMode.Valid() {
+		return nil, nil, cmd, fmt.Errorf("--userns: invalid user namespace mode")
+	}
+
 	restartPolicy, err := ParseRestartPolicy(*flRestartPolicy)
 	if err != nil {
 		return nil, nil, cmd, err
@@ -297,6 +378,11 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		return nil, nil, cmd, err
 	}
 
+	securityOpts, err := parseSecurityOpts(flSecurityOpt.GetAll())
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
 	config := &Config{
 		Hostname:        hostname,
 		Domainname:      domainname,
@@ -338,17 +424,28 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		PublishAllPorts: *flPublishAll,
 		Dns:             flDns.GetAll(),
 		DnsSearch:       flDnsSearch.GetAll(),
+		DnsOptions:      flDnsOptions.GetAll(),
+		IPAddress:       *flIPAddress,
+		IPv6Address:     *flIPv6Address,
+		EgressRate:      bandwidth[0],
+		EgressCeil:      bandwidth[1],
+		IngressRate:     bandwidth[2],
+		IngressCeil:     bandwidth[3],
 		ExtraHosts:      flExtraHosts.GetAll(),
 		VolumesFrom:     flVolumesFrom.GetAll(),
 		NetworkMode:     netMode,
 		IpcMode:         ipcMode,
 		PidMode:         pidMode,
 		UTSMode:         utsMode,
+		UsernsMode:      usernsMode,
 		Devices:         deviceMappings,
+		Tmpfs:           tmpfs,
+		Mounts:          mounts,
+		Secrets:         flSecrets.GetAll(),
 		CapAdd:          flCapAdd.GetAll(),
 		CapDrop:         flCapDrop.GetAll(),
 		RestartPolicy:   restartPolicy,
-		SecurityOpt:     flSecurityOpt.GetAll(),
+		SecurityOpt:     securityOpts,
 		ReadonlyRootfs:  *flReadonlyRootfs,
 		Ulimits:         flUlimits.GetList(),
 		LogConfig:       LogConfig{Type: *flLoggingDriver, Config: loggingOpts},
@@ -402,6 +499,32 @@ func parseLoggingOpts(loggingDriver string, loggingOpts []string) (map[string]st
 	return loggingOptsMap, nil
 }
 
+// parseSecurityOpts replaces the files of the seccomp profiles of
+// securityOpts, given as seccomp:FILE or seccomp=FILE, with their content,
+// which the daemon reads.
+func parseSecurityOpts(securityOpts []string) ([]string, error) {
+	for i, opt := range securityOpts {
+		if !strings.HasPrefix(opt, "seccomp:") && !strings.HasPrefix(opt, "seccomp=") {
+			continue
+		}
+		profile := opt[len("seccomp:"):]
+		if profile == "unconfined" {
+			securityOpts[i] = "seccomp:unconfined"
+			continue
+		}
+		body, err := ioutil.ReadFile(profile)
+		if err != nil {
+			return nil, fmt.Errorf("Opening seccomp profile %s failed: %v", profile, err)
+		}
+		buf := bytes.NewBuffer(nil)
+		if err := json.Compact(buf, body); err != nil {
+			return nil, fmt.Errorf("Invalid seccomp profile %s: %v", profile, err)
+		}
+		securityOpts[i] = "seccomp:" + buf.String()
+	}
+	return securityOpts, nil
+}
+
 // ParseRestartPolicy returns the parsed policy or an error indicating what is incorrect
 func ParseRestartPolicy(policy string) (RestartPolicy, error) {
 	p := RestartPolicy{}
@@ -479,11 +602,46 @@ func parseNetMode(netMode string) (NetworkMode, error) {
 			return "", fmt.Errorf("invalid container format container:<name|id>")
 		}
 	default:
-		return "", fmt.Errorf("invalid --net: %s", netMode)
+		// Any other mode names a user-defined network
+		if len(parts) > 1 || mode == "" {
+			return "", fmt.Errorf("invalid --net: %s", netMode)
+		}
 	}
 	return NetworkMode(netMode), nil
 }
 
+// ParseTmpfs parses the specification of a tmpfs mount, the path of the
+// mount in the container optionally followed by a colon and the mount
+// options, e.g. /run:rw,size=64m,mode=1777.
+func ParseTmpfs(spec string) (string, string, error) {
+	arr := strings.SplitN(spec, ":", 2)
+	path, options := filepath.Clean(arr[0]), ""
+	if len(arr) == 2 {
+		options = arr[1]
+	}
+	if err := ValidateTmpfs(map[string]string{path: options}); err != nil {
+		return "", "", err
+	}
+	return path, options, nil
+}
+
+// ValidateTmpfs checks the tmpfs mounts of a container, by path of the mount
+// in the container.
+func ValidateTmpfs(tmpfs map[string]string) error {
+	for path, options := range tmpfs {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("Invalid tmpfs mount %s, the path must be absolute", path)
+		}
+		if filepath.Clean(path) == "/" {
+			return fmt.Errorf("Invalid tmpfs mount %s, cannot mount over the root of the container", path)
+		}
+		if _, _, err := mount.ParseTmpfsOptions(options); err != nil {
+			return fmt.Errorf("Invalid tmpfs mount %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
 func ParseDevice(device string) (DeviceMapping, error) {
 	src := ""
 	dst := ""
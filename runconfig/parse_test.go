@@ -2,6 +2,8 @@ package runconfig
 
 import (
 	"io/ioutil"
+	"os"
+	"reflect"
 	"testing"
 
 	flag "github.com/docker/docker/pkg/mflag"
@@ -63,3 +65,194 @@ func TestConflictContainerNetworkAndLinks(t *testing.T) {
 		t.Fatalf("Expected error ErrConflictContainerNetworkAndLinks, got: %s", err)
 	}
 }
+
+func TestNetUserDefined(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--net=foo", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.NetworkMode != "foo" || !hostConfig.NetworkMode.IsUserDefined() {
+		t.Fatalf("Expected the user-defined network foo, got %q", hostConfig.NetworkMode)
+	}
+	for _, mode := range []NetworkMode{"", "bridge", "host", "none", "container:other"} {
+		if mode.IsUserDefined() {
+			t.Fatalf("Expected %q not to be a user-defined network", mode)
+		}
+	}
+	if _, _, _, err := parseRun([]string{"--net=foo:bar", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for --net=foo:bar")
+	}
+}
+
+func TestNetStaticAddresses(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--net=foo", "--ip=10.10.0.5", "--ip6=2001:db8::5", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.IPAddress != "10.10.0.5" || hostConfig.IPv6Address != "2001:db8::5" {
+		t.Fatalf("Unexpected addresses %s, %s", hostConfig.IPAddress, hostConfig.IPv6Address)
+	}
+	for _, mode := range []string{"bridge", "host", "none", "container:other"} {
+		if _, _, _, err := parseRun([]string{"--net=" + mode, "--ip=10.10.0.5", "img", "cmd"}); err != ErrConflictNetworkAndIP {
+			t.Fatalf("Expected error ErrConflictNetworkAndIP for --net=%s, got: %v", mode, err)
+		}
+	}
+	for _, args := range [][]string{{"--ip=2001:db8::5"}, {"--ip6=10.10.0.5"}, {"--ip=foo"}} {
+		if _, _, _, err := parseRun(append([]string{"--net=foo"}, append(args, "img", "cmd")...)); err == nil {
+			t.Fatalf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestNetBandwidth(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--egress-rate=10m", "--egress-ceil=20m", "--ingress-rate=500k", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.EgressRate != 10000000 || hostConfig.EgressCeil != 20000000 {
+		t.Fatalf("Unexpected egress bandwidth %d, %d", hostConfig.EgressRate, hostConfig.EgressCeil)
+	}
+	if hostConfig.IngressRate != 500000 || hostConfig.IngressCeil != 0 {
+		t.Fatalf("Unexpected ingress bandwidth %d, %d", hostConfig.IngressRate, hostConfig.IngressCeil)
+	}
+	for _, mode := range []string{"host", "none", "container:other"} {
+		if _, _, _, err := parseRun([]string{"--net=" + mode, "--egress-rate=10m", "img", "cmd"}); err != ErrConflictNetworkAndBandwidth {
+			t.Fatalf("Expected error ErrConflictNetworkAndBandwidth for --net=%s, got: %v", mode, err)
+		}
+	}
+	if _, _, _, err := parseRun([]string{"--ingress-ceil=fast", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for an invalid bandwidth")
+	}
+}
+
+func TestParseUsernsMode(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--userns=host", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hostConfig.UsernsMode.IsHost() {
+		t.Fatalf("Expected the host user namespace, got %q", hostConfig.UsernsMode)
+	}
+	if _, _, _, err := parseRun([]string{"--userns=container:other", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for an invalid user namespace mode")
+	}
+}
+
+func TestParseTmpfs(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--read-only", "--tmpfs=/run:rw,size=64m,mode=1777", "--tmpfs=/tmp/", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.Tmpfs) != 2 || hostConfig.Tmpfs["/run"] != "rw,size=64m,mode=1777" {
+		t.Fatalf("Unexpected tmpfs mounts %v", hostConfig.Tmpfs)
+	}
+	if options, exists := hostConfig.Tmpfs["/tmp"]; !exists || options != "" {
+		t.Fatalf("Expected a tmpfs mount on /tmp without options, got %v", hostConfig.Tmpfs)
+	}
+	for _, spec := range []string{"run", "/", "/run:size=64mb", "/run:mode=rwx", "/run:bind", "/run:uid=root"} {
+		if _, _, _, err := parseRun([]string{"--tmpfs=" + spec, "img", "cmd"}); err == nil {
+			t.Fatalf("Expected an error for --tmpfs=%s", spec)
+		}
+	}
+	if _, _, _, err := parseRun([]string{"--tmpfs=/run", "--tmpfs=/run/", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for a duplicate tmpfs mount")
+	}
+}
+
+func TestParseMount(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{
+		"--mount=type=bind,source=/srv/data,target=/data,readonly,bind-propagation=rslave",
+		"--mount", `target=/cache,src=cache,volume-nocopy,volume-driver=local,"volume-opt=o=size=1m,uid=1000",volume-opt=type=tmpfs`,
+		"--mount=type=tmpfs,dst=/run,tmpfs-size=64m,tmpfs-mode=1777",
+		"img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.Mounts) != 3 {
+		t.Fatalf("Expected 3 mounts, got %v", hostConfig.Mounts)
+	}
+	bind := hostConfig.Mounts[0]
+	if bind.Type != MountTypeBind || bind.Source != "/srv/data" || bind.Target != "/data" || !bind.ReadOnly || bind.BindOptions == nil || bind.BindOptions.Propagation != "rslave" || bind.BindOptions.ReadOnlyRecursive {
+		t.Fatalf("Unexpected bind mount %+v", bind)
+	}
+	volume := hostConfig.Mounts[1]
+	if volume.Type != MountTypeVolume || volume.Source != "cache" || volume.ReadOnly || volume.VolumeOptions == nil {
+		t.Fatalf("Unexpected volume mount %+v", volume)
+	}
+	if o := volume.VolumeOptions; !o.NoCopy || o.Driver != "local" || o.DriverOpts["o"] != "size=1m,uid=1000" || o.DriverOpts["type"] != "tmpfs" {
+		t.Fatalf("Unexpected volume options %+v", o)
+	}
+	if tmpfs := hostConfig.TmpfsMounts(); len(tmpfs) != 1 || tmpfs["/run"] != "size=67108864,mode=1777" {
+		t.Fatalf("Unexpected tmpfs mounts %v", tmpfs)
+	}
+
+	for _, spec := range []string{
+		"type=bind,target=/data",
+		"type=bind,source=data,target=/data",
+		"type=volume,source=/srv/data,target=/data",
+		"type=nfs,target=/data",
+		"source=data",
+		"source=data,target=data",
+		"source=data,target=/",
+		"target=/data,volume-driver=local",
+		"source=data,target=/data,bind-propagation=rslave",
+		"source=data,target=/data,readonly=maybe",
+		"source=data,target=/data,readonly=recursive",
+		"source=data,target=/data,relabel=x",
+		"source=data,target=/data,volume-opt=size",
+		"source=data,target=/data,color=blue",
+		"type=tmpfs,source=tmp,target=/tmp",
+		"type=tmpfs,target=/tmp,tmpfs-mode=rwx",
+		"type=tmpfs,target=/tmp,tmpfs-size=big",
+	} {
+		if _, _, _, err := parseRun([]string{"--mount=" + spec, "img", "cmd"}); err == nil {
+			t.Fatalf("Expected an error for --mount=%s", spec)
+		}
+	}
+	if _, _, _, err := parseRun([]string{"--mount=target=/data", "--mount=type=tmpfs,target=/data/", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for a duplicate mount point")
+	}
+
+	_, hostConfig, _, err = parseRun([]string{"--mount=type=bind,source=/srv/data,target=/data,readonly=recursive,bind-propagation=rslave", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bind := hostConfig.Mounts[0]; !bind.ReadOnly || bind.BindOptions == nil || bind.BindOptions.Propagation != "rslave" || !bind.BindOptions.ReadOnlyRecursive {
+		t.Fatalf("Unexpected recursive read-only bind mount %+v", bind)
+	}
+	recursive := Mount{Type: MountTypeBind, Source: "/srv", Target: "/srv", BindOptions: &BindOptions{ReadOnlyRecursive: true}}
+	if err := ValidateMounts([]Mount{recursive}); err == nil {
+		t.Fatal("Expected an error for a recursive read-only mount which is not read-only")
+	}
+}
+
+func TestParseSeccompOpt(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "seccomp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	tmpFile.WriteString(`{
+	"defaultAction": "SCMP_ACT_ALLOW",
+	"syscalls": [{"name": "chmod", "action": "SCMP_ACT_ERRNO"}]
+}`)
+	tmpFile.Close()
+
+	_, hostConfig, _, err := parseRun([]string{"--security-opt=seccomp=" + tmpFile.Name(), "--security-opt=apparmor:unconfined", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{`seccomp:{"defaultAction":"SCMP_ACT_ALLOW","syscalls":[{"name":"chmod","action":"SCMP_ACT_ERRNO"}]}`, "apparmor:unconfined"}
+	if !reflect.DeepEqual(hostConfig.SecurityOpt, expected) {
+		t.Fatalf("Expected security options %q, got %q", expected, hostConfig.SecurityOpt)
+	}
+
+	_, hostConfig, _, err = parseRun([]string{"--security-opt=seccomp:unconfined", "img", "cmd"})
+	if err != nil || len(hostConfig.SecurityOpt) != 1 || hostConfig.SecurityOpt[0] != "seccomp:unconfined" {
+		t.Fatalf("Unexpected security options %q (%v)", hostConfig.SecurityOpt, err)
+	}
+
+	if _, _, _, err := parseRun([]string{"--security-opt=seccomp=/nonexistent.json", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for a missing seccomp profile")
+	}
+}
@@ -0,0 +1,168 @@
+package trust
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/libtrust"
+)
+
+// Requirements are the requirements the images of a scope of the image
+// policy must meet to be pulled and run.
+type Requirements struct {
+	// Reject rejects all the images.
+	Reject bool
+	// Digests, if any, are the only manifest digests accepted.
+	Digests []string
+	// SignedBy, if any, are the keys one of which must have signed the
+	// manifest, given as the path of a public key file or as a key ID.
+	SignedBy []string
+	// RejectUnsigned rejects the manifests which are not signed by a key of
+	// SignedBy or by a key granted by the trust graph of the daemon.
+	RejectUnsigned bool
+
+	keyIDs []string
+}
+
+// IsEmpty returns whether the requirements accept any image.
+func (r *Requirements) IsEmpty() bool {
+	return !r.Reject && len(r.Digests) == 0 && len(r.SignedBy) == 0 && !r.RejectUnsigned
+}
+
+// Policy is the image policy of the daemon. It maps scopes, a registry such
+// as "registry.example.com", a namespace such as "docker.io/library" or a
+// repository such as "docker.io/library/ubuntu", to the requirements of the
+// images of the scope. The requirements of the longest scope matching the
+// name of an image apply, and those of Default when no scope matches.
+type Policy struct {
+	Default      Requirements
+	Repositories map[string]*Requirements
+}
+
+// Provenance records where an image was pulled from, for the image policy
+// to be enforced on the containers of the image.
+type Provenance struct {
+	// Repository is the scope of the repository the image was pulled from.
+	Repository string
+	// Digest is the digest of the manifest of the image, if pulled from a v2
+	// registry.
+	Digest string `json:",omitempty"`
+	// Signers are the IDs of the keys which signed the manifest.
+	Signers []string `json:",omitempty"`
+	// Trusted is whether a signer is granted by the trust graph.
+	Trusted bool `json:",omitempty"`
+}
+
+// PolicyError is returned when an image does not meet the image policy.
+type PolicyError struct {
+	Name   string
+	Reason string
+}
+
+func (e PolicyError) Error() string {
+	return fmt.Sprintf("Image %s is rejected by the image policy: %s", e.Name, e.Reason)
+}
+
+// LoadPolicy reads the image policy from the JSON file at path, and the
+// public keys it names.
+func LoadPolicy(path string) (*Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var p Policy
+	if err := json.NewDecoder(f).Decode(&p); err != nil {
+		return nil, fmt.Errorf("Error reading the image policy %s: %v", path, err)
+	}
+	if err := p.Default.loadKeys(); err != nil {
+		return nil, err
+	}
+	for scope, r := range p.Repositories {
+		if r == nil {
+			return nil, fmt.Errorf("Invalid image policy %s: no requirements for %q", path, scope)
+		}
+		if err := r.loadKeys(); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+func (r *Requirements) loadKeys() error {
+	r.keyIDs = nil
+	for _, k := range r.SignedBy {
+		if !filepath.IsAbs(k) {
+			r.keyIDs = append(r.keyIDs, k)
+			continue
+		}
+		key, err := libtrust.LoadPublicKeyFile(k)
+		if err != nil {
+			return fmt.Errorf("Error loading the key %s of the image policy: %v", k, err)
+		}
+		r.keyIDs = append(r.keyIDs, key.KeyID())
+	}
+	return nil
+}
+
+// Requirements returns the requirements of the images of scope, the name of
+// a repository including its registry.
+func (p *Policy) Requirements(scope string) *Requirements {
+	for s := scope; s != ""; {
+		if r, ok := p.Repositories[s]; ok {
+			return r
+		}
+		i := strings.LastIndex(s, "/")
+		if i == -1 {
+			break
+		}
+		s = s[:i]
+	}
+	return &p.Default
+}
+
+// Check returns whether the image of provenance prov meets the requirements
+// r, the error explaining why not otherwise. A nil prov is an image built
+// or loaded locally, or pulled from a v1 registry, which carries no
+// signature.
+func (r *Requirements) Check(prov *Provenance) error {
+	if r.Reject {
+		return fmt.Errorf("images of this repository are rejected")
+	}
+	if r.IsEmpty() {
+		return nil
+	}
+	if prov == nil || prov.Digest == "" {
+		return fmt.Errorf("the image has no signed manifest")
+	}
+	if len(r.Digests) > 0 && !contains(r.Digests, prov.Digest) {
+		return fmt.Errorf("the digest %s is not allowed", prov.Digest)
+	}
+	signed := false
+	for _, id := range prov.Signers {
+		if contains(r.keyIDs, id) {
+			signed = true
+			break
+		}
+	}
+	if len(r.keyIDs) > 0 && !signed {
+		return fmt.Errorf("the manifest is not signed by a required key")
+	}
+	if r.RejectUnsigned && !signed && !prov.Trusted {
+		return fmt.Errorf("the manifest is not signed by a trusted key")
+	}
+	return nil
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
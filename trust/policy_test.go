@@ -0,0 +1,83 @@
+package trust
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/libtrust"
+)
+
+func TestPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-policy-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := libtrust.GenerateECP256PrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "ci.pem")
+	if err := libtrust.SavePublicKey(keyFile, key.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	policyFile := filepath.Join(dir, "policy.json")
+	if err := ioutil.WriteFile(policyFile, []byte(`{
+		"Default": {"RejectUnsigned": true},
+		"Repositories": {
+			"docker.io": {},
+			"evil.example.com": {"Reject": true},
+			"registry.example.com/prod": {"SignedBy": ["`+keyFile+`"]},
+			"registry.example.com/prod/base": {"Digests": ["sha256:1234"], "SignedBy": ["ABCD:EFGH"]}
+		}
+	}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadPolicy(policyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := &Provenance{Digest: "sha256:1234", Signers: []string{"IJKL:MNOP", key.KeyID()}}
+	unsigned := &Provenance{Digest: "sha256:1234", Signers: []string{"IJKL:MNOP"}}
+	trusted := &Provenance{Digest: "sha256:5678", Signers: []string{"IJKL:MNOP"}, Trusted: true}
+	base := &Provenance{Digest: "sha256:1234", Signers: []string{"ABCD:EFGH"}}
+
+	for _, c := range []struct {
+		scope string
+		prov  *Provenance
+		err   string
+	}{
+		{"docker.io/library/ubuntu", nil, ""},
+		{"evil.example.com/app", signed, "rejected"},
+		{"registry.example.com/prod/app", signed, ""},
+		{"registry.example.com/prod/app", unsigned, "not signed by a required key"},
+		{"registry.example.com/prod/app", trusted, "not signed by a required key"},
+		{"registry.example.com/prod/app", nil, "no signed manifest"},
+		{"registry.example.com/prod/base", base, ""},
+		{"registry.example.com/prod/base", signed, "not signed by a required key"},
+		{"registry.example.com/prod/base", &Provenance{Digest: "sha256:5678", Signers: []string{"ABCD:EFGH"}}, "digest sha256:5678 is not allowed"},
+		{"registry.example.com/production", trusted, ""},
+		{"registry.example.com/production", unsigned, "not signed by a trusted key"},
+		{"localhost:5000/app", &Provenance{}, "no signed manifest"},
+	} {
+		err := p.Requirements(c.scope).Check(c.prov)
+		if c.err == "" && err != nil {
+			t.Fatalf("Expected %s %v to be accepted, got %v", c.scope, c.prov, err)
+		}
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Fatalf("Expected %s %v to be rejected with %q, got %v", c.scope, c.prov, c.err, err)
+		}
+	}
+
+	if err := ioutil.WriteFile(policyFile, []byte(`{"Repositories": {"registry.example.com": {"SignedBy": ["/nonexistent.pem"]}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicy(policyFile); err == nil {
+		t.Fatal("Expected a policy with a missing key to be rejected")
+	}
+}
@@ -5,18 +5,42 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/volumes/volumedriver"
+	"github.com/docker/docker/volumes/volumedriver/remote"
 )
 
+const (
+	// DefaultDriver is the driver of the volumes stored on the host.
+	DefaultDriver = "local"
+	// ContainerLabel labels the anonymous volumes with the ID of the
+	// container they were created for.
+	ContainerLabel = "com.docker.volume.container"
+
+	validNameChars = `[a-zA-Z0-9][a-zA-Z0-9_.-]`
+)
+
+var validNamePattern = regexp.MustCompile(`^` + validNameChars + `+$`)
+
+// ValidName reports whether name can name a volume.
+func ValidName(name string) bool {
+	return validNamePattern.MatchString(name)
+}
+
 type Repository struct {
 	configPath string
 	driver     graphdriver.Driver
 	volumes    map[string]*Volume
-	lock       sync.Mutex
+	// names indexes the volumes which are not bind mounts by name
+	names map[string]*Volume
+	lock  sync.Mutex
 }
 
 func NewRepository(configPath string, driver graphdriver.Driver) (*Repository, error) {
@@ -34,17 +58,27 @@ func NewRepository(configPath string, driver graphdriver.Driver) (*Repository, e
 		driver:     driver,
 		configPath: abspath,
 		volumes:    make(map[string]*Volume),
+		names:      make(map[string]*Volume),
 	}
 
 	return repo, repo.restore()
 }
 
 func (r *Repository) newVolume(path string, writable bool) (*Volume, error) {
+	return r.newNamedVolume("", path, writable, nil, nil)
+}
+
+// newNamedVolume creates the volume name, named after its ID when name is
+// empty. The volume is a bind mount of path when path is not empty.
+func (r *Repository) newNamedVolume(name, path string, writable bool, options, labels map[string]string) (*Volume, error) {
 	var (
 		isBindMount bool
 		err         error
 		id          = stringid.GenerateRandomID()
 	)
+	if name == "" {
+		name = id
+	}
 	if path != "" {
 		isBindMount = true
 	}
@@ -65,6 +99,11 @@ func (r *Repository) newVolume(path string, writable bool) (*Volume, error) {
 
 	v := &Volume{
 		ID:          id,
+		Name:        name,
+		Driver:      DefaultDriver,
+		Options:     options,
+		Labels:      labels,
+		CreatedAt:   time.Now().UTC(),
 		Path:        path,
 		repository:  r,
 		Writable:    writable,
@@ -104,6 +143,11 @@ func (r *Repository) restore() error {
 				continue
 			}
 		}
+		if vol.CreatedAt.IsZero() {
+			if fi, err := os.Stat(vol.configPath); err == nil {
+				vol.CreatedAt = fi.ModTime().UTC()
+			}
+		}
 		r.add(vol)
 	}
 	return nil
@@ -117,42 +161,313 @@ func (r *Repository) Get(path string) *Volume {
 }
 
 func (r *Repository) get(path string) *Volume {
-	path, err := filepath.EvalSymlinks(path)
+	cleanPath, err := filepath.EvalSymlinks(path)
 	if err != nil {
+		// The mountpoint of a volume of a driver may be gone while the
+		// volume is not mounted
+		if v := r.volumes[filepath.Clean(path)]; v != nil && v.IsExternal() {
+			return v
+		}
 		return nil
 	}
-	return r.volumes[filepath.Clean(path)]
+	return r.volumes[filepath.Clean(cleanPath)]
 }
 
 func (r *Repository) add(volume *Volume) {
-	if vol := r.get(volume.Path); vol != nil {
+	// Volumes of drivers have no path until they are first mounted
+	if volume.Path != "" {
+		if vol := r.get(volume.Path); vol != nil {
+			return
+		}
+		r.volumes[volume.Path] = volume
+	}
+	if volume.IsBindMount {
 		return
 	}
-	r.volumes[volume.Path] = volume
+	// Volumes created by older daemons have no name nor driver
+	if volume.Name == "" {
+		volume.Name = volume.ID
+	}
+	if volume.Driver == "" {
+		volume.Driver = DefaultDriver
+	}
+	r.names[volume.Name] = volume
 }
 
-func (r *Repository) Delete(path string) error {
+// Create creates a volume named name with driver, the local driver when
+// empty, passing it options. The volume is named after its ID when name is
+// empty.
+func (r *Repository) Create(name, driver string, options, labels map[string]string) (*Volume, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if name != "" {
+		if !ValidName(name) {
+			return nil, fmt.Errorf("Invalid volume name (%s), only %s are allowed", name, validNameChars)
+		}
+		if _, exists := r.names[name]; exists {
+			return nil, fmt.Errorf("volume with name %s already exists", name)
+		}
+	}
+	if driver == "" || driver == DefaultDriver {
+		options, err := parseLocalOptions(options)
+		if err != nil {
+			return nil, err
+		}
+		return r.newNamedVolume(name, "", true, options, labels)
+	}
+	return r.newDriverVolume(name, driver, options, labels)
+}
+
+// localOptions are the options of the local driver: the type, device and
+// mount options of the filesystem to mount on the volume, and the size of
+// tmpfs volumes.
+var localOptions = map[string]bool{
+	"type":   true,
+	"device": true,
+	"o":      true,
+	"size":   true,
+}
+
+// parseLocalOptions checks the options of a local volume, returning them
+// with their defaults.
+func parseLocalOptions(options map[string]string) (map[string]string, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	opts := make(map[string]string, len(options))
+	for k, v := range options {
+		if !localOptions[k] {
+			return nil, fmt.Errorf("invalid option for the local volume driver: %s", k)
+		}
+		opts[k] = v
+	}
+	if opts["type"] == "" {
+		return nil, fmt.Errorf("the type option of the local volume driver is required with its other options")
+	}
+	if size, ok := opts["size"]; ok {
+		if opts["type"] != "tmpfs" {
+			return nil, fmt.Errorf("the size option of the local volume driver only applies to tmpfs")
+		}
+		delete(opts, "size")
+		if opts["o"] != "" {
+			opts["o"] += ","
+		}
+		opts["o"] += "size=" + size
+	}
+	if opts["device"] == "" {
+		if opts["type"] != "tmpfs" {
+			return nil, fmt.Errorf("the device option of the local volume driver is required for %s", opts["type"])
+		}
+		opts["device"] = "tmpfs"
+	}
+	return opts, nil
+}
+
+// newDriverVolume creates the volume name with the volume driver driver. The
+// volumes of drivers of global scope may already exist, in which case they
+// are used as is.
+func (r *Repository) newDriverVolume(name, driver string, options, labels map[string]string) (*Volume, error) {
+	d, err := lookupDriver(driver)
+	if err != nil {
+		return nil, fmt.Errorf("volume driver %s not found: %v", driver, err)
+	}
+
+	id := stringid.GenerateRandomID()
+	if name == "" {
+		name = id
+	}
+
+	exists := false
+	if d.Capabilities().Scope == volumedriver.GlobalScope {
+		_, err := d.Get(name)
+		exists = err == nil
+	}
+	if !exists {
+		if err := d.Create(name, options); err != nil {
+			return nil, err
+		}
+	}
+
+	v := &Volume{
+		ID:         id,
+		Name:       name,
+		Driver:     driver,
+		Options:    options,
+		Labels:     labels,
+		CreatedAt:  time.Now().UTC(),
+		repository: r,
+		Writable:   true,
+		containers: make(map[string]struct{}),
+		configPath: r.configPath + "/" + id,
+	}
+	if err := v.initialize(); err != nil {
+		return nil, err
+	}
+
+	r.add(v)
+	return v, nil
+}
+
+// lookupDriver returns the volume driver registered under name, activating
+// the volume plugin of that name if no such driver is registered yet.
+func lookupDriver(name string) (volumedriver.Driver, error) {
+	if d, err := volumedriver.GetDriver(name); err == nil {
+		return d, nil
+	}
+	return remote.Load(name)
+}
+
+// Mount mounts the volume v if it is stored by a volume driver, or has a
+// mount of its own, and returns its path. The volumes of drivers are known
+// by every path they were mounted at, so that the containers which recorded
+// an earlier one still find them.
+func (r *Repository) Mount(v *Volume) (string, error) {
+	if v.hasMount() {
+		return v.Path, v.mount()
+	}
+	if !v.IsExternal() {
+		return v.Path, nil
+	}
+	d, err := lookupDriver(v.Driver)
+	if err != nil {
+		return "", err
+	}
+	path, err := d.Mount(v.Name)
+	if err != nil {
+		return "", err
+	}
+	path = filepath.Clean(path)
+
 	r.lock.Lock()
 	defer r.lock.Unlock()
-	path, err := filepath.EvalSymlinks(path)
+	if path != v.Path {
+		v.Path = path
+		r.volumes[path] = v
+		if err := v.ToDisk(); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// Unmount unmounts the volume v if it is stored by a volume driver, or has a
+// mount of its own.
+func (r *Repository) Unmount(v *Volume) error {
+	if v.hasMount() {
+		return v.unmount()
+	}
+	if !v.IsExternal() {
+		return nil
+	}
+	d, err := lookupDriver(v.Driver)
 	if err != nil {
 		return err
 	}
-	volume := r.get(filepath.Clean(path))
+	return d.Unmount(v.Name)
+}
+
+// Mountpoint returns the path the volume v is mounted at, an empty string
+// when it is stored by a volume driver and not mounted.
+func (r *Repository) Mountpoint(v *Volume) (string, error) {
+	if !v.IsExternal() {
+		return v.Path, nil
+	}
+	d, err := lookupDriver(v.Driver)
+	if err != nil {
+		return "", err
+	}
+	return d.Path(v.Name)
+}
+
+// Capabilities returns the capabilities of the driver of the volume v. The
+// volumes stored on the host have none.
+func (r *Repository) Capabilities(v *Volume) (volumedriver.Capabilities, error) {
+	if !v.IsExternal() {
+		return volumedriver.Capabilities{Scope: volumedriver.LocalScope}, nil
+	}
+	d, err := lookupDriver(v.Driver)
+	if err != nil {
+		return volumedriver.Capabilities{}, err
+	}
+	return d.Capabilities(), nil
+}
+
+// GetByName returns the volume whose name is name. It does not return bind
+// mounts.
+func (r *Repository) GetByName(name string) (*Volume, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	v, exists := r.names[name]
+	if !exists {
+		return nil, fmt.Errorf("no such volume: %s", name)
+	}
+	return v, nil
+}
+
+// List returns the volumes of the repository which are not bind mounts,
+// sorted by name.
+func (r *Repository) List() []*Volume {
+	r.lock.Lock()
+	list := make([]*Volume, 0, len(r.names))
+	for _, v := range r.names {
+		list = append(list, v)
+	}
+	r.lock.Unlock()
+
+	sort.Sort(byName(list))
+	return list
+}
+
+type byName []*Volume
+
+func (v byName) Len() int           { return len(v) }
+func (v byName) Less(i, j int) bool { return v[i].Name < v[j].Name }
+func (v byName) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+
+func (r *Repository) Delete(path string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	volume := r.get(path)
 	if volume == nil {
 		return fmt.Errorf("Volume %s does not exist", path)
 	}
+	return r.delete(volume)
+}
+
+// DeleteByName deletes the volume whose name is name.
+func (r *Repository) DeleteByName(name string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	volume, exists := r.names[name]
+	if !exists {
+		return fmt.Errorf("no such volume: %s", name)
+	}
+	return r.delete(volume)
+}
 
+func (r *Repository) delete(volume *Volume) error {
 	containers := volume.Containers()
 	if len(containers) > 0 {
 		return fmt.Errorf("Volume %s is being used and cannot be removed: used by containers %s", volume.Path, containers)
 	}
 
+	if volume.IsExternal() {
+		d, err := lookupDriver(volume.Driver)
+		if err != nil {
+			return err
+		}
+		if err := d.Remove(volume.Name); err != nil {
+			return err
+		}
+	}
+
 	if err := os.RemoveAll(volume.configPath); err != nil {
 		return err
 	}
 
-	if !volume.IsBindMount {
+	if !volume.IsBindMount && !volume.IsExternal() {
 		if err := r.driver.Remove(volume.ID); err != nil {
 			if !os.IsNotExist(err) {
 				return err
@@ -160,7 +475,14 @@ func (r *Repository) Delete(path string) error {
 		}
 	}
 
-	delete(r.volumes, volume.Path)
+	for path, v := range r.volumes {
+		if v == volume {
+			delete(r.volumes, path)
+		}
+	}
+	if !volume.IsBindMount {
+		delete(r.names, volume.Name)
+	}
 	return nil
 }
 
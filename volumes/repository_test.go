@@ -1,6 +1,7 @@
 package volumes
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/docker/docker/daemon/graphdriver"
 	_ "github.com/docker/docker/daemon/graphdriver/vfs"
+	"github.com/docker/docker/volumes/volumedriver"
 )
 
 func TestRepositoryFindOrCreate(t *testing.T) {
@@ -152,11 +154,261 @@ func TestRepositoryDelete(t *testing.T) {
 
 }
 
+func TestRepositoryNamedVolumes(t *testing.T) {
+	root, err := ioutil.TempDir(os.TempDir(), "volumes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	repo, err := newRepo(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := repo.Create("data", "", nil, map[string]string{"com.example.role": "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "data" || v.Driver != DefaultDriver || v.IsBindMount {
+		t.Fatalf("Expected a local volume named data, got %+v", v)
+	}
+	if _, err := repo.Create("data", "", nil, nil); err == nil {
+		t.Fatal("Expected an error creating a volume with a name in use")
+	}
+	for _, name := range []string{"-data", "da/ta", "a"} {
+		if _, err := repo.Create(name, "", nil, nil); err == nil {
+			t.Fatalf("Expected an error creating a volume named %q", name)
+		}
+	}
+	if _, err := repo.Create("other", "nfs", nil, nil); err == nil {
+		t.Fatal("Expected an error creating a volume with an unknown driver")
+	}
+
+	anonymous, err := repo.FindOrCreateVolume("", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anonymous.Name != anonymous.ID {
+		t.Fatalf("Expected an anonymous volume to be named after its ID, got %s", anonymous.Name)
+	}
+	if !anonymous.IsAnonymous() || v.IsAnonymous() {
+		t.Fatal("Expected only the unnamed volume to be anonymous")
+	}
+	if _, err := repo.FindOrCreateVolume(filepath.Join(root, "bind"), true); err != nil {
+		t.Fatal(err)
+	}
+
+	list := repo.List()
+	if len(list) != 2 {
+		t.Fatalf("Expected the bind mount not to be listed, got %d volumes", len(list))
+	}
+	if v2, err := repo.GetByName("data"); err != nil || v2 != v {
+		t.Fatalf("Expected to get the volume by name, got %v (%v)", v2, err)
+	}
+
+	// Names are restored from disk
+	repo, err = newRepo(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = repo.GetByName("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Labels["com.example.role"] != "db" {
+		t.Fatalf("Expected the labels to be restored, got %v", v.Labels)
+	}
+	if err := repo.Delete(v.Path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByName("data"); err == nil {
+		t.Fatal("Expected the volume to be deleted")
+	}
+}
+
+type fakeDriver struct {
+	root    string
+	scope   string
+	volumes map[string]int
+	options map[string]map[string]string
+}
+
+func (d *fakeDriver) Create(name string, options map[string]string) error {
+	if _, exists := d.volumes[name]; exists {
+		return fmt.Errorf("volume %s exists", name)
+	}
+	d.volumes[name] = 0
+	d.options[name] = options
+	return nil
+}
+
+func (d *fakeDriver) Remove(name string) error {
+	delete(d.volumes, name)
+	return nil
+}
+
+func (d *fakeDriver) Mount(name string) (string, error) {
+	d.volumes[name]++
+	return d.Path(name)
+}
+
+func (d *fakeDriver) Unmount(name string) error {
+	d.volumes[name]--
+	return nil
+}
+
+func (d *fakeDriver) Path(name string) (string, error) {
+	if d.volumes[name] == 0 {
+		return "", nil
+	}
+	return filepath.Join(d.root, name), nil
+}
+
+func (d *fakeDriver) Get(name string) (*volumedriver.Volume, error) {
+	if _, exists := d.volumes[name]; !exists {
+		return nil, fmt.Errorf("no such volume %s", name)
+	}
+	path, _ := d.Path(name)
+	return &volumedriver.Volume{Name: name, Mountpoint: path}, nil
+}
+
+func (d *fakeDriver) List() ([]*volumedriver.Volume, error) {
+	var list []*volumedriver.Volume
+	for name := range d.volumes {
+		v, _ := d.Get(name)
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (d *fakeDriver) Capabilities() volumedriver.Capabilities {
+	return volumedriver.Capabilities{Scope: d.scope}
+}
+
+func (d *fakeDriver) Type() string {
+	return "fake-" + d.scope
+}
+
+func TestRepositoryDriverVolumes(t *testing.T) {
+	root, err := ioutil.TempDir(os.TempDir(), "volumes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	repo, err := newRepo(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := &fakeDriver{root: filepath.Join(root, "local"), scope: volumedriver.LocalScope, volumes: make(map[string]int), options: make(map[string]map[string]string)}
+	global := &fakeDriver{root: filepath.Join(root, "global"), scope: volumedriver.GlobalScope, volumes: map[string]int{"shared": 0}, options: make(map[string]map[string]string)}
+	for _, d := range []*fakeDriver{local, global} {
+		if err := volumedriver.RegisterDriver(d.Type(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v, err := repo.Create("data", local.Type(), map[string]string{"size": "10G"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.IsExternal() || v.Path != "" {
+		t.Fatalf("Expected an unmounted volume of the driver, got %+v", v)
+	}
+	if _, exists := local.volumes["data"]; !exists {
+		t.Fatal("Expected the volume to be created by the driver")
+	}
+	if size := local.options["data"]["size"]; size != "10G" || v.Options["size"] != size {
+		t.Fatalf("Expected the options to be passed to the driver, got %v", local.options["data"])
+	}
+
+	path, err := repo.Mount(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(local.root, "data") || v.Path != path {
+		t.Fatalf("Expected the volume to be mounted at the path of the driver, got %s", path)
+	}
+	// The mountpoint does not exist on the host, the volume is still found
+	if v2 := repo.Get(path); v2 != v {
+		t.Fatalf("Expected to get the volume by its mountpoint, got %v", v2)
+	}
+	if err := repo.Unmount(v); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := repo.Mountpoint(v); err != nil || path != "" {
+		t.Fatalf("Expected the volume not to be mounted, got %s (%v)", path, err)
+	}
+
+	// Volumes of global drivers created elsewhere are used as is
+	if _, err := repo.Create("shared", global.Type(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Create("shared", local.Type(), nil, nil); err == nil {
+		t.Fatal("Expected an error creating a volume with a name in use")
+	}
+
+	// The driver of volumes is restored from disk
+	repo, err = newRepo(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = repo.GetByName("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Driver != local.Type() || repo.Get(path) != v {
+		t.Fatalf("Expected the volume of the driver to be restored, got %+v", v)
+	}
+	if err := repo.DeleteByName("data"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := local.volumes["data"]; exists {
+		t.Fatal("Expected the volume to be removed by the driver")
+	}
+	if repo.Get(path) != nil {
+		t.Fatal("Expected the mountpoints of the volume to be forgotten")
+	}
+}
+
+func TestParseLocalOptions(t *testing.T) {
+	opts, err := parseLocalOptions(map[string]string{"type": "tmpfs", "size": "100m", "o": "uid=1000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts["device"] != "tmpfs" || opts["o"] != "uid=1000,size=100m" || opts["size"] != "" {
+		t.Fatalf("Expected a tmpfs of 100m, got %v", opts)
+	}
+
+	opts, err = parseLocalOptions(map[string]string{"type": "nfs", "device": ":/export", "o": "addr=10.0.0.1,rw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts["device"] != ":/export" || opts["o"] != "addr=10.0.0.1,rw" {
+		t.Fatalf("Expected an nfs share, got %v", opts)
+	}
+
+	if opts, err := parseLocalOptions(nil); err != nil || opts != nil {
+		t.Fatalf("Expected no options, got %v (%v)", opts, err)
+	}
+
+	for _, options := range []map[string]string{
+		{"device": "/dev/sdb"},
+		{"type": "ext4"},
+		{"type": "ext4", "device": "/dev/sdb", "size": "10G"},
+		{"type": "tmpfs", "mode": "1777"},
+	} {
+		if _, err := parseLocalOptions(options); err == nil {
+			t.Fatalf("Expected an error parsing %v", options)
+		}
+	}
+}
+
 func newRepo(root string) (*Repository, error) {
 	configPath := filepath.Join(root, "repo-config")
 	graphDir := filepath.Join(root, "repo-graph")
 
-	driver, err := graphdriver.GetDriver("vfs", graphDir, []string{})
+	driver, err := graphdriver.GetDriver("vfs", graphDir, []string{}, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -5,12 +5,24 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/pkg/symlink"
 )
 
 type Volume struct {
-	ID          string
+	ID string
+	// Name is given at creation, or else the ID. Bind mounts have no name.
+	Name string
+	// Driver is the name of the driver the volume is stored by.
+	Driver string
+	// Options are the options of the driver the volume was created with.
+	Options map[string]string
+	Labels  map[string]string
+	// CreatedAt is when the volume was created, or first restored for the
+	// volumes created by older daemons.
+	CreatedAt   time.Time
 	Path        string
 	IsBindMount bool
 	Writable    bool
@@ -18,6 +30,8 @@ type Volume struct {
 	configPath  string
 	repository  *Repository
 	lock        sync.Mutex
+	// mounts counts the users of a local volume with a mount
+	mounts int
 }
 
 func (v *Volume) IsDir() (bool, error) {
@@ -29,6 +43,55 @@ func (v *Volume) IsDir() (bool, error) {
 	return stat.IsDir(), nil
 }
 
+// IsAnonymous reports whether the volume was created without a name, for a
+// container.
+func (v *Volume) IsAnonymous() bool {
+	return !v.IsBindMount && (v.Name == "" || v.Name == v.ID)
+}
+
+// IsExternal reports whether the volume is stored by a volume driver rather
+// than on the host.
+func (v *Volume) IsExternal() bool {
+	return !v.IsBindMount && v.Driver != "" && v.Driver != DefaultDriver
+}
+
+// hasMount reports whether the volume is stored on the host by the local
+// driver on a mount of its own, given by its options.
+func (v *Volume) hasMount() bool {
+	return !v.IsBindMount && !v.IsExternal() && v.Options["type"] != ""
+}
+
+// mount mounts the volume if it has a mount and is not mounted yet.
+func (v *Volume) mount() error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.mounts == 0 {
+		if err := mount.Mount(v.Options["device"], v.Path, v.Options["type"], v.Options["o"]); err != nil {
+			return err
+		}
+	}
+	v.mounts++
+	return nil
+}
+
+// unmount unmounts the volume once it has no more users.
+func (v *Volume) unmount() error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.mounts == 0 {
+		return nil
+	}
+	if v.mounts == 1 {
+		if err := mount.Unmount(v.Path); err != nil {
+			return err
+		}
+	}
+	v.mounts--
+	return nil
+}
+
 func (v *Volume) Containers() []string {
 	v.lock.Lock()
 
@@ -57,12 +120,14 @@ func (v *Volume) initialize() error {
 	v.lock.Lock()
 	defer v.lock.Unlock()
 
-	if _, err := os.Stat(v.Path); err != nil {
-		if !os.IsNotExist(err) {
-			return err
-		}
-		if err := os.MkdirAll(v.Path, 0755); err != nil {
-			return err
+	if v.Path != "" {
+		if _, err := os.Stat(v.Path); err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+			if err := os.MkdirAll(v.Path, 0755); err != nil {
+				return err
+			}
 		}
 	}
 
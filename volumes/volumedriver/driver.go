@@ -0,0 +1,92 @@
+// Package volumedriver defines the interface of the drivers storing named
+// volumes outside of the host, e.g. on NFS shares or EBS disks.
+package volumedriver
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// LocalScope is the scope of the drivers whose volumes are only known
+	// to the host they were created on.
+	LocalScope = "local"
+	// GlobalScope is the scope of the drivers whose volumes are shared
+	// between hosts. A volume created on another host is used as is
+	// instead of being created again.
+	GlobalScope = "global"
+)
+
+// Driver is implemented by the providers of named volumes. Volumes are
+// identified by their name, the driver chooses where they are mounted on the
+// host.
+type Driver interface {
+	// Create creates the volume name, options are driver specific.
+	Create(name string, options map[string]string) error
+	// Remove deletes the volume name and its data.
+	Remove(name string) error
+	// Mount makes the volume name available on the host and returns the
+	// path it is mounted at. Mount is called every time a container using
+	// the volume starts, the driver must count the mounts of a volume.
+	Mount(name string) (string, error)
+	// Unmount is called when a container using the volume name stops.
+	Unmount(name string) error
+	// Path returns the path the volume name is mounted at, or an empty
+	// string when it is not mounted.
+	Path(name string) (string, error)
+	// Get returns the volume name, or an error if the driver does not
+	// know of it.
+	Get(name string) (*Volume, error)
+	// List returns the volumes of the driver.
+	List() ([]*Volume, error)
+	// Capabilities returns what the driver supports.
+	Capabilities() Capabilities
+	// Type returns the name the driver is registered with.
+	Type() string
+}
+
+// Volume describes a volume of a driver.
+type Volume struct {
+	Name string
+	// Mountpoint is the path the volume is mounted at, if any.
+	Mountpoint string
+}
+
+// Capabilities describes what a driver supports.
+type Capabilities struct {
+	// Scope is LocalScope or GlobalScope.
+	Scope string
+	// NoCopy is set by the drivers whose volumes must not be populated
+	// with the contents of the image they are mounted over, e.g. because
+	// they already hold data.
+	NoCopy bool
+}
+
+var drivers = struct {
+	sync.Mutex
+	m map[string]Driver
+}{m: make(map[string]Driver)}
+
+// RegisterDriver makes a volume driver available under name.
+func RegisterDriver(name string, d Driver) error {
+	drivers.Lock()
+	defer drivers.Unlock()
+
+	if _, ok := drivers.m[name]; ok {
+		return fmt.Errorf("volumedriver: driver named '%s' is already registered", name)
+	}
+	drivers.m[name] = d
+	return nil
+}
+
+// GetDriver returns the volume driver registered under name.
+func GetDriver(name string) (Driver, error) {
+	drivers.Lock()
+	defer drivers.Unlock()
+
+	d, ok := drivers.m[name]
+	if !ok {
+		return nil, fmt.Errorf("volumedriver: no driver named '%s' is registered", name)
+	}
+	return d, nil
+}
@@ -0,0 +1,65 @@
+package remote
+
+import "github.com/docker/docker/volumes/volumedriver"
+
+// The types below are the JSON payloads of the VolumeDriver plugin protocol.
+// Every response may carry an error message in Err.
+
+type response struct {
+	Err string
+}
+
+func (r *response) getError() string {
+	return r.Err
+}
+
+type maybeError interface {
+	getError() string
+}
+
+// volumeRequest is sent to VolumeDriver.Remove, VolumeDriver.Mount,
+// VolumeDriver.Unmount, VolumeDriver.Path and VolumeDriver.Get.
+type volumeRequest struct {
+	Name string
+}
+
+// createRequest is sent to VolumeDriver.Create.
+type createRequest struct {
+	Name string
+	Opts map[string]string
+}
+
+// mountpointResponse is returned by VolumeDriver.Mount and VolumeDriver.Path.
+type mountpointResponse struct {
+	response
+	Mountpoint string
+}
+
+type volume struct {
+	Name       string
+	Mountpoint string
+}
+
+type getResponse struct {
+	response
+	Volume *volume
+}
+
+type listResponse struct {
+	response
+	Volumes []*volume
+}
+
+// capabilitiesResponse is returned by VolumeDriver.Capabilities. Plugins
+// which do not implement it are of local scope.
+type capabilitiesResponse struct {
+	response
+	Capabilities volumedriver.Capabilities
+}
+
+func (v *volume) toDriver() *volumedriver.Volume {
+	return &volumedriver.Volume{
+		Name:       v.Name,
+		Mountpoint: v.Mountpoint,
+	}
+}
@@ -0,0 +1,135 @@
+// Package remote implements a volume driver proxying every operation to a
+// plugin implementing the VolumeDriver protocol.
+package remote
+
+import (
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/plugins"
+	"github.com/docker/docker/volumes/volumedriver"
+)
+
+// PluginType is the name of the subsystem volume plugins implement.
+const PluginType = "VolumeDriver"
+
+type driver struct {
+	name         string
+	endpoint     *plugins.Client
+	capabilities volumedriver.Capabilities
+}
+
+func init() {
+	plugins.Handle(PluginType, func(name string, client *plugins.Client) {
+		if err := volumedriver.RegisterDriver(name, newDriver(name, client)); err != nil {
+			logrus.Errorf("Error registering volume driver plugin %s: %v", name, err)
+		}
+	})
+}
+
+// newDriver returns the driver of the plugin name, after asking the plugin
+// for its capabilities.
+func newDriver(name string, client *plugins.Client) volumedriver.Driver {
+	d := &driver{
+		name:         name,
+		endpoint:     client,
+		capabilities: volumedriver.Capabilities{Scope: volumedriver.LocalScope},
+	}
+
+	var res capabilitiesResponse
+	if err := d.call("Capabilities", nil, &res); err != nil {
+		logrus.Debugf("Volume driver plugin %s did not report its capabilities, assuming local scope: %v", name, err)
+		return d
+	}
+	switch res.Capabilities.Scope {
+	case volumedriver.LocalScope, volumedriver.GlobalScope:
+	case "":
+		res.Capabilities.Scope = volumedriver.LocalScope
+	default:
+		logrus.Warnf("Volume driver plugin %s reported an unknown scope %s, assuming local scope", name, res.Capabilities.Scope)
+		res.Capabilities.Scope = volumedriver.LocalScope
+	}
+	d.capabilities = res.Capabilities
+	return d
+}
+
+// Load activates the volume plugin named name and returns its driver.
+func Load(name string) (volumedriver.Driver, error) {
+	if _, err := plugins.Get(name, PluginType); err != nil {
+		return nil, err
+	}
+	return volumedriver.GetDriver(name)
+}
+
+func (d *driver) call(methodName string, arg interface{}, retVal maybeError) error {
+	method := PluginType + "." + methodName
+	if err := d.endpoint.Call(method, arg, retVal); err != nil {
+		return err
+	}
+	if e := retVal.getError(); e != "" {
+		return fmt.Errorf("remote: %s", e)
+	}
+	return nil
+}
+
+func (d *driver) Create(name string, options map[string]string) error {
+	return d.call("Create", &createRequest{Name: name, Opts: options}, &response{})
+}
+
+func (d *driver) Remove(name string) error {
+	return d.call("Remove", &volumeRequest{Name: name}, &response{})
+}
+
+func (d *driver) Mount(name string) (string, error) {
+	var res mountpointResponse
+	if err := d.call("Mount", &volumeRequest{Name: name}, &res); err != nil {
+		return "", err
+	}
+	if res.Mountpoint == "" {
+		return "", fmt.Errorf("remote: %s returned no mountpoint for volume %s", d.name, name)
+	}
+	return res.Mountpoint, nil
+}
+
+func (d *driver) Unmount(name string) error {
+	return d.call("Unmount", &volumeRequest{Name: name}, &response{})
+}
+
+func (d *driver) Path(name string) (string, error) {
+	var res mountpointResponse
+	if err := d.call("Path", &volumeRequest{Name: name}, &res); err != nil {
+		return "", err
+	}
+	return res.Mountpoint, nil
+}
+
+func (d *driver) Get(name string) (*volumedriver.Volume, error) {
+	var res getResponse
+	if err := d.call("Get", &volumeRequest{Name: name}, &res); err != nil {
+		return nil, err
+	}
+	if res.Volume == nil || res.Volume.Name != name {
+		return nil, fmt.Errorf("remote: %s returned no volume %s", d.name, name)
+	}
+	return res.Volume.toDriver(), nil
+}
+
+func (d *driver) List() ([]*volumedriver.Volume, error) {
+	var res listResponse
+	if err := d.call("List", nil, &res); err != nil {
+		return nil, err
+	}
+	list := make([]*volumedriver.Volume, 0, len(res.Volumes))
+	for _, v := range res.Volumes {
+		list = append(list, v.toDriver())
+	}
+	return list, nil
+}
+
+func (d *driver) Capabilities() volumedriver.Capabilities {
+	return d.capabilities
+}
+
+func (d *driver) Type() string {
+	return d.name
+}
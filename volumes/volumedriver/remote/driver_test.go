@@ -0,0 +1,138 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/pkg/plugins"
+	"github.com/docker/docker/volumes/volumedriver"
+)
+
+func handle(t *testing.T, mux *http.ServeMux, method string, h func(map[string]interface{}) interface{}) {
+	mux.HandleFunc(fmt.Sprintf("/%s.%s", PluginType, method), func(w http.ResponseWriter, r *http.Request) {
+		var ask map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&ask); err != nil && r.ContentLength != 0 {
+			t.Fatal(err)
+		}
+		answer := h(ask)
+		if err := json.NewEncoder(w).Encode(&answer); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func setupPlugin(t *testing.T) (*http.ServeMux, *plugins.Client, func()) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	client, err := plugins.NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mux, client, server.Close
+}
+
+func TestRemoteDriver(t *testing.T) {
+	mux, client, cleanup := setupPlugin(t)
+	defer cleanup()
+
+	handle(t, mux, "Create", func(msg map[string]interface{}) interface{} {
+		if msg["Name"] != "data" {
+			return map[string]interface{}{"Err": "unexpected request"}
+		}
+		return map[string]interface{}{}
+	})
+	for _, method := range []string{"Mount", "Path"} {
+		handle(t, mux, method, func(msg map[string]interface{}) interface{} {
+			return map[string]interface{}{"Mountpoint": "/mnt/data"}
+		})
+	}
+	handle(t, mux, "Get", func(msg map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"Volume": map[string]interface{}{"Name": msg["Name"], "Mountpoint": "/mnt/data"},
+		}
+	})
+	handle(t, mux, "List", func(msg map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"Volumes": []interface{}{
+				map[string]interface{}{"Name": "data", "Mountpoint": "/mnt/data"},
+				map[string]interface{}{"Name": "other"},
+			},
+		}
+	})
+	for _, method := range []string{"Unmount", "Remove"} {
+		handle(t, mux, method, func(msg map[string]interface{}) interface{} {
+			return map[string]string{}
+		})
+	}
+
+	d := newDriver("test", client)
+	if scope := d.Capabilities().Scope; scope != volumedriver.LocalScope {
+		t.Fatalf("Expected a plugin without capabilities to be of local scope, got %s", scope)
+	}
+
+	if err := d.Create("data", map[string]string{"size": "10G"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Create("other", nil); err == nil || !strings.Contains(err.Error(), "unexpected request") {
+		t.Fatalf("Expected the plugin error to be returned, got %v", err)
+	}
+
+	mountpoint, err := d.Mount("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mountpoint != "/mnt/data" {
+		t.Fatalf("Expected mountpoint /mnt/data, got %s", mountpoint)
+	}
+	if mountpoint, err := d.Path("data"); err != nil || mountpoint != "/mnt/data" {
+		t.Fatalf("Expected path /mnt/data, got %s (%v)", mountpoint, err)
+	}
+
+	v, err := d.Get("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "data" || v.Mountpoint != "/mnt/data" {
+		t.Fatalf("Unexpected volume: %v", v)
+	}
+	list, err := d.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Name != "data" || list[1].Name != "other" {
+		t.Fatalf("Unexpected volumes: %v", list)
+	}
+
+	if err := d.Unmount("data"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove("data"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoteDriverCapabilities(t *testing.T) {
+	mux, client, cleanup := setupPlugin(t)
+	defer cleanup()
+
+	handle(t, mux, "Capabilities", func(msg map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"Capabilities": map[string]interface{}{"Scope": "global", "NoCopy": true},
+		}
+	})
+	handle(t, mux, "Mount", func(msg map[string]interface{}) interface{} {
+		return map[string]interface{}{}
+	})
+
+	d := newDriver("test", client)
+	if caps := d.Capabilities(); caps.Scope != volumedriver.GlobalScope || !caps.NoCopy {
+		t.Fatalf("Expected global scope without copy, got %+v", caps)
+	}
+	if _, err := d.Mount("data"); err == nil {
+		t.Fatal("Expected an error when the plugin returns no mountpoint")
+	}
+}